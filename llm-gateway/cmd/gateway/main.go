@@ -2,24 +2,58 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/analytics"
 	"github.com/username/llm-gateway/internal/api/rest"
+	"github.com/username/llm-gateway/internal/canary"
+	"github.com/username/llm-gateway/internal/compat"
 	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/controlplane"
+	"github.com/username/llm-gateway/internal/cost"
+	"github.com/username/llm-gateway/internal/discovery"
+	"github.com/username/llm-gateway/internal/ids"
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/notify"
+	"github.com/username/llm-gateway/internal/observability"
 	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/privacy"
 	"github.com/username/llm-gateway/internal/proxy"
 	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/internal/reload"
+	"github.com/username/llm-gateway/internal/retention"
+	"github.com/username/llm-gateway/internal/sla"
+	"github.com/username/llm-gateway/internal/styles"
+	"github.com/username/llm-gateway/pkg/models"
 )
 
 func main() {
+	// List config keys and their environment variables instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "config-keys" {
+		if err := runConfigKeys(os.Stdout, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "config-keys: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -28,9 +62,12 @@ func main() {
 	}
 
 	// Initialize logger
-	initLogger(cfg)
+	logCloser := initLogger(cfg)
 	log.Info().Str("version", cfg.Version).Msg("Starting LLM Gateway")
 
+	// Format of the IDs generated for responses, stream chunks and tool calls
+	ids.SetFormat(cfg.Server.ResponseIDFormat)
+
 	// Initialize HTTP connection pool for providers
 	poolConfig := performance.PoolConfig{
 		MaxIdleConns:        cfg.Performance.ConnectionPool.MaxIdleConns,
@@ -45,34 +82,216 @@ func main() {
 	performance.InitGlobalPool(poolConfig)
 	defer performance.CloseGlobalPool()
 
+	// Response cache for chat completions, shared by all instances with the redis backend (nil when disabled)
+	responseCache, err := performance.NewSemanticCache(performance.CacheConfig{
+		Enabled:        cfg.Cache.Enabled,
+		TTL:            cfg.Cache.TTL,
+		MaxEntries:     cfg.Cache.MaxEntries,
+		Backend:        cfg.Cache.Backend,
+		RedisAddress:   cfg.Cache.Redis.Address,
+		RedisPassword:  cfg.Cache.Redis.Password,
+		RedisDB:        cfg.Cache.Redis.DB,
+		RedisKeyPrefix: cfg.Cache.KeyPrefix,
+		RedisPoolSize:  cfg.Cache.PoolSize,
+		RedisTimeout:   cfg.Cache.Timeout,
+
+		SimilarityThreshold: cfg.Cache.Similarity.Threshold,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up response cache")
+	}
+	performance.SetDefaultCache(responseCache)
+	if responseCache != nil {
+		// Keep responses past their TTL for degraded mode
+		responseCache.SetMaxStale(cfg.Degraded.MaxStale)
+		defer responseCache.Close()
+	}
+
+	// Elect one replica to run singleton background jobs (nil when disabled)
+	elector, err := leader.New(cfg.LeaderElection)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up leader election")
+	}
+	leader.SetDefault(elector)
+	electCtx, electCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	elector.Start(electCtx)
+	electCancel()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		elector.Stop(ctx)
+	}()
+
 	// Initialize providers
-	providerRegistry := initProviders(cfg)
+	providerRegistry := initProviders(cfg, elector)
+	defer stopProviders(providerRegistry)
+
+	// Keep provider endpoints in sync with service discovery
+	watchers := startDiscovery(cfg, providerRegistry)
+	defer func() {
+		for _, w := range watchers {
+			w.Stop()
+		}
+	}()
 
 	// Initialize proxy router
 	proxyRouter := proxy.NewRouter(providerRegistry, cfg)
 
-	// Initialize HTTP server
-	router := rest.NewRouter(cfg, proxyRouter)
+	// Match cached responses by prompt similarity, embedding prompts through the router
+	if responseCache != nil && cfg.Cache.Similarity.Enabled {
+		responseCache.SetEmbedder(cacheEmbedder(proxyRouter, cfg.Cache.Similarity))
+	}
 
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      router,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+	// Pull routing rules, provider keys and budgets from the control plane (nil when disabled)
+	syncer := controlplane.New(cfg.ControlPlane)
+	controlplane.SetDefault(syncer)
+	syncer.OnUpdate(func(payload *controlplane.Payload) {
+		applyControlPlane(proxyRouter, payload)
+	})
+	// New payloads may first be canaried on a share of traffic; only routes and
+	// budgets differ per cohort, provider keys change once a payload is promoted
+	canary.SetDefault(syncer.Rollout())
+	syncer.OnCanary(func(payload *controlplane.Payload) {
+		if payload == nil {
+			proxyRouter.SetCanaryModelRoutes(nil)
+			return
+		}
+		if unknown := proxyRouter.SetCanaryModelRoutes(payload.Routes); len(unknown) > 0 {
+			log.Warn().Strs("models", unknown).Msg("Ignoring canary routes to unknown providers")
+		}
+	})
+
+	// Reload the config file on SIGHUP or, with watch, when it changes (nil when
+	// disabled); the API router registers the rate limiter
+	reloader := reload.New(cfg.ConfigReload, cfg)
+	reload.SetDefault(reloader)
+	reloader.OnReload(func(old, cur *config.Config) {
+		applyConfigReload(proxyRouter, responseCache, old, cur)
+	})
+
+	// Purge audit events and usage past their retention period (nil when disabled);
+	// the API router registers the usage tracker
+	retainer := retention.New(cfg.Retention)
+	retention.SetDefault(retainer)
+	retainer.Register("audit", observability.DefaultAuditBuffer())
+
+	// Per-user deletion requests; the API router registers the usage tracker and flight recorder
+	eraser := privacy.New(cfg.Privacy)
+	privacy.SetDefault(eraser)
+	eraser.Register("audit", observability.DefaultAuditBuffer())
+	if responseCache != nil {
+		// Cached responses are keyed by tenant and end user
+		retainer.Register("response_cache", responseCache)
+		eraser.Register("response_cache", responseCache)
+	}
+
+	// System prompt presets requests can reference by name (nil when disabled)
+	styleRegistry, err := styles.New(cfg.Styles)
+	if err != nil {
+		log.Fatal().Err(err).Str("file", cfg.Styles.File).Msg("Failed to load styles")
 	}
+	styles.SetDefault(styleRegistry)
+
+	// Repeated prompt statistics (nil when disabled); the API router registers it for deletion requests
+	analytics.SetDefault(analytics.NewPromptStats(cfg.PromptStats, cfg.Pricing))
+
+	// Per-key abuse signals and restrictions (nil when disabled)
+	abuse.SetDefault(abuse.New(cfg.AbuseDetection))
+
+	// Per-client report of ignored request fields (nil when disabled)
+	compat.SetDefault(compat.New(cfg.UnknownFields))
+
+	// Managed client API keys with quotas (nil when disabled)
+	keyManager, err := keys.New(cfg.APIKeys)
+	if err != nil {
+		log.Fatal().Err(err).Str("store", cfg.APIKeys.Store).Msg("Failed to load API keys")
+	}
+	keys.SetDefault(keyManager)
+	keyManager.Start()
+	defer keyManager.Stop()
+
+	// Spend per key, provider and model with spend budgets (nil when disabled)
+	cost.SetDefault(cost.New(cfg.Cost, cfg.Pricing))
+
+	// Notification webhook for gateway events such as quota warnings (nil when disabled)
+	notifier := notify.New(cfg.Notifications)
+	notify.SetDefault(notifier)
+	defer notifier.Stop()
+
+	// Daily and weekly provider SLA reports (nil when disabled)
+	slaCollector := sla.New(cfg.SLAReports, cfg.Pricing)
+	sla.SetDefault(slaCollector)
+	slaCollector.SetBreakerStates(proxyRouter.BreakerStates)
+	if slaCollector.Shared() {
+		// Only the leader purges reports kept in Redis
+		retainer.RegisterShared("sla_reports", slaCollector)
+	} else if slaCollector != nil {
+		retainer.Register("sla_reports", slaCollector)
+	}
+
+	// Initialize HTTP server (health, metrics and admin get their own listener if admin_port is set)
+	router, opsRouter := rest.NewRouters(cfg, proxyRouter)
+	retainer.Start()
+	defer retainer.Stop()
+	slaCollector.Start()
+	defer slaCollector.Stop()
+
+	// Sync before serving, so the first requests see control plane state
+	syncCtx, syncCancel := context.WithTimeout(context.Background(), cfg.ControlPlane.Timeout)
+	syncer.Start(syncCtx)
+	syncCancel()
+	defer syncer.Stop()
+	reloader.Start()
+	defer reloader.Stop()
+
+	server := newHTTPServer(cfg.Server, cfg.Server.Port, router)
 
 	// Start server in goroutine
 	go func() {
 		log.Info().
 			Int("port", cfg.Server.Port).
+			Str("protocols", server.Protocols.String()).
 			Msg("HTTP server starting")
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listenAndServe(server, cfg.Server); err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("HTTP server failed")
 		}
 	}()
 
+	// Also serve the API on a Unix socket for sidecar deployments
+	if cfg.Server.UnixSocket.Path != "" {
+		listener, err := listenUnix(cfg.Server.UnixSocket)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.Server.UnixSocket.Path).Msg("Failed to listen on unix socket")
+		}
+
+		go func() {
+			log.Info().
+				Str("path", cfg.Server.UnixSocket.Path).
+				Msg("Unix socket server starting")
+
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Unix socket server failed")
+			}
+		}()
+	}
+
+	// Start the internal health/metrics/admin server if configured
+	var opsServer *http.Server
+	if opsRouter != nil {
+		opsServer = newHTTPServer(cfg.Server, cfg.Server.AdminPort, opsRouter)
+
+		go func() {
+			log.Info().
+				Int("port", cfg.Server.AdminPort).
+				Msg("Admin/metrics server starting")
+
+			if err := listenAndServe(opsServer, cfg.Server); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Admin/metrics server failed")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -87,23 +306,116 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
+	if opsServer != nil {
+		if err := opsServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Admin/metrics server forced to shutdown")
+		}
+	}
+	if cfg.Observability.Tracing.Enabled {
+		if err := observability.GetTracer().Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to export the remaining trace spans")
+		}
+	}
 
 	log.Info().Msg("Server stopped")
+
+	// Flush buffered log output
+	if logCloser != nil {
+		logCloser.Close()
+	}
 }
 
-// initLogger configures the global zerolog logger
-func initLogger(cfg *config.Config) {
-	// Set log level
-	level, err := zerolog.ParseLevel(cfg.Log.Level)
+// newHTTPServer creates an inbound server on port with the configured timeouts and protocols
+func newHTTPServer(cfg config.ServerConfig, port int, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", port),
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		Protocols:    new(http.Protocols),
+	}
+
+	server.Protocols.SetHTTP1(true)
+	if cfg.HTTP2.Enabled && cfg.TLSCertFile != "" {
+		server.Protocols.SetHTTP2(true)
+	}
+	if cfg.HTTP2.H2C {
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+	server.HTTP2 = &http.HTTP2Config{
+		MaxConcurrentStreams: cfg.HTTP2.MaxConcurrentStreams,
+	}
+
+	return server
+}
+
+// listenAndServe serves over TLS when a certificate is configured, otherwise cleartext
+func listenAndServe(server *http.Server, cfg config.ServerConfig) error {
+	if cfg.TLSCertFile != "" {
+		return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.ListenAndServe()
+}
+
+// listenUnix listens on the configured Unix socket, replacing a stale socket
+// left by a previous run, and applies the configured file mode
+func listenUnix(cfg config.UnixSocketConfig) (net.Listener, error) {
+	mode, err := cfg.FileMode()
 	if err != nil {
-		level = zerolog.InfoLevel
+		return nil, err
+	}
+
+	if info, err := os.Stat(cfg.Path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", cfg.Path)
+		}
+		if err := os.Remove(cfg.Path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(cfg.Path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return listener, nil
+}
+
+// initLogger configures the global zerolog logger. The returned closer, if
+// any, flushes and closes the log file.
+func initLogger(cfg *config.Config) io.Closer {
+	// Log to a rotating, async-buffered file if configured
+	var output io.Writer = os.Stdout
+	var closer io.Closer
+	if cfg.Log.Output == "file" {
+		fileOutput, fileCloser, err := observability.NewLogOutput(observability.LoggingConfig{
+			Output:         cfg.Log.Output,
+			FilePath:       cfg.Log.FilePath,
+			MaxSizeMB:      cfg.Log.MaxSizeMB,
+			RotateInterval: cfg.Log.RotateInterval,
+			MaxBackups:     cfg.Log.MaxBackups,
+			Compress:       cfg.Log.Compress,
+			Async:          cfg.Log.Async,
+			BufferSize:     cfg.Log.BufferSize,
+			OverflowPolicy: cfg.Log.OverflowPolicy,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open log file, logging to stdout: %v\n", err)
+		} else {
+			output, closer = fileOutput, fileCloser
+			log.Logger = log.Output(output)
+		}
 	}
-	zerolog.SetGlobalLevel(level)
 
 	// Configure output format
 	if cfg.Log.Format == "pretty" {
 		log.Logger = log.Output(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+			Out:        output,
 			TimeFormat: time.RFC3339,
 		})
 	} else {
@@ -115,18 +427,44 @@ func initLogger(cfg *config.Config) {
 		Str("service", "llm-gateway").
 		Str("version", cfg.Version).
 		Logger()
+
+	// Apply the global and per-module levels; module loggers are rebuilt on the output above
+	logger, err := observability.InitLogLevels(log.Logger, cfg.Log.Level, cfg.Log.Modules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log level, using info: %v\n", err)
+		logger, _ = observability.InitLogLevels(log.Logger, "info", nil)
+	}
+	log.Logger = logger
+
+	return closer
+}
+
+// providerRegions converts configured provider regions
+func providerRegions(regions []config.RegionConfig) []providers.Region {
+	out := make([]providers.Region, 0, len(regions))
+	for _, r := range regions {
+		out = append(out, providers.Region{Name: r.Name, BaseURL: r.BaseURL})
+	}
+	return out
 }
 
 // initProviders initializes all configured LLM providers
-func initProviders(cfg *config.Config) *providers.Registry {
+func initProviders(cfg *config.Config, elector *leader.Elector) *providers.Registry {
 	registry := providers.NewRegistry()
+	registry.SetModelCacheTTL(cfg.Providers.ModelCacheTTL)
 
 	// Register OpenAI provider if configured
 	if cfg.Providers.OpenAI.APIKey != "" {
 		openai := providers.NewOpenAIProvider(providers.OpenAIConfig{
-			APIKey:  cfg.Providers.OpenAI.APIKey,
-			BaseURL: cfg.Providers.OpenAI.BaseURL,
-			Timeout: cfg.Providers.OpenAI.Timeout,
+			APIKey:            cfg.Providers.OpenAI.APIKey,
+			BaseURL:           cfg.Providers.OpenAI.BaseURL,
+			Timeout:           cfg.Providers.OpenAI.Timeout,
+			StandbyAPIKey:     cfg.Providers.OpenAI.StandbyAPIKey,
+			FailoverThreshold: cfg.Providers.CredentialFailoverThreshold,
+			APIKeys:           cfg.Providers.OpenAI.APIKeys,
+			KeyCooldown:       cfg.Providers.KeyCooldown,
+			Regions:           providerRegions(cfg.Providers.OpenAI.Regions),
+			APIVersion:        cfg.Providers.OpenAI.APIVersion,
 		})
 		registry.Register("openai", openai)
 		log.Info().Msg("OpenAI provider registered")
@@ -135,24 +473,261 @@ func initProviders(cfg *config.Config) *providers.Registry {
 	// Register Anthropic provider if configured
 	if cfg.Providers.Anthropic.APIKey != "" {
 		anthropic := providers.NewAnthropicProvider(providers.AnthropicConfig{
-			APIKey:  cfg.Providers.Anthropic.APIKey,
-			BaseURL: cfg.Providers.Anthropic.BaseURL,
-			Timeout: cfg.Providers.Anthropic.Timeout,
-			Version: cfg.Providers.Anthropic.Version,
+			APIKey:            cfg.Providers.Anthropic.APIKey,
+			BaseURL:           cfg.Providers.Anthropic.BaseURL,
+			Timeout:           cfg.Providers.Anthropic.Timeout,
+			Version:           cfg.Providers.Anthropic.Version,
+			StandbyAPIKey:     cfg.Providers.Anthropic.StandbyAPIKey,
+			FailoverThreshold: cfg.Providers.CredentialFailoverThreshold,
+			APIKeys:           cfg.Providers.Anthropic.APIKeys,
+			KeyCooldown:       cfg.Providers.KeyCooldown,
+			Regions:           providerRegions(cfg.Providers.Anthropic.Regions),
 		})
 		registry.Register("anthropic", anthropic)
 		log.Info().Msg("Anthropic provider registered")
 	}
 
 	// Register Ollama provider if configured
-	if cfg.Providers.Ollama.BaseURL != "" {
+	if cfg.Providers.Ollama.BaseURL != "" || cfg.Providers.Ollama.Discovery.Type != "" {
 		ollama := providers.NewOllamaProvider(providers.OllamaProviderConfig{
-			BaseURL: cfg.Providers.Ollama.BaseURL,
-			Timeout: cfg.Providers.Ollama.Timeout,
+			BaseURL:        cfg.Providers.Ollama.BaseURL,
+			Timeout:        cfg.Providers.Ollama.Timeout,
+			PrefetchModels: cfg.Providers.Ollama.PrefetchModels,
+			AutoPull:       cfg.Providers.Ollama.AutoPull,
+			PullTimeout:    cfg.Providers.Ollama.PullTimeout,
+			PullGate:       elector.IsLeader,
+			RefreshGate:    elector.IsLeader,
+			Instances:      cfg.Providers.Ollama.Instances,
+			PollInterval:   cfg.Providers.Ollama.PollInterval,
+			ModelCacheTTL:  cfg.Providers.ModelCacheTTL,
+
+			EmbedBatchSize:   cfg.Providers.Ollama.EmbedBatchSize,
+			EmbedConcurrency: cfg.Providers.Ollama.EmbedConcurrency,
+
+			ModelRefreshInterval: cfg.Providers.Ollama.ModelRefreshInterval,
 		})
 		registry.Register("ollama", ollama)
 		log.Info().Str("base_url", cfg.Providers.Ollama.BaseURL).Msg("Ollama provider registered")
+
+		// Make sure configured models are available before reporting ready
+		if len(cfg.Providers.Ollama.PrefetchModels) > 0 {
+			ollama.StartPrefetch()
+
+			// A replica elected later pulls whatever is still missing
+			if cfg.Providers.Ollama.AutoPull {
+				elector.OnElected(func() {
+					if err := ollama.StartPrefetch(); err != nil {
+						log.Warn().Err(err).Msg("Ollama prefetch on election skipped")
+					}
+				})
+			}
+		}
+	}
+
+	// Register out-of-process providers speaking the remote provider protocol
+	for name, remote := range cfg.Providers.Remote {
+		provider, err := providers.NewRemoteProvider(providers.RemoteProviderConfig{
+			Name:           name,
+			Address:        remote.Address,
+			TLS:            remote.TLS,
+			Timeout:        remote.Timeout,
+			Models:         remote.Models,
+			ModelCacheTTL:  cfg.Providers.ModelCacheTTL,
+			Command:        remote.Command,
+			HealthInterval: remote.HealthInterval,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Str("provider", name).Msg("Failed to set up remote provider")
+		}
+		registry.Register(name, provider)
+		log.Info().Str("provider", name).Str("address", remote.Address).Msg("Remote provider registered")
 	}
 
 	return registry
 }
+
+// stopProviders stops the background work of providers that have any, such
+// as health polling and the subprocesses of remote providers
+func stopProviders(registry *providers.Registry) {
+	for _, name := range registry.List() {
+		provider, _ := registry.Get(name)
+		if stopper, ok := provider.(interface{ Stop() }); ok {
+			stopper.Stop()
+		}
+	}
+}
+
+// applyControlPlane applies model routes and provider keys pushed by the control plane
+func applyControlPlane(router *proxy.Router, payload *controlplane.Payload) {
+	if unknown := router.SetModelRoutes(payload.Routes); len(unknown) > 0 {
+		log.Warn().Strs("models", unknown).Msg("Ignoring control plane routes to unknown providers")
+	}
+	for name, key := range payload.ProviderKeys {
+		if err := router.SetActiveCredential(name, key, "control_plane"); err != nil {
+			log.Warn().Err(err).Str("provider", name).Msg("Failed to apply control plane provider key")
+		}
+	}
+}
+
+// applyConfigReload applies the reloaded settings that are safe at runtime:
+// provider API keys, the model routing table, the cache TTL and log levels. Only changed settings are
+// applied, so keys and levels set at runtime by other means are kept.
+func applyConfigReload(router *proxy.Router, cache *performance.SemanticCache, old, cur *config.Config) {
+	apiKeys := map[string][2]string{
+		"openai":    {old.Providers.OpenAI.APIKey, cur.Providers.OpenAI.APIKey},
+		"anthropic": {old.Providers.Anthropic.APIKey, cur.Providers.Anthropic.APIKey},
+	}
+	for name, key := range apiKeys {
+		if key[0] == key[1] || key[1] == "" {
+			continue
+		}
+		if err := router.SetActiveCredential(name, key[1], "config_reload"); err != nil {
+			log.Warn().Err(err).Str("provider", name).Msg("Failed to apply reloaded provider key")
+		}
+	}
+
+	if !reflect.DeepEqual(old.Routing, cur.Routing) {
+		if unknown := router.SetRouting(cur.Routing); len(unknown) > 0 {
+			log.Warn().Strs("routes", unknown).Msg("Ignoring reloaded routes to unknown providers")
+		}
+	}
+
+	if cache != nil && old.Cache.TTL != cur.Cache.TTL {
+		cache.SetTTL(cur.Cache.TTL)
+	}
+	if cache != nil && old.Degraded.MaxStale != cur.Degraded.MaxStale {
+		cache.SetMaxStale(cur.Degraded.MaxStale)
+	}
+
+	if old.Log.Level != cur.Log.Level || !maps.Equal(old.Log.Modules, cur.Log.Modules) {
+		level := ""
+		if old.Log.Level != cur.Log.Level {
+			level = cur.Log.Level
+		}
+		modules := map[string]string{}
+		for module, l := range cur.Log.Modules {
+			if old.Log.Modules[module] != l {
+				modules[module] = l
+			}
+		}
+		// Modules dropped from the config lose their override
+		for module := range old.Log.Modules {
+			if _, ok := cur.Log.Modules[module]; !ok {
+				modules[module] = ""
+			}
+		}
+		if err := observability.UpdateLogLevels(level, modules); err != nil {
+			log.Warn().Err(err).Msg("Failed to apply reloaded log levels")
+		}
+	}
+}
+
+// cacheEmbedder embeds prompts for the response cache with the configured
+// embedding model
+func cacheEmbedder(router *proxy.Router, cfg config.CacheSimilarityConfig) performance.Embedder {
+	return func(ctx context.Context, text string) ([]float64, error) {
+		var provider proxy.Provider
+		var err error
+		if cfg.Provider != "" {
+			provider, err = router.GetProvider(cfg.Provider)
+		} else {
+			provider, err = router.GetProviderForModel(cfg.Model)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+		}
+		resp, err := provider.Embedding(ctx, &models.EmbeddingRequest{Model: cfg.Model, Input: text})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) == 0 {
+			return nil, fmt.Errorf("embedding provider %s returned no data", provider.Name())
+		}
+		return resp.Data[0].Embedding, nil
+	}
+}
+
+// startDiscovery starts a watcher for every registered provider that resolves
+// its endpoints via service discovery
+func startDiscovery(cfg *config.Config, registry *providers.Registry) []*discovery.Watcher {
+	var watchers []*discovery.Watcher
+	for name, dcfg := range map[string]config.DiscoveryConfig{
+		"openai": cfg.Providers.OpenAI.Discovery,
+		"ollama": cfg.Providers.Ollama.Discovery,
+	} {
+		if dcfg.Type == "" {
+			continue
+		}
+		provider, ok := registry.Get(name)
+		if !ok {
+			continue
+		}
+		updater, ok := provider.(providers.EndpointUpdater)
+		if !ok {
+			continue
+		}
+
+		resolver, err := discovery.NewResolver(dcfg)
+		if err != nil {
+			log.Fatal().Err(err).Str("provider", name).Msg("Failed to set up service discovery")
+		}
+		w := discovery.NewWatcher(name, resolver, dcfg.RefreshInterval, func(baseURLs []string) {
+			updater.SetEndpoints(baseURLs)
+			registry.InvalidateModels(name)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		w.Start(ctx)
+		cancel()
+
+		log.Info().
+			Str("provider", name).
+			Str("type", dcfg.Type).
+			Str("service", dcfg.Service).
+			Msg("Service discovery enabled")
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+// runConfigKeys prints every config path with its type, default, current
+// source and environment variable, as a table or as JSON with -json
+func runConfigKeys(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("config-keys", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := config.Keys()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(keys)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tTYPE\tDEFAULT\tSOURCE\tENV")
+	for _, key := range keys {
+		def := ""
+		if key.Default != nil {
+			def = fmt.Sprint(key.Default)
+		}
+		env := key.EnvVar
+		if env == "" {
+			env = "(file only)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", key.Path, key.Type, def, key.Source, env)
+	}
+	return tw.Flush()
+}
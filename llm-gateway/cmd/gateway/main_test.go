@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestNewHTTPServer_H2C(t *testing.T) {
+	cfg := config.ServerConfig{
+		HTTP2: config.HTTP2Config{Enabled: true, H2C: true, MaxConcurrentStreams: 10},
+	}
+	server := newHTTPServer(cfg, 0, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+	}))
+	if server.HTTP2.MaxConcurrentStreams != 10 {
+		t.Errorf("MaxConcurrentStreams = %d, want 10", server.HTTP2.MaxConcurrentStreams)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name  string
+		proto func(*http.Protocols)
+		want  string
+	}{
+		{"http1", func(p *http.Protocols) { p.SetHTTP1(true) }, "HTTP/1.1"},
+		{"h2c", func(p *http.Protocols) { p.SetUnencryptedHTTP2(true) }, "HTTP/2.0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &http.Transport{Protocols: new(http.Protocols)}
+			tc.proto(transport.Protocols)
+			defer transport.CloseIdleConnections()
+
+			resp, err := (&http.Client{Transport: transport}).Get("http://" + ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Proto"); got != tc.want {
+				t.Errorf("proto = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewHTTPServer_H2CDisabled(t *testing.T) {
+	server := newHTTPServer(config.ServerConfig{HTTP2: config.HTTP2Config{Enabled: true}}, 8080, http.NotFoundHandler())
+
+	if server.Protocols.UnencryptedHTTP2() {
+		t.Error("h2c should be disabled by default")
+	}
+	if server.Protocols.HTTP2() {
+		t.Error("HTTP/2 over TLS should require a certificate")
+	}
+	if !server.Protocols.HTTP1() {
+		t.Error("HTTP/1 should always be enabled")
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.sock")
+
+	// A stale socket from a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listenUnix(config.UnixSocketConfig{Path: path, Mode: "0600"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %o, want 0600", info.Mode().Perm())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://gateway/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusTeapot)
+	}
+}
+
+func TestListenUnix_RefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.sock")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := listenUnix(config.UnixSocketConfig{Path: path}); err == nil {
+		t.Error("expected an error for a non-socket file")
+	}
+}
+
+func TestRunConfigKeys(t *testing.T) {
+	var table bytes.Buffer
+	if err := runConfigKeys(&table, nil); err != nil {
+		t.Fatalf("runConfigKeys: %v", err)
+	}
+	if !strings.Contains(table.String(), "LLM_GATEWAY_SERVER_PORT") {
+		t.Errorf("table is missing server.port:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := runConfigKeys(&out, []string{"-json"}); err != nil {
+		t.Fatalf("runConfigKeys -json: %v", err)
+	}
+	var keys []config.KeyInfo
+	if err := json.Unmarshal(out.Bytes(), &keys); err != nil || len(keys) == 0 {
+		t.Errorf("invalid JSON output (%v): %s", err, out.String())
+	}
+}
@@ -0,0 +1,415 @@
+// Package abuse flags API keys that behave like abusers, such as a leaked key
+// used from many addresses or a script enumerating model names. Signals are
+// counted per key over a window; a key raising enough of them is moved to a
+// restricted rate limit tier for a while and operators are notified. State is
+// kept per replica.
+package abuse
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/notify"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the abuse module logger; its level can be set via log.modules.abuse
+var logger = observability.ModuleLogger("abuse")
+
+// Abuse signals
+const (
+	SignalVolumeSpike = "volume_spike"
+	SignalRefusalRate = "refusal_rate"
+	SignalManyIPs     = "many_ips"
+	SignalModelScan   = "model_scan"
+)
+
+// Restriction is a key moved to the restricted tier
+type Restriction struct {
+	// ID is the masked key (or "user:<id>" for token callers)
+	ID      string    `json:"id"`
+	Signals []string  `json:"signals"`
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until"`
+}
+
+// KeyActivity is a key's signal counts in the current window
+type KeyActivity struct {
+	ID       string   `json:"id"`
+	Requests int      `json:"requests"`
+	Refusals int      `json:"refusals"`
+	IPs      int      `json:"ips"`
+	Models   int      `json:"models"`
+	Signals  []string `json:"signals,omitempty"`
+	// Baseline is the average requests per window before the current one
+	Baseline float64 `json:"baseline"`
+}
+
+// keyState holds the counters of one key
+type keyState struct {
+	id          string
+	windowStart time.Time
+	requests    int
+	refusals    int
+	ips         map[string]struct{}
+	models      map[string]struct{}
+	fired       map[string]bool
+	// baseline averages the requests of earlier windows, over windows of them
+	baseline float64
+	windows  int
+	lastSeen time.Time
+}
+
+// Detector evaluates abuse signals per key
+type Detector struct {
+	cfg config.AbuseDetectionConfig
+	now func() time.Time
+
+	mu           sync.Mutex
+	keys         map[string]*keyState
+	restrictions map[string]*Restriction
+	lastPrune    time.Time
+}
+
+// New creates a detector from configuration, or returns nil if disabled
+func New(cfg config.AbuseDetectionConfig) *Detector {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Detector{
+		cfg:          cfg,
+		now:          time.Now,
+		keys:         make(map[string]*keyState),
+		restrictions: make(map[string]*Restriction),
+	}
+}
+
+// observation collects what one request contributes to its key's signals
+type observation struct {
+	mu      sync.Mutex
+	model   string
+	refused bool
+}
+
+type observationKey struct{}
+
+// Middleware counts each API request against its key and, for restricted
+// keys, selects the restricted rate limit tier. It must run before rate
+// limiting; requests outside /v1 are passed through.
+func (d *Detector) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := subject(r)
+			if key == "" || !strings.HasPrefix(r.URL.Path, "/v1") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			if d.cfg.RestrictedTier != "" && d.Restricted(key) {
+				ctx = context.WithValue(ctx, middleware.RateLimitTierContextKey, d.cfg.RestrictedTier)
+			}
+			obs := &observation{}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, observationKey{}, obs)))
+
+			obs.mu.Lock()
+			defer obs.mu.Unlock()
+			d.observe(key, clientIP(r), obs.model, obs.refused)
+		})
+	}
+}
+
+// ObserveModel records the model the current request asked for
+func ObserveModel(ctx context.Context, model string) {
+	if obs, ok := ctx.Value(observationKey{}).(*observation); ok {
+		obs.mu.Lock()
+		obs.model = model
+		obs.mu.Unlock()
+	}
+}
+
+// ObserveRefusal records that the current request was refused, e.g. by a
+// provider's content filter or a gateway policy
+func ObserveRefusal(ctx context.Context) {
+	if obs, ok := ctx.Value(observationKey{}).(*observation); ok {
+		obs.mu.Lock()
+		obs.refused = true
+		obs.mu.Unlock()
+	}
+}
+
+// subject identifies the caller: the user of an access token, else the API key
+func subject(r *http.Request) string {
+	if userID := middleware.GetUserID(r.Context()); userID != "" {
+		return "user:" + userID
+	}
+	if key := middleware.RequestAPIKey(r); key != "" {
+		return "key:" + key
+	}
+	return ""
+}
+
+// displayID hides the secret part of a key subject
+func displayID(key string) string {
+	if apiKey, ok := strings.CutPrefix(key, "key:"); ok {
+		return middleware.MaskAPIKey(apiKey)
+	}
+	return key
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// observe counts one request and raises the signals it pushes over their thresholds
+func (d *Detector) observe(key, ip, model string, refused bool) {
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune(now)
+
+	state := d.keys[key]
+	if state == nil {
+		state = &keyState{id: displayID(key)}
+		state.reset(now)
+		d.keys[key] = state
+	}
+	if now.Sub(state.windowStart) >= d.cfg.Window {
+		state.roll(now, d.cfg.Window)
+	}
+	state.lastSeen = now
+	state.requests++
+	if refused {
+		state.refusals++
+	}
+	if ip != "" {
+		state.ips[ip] = struct{}{}
+	}
+	if model != "" {
+		state.models[model] = struct{}{}
+	}
+
+	for _, signal := range d.signals(state) {
+		if state.fired[signal] {
+			continue
+		}
+		state.fired[signal] = true
+		d.raise(key, state, signal, now)
+	}
+}
+
+// signals returns the signals a key's counters currently exceed
+func (d *Detector) signals(state *keyState) []string {
+	var signals []string
+	if d.cfg.SpikeFactor > 0 && state.windows > 0 && state.requests >= d.cfg.SpikeMinRequests &&
+		float64(state.requests) > d.cfg.SpikeFactor*state.baseline {
+		signals = append(signals, SignalVolumeSpike)
+	}
+	if d.cfg.RefusalRate > 0 && state.requests >= max(d.cfg.RefusalMinRequests, 1) &&
+		float64(state.refusals)/float64(state.requests) >= d.cfg.RefusalRate {
+		signals = append(signals, SignalRefusalRate)
+	}
+	if d.cfg.MaxIPs > 0 && len(state.ips) > d.cfg.MaxIPs {
+		signals = append(signals, SignalManyIPs)
+	}
+	if d.cfg.MaxModels > 0 && len(state.models) > d.cfg.MaxModels {
+		signals = append(signals, SignalModelScan)
+	}
+	return signals
+}
+
+// raise reports a signal and restricts the key once it has raised enough of
+// them in the window
+func (d *Detector) raise(key string, state *keyState, signal string, now time.Time) {
+	fired := state.firedSignals()
+	restrict := d.cfg.RestrictedTier != "" && len(fired) >= d.cfg.RestrictAfter
+
+	var restriction *Restriction
+	if restrict {
+		restriction = d.restrictions[key]
+		if restriction == nil || !now.Before(restriction.Until) {
+			restriction = &Restriction{ID: state.id, Since: now}
+			d.restrictions[key] = restriction
+		}
+		restriction.Signals = fired
+		restriction.Until = now.Add(d.cfg.RestrictFor)
+	}
+
+	logger.Warn().
+		Str("key", state.id).
+		Str("signal", signal).
+		Int("requests", state.requests).
+		Int("refusals", state.refusals).
+		Int("ips", len(state.ips)).
+		Int("models", len(state.models)).
+		Bool("restricted", restrict).
+		Msg("Abuse signal raised")
+	observability.GetMetrics().RecordAbuseSignal(signal, restrict)
+
+	data := map[string]interface{}{
+		"key":      state.id,
+		"signal":   signal,
+		"signals":  fired,
+		"requests": state.requests,
+		"refusals": state.refusals,
+		"ips":      len(state.ips),
+		"models":   len(state.models),
+	}
+	if restrict {
+		data["restricted_tier"] = d.cfg.RestrictedTier
+		data["restricted_until"] = restriction.Until
+	}
+	observability.LogAudit(context.Background(), "abuse.signal", "api_key", data)
+	notify.Default().Emit(notify.Event{Type: "abuse.signal", Data: data})
+}
+
+// prune drops keys idle for two windows and expired restrictions, at most once a window
+func (d *Detector) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.cfg.Window {
+		return
+	}
+	d.lastPrune = now
+	for key, state := range d.keys {
+		if now.Sub(state.lastSeen) >= 2*d.cfg.Window {
+			delete(d.keys, key)
+		}
+	}
+	for key, restriction := range d.restrictions {
+		if !now.Before(restriction.Until) {
+			delete(d.restrictions, key)
+		}
+	}
+}
+
+func (s *keyState) reset(now time.Time) {
+	s.windowStart = now
+	s.requests = 0
+	s.refusals = 0
+	s.ips = make(map[string]struct{})
+	s.models = make(map[string]struct{})
+	s.fired = make(map[string]bool)
+}
+
+// roll starts a new window, folding the finished one into the baseline.
+// Windows without requests count as empty.
+func (s *keyState) roll(now time.Time, window time.Duration) {
+	elapsed := int(now.Sub(s.windowStart) / window)
+	total := s.baseline*float64(s.windows) + float64(s.requests)
+	s.windows += elapsed
+	s.baseline = total / float64(s.windows)
+	s.reset(s.windowStart.Add(time.Duration(elapsed) * window))
+}
+
+func (s *keyState) firedSignals() []string {
+	signals := make([]string, 0, len(s.fired))
+	for signal := range s.fired {
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	return signals
+}
+
+// Restricted reports whether a key is currently restricted
+func (d *Detector) Restricted(key string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	restriction, ok := d.restrictions[key]
+	return ok && d.now().Before(restriction.Until)
+}
+
+// Restrictions returns the keys currently restricted, most recent first
+func (d *Detector) Restrictions() []Restriction {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	restrictions := make([]Restriction, 0, len(d.restrictions))
+	for _, restriction := range d.restrictions {
+		if now.Before(restriction.Until) {
+			restrictions = append(restrictions, *restriction)
+		}
+	}
+	sort.Slice(restrictions, func(i, j int) bool { return restrictions[i].Since.After(restrictions[j].Since) })
+	return restrictions
+}
+
+// Activity returns the current window's counters of the keys that raised a
+// signal in it
+func (d *Detector) Activity() []KeyActivity {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var activity []KeyActivity
+	for _, state := range d.keys {
+		if len(state.fired) == 0 {
+			continue
+		}
+		activity = append(activity, KeyActivity{
+			ID:       state.id,
+			Requests: state.requests,
+			Refusals: state.refusals,
+			IPs:      len(state.ips),
+			Models:   len(state.models),
+			Signals:  state.firedSignals(),
+			Baseline: state.baseline,
+		})
+	}
+	sort.Slice(activity, func(i, j int) bool { return activity[i].ID < activity[j].ID })
+	return activity
+}
+
+// Lift ends the restriction of the key with the given ID (as listed by
+// Restrictions). It reports whether a restriction was lifted.
+func (d *Detector) Lift(id string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lifted := false
+	for key, restriction := range d.restrictions {
+		if restriction.ID == id {
+			delete(d.restrictions, key)
+			lifted = true
+		}
+	}
+	return lifted
+}
+
+// defaultDetector is the process-wide detector used by the API router and admin endpoints
+var defaultDetector atomic.Pointer[Detector]
+
+// SetDefault sets the process-wide detector
+func SetDefault(d *Detector) {
+	defaultDetector.Store(d)
+}
+
+// Default returns the process-wide detector (nil when disabled)
+func Default() *Detector {
+	return defaultDetector.Load()
+}
@@ -0,0 +1,149 @@
+package abuse
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+func testDetector(t *testing.T) (*Detector, *time.Time) {
+	t.Helper()
+	d := New(config.AbuseDetectionConfig{
+		Enabled:            true,
+		Window:             time.Minute,
+		SpikeFactor:        5,
+		SpikeMinRequests:   20,
+		RefusalRate:        0.5,
+		RefusalMinRequests: 4,
+		MaxIPs:             3,
+		MaxModels:          3,
+		RestrictedTier:     "restricted",
+		RestrictAfter:      1,
+		RestrictFor:        time.Hour,
+	})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func TestDetector_ModelScanRestrictsKey(t *testing.T) {
+	d, _ := testDetector(t)
+
+	var tier string
+	handler := d.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tier = middleware.GetRateLimitTier(r.Context())
+		ObserveModel(r.Context(), r.URL.Query().Get("model"))
+	}))
+	send := func(key, model string) {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?model="+model, nil)
+		r.Header.Set("Authorization", "Bearer "+key)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	for i := 0; i < 4; i++ {
+		send("sk-scanner-key-1234", fmt.Sprintf("model-%d", i))
+	}
+	if !d.Restricted("key:sk-scanner-key-1234") {
+		t.Fatal("key enumerating models should be restricted")
+	}
+	send("sk-scanner-key-1234", "model-0")
+	if tier != "restricted" {
+		t.Errorf("tier = %q, want restricted", tier)
+	}
+
+	// Other keys keep their tier
+	send("sk-other-key-5678", "model-0")
+	if tier != "" {
+		t.Errorf("tier of an unflagged key = %q", tier)
+	}
+
+	restrictions := d.Restrictions()
+	if len(restrictions) != 1 || restrictions[0].ID != "sk-scanner-k***" || restrictions[0].Signals[0] != SignalModelScan {
+		t.Fatalf("restrictions = %+v", restrictions)
+	}
+	if !d.Lift(restrictions[0].ID) || d.Restricted("key:sk-scanner-key-1234") {
+		t.Error("restriction should be lifted")
+	}
+}
+
+func TestDetector_ManyIPs(t *testing.T) {
+	d, _ := testDetector(t)
+	for i := 0; i < 4; i++ {
+		d.observe("key:leaked", fmt.Sprintf("10.0.0.%d", i), "gpt-4o", false)
+	}
+	activity := d.Activity()
+	if len(activity) != 1 || activity[0].IPs != 4 || activity[0].Signals[0] != SignalManyIPs {
+		t.Errorf("activity = %+v", activity)
+	}
+}
+
+func TestDetector_RefusalRate(t *testing.T) {
+	d, _ := testDetector(t)
+	for i := 0; i < 3; i++ {
+		d.observe("key:probe", "10.0.0.1", "gpt-4o", true)
+	}
+	if d.Restricted("key:probe") {
+		t.Fatal("refusal rate judged before refusal_min_requests")
+	}
+	d.observe("key:probe", "10.0.0.1", "gpt-4o", false)
+	if !d.Restricted("key:probe") {
+		t.Error("key with 3 of 4 requests refused should be restricted")
+	}
+}
+
+func TestDetector_VolumeSpike(t *testing.T) {
+	d, now := testDetector(t)
+
+	// Two windows of steady traffic set the baseline
+	for w := 0; w < 2; w++ {
+		for i := 0; i < 4; i++ {
+			d.observe("key:busy", "10.0.0.1", "gpt-4o", false)
+		}
+		*now = now.Add(time.Minute)
+	}
+	for i := 0; i < 20; i++ {
+		d.observe("key:busy", "10.0.0.1", "gpt-4o", false)
+	}
+	if d.Restricted("key:busy") {
+		t.Fatal("5x the baseline is not a spike")
+	}
+	d.observe("key:busy", "10.0.0.1", "gpt-4o", false)
+	if !d.Restricted("key:busy") {
+		t.Error("more than 5x the baseline should be a spike")
+	}
+
+	// A new key has no baseline to spike from
+	for i := 0; i < 50; i++ {
+		d.observe("key:new", "10.0.0.1", "gpt-4o", false)
+	}
+	if d.Restricted("key:new") {
+		t.Error("new key restricted without a baseline")
+	}
+}
+
+func TestDetector_RestrictionExpires(t *testing.T) {
+	d, now := testDetector(t)
+	for i := 0; i < 4; i++ {
+		d.observe("key:leaked", fmt.Sprintf("10.0.0.%d", i), "", false)
+	}
+	*now = now.Add(time.Hour)
+	if d.Restricted("key:leaked") || len(d.Restrictions()) != 0 {
+		t.Error("restriction should expire after restrict_for")
+	}
+}
+
+func TestDetector_Disabled(t *testing.T) {
+	var d *Detector
+	if New(config.AbuseDetectionConfig{}) != nil {
+		t.Fatal("New should return nil when disabled")
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if d.Middleware()(next) == nil || d.Restricted("key:x") || d.Restrictions() != nil {
+		t.Error("nil detector should be a no-op")
+	}
+}
@@ -0,0 +1,400 @@
+// Package analytics reports on traffic patterns. PromptStats fingerprints the
+// prompt of each request with simhash and groups identical or nearly
+// identical prompts, so the prompts repeated most often over a window, and
+// what they cost, show which requests are worth caching, templating or
+// precomputing.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the analytics module logger; its level can be set via log.modules.analytics
+var logger = observability.ModuleLogger("analytics")
+
+const (
+	// bucketsPerWindow is the resolution of the sliding window
+	bucketsPerWindow = 24
+	// maxFingerprintText bounds the bytes of a prompt that are fingerprinted
+	maxFingerprintText = 32 << 10
+	// shingleSize is the number of consecutive characters hashed as one
+	// feature; character shingles give short prompts enough features for small
+	// edits to move few bits
+	shingleSize = 4
+)
+
+// Simhash returns the 64-bit simhash of text over character shingles of its
+// lowercased words. Texts that share most of their shingles have fingerprints
+// a small Hamming distance apart; case, punctuation and spacing are ignored.
+func Simhash(text string) uint64 {
+	if len(text) > maxFingerprintText {
+		text = text[:maxFingerprintText]
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	normalized := []byte(strings.Join(words, " "))
+	n := min(shingleSize, len(normalized))
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+n <= len(normalized) && n > 0; i++ {
+		h.Reset()
+		h.Write(normalized[i : i+n])
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// PromptGroup is a set of identical or nearly identical prompts
+type PromptGroup struct {
+	// Fingerprint is the simhash of the first prompt of the group (hex)
+	Fingerprint string `json:"fingerprint"`
+	// Sample is the start of the first prompt, unless samples are disabled
+	Sample   string   `json:"sample,omitempty"`
+	Models   []string `json:"models"`
+	Requests int64    `json:"requests"`
+	// Variants is the number of distinct fingerprints folded into the group
+	Variants         int       `json:"variants"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+}
+
+// PromptReport summarizes the prompts seen over the window
+type PromptReport struct {
+	Window string `json:"window"`
+	// Requests counts every fingerprinted request in the window
+	Requests int64 `json:"requests"`
+	// RepeatedRequests counts requests whose prompt had been seen before in the window
+	RepeatedRequests int64   `json:"repeated_requests"`
+	RepeatedCost     float64 `json:"repeated_cost"`
+	// Groups are the prompts seen more than once
+	Groups []PromptGroup `json:"groups"`
+}
+
+// bucket holds one slice of the window for a group
+type bucket struct {
+	start            time.Time
+	requests         int64
+	promptTokens     int64
+	completionTokens int64
+	cost             float64
+}
+
+// group accumulates the requests of one PromptGroup
+type group struct {
+	fingerprint uint64
+	sample      string
+	// sampleUser is the end user whose prompt is the sample
+	sampleUser string
+	models     map[string]struct{}
+	variants   map[uint64]struct{}
+	buckets    []bucket
+	firstSeen  time.Time
+	lastSeen   time.Time
+}
+
+// PromptStats tracks repeated prompts over a sliding window
+type PromptStats struct {
+	cfg        config.PromptStatsConfig
+	pricing    config.PricingConfig
+	bucketSize time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	groups map[uint64]*group
+	// byVariant finds the group of a fingerprint seen before without a scan
+	byVariant map[uint64]*group
+}
+
+// NewPromptStats creates prompt statistics from configuration, pricing costs
+// with pricing, or returns nil if disabled
+func NewPromptStats(cfg config.PromptStatsConfig, pricing config.PricingConfig) *PromptStats {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &PromptStats{
+		cfg:        cfg,
+		pricing:    pricing,
+		bucketSize: cfg.Window / bucketsPerWindow,
+		now:        time.Now,
+		groups:     make(map[uint64]*group),
+		byVariant:  make(map[uint64]*group),
+	}
+}
+
+// observation collects what one request contributes to the statistics
+type observation struct {
+	mu               sync.Mutex
+	observed         bool
+	fingerprint      uint64
+	model            string
+	user             string
+	sample           string
+	promptTokens     int64
+	completionTokens int64
+}
+
+type observationKey struct{}
+
+// Middleware records the prompt and tokens of each request once it completes
+func (p *PromptStats) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if p == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			obs := &observation{}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), observationKey{}, obs)))
+
+			obs.mu.Lock()
+			defer obs.mu.Unlock()
+			if obs.observed {
+				p.record(obs)
+			}
+		})
+	}
+}
+
+// ObservePrompt fingerprints the prompt of the current request. user is the
+// end user the request was made for, if known.
+func ObservePrompt(ctx context.Context, model, user, text string) {
+	obs, ok := ctx.Value(observationKey{}).(*observation)
+	if !ok || strings.TrimSpace(text) == "" {
+		return
+	}
+	fingerprint := Simhash(text)
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	obs.observed = true
+	obs.fingerprint = fingerprint
+	obs.model = model
+	obs.user = user
+	obs.sample = text
+}
+
+// AddTokens adds tokens used by the current request to its prompt's cost
+func AddTokens(ctx context.Context, promptTokens, completionTokens int) {
+	obs, ok := ctx.Value(observationKey{}).(*observation)
+	if !ok {
+		return
+	}
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	obs.promptTokens += int64(promptTokens)
+	obs.completionTokens += int64(completionTokens)
+}
+
+// record adds a completed request to its prompt's group
+func (p *PromptStats) record(obs *observation) {
+	now := p.now()
+	cost := p.pricing.Cost(obs.model, obs.promptTokens, obs.completionTokens)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	g := p.find(obs.fingerprint)
+	if g == nil {
+		if len(p.groups) >= p.cfg.MaxPrompts {
+			p.evict(now)
+		}
+		g = &group{
+			fingerprint: obs.fingerprint,
+			sample:      truncate(obs.sample, p.cfg.SampleChars),
+			sampleUser:  obs.user,
+			models:      make(map[string]struct{}),
+			variants:    make(map[uint64]struct{}),
+			firstSeen:   now,
+		}
+		p.groups[obs.fingerprint] = g
+	}
+	if _, ok := g.variants[obs.fingerprint]; !ok {
+		g.variants[obs.fingerprint] = struct{}{}
+		p.byVariant[obs.fingerprint] = g
+	}
+	g.models[obs.model] = struct{}{}
+	g.lastSeen = now
+
+	start := now.Truncate(p.bucketSize)
+	if n := len(g.buckets); n == 0 || g.buckets[n-1].start.Before(start) {
+		g.buckets = append(g.buckets, bucket{start: start})
+	}
+	b := &g.buckets[len(g.buckets)-1]
+	b.requests++
+	b.promptTokens += obs.promptTokens
+	b.completionTokens += obs.completionTokens
+	b.cost += cost
+}
+
+// find returns the group of fingerprint: the group it was added to before, or
+// the first group within the configured distance. Callers hold p.mu.
+func (p *PromptStats) find(fingerprint uint64) *group {
+	if g, ok := p.byVariant[fingerprint]; ok {
+		return g
+	}
+	if p.cfg.Distance == 0 {
+		return nil
+	}
+	// A linear scan is cheap at the configured max_prompts (one XOR and
+	// popcount per group) and finds the nearest neighbour without an index
+	var best *group
+	bestDistance := p.cfg.Distance + 1
+	for _, g := range p.groups {
+		if d := bits.OnesCount64(g.fingerprint ^ fingerprint); d < bestDistance {
+			best, bestDistance = g, d
+		}
+	}
+	return best
+}
+
+// evict drops expired groups, or the least recently seen one if none expired.
+// Callers hold p.mu.
+func (p *PromptStats) evict(now time.Time) {
+	p.expire(now)
+	if len(p.groups) < p.cfg.MaxPrompts {
+		return
+	}
+	var oldest *group
+	for _, g := range p.groups {
+		if oldest == nil || g.lastSeen.Before(oldest.lastSeen) {
+			oldest = g
+		}
+	}
+	p.remove(oldest)
+	logger.Debug().
+		Int("max_prompts", p.cfg.MaxPrompts).
+		Time("last_seen", oldest.lastSeen).
+		Msg("Prompt statistics full, dropped least recently seen prompt")
+}
+
+// expire drops buckets that left the window and groups left without any.
+// Callers hold p.mu.
+func (p *PromptStats) expire(now time.Time) {
+	cutoff := now.Add(-p.cfg.Window)
+	for _, g := range p.groups {
+		i := 0
+		for i < len(g.buckets) && !g.buckets[i].start.After(cutoff) {
+			i++
+		}
+		g.buckets = g.buckets[i:]
+		if len(g.buckets) == 0 {
+			p.remove(g)
+		}
+	}
+}
+
+// remove drops a group and its variants. Callers hold p.mu.
+func (p *PromptStats) remove(g *group) {
+	delete(p.groups, g.fingerprint)
+	for variant := range g.variants {
+		delete(p.byVariant, variant)
+	}
+}
+
+// Report returns the prompts seen more than once in the window
+func (p *PromptStats) Report() *PromptReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expire(p.now())
+
+	report := &PromptReport{Window: p.cfg.Window.String(), Groups: []PromptGroup{}}
+	for _, g := range p.groups {
+		pg := PromptGroup{
+			Fingerprint: fmt.Sprintf("%016x", g.fingerprint),
+			Sample:      g.sample,
+			Models:      make([]string, 0, len(g.models)),
+			Variants:    len(g.variants),
+			FirstSeen:   g.firstSeen,
+			LastSeen:    g.lastSeen,
+		}
+		for _, b := range g.buckets {
+			pg.Requests += b.requests
+			pg.PromptTokens += b.promptTokens
+			pg.CompletionTokens += b.completionTokens
+			pg.Cost += b.cost
+		}
+		report.Requests += pg.Requests
+		if pg.Requests < 2 {
+			continue
+		}
+		for model := range g.models {
+			pg.Models = append(pg.Models, model)
+		}
+		sort.Strings(pg.Models)
+		// Every request after the first could have been served without the provider
+		report.RepeatedRequests += pg.Requests - 1
+		report.RepeatedCost += pg.Cost * float64(pg.Requests-1) / float64(pg.Requests)
+		report.Groups = append(report.Groups, pg)
+	}
+	return report
+}
+
+// DeleteUser drops the prompt samples taken from user's requests, returning
+// the number of samples dropped. Counts and fingerprints are kept.
+func (p *PromptStats) DeleteUser(user string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	deleted := 0
+	for _, g := range p.groups {
+		if g.sampleUser == user && g.sample != "" {
+			g.sample, g.sampleUser = "", ""
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// truncate returns the first n characters of text
+func truncate(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return text
+}
+
+// defaultStats is the process-wide prompt statistics used by the API router and admin endpoints
+var defaultStats atomic.Pointer[PromptStats]
+
+// SetDefault sets the process-wide prompt statistics
+func SetDefault(p *PromptStats) {
+	defaultStats.Store(p)
+}
+
+// Default returns the process-wide prompt statistics (nil when disabled)
+func Default() *PromptStats {
+	return defaultStats.Load()
+}
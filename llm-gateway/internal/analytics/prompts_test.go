@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestSimhash(t *testing.T) {
+	base := "Summarize the following support ticket in three bullet points for the on-call engineer: " +
+		"the customer reports that invoices generated after the upgrade show the wrong currency symbol"
+	near := base + " again"
+	other := "Write a haiku about autumn leaves falling on a quiet mountain lake at dawn"
+
+	if Simhash(base) != Simhash("SUMMARIZE the following support ticket, in three bullet points for the on-call engineer: "+
+		"the customer reports that invoices generated after the upgrade show the wrong currency symbol") {
+		t.Error("case and punctuation should not change the fingerprint")
+	}
+	if d := bits.OnesCount64(Simhash(base) ^ Simhash(near)); d > 6 {
+		t.Errorf("near duplicate distance = %d, want small", d)
+	}
+	if d := bits.OnesCount64(Simhash(base) ^ Simhash(other)); d < 10 {
+		t.Errorf("unrelated prompt distance = %d, want large", d)
+	}
+}
+
+func newTestStats(cfg config.PromptStatsConfig) (*PromptStats, *time.Time) {
+	cfg.Enabled = true
+	if cfg.Window == 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	if cfg.MaxPrompts == 0 {
+		cfg.MaxPrompts = 100
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := NewPromptStats(cfg, nil)
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+// send passes one request with prompt through the stats middleware
+func send(p *PromptStats, model, user, prompt string, promptTokens, completionTokens int) {
+	handler := p.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ObservePrompt(r.Context(), model, user, prompt)
+		AddTokens(r.Context(), promptTokens, completionTokens)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/chat/completions", nil))
+}
+
+func TestPromptStats_Report(t *testing.T) {
+	p, _ := newTestStats(config.PromptStatsConfig{
+		Distance:    3,
+		SampleChars: 10,
+	})
+	p.pricing = config.PricingConfig{
+		"gpt-4o": {Prompt: 5, Completion: 15},
+		"*":      {Prompt: 1, Completion: 1},
+	}
+	prompt := "Classify the sentiment of this product review as positive, negative or neutral and explain why"
+	for i := 0; i < 3; i++ {
+		send(p, "gpt-4o", "user-1", prompt, 100_000, 10_000)
+	}
+	send(p, "llama3", "user-2", prompt+" please", 1_000_000, 0)
+	send(p, "gpt-4o", "user-1", "What is the capital of France?", 10, 10)
+
+	report := p.Report()
+	if report.Requests != 5 || report.RepeatedRequests != 3 {
+		t.Errorf("requests = %d, repeated = %d, want 5 and 3", report.Requests, report.RepeatedRequests)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("groups = %+v, want the repeated prompt only", report.Groups)
+	}
+	g := report.Groups[0]
+	if g.Requests != 4 || g.Variants != 2 || len(g.Models) != 2 || g.Sample != "Classify t" {
+		t.Errorf("group = %+v", g)
+	}
+	// 3 x (0.1M x $5 + 0.01M x $15) + 1M x $1
+	if want := 3*(0.5+0.15) + 1.0; g.Cost < want-1e-9 || g.Cost > want+1e-9 {
+		t.Errorf("cost = %f, want %f", g.Cost, want)
+	}
+	if report.RepeatedCost <= 0 || report.RepeatedCost >= g.Cost {
+		t.Errorf("repeated cost = %f of %f", report.RepeatedCost, g.Cost)
+	}
+}
+
+func TestPromptStats_ExactOnly(t *testing.T) {
+	p, _ := newTestStats(config.PromptStatsConfig{Distance: 0})
+	prompt := "Translate the following paragraph into German and keep the formatting intact"
+	send(p, "m", "", prompt, 1, 1)
+	send(p, "m", "", prompt+" please", 1, 1)
+	if groups := p.Report().Groups; len(groups) != 0 {
+		t.Errorf("groups = %+v, want near duplicates kept apart", groups)
+	}
+}
+
+func TestPromptStats_Window(t *testing.T) {
+	p, now := newTestStats(config.PromptStatsConfig{Window: time.Hour})
+	send(p, "m", "", "hello there", 1, 1)
+	send(p, "m", "", "hello there", 1, 1)
+
+	*now = now.Add(30 * time.Minute)
+	send(p, "m", "", "hello there", 1, 1)
+	if g := p.Report().Groups; len(g) != 1 || g[0].Requests != 3 {
+		t.Fatalf("groups = %+v, want 3 requests in the window", g)
+	}
+
+	*now = now.Add(45 * time.Minute)
+	if report := p.Report(); report.Requests != 1 || len(report.Groups) != 0 {
+		t.Errorf("report = %+v, want only the last request left in the window", report)
+	}
+}
+
+func TestPromptStats_MaxPrompts(t *testing.T) {
+	p, now := newTestStats(config.PromptStatsConfig{MaxPrompts: 2})
+	send(p, "m", "", "first prompt seen here", 1, 1)
+	*now = now.Add(time.Minute)
+	send(p, "m", "", "second prompt is different", 1, 1)
+	*now = now.Add(time.Minute)
+	send(p, "m", "", "third one talks about cats", 1, 1)
+	send(p, "m", "", "third one talks about cats", 1, 1)
+
+	if report := p.Report(); report.Requests != 3 || len(p.groups) != 2 {
+		t.Errorf("requests = %d, groups = %d, want the oldest prompt dropped", report.Requests, len(p.groups))
+	}
+}
+
+func TestPromptStats_DeleteUser(t *testing.T) {
+	p, _ := newTestStats(config.PromptStatsConfig{SampleChars: 100})
+	send(p, "m", "user-1", "my account number is 1234", 1, 1)
+	send(p, "m", "user-2", "my account number is 1234", 1, 1)
+
+	if n := p.DeleteUser("user-1"); n != 1 {
+		t.Errorf("DeleteUser() = %d, want 1", n)
+	}
+	if g := p.Report().Groups; len(g) != 1 || g[0].Sample != "" || g[0].Requests != 2 {
+		t.Errorf("groups = %+v, want the sample dropped and counts kept", g)
+	}
+}
+
+func TestPromptStats_Disabled(t *testing.T) {
+	var p *PromptStats = NewPromptStats(config.PromptStatsConfig{}, nil)
+	called := false
+	handler := p.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ObservePrompt(r.Context(), "m", "", "hello")
+		called = true
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if p != nil || !called {
+		t.Error("disabled stats should pass requests through")
+	}
+}
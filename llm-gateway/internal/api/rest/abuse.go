@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// abuseDetector returns the process-wide abuse detector, writing a 404 if it
+// is not enabled
+func abuseDetector(w http.ResponseWriter) *abuse.Detector {
+	detector := abuse.Default()
+	if detector == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Abuse detection is not enabled")
+	}
+	return detector
+}
+
+// GetAbuseRestrictions handles GET /admin/v1/abuse/restrictions: the keys
+// moved to the restricted tier, most recent first
+func (h *AdminHandler) GetAbuseRestrictions(w http.ResponseWriter, r *http.Request) {
+	if detector := abuseDetector(w); detector != nil {
+		writeList(w, r, detector.Restrictions(), "id", "-since")
+	}
+}
+
+// GetAbuseActivity handles GET /admin/v1/abuse/activity: the counters of the
+// keys that raised signals in the current window
+func (h *AdminHandler) GetAbuseActivity(w http.ResponseWriter, r *http.Request) {
+	if detector := abuseDetector(w); detector != nil {
+		writeList(w, r, detector.Activity(), "id", "-requests")
+	}
+}
+
+// LiftAbuseRestriction handles DELETE /admin/v1/abuse/restrictions/{id}
+func (h *AdminHandler) LiftAbuseRestriction(w http.ResponseWriter, r *http.Request) {
+	detector := abuseDetector(w)
+	if detector == nil {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if !detector.Lift(id) {
+		writeJSONError(w, http.StatusNotFound, "not_found", "No restriction for key "+id)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "abuse.lift", id, map[string]interface{}{
+		"actor": middleware.GetUserID(r.Context()),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// contentFilterFinish is the finish reason of a choice cut off by a provider's content filter
+const contentFilterFinish = "content_filter"
+
+// observeContentFilter counts a response with a content-filtered choice as a
+// refusal for abuse detection
+func observeContentFilter(r *http.Request, finishReasons ...string) {
+	for _, reason := range finishReasons {
+		if reason == contentFilterFinish {
+			abuse.ObserveRefusal(r.Context())
+			return
+		}
+	}
+}
+
+func chatFinishReasons(resp *models.ChatCompletionResponse) []string {
+	reasons := make([]string, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		reasons[i] = choice.FinishReason
+	}
+	return reasons
+}
+
+func completionFinishReasons(resp *models.CompletionResponse) []string {
+	reasons := make([]string, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		reasons[i] = choice.FinishReason
+	}
+	return reasons
+}
@@ -0,0 +1,722 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/canary"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/controlplane"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/reload"
+	"github.com/username/llm-gateway/internal/retention"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// AdminHandler handles HTTP requests for runtime administration endpoints
+type AdminHandler struct {
+	proxyRouter *proxy.Router
+	maintenance *middleware.MaintenanceMode
+	usage       *middleware.UsageTracker
+}
+
+// NewAdminHandler creates a new AdminHandler with dependencies
+func NewAdminHandler(proxyRouter *proxy.Router, maintenance *middleware.MaintenanceMode, usage *middleware.UsageTracker) *AdminHandler {
+	return &AdminHandler{
+		proxyRouter: proxyRouter,
+		maintenance: maintenance,
+		usage:       usage,
+	}
+}
+
+// maintenanceRequest is the body accepted by PUT /admin/v1/maintenance
+type maintenanceRequest struct {
+	Enabled    bool   `json:"enabled"`
+	Message    string `json:"message,omitempty"`
+	RetryAfter string `json:"retry_after,omitempty"` // Go duration, e.g. "10m"
+}
+
+// GetMaintenance handles GET /admin/v1/maintenance
+func (h *AdminHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"maintenance": h.maintenance.Status(),
+		"providers":   h.proxyRouter.DrainStatus(),
+	})
+}
+
+// SetMaintenance handles PUT /admin/v1/maintenance
+func (h *AdminHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	var retryAfter time.Duration
+	if req.RetryAfter != "" {
+		d, err := time.ParseDuration(req.RetryAfter)
+		if err != nil || d <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "retry_after must be a positive duration")
+			return
+		}
+		retryAfter = d
+	}
+
+	if req.Enabled {
+		h.maintenance.Enable(req.Message, retryAfter)
+	} else {
+		h.maintenance.Disable()
+	}
+
+	observability.LogAudit(r.Context(), "maintenance.set", "gateway", map[string]interface{}{
+		"enabled": req.Enabled,
+		"actor":   middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, h.maintenance.Status())
+}
+
+// DrainProvider handles POST /admin/v1/providers/{provider}/drain
+func (h *AdminHandler) DrainProvider(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	if err := h.proxyRouter.DrainProvider(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "provider.drain", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"provider":  name,
+		"drained":   true,
+		"in_flight": h.proxyRouter.InFlight(name),
+	})
+}
+
+// UndrainProvider handles DELETE /admin/v1/providers/{provider}/drain
+func (h *AdminHandler) UndrainProvider(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	h.proxyRouter.UndrainProvider(name)
+
+	observability.LogAudit(r.Context(), "provider.undrain", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": name,
+		"drained":  false,
+	})
+}
+
+// ListProviders handles GET /admin/v1/providers: each provider's health,
+// drain and circuit breaker state
+func (h *AdminHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.providerHealth(), "provider", "provider")
+}
+
+// SetProviderEnabled handles PUT /admin/v1/providers/{provider}:
+// {"enabled": false} takes a provider out of rotation like a drain, and
+// {"enabled": true} returns it
+func (h *AdminHandler) SetProviderEnabled(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	if req.Enabled {
+		h.proxyRouter.UndrainProvider(name)
+	} else if err := h.proxyRouter.DrainProvider(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "provider.set_enabled", "provider", map[string]interface{}{
+		"provider": name,
+		"enabled":  req.Enabled,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider":  name,
+		"enabled":   req.Enabled,
+		"in_flight": h.proxyRouter.InFlight(name),
+	})
+}
+
+// GetCircuitBreakers handles GET /admin/v1/circuit-breakers
+func (h *AdminHandler) GetCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":   h.proxyRouter.IsReliabilityEnabled(),
+		"providers": h.proxyRouter.GetReliabilityStats(),
+	})
+}
+
+// ResetCircuitBreaker handles POST /admin/v1/providers/{provider}/circuit-breaker/reset
+func (h *AdminHandler) ResetCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	if err := h.proxyRouter.ResetCircuitBreaker(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "circuit_breaker.reset", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": name,
+		"state":    h.proxyRouter.BreakerStates()[name],
+	})
+}
+
+// GetRateLimits handles GET /admin/v1/rate-limits: the client rate limiter,
+// the request queue and the outbound limits per provider
+func (h *AdminHandler) GetRateLimits(w http.ResponseWriter, r *http.Request) {
+	inbound := map[string]interface{}{"enabled": false}
+	if rateLimiter != nil {
+		inbound = rateLimiter.GetStats()
+		inbound["enabled"] = true
+	}
+	queue := map[string]interface{}{"enabled": false}
+	if requestQueue != nil {
+		queue = requestQueue.Stats()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"inbound":  inbound,
+		"queue":    queue,
+		"outbound": h.proxyRouter.OutboundLimitStats(),
+	})
+}
+
+// mirrorStatsHandler handles GET /admin/v1/mirror: the requests mirrored to
+// the staging gateway, and those dropped, failed or too large to mirror
+func mirrorStatsHandler(mirror *middleware.Mirror) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mirror == nil {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": false})
+			return
+		}
+		stats := mirror.GetStats()
+		stats["enabled"] = true
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// DrainQueue handles POST /admin/v1/queue/drain: requests waiting for
+// outbound quota get 503 rather than waiting on. ?provider= limits it to one
+// provider's queue.
+func (h *AdminHandler) DrainQueue(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+
+	flushed, err := h.proxyRouter.FlushOutboundQueues(provider)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "queue.drain", "queue", map[string]interface{}{
+		"provider": provider,
+		"flushed":  flushed,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"flushed": flushed})
+}
+
+// standbyCredentialRequest is the body accepted by PUT /admin/v1/providers/{provider}/credentials/standby
+type standbyCredentialRequest struct {
+	APIKey string `json:"api_key"`
+}
+
+// GetCredentials handles GET /admin/v1/credentials
+func (h *AdminHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.proxyRouter.CredentialStatus(), "provider", "provider")
+}
+
+// GetOutboundLimits handles GET /admin/v1/outbound-limits
+func (h *AdminHandler) GetOutboundLimits(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.proxyRouter.OutboundLimitStats(), "provider", "provider")
+}
+
+// GetConfigKeys handles GET /admin/v1/config-keys
+func (h *AdminHandler) GetConfigKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := config.Keys()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "config_error", err.Error())
+		return
+	}
+	writeList(w, r, keys, "path", "path")
+}
+
+// GetConfigReload handles GET /admin/v1/config/reload: the generation of the
+// configuration in use and what the last reload changed
+func (h *AdminHandler) GetConfigReload(w http.ResponseWriter, r *http.Request) {
+	reloader := reload.Default()
+	if reloader == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Config reload is not enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, reloader.Status())
+}
+
+// ReloadConfig handles POST /admin/v1/config/reload: reads the config file
+// again, as on SIGHUP. An invalid file is rejected and the current
+// configuration kept.
+func (h *AdminHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	reloader := reload.Default()
+	if reloader == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Config reload is not enabled")
+		return
+	}
+	status, err := reloader.Reload()
+	observability.LogAudit(r.Context(), "config.reload", "config", map[string]interface{}{
+		"generation": status.Generation,
+		"applied":    status.Applied,
+		"error":      status.LastError,
+		"actor":      middleware.GetUserID(r.Context()),
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, "invalid_config", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// GetUsage handles GET /admin/v1/usage
+func (h *AdminHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if h.usage == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Usage tracking is not enabled")
+		return
+	}
+	// ?day=YYYY-MM-DD selects an earlier day kept by retention; rows carry their
+	// day, so the parameter also passes the list filter
+	day, tenants := h.usage.Today()
+	if d := r.URL.Query().Get("day"); d != "" && d != day {
+		day, tenants = d, h.usage.Day(d)
+	}
+	page, err := paginate(r, tenants, "tenant", "-requests")
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*listPage
+		Day string `json:"day"`
+	}{page, day})
+}
+
+// GetAudit handles GET /admin/v1/audit
+func (h *AdminHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, observability.AuditRecords(), "id", "-id")
+}
+
+// GetLeader handles GET /admin/v1/leader
+func (h *AdminHandler) GetLeader(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, leader.Default().Stats())
+}
+
+// GetCache handles GET /admin/v1/cache
+func (h *AdminHandler) GetCache(w http.ResponseWriter, r *http.Request) {
+	cache := performance.DefaultCache()
+	if cache == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, cache.Stats())
+}
+
+// FlushCache handles DELETE /admin/v1/cache: removes every cached response
+func (h *AdminHandler) FlushCache(w http.ResponseWriter, r *http.Request) {
+	cache := performance.DefaultCache()
+	if cache == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "The response cache is not enabled")
+		return
+	}
+	if err := cache.Clear(r.Context()); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to flush the cache: "+err.Error())
+		return
+	}
+
+	observability.LogAudit(r.Context(), "cache.flush", "cache", map[string]interface{}{
+		"actor": middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, cache.Stats())
+}
+
+// ResolveModel handles GET /admin/v1/models/resolve?model=: how a model
+// name resolves to a provider and why. canary=true resolves it as for a
+// request in the canary cohort.
+func (h *AdminHandler) ResolveModel(w http.ResponseWriter, r *http.Request) {
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "model is required")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.proxyRouter.ExplainModel(model, r.URL.Query().Get("canary") == "true"))
+}
+
+// GetRoutes handles GET /admin/v1/routes: the effective model routing table
+func (h *AdminHandler) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.proxyRouter.RoutingTable())
+}
+
+// GetModelConflicts handles GET /admin/v1/models/conflicts: the models
+// claimed by several providers
+func (h *AdminHandler) GetModelConflicts(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.proxyRouter.ModelConflicts(), "model", "model")
+}
+
+// GetTTFTSLOs handles GET /admin/v1/ttft-slos: each model's rolling
+// first-token latency on its provider and whether traffic is shifted
+func (h *AdminHandler) GetTTFTSLOs(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.proxyRouter.TTFTSLOStatus(), "id", "id")
+}
+
+// GetTTFTSLODecisions handles GET /admin/v1/ttft-slos/decisions: recent
+// reroutes and recoveries, newest first
+func (h *AdminHandler) GetTTFTSLODecisions(w http.ResponseWriter, r *http.Request) {
+	writeList(w, r, h.proxyRouter.TTFTSLODecisions(), "time", "-time")
+}
+
+// RevertTTFTSLO handles DELETE /admin/v1/ttft-slos/{id}/reroute: sends a
+// rerouted model's traffic back to its provider. The ID is "model@provider",
+// path-escaped if the model contains a slash.
+func (h *AdminHandler) RevertTTFTSLO(w http.ResponseWriter, r *http.Request) {
+	id, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Invalid route ID")
+		return
+	}
+	decision, ok := h.proxyRouter.RevertTTFTSLO(id, middleware.GetUserID(r.Context()))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Route "+id+" is not rerouted")
+		return
+	}
+
+	observability.LogAudit(r.Context(), "ttft_slo.revert", id, map[string]interface{}{
+		"model":    decision.Model,
+		"provider": decision.Provider,
+		"actor":    decision.Actor,
+	})
+	writeJSON(w, http.StatusOK, decision)
+}
+
+// GetControlPlane handles GET /admin/v1/control-plane
+func (h *AdminHandler) GetControlPlane(w http.ResponseWriter, r *http.Request) {
+	stats := controlplane.Default().Stats()
+	stats["model_routes"] = h.proxyRouter.ModelRoutes()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// SyncControlPlane handles POST /admin/v1/control-plane/sync
+func (h *AdminHandler) SyncControlPlane(w http.ResponseWriter, r *http.Request) {
+	syncer := controlplane.Default()
+	if syncer == nil {
+		writeJSONError(w, http.StatusConflict, "control_plane_disabled", "control plane sync is not enabled")
+		return
+	}
+	if err := syncer.Sync(r.Context()); err != nil {
+		writeJSONError(w, http.StatusBadGateway, "control_plane_sync_failed", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, syncer.Stats())
+}
+
+// GetCanary handles GET /admin/v1/canary
+func (h *AdminHandler) GetCanary(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, canary.Default().Stats())
+}
+
+// PromoteCanary handles POST /admin/v1/canary/promote
+func (h *AdminHandler) PromoteCanary(w http.ResponseWriter, r *http.Request) {
+	h.endCanary(w, r, canary.Default().Promote)
+}
+
+// RollbackCanary handles POST /admin/v1/canary/rollback
+func (h *AdminHandler) RollbackCanary(w http.ResponseWriter, r *http.Request) {
+	h.endCanary(w, r, canary.Default().Rollback)
+}
+
+// endCanary ends the rollout in progress by hand
+func (h *AdminHandler) endCanary(w http.ResponseWriter, r *http.Request, end func(reason string) error) {
+	if err := end("by " + middleware.GetUserID(r.Context())); err != nil {
+		writeJSONError(w, http.StatusConflict, "no_canary", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, canary.Default().Stats())
+}
+
+// GetRetention handles GET /admin/v1/retention
+func (h *AdminHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, retention.Default().Stats())
+}
+
+// PurgeRetention handles POST /admin/v1/retention/purge
+func (h *AdminHandler) PurgeRetention(w http.ResponseWriter, r *http.Request) {
+	retainer := retention.Default()
+	if retainer == nil {
+		writeJSONError(w, http.StatusConflict, "retention_disabled", "retention is not enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"purged": retainer.Purge(),
+	})
+}
+
+// DeleteTenant handles DELETE /admin/v1/tenants/{tenant}. The tenant's records
+// are hidden straight away and purged after retention.delete_grace.
+func (h *AdminHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	retainer := retention.Default()
+	if retainer == nil {
+		writeJSONError(w, http.StatusConflict, "retention_disabled", "retention is not enabled")
+		return
+	}
+	tenant := chi.URLParam(r, "tenant")
+	deleted := retainer.DeleteTenant(tenant)
+
+	// Recorded under tenant_id rather than tenant, so the deletion record itself
+	// belongs to the admin and outlives the tenant's data
+	observability.LogAudit(r.Context(), "tenant.delete", "tenant", map[string]interface{}{
+		"tenant_id": tenant,
+		"deleted":   deleted,
+		"actor":     middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"tenant":  tenant,
+		"deleted": deleted,
+	})
+}
+
+// FlipCredentials handles POST /admin/v1/providers/{provider}/credentials/flip
+func (h *AdminHandler) FlipCredentials(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	if err := h.proxyRouter.FlipCredentials(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "credentials.flip", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": name,
+		"flipped":  true,
+	})
+}
+
+// SetStandbyCredential handles PUT /admin/v1/providers/{provider}/credentials/standby
+func (h *AdminHandler) SetStandbyCredential(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	var req standbyCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+	if req.APIKey == "" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "api_key is required")
+		return
+	}
+
+	if err := h.proxyRouter.SetStandbyCredential(name, req.APIKey); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "credentials.set_standby", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPrefetch handles GET /admin/v1/providers/{provider}/prefetch
+func (h *AdminHandler) GetPrefetch(w http.ResponseWriter, r *http.Request) {
+	status, err := h.proxyRouter.PrefetchStatus(chi.URLParam(r, "provider"))
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// StartPrefetch handles POST /admin/v1/providers/{provider}/prefetch
+func (h *AdminHandler) StartPrefetch(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	if err := h.proxyRouter.StartPrefetch(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "provider.prefetch", "provider", map[string]interface{}{
+		"provider": name,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"provider": name,
+		"started":  true,
+	})
+}
+
+// RefreshModels handles POST /admin/v1/providers/{provider}/models/refresh
+func (h *AdminHandler) RefreshModels(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	list, err := h.proxyRouter.RefreshModels(r.Context(), name)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "provider.models_refresh", "provider", map[string]interface{}{
+		"provider": name,
+		"models":   len(list),
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+
+	ids := make([]string, len(list))
+	for i, m := range list {
+		ids[i] = m.ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": name,
+		"models":   ids,
+	})
+}
+
+// GetInstances handles GET /admin/v1/providers/{provider}/instances
+func (h *AdminHandler) GetInstances(w http.ResponseWriter, r *http.Request) {
+	instances, err := h.proxyRouter.InstanceStatus(chi.URLParam(r, "provider"))
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	writeList(w, r, instances, "base_url", "base_url")
+}
+
+// logLevelRequest is the body accepted by PUT /admin/v1/log-level
+type logLevelRequest struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"` // "" removes a module override
+}
+
+// GetLogLevel handles GET /admin/v1/log-level
+func (h *AdminHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, observability.LogLevelStatus())
+}
+
+// SetLogLevel handles PUT /admin/v1/log-level
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+	if req.Level == "" && len(req.Modules) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "level or modules is required")
+		return
+	}
+
+	if err := observability.UpdateLogLevels(req.Level, req.Modules); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	observability.LogAudit(r.Context(), "log.set_level", "log_level", map[string]interface{}{
+		"level":   req.Level,
+		"modules": req.Modules,
+		"actor":   middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, observability.LogLevelStatus())
+}
+
+// GetFlightRecorder handles GET /admin/v1/flight-recorder
+func (h *AdminHandler) GetFlightRecorder(w http.ResponseWriter, r *http.Request) {
+	recorder := observability.GetFlightRecorder()
+	if recorder == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Flight recorder is not enabled")
+		return
+	}
+	page, err := paginate(r, recorder.Records(), "request_id", "-start")
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*listPage
+		Status map[string]interface{} `json:"status"`
+	}{page, recorder.Status()})
+}
+
+// DumpFlightRecorder handles POST /admin/v1/flight-recorder/dump
+func (h *AdminHandler) DumpFlightRecorder(w http.ResponseWriter, r *http.Request) {
+	recorder := observability.GetFlightRecorder()
+	if recorder == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Flight recorder is not enabled")
+		return
+	}
+
+	status := recorder.Status()
+	rate, _ := status["error_rate"].(float64)
+	recorder.Dump(r.Context(), "manual", rate)
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"dumped": status["buffered"],
+	})
+}
+
+// writeProviderError writes a ProviderError using its status, or a 500 for other errors
+func writeProviderError(w http.ResponseWriter, err error) {
+	var providerErr *proxy.ProviderError
+	if errors.As(err, &providerErr) {
+		writeJSONError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes an OpenAI-style JSON error response
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, models.ErrorResponse{
+		Error: models.APIError{
+			Type:    code,
+			Message: message,
+		},
+	})
+}
@@ -0,0 +1,315 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/ids"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// anthropicOutputChunk holds the fields of an OpenAI stream chunk, or of the
+// error events the gateway sends, that the Anthropic conversion uses
+type anthropicOutputChunk struct {
+	ID      string                              `json:"id"`
+	Model   string                              `json:"model"`
+	Choices []models.ChatCompletionStreamChoice `json:"choices"`
+	Usage   *models.Usage                       `json:"usage"`
+	Error   *models.APIError                    `json:"error"`
+}
+
+// anthropicStreamWriter converts the OpenAI chunks written to a /v1/messages
+// stream into Anthropic Messages events, so Anthropic SDKs can read streams
+// from any provider. The first chunk becomes message_start; text deltas
+// become text blocks and tool call deltas tool_use blocks with
+// input_json_delta arguments, each block opened with content_block_start and
+// closed with content_block_stop; [DONE] becomes message_delta, carrying the
+// stop reason and output tokens, and message_stop; error events become error
+// events. Anthropic blocks follow one another while OpenAI tool calls may
+// interleave, so the first tool call streams and stays open until the end;
+// later tool calls, and text after it, are buffered and written as complete
+// blocks after it. Responses that are not event streams, such as errors written before
+// the stream starts, pass through unchanged.
+type anthropicStreamWriter struct {
+	http.ResponseWriter
+	model string
+	// inputTokens is the prompt's estimate until the provider reports usage
+	inputTokens  int
+	outputTokens int
+
+	checked  bool
+	sse      bool
+	partial  []byte
+	started  bool
+	finished bool
+
+	// open is the index of the open content block, -1 when none is
+	open       int
+	openType   string
+	next       int
+	stopReason string
+	// liveTool is the index of the tool call streaming in the open block
+	liveTool int
+	// pendingText and pendingTools hold the content that arrived once a tool
+	// call was streaming, tool calls in order of appearance
+	pendingText  strings.Builder
+	pendingTools []*models.ToolCall
+	pendingIndex map[int]*models.ToolCall
+}
+
+func newAnthropicStreamWriter(w http.ResponseWriter, model string, inputTokens int) *anthropicStreamWriter {
+	return &anthropicStreamWriter{
+		ResponseWriter: w,
+		model:          model,
+		inputTokens:    inputTokens,
+		open:           -1,
+		stopReason:     "end_turn",
+		pendingIndex:   make(map[int]*models.ToolCall),
+	}
+}
+
+// Write converts the complete SSE lines in p, holding a trailing partial line
+func (w *anthropicStreamWriter) Write(p []byte) (int, error) {
+	if !w.checked {
+		w.checked = true
+		w.sse = strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+	}
+	if !w.sse {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.partial = append(w.partial, p...)
+	var out bytes.Buffer
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.convert(&out, w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+	if out.Len() > 0 {
+		if _, err := w.ResponseWriter.Write(out.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the converted events to the client
+func (w *anthropicStreamWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *anthropicStreamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// convert writes the Anthropic events for one line of the OpenAI stream.
+// Only data lines matter; other events, such as citations, have no
+// Anthropic equivalent here and are dropped.
+func (w *anthropicStreamWriter) convert(out *bytes.Buffer, line []byte) {
+	if w.finished {
+		return
+	}
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if bytes.Equal(payload, []byte("[DONE]")) {
+		w.finish(out)
+		return
+	}
+	var chunk anthropicOutputChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return
+	}
+
+	if chunk.Error != nil {
+		writeAnthropicEvent(out, "error", map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": chunk.Error.Type, "message": chunk.Error.Message},
+		})
+		w.finished = true
+		return
+	}
+	if chunk.Usage != nil {
+		w.inputTokens = chunk.Usage.PromptTokens
+		w.outputTokens = chunk.Usage.CompletionTokens
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	w.start(out, chunk.ID, chunk.Model)
+
+	// Anthropic messages have one choice
+	choice := chunk.Choices[0]
+	if text := choice.Delta.Content; text != "" {
+		switch w.openType {
+		case "tool_use":
+			w.pendingText.WriteString(text)
+		case "text":
+			w.delta(out, w.open, map[string]interface{}{"type": "text_delta", "text": text})
+		default:
+			w.openBlock(out, map[string]interface{}{"type": "text", "text": ""})
+			w.delta(out, w.open, map[string]interface{}{"type": "text_delta", "text": text})
+		}
+	}
+	for i, call := range choice.Delta.ToolCalls {
+		index := i
+		if call.Index != nil {
+			index = *call.Index
+		}
+		switch {
+		case w.openType != "tool_use":
+			w.liveTool = index
+			w.openToolBlock(out, call.ID, call.Function.Name)
+			fallthrough
+		case index == w.liveTool:
+			if call.Function.Arguments != "" {
+				w.delta(out, w.open, map[string]interface{}{"type": "input_json_delta", "partial_json": call.Function.Arguments})
+			}
+		default:
+			w.bufferToolCall(index, call)
+		}
+	}
+	if choice.FinishReason != nil {
+		w.stopReason = anthropicStopReason(*choice.FinishReason)
+	}
+}
+
+// start writes message_start before the first content
+func (w *anthropicStreamWriter) start(out *bytes.Buffer, id, model string) {
+	if w.started {
+		return
+	}
+	w.started = true
+	if model == "" {
+		model = w.model
+	}
+	if id == "" {
+		id = ids.New()
+	}
+	writeAnthropicEvent(out, "message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":            "msg_" + strings.TrimPrefix(id, "chatcmpl-"),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": w.inputTokens, "output_tokens": 0},
+		},
+	})
+}
+
+// openBlock closes the open content block and starts block, returning its index
+func (w *anthropicStreamWriter) openBlock(out *bytes.Buffer, block map[string]interface{}) int {
+	w.closeBlock(out)
+	w.open, w.openType = w.next, block["type"].(string)
+	w.next++
+	writeAnthropicEvent(out, "content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         w.open,
+		"content_block": block,
+	})
+	return w.open
+}
+
+// openToolBlock starts a tool_use block
+func (w *anthropicStreamWriter) openToolBlock(out *bytes.Buffer, id, name string) {
+	w.openBlock(out, map[string]interface{}{
+		"type":  "tool_use",
+		"id":    id,
+		"name":  name,
+		"input": map[string]interface{}{},
+	})
+}
+
+// bufferToolCall adds a tool call delta to the tool calls written at the end
+func (w *anthropicStreamWriter) bufferToolCall(index int, call models.ToolCall) {
+	pending, ok := w.pendingIndex[index]
+	if !ok {
+		pending = &models.ToolCall{}
+		w.pendingIndex[index] = pending
+		w.pendingTools = append(w.pendingTools, pending)
+	}
+	if pending.ID == "" {
+		pending.ID = call.ID
+	}
+	if pending.Function.Name == "" {
+		pending.Function.Name = call.Function.Name
+	}
+	pending.Function.Arguments += call.Function.Arguments
+}
+
+// closeBlock writes content_block_stop for the open content block
+func (w *anthropicStreamWriter) closeBlock(out *bytes.Buffer) {
+	if w.open < 0 {
+		return
+	}
+	writeAnthropicEvent(out, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": w.open})
+	w.open, w.openType = -1, ""
+}
+
+// delta writes a content_block_delta for block
+func (w *anthropicStreamWriter) delta(out *bytes.Buffer, block int, delta map[string]interface{}) {
+	writeAnthropicEvent(out, "content_block_delta", map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": block,
+		"delta": delta,
+	})
+}
+
+// finish ends the message with message_delta and message_stop
+func (w *anthropicStreamWriter) finish(out *bytes.Buffer) {
+	w.start(out, "", "")
+	w.closeBlock(out)
+	if w.pendingText.Len() > 0 {
+		w.openBlock(out, map[string]interface{}{"type": "text", "text": ""})
+		w.delta(out, w.open, map[string]interface{}{"type": "text_delta", "text": w.pendingText.String()})
+	}
+	for _, call := range w.pendingTools {
+		w.openToolBlock(out, call.ID, call.Function.Name)
+		if call.Function.Arguments != "" {
+			w.delta(out, w.open, map[string]interface{}{"type": "input_json_delta", "partial_json": call.Function.Arguments})
+		}
+	}
+	w.closeBlock(out)
+	writeAnthropicEvent(out, "message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": w.stopReason, "stop_sequence": nil},
+		"usage": map[string]int{"input_tokens": w.inputTokens, "output_tokens": w.outputTokens},
+	})
+	writeAnthropicEvent(out, "message_stop", map[string]interface{}{"type": "message_stop"})
+	w.finished = true
+}
+
+// writeAnthropicEvent writes a named SSE event
+func writeAnthropicEvent(out *bytes.Buffer, name string, event interface{}) {
+	out.WriteString("event: " + name + "\n")
+	out.Write(encodeDataLine(event))
+	out.WriteString("\n")
+}
+
+// anthropicStopReason maps an OpenAI finish_reason to an Anthropic stop_reason
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
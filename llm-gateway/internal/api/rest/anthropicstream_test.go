@@ -0,0 +1,232 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// anthropicEvent is one parsed event of an Anthropic stream
+type anthropicEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// readAnthropicEvents parses the named SSE events of body
+func readAnthropicEvents(t *testing.T, body string) []anthropicEvent {
+	t.Helper()
+	var events []anthropicEvent
+	var name string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			name = event
+			continue
+		}
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &data); err != nil {
+			t.Fatalf("event %s: invalid data %q", name, payload)
+		}
+		if data["type"] != name {
+			t.Errorf("event %s has type %v", name, data["type"])
+		}
+		events = append(events, anthropicEvent{name: name, data: data})
+	}
+	return events
+}
+
+// eventNames lists the names of events
+func eventNames(events []anthropicEvent) string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestAnthropicStreamWriter_TextAndToolCalls(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("Content-Type", "text/event-stream")
+	w := newAnthropicStreamWriter(rr, "claude-3-5-haiku-20241022", 12)
+
+	stream := `data: {"id":"chatcmpl-abc","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Let me "}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-abc","choices":[{"index":0,"delta":{"content":"check."}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-abc","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-abc","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-abc","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-abc","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":9,"total_tokens":29}}` + "\n\n" +
+		"data: [DONE]\n\n"
+	// Write in pieces that split lines, as a stream would
+	for len(stream) > 0 {
+		n := min(37, len(stream))
+		if _, err := w.Write([]byte(stream[:n])); err != nil {
+			t.Fatal(err)
+		}
+		stream = stream[n:]
+	}
+
+	events := readAnthropicEvents(t, rr.Body.String())
+	want := "message_start,content_block_start,content_block_delta,content_block_delta,content_block_stop," +
+		"content_block_start,content_block_delta,content_block_delta,content_block_stop,message_delta,message_stop"
+	if got := eventNames(events); got != want {
+		t.Fatalf("events = %s\nwant %s", got, want)
+	}
+
+	message := events[0].data["message"].(map[string]interface{})
+	if message["id"] != "msg_abc" || message["model"] != "gpt-4o" || message["role"] != "assistant" {
+		t.Errorf("message_start = %v", message)
+	}
+	if text := events[2].data["delta"].(map[string]interface{})["text"]; text != "Let me " {
+		t.Errorf("first text delta = %v", text)
+	}
+	tool := events[5].data["content_block"].(map[string]interface{})
+	if events[5].data["index"] != 1.0 || tool["type"] != "tool_use" || tool["id"] != "call_1" || tool["name"] != "get_weather" {
+		t.Errorf("tool_use block = %v", events[5].data)
+	}
+	var arguments string
+	for _, e := range events[6:8] {
+		delta := e.data["delta"].(map[string]interface{})
+		if delta["type"] != "input_json_delta" {
+			t.Errorf("tool delta = %v", delta)
+		}
+		arguments += delta["partial_json"].(string)
+	}
+	if arguments != `{"city":"Paris"}` {
+		t.Errorf("arguments = %s", arguments)
+	}
+
+	delta := events[9].data
+	if delta["delta"].(map[string]interface{})["stop_reason"] != "tool_use" {
+		t.Errorf("message_delta = %v", delta)
+	}
+	if usage := delta["usage"].(map[string]interface{}); usage["output_tokens"] != 9.0 || usage["input_tokens"] != 20.0 {
+		t.Errorf("message_delta usage = %v", usage)
+	}
+}
+
+func TestAnthropicStreamWriter_InterleavedToolCalls(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("Content-Type", "text/event-stream")
+	w := newAnthropicStreamWriter(rr, "claude-3-5-haiku-20241022", 12)
+
+	chunks := []string{
+		`{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_weather","arguments":"{\"city\":"}}]}`,
+		`{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"get_time","arguments":"{\"zone\":"}}]}`,
+		`{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}`,
+		`{"content":"Checking."}`,
+		`{"tool_calls":[{"index":1,"function":{"arguments":"\"CET\"}"}}]}`,
+	}
+	for _, delta := range chunks {
+		w.Write([]byte(`data: {"id":"chatcmpl-abc","choices":[{"index":0,"delta":` + delta + `}]}` + "\n\n"))
+	}
+	w.Write([]byte("data: [DONE]\n\n"))
+
+	events := readAnthropicEvents(t, rr.Body.String())
+	stopped := make(map[float64]bool)
+	blocks := make(map[float64]map[string]interface{})
+	content := make(map[float64]string)
+	for _, e := range events {
+		index, _ := e.data["index"].(float64)
+		switch e.name {
+		case "content_block_start":
+			blocks[index] = e.data["content_block"].(map[string]interface{})
+		case "content_block_delta":
+			if stopped[index] {
+				t.Errorf("delta for stopped block %v: %v", index, e.data)
+			}
+			delta := e.data["delta"].(map[string]interface{})
+			if delta["type"] == "input_json_delta" {
+				content[index] += delta["partial_json"].(string)
+			} else {
+				content[index] += delta["text"].(string)
+			}
+		case "content_block_stop":
+			stopped[index] = true
+		}
+	}
+
+	want := []struct{ typ, id, content string }{
+		{"tool_use", "call_1", `{"city":"Paris"}`},
+		{"text", "", "Checking."},
+		{"tool_use", "call_2", `{"zone":"CET"}`},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("blocks = %v", blocks)
+	}
+	for i, w := range want {
+		index := float64(i)
+		if blocks[index]["type"] != w.typ || w.id != "" && blocks[index]["id"] != w.id || content[index] != w.content || !stopped[index] {
+			t.Errorf("block %d = %v, content %q, stopped %v", i, blocks[index], content[index], stopped[index])
+		}
+	}
+}
+
+func TestAnthropicStreamWriter_Error(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("Content-Type", "text/event-stream")
+	w := newAnthropicStreamWriter(rr, "claude-3-5-haiku-20241022", 12)
+
+	w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Hi"}}]}` + "\n\n"))
+	w.Write([]byte(`data: {"error":{"type":"output_filtered","message":"blocked"}}` + "\n\n" + "data: [DONE]\n\n"))
+
+	events := readAnthropicEvents(t, rr.Body.String())
+	if got := eventNames(events); got != "message_start,content_block_start,content_block_delta,error" {
+		t.Fatalf("events = %s", got)
+	}
+	if e := events[3].data["error"].(map[string]interface{}); e["type"] != "output_filtered" || e["message"] != "blocked" {
+		t.Errorf("error = %v", e)
+	}
+}
+
+func TestAnthropicStreamWriter_PassesThroughNonStreams(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("Content-Type", "application/json")
+	w := newAnthropicStreamWriter(rr, "claude-3-5-haiku-20241022", 12)
+
+	body := `{"error":{"type":"rate_limit_exceeded","message":"Too many streams"}}` + "\n"
+	w.Write([]byte(body))
+	if rr.Body.String() != body {
+		t.Errorf("body = %q, want %q", rr.Body.String(), body)
+	}
+}
+
+func TestHandler_AnthropicMessages_Stream(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &models.ChatCompletionRequest{Model: "claude-3-5-haiku-20241022", Stream: true, Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+		req.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+		h.handleStreamingResponse(newAnthropicStreamWriter(w, req.Model, 1), r, &usageProvider{}, req)
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	events := readAnthropicEvents(t, string(body))
+	want := "message_start,content_block_start,content_block_delta,content_block_stop,message_delta,message_stop"
+	if got := eventNames(events); got != want {
+		t.Fatalf("events = %s\nwant %s\nbody %s", got, want, body)
+	}
+	if strings.Contains(string(body), "[DONE]") || strings.Contains(string(body), "chat.completion") {
+		t.Errorf("OpenAI chunks leaked into the Anthropic stream: %s", body)
+	}
+	if usage := events[4].data["usage"].(map[string]interface{}); usage["output_tokens"] != 500.0 {
+		t.Errorf("message_delta usage = %v", usage)
+	}
+}
@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// attemptTimeline collects the provider attempts made for each API request, so
+// a failed request can report them
+func attemptTimeline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(observability.WithAttemptTimeline(r.Context())))
+	})
+}
+
+// fallbackHeader names the fallback that served a request, as "provider/model"
+const fallbackHeader = "X-Fallback-Model"
+
+// overflowHeader names the model that served a request over its model's
+// outbound quota, as "provider/model"
+const overflowHeader = "X-Overflow-Model"
+
+// setFallbackHeader reports the fallback or overflow model that served the
+// request, if any
+func setFallbackHeader(w http.ResponseWriter, r *http.Request) {
+	if fallback, ok := observability.FallbackUsed(r.Context()); ok {
+		header := fallbackHeader
+		if fallback.Overflow {
+			header = overflowHeader
+		}
+		w.Header().Set(header, fallback.Provider+"/"+fallback.Model)
+	}
+}
+
+// attemptMetadata summarises the provider attempts made for a request, or
+// returns nil if none were recorded
+func attemptMetadata(ctx context.Context) *models.ErrorMetadata {
+	attempts := observability.ProviderAttempts(ctx)
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	summaries := make([]models.AttemptSummary, len(attempts))
+	for i, a := range attempts {
+		summaries[i] = models.AttemptSummary{
+			Provider:          a.Provider,
+			Status:            a.Status,
+			DurationMs:        a.Duration.Milliseconds(),
+			ErrorClass:        a.ErrorClass,
+			UpstreamRequestID: a.UpstreamRequestID,
+		}
+	}
+	return &models.ErrorMetadata{Attempts: summaries}
+}
+
+// providerFailure converts a failed provider call into an API error with the attempt timeline
+func providerFailure(ctx context.Context, err error) (int, models.ErrorResponse) {
+	status := http.StatusInternalServerError
+	apiErr := models.APIError{Type: "provider_error", Message: err.Error()}
+
+	var providerErr *proxy.ProviderError
+	if errors.As(err, &providerErr) {
+		status = providerErr.StatusCode
+		apiErr = models.APIError{Type: providerErr.Code, Message: providerErr.Message}
+	}
+	apiErr.Metadata = attemptMetadata(ctx)
+	return status, models.ErrorResponse{Error: apiErr}
+}
+
+// writeProviderFailure writes the error response for a failed provider call
+func (h *Handler) writeProviderFailure(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := providerFailure(r.Context(), err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeSSEProviderFailure writes the error event for a stream that failed to start
+func (h *Handler) writeSSEProviderFailure(w http.ResponseWriter, r *http.Request, err error) {
+	_, resp := providerFailure(r.Context(), err)
+	errData, _ := json.Marshal(resp)
+	w.Write([]byte("data: " + string(errData) + "\n\n"))
+	w.Write([]byte("data: [DONE]\n\n"))
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// requestBundle is everything the gateway knows about one request, for
+// attaching to bug reports
+type requestBundle struct {
+	RequestID   string    `json:"request_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Record is the flight record: the request, its provider attempts and,
+	// with capture_bodies, the captured request, provider requests and stream
+	Record *observability.FlightRecord `json:"record"`
+	// Spans are the request's trace spans still held by the tracer
+	Spans []observability.SpanRecord `json:"spans"`
+	// Audit holds the audit events written while serving the request
+	Audit []observability.AuditLog `json:"audit"`
+	// Metrics is a snapshot of the gateway counters and of the state of the
+	// providers the request was sent to
+	Metrics map[string]interface{} `json:"metrics"`
+}
+
+// GetRequestBundle handles GET /admin/v1/requests/{id}/bundle: a request's
+// flight record, spans, audit events and a metrics excerpt, as JSON or, with
+// ?format=zip, as a zip archive of separate files. Request IDs containing a
+// slash must be escaped as %2F.
+func (h *AdminHandler) GetRequestBundle(w http.ResponseWriter, r *http.Request) {
+	recorder := observability.GetFlightRecorder()
+	if recorder == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Flight recorder is not enabled")
+		return
+	}
+	id, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Invalid request ID")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Invalid format: "+format+" (must be json or zip)")
+		return
+	}
+	record := recorder.Record(id)
+	if record == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Request "+id+" is not in the flight recorder")
+		return
+	}
+
+	bundle := h.requestBundle(record)
+	observability.LogAudit(r.Context(), "request_bundle.export", id, map[string]interface{}{
+		"format": format,
+		"actor":  middleware.GetUserID(r.Context()),
+	})
+	if format != "zip" {
+		writeJSON(w, http.StatusOK, bundle)
+		return
+	}
+	writeBundleZip(w, bundle)
+}
+
+// requestBundle collects what is known about a recorded request
+func (h *AdminHandler) requestBundle(record *observability.FlightRecord) requestBundle {
+	bundle := requestBundle{
+		RequestID:   record.RequestID,
+		GeneratedAt: time.Now().UTC(),
+		Record:      record,
+		Spans:       []observability.SpanRecord{},
+		Audit:       []observability.AuditLog{},
+		Metrics:     map[string]interface{}{"requests": observability.GetMetrics().GetStats()},
+	}
+	if record.TraceID != "" {
+		bundle.Spans = observability.GetTracer().SpansForTrace(record.TraceID)
+	}
+
+	for _, event := range observability.AuditRecords() {
+		// Dumps hold the whole flight recorder rather than this request
+		if event.Action == "flight_recorder.dump" {
+			continue
+		}
+		if (record.TraceID != "" && event.TraceID == record.TraceID) || event.Details["request_id"] == record.RequestID {
+			bundle.Audit = append(bundle.Audit, event)
+		}
+	}
+
+	if h.proxyRouter != nil {
+		stats := h.proxyRouter.GetReliabilityStats()
+		providers := map[string]interface{}{}
+		for _, attempt := range record.Attempts {
+			if s, ok := stats[attempt.Provider]; ok {
+				providers[attempt.Provider] = s
+			}
+		}
+		bundle.Metrics["providers"] = providers
+	}
+	return bundle
+}
+
+// writeBundleZip writes a bundle as a zip archive with one file per part
+func writeBundleZip(w http.ResponseWriter, bundle requestBundle) {
+	record := bundle.Record
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"bundle.json", map[string]interface{}{"request_id": bundle.RequestID, "generated_at": bundle.GeneratedAt}},
+		{"record.json", record},
+		{"provider_requests.json", record.ProviderRequests},
+		{"spans.json", bundle.Spans},
+		{"audit.json", bundle.Audit},
+		{"metrics.json", bundle.Metrics},
+	}
+
+	name := strings.NewReplacer("/", "_", `"`, "_", "\\", "_").Replace(bundle.RequestID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="request-`+name+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(w)
+	defer archive.Close()
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		enc.Encode(file.data)
+	}
+	// The raw request body and the stream as the client received it
+	if record.Request != nil {
+		if f, err := archive.Create("request_body"); err == nil {
+			f.Write([]byte(record.Request.Body))
+		}
+	}
+	if len(record.StreamChunks) > 0 {
+		if f, err := archive.Create("stream.sse"); err == nil {
+			f.Write([]byte(strings.Join(record.StreamChunks, "\n\n") + "\n\n"))
+		}
+	}
+}
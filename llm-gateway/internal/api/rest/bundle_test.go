@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+func TestAdminRequestBundle(t *testing.T) {
+	ah := NewAdminHandler(nil, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/admin/v1/requests/{id}/bundle", ah.GetRequestBundle)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	if observability.GetFlightRecorder() == nil {
+		if rr := get("/admin/v1/requests/req-1/bundle"); rr.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404 without a flight recorder", rr.Code)
+		}
+	}
+	recorder := observability.InitGlobalFlightRecorder(observability.FlightRecorderConfig{
+		Enabled: true, Size: 10, ErrorRateThreshold: 1, CaptureBodies: true,
+	})
+
+	// Serve a streamed request with an ID containing a slash, as chi generates them
+	api := chimiddleware.RequestID(recorder.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observability.RecordProviderAttempt(r.Context(), observability.ProviderAttempt{Provider: "openai", Attempt: 1})
+		observability.CaptureProviderRequest(r.Context(), "openai", "chat_completion_stream", map[string]interface{}{"model": "gpt-4o", "stream": true})
+		observability.CaptureStreamChunk(r.Context(), []byte(`data: {"choices":[]}`+"\n"))
+	})))
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","stream":true}`))
+	req.Header.Set("X-Request-Id", "host/abc-000001")
+	api.ServeHTTP(httptest.NewRecorder(), req)
+
+	if rr := get("/admin/v1/requests/unknown/bundle"); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown request: %d", rr.Code)
+	}
+	if rr := get("/admin/v1/requests/host%2Fabc-000001/bundle?format=tar"); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad format: %d", rr.Code)
+	}
+
+	rr := get("/admin/v1/requests/host%2Fabc-000001/bundle")
+	var bundle struct {
+		RequestID string `json:"request_id"`
+		Record    struct {
+			Request struct {
+				Body string `json:"body"`
+			} `json:"request"`
+			ProviderRequests []observability.CapturedProviderRequest `json:"provider_requests"`
+			Attempts         []observability.ProviderAttempt         `json:"attempts"`
+			StreamChunks     []string                                `json:"stream_chunks"`
+		} `json:"record"`
+		Metrics map[string]interface{} `json:"metrics"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&bundle); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("bundle: %d %v", rr.Code, err)
+	}
+	if bundle.RequestID != "host/abc-000001" || bundle.Record.Request.Body != `{"model":"gpt-4o","stream":true}` ||
+		len(bundle.Record.ProviderRequests) != 1 || len(bundle.Record.Attempts) != 1 || len(bundle.Record.StreamChunks) != 1 ||
+		bundle.Metrics["requests"] == nil {
+		t.Errorf("bundle = %+v", bundle)
+	}
+
+	rr = get("/admin/v1/requests/host%2Fabc-000001/bundle?format=zip")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Disposition") != `attachment; filename="request-host_abc-000001.zip"` {
+		t.Fatalf("zip: %d %v", rr.Code, rr.Header())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"bundle.json", "record.json", "provider_requests.json", "spans.json", "audit.json", "metrics.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("zip lacks %s", name)
+		}
+	}
+	if files["request_body"] != `{"model":"gpt-4o","stream":true}` || files["stream.sse"] != "data: {\"choices\":[]}\n\n" {
+		t.Errorf("request_body = %q, stream.sse = %q", files["request_body"], files["stream.sse"])
+	}
+}
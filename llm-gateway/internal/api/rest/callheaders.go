@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Call headers, sent with call_headers.enabled so callers can see routing
+// and cost decisions without the gateway's logs
+const (
+	llmProviderHeader         = "X-LLM-Provider"
+	llmModelHeader            = "X-LLM-Model"
+	llmLatencyHeader          = "X-LLM-Latency-Ms"
+	llmPromptTokensHeader     = "X-LLM-Tokens-Prompt"
+	llmCompletionTokensHeader = "X-LLM-Tokens-Completion"
+	llmCostHeader             = "X-LLM-Cost-USD"
+	cacheHeader               = "X-Cache"
+)
+
+// Cache outcomes reported in X-Cache
+const (
+	cacheHit  = "HIT"
+	cacheMiss = "MISS"
+)
+
+// callInfo describes how a call was served
+type callInfo struct {
+	// provider served the call; empty for cache hits
+	provider string
+	// model is the requested model; a fallback or overflow model that served
+	// the call is reported instead
+	model string
+	// latency is the time spent waiting on the provider, or the cache
+	latency          time.Duration
+	promptTokens     int
+	completionTokens int
+	// cache is cacheHit or cacheMiss for requests the response cache was
+	// consulted for, and empty otherwise
+	cache string
+}
+
+// setCallHeaders reports a call's provider, model, latency, tokens and cost.
+// Cache hits cost nothing.
+func (h *Handler) setCallHeaders(w http.ResponseWriter, r *http.Request, call callInfo) {
+	if !h.config.CallHeaders.Enabled {
+		return
+	}
+	model := h.setCallRouting(w, r, call)
+	header := w.Header()
+	header.Set(llmPromptTokensHeader, strconv.Itoa(call.promptTokens))
+	header.Set(llmCompletionTokensHeader, strconv.Itoa(call.completionTokens))
+
+	usd := 0.0
+	if call.cache != cacheHit {
+		usd = h.config.Pricing.Cost(model, int64(call.promptTokens), int64(call.completionTokens))
+	}
+	header.Set(llmCostHeader, formatUSD(usd))
+}
+
+// setStreamCallHeaders reports the provider, model and time to the start of
+// a stream, and announces its tokens and cost as trailers, which
+// setStreamCallTrailers sends once the stream ends
+func (h *Handler) setStreamCallHeaders(w http.ResponseWriter, r *http.Request, call callInfo) {
+	if !h.config.CallHeaders.Enabled {
+		return
+	}
+	h.setCallRouting(w, r, call)
+	w.Header().Add("Trailer", llmPromptTokensHeader+", "+llmCompletionTokensHeader+", "+llmCostHeader)
+}
+
+// setStreamCallTrailers sends the tokens and cost of a finished stream
+func (h *Handler) setStreamCallTrailers(w http.ResponseWriter, r *http.Request, req *models.ChatCompletionRequest, usage *streamUsage) {
+	if !h.config.CallHeaders.Enabled {
+		return
+	}
+	promptTokens, completionTokens, _ := usage.totals(req)
+	model := servedModel(r, req.Model)
+	header := w.Header()
+	header.Set(llmPromptTokensHeader, strconv.Itoa(promptTokens))
+	header.Set(llmCompletionTokensHeader, strconv.Itoa(completionTokens))
+	header.Set(llmCostHeader, formatUSD(h.config.Pricing.Cost(model, int64(promptTokens), int64(completionTokens))))
+}
+
+// setCallRouting sets the headers known before a response is read, and
+// returns the model that served the call
+func (h *Handler) setCallRouting(w http.ResponseWriter, r *http.Request, call callInfo) string {
+	header := w.Header()
+	model := servedModel(r, call.model)
+	if call.provider != "" {
+		header.Set(llmProviderHeader, call.provider)
+	}
+	header.Set(llmModelHeader, model)
+	header.Set(llmLatencyHeader, strconv.FormatInt(call.latency.Milliseconds(), 10))
+	if call.cache != "" {
+		header.Set(cacheHeader, call.cache)
+	}
+	return model
+}
+
+// servedModel returns the fallback or overflow model that served a request
+// for model, if one did
+func servedModel(r *http.Request, model string) string {
+	if fallback, ok := observability.FallbackUsed(r.Context()); ok {
+		return fallback.Model
+	}
+	return model
+}
+
+// formatUSD formats a cost to a millionth of a dollar
+func formatUSD(usd float64) string {
+	return strconv.FormatFloat(usd, 'f', 6, 64)
+}
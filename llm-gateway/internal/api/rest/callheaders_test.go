@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// usageProvider answers chat completions with fixed usage
+type usageProvider struct {
+	proxy.Provider
+}
+
+func (p *usageProvider) Name() string { return "openai" }
+
+func (p *usageProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	return &models.ChatCompletionResponse{
+		ID:    "chatcmpl-1",
+		Model: req.Model,
+		Usage: models.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500},
+	}, nil
+}
+
+func (p *usageProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(
+		`data: {"choices":[{"delta":{"content":"hi"}}]}` + "\n\n" +
+			`data: {"choices":[],"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}` + "\n\n" +
+			"data: [DONE]\n\n")), nil
+}
+
+func newCallHeadersHandler() *Handler {
+	return NewHandler(&config.Config{
+		CallHeaders: config.CallHeadersConfig{Enabled: true},
+		Pricing:     config.PricingConfig{"gpt-4o": {Prompt: 2.5, Completion: 10}},
+	}, nil)
+}
+
+func TestHandler_CallHeaders(t *testing.T) {
+	cache, err := performance.NewSemanticCache(performance.CacheConfig{Enabled: true, TTL: time.Hour, Backend: "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	performance.SetDefaultCache(cache)
+	defer performance.SetDefaultCache(nil)
+
+	h := newCallHeadersHandler()
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+
+	tests := []struct {
+		cache    string
+		provider string
+		cost     string
+	}{
+		{cacheMiss, "openai", "0.007500"},
+		{cacheHit, "", "0.000000"},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		h.handleSyncResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), &usageProvider{}, req)
+
+		header := rr.Header()
+		if header.Get(cacheHeader) != tt.cache || header.Get(llmProviderHeader) != tt.provider || header.Get(llmModelHeader) != "gpt-4o" {
+			t.Errorf("cache %s: routing headers = %v", tt.cache, header)
+		}
+		if header.Get(llmPromptTokensHeader) != "1000" || header.Get(llmCompletionTokensHeader) != "500" {
+			t.Errorf("cache %s: token headers = %v", tt.cache, header)
+		}
+		if got := header.Get(llmCostHeader); got != tt.cost {
+			t.Errorf("cache %s: %s = %s, want %s", tt.cache, llmCostHeader, got, tt.cost)
+		}
+		if header.Get(llmLatencyHeader) == "" {
+			t.Errorf("cache %s: no %s", tt.cache, llmLatencyHeader)
+		}
+	}
+}
+
+func TestHandler_CallHeaders_Disabled(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+
+	rr := httptest.NewRecorder()
+	h.handleSyncResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), &usageProvider{}, req)
+	for _, name := range []string{llmProviderHeader, llmModelHeader, llmCostHeader, cacheHeader} {
+		if rr.Header().Get(name) != "" {
+			t.Errorf("%s sent without call_headers.enabled", name)
+		}
+	}
+}
+
+func TestHandler_CallHeaders_StreamTrailers(t *testing.T) {
+	h := newCallHeadersHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &models.ChatCompletionRequest{Model: "gpt-4o", Stream: true, Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+		h.handleStreamingResponse(w, r, &usageProvider{}, req)
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get(llmProviderHeader) != "openai" || resp.Header.Get(llmModelHeader) != "gpt-4o" {
+		t.Errorf("stream headers = %v", resp.Header)
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.Trailer.Get(llmPromptTokensHeader) != "1000" || resp.Trailer.Get(llmCompletionTokensHeader) != "500" {
+		t.Errorf("stream trailers = %v", resp.Trailer)
+	}
+	if got := resp.Trailer.Get(llmCostHeader); got != "0.007500" {
+		t.Errorf("%s trailer = %s, want 0.007500", llmCostHeader, got)
+	}
+}
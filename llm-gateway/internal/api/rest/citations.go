@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// citationsEvent is the data of a "citations" SSE event
+type citationsEvent struct {
+	Index     int               `json:"index"`
+	Citations []models.Citation `json:"citations"`
+}
+
+// citationStream turns the citations found in a provider stream into
+// dedicated "citations" SSE events, so clients do not parse each provider's
+// format. Sources some providers repeat in every chunk are sent once.
+type citationStream struct {
+	seen map[citationKey]bool
+}
+
+type citationKey struct {
+	index int
+	models.Citation
+	start, end int
+}
+
+func newCitationStream() *citationStream {
+	return &citationStream{seen: make(map[citationKey]bool)}
+}
+
+// process returns the SSE events for the citations new in line, or nil
+func (s *citationStream) process(line []byte) []byte {
+	data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return nil
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("[DONE]")) {
+		return nil
+	}
+
+	found := providers.StreamCitations(data)
+	indexes := make([]int, 0, len(found))
+	for index := range found {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var out bytes.Buffer
+	for _, index := range indexes {
+		var fresh []models.Citation
+		for _, c := range found[index] {
+			key := citationKey{index: index, Citation: c, start: -1, end: -1}
+			key.StartIndex, key.EndIndex = nil, nil
+			if c.StartIndex != nil {
+				key.start = *c.StartIndex
+			}
+			if c.EndIndex != nil {
+				key.end = *c.EndIndex
+			}
+			if !s.seen[key] {
+				s.seen[key] = true
+				fresh = append(fresh, c)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		payload, err := json.Marshal(citationsEvent{Index: index, Citations: fresh})
+		if err != nil {
+			continue
+		}
+		out.WriteString("event: citations\ndata: ")
+		out.Write(payload)
+		out.WriteString("\n\n")
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return out.Bytes()
+}
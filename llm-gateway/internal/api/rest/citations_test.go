@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCitationStream(t *testing.T) {
+	s := newCitationStream()
+	chunk := `data: {"choices": [{"index": 0, "delta": {"content": "Hi"}}], "citations": ["https://a.example"]}` + "\n"
+
+	events := string(s.process([]byte(chunk)))
+	want := "event: citations\ndata: {\"index\":0,\"citations\":[{\"url\":\"https://a.example\"}]}\n\n"
+	if events != want {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+
+	// Sources repeated in later chunks are sent once
+	if events := s.process([]byte(chunk)); events != nil {
+		t.Errorf("repeated citation sent again: %q", events)
+	}
+	chunk = strings.Replace(chunk, `"https://a.example"`, `"https://a.example", "https://b.example"`, 1)
+	if events := string(s.process([]byte(chunk))); !strings.Contains(events, "b.example") || strings.Contains(events, "a.example") {
+		t.Errorf("events = %q, want only the new source", events)
+	}
+
+	for _, line := range []string{"data: [DONE]\n", "\n", `data: {"choices": [{"index": 0, "delta": {"content": "Hi"}}]}` + "\n"} {
+		if events := s.process([]byte(line)); events != nil {
+			t.Errorf("process(%q) = %q, want nil", line, events)
+		}
+	}
+}
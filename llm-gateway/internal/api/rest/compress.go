@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/sla"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// summarizePrompt instructs the summarization model
+const summarizePrompt = "You compress chat histories. Summarize the conversation below so an " +
+	"assistant can continue it without the original messages. Keep facts, names, numbers, " +
+	"decisions, open questions and instructions the user gave. Write in the conversation's " +
+	"language, as concise notes, without commentary."
+
+// summaryHeading introduces the summary in the conversation sent to the provider
+const summaryHeading = "Summary of the earlier conversation:\n"
+
+var errEmptySummary = errors.New("summarization model returned an empty summary")
+
+// summaryCache keeps recent summaries by conversation prefix, so the next turn
+// of a conversation only summarizes the messages that were added since
+type summaryCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]string
+	order   []string
+}
+
+func newSummaryCache(max int) *summaryCache {
+	return &summaryCache{max: max, entries: make(map[string]string)}
+}
+
+func (c *summaryCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.entries[key]
+	return summary, ok
+}
+
+func (c *summaryCache) put(key, summary string) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = summary
+	for len(c.order) > c.max {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// prefixKeys returns a key for every prefix of messages (keys[i] covers
+// messages[:i+1]), scoped to tenant so summaries are never shared across tenants
+func prefixKeys(tenant string, messages []models.ChatMessage) []string {
+	keys := make([]string, len(messages))
+	h := sha256.New()
+	h.Write([]byte(tenant))
+	for i, msg := range messages {
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Text()))
+		keys[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return keys
+}
+
+// conversationTokens estimates the prompt tokens of messages at ~4 bytes per token
+func conversationTokens(messages []models.ChatMessage) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += (len(msg.Text()) + 3) / 4
+	}
+	return tokens
+}
+
+// compressionSplit returns the range [start, end) of messages to summarize:
+// after the leading system messages, up to the latest keepRecent messages. The
+// kept part starts at a user message, so tool results stay with their calls
+// and Anthropic's alternation is preserved. ok is false if fewer than two
+// messages would be summarized.
+func compressionSplit(messages []models.ChatMessage, keepRecent int) (start, end int, ok bool) {
+	for start < len(messages) && messages[start].Role == "system" {
+		start++
+	}
+	end = len(messages) - keepRecent
+	for end > start && messages[end].Role != "user" {
+		end--
+	}
+	return start, end, end-start >= 2
+}
+
+// compressConversation replaces the older turns of a chat request whose
+// history exceeds conversation_compression.threshold with a summary written by
+// the summarization model. The summary tokens are attributed to the tenant
+// apart from the request's own. Failures leave the conversation as it is.
+func (h *Handler) compressConversation(r *http.Request, req *models.ChatCompletionRequest) {
+	ctx := r.Context()
+	cfg := h.config.ConversationCompression
+	if !cfg.Enabled || h.summaries == nil || conversationTokens(req.Messages) <= cfg.Threshold {
+		return
+	}
+	start, end, ok := compressionSplit(req.Messages, cfg.KeepRecent)
+	if !ok {
+		return
+	}
+	older := req.Messages[start:end]
+
+	// Extend the longest summary cached for a prefix of the older messages
+	keys := prefixKeys(middleware.TenantID(r), older)
+	previous, covered := "", 0
+	for i := len(keys) - 1; i >= 0; i-- {
+		if summary, ok := h.summaries.get(keys[i]); ok {
+			previous, covered = summary, i+1
+			break
+		}
+	}
+
+	summary := previous
+	if covered < len(older) {
+		var err error
+		summary, err = h.summarize(ctx, previous, older[covered:])
+		if err != nil {
+			logger.Warn().Err(err).Str("model", cfg.Model).Msg("Conversation summarization failed, sending full history")
+			return
+		}
+		h.summaries.put(keys[len(keys)-1], summary)
+	}
+
+	// Keep a single system message, as some providers only take one
+	messages := make([]models.ChatMessage, 0, start+1+len(req.Messages)-end)
+	messages = append(messages, req.Messages[:start]...)
+	if start > 0 {
+		messages[start-1].AppendText("\n\n" + summaryHeading + summary)
+	} else {
+		messages = append(messages, models.ChatMessage{Role: "system", Content: summaryHeading + summary})
+	}
+	messages = append(messages, req.Messages[end:]...)
+
+	logger.Debug().
+		Str("model", req.Model).
+		Int("summarized_messages", len(older)).
+		Int("cached_messages", covered).
+		Int("tokens_before", conversationTokens(req.Messages)).
+		Int("tokens_after", conversationTokens(messages)).
+		Msg("Compressed conversation")
+	if span := observability.SpanFromContext(ctx); span != nil {
+		span.SetAttribute("llm.request.summarized_messages", len(older))
+	}
+	req.Messages = messages
+}
+
+// summarize asks the summarization model for a summary of messages, extending
+// previous (a summary of the messages before them) if set
+func (h *Handler) summarize(ctx context.Context, previous string, messages []models.ChatMessage) (string, error) {
+	cfg := h.config.ConversationCompression
+	provider, err := h.proxyRouter.GetProviderForRequest(ctx, cfg.Model)
+	if err != nil {
+		return "", err
+	}
+
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString(summaryHeading + previous + "\n\nLater messages:\n\n")
+	}
+	for _, msg := range messages {
+		transcript.WriteString(msg.Role + ": " + msg.Text() + "\n\n")
+	}
+	temperature := 0.0
+	req := &models.ChatCompletionRequest{
+		Model: cfg.Model,
+		Messages: []models.ChatMessage{
+			{Role: "system", Content: summarizePrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+		Temperature: &temperature,
+		MaxTokens:   cfg.MaxSummaryTokens,
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	resp, err := provider.ChatCompletion(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	middleware.AddSummarizationUsage(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	sla.Default().RecordTokens(provider.Name(), cfg.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", errEmptySummary
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestCompressionSplit(t *testing.T) {
+	msgs := func(roles ...string) []models.ChatMessage {
+		messages := make([]models.ChatMessage, len(roles))
+		for i, role := range roles {
+			messages[i] = models.ChatMessage{Role: role}
+		}
+		return messages
+	}
+	tests := []struct {
+		name       string
+		messages   []models.ChatMessage
+		keepRecent int
+		start, end int
+		ok         bool
+	}{
+		{"keeps system and recent", msgs("system", "user", "assistant", "user", "assistant", "user"), 2, 1, 3, true},
+		{"kept part starts at a user message", msgs("user", "assistant", "user", "assistant", "tool", "assistant"), 2, 0, 2, true},
+		{"too few older messages", msgs("system", "user", "assistant", "user"), 2, 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := compressionSplit(tt.messages, tt.keepRecent)
+			if start != tt.start || end != tt.end || ok != tt.ok {
+				t.Errorf("got [%d, %d) %v, want [%d, %d) %v", start, end, ok, tt.start, tt.end, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHandler_compressConversation(t *testing.T) {
+	var summarized []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		summarized = append(summarized, req.Messages[1].Content)
+		json.NewEncoder(w).Encode(models.ChatCompletionResponse{
+			Model:   req.Model,
+			Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Role: "assistant", Content: "summary " + string(rune('0'+len(summarized)))}}},
+			Usage:   models.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+		})
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{ConversationCompression: config.ConversationCompressionConfig{
+		Enabled: true, Threshold: 50, KeepRecent: 2, Model: "gpt-4o-mini",
+		MaxSummaryTokens: 100, Timeout: 5 * time.Second, CacheEntries: 10,
+	}}
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: backend.URL}))
+	h := NewHandler(cfg, proxy.NewRouter(registry, cfg))
+
+	long := strings.Repeat("word ", 40)
+	conversation := []models.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "first " + long},
+		{Role: "assistant", Content: "reply one"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "reply two"},
+	}
+
+	usage := middleware.NewUsageTracker()
+	send := func(messages []models.ChatMessage) *models.ChatCompletionRequest {
+		req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: append([]models.ChatMessage(nil), messages...)}
+		usage.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.compressConversation(r, req)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/chat/completions", nil))
+		return req
+	}
+
+	req := send(append(conversation, models.ChatMessage{Role: "user", Content: "third"}))
+	if len(req.Messages) != 4 {
+		t.Fatalf("messages = %+v, want system and the last turn", req.Messages)
+	}
+	if req.Messages[0].Content != "Be brief.\n\n"+summaryHeading+"summary 1" {
+		t.Errorf("system = %q", req.Messages[0].Content)
+	}
+	if req.Messages[1].Content != "second" || req.Messages[3].Content != "third" {
+		t.Errorf("recent messages not kept: %+v", req.Messages[1:])
+	}
+
+	// The next turn extends the cached summary with the new messages only
+	conversation = append(conversation, models.ChatMessage{Role: "user", Content: "third"}, models.ChatMessage{Role: "assistant", Content: "reply three"})
+	send(append(conversation, models.ChatMessage{Role: "user", Content: "fourth"}))
+	if len(summarized) != 2 {
+		t.Fatalf("expected 2 summarization calls, got %d", len(summarized))
+	}
+	if !strings.HasPrefix(summarized[1], summaryHeading+"summary 1") || strings.Contains(summarized[1], "first") {
+		t.Errorf("second summarization should extend the first: %q", summarized[1])
+	}
+
+	// Short conversations are sent as they are
+	if req := send(conversation[:3]); len(req.Messages) != 3 {
+		t.Errorf("short conversation compressed: %+v", req.Messages)
+	}
+
+	_, rows := usage.Today()
+	if len(rows) != 1 || rows[0]["summarization_prompt_tokens"] != int64(200) || rows[0]["total_tokens"] != int64(0) {
+		t.Errorf("usage = %+v, want summarization tokens apart from the requests'", rows)
+	}
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/username/llm-gateway/internal/cost"
+	"github.com/username/llm-gateway/internal/proxy"
+)
+
+// admitSpend checks the spend budgets of a request for model on provider,
+// returning a 429 spend_limit_exceeded error once a reject budget is exhausted
+func admitSpend(w http.ResponseWriter, r *http.Request, provider, model string) error {
+	err := cost.Default().Admit(r.Context(), w.Header(), provider, model)
+	if err == nil {
+		return nil
+	}
+	var budgetErr *cost.BudgetError
+	if errors.As(err, &budgetErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(budgetErr.RetryAfter.Seconds()))))
+	}
+	return &proxy.ProviderError{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       "spend_limit_exceeded",
+		Message:    err.Error(),
+	}
+}
+
+// Usage handles GET /v1/usage: the caller's spend today and this month, by
+// provider and model, with the budgets applying to its key
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	engine := cost.Default()
+	if engine == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Cost tracking is not enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, engine.Usage(cost.KeyFromContext(r.Context())))
+}
+
+// spendRow is a row of the spend listing, with an ID for pagination
+type spendRow struct {
+	ID string `json:"id"`
+	cost.Row
+}
+
+// ListSpend handles GET /admin/v1/spend: spend per API key, provider and
+// model, filterable by any of them, e.g. ?provider=openai
+func (h *AdminHandler) ListSpend(w http.ResponseWriter, r *http.Request) {
+	engine := cost.Default()
+	if engine == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Cost tracking is not enabled")
+		return
+	}
+	rows := engine.Rows()
+	views := make([]spendRow, len(rows))
+	for i, row := range rows {
+		views[i] = spendRow{ID: row.Key + "/" + row.Provider + "/" + row.Model, Row: row}
+	}
+	writeList(w, r, views, "id", "id")
+}
+
+// ListSpendForecasts handles GET /admin/v1/spend/forecast: each tenant's
+// spend projected to the end of the day and month, highest first
+func (h *AdminHandler) ListSpendForecasts(w http.ResponseWriter, r *http.Request) {
+	engine := cost.Default()
+	if engine == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Cost tracking is not enabled")
+		return
+	}
+	writeList(w, r, engine.Forecasts(), "tenant", "-forecast_month")
+}
+
+// ListSpendAnomalies handles GET /admin/v1/spend/anomalies: recent hours in
+// which a tenant's spend jumped far above its baseline, newest first
+func (h *AdminHandler) ListSpendAnomalies(w http.ResponseWriter, r *http.Request) {
+	engine := cost.Default()
+	if engine == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Cost tracking is not enabled")
+		return
+	}
+	writeList(w, r, engine.Anomalies(), "id", "-detected_at")
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/cost"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/proxy"
+)
+
+func TestHandler_SpendBudget(t *testing.T) {
+	h := newOverrideHandler("http://localhost:9999")
+	engine := cost.New(config.CostConfig{Enabled: true, Budgets: []config.SpendBudgetConfig{
+		{Scope: "key", ID: "*", Limit: 1, Period: "day", Action: "reject"},
+	}}, config.PricingConfig{"*": {Prompt: 1, Completion: 1}})
+	cost.SetDefault(engine)
+	t.Cleanup(func() { cost.SetDefault(nil) })
+
+	handler := engine.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.selectProvider(w, r, "gpt-4o", ""); err != nil {
+			h.writeRoutingError(w, err)
+			return
+		}
+		h.recordUsage(r.Context(), "openai", "gpt-4o", 600_000, 600_000)
+		h.Usage(w, r)
+	}))
+
+	// The first request spends $1.20, exhausting the budget
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, overrideRequest("sk-client-key-123456", "", ""))
+	var usage cost.KeyUsage
+	if err := json.NewDecoder(rr.Body).Decode(&usage); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("first request: %d %v", rr.Code, err)
+	}
+	if usage.Key != "sk-client-ke***" || usage.Today.USD != 1.2 || len(usage.Budgets) != 1 || usage.Budgets[0].Remaining != 0 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, overrideRequest("sk-client-key-123456", "", ""))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("over budget: %d %s", rr.Code, rr.Body)
+	}
+
+	// Other keys have budgets of their own
+	var providerErr *proxy.ProviderError
+	handler = engine.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.selectProvider(w, r, "gpt-4o", ""); errors.As(err, &providerErr) {
+			t.Errorf("another key: err = %v", err)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), overrideRequest("sk-other-key-654321", "", ""))
+}
+
+func TestAdminHandler_SpendForecast(t *testing.T) {
+	ah := NewAdminHandler(nil, nil, nil)
+	rr := httptest.NewRecorder()
+	ah.ListSpendForecasts(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/spend/forecast", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("cost disabled: %d, want 404", rr.Code)
+	}
+
+	engine := cost.New(config.CostConfig{Enabled: true}, config.PricingConfig{"*": {Prompt: 1, Completion: 1}})
+	cost.SetDefault(engine)
+	t.Cleanup(func() { cost.SetDefault(nil) })
+	handler := engine.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		engine.Record(r.Context(), "openai", "gpt-4o", 1_000_000, 0)
+	}))
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		req := overrideRequest("", "", "")
+		req.Header.Set(middleware.TenantHeader, tenant)
+		req = req.WithContext(middleware.WithTrustedTenant(req.Context()))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr = httptest.NewRecorder()
+	ah.ListSpendForecasts(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/spend/forecast", nil))
+	var page struct {
+		Data []cost.Forecast `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("forecast: %d %v", rr.Code, err)
+	}
+	if len(page.Data) != 2 || page.Data[0].Tenant != "acme" || page.Data[0].Today != 2 || page.Data[1].Tenant != "globex" {
+		t.Errorf("forecasts = %+v", page.Data)
+	}
+}
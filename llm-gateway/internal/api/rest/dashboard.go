@@ -0,0 +1,85 @@
+package rest
+
+import (
+	_ "embed"
+	"net/http"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// dashboardPage is the single-page operator UI served at /admin/ui. It holds no
+// data itself; it asks for the admin key and polls /admin/v1/dashboard.
+//
+//go:embed ui/index.html
+var dashboardPage []byte
+
+// dashboardUIHandler serves the embedded dashboard page
+func dashboardUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Write(dashboardPage)
+}
+
+// GetDashboard handles GET /admin/v1/dashboard
+func (h *AdminHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	providers := h.providerHealth()
+
+	// Requests waiting on upstream providers
+	var queueDepth int64
+	for _, entry := range providers {
+		queueDepth += entry["in_flight"].(int64)
+	}
+
+	data := map[string]interface{}{
+		"generated_at": time.Now().UTC().Format(time.RFC3339),
+		"maintenance":  h.maintenance.Enabled(),
+		"providers":    providers,
+		"queue_depth":  queueDepth,
+		"requests":     observability.GetMetrics().GetStats(),
+	}
+
+	if h.usage != nil {
+		day, tenants := h.usage.Today()
+		data["usage"] = map[string]interface{}{
+			"day":     day,
+			"tenants": tenants,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, data)
+}
+
+// providerHealth combines drain, warm-up and circuit breaker state per provider
+func (h *AdminHandler) providerHealth() []map[string]interface{} {
+	pending := make(map[string]bool)
+	for _, name := range h.proxyRouter.PendingProviders() {
+		pending[name] = true
+	}
+	reliability := h.proxyRouter.GetReliabilityStats()
+
+	providers := h.proxyRouter.DrainStatus()
+	for _, entry := range providers {
+		name := entry["provider"].(string)
+		entry["warming_up"] = pending[name]
+		entry["circuit_state"] = "disabled"
+
+		if stats, ok := reliability[name].(map[string]interface{}); ok {
+			if breaker, ok := stats["circuit_breaker"].(map[string]interface{}); ok {
+				entry["circuit_state"] = breaker["state"]
+				entry["circuit_failures"] = breaker["failures"]
+			}
+		}
+
+		status := "healthy"
+		switch {
+		case entry["circuit_state"] == "open":
+			status = "unhealthy"
+		case entry["drained"] == true, pending[name], entry["circuit_state"] == "half-open":
+			status = "degraded"
+		}
+		entry["status"] = status
+	}
+	return providers
+}
@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// degradedHeader tells the caller a response came from degraded mode, and why
+const degradedHeader = "X-Degraded"
+
+// degradedMode returns the degraded mode requests for model are served in,
+// or "" when they go to providers
+func (h *Handler) degradedMode(model string) string {
+	if h.proxyRouter == nil {
+		return ""
+	}
+	return h.proxyRouter.DegradedMode(model)
+}
+
+// serveDegraded answers a chat request from the response cache in degraded
+// mode, stale entries included, reporting whether it wrote the response. A
+// request that misses the cache, or cannot be cached, gets a 503.
+func (h *Handler) serveDegraded(w http.ResponseWriter, r *http.Request, req *models.ChatCompletionRequest) bool {
+	mode := h.degradedMode(req.Model)
+	if mode == "" || !keys.FromContext(r.Context()).AllowsModel(req.Model) {
+		return false
+	}
+	w.Header().Set(degradedHeader, mode)
+	metrics := observability.GetMetrics()
+
+	cache := performance.DefaultCache()
+	if cache == nil || req.Stream || !performance.IsCacheable(req) {
+		metrics.RecordDegradedRequest(mode, "uncacheable")
+		h.writeDegradedUnavailable(w, "degraded_uncacheable", "The gateway is in degraded mode and only serves cacheable requests")
+		return true
+	}
+	start := time.Now()
+	resp, staleness, err := cache.GetStale(performance.WithCacheTenant(r.Context(), middleware.TenantID(r)), req)
+	if err != nil {
+		metrics.RecordCacheMiss(req.Model)
+		metrics.RecordDegradedRequest(mode, "cache_miss")
+		h.writeDegradedUnavailable(w, "degraded_cache_miss", "The gateway is in degraded mode and has no cached response for this request")
+		return true
+	}
+	metrics.RecordCacheHit(req.Model)
+	metrics.RecordDegradedRequest(mode, "stale_hit")
+	w.Header().Set("X-Cache-Staleness", strconv.Itoa(int(math.Ceil(staleness.Seconds()))))
+	h.setCallHeaders(w, r, callInfo{
+		model:            req.Model,
+		latency:          time.Since(start),
+		promptTokens:     resp.Usage.PromptTokens,
+		completionTokens: resp.Usage.CompletionTokens,
+		cache:            cacheHit,
+	})
+	h.writeChatResponse(w, r, resp)
+	return true
+}
+
+// rejectDegraded returns a 503 degraded_mode error for requests that cannot
+// be answered from the cache while model is in degraded mode
+func (h *Handler) rejectDegraded(w http.ResponseWriter, model string) error {
+	mode := h.degradedMode(model)
+	if mode == "" {
+		return nil
+	}
+	w.Header().Set(degradedHeader, mode)
+	h.setDegradedRetryAfter(w)
+	observability.GetMetrics().RecordDegradedRequest(mode, "uncacheable")
+	return &proxy.ProviderError{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "degraded_mode",
+		Message:    "The gateway is in degraded mode and only serves cached chat completions",
+	}
+}
+
+// writeDegradedUnavailable writes a 503 telling the caller when to retry
+func (h *Handler) writeDegradedUnavailable(w http.ResponseWriter, code, message string) {
+	h.setDegradedRetryAfter(w)
+	h.writeError(w, http.StatusServiceUnavailable, code, message)
+}
+
+func (h *Handler) setDegradedRetryAfter(w http.ResponseWriter) {
+	if retryAfter := h.config.Degraded.RetryAfter; retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+}
+
+// GetDegraded handles GET /admin/v1/degraded
+func (h *AdminHandler) GetDegraded(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.proxyRouter.DegradedStatus())
+}
+
+// SetDegraded handles PUT /admin/v1/degraded: {"enabled": true} answers chat
+// completions from the response cache only, until switched off
+func (h *AdminHandler) SetDegraded(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	h.proxyRouter.SetDegraded(req.Enabled)
+
+	observability.LogAudit(r.Context(), "degraded.set", "gateway", map[string]interface{}{
+		"enabled": req.Enabled,
+		"actor":   middleware.GetUserID(r.Context()),
+	})
+
+	writeJSON(w, http.StatusOK, h.proxyRouter.DegradedStatus())
+}
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestHandler_ChatCompletions_Degraded(t *testing.T) {
+	cache, err := performance.NewSemanticCache(performance.CacheConfig{Enabled: true, TTL: time.Hour, Backend: "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	performance.SetDefaultCache(cache)
+	defer performance.SetDefaultCache(nil)
+
+	cfg := &config.Config{}
+	cfg.Degraded.RetryAfter = 45 * time.Second
+	router := proxy.NewRouter(providers.NewRegistry(), cfg)
+	router.SetDegraded(true)
+	h := NewHandler(cfg, router)
+
+	cached := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+	// Cached for unauthenticated callers, the tenant of the requests below
+	anonymous := performance.WithCacheTenant(context.Background(), "anonymous")
+	if err := cache.Set(anonymous, cached, &models.ChatCompletionResponse{ID: "chatcmpl-cached"}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: content}}})
+		rr := httptest.NewRecorder()
+		h.ChatCompletions(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := post("hi")
+	if rr.Code != http.StatusOK || !bytes.Contains(rr.Body.Bytes(), []byte("chatcmpl-cached")) {
+		t.Fatalf("cached request: %d %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("X-Degraded"); got != proxy.DegradedManual {
+		t.Errorf("X-Degraded = %q, want %q", got, proxy.DegradedManual)
+	}
+	if got := rr.Header().Get("X-Cache-Staleness"); got != "0" {
+		t.Errorf("X-Cache-Staleness = %q, want 0", got)
+	}
+
+	rr = post("not cached")
+	if rr.Code != http.StatusServiceUnavailable || !bytes.Contains(rr.Body.Bytes(), []byte("degraded_cache_miss")) {
+		t.Fatalf("uncached request: %d %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Retry-After"); got != "45" {
+		t.Errorf("Retry-After = %q, want 45", got)
+	}
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/compat"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+// ignoredFieldsHeader lists the request fields that had no effect
+const ignoredFieldsHeader = "X-Ignored-Fields"
+
+// knownFieldsByType caches the JSON field names of request types
+var knownFieldsByType sync.Map
+
+// knownFields returns the lowercased JSON names of t's fields, including
+// those of embedded structs
+func knownFields(t reflect.Type) map[string]bool {
+	if cached, ok := knownFieldsByType.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for embedded := range knownFields(f.Type) {
+				fields[embedded] = true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = true
+	}
+	knownFieldsByType.Store(t, fields)
+	return fields
+}
+
+// decodeRequest decodes the JSON request body into v, a pointer to a request
+// struct. When unknown field tracking is enabled, it also returns the
+// top-level fields of the body that v does not have, which would otherwise
+// be dropped without notice.
+func decodeRequest(r *http.Request, v interface{}) ([]string, error) {
+	if compat.Default() == nil {
+		return nil, json.NewDecoder(r.Body).Decode(v)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&raw); err != nil {
+		return nil, nil
+	}
+
+	// Field names match case-insensitively, as they do when decoding
+	known := knownFields(reflect.TypeOf(v).Elem())
+	var unknown []string
+	for name := range raw {
+		if !known[strings.ToLower(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// checkIgnoredFields records the unknown fields of a request and the fields
+// provider does not support. In strict mode the request is rejected with 400
+// unsupported_field, as the request limits reject unknown fields before
+// decoding; otherwise it proceeds and the fields are listed in the
+// X-Ignored-Fields header. It reports whether the request may proceed.
+func (h *Handler) checkIgnoredFields(w http.ResponseWriter, r *http.Request, provider string, unknown, unsupported []string) bool {
+	tracker := compat.Default()
+	if tracker == nil || len(unknown)+len(unsupported) == 0 {
+		return true
+	}
+	client := middleware.TenantID(r)
+	tracker.Observe(client, r.URL.Path, "", compat.KindUnknown, unknown)
+	tracker.Observe(client, r.URL.Path, provider, compat.KindUnsupported, unsupported)
+
+	if tracker.Strict() {
+		logger.Info().
+			Str("request_id", chimiddleware.GetReqID(r.Context())).
+			Str("client", client).
+			Strs("unknown", unknown).
+			Strs("unsupported", unsupported).
+			Msg("Request rejected for ignored fields")
+
+		var problems, fields []string
+		if len(unknown) > 0 {
+			problems = append(problems, "unknown fields: "+strings.Join(unknown, ", "))
+			fields = append(fields, unknown...)
+		}
+		if len(unsupported) > 0 {
+			problems = append(problems, "fields not supported by "+provider+": "+strings.Join(unsupported, ", "))
+			fields = append(fields, unsupported...)
+		}
+		(&requestLimitError{http.StatusBadRequest, unsupportedFieldCode, fields[0],
+			"Request has fields that would be ignored (" + strings.Join(problems, "; ") + ")"}).write(w)
+		return false
+	}
+	w.Header().Set(ignoredFieldsHeader, strings.Join(append(append([]string{}, unknown...), unsupported...), ", "))
+	return true
+}
+
+// GetIgnoredFields handles GET /admin/v1/ignored-fields: the unknown and
+// unsupported request fields seen per client, most frequent first
+func (h *AdminHandler) GetIgnoredFields(w http.ResponseWriter, r *http.Request) {
+	tracker := compat.Default()
+	if tracker == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Unknown field tracking is not enabled")
+		return
+	}
+	fields, untracked := tracker.Report()
+	page, err := paginate(r, fields, "id", "-requests")
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*listPage
+		Strict    bool  `json:"strict"`
+		Untracked int64 `json:"untracked"`
+	}{page, tracker.Strict(), untracked})
+}
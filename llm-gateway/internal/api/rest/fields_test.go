@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/compat"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func enableFieldTracking(t *testing.T, strict bool) *compat.Tracker {
+	t.Helper()
+	tracker := compat.New(config.UnknownFieldsConfig{Enabled: true, Strict: strict, MaxEntries: 100})
+	compat.SetDefault(tracker)
+	t.Cleanup(func() { compat.SetDefault(nil) })
+	return tracker
+}
+
+func TestDecodeRequest_UnknownFields(t *testing.T) {
+	body := `{"model": "gpt-4o", "Messages": [{"role": "user", "content": "hi"}], "store": true, "metadata": {"a": "b"}}`
+
+	// Without tracking, fields are dropped silently
+	var req models.ChatCompletionRequest
+	unknown, err := decodeRequest(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)), &req)
+	if err != nil || unknown != nil {
+		t.Fatalf("decodeRequest() = %v, %v", unknown, err)
+	}
+
+	enableFieldTracking(t, false)
+	req = models.ChatCompletionRequest{}
+	unknown, err = decodeRequest(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Field names match case-insensitively, like the decoder
+	if len(unknown) != 2 || unknown[0] != "metadata" || unknown[1] != "store" {
+		t.Errorf("unknown = %v, want [metadata store]", unknown)
+	}
+	if req.Model != "gpt-4o" || len(req.Messages) != 1 {
+		t.Errorf("request = %+v", req)
+	}
+
+	if _, err := decodeRequest(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"model":`)), &req); err == nil {
+		t.Error("invalid JSON should fail")
+	}
+}
+
+func TestCheckIgnoredFields(t *testing.T) {
+	tracker := enableFieldTracking(t, false)
+	h := NewHandler(&config.Config{}, nil)
+
+	req := &models.ChatCompletionRequest{Model: "claude-3-5-haiku-20241022", LogitBias: map[string]int{"50256": -100}, Temperature: new(float64)}
+	unsupported := providers.UnsupportedChatFields("anthropic", req)
+	if len(unsupported) != 1 || unsupported[0] != "logit_bias" {
+		t.Fatalf("unsupported = %v, want [logit_bias]", unsupported)
+	}
+	if fields := providers.UnsupportedChatFields("openai", req); fields != nil {
+		t.Errorf("openai drops %v", fields)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("X-Tenant-ID", "team-a")
+	r = r.WithContext(middleware.WithTrustedTenant(r.Context()))
+	rr := httptest.NewRecorder()
+	if !h.checkIgnoredFields(rr, r, "anthropic", []string{"store"}, unsupported) {
+		t.Fatal("request should proceed outside strict mode")
+	}
+	if got := rr.Header().Get(ignoredFieldsHeader); got != "store, logit_bias" {
+		t.Errorf("%s = %q", ignoredFieldsHeader, got)
+	}
+	if fields, _ := tracker.Report(); len(fields) != 2 || fields[0].Client != "team-a" || fields[0].Field != "logit_bias" {
+		t.Errorf("report = %+v", fields)
+	}
+
+	enableFieldTracking(t, true)
+	rr = httptest.NewRecorder()
+	if h.checkIgnoredFields(rr, r, "anthropic", nil, unsupported) {
+		t.Fatal("strict mode should reject the request")
+	}
+	var resp models.ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusBadRequest || resp.Error.Code != "unsupported_field" || resp.Error.Param != "logit_bias" || !strings.Contains(resp.Error.Message, "not supported by anthropic: logit_bias") {
+		t.Errorf("response = %d %+v", rr.Code, resp)
+	}
+}
+
+func TestGetIgnoredFields(t *testing.T) {
+	ah := NewAdminHandler(nil, nil, nil)
+	rr := httptest.NewRecorder()
+	ah.GetIgnoredFields(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/ignored-fields", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 when disabled", rr.Code)
+	}
+
+	tracker := enableFieldTracking(t, false)
+	tracker.Observe("team-a", "/v1/chat/completions", "", compat.KindUnknown, []string{"store"})
+	tracker.Observe("team-b", "/v1/chat/completions", "ollama", compat.KindUnsupported, []string{"seed"})
+
+	rr = httptest.NewRecorder()
+	ah.GetIgnoredFields(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/ignored-fields?kind=unsupported", nil))
+	var page struct {
+		Data  []compat.Field `json:"data"`
+		Total int            `json:"total"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Data[0].Field != "seed" || page.Data[0].Provider != "ollama" {
+		t.Errorf("page = %+v", page)
+	}
+}
@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/guardrails"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// guardrailPolicy returns the caller's guardrail policy, or nil if none applies
+func (h *Handler) guardrailPolicy(r *http.Request) *guardrails.Policy {
+	return h.guardrails.PolicyFor(r.Context(), middleware.RequestAPIKey(r))
+}
+
+// checkGuardrailInput applies the caller's guardrail policy to a request's
+// prompt texts, redacting them in place. It returns false if the request was
+// blocked and an error response written.
+func (h *Handler) checkGuardrailInput(w http.ResponseWriter, r *http.Request, texts ...*string) bool {
+	v := h.guardrailPolicy(r).CheckInput(r.Context(), texts)
+	if v == nil {
+		return true
+	}
+	logGuardrailViolation(r, v)
+	h.writeError(w, http.StatusBadRequest, "guardrail_blocked", v.Error())
+	return false
+}
+
+// checkGuardrailOutput applies the caller's guardrail policy to the texts of
+// a complete response, redacting them in place. It returns false if the
+// response was blocked and an error response written.
+func (h *Handler) checkGuardrailOutput(w http.ResponseWriter, r *http.Request, texts ...*string) bool {
+	policy := h.guardrailPolicy(r)
+	if !policy.ChecksOutput() {
+		return true
+	}
+	v := policy.CheckOutput(r.Context(), texts)
+	if v == nil {
+		return true
+	}
+	logGuardrailViolation(r, v)
+	h.writeError(w, http.StatusBadGateway, "guardrail_blocked", v.Error())
+	return false
+}
+
+// logGuardrailViolation logs and audits a blocked prompt or response, which
+// counts as a refusal for abuse detection
+func logGuardrailViolation(r *http.Request, v *guardrails.Violation) {
+	abuse.ObserveRefusal(r.Context())
+	requestID := chimiddleware.GetReqID(r.Context())
+	logger.Warn().
+		Str("request_id", requestID).
+		Str("path", r.URL.Path).
+		Str("policy", v.Policy).
+		Str("check", v.Check).
+		Str("direction", v.Direction).
+		Str("rule", v.Rule).
+		Msg("Blocked by guardrail")
+	observability.LogAudit(r.Context(), "guardrail.blocked", v.Direction, map[string]interface{}{
+		"request_id": requestID,
+		"client_id":  middleware.MaskAPIKey(middleware.RequestAPIKey(r)),
+		"path":       r.URL.Path,
+		"policy":     v.Policy,
+		"check":      v.Check,
+		"rule":       v.Rule,
+	})
+}
+
+// messageTexts returns pointers to the text of chat messages, including the
+// text parts of multimodal messages
+func messageTexts(messages []models.ChatMessage) []*string {
+	texts := make([]*string, 0, len(messages))
+	for i := range messages {
+		texts = append(texts, messages[i].TextFields()...)
+	}
+	return texts
+}
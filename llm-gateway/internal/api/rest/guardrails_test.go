@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func newGuardrailsHandler() *Handler {
+	cfg := &config.Config{}
+	cfg.Guardrails = config.GuardrailsConfig{Enabled: true, Policies: map[string]config.GuardrailPolicy{
+		"support": {
+			Keys: []string{"sk-support-key"},
+			Denylist: []config.GuardrailRule{
+				{Name: "codenames", Keywords: []string{"bluebird"}, Action: "block"},
+				{Name: "hosts", Pattern: `[a-z0-9-]+\.internal\.example`, Action: "redact", Apply: "output"},
+			},
+		},
+	}}
+	return NewHandler(cfg, nil)
+}
+
+func TestChatCompletions_GuardrailBlocksPrompt(t *testing.T) {
+	h := newGuardrailsHandler()
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"status of bluebird?"}]}`
+
+	// Callers without a policy are not checked
+	prompt := "status of bluebird?"
+	if !h.checkGuardrailInput(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), &prompt) {
+		t.Error("caller without a policy was blocked")
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer sk-support-key")
+	rr := httptest.NewRecorder()
+	h.ChatCompletions(rr, r)
+	var resp models.ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if rr.Code != http.StatusBadRequest || resp.Error.Type != "guardrail_blocked" || !strings.Contains(resp.Error.Message, "codenames") {
+		t.Errorf("blocked: %d %+v", rr.Code, resp.Error)
+	}
+}
+
+func TestWriteChatResponse_GuardrailRedactsOutput(t *testing.T) {
+	h := newGuardrailsHandler()
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer sk-support-key")
+	resp := &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{
+		{Message: models.ChatMessage{Role: "assistant", Content: "Try db-1.internal.example"}},
+	}}
+	rr := httptest.NewRecorder()
+	h.writeChatResponse(rr, r, resp)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "internal.example") || !strings.Contains(rr.Body.String(), "Try [REDACTED]") {
+		t.Errorf("response = %d %s", rr.Code, rr.Body)
+	}
+}
@@ -2,16 +2,25 @@ package rest
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"time"
 
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/rs/zerolog/log"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/analytics"
 	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/guardrails"
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/performance"
 	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
@@ -19,48 +28,129 @@ import (
 type Handler struct {
 	config      *config.Config
 	proxyRouter *proxy.Router
+	// summaries caches conversation summaries (nil unless conversation compression is enabled)
+	summaries *summaryCache
+	// outputFilter masks or blocks banned patterns in model output (nil unless enabled)
+	outputFilter *outputFilter
+	// secrets detects credentials in prompts (nil unless enabled)
+	secrets *secretScanner
+	// transcripts keeps the assembled message of streams (nil unless enabled)
+	transcripts *transcriptStore
+	// guardrails checks prompts and completions by the caller's policy (nil unless enabled)
+	guardrails *guardrails.Engine
+	// pii masks personal data in chat prompts (nil unless enabled)
+	pii *piiMasker
 }
 
 // NewHandler creates a new Handler with dependencies
 func NewHandler(cfg *config.Config, proxyRouter *proxy.Router) *Handler {
-	return &Handler{
+	h := &Handler{
 		config:      cfg,
 		proxyRouter: proxyRouter,
 	}
+	if cfg != nil && cfg.ConversationCompression.Enabled {
+		h.summaries = newSummaryCache(cfg.ConversationCompression.CacheEntries)
+	}
+	if cfg != nil && cfg.OutputFilters.Enabled {
+		filter, err := newOutputFilter(cfg.OutputFilters)
+		if err != nil {
+			logger.Error().Err(err).Msg("Invalid output filter rules, output filtering disabled")
+		} else {
+			h.outputFilter = filter
+		}
+	}
+	if cfg != nil && cfg.Transcripts.Enabled {
+		h.transcripts = newTranscriptStore(cfg.Transcripts)
+	}
+	if cfg != nil && cfg.PromptSecrets.Enabled {
+		scanner, err := newSecretScanner(cfg.PromptSecrets)
+		if err != nil {
+			logger.Error().Err(err).Msg("Invalid prompt secret detectors, prompt scanning disabled")
+		} else {
+			h.secrets = scanner
+		}
+	}
+	if cfg != nil && cfg.Guardrails.Enabled {
+		engine, err := guardrails.New(cfg.Guardrails)
+		if err != nil {
+			logger.Error().Err(err).Msg("Invalid guardrail policies, guardrails disabled")
+		} else {
+			h.guardrails = engine
+		}
+	}
+	if cfg != nil && cfg.PII.Enabled {
+		h.pii = newPIIMasker(cfg.PII)
+	}
+	return h
 }
 
 // ChatCompletions handles POST /v1/chat/completions (OpenAI-compatible)
 func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	requestID := middleware.GetReqID(ctx)
+	requestID := chimiddleware.GetReqID(ctx)
 
 	// Parse request body
 	var req models.ChatCompletionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	unknown, err := decodeRequest(r, &req)
+	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
 		return
 	}
+	middleware.SetEndUser(ctx, req.User)
+	req.Model = h.resolveAlias(req.Model)
+	abuse.ObserveModel(ctx, req.Model)
 
 	// Validate request
 	if err := req.Validate(); err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
+	if req.Assemble && req.Stream && h.transcripts == nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "assemble is not enabled on this gateway")
+		return
+	}
+	r, ok := h.applyTranslateTo(w, r, &req)
+	if !ok {
+		return
+	}
+	r = h.lintChatRequest(w, r, req.Messages)
+	if !h.checkPromptSecrets(w, r, func(scan *promptScan) { scan.messages(req.Messages) }) {
+		return
+	}
+	r = h.maskPII(r, messageTexts(req.Messages)...)
+	if !h.checkGuardrailInput(w, r, messageTexts(req.Messages)...) {
+		return
+	}
+	if !h.applyChatStyle(w, r, &req) || !h.applyChatPreset(w, r, &req) {
+		return
+	}
+	req.Model = h.applyLanguage(w, r, promptText(req.Messages), req.Model)
+	h.applyUseCase(r, chatUseCaseRequest(&req))
+	observePrompt(r, req.Model, req.User, req.Messages)
 
-	log.Debug().
+	logger.Debug().
 		Str("request_id", requestID).
 		Str("model", req.Model).
 		Bool("stream", req.Stream).
 		Int("messages", len(req.Messages)).
 		Msg("Processing chat completion request")
 
+	if h.serveDegraded(w, r, &req) {
+		return
+	}
+
 	// Determine provider from model name
-	provider, err := h.proxyRouter.GetProviderForModel(req.Model)
+	provider, err := h.selectProvider(w, r, req.Model, "")
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_model", err.Error())
+		h.writeRoutingError(w, err)
+		return
+	}
+	if !h.checkIgnoredFields(w, r, provider.Name(), unknown, providers.UnsupportedChatFields(provider.Name(), &req)) {
 		return
 	}
 
+	h.compressConversation(r, &req)
+
 	// Handle streaming vs non-streaming
 	if req.Stream {
 		h.handleStreamingResponse(w, r, provider, &req)
@@ -71,16 +161,86 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 
 // handleSyncResponse handles non-streaming chat completion
 func (h *Handler) handleSyncResponse(w http.ResponseWriter, r *http.Request, provider proxy.Provider, req *models.ChatCompletionRequest) {
-	ctx := r.Context()
+	ctx := performance.WithCacheTenant(r.Context(), middleware.TenantID(r))
+
+	cache := performance.DefaultCache()
+	cacheable := cache != nil && performance.IsCacheable(req)
+	cacheStatus := ""
+	if cacheable {
+		start := time.Now()
+		if cached, err := cache.Get(ctx, req); err == nil {
+			observability.GetMetrics().RecordCacheHit(req.Model)
+			h.setCallHeaders(w, r, callInfo{
+				model:            req.Model,
+				latency:          time.Since(start),
+				promptTokens:     cached.Usage.PromptTokens,
+				completionTokens: cached.Usage.CompletionTokens,
+				cache:            cacheHit,
+			})
+			h.writeChatResponse(w, r, cached)
+			return
+		}
+		observability.GetMetrics().RecordCacheMiss(req.Model)
+		cacheStatus = cacheMiss
+	}
 
+	start := time.Now()
 	resp, err := provider.ChatCompletion(ctx, req)
 	if err != nil {
-		var providerErr *proxy.ProviderError
-		if errors.As(err, &providerErr) {
-			h.writeError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
+		h.writeProviderFailure(w, r, err)
+		return
+	}
+	setFallbackHeader(w, r)
+	h.setCallHeaders(w, r, callInfo{
+		provider:         provider.Name(),
+		model:            req.Model,
+		latency:          time.Since(start),
+		promptTokens:     resp.Usage.PromptTokens,
+		completionTokens: resp.Usage.CompletionTokens,
+		cache:            cacheStatus,
+	})
+	h.recordUsage(ctx, provider.Name(), req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	resp = h.enforceResponseLanguage(w, r, provider, req, resp)
+
+	observeContentFilter(r, chatFinishReasons(resp)...)
+
+	if h.outputFilter != nil {
+		if rule := h.outputFilter.filterChatResponse(resp); rule != "" {
+			h.writeOutputFiltered(w, r, rule)
 			return
 		}
-		h.writeError(w, http.StatusInternalServerError, "provider_error", err.Error())
+	}
+
+	// Cache the filtered response; response limits depend on the caller and are applied on every hit
+	if cacheable {
+		if err := cache.Set(ctx, req, resp); err != nil {
+			logger.Warn().Err(err).Str("model", req.Model).Msg("Failed to cache response")
+		}
+	}
+	h.writeChatResponse(w, r, resp)
+}
+
+// writeChatResponse writes a complete chat response within the caller's response limits
+func (h *Handler) writeChatResponse(w http.ResponseWriter, r *http.Request, resp *models.ChatCompletionResponse) {
+	resp.PromptWarnings = promptWarningsFrom(r.Context())
+	if !h.translateChatResponse(w, r, resp) {
+		return
+	}
+	texts := make([]*string, 0, len(resp.Choices))
+	for i := range resp.Choices {
+		texts = append(texts, &resp.Choices[i].Message.Content)
+		if translation := resp.Choices[i].Translation; translation != nil {
+			texts = append(texts, &translation.Content)
+		}
+	}
+	if !h.checkGuardrailOutput(w, r, texts...) {
+		return
+	}
+	if vault := piiVaultFrom(r.Context()); vault != nil {
+		vault.restoreChatResponse(resp)
+	}
+	if limit, ok := h.responseLimit(r); ok && !limit.limitChatResponse(resp) {
+		h.writeResponseTooLarge(w, r)
 		return
 	}
 
@@ -93,25 +253,62 @@ func (h *Handler) handleSyncResponse(w http.ResponseWriter, r *http.Request, pro
 func (h *Handler) handleStreamingResponse(w http.ResponseWriter, r *http.Request, provider proxy.Provider, req *models.ChatCompletionRequest) {
 	ctx := r.Context()
 
+	// Cap simultaneous streams per API key; the 429 is written by AcquireStream
+	release, ok := middleware.AcquireStream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Hold the stream's estimated tokens against its key's token quotas; the
+	// tokens it uses are counted once it ends
+	promptTokens := conversationTokens(req.Messages)
+	reservation := keys.Default().Reserve(keys.FromContext(ctx), int64(promptTokens), int64(req.MaxTokens))
+	defer reservation.Release()
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	if r.ProtoMajor == 1 {
+		// Connection-specific headers are forbidden in HTTP/2
+		w.Header().Set("Connection", "keep-alive")
+	}
 	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
 
+	// Keep the assembled message for clients that lose part of the stream,
+	// reading the stream to its end even if the client disconnects
+	var record *transcript
+	streamCtx := ctx
+	if req.Assemble && h.transcripts != nil {
+		requestID := chimiddleware.GetReqID(ctx)
+		record = h.transcripts.start(requestID, middleware.TenantID(r), req.User)
+		w.Header().Set("X-Request-ID", requestID)
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), h.config.Transcripts.MaxDuration)
+		defer cancel()
+	}
+	completed := false
+	defer func() { record.finish(completed, h.config.Transcripts.TTL) }()
+
+	// Ask the provider to report usage, forwarding it only if the client asked too
+	usage := &streamUsage{forward: req.StreamOptions != nil && req.StreamOptions.IncludeUsage}
+	streamReq := *req
+	streamReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	streamReq.Assemble = false
+
 	// Get streaming response from provider
-	stream, err := provider.ChatCompletionStream(ctx, req)
+	start := time.Now()
+	stream, err := provider.ChatCompletionStream(streamCtx, &streamReq)
 	if err != nil {
-		var providerErr *proxy.ProviderError
-		if errors.As(err, &providerErr) {
-			// For streaming, we need to send error as SSE event
-			h.writeSSEError(w, providerErr.Code, providerErr.Message)
-			return
-		}
-		h.writeSSEError(w, "provider_error", err.Error())
+		// For streaming, we need to send error as SSE event
+		h.writeSSEProviderFailure(w, r, err)
 		return
 	}
 	defer stream.Close()
+	defer h.recordStreamUsage(r, provider.Name(), req, usage)
+	setFallbackHeader(w, r)
+	h.setStreamCallHeaders(w, r, callInfo{provider: provider.Name(), model: req.Model, latency: time.Since(start)})
+	defer h.setStreamCallTrailers(w, r, req, usage)
 
 	// Flush writer for SSE
 	flusher, ok := w.(http.Flusher)
@@ -120,27 +317,144 @@ func (h *Handler) handleStreamingResponse(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Enforce the route's response size limit while forwarding
+	var limiter *streamLimiter
+	if limit, ok := h.responseLimit(r); ok {
+		limiter = &streamLimiter{limit: limit}
+	}
+
+	// Mask or block banned patterns and restore masked personal data,
+	// including matches spanning chunks
+	var filter *filterStream
+	if vault := piiVaultFrom(ctx); vault != nil {
+		filter = newFilterStream(vault.streamFilter(h.outputFilter))
+	} else if h.outputFilter != nil {
+		filter = newFilterStream(h.outputFilter)
+	}
+
+	// Watch for the model getting stuck repeating itself
+	var loops *loopDetector
+	if h.config.LoopDetection.Enabled {
+		loops = newLoopDetector(h.config.LoopDetection)
+	}
+
+	// Send tool calls whole for clients that cannot merge argument deltas
+	var toolCalls *toolCallAssembler
+	if mode := h.toolCallAssemblyMode(r); mode != assembleOff {
+		toolCalls = newToolCallAssembler(mode)
+	}
+
+	// Send citations as dedicated events, whatever the provider's format
+	var citations *citationStream
+	if h.config.Citations.StreamEvents {
+		citations = newCitationStream()
+	}
+
 	// Read and forward stream
 	reader := bufio.NewReader(stream)
+	done := ctx.Done()
 	for {
 		select {
-		case <-ctx.Done():
-			return
+		case <-done:
+			if record == nil {
+				return
+			}
+			// Finish assembling the message for the client to fetch later
+			logger.Debug().
+				Str("request_id", chimiddleware.GetReqID(ctx)).
+				Msg("Client disconnected, reading the rest of the stream for its transcript")
+			done = nil
 		default:
 			line, err := reader.ReadBytes('\n')
 			if err != nil {
 				if err == io.EOF {
+					completed = true
+					if toolCalls != nil {
+						w.Write(toolCalls.flush())
+					}
+					if filter != nil {
+						w.Write(filter.flush())
+					}
 					// Send final [DONE] message if not already sent
 					w.Write([]byte("data: [DONE]\n\n"))
 					flusher.Flush()
 					return
 				}
-				log.Error().Err(err).Msg("Error reading stream")
+				logger.Error().Err(err).Msg("Error reading stream")
+				return
+			}
+
+			if usage.process(line) {
+				// The transcript keeps the usage the client did not ask for
+				record.process(line)
+				continue
+			}
+
+			if reservation.Exceeded(int64(usage.tokensSoFar(promptTokens))) {
+				logger.Warn().
+					Str("request_id", chimiddleware.GetReqID(ctx)).
+					Str("key_id", middleware.GetKeyID(ctx)).
+					Int64("reserved_tokens", reservation.Tokens()).
+					Msg("Stream exceeded its token reservation near the key's quota")
+				h.writeSSEError(w, "quota_exceeded", "The stream exceeded its token reservation and the API key is near its token quota")
 				return
 			}
 
+			if toolCalls != nil {
+				if line = toolCalls.process(line); line == nil {
+					continue
+				}
+			}
+
+			if filter != nil {
+				out, rule := filter.process(line)
+				if rule != "" {
+					logOutputFiltered(r, rule)
+					h.writeSSEError(w, "output_filtered", "The generated response was blocked by an output filter")
+					return
+				}
+				if out == nil {
+					continue
+				}
+				line = out
+			}
+
+			if loops != nil {
+				out, detected := loops.process(line)
+				if detected {
+					logger.Warn().
+						Str("request_id", chimiddleware.GetReqID(ctx)).
+						Str("model", req.Model).
+						Bool("terminated", h.config.LoopDetection.Terminate).
+						Msg("Repetition loop detected in stream")
+					if h.config.LoopDetection.Terminate {
+						w.Write(out)
+						w.Write([]byte("data: [DONE]\n\n"))
+						flusher.Flush()
+						return
+					}
+				}
+			}
+
+			if limiter != nil {
+				out, exceeded := limiter.process(line)
+				if exceeded {
+					h.endLimitedStream(w, r, limiter.limit, out)
+					flusher.Flush()
+					return
+				}
+				line = out
+			}
+
 			// Forward the line as-is (provider returns SSE-formatted data)
 			w.Write(line)
+			observability.CaptureStreamChunk(ctx, line)
+			record.process(line)
+			if citations != nil {
+				if events := citations.process(line); events != nil {
+					w.Write(events)
+				}
+			}
 			flusher.Flush()
 		}
 	}
@@ -149,39 +463,87 @@ func (h *Handler) handleStreamingResponse(w http.ResponseWriter, r *http.Request
 // Completions handles POST /v1/completions (legacy endpoint)
 func (h *Handler) Completions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	requestID := middleware.GetReqID(ctx)
+	requestID := chimiddleware.GetReqID(ctx)
 
 	var req models.CompletionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	unknown, err := decodeRequest(r, &req)
+	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
 		return
 	}
+	middleware.SetEndUser(ctx, req.User)
+	req.Model = h.resolveAlias(req.Model)
+	abuse.ObserveModel(ctx, req.Model)
 
 	if err := req.Validate(); err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
+	if !h.checkPromptSecrets(w, r, func(scan *promptScan) {
+		scan.text(&req.Prompt)
+		scan.text(&req.Suffix)
+	}) {
+		return
+	}
+	if !h.checkGuardrailInput(w, r, &req.Prompt, &req.Suffix) {
+		return
+	}
+	if !h.applyCompletionPreset(w, r, &req) {
+		return
+	}
+	req.Model = h.applyLanguage(w, r, req.Prompt, req.Model)
+	h.applyUseCase(r, useCaseRequest{model: req.Model, text: req.Prompt})
+	analytics.ObservePrompt(ctx, req.Model, req.User, req.Prompt)
 
-	log.Debug().
+	logger.Debug().
 		Str("request_id", requestID).
 		Str("model", req.Model).
 		Bool("stream", req.Stream).
 		Msg("Processing legacy completion request")
 
-	provider, err := h.proxyRouter.GetProviderForModel(req.Model)
+	provider, err := h.selectProvider(w, r, req.Model, "")
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_model", err.Error())
+		h.writeRoutingError(w, err)
+		return
+	}
+	if !h.checkIgnoredFields(w, r, provider.Name(), unknown, nil) {
 		return
 	}
 
+	start := time.Now()
 	resp, err := provider.Completion(ctx, &req)
 	if err != nil {
-		var providerErr *proxy.ProviderError
-		if errors.As(err, &providerErr) {
-			h.writeError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
+		h.writeProviderFailure(w, r, err)
+		return
+	}
+	setFallbackHeader(w, r)
+	h.setCallHeaders(w, r, callInfo{
+		provider:         provider.Name(),
+		model:            req.Model,
+		latency:          time.Since(start),
+		promptTokens:     resp.Usage.PromptTokens,
+		completionTokens: resp.Usage.CompletionTokens,
+	})
+	h.recordUsage(ctx, provider.Name(), req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
+	observeContentFilter(r, completionFinishReasons(resp)...)
+
+	if h.outputFilter != nil {
+		if rule := h.outputFilter.filterCompletionResponse(resp); rule != "" {
+			h.writeOutputFiltered(w, r, rule)
 			return
 		}
-		h.writeError(w, http.StatusInternalServerError, "provider_error", err.Error())
+	}
+
+	texts := make([]*string, len(resp.Choices))
+	for i := range resp.Choices {
+		texts[i] = &resp.Choices[i].Text
+	}
+	if !h.checkGuardrailOutput(w, r, texts...) {
+		return
+	}
+	if limit, ok := h.responseLimit(r); ok && !limit.limitCompletionResponse(resp) {
+		h.writeResponseTooLarge(w, r)
 		return
 	}
 
@@ -195,32 +557,47 @@ func (h *Handler) Embeddings(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req models.EmbeddingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	unknown, err := decodeRequest(r, &req)
+	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
 		return
 	}
+	middleware.SetEndUser(ctx, req.User)
+	req.Model = h.resolveAlias(req.Model)
+	abuse.ObserveModel(ctx, req.Model)
 
 	if err := req.Validate(); err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
+	if !h.checkPromptSecrets(w, r, func(scan *promptScan) { scan.embeddingInput(&req) }) {
+		return
+	}
+	h.applyUseCase(r, useCaseRequest{embedding: true})
 
-	provider, err := h.proxyRouter.GetProviderForModel(req.Model)
+	provider, err := h.selectProvider(w, r, req.Model, "")
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_model", err.Error())
+		h.writeRoutingError(w, err)
+		return
+	}
+	if !h.checkIgnoredFields(w, r, provider.Name(), unknown, nil) {
 		return
 	}
 
+	start := time.Now()
 	resp, err := provider.Embedding(ctx, &req)
 	if err != nil {
-		var providerErr *proxy.ProviderError
-		if errors.As(err, &providerErr) {
-			h.writeError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
-			return
-		}
-		h.writeError(w, http.StatusInternalServerError, "provider_error", err.Error())
+		h.writeProviderFailure(w, r, err)
 		return
 	}
+	setFallbackHeader(w, r)
+	h.setCallHeaders(w, r, callInfo{
+		provider:     provider.Name(),
+		model:        req.Model,
+		latency:      time.Since(start),
+		promptTokens: resp.Usage.PromptTokens,
+	})
+	h.recordUsage(ctx, provider.Name(), req.Model, resp.Usage.PromptTokens, 0)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -231,6 +608,8 @@ func (h *Handler) Embeddings(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
 	models := h.proxyRouter.ListModels()
 
+	models = allowedModels(r, models)
+
 	resp := map[string]interface{}{
 		"object": "list",
 		"data":   models,
@@ -244,32 +623,65 @@ func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
 // AnthropicMessages handles POST /v1/messages (Anthropic-compatible)
 func (h *Handler) AnthropicMessages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	requestID := middleware.GetReqID(ctx)
+	requestID := chimiddleware.GetReqID(ctx)
 
 	var req models.AnthropicMessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	unknown, err := decodeRequest(r, &req)
+	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
 		return
 	}
+	middleware.SetEndUser(ctx, anthropicUserID(req.Metadata))
+	req.Model = h.resolveAlias(req.Model)
+	abuse.ObserveModel(ctx, req.Model)
+	if !h.checkPromptSecrets(w, r, func(scan *promptScan) {
+		scan.text(&req.System)
+		scan.messages(req.Messages)
+	}) {
+		return
+	}
+	r = h.maskPII(r, append(messageTexts(req.Messages), &req.System)...)
+	if !h.checkGuardrailInput(w, r, append(messageTexts(req.Messages), &req.System)...) {
+		return
+	}
+	if !h.applyAnthropicStyle(w, r, &req) || !h.applyAnthropicPreset(w, r, &req) {
+		return
+	}
+	req.Model = h.applyLanguage(w, r, promptText(req.Messages), req.Model)
+	h.applyUseCase(r, useCaseRequest{model: req.Model, text: req.System + "\n" + promptText(req.Messages)})
 
-	log.Debug().
+	logger.Debug().
 		Str("request_id", requestID).
 		Str("model", req.Model).
 		Bool("stream", req.Stream).
 		Msg("Processing Anthropic-style message request")
 
 	// Route to Anthropic provider
-	provider, err := h.proxyRouter.GetProvider("anthropic")
+	provider, err := h.selectProvider(w, r, req.Model, "anthropic")
 	if err != nil {
+		var providerErr *proxy.ProviderError
+		if errors.As(err, &providerErr) {
+			h.writeError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
+			return
+		}
 		h.writeError(w, http.StatusBadRequest, "provider_unavailable", "Anthropic provider not configured")
 		return
 	}
+	if !h.checkIgnoredFields(w, r, provider.Name(), unknown, nil) {
+		return
+	}
 
 	// Convert to internal format and process
 	chatReq := req.ToChatCompletionRequest()
+	observePrompt(r, chatReq.Model, anthropicUserID(req.Metadata), chatReq.Messages)
+	h.compressConversation(r, chatReq)
 	
 	if req.Stream {
-		h.handleStreamingResponse(w, r, provider, chatReq)
+		// Stream Anthropic events whatever the provider; the usage chunk
+		// supplies the message's token counts
+		chatReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+		out := newAnthropicStreamWriter(w, chatReq.Model, conversationTokens(chatReq.Messages))
+		h.handleStreamingResponse(out, r, provider, chatReq)
 	} else {
 		h.handleSyncResponse(w, r, provider, chatReq)
 	}
@@ -289,6 +701,50 @@ func (h *Handler) writeError(w http.ResponseWriter, status int, code, message st
 	json.NewEncoder(w).Encode(resp)
 }
 
+// writeRoutingError writes the error returned when no provider could be selected
+func (h *Handler) writeRoutingError(w http.ResponseWriter, err error) {
+	var providerErr *proxy.ProviderError
+	if errors.As(err, &providerErr) {
+		if providerErr.StatusCode == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+			w.Header().Set("Retry-After", "60")
+		}
+		h.writeError(w, providerErr.StatusCode, providerErr.Code, providerErr.Message)
+		return
+	}
+	h.writeError(w, http.StatusBadRequest, "invalid_model", err.Error())
+}
+
+// writeResponseTooLarge rejects a complete response that exceeds the route's limit
+func (h *Handler) writeResponseTooLarge(w http.ResponseWriter, r *http.Request) {
+	logger.Warn().
+		Str("request_id", chimiddleware.GetReqID(r.Context())).
+		Str("path", r.URL.Path).
+		Msg("Response exceeds size limit, rejected")
+	h.writeError(w, http.StatusBadGateway, "response_too_large", "The generated response exceeds the maximum size for this route")
+}
+
+// endLimitedStream ends a stream that reached the route's response limit:
+// truncate finishes it with the trimmed chunk, reject sends an error event and
+// terminate closes it without [DONE]
+func (h *Handler) endLimitedStream(w http.ResponseWriter, r *http.Request, limit responseLimit, lastChunk []byte) {
+	logger.Warn().
+		Str("request_id", chimiddleware.GetReqID(r.Context())).
+		Str("path", r.URL.Path).
+		Str("policy", limit.policy).
+		Int64("max_bytes", limit.budget).
+		Msg("Stream reached response size limit")
+
+	switch limit.policy {
+	case limitPolicyTruncate:
+		if lastChunk != nil {
+			w.Write(lastChunk)
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	case limitPolicyReject:
+		h.writeSSEError(w, "response_too_large", "The generated response exceeds the maximum size for this route")
+	}
+}
+
 // writeSSEError writes an error as SSE event
 func (h *Handler) writeSSEError(w http.ResponseWriter, code, message string) {
 	errData, _ := json.Marshal(map[string]interface{}{
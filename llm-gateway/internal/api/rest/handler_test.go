@@ -2,11 +2,18 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
@@ -375,3 +382,74 @@ func TestHTTPMethods(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_writeProviderFailure(t *testing.T) {
+	h := &Handler{}
+
+	handler := attemptTimeline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observability.RecordProviderAttempt(r.Context(), observability.ProviderAttempt{
+			Provider: "openai", Attempt: 1, Duration: 2 * time.Second, Status: 503, ErrorClass: "upstream_error",
+		})
+		h.writeProviderFailure(w, r, &proxy.ProviderError{
+			Provider: "openai", StatusCode: http.StatusBadGateway, Code: "upstream_error", Message: "all attempts failed",
+		})
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+	var resp models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	meta := resp.Error.Metadata
+	if meta == nil || len(meta.Attempts) != 1 {
+		t.Fatalf("metadata = %+v, want one attempt", meta)
+	}
+	if a := meta.Attempts[0]; a.Provider != "openai" || a.Status != 503 || a.DurationMs != 2000 || a.ErrorClass != "upstream_error" {
+		t.Errorf("attempt = %+v", a)
+	}
+}
+
+// countingProvider answers chat completions, counting the calls
+type countingProvider struct {
+	proxy.Provider
+	calls int
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	p.calls++
+	return &models.ChatCompletionResponse{ID: "chatcmpl-1", Model: req.Model}, nil
+}
+
+func (p *countingProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	return nil, io.EOF
+}
+
+func TestHandler_handleSyncResponse_Cache(t *testing.T) {
+	cache, err := performance.NewSemanticCache(performance.CacheConfig{Enabled: true, TTL: time.Hour, Backend: "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	performance.SetDefaultCache(cache)
+	defer performance.SetDefaultCache(nil)
+
+	h := NewHandler(&config.Config{}, nil)
+	provider := &countingProvider{}
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		h.handleSyncResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), provider, req)
+		if rr.Code != http.StatusOK || !bytes.Contains(rr.Body.Bytes(), []byte("chatcmpl-1")) {
+			t.Fatalf("response %d: %d %s", i, rr.Code, rr.Body.String())
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider calls = %d, want 1 (second response from cache)", provider.calls)
+	}
+}
@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// allowedModels drops the models the caller's managed key may not use
+func allowedModels(r *http.Request, list []models.Model) []models.Model {
+	key := keys.FromContext(r.Context())
+	if key == nil {
+		return list
+	}
+	allowed := make([]models.Model, 0, len(list))
+	for _, m := range list {
+		if key.AllowsModel(m.ID) {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// keyResponse is a managed key as returned by the admin API: without the
+// secret's hash, with the key's usage on this replica
+type keyResponse struct {
+	keys.Key
+	Hash  *string    `json:"hash,omitempty"`
+	Usage keys.Usage `json:"usage"`
+}
+
+// keyManager returns the process-wide key manager, writing a 404 if managed
+// keys are not enabled
+func keyManager(w http.ResponseWriter) *keys.Manager {
+	manager := keys.Default()
+	if manager == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Managed API keys are not enabled")
+	}
+	return manager
+}
+
+// writeKeyError maps a keys error to a response
+func writeKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, keys.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, keys.ErrInvalid):
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, keys.ErrExists):
+		writeJSONError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// ListKeys handles GET /admin/v1/keys, filterable by any key field such as
+// ?tenant=acme
+func (h *AdminHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	manager := keyManager(w)
+	if manager == nil {
+		return
+	}
+	list := manager.List()
+	views := make([]keyResponse, len(list))
+	for i, k := range list {
+		views[i] = keyResponse{Key: k, Usage: manager.Usage(k.ID)}
+	}
+	writeList(w, r, views, "id", "id")
+}
+
+// GetKey handles GET /admin/v1/keys/{id}
+func (h *AdminHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	manager := keyManager(w)
+	if manager == nil {
+		return
+	}
+	k, err := manager.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keyResponse{Key: k, Usage: manager.Usage(k.ID)})
+}
+
+// CreateKey handles POST /admin/v1/keys ({"tenant": "acme", "quota": {...},
+// "models": [...], "expires_at": "..."}). The response carries the secret,
+// which cannot be retrieved later; "secret" in the request imports an
+// existing one instead of generating it.
+func (h *AdminHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	manager := keyManager(w)
+	if manager == nil {
+		return
+	}
+	var req struct {
+		keys.Settings
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	k, secret, err := manager.Create(r.Context(), req.Settings, req.Secret)
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "api_key.create", k.ID, map[string]interface{}{
+		"tenant":   k.Tenant,
+		"imported": req.Secret != "",
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+	writeJSON(w, http.StatusCreated, struct {
+		keyResponse
+		Secret string `json:"secret"`
+	}{keyResponse{Key: k}, secret})
+}
+
+// UpdateKey handles PATCH /admin/v1/keys/{id}; fields left out are unchanged
+func (h *AdminHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
+	manager := keyManager(w)
+	if manager == nil {
+		return
+	}
+	var settings keys.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	k, err := manager.Update(r.Context(), chi.URLParam(r, "id"), settings)
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "api_key.update", k.ID, map[string]interface{}{
+		"tenant":   k.Tenant,
+		"disabled": k.Disabled,
+		"actor":    middleware.GetUserID(r.Context()),
+	})
+	writeJSON(w, http.StatusOK, keyResponse{Key: k, Usage: manager.Usage(k.ID)})
+}
+
+// DeleteKey handles DELETE /admin/v1/keys/{id}
+func (h *AdminHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	manager := keyManager(w)
+	if manager == nil {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := manager.Delete(r.Context(), id); err != nil {
+		writeKeyError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "api_key.delete", id, map[string]interface{}{
+		"actor": middleware.GetUserID(r.Context()),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
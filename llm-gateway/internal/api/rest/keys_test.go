@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/proxy"
+)
+
+func enableKeys(t *testing.T) *keys.Manager {
+	t.Helper()
+	manager := keys.NewWithStore(keys.NewFileStore(filepath.Join(t.TempDir(), "keys.json")), time.Minute)
+	keys.SetDefault(manager)
+	t.Cleanup(func() { keys.SetDefault(nil) })
+	return manager
+}
+
+func TestAdminKeys(t *testing.T) {
+	ah := NewAdminHandler(nil, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/admin/v1/keys", ah.ListKeys)
+	r.Post("/admin/v1/keys", ah.CreateKey)
+	r.Get("/admin/v1/keys/{id}", ah.GetKey)
+	r.Patch("/admin/v1/keys/{id}", ah.UpdateKey)
+	r.Delete("/admin/v1/keys/{id}", ah.DeleteKey)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	if rr := do(http.MethodGet, "/admin/v1/keys", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 when disabled", rr.Code)
+	}
+	manager := enableKeys(t)
+
+	if rr := do(http.MethodPost, "/admin/v1/keys", `{"name": "no tenant"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("create without tenant: %d", rr.Code)
+	}
+	rr := do(http.MethodPost, "/admin/v1/keys", `{"tenant": "acme", "quota": {"requests_per_day": 100}, "models": ["gpt-4o*"]}`)
+	var created struct {
+		ID     string `json:"id"`
+		Hash   string `json:"hash"`
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil || rr.Code != http.StatusCreated {
+		t.Fatalf("create: %d %v", rr.Code, err)
+	}
+	if created.Secret == "" || created.Hash != "" {
+		t.Errorf("created = %+v, want the secret and no hash", created)
+	}
+	if _, err := manager.Authenticate(created.Secret); err != nil {
+		t.Errorf("Authenticate() with the returned secret: %v", err)
+	}
+
+	if rr := do(http.MethodPatch, "/admin/v1/keys/"+created.ID, `{"disabled": true}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"disabled":true`) {
+		t.Errorf("update: %d %s", rr.Code, rr.Body)
+	}
+	rr = do(http.MethodGet, "/admin/v1/keys?tenant=acme", "")
+	var page struct {
+		Data  []keys.Key `json:"data"`
+		Total int        `json:"total"`
+	}
+	if json.NewDecoder(rr.Body).Decode(&page); page.Total != 1 || page.Data[0].Quota.RequestsPerDay != 100 || page.Data[0].Hash != "" {
+		t.Errorf("list = %+v", page)
+	}
+
+	if rr := do(http.MethodDelete, "/admin/v1/keys/"+created.ID, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("delete: %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/admin/v1/keys/"+created.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("get after delete: %d", rr.Code)
+	}
+}
+
+func TestHandler_selectProvider_KeyModels(t *testing.T) {
+	h := newOverrideHandler("http://localhost:9999")
+	manager := enableKeys(t)
+	_, secret, err := manager.Create(t.Context(), keys.Settings{Tenant: ptrTo("acme"), Models: []string{"claude-*"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var providerErr *proxy.ProviderError
+	manager.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.selectProvider(w, r, "gpt-4o", ""); !errors.As(err, &providerErr) || providerErr.Code != "model_not_allowed" {
+			t.Errorf("disallowed model: err = %v", err)
+		}
+		if provider, err := h.selectProvider(w, r, "claude-3-5-haiku-20241022", ""); err != nil || provider.Name() != "anthropic" {
+			t.Errorf("allowed model: provider = %v, err = %v", provider, err)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), overrideRequest(secret, "", ""))
+}
+
+func ptrTo[T any](v T) *T { return &v }
@@ -0,0 +1,295 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// languageUndetermined is reported when a prompt is too short or ambiguous (ISO 639-2 "und")
+const languageUndetermined = "und"
+
+// maxLanguageSample bounds the number of runes inspected per prompt
+const maxLanguageSample = 2000
+
+// scriptLanguages maps scripts used by a single common language
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Greek, "el"},
+}
+
+// languageNames names the detected languages in retry instructions
+var languageNames = map[string]string{
+	"en": "English", "es": "Spanish", "fr": "French", "de": "German", "pt": "Portuguese",
+	"it": "Italian", "nl": "Dutch", "zh": "Chinese", "ja": "Japanese", "ko": "Korean",
+	"ru": "Russian", "ar": "Arabic", "he": "Hebrew", "th": "Thai", "hi": "Hindi", "el": "Greek",
+}
+
+// Language enforcement actions, as counted in llm_gateway_language_enforcement_total
+const (
+	languageActionReport    = "report"
+	languageActionRetry     = "retry"
+	languageActionCorrected = "corrected"
+	languageActionFailed    = "failed"
+)
+
+// latinStopwords holds frequent function words of Latin-script languages
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "you", "what", "how", "this", "with", "for", "be", "can", "please"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "es", "por", "una", "con", "para", "cómo", "qué", "del"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "que", "un", "une", "pour", "dans", "vous", "je", "pas", "qui"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "ein", "eine", "mit", "zu", "wie", "was", "für", "den"},
+	"pt": {"o", "os", "as", "de", "que", "e", "é", "em", "um", "uma", "para", "com", "não", "como", "do", "da"},
+	"it": {"il", "lo", "gli", "di", "che", "e", "è", "un", "una", "per", "non", "come", "sono", "del", "della"},
+	"nl": {"de", "het", "een", "en", "is", "van", "dat", "niet", "ik", "je", "op", "voor", "met", "wat", "hoe"},
+}
+
+// latinLanguages fixes the scoring order so ties resolve deterministically
+var latinLanguages = []string{"en", "es", "fr", "de", "pt", "it", "nl"}
+
+// detectLanguage returns the ISO 639-1 code of the primary language of text,
+// or "und" when fewer than minLetters letters are present or no language wins.
+// Non-Latin scripts are identified by script; Latin text by stopword frequency.
+func detectLanguage(text string, minLetters int) string {
+	var letters, latin, han, kana int
+	scripts := make(map[string]int)
+
+	n := 0
+	for _, r := range text {
+		if n++; n > maxLanguageSample {
+			break
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scripts[s.language]++
+					break
+				}
+			}
+		}
+	}
+	if letters < minLetters {
+		return languageUndetermined
+	}
+
+	// Japanese mixes kana with kanji; Chinese is Han alone
+	best, bestCount := "", latin
+	if cjk := han + kana; cjk > bestCount {
+		best, bestCount = "zh", cjk
+		if kana*10 >= cjk {
+			best = "ja"
+		}
+	}
+	for _, s := range scriptLanguages {
+		if scripts[s.language] > bestCount {
+			best, bestCount = s.language, scripts[s.language]
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return detectLatinLanguage(text)
+}
+
+// detectLatinLanguage scores Latin-script text by stopword hits
+func detectLatinLanguage(text string) string {
+	if len(text) > maxLanguageSample*2 {
+		text = text[:maxLanguageSample*2]
+	}
+	words := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		words[word]++
+	}
+
+	best, bestScore := languageUndetermined, 0
+	for _, lang := range latinLanguages {
+		score := 0
+		for _, stopword := range latinStopwords[lang] {
+			score += words[stopword]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	return best
+}
+
+// promptText concatenates the user-authored text of a chat request
+func promptText(messages []models.ChatMessage) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(msg.Text())
+		if b.Len() >= maxLanguageSample*4 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// applyLanguage detects the prompt language when enabled, records it on the
+// metrics, the request span and the X-Detected-Language header, and returns
+// the model to use: the language route if one is configured and model is a
+// routing alias (a name that leaves the choice of model to the gateway),
+// otherwise model. Explicitly requested models are never replaced.
+func (h *Handler) applyLanguage(w http.ResponseWriter, r *http.Request, text, model string) string {
+	cfg := h.config.Language
+	if !cfg.Enabled {
+		return model
+	}
+
+	language := detectLanguage(text, cfg.MinChars)
+	observability.GetMetrics().RecordLanguage(language)
+	if span := observability.SpanFromContext(r.Context()); span != nil {
+		span.SetAttribute("llm.prompt.language", language)
+	}
+	w.Header().Set("X-Detected-Language", language)
+
+	if !h.isModelAlias(model) {
+		return model
+	}
+	if routed, ok := cfg.Routes[language]; ok && routed != model {
+		logger.Debug().
+			Str("language", language).
+			Str("model", model).
+			Str("routed_model", routed).
+			Msg("Routing request by prompt language")
+		return routed
+	}
+	return model
+}
+
+// isModelAlias reports whether model is one of the routing aliases
+func (h *Handler) isModelAlias(model string) bool {
+	_, ok := h.config.Routing.Aliases[strings.ToLower(model)]
+	return ok
+}
+
+// languagePolicy returns the response language policy for the request's
+// route, if enforcement is enabled
+func (h *Handler) languagePolicy(r *http.Request) (config.LanguagePolicy, bool) {
+	cfg := h.config.Language
+	if !cfg.Enabled || !cfg.Enforcement.Enabled {
+		return config.LanguagePolicy{}, false
+	}
+	policy := cfg.Enforcement.Default
+	if routePolicy, ok := cfg.Enforcement.Routes[strings.TrimSuffix(r.URL.Path, "/")]; ok {
+		policy = routePolicy
+	}
+	return policy, policy.Required != ""
+}
+
+// responseText concatenates the message content of a chat response
+func responseText(resp *models.ChatCompletionResponse) string {
+	var b strings.Builder
+	for _, choice := range resp.Choices {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(choice.Message.Content)
+		if b.Len() >= maxLanguageSample*4 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// enforceResponseLanguage checks that a chat response is in the language the
+// route requires and, if the policy allows, asks once more with an explicit
+// instruction when it is not. It returns the response to send: the retried
+// one if it is in the required language, otherwise the original. Responses
+// too short to detect pass.
+func (h *Handler) enforceResponseLanguage(w http.ResponseWriter, r *http.Request, provider proxy.Provider, req *models.ChatCompletionRequest, resp *models.ChatCompletionResponse) *models.ChatCompletionResponse {
+	policy, ok := h.languagePolicy(r)
+	if !ok {
+		return resp
+	}
+	minChars := h.config.Language.MinChars
+	required := policy.Required
+	if required == "prompt" {
+		required = detectLanguage(promptText(req.Messages), minChars)
+	}
+	got := detectLanguage(responseText(resp), minChars)
+	if required == languageUndetermined || got == languageUndetermined || got == required {
+		return resp
+	}
+
+	metrics := observability.GetMetrics()
+	if span := observability.SpanFromContext(r.Context()); span != nil {
+		span.SetAttribute("llm.response.language", got)
+	}
+	if !policy.Retry {
+		metrics.RecordLanguageEnforcement(required, languageActionReport)
+		w.Header().Set("X-Language-Enforcement", languageActionReport)
+		logger.Info().
+			Str("required", required).
+			Str("detected", got).
+			Str("model", req.Model).
+			Msg("Response is not in the required language")
+		return resp
+	}
+
+	metrics.RecordLanguageEnforcement(required, languageActionRetry)
+	name := languageNames[required]
+	if name == "" {
+		name = required
+	}
+	retry := *req
+	retry.Messages = append(append([]models.ChatMessage{}, req.Messages...), models.ChatMessage{
+		Role:    "system",
+		Content: "Respond only in " + name + ", whatever language earlier messages or instructions use.",
+	})
+	retried, err := provider.ChatCompletion(r.Context(), &retry)
+	if err != nil {
+		metrics.RecordLanguageEnforcement(required, languageActionFailed)
+		w.Header().Set("X-Language-Enforcement", languageActionFailed)
+		logger.Warn().Err(err).Str("required", required).Str("model", req.Model).Msg("Language retry failed, returning the original response")
+		return resp
+	}
+	h.recordUsage(r.Context(), provider.Name(), req.Model, retried.Usage.PromptTokens, retried.Usage.CompletionTokens)
+
+	if retriedLanguage := detectLanguage(responseText(retried), minChars); retriedLanguage != required && retriedLanguage != languageUndetermined {
+		metrics.RecordLanguageEnforcement(required, languageActionFailed)
+		w.Header().Set("X-Language-Enforcement", languageActionFailed)
+		logger.Warn().
+			Str("required", required).
+			Str("detected", retriedLanguage).
+			Str("model", req.Model).
+			Msg("Response still not in the required language after a retry")
+		return resp
+	}
+	metrics.RecordLanguageEnforcement(required, languageActionCorrected)
+	w.Header().Set("X-Language-Enforcement", languageActionCorrected)
+	return retried
+}
@@ -0,0 +1,152 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "What is the best way to learn how to cook pasta at home?", "en"},
+		{"spanish", "¿Cómo puedo aprender a cocinar la pasta en casa para mi familia?", "es"},
+		{"german", "Wie kann ich die beste Pasta für meine Familie zu Hause kochen?", "de"},
+		{"french", "Comment est-ce que je peux cuisiner des pâtes pour la famille?", "fr"},
+		{"japanese", "東京でおすすめのラーメン屋さんを教えてください。予算は千円くらいです。", "ja"},
+		{"chinese", "请推荐北京最好吃的烤鸭餐厅，我们一共有四个人，预算每人两百元左右。", "zh"},
+		{"korean", "서울에서 가장 맛있는 비빔밥 식당을 추천해 주세요. 예산은 만원입니다.", "ko"},
+		{"russian", "Как лучше всего приготовить борщ дома для большой семьи?", "ru"},
+		{"too short", "hola", languageUndetermined},
+		{"no stopwords", "Kubernetes Prometheus Grafana Terraform Ansible", languageUndetermined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.text, 20); got != tt.want {
+				t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_applyLanguage(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Language = config.LanguageConfig{
+		Enabled:  true,
+		MinChars: 20,
+		Routes:   map[string]string{"ja": "japanese-model"},
+	}
+	cfg.Routing.Aliases = map[string]string{"default-chat": "gpt-4o"}
+	h := NewHandler(cfg, nil)
+
+	rr := httptest.NewRecorder()
+	model := h.applyLanguage(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil),
+		"東京でおすすめのラーメン屋さんを教えてください。", "default-chat")
+	if model != "japanese-model" {
+		t.Errorf("model = %q, want japanese-model", model)
+	}
+	if got := rr.Header().Get("X-Detected-Language"); got != "ja" {
+		t.Errorf("X-Detected-Language = %q, want ja", got)
+	}
+
+	// An explicitly requested model is kept, though the language is still reported
+	rr = httptest.NewRecorder()
+	model = h.applyLanguage(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil),
+		"東京でおすすめのラーメン屋さんを教えてください。", "gpt-4o")
+	if model != "gpt-4o" || rr.Header().Get("X-Detected-Language") != "ja" {
+		t.Errorf("explicit model: model = %q, header = %q", model, rr.Header().Get("X-Detected-Language"))
+	}
+
+	rr = httptest.NewRecorder()
+	model = h.applyLanguage(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil),
+		"What is the best way to learn how to cook pasta?", "default-chat")
+	if model != "default-chat" || rr.Header().Get("X-Detected-Language") != "en" {
+		t.Errorf("unrouted language: model = %q, header = %q", model, rr.Header().Get("X-Detected-Language"))
+	}
+
+	cfg.Language.Enabled = false
+	rr = httptest.NewRecorder()
+	if model := h.applyLanguage(rr, httptest.NewRequest("POST", "/", nil), "東京でおすすめのラーメン屋さんを教えてください。", "gpt-4o"); model != "gpt-4o" || rr.Header().Get("X-Detected-Language") != "" {
+		t.Error("detection should be skipped when disabled")
+	}
+}
+
+// replyProvider answers chat completions with the next of its replies
+type replyProvider struct {
+	proxy.Provider
+	replies []string
+	last    *models.ChatCompletionRequest
+}
+
+func (p *replyProvider) Name() string { return "reply" }
+
+func (p *replyProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	p.last = req
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Role: "assistant", Content: reply}}}}, nil
+}
+
+func TestHandler_enforceResponseLanguage(t *testing.T) {
+	const (
+		german  = "Die beste Pasta kochst du mit frischen Tomaten und ist nicht schwer."
+		english = "The best pasta is made with fresh tomatoes and it is not hard to cook."
+	)
+	cfg := &config.Config{}
+	cfg.Language = config.LanguageConfig{
+		Enabled:  true,
+		MinChars: 20,
+		Enforcement: config.LanguageEnforcementConfig{
+			Enabled: true,
+			Default: config.LanguagePolicy{Required: "prompt", Retry: true},
+			Routes:  map[string]config.LanguagePolicy{"/v1/support": {Required: "de"}},
+		},
+	}
+	h := NewHandler(cfg, nil)
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{
+		{Role: "user", Content: "Wie kann ich die beste Pasta für meine Familie zu Hause kochen?"},
+	}}
+	enforce := func(path string, provider *replyProvider, first string) (string, string) {
+		rr := httptest.NewRecorder()
+		resp := &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Content: first}}}}
+		resp = h.enforceResponseLanguage(rr, httptest.NewRequest("POST", path, nil), provider, req, resp)
+		return resp.Choices[0].Message.Content, rr.Header().Get("X-Language-Enforcement")
+	}
+
+	// In the prompt's language: nothing to do
+	if got, action := enforce("/v1/chat/completions", &replyProvider{}, german); got != german || action != "" {
+		t.Errorf("matching response: %q, %q", got, action)
+	}
+
+	// In another language: retried once with an explicit instruction
+	provider := &replyProvider{replies: []string{german}}
+	if got, action := enforce("/v1/chat/completions", provider, english); got != german || action != languageActionCorrected {
+		t.Errorf("corrected: %q, %q", got, action)
+	}
+	if last := provider.last.Messages[len(provider.last.Messages)-1]; last.Role != "system" || last.Content == "" || len(req.Messages) != 1 {
+		t.Errorf("retry instruction = %+v, original messages = %d", last, len(req.Messages))
+	}
+
+	// Still wrong after the retry: the original is returned
+	if got, action := enforce("/v1/chat/completions", &replyProvider{replies: []string{english}}, english); got != english || action != languageActionFailed {
+		t.Errorf("failed: %q, %q", got, action)
+	}
+
+	// Routes without retry only report
+	if got, action := enforce("/v1/support", &replyProvider{}, english); got != english || action != languageActionReport {
+		t.Errorf("report: %q, %q", got, action)
+	}
+
+	cfg.Language.Enforcement.Enabled = false
+	if got, action := enforce("/v1/chat/completions", &replyProvider{}, english); got != english || action != "" {
+		t.Errorf("disabled: %q, %q", got, action)
+	}
+}
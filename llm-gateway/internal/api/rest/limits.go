@@ -0,0 +1,185 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Response limit policies
+const (
+	limitPolicyReject    = "reject"
+	limitPolicyTruncate  = "truncate"
+	limitPolicyTerminate = "terminate"
+)
+
+// responseLimit is the effective limit for one request. Token limits are
+// converted to bytes at ~4 bytes per token so both share one budget.
+type responseLimit struct {
+	budget int64
+	policy string
+}
+
+// responseLimit returns the limit for the request's route, if any
+func (h *Handler) responseLimit(r *http.Request) (responseLimit, bool) {
+	cfg := h.config.ResponseLimits
+	if !cfg.Enabled {
+		return responseLimit{}, false
+	}
+
+	rule := cfg.Default
+	if routeRule, ok := cfg.Routes[strings.TrimSuffix(r.URL.Path, "/")]; ok {
+		rule = routeRule
+	}
+	return newResponseLimit(rule)
+}
+
+func newResponseLimit(rule config.ResponseLimitRule) (responseLimit, bool) {
+	budget := rule.MaxBytes
+	if tokenBytes := int64(rule.MaxTokens) * 4; tokenBytes > 0 && (budget <= 0 || tokenBytes < budget) {
+		budget = tokenBytes
+	}
+	if budget <= 0 {
+		return responseLimit{}, false
+	}
+
+	policy := rule.Policy
+	if policy == "" {
+		policy = limitPolicyTruncate
+	}
+	return responseLimit{budget: budget, policy: policy}, true
+}
+
+// limitChatResponse applies the limit to a complete chat response. It returns
+// false if the response must be rejected; truncated choices end with finish_reason "length".
+func (l responseLimit) limitChatResponse(resp *models.ChatCompletionResponse) bool {
+	var total int64
+	for _, choice := range resp.Choices {
+		total += int64(len(choice.Message.Content))
+	}
+	if total <= l.budget {
+		return true
+	}
+	if l.policy != limitPolicyTruncate {
+		return false
+	}
+
+	remaining := l.budget
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		if int64(len(choice.Message.Content)) > remaining {
+			choice.Message.Content = truncateUTF8(choice.Message.Content, remaining)
+			choice.FinishReason = "length"
+		}
+		remaining -= int64(len(choice.Message.Content))
+	}
+	return true
+}
+
+// limitCompletionResponse applies the limit to a complete legacy completion response
+func (l responseLimit) limitCompletionResponse(resp *models.CompletionResponse) bool {
+	var total int64
+	for _, choice := range resp.Choices {
+		total += int64(len(choice.Text))
+	}
+	if total <= l.budget {
+		return true
+	}
+	if l.policy != limitPolicyTruncate {
+		return false
+	}
+
+	remaining := l.budget
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		if int64(len(choice.Text)) > remaining {
+			choice.Text = truncateUTF8(choice.Text, remaining)
+			choice.FinishReason = "length"
+		}
+		remaining -= int64(len(choice.Text))
+	}
+	return true
+}
+
+// streamLimiter tracks content forwarded on an SSE stream against a response limit
+type streamLimiter struct {
+	limit responseLimit
+	sent  int64
+}
+
+// process checks one SSE line. It returns the line to forward and whether the
+// limit was reached; with the truncate policy the returned line is the chunk
+// trimmed to the remaining budget and marked finish_reason "length".
+func (s *streamLimiter) process(line []byte) ([]byte, bool) {
+	chunk, ok := parseStreamChunk(line)
+	if !ok {
+		return line, false
+	}
+
+	var size int64
+	for _, choice := range chunk.Choices {
+		size += int64(len(choice.Delta.Content))
+	}
+	if s.sent+size <= s.limit.budget {
+		s.sent += size
+		return line, false
+	}
+	if s.limit.policy != limitPolicyTruncate {
+		return nil, true
+	}
+
+	remaining := s.limit.budget - s.sent
+	length := "length"
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		choice.Delta.Content = truncateUTF8(choice.Delta.Content, remaining)
+		remaining -= int64(len(choice.Delta.Content))
+		choice.FinishReason = &length
+	}
+	s.sent = s.limit.budget
+
+	return formatStreamChunk(chunk), true
+}
+
+// parseStreamChunk decodes an SSE "data:" line holding a chat completion chunk
+func parseStreamChunk(line []byte) (*models.ChatCompletionStreamResponse, bool) {
+	data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data: "))
+	if !ok || bytes.Equal(data, []byte("[DONE]")) {
+		return nil, false
+	}
+
+	var chunk models.ChatCompletionStreamResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, false
+	}
+	return &chunk, true
+}
+
+// formatStreamChunk encodes a chunk as an SSE event
+func formatStreamChunk(chunk *models.ChatCompletionStreamResponse) []byte {
+	out, err := json.Marshal(chunk)
+	if err != nil {
+		return nil
+	}
+	return []byte("data: " + string(out) + "\n\n")
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int64) string {
+	if n <= 0 {
+		return ""
+	}
+	if int64(len(s)) <= n {
+		return s
+	}
+	s = s[:n]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestHandler_responseLimit(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ResponseLimits = config.ResponseLimitsConfig{
+		Enabled: true,
+		Default: config.ResponseLimitRule{MaxBytes: 1000},
+		Routes: map[string]config.ResponseLimitRule{
+			"/v1/completions": {MaxBytes: 1000, MaxTokens: 10, Policy: "reject"},
+		},
+	}
+	h := NewHandler(cfg, nil)
+
+	limit, ok := h.responseLimit(httptest.NewRequest("POST", "/v1/chat/completions", nil))
+	if !ok || limit.budget != 1000 || limit.policy != limitPolicyTruncate {
+		t.Errorf("default limit = %+v, %v", limit, ok)
+	}
+
+	// The smaller of max_bytes and max_tokens (at 4 bytes per token) applies
+	limit, ok = h.responseLimit(httptest.NewRequest("POST", "/v1/completions", nil))
+	if !ok || limit.budget != 40 || limit.policy != limitPolicyReject {
+		t.Errorf("route limit = %+v, %v", limit, ok)
+	}
+
+	cfg.ResponseLimits.Enabled = false
+	if _, ok := h.responseLimit(httptest.NewRequest("POST", "/v1/completions", nil)); ok {
+		t.Error("limits should not apply when disabled")
+	}
+}
+
+func TestResponseLimit_ChatResponse(t *testing.T) {
+	newResp := func() *models.ChatCompletionResponse {
+		return &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{
+			{Message: models.ChatMessage{Content: "héllo world"}, FinishReason: "stop"},
+		}}
+	}
+
+	resp := newResp()
+	if !(responseLimit{budget: 2, policy: limitPolicyTruncate}).limitChatResponse(resp) {
+		t.Fatal("truncate policy should not reject")
+	}
+	// "hé" is 3 bytes, so only "h" fits in 2 bytes
+	if got := resp.Choices[0]; got.Message.Content != "h" || got.FinishReason != "length" {
+		t.Errorf("truncated choice = %+v", got)
+	}
+
+	if (responseLimit{budget: 2, policy: limitPolicyReject}).limitChatResponse(newResp()) {
+		t.Error("reject policy should reject an oversized response")
+	}
+	if !(responseLimit{budget: 100, policy: limitPolicyReject}).limitChatResponse(newResp()) {
+		t.Error("response within the limit should pass")
+	}
+}
+
+func TestStreamLimiter_Truncate(t *testing.T) {
+	s := &streamLimiter{limit: responseLimit{budget: 8, policy: limitPolicyTruncate}}
+
+	out, exceeded := s.process([]byte(`data: {"id":"1","choices":[{"index":0,"delta":{"content":"hello"}}]}` + "\n"))
+	if exceeded || !strings.Contains(string(out), "hello") {
+		t.Fatalf("first chunk: exceeded=%v out=%s", exceeded, out)
+	}
+
+	out, exceeded = s.process([]byte(`data: {"id":"1","choices":[{"index":0,"delta":{"content":" world"}}]}` + "\n"))
+	if !exceeded {
+		t.Fatal("second chunk should exceed the limit")
+	}
+	if !strings.Contains(string(out), `"content":" wo"`) || !strings.Contains(string(out), `"finish_reason":"length"`) {
+		t.Errorf("truncated chunk = %s", out)
+	}
+
+	// Non-JSON lines are passed through untouched
+	if out, exceeded := s.process([]byte("data: [DONE]\n")); exceeded || string(out) != "data: [DONE]\n" {
+		t.Errorf("[DONE] line = %q, %v", out, exceeded)
+	}
+}
+
+func TestStreamLimiter_Terminate(t *testing.T) {
+	s := &streamLimiter{limit: responseLimit{budget: 3, policy: limitPolicyTerminate}}
+
+	out, exceeded := s.process([]byte(`data: {"choices":[{"delta":{"content":"hello"}}]}` + "\n"))
+	if !exceeded || out != nil {
+		t.Errorf("terminate policy should stop without a final chunk: %s", out)
+	}
+}
@@ -0,0 +1,259 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Prompt lint rules
+const (
+	lintNoSystemMessage   = "no_system_message"
+	lintContradictory     = "contradictory_instructions"
+	lintLargeContext      = "large_context"
+	lintRedundantContext  = "redundant_context"
+	lintUnclosedCodeFence = "unclosed_code_fence"
+)
+
+// promptWarningsHeader lists the rules of the warnings for an annotated request
+const promptWarningsHeader = "X-Prompt-Warnings"
+
+// contradictions are instructions that cannot both be followed, as pairs of
+// phrase lists
+var contradictions = []struct {
+	about string
+	a, b  []string
+}{
+	{"length", []string{"be concise", "be brief", "keep it short", "in one sentence"}, []string{"be detailed", "in detail", "be thorough", "elaborate on", "be comprehensive"}},
+	{"format", []string{"respond in json", "reply in json", "answer in json", "output json", "return json"}, []string{"plain text", "do not use json", "don't use json", "no json"}},
+	{"formatting", []string{"use markdown", "format with markdown"}, []string{"no markdown", "do not use markdown", "don't use markdown", "without markdown"}},
+}
+
+var (
+	alwaysPattern = regexp.MustCompile(`\balways (\w+ \w+)`)
+	neverPattern  = regexp.MustCompile(`\b(?:never|do not ever) (\w+ \w+)`)
+)
+
+type promptWarningsContextKey struct{}
+
+// promptWarningsFrom returns the warnings an annotated request got
+func promptWarningsFrom(ctx context.Context) []models.PromptWarning {
+	warnings, _ := ctx.Value(promptWarningsContextKey{}).([]models.PromptWarning)
+	return warnings
+}
+
+// Lint handles POST /v1/lint: the prompt linter's warnings for a chat
+// completion request, which is not sent to a provider
+func (h *Handler) Lint(w http.ResponseWriter, r *http.Request) {
+	var req models.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "messages is required")
+		return
+	}
+
+	warnings := lintPrompt(h.config.PromptLint, req.Messages)
+	recordPromptWarnings(warnings)
+	if warnings == nil {
+		warnings = []models.PromptWarning{}
+	}
+	writeJSON(w, http.StatusOK, models.LintResponse{Warnings: warnings})
+}
+
+// lintChatRequest lints the prompt of a chat completion request with
+// prompt_lint.annotate. The rules found are reported in X-Prompt-Warnings at
+// once, so streams get them too; the warnings go in the response body.
+func (h *Handler) lintChatRequest(w http.ResponseWriter, r *http.Request, messages []models.ChatMessage) *http.Request {
+	cfg := h.config.PromptLint
+	if !cfg.Enabled || !cfg.Annotate {
+		return r
+	}
+	warnings := lintPrompt(cfg, messages)
+	if len(warnings) == 0 {
+		return r
+	}
+	recordPromptWarnings(warnings)
+
+	rules := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		if !slices.Contains(rules, warning.Rule) {
+			rules = append(rules, warning.Rule)
+		}
+	}
+	w.Header().Set(promptWarningsHeader, strings.Join(rules, ","))
+	return r.WithContext(context.WithValue(r.Context(), promptWarningsContextKey{}, warnings))
+}
+
+func recordPromptWarnings(warnings []models.PromptWarning) {
+	metrics := observability.GetMetrics()
+	for _, warning := range warnings {
+		metrics.RecordPromptLintWarning(warning.Rule)
+	}
+}
+
+// lintPrompt checks messages for common prompt mistakes
+func lintPrompt(cfg config.PromptLintConfig, messages []models.ChatMessage) []models.PromptWarning {
+	var warnings []models.PromptWarning
+
+	hasSystem := slices.ContainsFunc(messages, func(m models.ChatMessage) bool {
+		return m.Role == "system" || m.Role == "developer"
+	})
+	if !hasSystem {
+		warnings = append(warnings, models.PromptWarning{
+			Rule:    lintNoSystemMessage,
+			Message: "The prompt has no system message; one stating the task, tone and output format makes answers more consistent",
+		})
+	}
+
+	warnings = append(warnings, lintContradictions(messages)...)
+
+	total := 0
+	for _, m := range messages {
+		total += utf8.RuneCountInString(m.Text())
+	}
+	if cfg.MaxContextChars > 0 && total > cfg.MaxContextChars {
+		warnings = append(warnings, models.PromptWarning{
+			Rule:    lintLargeContext,
+			Message: fmt.Sprintf("The prompt is %d characters, more than %d; send only the context the task needs", total, cfg.MaxContextChars),
+		})
+	}
+
+	if warning, ok := lintRedundancy(messages, cfg.RepeatedMinChars); ok {
+		warnings = append(warnings, warning)
+	}
+
+	var unclosed []int
+	for i, m := range messages {
+		fences := 0
+		for _, line := range strings.Split(m.Text(), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				fences++
+			}
+		}
+		if fences%2 == 1 {
+			unclosed = append(unclosed, i)
+		}
+	}
+	if len(unclosed) > 0 {
+		warnings = append(warnings, models.PromptWarning{
+			Rule:     lintUnclosedCodeFence,
+			Message:  "A code block is opened with ``` but never closed, so the rest of the message reads as code",
+			Messages: unclosed,
+		})
+	}
+	return warnings
+}
+
+// lintContradictions finds instructions in the system and user messages that
+// cannot both be followed
+func lintContradictions(messages []models.ChatMessage) []models.PromptWarning {
+	lower := make([]string, len(messages))
+	for i, m := range messages {
+		if m.Role != "assistant" && m.Role != "tool" {
+			lower[i] = strings.ToLower(m.Text())
+		}
+	}
+	containing := func(phrases []string) []int {
+		var indexes []int
+		for i, text := range lower {
+			if slices.ContainsFunc(phrases, func(p string) bool { return strings.Contains(text, p) }) {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+
+	var warnings []models.PromptWarning
+	for _, c := range contradictions {
+		a, b := containing(c.a), containing(c.b)
+		if len(a) > 0 && len(b) > 0 {
+			warnings = append(warnings, models.PromptWarning{
+				Rule:     lintContradictory,
+				Message:  "The prompt gives conflicting instructions about " + c.about,
+				Messages: mergeIndexes(a, b),
+			})
+		}
+	}
+
+	// "always X Y" and "never X Y" for the same words
+	always := map[string][]int{}
+	for i, text := range lower {
+		for _, match := range alwaysPattern.FindAllStringSubmatch(text, -1) {
+			always[match[1]] = append(always[match[1]], i)
+		}
+	}
+	seen := map[string]bool{}
+	for i, text := range lower {
+		for _, match := range neverPattern.FindAllStringSubmatch(text, -1) {
+			action := match[1]
+			if len(always[action]) == 0 || seen[action] {
+				continue
+			}
+			seen[action] = true
+			warnings = append(warnings, models.PromptWarning{
+				Rule:     lintContradictory,
+				Message:  fmt.Sprintf("The prompt says both to always and to never %q", action),
+				Messages: mergeIndexes(always[action], []int{i}),
+			})
+		}
+	}
+	return warnings
+}
+
+// lintRedundancy reports paragraphs of at least minChars sent more than once
+func lintRedundancy(messages []models.ChatMessage, minChars int) (models.PromptWarning, bool) {
+	if minChars <= 0 {
+		return models.PromptWarning{}, false
+	}
+	where := map[string][]int{}
+	var order []string
+	for i, m := range messages {
+		for _, paragraph := range strings.Split(m.Text(), "\n\n") {
+			normalized := strings.ToLower(strings.Join(strings.Fields(paragraph), " "))
+			if utf8.RuneCountInString(normalized) < minChars {
+				continue
+			}
+			if where[normalized] == nil {
+				order = append(order, normalized)
+			}
+			where[normalized] = append(where[normalized], i)
+		}
+	}
+
+	repeated, wasted := 0, 0
+	var indexes []int
+	for _, paragraph := range order {
+		if n := len(where[paragraph]); n > 1 {
+			repeated++
+			wasted += (n - 1) * utf8.RuneCountInString(paragraph)
+			indexes = mergeIndexes(indexes, where[paragraph])
+		}
+	}
+	if repeated == 0 {
+		return models.PromptWarning{}, false
+	}
+	message := fmt.Sprintf("%d paragraphs are sent more than once, repeating %d characters", repeated, wasted)
+	if repeated == 1 {
+		message = fmt.Sprintf("A paragraph is sent more than once, repeating %d characters", wasted)
+	}
+	return models.PromptWarning{Rule: lintRedundantContext, Message: message, Messages: indexes}, true
+}
+
+// mergeIndexes returns the sorted union of message indexes
+func mergeIndexes(a, b []int) []int {
+	merged := append(slices.Clone(a), b...)
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+var lintTestConfig = config.PromptLintConfig{Enabled: true, MaxContextChars: 2000, RepeatedMinChars: 50}
+
+func lintRules(warnings []models.PromptWarning) []string {
+	rules := make([]string, len(warnings))
+	for i, warning := range warnings {
+		rules[i] = warning.Rule
+	}
+	return rules
+}
+
+func TestLintPrompt(t *testing.T) {
+	system := models.ChatMessage{Role: "system", Content: "You are a helpful assistant."}
+	report := strings.Repeat("The quarterly report covers revenue, costs and hiring. ", 3)
+
+	tests := []struct {
+		name     string
+		messages []models.ChatMessage
+		want     []string
+		indexes  []int
+	}{
+		{"clean", []models.ChatMessage{system, {Role: "user", Content: "Summarize this."}}, nil, nil},
+		{"no system message", []models.ChatMessage{{Role: "user", Content: "Summarize this."}}, []string{lintNoSystemMessage}, nil},
+		{"contradictory length", []models.ChatMessage{
+			{Role: "system", Content: "Be concise."},
+			{Role: "user", Content: "Explain it in detail."},
+		}, []string{lintContradictory}, []int{0, 1}},
+		{"always and never", []models.ChatMessage{
+			{Role: "system", Content: "Always cite sources. Never cite sources from forums."},
+		}, []string{lintContradictory}, []int{0}},
+		{"assistant turns are not instructions", []models.ChatMessage{
+			{Role: "system", Content: "Be concise."},
+			{Role: "assistant", Content: "Here it is in detail."},
+		}, nil, nil},
+		{"large context", []models.ChatMessage{system, {Role: "user", Content: strings.Repeat("x", 2001)}}, []string{lintLargeContext}, nil},
+		{"redundant context", []models.ChatMessage{
+			system,
+			{Role: "user", Content: report + "\n\nWhat were the costs?"},
+			{Role: "assistant", Content: "About 2M."},
+			{Role: "user", Content: report + "\n\nAnd the hiring?"},
+		}, []string{lintRedundantContext}, []int{1, 3}},
+		{"unclosed code fence", []models.ChatMessage{system, {Role: "user", Content: "Fix this:\n```go\nfunc main() {}\n"}}, []string{lintUnclosedCodeFence}, []int{1}},
+		{"closed code fence", []models.ChatMessage{system, {Role: "user", Content: "Fix this:\n```go\nfunc main() {}\n```"}}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := lintPrompt(lintTestConfig, tt.messages)
+			if got := lintRules(warnings); !slices.Equal(got, tt.want) {
+				t.Fatalf("rules = %v, want %v", got, tt.want)
+			}
+			if tt.indexes != nil && !slices.Equal(warnings[0].Messages, tt.indexes) {
+				t.Errorf("messages = %v, want %v", warnings[0].Messages, tt.indexes)
+			}
+		})
+	}
+}
+
+func TestLint_Endpoint(t *testing.T) {
+	h := NewHandler(&config.Config{PromptLint: lintTestConfig}, nil)
+
+	rr := httptest.NewRecorder()
+	h.Lint(rr, httptest.NewRequest(http.MethodPost, "/v1/lint", strings.NewReader(`{"messages":[{"role":"user","content":"Hi"}]}`)))
+	var resp models.LintResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if got := lintRules(resp.Warnings); !slices.Equal(got, []string{lintNoSystemMessage}) {
+		t.Errorf("rules = %v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Lint(rr, httptest.NewRequest(http.MethodPost, "/v1/lint", strings.NewReader(`{"model":"gpt-4o"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status = %d without messages, want 400", rr.Code)
+	}
+}
+
+func TestLintChatRequest_Annotates(t *testing.T) {
+	cfg := lintTestConfig
+	cfg.Annotate = true
+	h := NewHandler(&config.Config{PromptLint: cfg}, nil)
+
+	rr := httptest.NewRecorder()
+	r := h.lintChatRequest(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), []models.ChatMessage{
+		{Role: "user", Content: "Reply in JSON.\n```\n{}"},
+	})
+	if got := rr.Header().Get(promptWarningsHeader); got != lintNoSystemMessage+","+lintUnclosedCodeFence {
+		t.Errorf("%s = %q", promptWarningsHeader, got)
+	}
+
+	h.writeChatResponse(rr, r, &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Role: "assistant", Content: "{}"}}}})
+	var resp models.ChatCompletionResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.PromptWarnings) != 2 {
+		t.Errorf("prompt_warnings = %+v, want 2 warnings", resp.PromptWarnings)
+	}
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// finishReasonLoopDetected ends a stream cut short because the model was repeating itself
+const finishReasonLoopDetected = "loop_detected"
+
+// loopDetector watches streamed output for degenerate repetition: a phrase of
+// NgramSize words occurring more than MaxRepeats times within the last Window words
+type loopDetector struct {
+	cfg config.LoopDetectionConfig
+
+	partial string         // trailing word that may continue in the next chunk
+	words   []string       // last NgramSize-1 words, to build the next n-gram
+	grams   []string       // n-grams within the window, oldest first
+	counts  map[string]int // occurrences of each n-gram within the window
+
+	detected bool
+}
+
+func newLoopDetector(cfg config.LoopDetectionConfig) *loopDetector {
+	return &loopDetector{cfg: cfg, counts: make(map[string]int)}
+}
+
+// process feeds one SSE line to the detector. It returns true the first time a
+// loop is seen; with Terminate set, the returned line is a final chunk with
+// finish_reason "loop_detected" to send instead of the original line.
+func (d *loopDetector) process(line []byte) ([]byte, bool) {
+	if d.detected {
+		return line, false
+	}
+	chunk, ok := parseStreamChunk(line)
+	if !ok {
+		return line, false
+	}
+
+	var content strings.Builder
+	for _, choice := range chunk.Choices {
+		content.WriteString(choice.Delta.Content)
+	}
+	if !d.feed(content.String()) {
+		return line, false
+	}
+
+	d.detected = true
+	if !d.cfg.Terminate {
+		return line, true
+	}
+
+	reason := finishReasonLoopDetected
+	for i := range chunk.Choices {
+		chunk.Choices[i].Delta = models.ChatMessageDelta{}
+		chunk.Choices[i].FinishReason = &reason
+	}
+	return formatStreamChunk(chunk), true
+}
+
+// feed adds streamed text and reports whether a repetition loop was found
+func (d *loopDetector) feed(text string) bool {
+	if text == "" {
+		return false
+	}
+
+	text = d.partial + text
+	d.partial = ""
+	words := strings.Fields(text)
+	// The last word is incomplete unless the text ends with whitespace
+	if len(words) > 0 && !unicode.IsSpace(rune(text[len(text)-1])) {
+		d.partial = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	for _, word := range words {
+		if d.add(strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends one word and counts the n-gram it completes
+func (d *loopDetector) add(word string) bool {
+	d.words = append(d.words, word)
+	if len(d.words) < d.cfg.NgramSize {
+		return false
+	}
+	gram := strings.Join(d.words, " ")
+	d.words = d.words[1:]
+
+	d.grams = append(d.grams, gram)
+	d.counts[gram]++
+	if len(d.grams) > d.cfg.Window {
+		oldest := d.grams[0]
+		d.grams = d.grams[1:]
+		if d.counts[oldest]--; d.counts[oldest] == 0 {
+			delete(d.counts, oldest)
+		}
+	}
+
+	return d.counts[gram] > d.cfg.MaxRepeats
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func testLoopConfig(terminate bool) config.LoopDetectionConfig {
+	return config.LoopDetectionConfig{Enabled: true, NgramSize: 3, Window: 60, MaxRepeats: 4, Terminate: terminate}
+}
+
+func chunkLine(content string) []byte {
+	return []byte(`data: {"id":"1","choices":[{"index":0,"delta":{"content":"` + content + `"}}]}` + "\n")
+}
+
+func TestLoopDetector_DetectsRepetition(t *testing.T) {
+	d := newLoopDetector(testLoopConfig(true))
+
+	// Words split across chunks are joined before counting
+	for _, content := range []string{"The answer ", "is that it ", "depends. "} {
+		if _, detected := d.process(chunkLine(content)); detected {
+			t.Fatalf("false positive on %q", content)
+		}
+	}
+
+	var out []byte
+	detected := false
+	for i := 0; i < 10 && !detected; i++ {
+		out, detected = d.process(chunkLine("I am stuck in a lo"))
+		if !detected {
+			out, detected = d.process(chunkLine("op. "))
+		}
+	}
+	if !detected {
+		t.Fatal("expected a loop to be detected")
+	}
+	if !strings.Contains(string(out), `"finish_reason":"loop_detected"`) || strings.Contains(string(out), "stuck") {
+		t.Errorf("final chunk = %s", out)
+	}
+
+	// Detection is reported only once
+	if _, detected := d.process(chunkLine("I am stuck in a loop. ")); detected {
+		t.Error("loop should only be reported once")
+	}
+}
+
+func TestLoopDetector_LogOnly(t *testing.T) {
+	d := newLoopDetector(testLoopConfig(false))
+
+	line := chunkLine("again and again and again ")
+	for i := 0; i < 10; i++ {
+		out, detected := d.process(line)
+		if detected {
+			if string(out) != string(line) {
+				t.Errorf("log-only mode should forward the original line, got %s", out)
+			}
+			return
+		}
+	}
+	t.Fatal("expected a loop to be detected")
+}
+
+func TestLoopDetector_VariedText(t *testing.T) {
+	d := newLoopDetector(testLoopConfig(true))
+
+	text := "Go is an open source programming language that makes it simple to build secure scalable systems. " +
+		"It was designed at Google and is used for cloud services, command line tools and networking. " +
+		"The language has a small specification, fast compilation and built in concurrency primitives."
+	for _, word := range strings.Fields(text) {
+		if _, detected := d.process(chunkLine(word + " ")); detected {
+			t.Fatalf("false positive at %q", word)
+		}
+	}
+}
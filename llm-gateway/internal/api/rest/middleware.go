@@ -0,0 +1,324 @@
+package rest
+
+import (
+	"compress/gzip"
+	"net/http"
+	"reflect"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/i18n"
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/privacy"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/reload"
+)
+
+// globalMiddleware returns the global middleware by server.middleware name.
+// Each function sets up its middleware and returns it, or nil if the feature
+// is disabled; it is only called if the middleware is in the chain. Metrics,
+// tracing, the flight recorder and the time series are initialized whatever
+// the chain, as handlers and providers use them too.
+func globalMiddleware(cfg *config.Config, proxyRouter *proxy.Router) map[string]func() func(http.Handler) http.Handler {
+	metrics, tracer := initObservability(cfg)
+	recorder := initFlightRecorder(cfg, proxyRouter)
+	initTimeSeries(cfg, proxyRouter)
+
+	return map[string]func() func(http.Handler) http.Handler{
+		// Request ID for tracing
+		config.MiddlewareRequestID: func() func(http.Handler) http.Handler {
+			return middleware.RequestID(cfg.Server.RequestIDFormat)
+		},
+
+		// Real IP extraction (for reverse proxy setups)
+		config.MiddlewareRealIP: func() func(http.Handler) http.Handler {
+			return middleware.RealIP(cfg.Server.TrustedProxies)
+		},
+
+		// Custom structured logging with zerolog
+		config.MiddlewareLogger: func() func(http.Handler) http.Handler {
+			return middleware.Logger()
+		},
+
+		// Classic access log (combined/common/JSON lines) for the edge log pipeline
+		config.MiddlewareAccessLog: func() func(http.Handler) http.Handler {
+			if !cfg.AccessLog.Enabled {
+				return nil
+			}
+			accessLog, err := middleware.NewAccessLog(cfg.AccessLog)
+			if err != nil {
+				logger.Error().Err(err).Str("output", cfg.AccessLog.Output).Msg("Failed to open access log, access logging disabled")
+				return nil
+			}
+			logger.Info().
+				Str("format", cfg.AccessLog.Format).
+				Str("output", cfg.AccessLog.Output).
+				Msg("Access log enabled")
+			return accessLog.Middleware()
+		},
+
+		// Panic recovery
+		config.MiddlewareRecoverer: func() func(http.Handler) http.Handler {
+			return chimiddleware.Recoverer
+		},
+
+		// Request timeout (configurable)
+		config.MiddlewareTimeout: func() func(http.Handler) http.Handler {
+			return chimiddleware.Timeout(cfg.Server.WriteTimeout)
+		},
+
+		// Localized error messages by Accept-Language (if enabled); outside the
+		// middleware that reject requests, so their errors are localized too
+		config.MiddlewareErrorMessages: func() func(http.Handler) http.Handler {
+			if !cfg.ErrorMessages.Enabled {
+				return nil
+			}
+			catalog := i18n.NewCatalog(cfg.ErrorMessages)
+			logger.Info().
+				Strs("languages", catalog.Languages()).
+				Msg("Error message localization enabled")
+			return catalog.Middleware()
+		},
+
+		// JWT access tokens identify API callers and their rate limit tier (if enabled)
+		config.MiddlewareJWTAuth: func() func(http.Handler) http.Handler {
+			if !cfg.JWTAuth.Enabled {
+				return nil
+			}
+			logger.Info().
+				Str("jwks_url", cfg.JWTAuth.JWKSURL).
+				Str("issuer", cfg.JWTAuth.Issuer).
+				Msg("JWT authentication enabled")
+			return middleware.NewJWTAuth(cfg.JWTAuth).Middleware()
+		},
+
+		// Managed API keys identify callers, their tenant and rate limit tier (if enabled)
+		config.MiddlewareAPIKeys: func() func(http.Handler) http.Handler {
+			manager := keys.Default()
+			if manager == nil {
+				return nil
+			}
+			logger.Info().
+				Str("store", cfg.APIKeys.Store).
+				Bool("required", cfg.APIKeys.Required).
+				Msg("Managed API keys enabled")
+			return manager.Middleware()
+		},
+
+		// Abuse detection moves flagged keys to a restricted rate limit tier (if enabled)
+		config.MiddlewareAbuse: func() func(http.Handler) http.Handler {
+			detector := abuse.Default()
+			if detector == nil {
+				return nil
+			}
+			logger.Info().
+				Str("restricted_tier", cfg.AbuseDetection.RestrictedTier).
+				Dur("window", cfg.AbuseDetection.Window).
+				Msg("Abuse detection enabled")
+			return detector.Middleware()
+		},
+
+		// Rate limiting (if enabled)
+		config.MiddlewareRateLimit: func() func(http.Handler) http.Handler {
+			if !cfg.RateLimit.Enabled {
+				return nil
+			}
+			rateLimiter = middleware.NewRateLimiter(cfg.RateLimit)
+			if cfg.QuotaWarnings.Enabled {
+				rateLimiter.SetQuotaWarnings(cfg.QuotaWarnings.Thresholds)
+			}
+			// Limits follow config reloads; requests already admitted are unaffected
+			reload.Default().OnReload(func(old, cur *config.Config) {
+				if !reflect.DeepEqual(old.RateLimit, cur.RateLimit) {
+					rateLimiter.SetLimits(cur.RateLimit)
+				}
+			})
+			logger.Info().
+				Int("requests_per_min", cfg.RateLimit.RequestsPerMin).
+				Int("burst_size", cfg.RateLimit.BurstSize).
+				Msg("Rate limiting enabled")
+			return rateLimiter.RateLimit()
+		},
+
+		// Concurrent streaming responses per API key (enforced by the streaming handler)
+		config.MiddlewareStreamLimit: func() func(http.Handler) http.Handler {
+			if !cfg.StreamLimit.Enabled {
+				return nil
+			}
+			logger.Info().
+				Int("max_per_key", cfg.StreamLimit.MaxPerKey).
+				Msg("Concurrent stream limit enabled")
+			return middleware.NewStreamLimiter(cfg.StreamLimit).Middleware()
+		},
+
+		// CORS (configure as needed for your frontend)
+		config.MiddlewareCORS: func() func(http.Handler) http.Handler {
+			return corsMiddleware
+		},
+
+		// Observability middleware (metrics and tracing)
+		config.MiddlewareObservability: func() func(http.Handler) http.Handler {
+			if !cfg.Observability.Metrics.Enabled && !cfg.Observability.Tracing.Enabled {
+				return nil
+			}
+			logger.Info().
+				Bool("metrics", cfg.Observability.Metrics.Enabled).
+				Bool("tracing", cfg.Observability.Tracing.Enabled).
+				Msg("Observability middleware enabled")
+			return observability.ObservabilityMiddleware(tracer, metrics)
+		},
+
+		// Flight recorder keeps recent request details and dumps them when errors spike
+		config.MiddlewareFlightRecorder: func() func(http.Handler) http.Handler {
+			if recorder == nil {
+				return nil
+			}
+			return recorder.Middleware()
+		},
+
+		// Response compression (if enabled)
+		config.MiddlewareCompression: func() func(http.Handler) http.Handler {
+			if !cfg.Performance.Compression.Enabled {
+				return nil
+			}
+			compressionLevel := cfg.Performance.Compression.Level
+			if compressionLevel == 0 {
+				compressionLevel = gzip.DefaultCompression
+			}
+			compressionConfig := performance.CompressionConfig{
+				Enabled: true,
+				Level:   compressionLevel,
+				MinSize: cfg.Performance.Compression.MinSize,
+				ContentTypes: []string{
+					"application/json",
+					"text/plain",
+					"text/html",
+				},
+			}
+			logger.Info().
+				Int("level", compressionLevel).
+				Int("min_size", cfg.Performance.Compression.MinSize).
+				Msg("Response compression enabled")
+			return performance.CompressionMiddleware(compressionConfig)
+		},
+
+		// Request and response body checksums (if enabled); inside compression,
+		// so responses are hashed as the client reads them once decoded
+		config.MiddlewareIntegrity: func() func(http.Handler) http.Handler {
+			if !cfg.Integrity.Enabled {
+				return nil
+			}
+			logger.Info().
+				Bool("require", cfg.Integrity.Require).
+				Msg("Body checksums enabled")
+			return middleware.NewIntegrity(cfg.Integrity).Middleware()
+		},
+	}
+}
+
+// initObservability initializes the global metrics and tracer, returning
+// those enabled
+func initObservability(cfg *config.Config) (*observability.Metrics, *observability.Tracer) {
+	var metrics *observability.Metrics
+	if cfg.Observability.Metrics.Enabled {
+		metricsConfig := observability.MetricsConfig{
+			Enabled:   true,
+			Path:      cfg.Observability.Metrics.Path,
+			Namespace: cfg.Observability.Metrics.Namespace,
+			Subsystem: "http",
+		}
+		metrics = observability.InitGlobalMetrics(metricsConfig)
+	}
+
+	var tracer *observability.Tracer
+	if cfg.Observability.Tracing.Enabled {
+		tracingConfig := observability.TracingConfig{
+			Enabled:         true,
+			ServiceName:     cfg.Observability.Tracing.ServiceName,
+			SamplingRate:    cfg.Observability.Tracing.SamplingRate,
+			ExporterType:    cfg.Observability.Tracing.ExporterType,
+			ExporterAddress: cfg.Observability.Tracing.ExporterAddress,
+			ExporterHeaders: cfg.Observability.Tracing.ExporterHeaders,
+		}
+		tracer = observability.InitGlobalTracer(tracingConfig)
+	}
+	return metrics, tracer
+}
+
+// initTimeSeries initializes the global time series and samples the
+// requests waiting on providers into them, if they are enabled
+func initTimeSeries(cfg *config.Config, proxyRouter *proxy.Router) {
+	if !cfg.Observability.TimeSeries.Enabled {
+		return
+	}
+	series := observability.InitGlobalTimeSeries(cfg.Observability.TimeSeries.Retention)
+	series.Start(func() int64 {
+		var depth int64
+		for _, entry := range proxyRouter.DrainStatus() {
+			depth += entry["in_flight"].(int64)
+		}
+		return depth
+	})
+	logger.Debug().Dur("retention", cfg.Observability.TimeSeries.Retention).Msg("Time series enabled")
+}
+
+// captureRedactor returns the redaction applied to flight recorder captures:
+// the enabled prompt secret and PII detectors, whatever their action, so
+// captures never hold what the gateway would not send on. It returns nil if
+// neither is enabled.
+func captureRedactor(cfg *config.Config) func(string) string {
+	var redactors []func(string) string
+	if cfg.PromptSecrets.Enabled {
+		if scanner, err := newSecretScanner(cfg.PromptSecrets); err == nil {
+			redactors = append(redactors, scanner.redact)
+		}
+	}
+	if cfg.PII.Enabled {
+		redactors = append(redactors, newPIIMasker(cfg.PII).redact)
+	}
+	if len(redactors) == 0 {
+		return nil
+	}
+	return func(text string) string {
+		for _, redact := range redactors {
+			text = redact(text)
+		}
+		return text
+	}
+}
+
+// initFlightRecorder initializes the global flight recorder, or returns nil if
+// it is disabled
+func initFlightRecorder(cfg *config.Config, proxyRouter *proxy.Router) *observability.FlightRecorder {
+	if !cfg.Observability.FlightRecorder.Enabled {
+		return nil
+	}
+	recorder := observability.InitGlobalFlightRecorder(observability.FlightRecorderConfig{
+		Enabled:            true,
+		Size:               cfg.Observability.FlightRecorder.Size,
+		ErrorRateThreshold: cfg.Observability.FlightRecorder.ErrorRateThreshold,
+		Window:             cfg.Observability.FlightRecorder.Window,
+		MinRequests:        cfg.Observability.FlightRecorder.MinRequests,
+		Cooldown:           cfg.Observability.FlightRecorder.Cooldown,
+		CaptureBodies:      cfg.Observability.FlightRecorder.CaptureBodies,
+		MaxCaptureBytes:    cfg.Observability.FlightRecorder.MaxCaptureBytes,
+	})
+	recorder.SetStateFunc(func() map[string]interface{} {
+		return map[string]interface{}{
+			"reliability": proxyRouter.GetReliabilityStats(),
+			"drain":       proxyRouter.DrainStatus(),
+		}
+	})
+	recorder.SetRedactor(captureRedactor(cfg))
+	privacy.Default().Register("flight_recorder", recorder)
+	logger.Info().
+		Int("size", cfg.Observability.FlightRecorder.Size).
+		Float64("error_rate_threshold", cfg.Observability.FlightRecorder.ErrorRateThreshold).
+		Msg("Flight recorder enabled")
+	return recorder
+}
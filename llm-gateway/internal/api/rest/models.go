@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// GetModel handles GET /v1/models/{model}. Aliases resolve to the model they
+// point to.
+func (h *Handler) GetModel(w http.ResponseWriter, r *http.Request) {
+	id := h.resolveAlias(chi.URLParam(r, "*"))
+	model, ok := h.proxyRouter.Model(id)
+	if !ok || len(allowedModels(r, []models.Model{model})) == 0 {
+		h.writeError(w, http.StatusNotFound, "model_not_found", "The model '"+id+"' does not exist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model)
+}
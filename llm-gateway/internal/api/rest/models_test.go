@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestHandler_GetModel(t *testing.T) {
+	cfg := &config.Config{}
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test"}))
+	h := NewHandler(cfg, proxy.NewRouter(registry, cfg))
+
+	r := chi.NewRouter()
+	r.Get("/v1/models/*", h.GetModel)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models/gpt-4o-mini", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rr.Code, rr.Body)
+	}
+	var model models.Model
+	if err := json.NewDecoder(rr.Body).Decode(&model); err != nil {
+		t.Fatal(err)
+	}
+	if model.ID != "gpt-4o-mini" || model.Object != "model" || model.ContextWindow == 0 || model.Capabilities == nil {
+		t.Errorf("model = %+v", model)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models/org/unknown-model", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown model: status = %d, want 404", rr.Code)
+	}
+}
@@ -0,0 +1,371 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Output filter actions
+const (
+	filterActionMask  = "mask"
+	filterActionAbort = "abort"
+)
+
+// defaultFilterReplacement replaces masked matches unless a rule sets its own
+const defaultFilterReplacement = "[REDACTED]"
+
+// filterRule is a compiled output filter rule
+type filterRule struct {
+	name        string
+	re          *regexp.Regexp
+	action      string
+	replacement string
+}
+
+// outputFilter scans model output for banned patterns such as internal
+// hostnames or secret formats (DLP), masking them or aborting the response
+type outputFilter struct {
+	rules  []filterRule
+	window int
+}
+
+// newOutputFilter compiles the configured rules; a rule's pattern and keywords
+// are combined into one expression
+func newOutputFilter(cfg config.OutputFiltersConfig) (*outputFilter, error) {
+	f := &outputFilter{window: cfg.Window}
+	for _, rule := range cfg.Rules {
+		var parts []string
+		if rule.Pattern != "" {
+			parts = append(parts, "(?:"+rule.Pattern+")")
+		}
+		for _, keyword := range rule.Keywords {
+			parts = append(parts, "(?i:"+regexp.QuoteMeta(keyword)+")")
+		}
+		re, err := regexp.Compile(strings.Join(parts, "|"))
+		if err != nil {
+			return nil, err
+		}
+
+		compiled := filterRule{name: rule.Name, re: re, action: rule.Action, replacement: rule.Replacement}
+		if compiled.action == "" {
+			compiled.action = filterActionMask
+		}
+		if compiled.replacement == "" {
+			compiled.replacement = defaultFilterReplacement
+		}
+		f.rules = append(f.rules, compiled)
+	}
+	return f, nil
+}
+
+// abortRule returns the first abort rule text matches, or ""
+func (f *outputFilter) abortRule(text string) string {
+	for _, rule := range f.rules {
+		if rule.action == filterActionAbort && rule.re.MatchString(text) {
+			observability.GetMetrics().RecordOutputFilterHit(rule.name, rule.action, 1)
+			return rule.name
+		}
+	}
+	return ""
+}
+
+// mask replaces the matches of mask rules in text, counting them per rule
+func (f *outputFilter) mask(text string) string {
+	for _, rule := range f.rules {
+		if rule.action != filterActionMask {
+			continue
+		}
+		if hits := len(rule.re.FindAllStringIndex(text, -1)); hits > 0 {
+			// Unnamed rules restore masked personal data and are not counted
+			if rule.name != "" {
+				observability.GetMetrics().RecordOutputFilterHit(rule.name, rule.action, hits)
+			}
+			text = rule.re.ReplaceAllLiteralString(text, rule.replacement)
+		}
+	}
+	return text
+}
+
+// filterChatResponse applies the filters to a complete chat response. It
+// returns the abort rule the response matched, if any.
+func (f *outputFilter) filterChatResponse(resp *models.ChatCompletionResponse) string {
+	for _, choice := range resp.Choices {
+		if rule := f.abortRule(choice.Message.Content); rule != "" {
+			return rule
+		}
+	}
+	for i := range resp.Choices {
+		resp.Choices[i].Message.Content = f.mask(resp.Choices[i].Message.Content)
+	}
+	return ""
+}
+
+// filterCompletionResponse applies the filters to a complete legacy completion response
+func (f *outputFilter) filterCompletionResponse(resp *models.CompletionResponse) string {
+	for _, choice := range resp.Choices {
+		if rule := f.abortRule(choice.Text); rule != "" {
+			return rule
+		}
+	}
+	for i := range resp.Choices {
+		resp.Choices[i].Text = f.mask(resp.Choices[i].Text)
+	}
+	return ""
+}
+
+// split returns the part of held-back text that can be sent: all but the last
+// window bytes, moved back so no match straddles the cut and no character is split
+func (f *outputFilter) split(text string) (send, keep string) {
+	cut := len(text) - f.window
+	if cut <= 0 {
+		return "", text
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, rule := range f.rules {
+			for _, loc := range rule.re.FindAllStringIndex(text, -1) {
+				if loc[0] < cut && loc[1] > cut {
+					cut, moved = loc[0], true
+				}
+			}
+		}
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut], text[cut:]
+}
+
+// writeOutputFiltered rejects a complete response that matched an abort rule
+func (h *Handler) writeOutputFiltered(w http.ResponseWriter, r *http.Request, rule string) {
+	logOutputFiltered(r, rule)
+	h.writeError(w, http.StatusBadGateway, "output_filtered", "The generated response was blocked by an output filter")
+}
+
+// logOutputFiltered logs a response blocked by an output filter, which counts
+// as a refusal for abuse detection
+func logOutputFiltered(r *http.Request, rule string) {
+	abuse.ObserveRefusal(r.Context())
+	logger.Warn().
+		Str("request_id", chimiddleware.GetReqID(r.Context())).
+		Str("path", r.URL.Path).
+		Str("rule", rule).
+		Msg("Model output blocked by output filter")
+}
+
+// filterStream applies the output filters to an SSE stream. The text of each
+// choice (or Anthropic content block) is held back by the filter's window, so
+// matches spanning chunks are caught, and released when the choice finishes.
+// Chunks are re-encoded generically so fields the gateway does not model pass
+// through.
+type filterStream struct {
+	filter  *outputFilter
+	pending map[int]string
+	// template holds the identifying fields of the last OpenAI chunk, to flush
+	// held text into at the end of a stream that never finished its choices
+	template map[string]interface{}
+	// event is the held "event:" line of the Anthropic event being read, so a
+	// flush can be sent ahead of it
+	event []byte
+}
+
+func newFilterStream(filter *outputFilter) *filterStream {
+	return &filterStream{filter: filter, pending: make(map[int]string)}
+}
+
+// process filters one SSE line. It returns the bytes to forward in its place
+// (nil while a line is held), or the name of the abort rule the stream matched.
+func (s *filterStream) process(line []byte) ([]byte, string) {
+	trimmed := bytes.TrimSpace(line)
+	if bytes.HasPrefix(trimmed, []byte("event:")) {
+		s.event = line
+		return nil, ""
+	}
+	data, ok := bytes.CutPrefix(trimmed, []byte("data:"))
+	if !ok {
+		return s.withEvent(line), ""
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("[DONE]")) {
+		return append(s.flush(), line...), ""
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var event map[string]interface{}
+	if err := decoder.Decode(&event); err != nil {
+		return s.withEvent(line), ""
+	}
+
+	if choices, ok := event["choices"].([]interface{}); ok {
+		return s.processChunk(line, event, choices)
+	}
+	switch event["type"] {
+	case "content_block_delta":
+		return s.processBlockDelta(line, event)
+	case "content_block_stop":
+		index := jsonIndex(event["index"])
+		out := s.flushBlock(index)
+		return append(out, s.withEvent(line)...), ""
+	}
+	return s.withEvent(line), ""
+}
+
+// processChunk filters the delta content of an OpenAI-format chunk
+func (s *filterStream) processChunk(line []byte, event map[string]interface{}, choices []interface{}) ([]byte, string) {
+	s.template = map[string]interface{}{}
+	for _, key := range []string{"id", "object", "created", "model"} {
+		if v, ok := event[key]; ok {
+			s.template[key] = v
+		}
+	}
+
+	changed := false
+	for _, c := range choices {
+		choice, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delta, _ := choice["delta"].(map[string]interface{})
+		content, _ := delta["content"].(string)
+		index := jsonIndex(choice["index"])
+		text := s.pending[index] + content
+		if text == "" {
+			continue
+		}
+		if rule := s.filter.abortRule(text); rule != "" {
+			return nil, rule
+		}
+
+		send, keep := text, ""
+		if choice["finish_reason"] == nil {
+			send, keep = s.filter.split(text)
+		}
+		if keep == "" {
+			delete(s.pending, index)
+		} else {
+			s.pending[index] = keep
+		}
+
+		if delta == nil {
+			delta = map[string]interface{}{}
+			choice["delta"] = delta
+		}
+		delta["content"] = s.filter.mask(send)
+		changed = true
+	}
+	if !changed {
+		return line, ""
+	}
+	return encodeDataLine(event), ""
+}
+
+// processBlockDelta filters the text of an Anthropic text_delta event
+func (s *filterStream) processBlockDelta(line []byte, event map[string]interface{}) ([]byte, string) {
+	delta, _ := event["delta"].(map[string]interface{})
+	if delta == nil || delta["type"] != "text_delta" {
+		return s.withEvent(line), ""
+	}
+	text, _ := delta["text"].(string)
+	index := jsonIndex(event["index"])
+	text = s.pending[index] + text
+	if rule := s.filter.abortRule(text); rule != "" {
+		return nil, rule
+	}
+
+	send, keep := s.filter.split(text)
+	s.pending[index] = keep
+	delta["text"] = s.filter.mask(send)
+	return s.withEvent(encodeDataLine(event)), ""
+}
+
+// flush releases all held text, at the end of the stream
+func (s *filterStream) flush() []byte {
+	indexes := make([]int, 0, len(s.pending))
+	for index := range s.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var out []byte
+	for _, index := range indexes {
+		if s.template == nil {
+			out = append(out, s.flushBlock(index)...)
+			continue
+		}
+		chunk := map[string]interface{}{}
+		for key, v := range s.template {
+			chunk[key] = v
+		}
+		chunk["choices"] = []interface{}{map[string]interface{}{
+			"index":         index,
+			"delta":         map[string]interface{}{"content": s.filter.mask(s.pending[index])},
+			"finish_reason": nil,
+		}}
+		delete(s.pending, index)
+		out = append(out, encodeDataLine(chunk)...)
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// flushBlock releases the held text of an Anthropic content block as a
+// text_delta event
+func (s *filterStream) flushBlock(index int) []byte {
+	text := s.pending[index]
+	delete(s.pending, index)
+	if text == "" {
+		return nil
+	}
+	event := map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]interface{}{"type": "text_delta", "text": s.filter.mask(text)},
+	}
+	out := []byte("event: content_block_delta\n")
+	out = append(out, encodeDataLine(event)...)
+	return append(out, '\n')
+}
+
+// withEvent prefixes line with the held "event:" line, if any
+func (s *filterStream) withEvent(line []byte) []byte {
+	if s.event == nil {
+		return line
+	}
+	out := append(s.event, line...)
+	s.event = nil
+	return out
+}
+
+// encodeDataLine encodes v as an SSE data line
+func encodeDataLine(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return []byte("data: " + string(data) + "\n")
+}
+
+// jsonIndex returns a choice or content block index decoded with UseNumber
+func jsonIndex(v interface{}) int {
+	switch v := v.(type) {
+	case json.Number:
+		index, _ := v.Int64()
+		return int(index)
+	case int:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,184 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func testOutputFilter(t *testing.T) *outputFilter {
+	t.Helper()
+	f, err := newOutputFilter(config.OutputFiltersConfig{
+		Enabled: true,
+		Window:  16,
+		Rules: []config.OutputFilterRule{
+			{Name: "api_keys", Pattern: `sk-[A-Za-z0-9]{8,}`},
+			{Name: "hostnames", Keywords: []string{"db.corp.internal"}, Replacement: "<host>"},
+			{Name: "codename", Keywords: []string{"Project Nightjar"}, Action: "abort"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// streamContent returns the content streamed in SSE output, by choice
+func streamContent(t *testing.T, out []byte) string {
+	t.Helper()
+	var content strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var event struct {
+			models.ChatCompletionStreamResponse
+			Delta struct {
+				Text string `json:"text"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid data line %q: %v", data, err)
+		}
+		for _, choice := range event.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+		content.WriteString(event.Delta.Text)
+	}
+	return content.String()
+}
+
+func TestOutputFilter_CompleteResponses(t *testing.T) {
+	f := testOutputFilter(t)
+
+	resp := &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{
+		{Message: models.ChatMessage{Content: "Use sk-abcdef123456 against DB.CORP.INTERNAL"}},
+	}}
+	if rule := f.filterChatResponse(resp); rule != "" {
+		t.Fatalf("unexpected abort by %s", rule)
+	}
+	if got := resp.Choices[0].Message.Content; got != "Use [REDACTED] against <host>" {
+		t.Errorf("masked content = %q", got)
+	}
+
+	completion := &models.CompletionResponse{Choices: []models.CompletionChoice{
+		{Text: "This is about project nightjar."},
+	}}
+	if rule := f.filterCompletionResponse(completion); rule != "codename" {
+		t.Errorf("abort rule = %q, want codename", rule)
+	}
+}
+
+func TestFilterStream_MasksAcrossChunks(t *testing.T) {
+	s := newFilterStream(testOutputFilter(t))
+
+	var out []byte
+	for _, content := range []string{"Your key is sk-abc", "def123456 and the ", "database is db.corp.", "internal, enjoy the rest of the day"} {
+		line, rule := s.process(chunkLine(content))
+		if rule != "" {
+			t.Fatalf("unexpected abort by %s", rule)
+		}
+		out = append(out, line...)
+	}
+	done, _ := s.process([]byte("data: [DONE]\n"))
+	out = append(out, done...)
+
+	if strings.Contains(string(out), "sk-abc") || strings.Contains(string(out), "corp") {
+		t.Fatalf("secret leaked in stream: %s", out)
+	}
+	want := "Your key is [REDACTED] and the database is <host>, enjoy the rest of the day"
+	if got := streamContent(t, out); got != want {
+		t.Errorf("streamed content = %q, want %q", got, want)
+	}
+	if !strings.HasSuffix(string(out), "data: [DONE]\n") {
+		t.Errorf("stream should end with [DONE], got %s", out)
+	}
+}
+
+func TestFilterStream_FinishReleasesHeldText(t *testing.T) {
+	s := newFilterStream(testOutputFilter(t))
+
+	first, _ := s.process(chunkLine("Hello"))
+	if got := streamContent(t, first); got != "" {
+		t.Errorf("text within the window should be held, got %q", got)
+	}
+	last, _ := s.process([]byte(`data: {"id":"1","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}` + "\n"))
+	if got := streamContent(t, last); got != "Hello world" {
+		t.Errorf("final chunk content = %q, want %q", got, "Hello world")
+	}
+	if !strings.Contains(string(last), `"finish_reason":"stop"`) {
+		t.Errorf("final chunk lost its finish_reason: %s", last)
+	}
+}
+
+func TestFilterStream_AbortsAcrossChunks(t *testing.T) {
+	s := newFilterStream(testOutputFilter(t))
+
+	if _, rule := s.process(chunkLine("As part of Project Night")); rule != "" {
+		t.Fatalf("aborted early by %s", rule)
+	}
+	if _, rule := s.process(chunkLine("jar we will")); rule != "codename" {
+		t.Errorf("abort rule = %q, want codename", rule)
+	}
+}
+
+func TestFilterStream_AnthropicEvents(t *testing.T) {
+	s := newFilterStream(testOutputFilter(t))
+
+	lines := []string{
+		"event: content_block_start\n",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n",
+		"\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"token: sk-1234"}}` + "\n",
+		"\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"56789 done"}}` + "\n",
+		"\n",
+		"event: content_block_stop\n",
+		`data: {"type":"content_block_stop","index":0}` + "\n",
+		"\n",
+	}
+	var out []byte
+	for _, line := range lines {
+		forwarded, rule := s.process([]byte(line))
+		if rule != "" {
+			t.Fatalf("unexpected abort by %s", rule)
+		}
+		out = append(out, forwarded...)
+	}
+
+	if got := streamContent(t, out); got != "token: [REDACTED] done" {
+		t.Errorf("streamed text = %q", got)
+	}
+	// The held text is released before the block stops, each data line after its event line
+	stop := strings.Index(string(out), "event: content_block_stop")
+	lastDelta := strings.LastIndex(string(out), "event: content_block_delta")
+	if stop < 0 || lastDelta > stop {
+		t.Errorf("flushed delta should precede content_block_stop: %s", out)
+	}
+	if strings.Count(string(out), "event: ") != 5 {
+		t.Errorf("expected 5 events, got %s", out)
+	}
+}
+
+func TestOutputFilter_SplitKeepsMatchesWhole(t *testing.T) {
+	f := testOutputFilter(t)
+
+	// The cut (16 bytes from the end) falls inside the key, so the whole key is held
+	text := "prefix text sk-abcdefgh12345 tail"
+	send, keep := f.split(text)
+	if send+keep != text {
+		t.Fatalf("split lost text: %q + %q", send, keep)
+	}
+	if !strings.HasPrefix(keep, "sk-") {
+		t.Errorf("held part = %q, want it to start at the key", keep)
+	}
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy"
+)
+
+// Provider override headers, honoured only for keys with the debug scope
+const (
+	providerHeader        = "X-Provider"
+	providerBaseURLHeader = "X-Provider-Base-URL"
+)
+
+// selectProvider returns the provider for a request: the named provider if
+// name is set, otherwise the one serving model. Debug callers may override the
+// choice with the X-Provider and X-Provider-Base-URL headers; every attempt is
+// written to the audit log. Requests over a spend budget are rejected.
+func (h *Handler) selectProvider(w http.ResponseWriter, r *http.Request, model, name string) (proxy.Provider, error) {
+	if key := keys.FromContext(r.Context()); !key.AllowsModel(model) {
+		return nil, &proxy.ProviderError{
+			StatusCode: http.StatusForbidden,
+			Code:       "model_not_allowed",
+			Message:    "The API key may not use model " + model,
+		}
+	}
+	if err := h.rejectDegraded(w, model); err != nil {
+		return nil, err
+	}
+	provider, err := h.routeProvider(w, r, model, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := admitSpend(w, r, provider.Name(), model); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// resolveAlias returns the model a model alias in the routing table stands
+// for, so the provider, key model allowlists and usage see the real model
+func (h *Handler) resolveAlias(model string) string {
+	if h.proxyRouter == nil {
+		return model
+	}
+	return h.proxyRouter.ResolveAlias(model)
+}
+
+// routeProvider returns the provider chosen by the router, or by a debug
+// caller's override headers
+func (h *Handler) routeProvider(w http.ResponseWriter, r *http.Request, model, name string) (proxy.Provider, error) {
+	override := strings.TrimSpace(r.Header.Get(providerHeader))
+	baseURL := strings.TrimSpace(r.Header.Get(providerBaseURLHeader))
+
+	if !h.config.ProviderOverride.Enabled || (override == "" && baseURL == "") {
+		if name != "" {
+			return h.proxyRouter.GetProvider(name)
+		}
+		provider, err := h.proxyRouter.GetProviderForRequest(r.Context(), model)
+		if err != nil {
+			return nil, err
+		}
+		return h.proxyRouter.WithOverflow(provider, model, middleware.GetRateLimitTier(r.Context())), nil
+	}
+
+	apiKey := middleware.RequestAPIKey(r)
+	details := map[string]interface{}{
+		"request_id": chimiddleware.GetReqID(r.Context()),
+		"client_id":  middleware.MaskAPIKey(apiKey),
+		"provider":   override,
+		"base_url":   baseURL,
+		"model":      model,
+	}
+	audit := func(status string, err error) {
+		details["status"] = status
+		if err != nil {
+			details["error"] = err.Error()
+		}
+		observability.LogAudit(r.Context(), "provider.override", "provider", details)
+	}
+
+	if apiKey == "" || !slices.Contains(h.config.ProviderOverride.DebugKeys, apiKey) {
+		err := overrideForbidden("Provider overrides require an API key with the debug scope")
+		audit("denied", err)
+		return nil, err
+	}
+	if baseURL != "" && !h.baseURLAllowed(baseURL) {
+		err := overrideForbidden("Base URL is not in provider_override.allowed_base_urls")
+		audit("denied", err)
+		return nil, err
+	}
+
+	if override == "" {
+		override = name
+	}
+	provider, err := h.proxyRouter.GetProviderOverride(override, baseURL, model)
+	if err != nil {
+		audit("failure", err)
+		return nil, err
+	}
+
+	details["provider"] = provider.Name()
+	audit("success", nil)
+	w.Header().Set("X-Provider-Override", provider.Name())
+	return provider, nil
+}
+
+// baseURLAllowed reports whether baseURL is an http(s) URL under one of the allowed prefixes
+func (h *Handler) baseURLAllowed(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	for _, prefix := range h.config.ProviderOverride.AllowedBaseURLs {
+		rest, ok := strings.CutPrefix(baseURL, prefix)
+		// Match whole path segments so http://mock does not allow http://mock.evil.com
+		if ok && (rest == "" || strings.HasSuffix(prefix, "/") || strings.HasPrefix(rest, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func overrideForbidden(message string) error {
+	return &proxy.ProviderError{
+		StatusCode: http.StatusForbidden,
+		Code:       "provider_override_forbidden",
+		Message:    message,
+	}
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func newOverrideHandler(allowedBaseURL string) *Handler {
+	cfg := &config.Config{}
+	cfg.ProviderOverride = config.ProviderOverrideConfig{
+		Enabled:         true,
+		DebugKeys:       []string{"debug-key"},
+		AllowedBaseURLs: []string{allowedBaseURL},
+	}
+
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test"}))
+	registry.Register("anthropic", providers.NewAnthropicProvider(providers.AnthropicConfig{APIKey: "sk-ant-test"}))
+	return NewHandler(cfg, proxy.NewRouter(registry, cfg))
+}
+
+func overrideRequest(key, provider, baseURL string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if key != "" {
+		r.Header.Set("Authorization", "Bearer "+key)
+	}
+	if provider != "" {
+		r.Header.Set(providerHeader, provider)
+	}
+	if baseURL != "" {
+		r.Header.Set(providerBaseURLHeader, baseURL)
+	}
+	return r
+}
+
+func TestHandler_selectProvider_Override(t *testing.T) {
+	h := newOverrideHandler("http://localhost:9999")
+
+	rr := httptest.NewRecorder()
+	provider, err := h.selectProvider(rr, overrideRequest("debug-key", "anthropic", ""), "gpt-4o", "")
+	if err != nil || provider.Name() != "anthropic" {
+		t.Fatalf("provider = %v, err = %v; want anthropic", provider, err)
+	}
+	if got := rr.Header().Get("X-Provider-Override"); got != "anthropic" {
+		t.Errorf("X-Provider-Override = %q", got)
+	}
+
+	// Without override headers, routing is unchanged
+	provider, err = h.selectProvider(httptest.NewRecorder(), overrideRequest("debug-key", "", ""), "gpt-4o", "")
+	if err != nil || provider.Name() != "openai" {
+		t.Errorf("provider = %v, err = %v; want openai", provider, err)
+	}
+}
+
+func TestHandler_selectProvider_Denied(t *testing.T) {
+	h := newOverrideHandler("http://localhost:9999")
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string
+	}{
+		{"no key", overrideRequest("", "anthropic", ""), http.StatusForbidden, "provider_override_forbidden"},
+		{"key without debug scope", overrideRequest("other-key", "anthropic", ""), http.StatusForbidden, "provider_override_forbidden"},
+		{"base URL not allowed", overrideRequest("debug-key", "", "http://localhost:9999.evil.com"), http.StatusForbidden, "provider_override_forbidden"},
+		{"unknown provider", overrideRequest("debug-key", "mystery", ""), http.StatusBadRequest, "invalid_provider_override"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.selectProvider(httptest.NewRecorder(), tt.req, "gpt-4o", "")
+			var providerErr *proxy.ProviderError
+			if !errors.As(err, &providerErr) || providerErr.StatusCode != tt.status || providerErr.Code != tt.code {
+				t.Errorf("err = %v, want %d %s", err, tt.status, tt.code)
+			}
+		})
+	}
+
+	// Headers are ignored entirely when overrides are disabled
+	h.config.ProviderOverride.Enabled = false
+	provider, err := h.selectProvider(httptest.NewRecorder(), overrideRequest("", "anthropic", ""), "gpt-4o", "")
+	if err != nil || provider.Name() != "openai" {
+		t.Errorf("provider = %v, err = %v; want openai", provider, err)
+	}
+}
+
+func TestHandler_selectProvider_BaseURL(t *testing.T) {
+	var gotPath string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"mock","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"from mock"},"finish_reason":"stop"}]}`))
+	}))
+	defer mock.Close()
+
+	h := newOverrideHandler(mock.URL)
+	provider, err := h.selectProvider(httptest.NewRecorder(), overrideRequest("debug-key", "", mock.URL+"/v1"), "gpt-4o", "")
+	if err != nil {
+		t.Fatalf("selectProvider: %v", err)
+	}
+
+	resp, err := provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if gotPath != "/v1/chat/completions" || resp.Choices[0].Message.Content != "from mock" {
+		t.Errorf("path = %q, content = %q", gotPath, resp.Choices[0].Message.Content)
+	}
+}
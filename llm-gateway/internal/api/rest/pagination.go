@@ -0,0 +1,233 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPageLimit is the page size when no limit is given
+	defaultPageLimit = 50
+	// maxPageLimit caps the page size a client may ask for
+	maxPageLimit = 500
+)
+
+// listPage is the envelope returned by admin list endpoints
+type listPage struct {
+	Object string                   `json:"object"`
+	Data   []map[string]interface{} `json:"data"`
+	// Total is the number of items matching the filters, across all pages
+	Total      int    `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor marks the last item of a page. It holds the item's sort value and
+// key rather than an offset, so items added or removed between requests do not
+// shift later pages.
+type pageCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	Key   interface{} `json:"k"`
+}
+
+// listQuery holds the pagination, sorting and filtering parameters of a request
+type listQuery struct {
+	limit   int
+	cursor  *pageCursor
+	sort    string
+	field   string
+	desc    bool
+	filters map[string][]string
+}
+
+// parseListQuery reads the list parameters from a request:
+//
+//	limit   page size (default 50, max 500)
+//	cursor  next_cursor of the previous page
+//	sort    field to sort by, "-field" for descending
+//
+// Any other parameter filters on an item field, e.g. ?provider=openai
+// (repeat it to match any of several values).
+func parseListQuery(r *http.Request, defaultSort string) (*listQuery, error) {
+	q := &listQuery{limit: defaultPageLimit, sort: defaultSort, filters: make(map[string][]string)}
+
+	for name, values := range r.URL.Query() {
+		switch name {
+		case "limit":
+			limit, err := strconv.Atoi(values[0])
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid limit: %s", values[0])
+			}
+			q.limit = min(limit, maxPageLimit)
+		case "sort":
+			if values[0] != "" {
+				q.sort = values[0]
+			}
+		case "cursor":
+			if values[0] == "" {
+				continue
+			}
+			raw, err := base64.RawURLEncoding.DecodeString(values[0])
+			if err != nil {
+				return nil, errInvalidCursor
+			}
+			var c pageCursor
+			if err := json.Unmarshal(raw, &c); err != nil {
+				return nil, errInvalidCursor
+			}
+			q.cursor = &c
+		default:
+			q.filters[name] = values
+		}
+	}
+
+	q.field, q.desc = strings.TrimPrefix(q.sort, "-"), strings.HasPrefix(q.sort, "-")
+	if q.cursor != nil && q.cursor.Sort != q.sort {
+		return nil, errors.New("cursor was issued for a different sort order")
+	}
+	return q, nil
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// paginate filters, sorts and pages items (a slice of structs or maps) by the
+// request's list parameters. key names the field that uniquely identifies an item.
+func paginate(r *http.Request, items interface{}, key, defaultSort string) (*listPage, error) {
+	q, err := parseListQuery(r, defaultSort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on generic maps so every endpoint shares one implementation
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var all []map[string]interface{}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+
+	matched := all[:0]
+	for _, item := range all {
+		if q.matches(item) {
+			matched = append(matched, item)
+		}
+	}
+
+	order := func(sortValue, keyValue interface{}, item map[string]interface{}) int {
+		c := compareValues(item[q.field], sortValue)
+		if c == 0 {
+			c = compareValues(item[key], keyValue)
+		}
+		if q.desc {
+			c = -c
+		}
+		return c
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return order(matched[j][q.field], matched[j][key], matched[i]) < 0
+	})
+
+	start := 0
+	if q.cursor != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return order(q.cursor.Value, q.cursor.Key, matched[i]) > 0
+		})
+	}
+	end := min(start+q.limit, len(matched))
+
+	page := &listPage{
+		Object:  "list",
+		Data:    matched[start:end],
+		Total:   len(matched),
+		HasMore: end < len(matched),
+	}
+	if page.HasMore {
+		last := matched[end-1]
+		c, _ := json.Marshal(pageCursor{Sort: q.sort, Value: last[q.field], Key: last[key]})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(c)
+	}
+	return page, nil
+}
+
+// matches reports whether item satisfies every filter
+func (q *listQuery) matches(item map[string]interface{}) bool {
+	for field, values := range q.filters {
+		value, ok := item[field]
+		if !ok {
+			return false
+		}
+		s := fmt.Sprint(value)
+		found := false
+		for _, want := range values {
+			if s == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compareValues orders JSON values: missing values first, then numbers and
+// booleans by value, and anything else by its string form
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case !x:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// writeList writes one page of items, or a 400 for invalid list parameters
+func writeList(w http.ResponseWriter, r *http.Request, items interface{}, key, defaultSort string) {
+	page, err := paginate(r, items, key, defaultSort)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// writeListError writes the error for invalid list parameters
+func writeListError(w http.ResponseWriter, err error) {
+	writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+}
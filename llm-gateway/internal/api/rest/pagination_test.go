@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func testItems() []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, 7)
+	for i := 0; i < 7; i++ {
+		items = append(items, map[string]interface{}{
+			"tenant":   fmt.Sprintf("t%d", i),
+			"requests": (i * 3) % 5,
+			"active":   i%2 == 0,
+		})
+	}
+	return items
+}
+
+func TestPaginate_CursorWalksAllItems(t *testing.T) {
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := paginate(httptest.NewRequest("GET", "/?limit=3&sort=-requests&cursor="+cursor, nil), testItems(), "tenant", "tenant")
+		if err != nil {
+			t.Fatalf("paginate: %v", err)
+		}
+		if page.Total != 7 || page.Object != "list" {
+			t.Fatalf("page = %+v", page)
+		}
+		for _, item := range page.Data {
+			seen = append(seen, item["tenant"].(string))
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	// requests: t0=0 t1=3 t2=1 t3=4 t4=2 t5=0 t6=3; ties broken by tenant, reversed with "-"
+	want := []string{"t3", "t6", "t1", "t4", "t2", "t5", "t0"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("order = %v, want %v", seen, want)
+	}
+}
+
+func TestPaginate_Filters(t *testing.T) {
+	page, err := paginate(httptest.NewRequest("GET", "/?active=true&requests=0&requests=1", nil), testItems(), "tenant", "tenant")
+	if err != nil {
+		t.Fatalf("paginate: %v", err)
+	}
+	if page.Total != 2 || page.Data[0]["tenant"] != "t0" || page.Data[1]["tenant"] != "t2" {
+		t.Errorf("filtered = %v", page.Data)
+	}
+}
+
+func TestPaginate_InvalidParams(t *testing.T) {
+	page, _ := paginate(httptest.NewRequest("GET", "/?limit=2", nil), testItems(), "tenant", "tenant")
+
+	for _, query := range []string{
+		"limit=0",
+		"limit=abc",
+		"cursor=not-a-cursor",
+		"sort=requests&cursor=" + page.NextCursor, // issued for sort=tenant
+	} {
+		if _, err := paginate(httptest.NewRequest("GET", "/?"+query, nil), testItems(), "tenant", "tenant"); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// piiDetector finds one kind of personal data; valid, if set, rejects
+// matches that fail the format's checksum or numbering rules
+type piiDetector struct {
+	name  string
+	label string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// piiDetectors run in this order, so card numbers and national IDs are
+// masked before the looser phone pattern sees their digits
+var piiDetectors = []piiDetector{
+	{name: "credit_card", label: "CREDIT_CARD", re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: validCardNumber},
+	// US social security numbers and UK national insurance numbers
+	{name: "national_id", label: "NATIONAL_ID", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`), valid: validNationalID},
+	{name: "email", label: "EMAIL", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)},
+	// The whole run of digits is matched, so a longer number is not masked in part
+	{name: "phone", label: "PHONE", re: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?|\b)\d(?:[ .-]?\d)+\b`), valid: validPhone},
+}
+
+// piiMasker replaces personal data in prompts with numbered placeholders,
+// such as [EMAIL_1], before the prompt leaves the gateway
+type piiMasker struct {
+	detectors []piiDetector
+	restore   bool
+}
+
+func newPIIMasker(cfg config.PIIConfig) *piiMasker {
+	m := &piiMasker{restore: cfg.Restore}
+	for _, d := range piiDetectors {
+		if len(cfg.Detectors) == 0 || slices.Contains(cfg.Detectors, d.name) {
+			m.detectors = append(m.detectors, d)
+		}
+	}
+	return m
+}
+
+// piiVault maps the placeholders of one request to the values they replaced
+type piiVault struct {
+	placeholders map[string]string
+	originals    map[string]string
+	// found counts the values masked per detector
+	found map[string]int
+	next  map[string]int
+}
+
+// mask replaces personal data in texts in place, giving a value the same
+// placeholder wherever it appears. It returns nil if nothing was found.
+func (m *piiMasker) mask(texts []*string) *piiVault {
+	v := &piiVault{
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		found:        make(map[string]int),
+		next:         make(map[string]int),
+	}
+	for _, text := range texts {
+		for _, d := range m.detectors {
+			*text = d.re.ReplaceAllStringFunc(*text, func(match string) string {
+				if d.valid != nil && !d.valid(match) {
+					return match
+				}
+				v.found[d.name]++
+				return v.placeholder(d.label, match)
+			})
+		}
+	}
+	if len(v.found) == 0 {
+		return nil
+	}
+	return v
+}
+
+// redact replaces personal data in text with unnumbered placeholders, such
+// as [EMAIL], for text kept by the gateway itself
+func (m *piiMasker) redact(text string) string {
+	for _, d := range m.detectors {
+		text = d.re.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			return "[" + d.label + "]"
+		})
+	}
+	return text
+}
+
+// placeholder returns the placeholder of value, numbering a new one by label
+func (v *piiVault) placeholder(label, value string) string {
+	if p, ok := v.placeholders[value]; ok {
+		return p
+	}
+	v.next[label]++
+	p := fmt.Sprintf("[%s_%d]", label, v.next[label])
+	v.placeholders[value] = p
+	v.originals[p] = value
+	return p
+}
+
+// restore replaces the placeholders in text with their original values
+func (v *piiVault) restore(text string) string {
+	pairs := make([]string, 0, 2*len(v.originals))
+	for p, value := range v.originals {
+		pairs = append(pairs, p, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// restoreChatResponse restores the placeholders in a complete chat response
+func (v *piiVault) restoreChatResponse(resp *models.ChatCompletionResponse) {
+	for i := range resp.Choices {
+		resp.Choices[i].Message.Content = v.restore(resp.Choices[i].Message.Content)
+		if translation := resp.Choices[i].Translation; translation != nil {
+			translation.Content = v.restore(translation.Content)
+		}
+	}
+}
+
+// streamFilter returns base with a mask rule per placeholder appended, so a
+// stream's placeholders are restored even when split across chunks. The
+// rules are unnamed so they are not counted as output filter hits, and run
+// after base so restored values are not taken for banned output.
+func (v *piiVault) streamFilter(base *outputFilter) *outputFilter {
+	f := &outputFilter{}
+	if base != nil {
+		f.rules = append(f.rules, base.rules...)
+		f.window = base.window
+	}
+	placeholders := make([]string, 0, len(v.originals))
+	for p := range v.originals {
+		placeholders = append(placeholders, p)
+	}
+	sort.Strings(placeholders)
+	for _, p := range placeholders {
+		f.rules = append(f.rules, filterRule{
+			re:          regexp.MustCompile(regexp.QuoteMeta(p)),
+			action:      filterActionMask,
+			replacement: v.originals[p],
+		})
+		f.window = max(f.window, len(p))
+	}
+	return f
+}
+
+// piiVaultContextKey is the context key of a request's piiVault
+type piiVaultContextKey struct{}
+
+// piiVaultFrom returns the placeholders to restore in a request's response, or nil
+func piiVaultFrom(ctx context.Context) *piiVault {
+	v, _ := ctx.Value(piiVaultContextKey{}).(*piiVault)
+	return v
+}
+
+// maskPII masks personal data in a request's prompt texts in place.
+// Detections are audited by detector and count, never with the values. If
+// placeholders are to be restored, it returns r carrying them.
+func (h *Handler) maskPII(r *http.Request, texts ...*string) *http.Request {
+	if h.pii == nil {
+		return r
+	}
+	vault := h.pii.mask(texts)
+	if vault == nil {
+		return r
+	}
+
+	detectors := make([]string, 0, len(vault.found))
+	for name, count := range vault.found {
+		detectors = append(detectors, name)
+		observability.GetMetrics().RecordPIIMasked(name, count)
+	}
+	sort.Strings(detectors)
+
+	requestID := chimiddleware.GetReqID(r.Context())
+	logger.Debug().
+		Str("request_id", requestID).
+		Str("path", r.URL.Path).
+		Strs("detectors", detectors).
+		Msg("Personal data masked in prompt")
+	observability.LogAudit(r.Context(), "prompt.pii_masked", "prompt", map[string]interface{}{
+		"request_id": requestID,
+		"client_id":  middleware.MaskAPIKey(middleware.RequestAPIKey(r)),
+		"path":       r.URL.Path,
+		"detections": vault.found,
+	})
+
+	if !h.pii.restore {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), piiVaultContextKey{}, vault))
+}
+
+// validCardNumber reports whether a card number has 13 to 19 digits and a
+// valid Luhn checksum
+func validCardNumber(s string) bool {
+	digits := onlyDigits(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validNationalID reports whether s is a social security number with an
+// assignable area, group and serial, or a national insurance number with an
+// allocated prefix
+func validNationalID(s string) bool {
+	if strings.Contains(s, "-") {
+		area, group, serial := s[0:3], s[4:6], s[7:11]
+		return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+	}
+	switch s[:2] {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return true
+}
+
+// ssnShape matches a number written like a social security number
+var ssnShape = regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)
+
+// validPhone reports whether s has the digits of a phone number and is
+// written like one, with a country code, area code or separators. Numbers
+// shaped like social security numbers are left to the national_id detector.
+func validPhone(s string) bool {
+	digits := onlyDigits(s)
+	return len(digits) >= 9 && len(digits) <= 15 && strings.ContainsAny(s, "+( .-") && !ssnShape.MatchString(s)
+}
+
+// onlyDigits returns the ASCII digits of s
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestPIIMasker_MasksValidValues(t *testing.T) {
+	m := newPIIMasker(config.PIIConfig{Enabled: true})
+
+	text := "Mail jane.doe@example.com or call +1 (555) 123-4567. Card 4111 1111 1111 1111, " +
+		"SSN 123-45-6789, NI AB 12 34 56 C. Again: jane.doe@example.com"
+	v := m.mask([]*string{&text})
+	if v == nil {
+		t.Fatal("expected personal data to be found")
+	}
+	want := "Mail [EMAIL_1] or call [PHONE_1]. Card [CREDIT_CARD_1], " +
+		"SSN [NATIONAL_ID_1], NI [NATIONAL_ID_2]. Again: [EMAIL_1]"
+	if text != want {
+		t.Errorf("masked text = %q, want %q", text, want)
+	}
+	if v.found["email"] != 2 || v.found["national_id"] != 2 || v.found["credit_card"] != 1 || v.found["phone"] != 1 {
+		t.Errorf("found = %v", v.found)
+	}
+}
+
+func TestPIIMasker_SkipsInvalidValues(t *testing.T) {
+	m := newPIIMasker(config.PIIConfig{Enabled: true})
+
+	// A card number failing the Luhn check, an unassigned SSN area, a date
+	// and an order number without phone separators
+	text := "Card 4111 1111 1111 1112, SSN 666-45-6789, on 2026-10-16, order 12345"
+	if v := m.mask([]*string{&text}); v != nil {
+		t.Errorf("nothing should be masked, got %q (%v)", text, v.found)
+	}
+}
+
+func TestPIIMasker_Detectors(t *testing.T) {
+	m := newPIIMasker(config.PIIConfig{Enabled: true, Detectors: []string{"email"}})
+
+	text := "jane@example.com, 4111111111111111"
+	m.mask([]*string{&text})
+	if text != "[EMAIL_1], 4111111111111111" {
+		t.Errorf("masked text = %q", text)
+	}
+}
+
+func TestValidCardNumber(t *testing.T) {
+	tests := map[string]bool{
+		"4111111111111111":    true,
+		"5500-0000-0000-0004": true,
+		"4111111111111112":    false,
+		"411111111111":        false,
+	}
+	for number, want := range tests {
+		if got := validCardNumber(number); got != want {
+			t.Errorf("validCardNumber(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestPIIVault_Restore(t *testing.T) {
+	m := newPIIMasker(config.PIIConfig{Enabled: true, Restore: true})
+	text := "Write to jane@example.com"
+	v := m.mask([]*string{&text})
+
+	resp := &models.ChatCompletionResponse{Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Content: "I wrote to [EMAIL_1]."}}}}
+	v.restoreChatResponse(resp)
+	if got := resp.Choices[0].Message.Content; got != "I wrote to jane@example.com." {
+		t.Errorf("restored content = %q", got)
+	}
+}
+
+func TestPIIVault_RestoresAcrossChunks(t *testing.T) {
+	m := newPIIMasker(config.PIIConfig{Enabled: true, Restore: true})
+	text := "Write to jane@example.com"
+	v := m.mask([]*string{&text})
+	s := newFilterStream(v.streamFilter(nil))
+
+	var out []byte
+	for _, content := range []string{"Sent to [EMA", "IL_1] just", " now, thanks"} {
+		line, _ := s.process(chunkLine(content))
+		out = append(out, line...)
+	}
+	done, _ := s.process([]byte("data: [DONE]\n"))
+	out = append(out, done...)
+
+	if want := "Sent to jane@example.com just now, thanks"; streamContent(t, out) != want {
+		t.Errorf("streamed content = %q, want %q", streamContent(t, out), want)
+	}
+}
+
+func TestHandler_MaskPII(t *testing.T) {
+	h := NewHandler(&config.Config{PII: config.PIIConfig{Enabled: true, Restore: true}}, nil)
+	messages := []models.ChatMessage{{Role: "user", Content: "My card is 4111-1111-1111-1111"}}
+
+	r := h.maskPII(httptest.NewRequest("POST", "/v1/chat/completions", nil), messageTexts(messages)...)
+	if strings.Contains(messages[0].Content, "4111") {
+		t.Errorf("card number sent to provider: %q", messages[0].Content)
+	}
+	v := piiVaultFrom(r.Context())
+	if v == nil {
+		t.Fatal("request should carry the placeholders to restore")
+	}
+	if got := v.restore(messages[0].Content); got != "My card is 4111-1111-1111-1111" {
+		t.Errorf("restored = %q", got)
+	}
+}
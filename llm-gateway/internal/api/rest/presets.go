@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// parameterPreset returns the preset called name for the request's route: the
+// route's own preset of that name, or the default one. It writes an error and
+// returns false if there is none.
+func (h *Handler) parameterPreset(w http.ResponseWriter, r *http.Request, name string) (config.ParameterPreset, bool) {
+	cfg := h.config.ParameterPresets
+	if !cfg.Enabled {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "parameter presets are not enabled")
+		return config.ParameterPreset{}, false
+	}
+
+	// Preset names are case-insensitive, as configuration keys are
+	key := strings.ToLower(name)
+	route := cfg.Routes[strings.TrimSuffix(r.URL.Path, "/")]
+	preset, ok := route[key]
+	if !ok {
+		preset, ok = cfg.Presets[key]
+	}
+	if !ok {
+		h.writeError(w, http.StatusBadRequest, "invalid_request",
+			"unknown preset "+name+" (available: "+strings.Join(presetNames(cfg.Presets, route), ", ")+")")
+		return config.ParameterPreset{}, false
+	}
+
+	if span := observability.SpanFromContext(r.Context()); span != nil {
+		span.SetAttribute("llm.request.preset", key)
+	}
+	logger.Debug().Str("preset", key).Str("route", r.URL.Path).Msg("Applied parameter preset")
+	return preset, true
+}
+
+// presetNames lists the presets available on a route, sorted
+func presetNames(presets, route map[string]config.ParameterPreset) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, m := range []map[string]config.ParameterPreset{presets, route} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fillFloat sets *dst to v when the request left it unset
+func fillFloat(dst **float64, v *float64) {
+	if *dst == nil && v != nil {
+		value := *v
+		*dst = &value
+	}
+}
+
+// fillPenalty sets *dst to v when the request left it at zero
+func fillPenalty(dst *float64, v *float64) {
+	if *dst == 0 && v != nil {
+		*dst = *v
+	}
+}
+
+// applyChatPreset expands the parameter preset a chat request selects.
+// Parameters the request sets itself take precedence. It returns false after
+// writing an error.
+func (h *Handler) applyChatPreset(w http.ResponseWriter, r *http.Request, req *models.ChatCompletionRequest) bool {
+	if req.Preset == "" {
+		return true
+	}
+	preset, ok := h.parameterPreset(w, r, req.Preset)
+	if !ok {
+		return false
+	}
+	fillFloat(&req.Temperature, preset.Temperature)
+	fillFloat(&req.TopP, preset.TopP)
+	fillPenalty(&req.PresencePenalty, preset.PresencePenalty)
+	fillPenalty(&req.FrequencyPenalty, preset.FrequencyPenalty)
+	req.Preset = ""
+	return true
+}
+
+// applyCompletionPreset expands the parameter preset a completion request selects
+func (h *Handler) applyCompletionPreset(w http.ResponseWriter, r *http.Request, req *models.CompletionRequest) bool {
+	if req.Preset == "" {
+		return true
+	}
+	preset, ok := h.parameterPreset(w, r, req.Preset)
+	if !ok {
+		return false
+	}
+	fillFloat(&req.Temperature, preset.Temperature)
+	fillFloat(&req.TopP, preset.TopP)
+	fillPenalty(&req.PresencePenalty, preset.PresencePenalty)
+	fillPenalty(&req.FrequencyPenalty, preset.FrequencyPenalty)
+	req.Preset = ""
+	return true
+}
+
+// applyAnthropicPreset expands the parameter preset an Anthropic request
+// selects; the Messages API has no penalties, so only temperature and top_p apply
+func (h *Handler) applyAnthropicPreset(w http.ResponseWriter, r *http.Request, req *models.AnthropicMessageRequest) bool {
+	if req.Preset == "" {
+		return true
+	}
+	preset, ok := h.parameterPreset(w, r, req.Preset)
+	if !ok {
+		return false
+	}
+	fillFloat(&req.Temperature, preset.Temperature)
+	fillFloat(&req.TopP, preset.TopP)
+	req.Preset = ""
+	return true
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestHandler_applyPreset(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	cfg := &config.Config{ParameterPresets: config.ParameterPresetsConfig{
+		Enabled: true,
+		Presets: map[string]config.ParameterPreset{
+			"creative": {Temperature: f(1.2), TopP: f(0.95), PresencePenalty: f(0.6)},
+			"precise":  {Temperature: f(0.1)},
+		},
+		Routes: map[string]map[string]config.ParameterPreset{
+			"/v1/completions": {"creative": {Temperature: f(0.9)}},
+		},
+	}}
+	h := NewHandler(cfg, nil)
+
+	req := &models.ChatCompletionRequest{Preset: "Creative", TopP: f(0.5)}
+	rr := httptest.NewRecorder()
+	if !h.applyChatPreset(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), req) {
+		t.Fatalf("applyChatPreset failed: %s", rr.Body.String())
+	}
+	if *req.Temperature != 1.2 || req.PresencePenalty != 0.6 {
+		t.Errorf("temperature = %v, presence_penalty = %v", *req.Temperature, req.PresencePenalty)
+	}
+	if *req.TopP != 0.5 {
+		t.Errorf("top_p = %v, the request's own value should win", *req.TopP)
+	}
+	if req.Preset != "" {
+		t.Error("preset should not be forwarded to the provider")
+	}
+
+	// The route's preset replaces the default one of the same name
+	completion := &models.CompletionRequest{Preset: "creative"}
+	if !h.applyCompletionPreset(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/completions", nil), completion) {
+		t.Fatal("applyCompletionPreset failed")
+	}
+	if *completion.Temperature != 0.9 || completion.TopP != nil {
+		t.Errorf("completion preset = %v, %v, want the route's preset", *completion.Temperature, completion.TopP)
+	}
+
+	anthropicReq := &models.AnthropicMessageRequest{Preset: "precise"}
+	if !h.applyAnthropicPreset(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/messages", nil), anthropicReq) {
+		t.Fatal("applyAnthropicPreset failed")
+	}
+	if *anthropicReq.Temperature != 0.1 {
+		t.Errorf("anthropic temperature = %v", *anthropicReq.Temperature)
+	}
+
+	rr = httptest.NewRecorder()
+	if h.applyChatPreset(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), &models.ChatCompletionRequest{Preset: "wild"}) || rr.Code != 400 {
+		t.Fatalf("unknown preset: status %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "available: creative, precise") {
+		t.Errorf("error should list the presets: %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	disabled := NewHandler(&config.Config{}, nil)
+	if disabled.applyChatPreset(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), &models.ChatCompletionRequest{Preset: "creative"}) || rr.Code != 400 {
+		t.Errorf("preset with presets disabled: status %d", rr.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/privacy"
+)
+
+// DeleteUserData handles POST /admin/v1/privacy/delete ({"user": "..."}). It
+// removes everything stored about an end user and returns the signed report.
+func (h *AdminHandler) DeleteUserData(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		User string `json:"user"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	eraser := privacy.Default()
+	if eraser == nil {
+		writeJSONError(w, http.StatusConflict, "privacy_not_configured", privacy.ErrNoSigningKey.Error())
+		return
+	}
+	actor := middleware.GetUserID(r.Context())
+	report, err := eraser.Delete(req.User, actor)
+	switch {
+	case errors.Is(err, privacy.ErrNoSigningKey):
+		writeJSONError(w, http.StatusConflict, "privacy_not_configured", err.Error())
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	// The audit entry references the user by hash, so the log does not keep
+	// the identifier that was just erased
+	observability.LogAudit(r.Context(), "privacy.delete", "end_user", map[string]interface{}{
+		"report_id":      report.ID,
+		"subject_sha256": privacy.SubjectHash(req.User),
+		"deleted":        report.Deleted,
+		"actor":          actor,
+	})
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// anthropicUserID returns metadata.user_id of an Anthropic request, if set
+func anthropicUserID(metadata interface{}) string {
+	if m, ok := metadata.(map[string]interface{}); ok {
+		if userID, ok := m["user_id"].(string); ok {
+			return userID
+		}
+	}
+	return ""
+}
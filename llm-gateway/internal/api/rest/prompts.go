@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/analytics"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// observePrompt fingerprints the messages of a chat request for the prompt
+// statistics. Roles are part of the text, so the same words sent as a system
+// prompt and as a user message are told apart.
+func observePrompt(r *http.Request, model, user string, messages []models.ChatMessage) {
+	if analytics.Default() == nil {
+		return
+	}
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(msg.Role)
+		b.WriteString(": ")
+		b.WriteString(msg.Text())
+		b.WriteByte('\n')
+	}
+	analytics.ObservePrompt(r.Context(), model, user, b.String())
+}
+
+// GetPromptStats handles GET /admin/v1/prompt-stats: the prompts repeated over
+// prompt_stats.window, most frequent first, with the totals of the window
+func (h *AdminHandler) GetPromptStats(w http.ResponseWriter, r *http.Request) {
+	stats := analytics.Default()
+	if stats == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Prompt statistics are not enabled")
+		return
+	}
+	report := stats.Report()
+	page, err := paginate(r, report.Groups, "fingerprint", "-requests")
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*listPage
+		Window           string  `json:"window"`
+		Requests         int64   `json:"requests"`
+		RepeatedRequests int64   `json:"repeated_requests"`
+		RepeatedCost     float64 `json:"repeated_cost"`
+	}{page, report.Window, report.Requests, report.RepeatedRequests, report.RepeatedCost})
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/performance"
+)
+
+// requestQueue holds the queue API requests are admitted through, or nil if
+// performance.queue is disabled
+var requestQueue *performance.RequestQueue
+
+// queueSlot is an API request waiting in the request queue. A worker
+// starts it and stays busy until the request is done, so the workers bound
+// the requests handled at once.
+type queueSlot struct {
+	start chan struct{}
+	done  chan struct{}
+}
+
+// runQueueSlot is the queue's processor: it lets the request through and
+// waits for it to finish
+func runQueueSlot(_ context.Context, payload interface{}) (interface{}, error) {
+	slot := payload.(*queueSlot)
+	close(slot.start)
+	<-slot.done
+	return nil, nil
+}
+
+// newRequestQueue creates the request queue from performance.queue, with
+// the queue weights of the rate limit tiers, or returns nil if it is disabled
+func newRequestQueue(cfg *config.Config) *performance.RequestQueue {
+	qc := cfg.Performance.Queue
+	if !qc.Enabled {
+		return nil
+	}
+	weights := make(map[string]int, len(cfg.RateLimit.Tiers))
+	for name, tier := range cfg.RateLimit.Tiers {
+		weights[name] = tier.QueueWeight
+	}
+	return performance.NewRequestQueue(performance.QueueConfig{
+		Enabled:         true,
+		MaxQueueSize:    qc.MaxQueueSize,
+		MaxWaitTime:     qc.MaxWaitTime,
+		WorkerCount:     qc.WorkerCount,
+		PriorityEnabled: qc.PriorityEnabled,
+		FairQueuing:     qc.FairQueuing,
+		TierWeights:     weights,
+	}, runQueueSlot)
+}
+
+// queueMiddleware admits requests through q: at most worker_count are
+// handled at once, and the others wait for a worker as their tenant's,
+// weighted by their rate limit tier. A request the queue cannot hold, or
+// that waits past maxWait, gets 503. GET requests, which list or look up
+// resources or open WebSocket sessions, are not queued, so they never hold a
+// worker for a session's lifetime.
+func queueMiddleware(q *performance.RequestQueue, maxWait time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			slot := &queueSlot{start: make(chan struct{}), done: make(chan struct{})}
+			// Frees the worker, or lets a worker skip the request if it
+			// starts after the request gave up
+			defer close(slot.done)
+
+			ctx := performance.WithTenant(r.Context(), middleware.TenantID(r), middleware.GetRateLimitTier(r.Context()))
+			ctx, cancel := context.WithTimeout(ctx, maxWait)
+			defer cancel()
+			queued := make(chan error, 1)
+			go func() {
+				_, err := q.Enqueue(ctx, chimiddleware.GetReqID(r.Context()), performance.PriorityNormal, slot)
+				queued <- err
+			}()
+
+			select {
+			case <-slot.start:
+			case err := <-queued:
+				select {
+				case <-slot.start:
+					// A worker took the request as it gave up waiting
+				default:
+					writeQueueError(w, r, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeQueueError answers a request the queue did not admit
+func writeQueueError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, performance.ErrQueueFull):
+		w.Header().Set("Retry-After", "1")
+		writeJSONError(w, http.StatusServiceUnavailable, "queue_full", "Too many requests are waiting; retry shortly")
+	case errors.Is(err, performance.ErrQueueClosed):
+		writeJSONError(w, http.StatusServiceUnavailable, "shutting_down", "The gateway is shutting down")
+	case r.Context().Err() != nil:
+		// The client went away while waiting
+	case errors.Is(err, performance.ErrRequestExpired), errors.Is(err, context.DeadlineExceeded):
+		writeJSONError(w, http.StatusServiceUnavailable, "queue_timeout", "The request waited too long for a worker")
+	}
+}
@@ -1,120 +1,76 @@
 package rest
 
 import (
-	"compress/gzip"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/rs/zerolog/log"
 
+	"github.com/username/llm-gateway/internal/analytics"
+	"github.com/username/llm-gateway/internal/canary"
 	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/controlplane"
+	"github.com/username/llm-gateway/internal/cost"
+	"github.com/username/llm-gateway/internal/keys"
 	"github.com/username/llm-gateway/internal/middleware"
 	"github.com/username/llm-gateway/internal/observability"
-	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/privacy"
 	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/retention"
 )
 
+// logger is the api module logger; its level can be set via log.modules.api
+var logger = observability.ModuleLogger("api")
+
 // rateLimiter holds the global rate limiter instance
 var rateLimiter *middleware.RateLimiter
 
 // NewRouter creates and configures a new Chi router with all routes and middleware
 func NewRouter(cfg *config.Config, proxyRouter *proxy.Router) http.Handler {
+	api, _ := newRouters(cfg, proxyRouter, false)
+	return api
+}
+
+// NewRouters returns the public API handler and, when server.admin_port is set, a
+// separate handler for health, metrics and admin routes. Without an admin port the
+// second handler is nil and every route is served by the first.
+func NewRouters(cfg *config.Config, proxyRouter *proxy.Router) (http.Handler, http.Handler) {
+	api, ops := newRouters(cfg, proxyRouter, cfg.Server.AdminPort != 0)
+	if ops == nil {
+		return api, nil
+	}
+	return api, ops
+}
+
+func newRouters(cfg *config.Config, proxyRouter *proxy.Router, separateOps bool) (*chi.Mux, *chi.Mux) {
 	r := chi.NewRouter()
 
 	// ============================================
 	// Global Middleware Stack
 	// ============================================
 
-	// Request ID for tracing
-	r.Use(chimiddleware.RequestID)
-
-	// Real IP extraction (for reverse proxy setups)
-	r.Use(chimiddleware.RealIP)
-
-	// Custom structured logging with zerolog
-	r.Use(middleware.Logger())
-
-	// Panic recovery
-	r.Use(chimiddleware.Recoverer)
-
-	// Request timeout (configurable)
-	r.Use(chimiddleware.Timeout(cfg.Server.WriteTimeout))
-
-	// Rate limiting (if enabled)
-	if cfg.RateLimit.Enabled {
-		rateLimiter = middleware.NewRateLimiter(cfg.RateLimit)
-		r.Use(rateLimiter.RateLimit())
-		log.Info().
-			Int("requests_per_min", cfg.RateLimit.RequestsPerMin).
-			Int("burst_size", cfg.RateLimit.BurstSize).
-			Msg("Rate limiting enabled")
+	// Middleware run in server.middleware order, outermost first; those left
+	// out of the list are not used
+	stack := globalMiddleware(cfg, proxyRouter)
+	for _, name := range cfg.Server.MiddlewareOrder() {
+		if mw := stack[name](); mw != nil {
+			r.Use(mw)
+		}
 	}
 
-	// CORS (configure as needed for your frontend)
-	r.Use(corsMiddleware)
-
-	// Observability middleware (metrics and tracing)
-	if cfg.Observability.Metrics.Enabled || cfg.Observability.Tracing.Enabled {
-		// Initialize metrics if enabled
-		var metrics *observability.Metrics
-		if cfg.Observability.Metrics.Enabled {
-			metricsConfig := observability.MetricsConfig{
-				Enabled:   true,
-				Path:      cfg.Observability.Metrics.Path,
-				Namespace: cfg.Observability.Metrics.Namespace,
-				Subsystem: "http",
-			}
-			metrics = observability.InitGlobalMetrics(metricsConfig)
-		}
-
-		// Initialize tracer if enabled
-		var tracer *observability.Tracer
-		if cfg.Observability.Tracing.Enabled {
-			tracingConfig := observability.TracingConfig{
-				Enabled:      true,
-				ServiceName:  cfg.Observability.Tracing.ServiceName,
-				SamplingRate: cfg.Observability.Tracing.SamplingRate,
-				ExporterType: cfg.Observability.Tracing.ExporterType,
-			}
-			tracer = observability.InitGlobalTracer(tracingConfig)
-		}
-
-		// Add combined observability middleware
-		r.Use(observability.ObservabilityMiddleware(tracer, metrics))
-		log.Info().
-			Bool("metrics", cfg.Observability.Metrics.Enabled).
-			Bool("tracing", cfg.Observability.Tracing.Enabled).
-			Msg("Observability middleware enabled")
-	}
-
-	// Response compression (if enabled)
-	if cfg.Performance.Compression.Enabled {
-		compressionLevel := cfg.Performance.Compression.Level
-		if compressionLevel == 0 {
-			compressionLevel = gzip.DefaultCompression
-		}
-		compressionConfig := performance.CompressionConfig{
-			Enabled: true,
-			Level:   compressionLevel,
-			MinSize: cfg.Performance.Compression.MinSize,
-			ContentTypes: []string{
-				"application/json",
-				"text/plain",
-				"text/html",
-			},
-		}
-		r.Use(performance.CompressionMiddleware(compressionConfig))
-		log.Info().
-			Int("level", compressionLevel).
-			Int("min_size", cfg.Performance.Compression.MinSize).
-			Msg("Response compression enabled")
+	// Health, metrics and admin routes move to their own listener when an admin port is set
+	ops := r
+	if separateOps {
+		ops = newOpsRouter(cfg.Server)
+		// Keep health checks on the public port for load balancers
+		r.Get("/health", healthHandler)
+		r.Get("/ready", readyHandler(proxyRouter))
 	}
 
 	// ============================================
 	// Health & Metrics Endpoints (no auth required)
 	// ============================================
-	r.Group(func(r chi.Router) {
+	ops.Group(func(r chi.Router) {
 		r.Get("/health", healthHandler)
 		r.Get("/ready", readyHandler(proxyRouter))
 		// Use real metrics handler if available
@@ -125,15 +81,228 @@ func NewRouter(cfg *config.Config, proxyRouter *proxy.Router) http.Handler {
 		}
 	})
 
+	// Maintenance switch (rejects API traffic with 503 while enabled)
+	maintenance := middleware.NewMaintenanceMode(cfg.Maintenance)
+
+	// Per-tenant usage for the current day, shown on the admin dashboard
+	usage := middleware.NewUsageTracker()
+	if cfg.QuotaWarnings.Enabled {
+		usage.SetQuotaWarnings(cfg.QuotaWarnings.Thresholds)
+	}
+
+	// Tenant token budgets are distributed by the control plane (if enabled)
+	controlplane.Default().OnUpdate(func(payload *controlplane.Payload) {
+		usage.SetBudgets(payload.Budgets)
+	})
+	controlplane.Default().OnCanary(func(payload *controlplane.Payload) {
+		if payload == nil {
+			usage.SetCanaryBudgets(nil)
+			return
+		}
+		usage.SetCanaryBudgets(payload.Budgets)
+	})
+
+	// Per-user deletion requests scrub end users from the usage counters
+	privacy.Default().Register("usage", usage)
+	if stats := analytics.Default(); stats != nil {
+		privacy.Default().Register("prompt_stats", stats)
+	}
+
+	// With retention enabled, earlier days are kept until their retention period ends
+	if retainer := retention.Default(); retainer != nil {
+		usage.KeepHistory()
+		retainer.Register("usage", usage)
+	}
+
+	// Traffic mirroring to a staging gateway (if enabled)
+	var mirror *middleware.Mirror
+	if cfg.Mirror.Enabled {
+		mirror = middleware.NewMirror(cfg.Mirror)
+		logger.Info().
+			Str("target_url", cfg.Mirror.TargetURL).
+			Float64("sample_rate", cfg.Mirror.SampleRate).
+			Msg("Request mirroring enabled")
+	}
+
+	// Blob references in message content (if enabled)
+	var blobs *middleware.BlobResolver
+	if cfg.Blobs.Enabled {
+		blobs = middleware.NewBlobResolver(cfg.Blobs)
+		privacy.Default().Register("blobs", blobs)
+		logger.Info().
+			Int64("max_blob_bytes", cfg.Blobs.MaxBlobBytes).
+			Bool("s3", cfg.Blobs.S3Endpoint != "").
+			Msg("Blob references enabled")
+	}
+
+	// ============================================
+	// Admin Routes (admin API key required)
+	// ============================================
+	if cfg.Admin.Enabled {
+		if len(cfg.Admin.APIKeys) == 0 {
+			logger.Warn().Msg("Admin API enabled but no admin API keys configured, admin routes disabled")
+		} else {
+			ops.Route("/admin/v1", func(r chi.Router) {
+				r.Use(middleware.Auth(adminAuthConfig(cfg.Admin)))
+
+				ah := NewAdminHandler(proxyRouter, maintenance, usage)
+
+				r.Get("/maintenance", ah.GetMaintenance)
+				r.Put("/maintenance", ah.SetMaintenance)
+				r.Get("/degraded", ah.GetDegraded)
+				r.Put("/degraded", ah.SetDegraded)
+				r.Get("/providers", ah.ListProviders)
+				r.Put("/providers/{provider}", ah.SetProviderEnabled)
+				r.Post("/providers/{provider}/drain", ah.DrainProvider)
+				r.Delete("/providers/{provider}/drain", ah.UndrainProvider)
+				r.Get("/credentials", ah.GetCredentials)
+				r.Get("/usage", ah.GetUsage)
+				r.Get("/audit", ah.GetAudit)
+				r.Post("/providers/{provider}/credentials/flip", ah.FlipCredentials)
+				r.Put("/providers/{provider}/credentials/standby", ah.SetStandbyCredential)
+				r.Get("/providers/{provider}/prefetch", ah.GetPrefetch)
+				r.Post("/providers/{provider}/prefetch", ah.StartPrefetch)
+				r.Get("/providers/{provider}/instances", ah.GetInstances)
+				r.Post("/providers/{provider}/models/refresh", ah.RefreshModels)
+				r.Get("/outbound-limits", ah.GetOutboundLimits)
+				r.Get("/config-keys", ah.GetConfigKeys)
+				r.Get("/config/reload", ah.GetConfigReload)
+				r.Post("/config/reload", ah.ReloadConfig)
+				r.Get("/leader", ah.GetLeader)
+				r.Get("/cache", ah.GetCache)
+				r.Delete("/cache", ah.FlushCache)
+				r.Get("/circuit-breakers", ah.GetCircuitBreakers)
+				r.Post("/providers/{provider}/circuit-breaker/reset", ah.ResetCircuitBreaker)
+				r.Get("/rate-limits", ah.GetRateLimits)
+				r.Post("/queue/drain", ah.DrainQueue)
+				r.Get("/mirror", mirrorStatsHandler(mirror))
+				r.Get("/models/resolve", ah.ResolveModel)
+				r.Get("/models/conflicts", ah.GetModelConflicts)
+				r.Get("/routes", ah.GetRoutes)
+				r.Get("/ttft-slos", ah.GetTTFTSLOs)
+				r.Get("/ttft-slos/decisions", ah.GetTTFTSLODecisions)
+				r.Delete("/ttft-slos/{id}/reroute", ah.RevertTTFTSLO)
+				r.Get("/control-plane", ah.GetControlPlane)
+				r.Post("/control-plane/sync", ah.SyncControlPlane)
+				r.Get("/canary", ah.GetCanary)
+				r.Post("/canary/promote", ah.PromoteCanary)
+				r.Post("/canary/rollback", ah.RollbackCanary)
+				r.Get("/retention", ah.GetRetention)
+				r.Post("/retention/purge", ah.PurgeRetention)
+				r.Delete("/tenants/{tenant}", ah.DeleteTenant)
+				r.Post("/privacy/delete", ah.DeleteUserData)
+				r.Get("/prompt-stats", ah.GetPromptStats)
+				r.Get("/sla-reports", ah.ListSLAReports)
+				r.Get("/sla-reports/current", ah.GetCurrentSLAReport)
+				r.Get("/sla-reports/{id}", ah.GetSLAReport)
+				r.Get("/styles", ah.ListStyles)
+				r.Get("/styles/{style}", ah.GetStyle)
+				r.Delete("/styles/{style}", ah.DeleteStyle)
+				r.Post("/styles/{style}/versions", ah.AddStyleVersion)
+				r.Put("/styles/{style}/rollout", ah.SetStyleRollout)
+				r.Get("/log-level", ah.GetLogLevel)
+				r.Put("/log-level", ah.SetLogLevel)
+				r.Get("/timeseries", ah.GetTimeSeries)
+				r.Get("/flight-recorder", ah.GetFlightRecorder)
+				r.Post("/flight-recorder/dump", ah.DumpFlightRecorder)
+				r.Get("/requests/{id}/bundle", ah.GetRequestBundle)
+				r.Get("/dashboard", ah.GetDashboard)
+				r.Get("/abuse/restrictions", ah.GetAbuseRestrictions)
+				r.Delete("/abuse/restrictions/{id}", ah.LiftAbuseRestriction)
+				r.Get("/abuse/activity", ah.GetAbuseActivity)
+				r.Get("/ignored-fields", ah.GetIgnoredFields)
+				r.Get("/keys", ah.ListKeys)
+				r.Post("/keys", ah.CreateKey)
+				r.Get("/keys/{id}", ah.GetKey)
+				r.Patch("/keys/{id}", ah.UpdateKey)
+				r.Delete("/keys/{id}", ah.DeleteKey)
+				r.Get("/spend", ah.ListSpend)
+				r.Get("/spend/forecast", ah.ListSpendForecasts)
+				r.Get("/spend/anomalies", ah.ListSpendAnomalies)
+			})
+
+			// The dashboard page is static; its data comes from /admin/v1/dashboard with the admin key
+			ops.Get("/admin/ui", dashboardUIHandler)
+			logger.Info().Msg("Admin API enabled")
+		}
+	}
+
+	// ============================================
+	// Analytics Routes (usage export or admin API key required)
+	// ============================================
+	if cfg.UsageExport.Enabled {
+		exportAuth := middleware.DefaultAuthConfig()
+		exportAuth.Enabled = true
+		if cfg.Admin.Enabled {
+			exportAuth = adminAuthConfig(cfg.Admin)
+		}
+		for _, key := range cfg.UsageExport.APIKeys {
+			exportAuth.ValidKeys[key] = "analytics"
+		}
+
+		if len(exportAuth.ValidKeys) == 0 {
+			logger.Warn().Msg("Usage export enabled but no API keys configured, export disabled")
+		} else {
+			ops.With(middleware.Auth(exportAuth)).Get("/analytics/v1/usage", usageExportHandler(usage, cfg.UsageExport))
+			logger.Info().
+				Int("min_users", cfg.UsageExport.MinUsers).
+				Float64("epsilon", cfg.UsageExport.Epsilon).
+				Msg("Usage export enabled")
+		}
+	}
+
+	// API requests wait for a worker of the request queue, when enabled. A
+	// rebuilt router replaces the queue; the previous one stops once it has
+	// finished the requests it admitted.
+	if requestQueue != nil {
+		go requestQueue.Close()
+	}
+	requestQueue = newRequestQueue(cfg)
+
 	// ============================================
 	// API v1 Routes
 	// ============================================
 	r.Route("/v1", func(r chi.Router) {
+		if series := observability.GetTimeSeries(); series != nil {
+			r.Use(series.Middleware())
+		}
+		if cfg.JWTAuth.Enabled {
+			r.Use(middleware.RequireUser())
+		}
+		r.Use(keys.Default().Enforce(cfg.APIKeys.Required))
+		r.Use(maintenance.Middleware())
+		r.Use(requestLimits(cfg.RequestLimits))
+		if requestQueue != nil {
+			r.Use(queueMiddleware(requestQueue, cfg.Performance.Queue.MaxWaitTime))
+		}
+		if mirror != nil {
+			r.Use(mirror.Middleware())
+		}
+		r.Use(canary.Default().Middleware())
+		r.Use(usage.Middleware())
+		r.Use(cost.Default().Middleware())
+		r.Use(analytics.Default().Middleware())
+		r.Use(attemptTimeline)
+		if blobs != nil {
+			r.Use(blobs.Middleware())
+			r.Use(resolvedRequestLimits(cfg.RequestLimits))
+			// Upload large prompts once and reference them by ID
+			r.Post("/files", blobs.UploadHandler)
+		}
+
 		// Create handler with dependencies
 		h := NewHandler(cfg, proxyRouter)
+		if h.transcripts != nil {
+			privacy.Default().Register("transcripts", h.transcripts)
+		}
 
 		// Chat completions (OpenAI-compatible)
 		r.Post("/chat/completions", h.ChatCompletions)
+		// The same, streamed over a WebSocket
+		r.Get("/chat/completions/ws", h.ChatCompletionsWebSocket)
+
+		// Messages assembled from streams requested with assemble: true
+		r.Get("/responses/{id}", h.GetResponse)
 
 		// Legacy completions endpoint
 		r.Post("/completions", h.Completions)
@@ -141,21 +310,81 @@ func NewRouter(cfg *config.Config, proxyRouter *proxy.Router) http.Handler {
 		// Embeddings
 		r.Post("/embeddings", h.Embeddings)
 
+		// Prompt linting, without calling a provider
+		if cfg.PromptLint.Enabled {
+			r.Post("/lint", h.Lint)
+		}
+
 		// Models listing
 		r.Get("/models", h.ListModels)
+		// A single model; IDs may contain slashes
+		r.Get("/models/*", h.GetModel)
+
+		// The caller's spend and budgets
+		r.Get("/usage", h.Usage)
 	})
 
 	// ============================================
 	// Anthropic-style Routes (optional compatibility)
 	// ============================================
 	r.Route("/v1/messages", func(r chi.Router) {
+		if series := observability.GetTimeSeries(); series != nil {
+			r.Use(series.Middleware())
+		}
+		if cfg.JWTAuth.Enabled {
+			r.Use(middleware.RequireUser())
+		}
+		r.Use(keys.Default().Enforce(cfg.APIKeys.Required))
+		r.Use(maintenance.Middleware())
+		r.Use(requestLimits(cfg.RequestLimits))
+		if requestQueue != nil {
+			r.Use(queueMiddleware(requestQueue, cfg.Performance.Queue.MaxWaitTime))
+		}
+		if mirror != nil {
+			r.Use(mirror.Middleware())
+		}
+		r.Use(canary.Default().Middleware())
+		r.Use(usage.Middleware())
+		r.Use(cost.Default().Middleware())
+		r.Use(analytics.Default().Middleware())
+		r.Use(attemptTimeline)
+		if blobs != nil {
+			r.Use(blobs.Middleware())
+			r.Use(resolvedRequestLimits(cfg.RequestLimits))
+		}
+
 		h := NewHandler(cfg, proxyRouter)
 		r.Post("/", h.AnthropicMessages)
 	})
 
+	if !separateOps {
+		return r, nil
+	}
+	return r, ops
+}
+
+// newOpsRouter creates the router for the internal health/metrics/admin listener
+func newOpsRouter(server config.ServerConfig) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID(server.RequestIDFormat))
+	r.Use(middleware.RealIP(server.TrustedProxies))
+	r.Use(middleware.Logger())
+	r.Use(chimiddleware.Recoverer)
 	return r
 }
 
+// adminAuthConfig builds the auth configuration guarding the admin API
+func adminAuthConfig(cfg config.AdminConfig) middleware.AuthConfig {
+	authConfig := middleware.DefaultAuthConfig()
+	authConfig.Enabled = true
+	// Admins act on behalf of tenants
+	authConfig.TrustTenantHeader = true
+	for _, key := range cfg.APIKeys {
+		authConfig.ValidKeys[key] = "admin"
+	}
+	return authConfig
+}
+
 // corsMiddleware handles CORS headers
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -194,6 +423,13 @@ func readyHandler(proxyRouter *proxy.Router) http.HandlerFunc {
 			return
 		}
 
+		// Hold traffic until providers finish warming up (e.g. Ollama model prefetch)
+		if pending := proxyRouter.PendingProviders(); len(pending) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"not_ready","reason":"providers warming up","providers":` + formatProviders(pending) + `}`))
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ready","providers":` + formatProviders(providers) + `}`))
 	}
@@ -0,0 +1,349 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/performance"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+)
+
+func testRouterConfig(adminPort int) *config.Config {
+	cfg := &config.Config{}
+	cfg.Server.Port = 8080
+	cfg.Server.AdminPort = adminPort
+	cfg.Server.WriteTimeout = 10 * time.Second
+	cfg.Observability.Metrics.Enabled = true
+	cfg.Observability.Metrics.Path = "/metrics"
+	cfg.Admin.Enabled = true
+	cfg.Admin.APIKeys = []string{"admin-key"}
+	return cfg
+}
+
+func status(t *testing.T, h http.Handler, path string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestNewRouters_SharedPort(t *testing.T) {
+	cfg := testRouterConfig(0)
+	api, ops := NewRouters(cfg, proxy.NewRouter(providers.NewRegistry(), cfg))
+
+	if ops != nil {
+		t.Fatal("ops handler should be nil without an admin port")
+	}
+	for _, path := range []string{"/health", "/metrics", "/admin/v1/maintenance"} {
+		if code := status(t, api, path); code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200 on the shared port", path, code)
+		}
+	}
+}
+
+func TestNewRouters_SeparateAdminPort(t *testing.T) {
+	cfg := testRouterConfig(9090)
+	api, ops := NewRouters(cfg, proxy.NewRouter(providers.NewRegistry(), cfg))
+
+	if ops == nil {
+		t.Fatal("ops handler should be returned when admin_port is set")
+	}
+	for _, path := range []string{"/health", "/metrics", "/admin/v1/maintenance", "/admin/ui"} {
+		if code := status(t, ops, path); code != http.StatusOK {
+			t.Errorf("ops GET %s = %d, want 200", path, code)
+		}
+	}
+	for _, path := range []string{"/metrics", "/admin/v1/maintenance", "/admin/ui"} {
+		if code := status(t, api, path); code != http.StatusNotFound {
+			t.Errorf("public GET %s = %d, want 404", path, code)
+		}
+	}
+	if code := status(t, api, "/health"); code != http.StatusOK {
+		t.Errorf("public GET /health = %d, want 200 for load balancers", code)
+	}
+}
+
+func TestNewRouters_UsageExportKeys(t *testing.T) {
+	cfg := testRouterConfig(0)
+	cfg.UsageExport = config.UsageExportConfig{Enabled: true, APIKeys: []string{"analytics-key"}, MinUsers: 5}
+	api, _ := NewRouters(cfg, proxy.NewRouter(providers.NewRegistry(), cfg))
+
+	get := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := get("/analytics/v1/usage", "analytics-key"); code != http.StatusOK {
+		t.Errorf("export with analytics key = %d, want 200", code)
+	}
+	if code := get("/analytics/v1/usage?min_users=2", "analytics-key"); code != http.StatusBadRequest {
+		t.Errorf("export below the configured threshold = %d, want 400", code)
+	}
+	if code := get("/admin/v1/usage", "analytics-key"); code != http.StatusUnauthorized {
+		t.Errorf("admin usage with analytics key = %d, want 401", code)
+	}
+}
+
+func TestNewRouters_MiddlewareOrder(t *testing.T) {
+	encoding := func(middleware []string) string {
+		cfg := testRouterConfig(0)
+		cfg.Performance.Compression.Enabled = true
+		cfg.Server.Middleware = middleware
+		api, _ := NewRouters(cfg, proxy.NewRouter(providers.NewRegistry(), cfg))
+
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET /health = %d, want 200", rr.Code)
+		}
+		return rr.Header().Get("Content-Encoding")
+	}
+
+	if got := encoding(nil); got != "gzip" {
+		t.Errorf("default chain Content-Encoding = %q, want gzip", got)
+	}
+	withoutCompression := []string{config.MiddlewareRequestID, config.MiddlewareRecoverer, config.MiddlewareCORS}
+	if got := encoding(withoutCompression); got != "" {
+		t.Errorf("chain without compression Content-Encoding = %q, want none", got)
+	}
+}
+
+func TestNewRouters_RuntimeOps(t *testing.T) {
+	cfg := testRouterConfig(0)
+	cfg.Reliability.CircuitBreaker.Enabled = true
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test"}))
+	proxyRouter := proxy.NewRouter(registry, cfg)
+	api, _ := NewRouters(cfg, proxyRouter)
+
+	do := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-key")
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/admin/v1/providers", "", http.StatusOK},
+		{http.MethodGet, "/admin/v1/circuit-breakers", "", http.StatusOK},
+		{http.MethodPost, "/admin/v1/providers/openai/circuit-breaker/reset", "", http.StatusOK},
+		{http.MethodPost, "/admin/v1/providers/unknown/circuit-breaker/reset", "", http.StatusNotFound},
+		{http.MethodGet, "/admin/v1/rate-limits", "", http.StatusOK},
+		{http.MethodPost, "/admin/v1/queue/drain", "", http.StatusOK},
+		{http.MethodGet, "/admin/v1/mirror", "", http.StatusOK},
+		{http.MethodPost, "/admin/v1/queue/drain?provider=unknown", "", http.StatusNotFound},
+		{http.MethodDelete, "/admin/v1/cache", "", http.StatusNotFound},
+		{http.MethodPut, "/admin/v1/providers/unknown", `{"enabled": false}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := do(tt.method, tt.path, tt.body); code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, code, tt.want)
+		}
+	}
+
+	if code := do(http.MethodPut, "/admin/v1/providers/openai", `{"enabled": false}`); code != http.StatusOK || !proxyRouter.IsDrained("openai") {
+		t.Errorf("disabling openai = %d, drained %v", code, proxyRouter.IsDrained("openai"))
+	}
+	if code := do(http.MethodPut, "/admin/v1/providers/openai", `{"enabled": true}`); code != http.StatusOK || proxyRouter.IsDrained("openai") {
+		t.Errorf("enabling openai = %d, drained %v", code, proxyRouter.IsDrained("openai"))
+	}
+}
+
+func TestNewRouters_RequestQueue(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testRouterConfig(0)
+	cfg.Performance.Queue = config.QueueConfig{Enabled: true, MaxQueueSize: 1, MaxWaitTime: 5 * time.Second, WorkerCount: 1, FairQueuing: true}
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: upstream.URL}))
+	api, _ := NewRouters(cfg, proxy.NewRouter(registry, cfg))
+	t.Cleanup(func() {
+		requestQueue.Close()
+		requestQueue = nil
+	})
+
+	chat := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`))
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	codes := make(chan int, 2)
+	go func() { codes <- chat() }()
+	<-started
+
+	// The only worker is busy, so the next request waits as its tenant's
+	go func() { codes <- chat() }()
+	for requestQueue.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if depths := requestQueue.Stats()["tenant_queue_lengths"].(map[string]int); depths["anonymous"] != 1 {
+		t.Errorf("tenant queue lengths = %v, want one anonymous request", depths)
+	}
+	select {
+	case <-started:
+		t.Fatal("a queued request reached the provider while the worker was busy")
+	default:
+	}
+
+	// A full queue turns requests away
+	if code := chat(); code != http.StatusServiceUnavailable {
+		t.Errorf("request over the queue size = %d, want 503", code)
+	}
+
+	close(release)
+	for range 2 {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("queued request = %d, want 200", code)
+		}
+	}
+}
+
+func TestNewRouters_RequestQueueTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testRouterConfig(0)
+	cfg.Performance.Queue = config.QueueConfig{Enabled: true, MaxQueueSize: 10, MaxWaitTime: 50 * time.Millisecond, WorkerCount: 1}
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: upstream.URL}))
+	NewRouters(cfg, proxy.NewRouter(registry, cfg))
+	previous := requestQueue
+	api, _ := NewRouters(cfg, proxy.NewRouter(registry, cfg))
+	t.Cleanup(func() {
+		requestQueue.Close()
+		requestQueue = nil
+	})
+
+	// Rebuilding the router stops the previous queue
+	done := make(chan struct{})
+	close(done)
+	for {
+		slot := &queueSlot{start: make(chan struct{}), done: done}
+		if _, err := previous.Enqueue(context.Background(), "late", performance.PriorityNormal, slot); errors.Is(err, performance.ErrQueueClosed) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+	chat := `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`
+	first := make(chan int, 1)
+	go func() { first <- serve(http.MethodPost, "/v1/chat/completions", chat).Code }()
+	<-started
+
+	// The only worker is busy past max_wait_time
+	rr := serve(http.MethodPost, "/v1/chat/completions", chat)
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), "queue_timeout") {
+		t.Errorf("waiting request = %d %s, want 503 queue_timeout", rr.Code, rr.Body.String())
+	}
+	if n := requestQueue.Len(); n != 0 {
+		t.Errorf("queue length = %d after the request timed out, want 0", n)
+	}
+
+	// GET requests do not wait for a worker
+	if rr := serve(http.MethodGet, "/v1/models", ""); rr.Code != http.StatusOK {
+		t.Errorf("GET /v1/models = %d while the worker is busy, want 200", rr.Code)
+	}
+
+	close(release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first request = %d, want 200", code)
+	}
+}
+
+func TestNewRouters_RequestLimitsCoverBlobs(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testRouterConfig(0)
+	cfg.RequestLimits = config.RequestLimitsConfig{Enabled: true, MaxContentChars: 20}
+	cfg.Blobs = config.BlobConfig{Enabled: true, MaxBlobBytes: 1 << 10, FileTTL: time.Hour, MaxFiles: 10}
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: upstream.URL}))
+	api, _ := NewRouters(cfg, proxy.NewRouter(registry, cfg))
+
+	chatWithBlob := func(content string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/files", strings.NewReader(content)))
+		var file struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &file); err != nil || file.ID == "" {
+			t.Fatalf("upload = %d %s", rr.Code, rr.Body.String())
+		}
+		body := `{"model":"gpt-4o","messages":[{"role":"user","content":{"$blob":"` + file.ID + `"}}]}`
+		rr = httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		return rr
+	}
+
+	if rr := chatWithBlob(strings.Repeat("x", 100)); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "content_too_long") {
+		t.Errorf("oversized blob = %d %s, want 400 content_too_long", rr.Code, rr.Body.String())
+	}
+	if rr := chatWithBlob("Hi"); rr.Code != http.StatusOK {
+		t.Errorf("small blob = %d %s, want 200", rr.Code, rr.Body.String())
+	}
+}
+
+func TestMirrorStatsHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	mirrorStatsHandler(nil)(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/mirror", nil))
+	if !strings.Contains(rr.Body.String(), `"enabled":false`) {
+		t.Errorf("without mirroring = %s", rr.Body.String())
+	}
+
+	mirror := middleware.NewMirror(config.MirrorConfig{Enabled: true, TargetURL: "http://staging.invalid", SampleRate: 1})
+	rr = httptest.NewRecorder()
+	mirrorStatsHandler(mirror)(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/mirror", nil))
+	var stats map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats["enabled"] != true || stats["target_url"] != "http://staging.invalid" || stats["mirrored"] != 0.0 {
+		t.Errorf("mirror stats = %v", stats)
+	}
+}
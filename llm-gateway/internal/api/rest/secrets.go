@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/abuse"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Prompt secret actions
+const (
+	secretActionBlock  = "block"
+	secretActionRedact = "redact"
+	secretActionAllow  = "allow"
+)
+
+// builtinSecretDetectors are the credential formats detected by default
+var builtinSecretDetectors = map[string]string{
+	"aws_access_key": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"aws_secret_key": `(?i)aws_secret_access_key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`,
+	"private_key":    `(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`,
+	"bearer_token":   `(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{20,}=*`,
+	"github_token":   `\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	"slack_token":    `\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+}
+
+// secretDetector is a compiled credential pattern
+type secretDetector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// secretScanner finds credentials in prompts so they can be blocked or
+// redacted before the prompt leaves the gateway
+type secretScanner struct {
+	detectors    []secretDetector
+	action       string
+	overrideKeys []string
+}
+
+// newSecretScanner compiles the enabled built-in detectors and the configured
+// patterns, in name order
+func newSecretScanner(cfg config.PromptSecretsConfig) (*secretScanner, error) {
+	patterns := make(map[string]string)
+	for name, pattern := range builtinSecretDetectors {
+		if len(cfg.Detectors) == 0 || slices.Contains(cfg.Detectors, name) {
+			patterns[name] = pattern
+		}
+	}
+	for _, name := range cfg.Detectors {
+		if _, ok := builtinSecretDetectors[name]; !ok {
+			logger.Warn().Str("detector", name).Msg("Unknown prompt secret detector ignored")
+		}
+	}
+	for name, pattern := range cfg.Patterns {
+		patterns[name] = pattern
+	}
+
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := &secretScanner{action: cfg.Action, overrideKeys: cfg.OverrideKeys}
+	for _, name := range names {
+		re, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("prompt secret detector %q: %w", name, err)
+		}
+		s.detectors = append(s.detectors, secretDetector{name: name, re: re})
+	}
+	return s, nil
+}
+
+// promptScan collects the secrets found in the texts of one request
+type promptScan struct {
+	scanner *secretScanner
+	redact  bool
+	found   map[string]int
+}
+
+// redact replaces each secret in text with the placeholder naming its
+// detector, whatever the action, for text kept by the gateway itself
+func (s *secretScanner) redact(text string) string {
+	for _, detector := range s.detectors {
+		text = detector.re.ReplaceAllLiteralString(text, "[REDACTED:"+detector.name+"]")
+	}
+	return text
+}
+
+// text scans one prompt text, replacing each secret with a placeholder
+// naming its detector when redacting
+func (p *promptScan) text(text *string) {
+	for _, detector := range p.scanner.detectors {
+		hits := len(detector.re.FindAllStringIndex(*text, -1))
+		if hits == 0 {
+			continue
+		}
+		p.found[detector.name] += hits
+		if p.redact {
+			*text = detector.re.ReplaceAllLiteralString(*text, "[REDACTED:"+detector.name+"]")
+		}
+	}
+}
+
+// messages scans the content and tool call arguments of chat messages
+func (p *promptScan) messages(messages []models.ChatMessage) {
+	for i := range messages {
+		for _, text := range messages[i].TextFields() {
+			p.text(text)
+		}
+		for j := range messages[i].ToolCalls {
+			p.text(&messages[i].ToolCalls[j].Function.Arguments)
+		}
+	}
+}
+
+// embeddingInput scans an embedding input, a string or a list of strings
+func (p *promptScan) embeddingInput(req *models.EmbeddingRequest) {
+	switch input := req.Input.(type) {
+	case string:
+		p.text(&input)
+		req.Input = input
+	case []interface{}:
+		for i, v := range input {
+			if text, ok := v.(string); ok {
+				p.text(&text)
+				input[i] = text
+			}
+		}
+	}
+}
+
+// checkPromptSecrets scans a request's prompt texts, passed to collect, for
+// credentials. Detections are audited by detector name and count, never with
+// the secret itself. Keys with the security scope are let through unchanged;
+// otherwise the request is blocked or the secrets are redacted in place. It
+// returns false if an error response was written.
+func (h *Handler) checkPromptSecrets(w http.ResponseWriter, r *http.Request, collect func(*promptScan)) bool {
+	if h.secrets == nil {
+		return true
+	}
+
+	apiKey := middleware.RequestAPIKey(r)
+	action := h.secrets.action
+	if apiKey != "" && slices.Contains(h.secrets.overrideKeys, apiKey) {
+		action = secretActionAllow
+	}
+	scan := &promptScan{scanner: h.secrets, redact: action == secretActionRedact, found: make(map[string]int)}
+	collect(scan)
+	if len(scan.found) == 0 {
+		return true
+	}
+
+	detectors := make([]string, 0, len(scan.found))
+	for name, count := range scan.found {
+		detectors = append(detectors, name)
+		observability.GetMetrics().RecordPromptSecrets(name, action, count)
+	}
+	sort.Strings(detectors)
+
+	requestID := chimiddleware.GetReqID(r.Context())
+	logger.Warn().
+		Str("request_id", requestID).
+		Str("path", r.URL.Path).
+		Strs("detectors", detectors).
+		Str("action", action).
+		Msg("Credentials detected in prompt")
+	observability.LogAudit(r.Context(), "prompt.secret_detected", "prompt", map[string]interface{}{
+		"request_id": requestID,
+		"client_id":  middleware.MaskAPIKey(apiKey),
+		"path":       r.URL.Path,
+		"detections": scan.found,
+		"status":     action,
+	})
+
+	if action == secretActionBlock {
+		abuse.ObserveRefusal(r.Context())
+		h.writeError(w, http.StatusBadRequest, "prompt_contains_secret",
+			"The prompt contains credentials ("+strings.Join(detectors, ", ")+"); remove them and retry")
+		return false
+	}
+	return true
+}
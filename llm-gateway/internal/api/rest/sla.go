@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/llm-gateway/internal/sla"
+)
+
+// slaCollector returns the SLA collector, writing an error if reports are disabled
+func slaCollector(w http.ResponseWriter) *sla.Collector {
+	collector := sla.Default()
+	if collector == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "SLA reports are not enabled")
+	}
+	return collector
+}
+
+// writeSLAReport writes a report as JSON, or as a plain text table with ?format=text
+func writeSLAReport(w http.ResponseWriter, r *http.Request, report *sla.Report) {
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sla.FormatText(report)))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ListSLAReports handles GET /admin/v1/sla-reports, newest first by default
+func (h *AdminHandler) ListSLAReports(w http.ResponseWriter, r *http.Request) {
+	collector := slaCollector(w)
+	if collector == nil {
+		return
+	}
+	reports, err := collector.Reports(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeList(w, r, reports, "id", "-start")
+}
+
+// GetCurrentSLAReport handles GET /admin/v1/sla-reports/current?period=daily:
+// the figures of the period in progress so far
+func (h *AdminHandler) GetCurrentSLAReport(w http.ResponseWriter, r *http.Request) {
+	collector := slaCollector(w)
+	if collector == nil {
+		return
+	}
+	period := r.URL.Query().Get("period")
+	switch period {
+	case "":
+		period = sla.PeriodDaily
+	case sla.PeriodDaily, sla.PeriodWeekly:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "period must be daily or weekly")
+		return
+	}
+	report, err := collector.Current(r.Context(), period)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeSLAReport(w, r, report)
+}
+
+// GetSLAReport handles GET /admin/v1/sla-reports/{id}
+func (h *AdminHandler) GetSLAReport(w http.ResponseWriter, r *http.Request) {
+	collector := slaCollector(w)
+	if collector == nil {
+		return
+	}
+	report, ok, err := collector.Report(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "not_found", "SLA report not found")
+		return
+	}
+	writeSLAReport(w, r, report)
+}
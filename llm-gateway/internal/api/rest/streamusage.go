@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/cost"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/sla"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// streamUsage accumulates the token usage of a streamed chat completion from
+// its SSE chunks. Usage reported by the provider in the final chunk
+// (stream_options.include_usage, which the Ollama and Anthropic providers
+// build from their own counts) is preferred. Streams without it are estimated
+// from the prompt and the streamed text.
+type streamUsage struct {
+	// forward is set when the client asked for usage itself; otherwise the
+	// usage-only chunk the gateway requested is dropped
+	forward bool
+
+	promptTokens     int
+	completionTokens int
+	reported         bool
+	contentBytes     int
+	chunks           int
+}
+
+// usageChunk holds the fields of a chunk that carry usage or text
+type usageChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *models.Usage `json:"usage"`
+}
+
+// process accounts one SSE line and reports whether it should be dropped
+func (u *streamUsage) process(line []byte) bool {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return false
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return false
+	}
+	var chunk usageChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return false
+	}
+	u.chunks++
+
+	for _, choice := range chunk.Choices {
+		u.contentBytes += len(choice.Delta.Content)
+	}
+
+	if chunk.Usage == nil {
+		return false
+	}
+	u.promptTokens = chunk.Usage.PromptTokens
+	u.completionTokens = chunk.Usage.CompletionTokens
+	u.reported = true
+	return !u.forward && len(chunk.Choices) == 0
+}
+
+// totals returns the stream's token counts and whether they were estimated
+func (u *streamUsage) totals(req *models.ChatCompletionRequest) (promptTokens, completionTokens int, estimated bool) {
+	if u.reported {
+		return u.promptTokens, u.completionTokens, false
+	}
+	return conversationTokens(req.Messages), (u.contentBytes + 3) / 4, true
+}
+
+// tokensSoFar returns the tokens of the stream so far: the reported usage,
+// else promptTokens and an estimate of the content sent
+func (u *streamUsage) tokensSoFar(promptTokens int) int {
+	if u.reported {
+		return u.promptTokens + u.completionTokens
+	}
+	return promptTokens + (u.contentBytes+3)/4
+}
+
+// recordStreamUsage records the tokens of a finished stream and logs them
+func (h *Handler) recordStreamUsage(r *http.Request, providerName string, req *models.ChatCompletionRequest, usage *streamUsage) {
+	if usage.chunks == 0 {
+		return
+	}
+	promptTokens, completionTokens, estimated := usage.totals(req)
+	h.recordUsage(r.Context(), providerName, req.Model, promptTokens, completionTokens)
+
+	logger.Info().
+		Str("request_id", chimiddleware.GetReqID(r.Context())).
+		Str("provider", providerName).
+		Str("model", req.Model).
+		Int("prompt_tokens", promptTokens).
+		Int("completion_tokens", completionTokens).
+		Bool("estimated", estimated).
+		Int("chunks", usage.chunks).
+		Msg("Stream completed")
+}
+
+// recordUsage attributes a request's tokens to its tenant, the SLA report,
+// the token metrics and the caller's spend
+func (h *Handler) recordUsage(ctx context.Context, providerName, model string, promptTokens, completionTokens int) {
+	middleware.AddTokenUsage(ctx, promptTokens, completionTokens)
+	cost.Default().Record(ctx, providerName, model, promptTokens, completionTokens)
+	sla.Default().RecordTokens(providerName, model, promptTokens, completionTokens)
+	observability.GetMetrics().RecordTokenUsage(providerName, model, promptTokens, completionTokens)
+}
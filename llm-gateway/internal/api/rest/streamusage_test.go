@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// sseProvider streams a fixed SSE body, keeping the request it was sent
+type sseProvider struct {
+	proxy.Provider
+	body string
+	req  *models.ChatCompletionRequest
+}
+
+func (p *sseProvider) Name() string { return "sse-usage" }
+
+func (p *sseProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	p.req = req
+	return io.NopCloser(strings.NewReader(p.body)), nil
+}
+
+const openAIUsageStream = `data: {"choices":[{"index":0,"delta":{"content":"Hello"}}]}
+
+data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}
+
+data: [DONE]
+
+`
+
+func TestHandleStreamingResponse_RecordsUsage(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	provider := &sseProvider{body: openAIUsageStream}
+	req := &models.ChatCompletionRequest{Model: "stream-usage-model", Stream: true}
+
+	labels := map[string]string{"provider": "sse-usage", "model": "stream-usage-model"}
+	metrics := observability.GetMetrics()
+	promptBefore := metrics.TokensPrompt.WithLabels(labels).Value()
+	completionBefore := metrics.TokensCompletion.WithLabels(labels).Value()
+
+	rr := httptest.NewRecorder()
+	h.handleStreamingResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), provider, req)
+
+	if provider.req.StreamOptions == nil || !provider.req.StreamOptions.IncludeUsage {
+		t.Error("provider should be asked to include usage")
+	}
+	if req.StreamOptions != nil {
+		t.Error("the caller's request must not be changed")
+	}
+	// The client did not ask for usage, so the usage chunk is dropped
+	if strings.Contains(rr.Body.String(), `"usage"`) || !strings.Contains(rr.Body.String(), "Hello") {
+		t.Errorf("body = %s", rr.Body.String())
+	}
+
+	if got := metrics.TokensPrompt.WithLabels(labels).Value() - promptBefore; got != 12 {
+		t.Errorf("prompt tokens = %d, want 12", got)
+	}
+	if got := metrics.TokensCompletion.WithLabels(labels).Value() - completionBefore; got != 3 {
+		t.Errorf("completion tokens = %d, want 3", got)
+	}
+}
+
+func TestHandleStreamingResponse_ForwardsRequestedUsage(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	provider := &sseProvider{body: openAIUsageStream}
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Stream: true, StreamOptions: &models.StreamOptions{IncludeUsage: true}}
+
+	rr := httptest.NewRecorder()
+	h.handleStreamingResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), provider, req)
+	if !strings.Contains(rr.Body.String(), `"prompt_tokens":12`) {
+		t.Errorf("usage chunk should be forwarded: %s", rr.Body.String())
+	}
+}
+
+func TestStreamUsage_EstimatesWithoutReport(t *testing.T) {
+	u := &streamUsage{}
+	u.process([]byte(`data: {"choices":[{"delta":{"content":"12345678"}}]}` + "\n"))
+	req := &models.ChatCompletionRequest{Messages: []models.ChatMessage{{Role: "user", Content: "abcdefghijkl"}}}
+
+	prompt, completion, estimated := u.totals(req)
+	if prompt != 3 || completion != 2 || !estimated {
+		t.Errorf("totals = %d, %d, %v, want an estimate of 3, 2", prompt, completion, estimated)
+	}
+}
+
+func TestHandleStreamingResponse_AbortsOverReservation(t *testing.T) {
+	manager, err := keys.New(config.APIKeysConfig{
+		Enabled: true, Store: "file", Path: filepath.Join(t.TempDir(), "keys.json"), RefreshInterval: time.Minute,
+		StreamReservation: config.StreamReservationConfig{Enabled: true, DefaultCompletionTokens: 100, NearLimit: 0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.SetDefault(manager)
+	t.Cleanup(func() { keys.SetDefault(nil) })
+	k, secret, _ := manager.Create(context.Background(), keys.Settings{Tenant: ptrTo("acme"), Quota: &keys.Quota{TokensPerDay: 20}}, "")
+
+	// The reservation is 2 prompt and 5 completion tokens; the first chunk
+	// alone brings the stream to 11 tokens, over half the quota
+	h := NewHandler(&config.Config{}, nil)
+	provider := &sseProvider{body: `data: {"choices":[{"index":0,"delta":{"content":"Hello world, this is a long answer"}}]}
+
+data: {"choices":[{"index":0,"delta":{"content":"never sent"}}]}
+
+data: [DONE]
+
+`}
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Stream: true, MaxTokens: 5, Messages: []models.ChatMessage{{Role: "user", Content: "Hi there"}}}
+	handler := manager.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.handleStreamingResponse(w, r, provider, req)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer "+secret)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	if body := rr.Body.String(); !strings.Contains(body, "quota_exceeded") || strings.Contains(body, "never sent") {
+		t.Errorf("body = %s", body)
+	}
+	if reserved := manager.Usage(k.ID).TokensReserved; reserved != 0 {
+		t.Errorf("tokens still reserved after the stream = %d", reserved)
+	}
+}
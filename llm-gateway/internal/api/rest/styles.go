@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/styles"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// applyChatStyle injects the system prompt of the style a chat request
+// references ahead of its messages. It returns false after writing an error.
+func (h *Handler) applyChatStyle(w http.ResponseWriter, r *http.Request, req *models.ChatCompletionRequest) bool {
+	if req.Style == "" {
+		return true
+	}
+	prompt, ok := h.resolveStyle(w, r, req.Style, req.User)
+	if !ok {
+		return false
+	}
+	req.Messages = append([]models.ChatMessage{{Role: "system", Content: prompt}}, req.Messages...)
+	req.Style = ""
+	return true
+}
+
+// applyAnthropicStyle injects the system prompt of the style an Anthropic
+// request references ahead of its own system prompt. It returns false after
+// writing an error.
+func (h *Handler) applyAnthropicStyle(w http.ResponseWriter, r *http.Request, req *models.AnthropicMessageRequest) bool {
+	if req.Style == "" {
+		return true
+	}
+	prompt, ok := h.resolveStyle(w, r, req.Style, anthropicUserID(req.Metadata))
+	if !ok {
+		return false
+	}
+	if req.System != "" {
+		prompt += "\n\n" + req.System
+	}
+	req.System = prompt
+	req.Style = ""
+	return true
+}
+
+// resolveStyle returns the system prompt of the style version serving the
+// caller (the end user, or the tenant without one) and records the version on
+// the metrics, the request span and the X-Style-Version header
+func (h *Handler) resolveStyle(w http.ResponseWriter, r *http.Request, ref, user string) (string, bool) {
+	registry := styles.Default()
+	if registry == nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "style presets are not enabled")
+		return "", false
+	}
+	caller := user
+	if caller == "" {
+		caller = middleware.TenantID(r)
+	}
+	resolved, err := registry.Resolve(ref, caller)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "style "+ref+": "+err.Error())
+		return "", false
+	}
+
+	observability.GetMetrics().RecordStyle(resolved.Name, resolved.Version)
+	if span := observability.SpanFromContext(r.Context()); span != nil {
+		span.SetAttribute("llm.request.style", resolved.String())
+	}
+	w.Header().Set("X-Style-Version", resolved.String())
+	logger.Debug().
+		Str("style", resolved.Name).
+		Int("version", resolved.Version).
+		Bool("candidate", resolved.Candidate).
+		Msg("Applied style preset")
+	return resolved.Content, true
+}
+
+// styleRegistry returns the style registry, writing an error if styles are disabled
+func styleRegistry(w http.ResponseWriter) *styles.Registry {
+	registry := styles.Default()
+	if registry == nil {
+		writeJSONError(w, http.StatusConflict, "styles_disabled", "style presets are not enabled")
+	}
+	return registry
+}
+
+// writeStyleError maps a styles error to a response
+func writeStyleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, styles.ErrNotFound), errors.Is(err, styles.ErrVersionNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, styles.ErrInvalid):
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	default:
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// ListStyles handles GET /admin/v1/styles
+func (h *AdminHandler) ListStyles(w http.ResponseWriter, r *http.Request) {
+	registry := styleRegistry(w)
+	if registry == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   registry.List(),
+	})
+}
+
+// GetStyle handles GET /admin/v1/styles/{style}
+func (h *AdminHandler) GetStyle(w http.ResponseWriter, r *http.Request) {
+	registry := styleRegistry(w)
+	if registry == nil {
+		return
+	}
+	style, err := registry.Get(chi.URLParam(r, "style"))
+	if err != nil {
+		writeStyleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, style)
+}
+
+// AddStyleVersion handles POST /admin/v1/styles/{style}/versions ({"content": "..."}).
+// The first version of a style serves all traffic; later ones need a rollout.
+func (h *AdminHandler) AddStyleVersion(w http.ResponseWriter, r *http.Request) {
+	registry := styleRegistry(w)
+	if registry == nil {
+		return
+	}
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	name := chi.URLParam(r, "style")
+	actor := middleware.GetUserID(r.Context())
+	version, err := registry.AddVersion(name, req.Content, actor)
+	if err != nil {
+		writeStyleError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "style.add_version", name, map[string]interface{}{
+		"version": version.Version,
+		"actor":   actor,
+	})
+	writeJSON(w, http.StatusCreated, version)
+}
+
+// SetStyleRollout handles PUT /admin/v1/styles/{style}/rollout
+// ({"version": 3, "percent": 10}). 100 promotes the version; 0 rolls it back.
+func (h *AdminHandler) SetStyleRollout(w http.ResponseWriter, r *http.Request) {
+	registry := styleRegistry(w)
+	if registry == nil {
+		return
+	}
+	var req struct {
+		Version int     `json:"version"`
+		Percent float64 `json:"percent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body: "+err.Error())
+		return
+	}
+
+	name := chi.URLParam(r, "style")
+	style, err := registry.SetRollout(name, req.Version, req.Percent)
+	if err != nil {
+		writeStyleError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "style.rollout", name, map[string]interface{}{
+		"version": req.Version,
+		"percent": req.Percent,
+		"actor":   middleware.GetUserID(r.Context()),
+	})
+	writeJSON(w, http.StatusOK, style)
+}
+
+// DeleteStyle handles DELETE /admin/v1/styles/{style}
+func (h *AdminHandler) DeleteStyle(w http.ResponseWriter, r *http.Request) {
+	registry := styleRegistry(w)
+	if registry == nil {
+		return
+	}
+	name := chi.URLParam(r, "style")
+	if err := registry.Delete(name); err != nil {
+		writeStyleError(w, err)
+		return
+	}
+
+	observability.LogAudit(r.Context(), "style.delete", name, map[string]interface{}{
+		"actor": middleware.GetUserID(r.Context()),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/styles"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestHandler_applyStyle(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	req := &models.ChatCompletionRequest{
+		Messages: []models.ChatMessage{{Role: "user", Content: "Hi"}},
+		Style:    "support_agent",
+	}
+
+	// Styles disabled
+	styles.SetDefault(nil)
+	rr := httptest.NewRecorder()
+	if h.applyChatStyle(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), req) || rr.Code != 400 {
+		t.Fatalf("style with styles disabled: status %d", rr.Code)
+	}
+
+	registry, _ := styles.New(config.StylesConfig{Enabled: true})
+	styles.SetDefault(registry)
+	defer styles.SetDefault(nil)
+	registry.AddVersion("support_agent", "Be helpful.", "")
+
+	rr = httptest.NewRecorder()
+	if !h.applyChatStyle(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), req) {
+		t.Fatalf("applyChatStyle failed: %s", rr.Body.String())
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "Be helpful." {
+		t.Errorf("messages = %+v, want the preset first", req.Messages)
+	}
+	if req.Style != "" {
+		t.Error("style should not be forwarded to the provider")
+	}
+	if got := rr.Header().Get("X-Style-Version"); got != "support_agent@1" {
+		t.Errorf("X-Style-Version = %q", got)
+	}
+
+	anthropicReq := &models.AnthropicMessageRequest{System: "Answer in French.", Style: "support_agent"}
+	if !h.applyAnthropicStyle(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/messages", nil), anthropicReq) {
+		t.Fatal("applyAnthropicStyle failed")
+	}
+	if anthropicReq.System != "Be helpful.\n\nAnswer in French." {
+		t.Errorf("system = %q", anthropicReq.System)
+	}
+
+	rr = httptest.NewRecorder()
+	req.Style = "unknown"
+	if h.applyChatStyle(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil), req) || rr.Code != 400 {
+		t.Errorf("unknown style: status %d", rr.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// GetTimeSeries handles GET /admin/v1/timeseries. resolution picks the 1s or
+// 10s series (default 10s) and window how far back to go (default the whole
+// retention).
+func (h *AdminHandler) GetTimeSeries(w http.ResponseWriter, r *http.Request) {
+	series := observability.GetTimeSeries()
+	if series == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Time series are not enabled")
+		return
+	}
+
+	resolution := 10 * time.Second
+	if v := r.URL.Query().Get("resolution"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "resolution must be 1s or 10s")
+			return
+		}
+		resolution = d
+	}
+	window := series.Retention()
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "window must be a positive duration")
+			return
+		}
+		window = min(d, window)
+	}
+
+	points, err := series.Points(resolution, window, time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "resolution must be 1s or 10s")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"resolution": resolution.String(),
+		"window":     window.String(),
+		"points":     points,
+	})
+}
@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// toolCallAssemblyHeader selects the tool call assembly mode of a stream,
+// overriding tool_call_assembly.mode
+const toolCallAssemblyHeader = "X-Tool-Call-Assembly"
+
+// Tool call assembly modes
+const (
+	assembleOff      = "off"
+	assembleComplete = "complete"
+	assembleFinal    = "final"
+)
+
+// toolCallAssemblyMode returns the assembly mode of a streamed request: the
+// header's if valid, otherwise the configured default
+func (h *Handler) toolCallAssemblyMode(r *http.Request) string {
+	switch mode := strings.ToLower(strings.TrimSpace(r.Header.Get(toolCallAssemblyHeader))); mode {
+	case assembleOff, assembleComplete, assembleFinal:
+		return mode
+	}
+	if mode := h.config.ToolCallAssembly.Mode; mode != "" {
+		return mode
+	}
+	return assembleOff
+}
+
+// toolCallAssembler buffers the tool call deltas of a stream and sends each
+// call as one complete object, so clients need not merge argument
+// fragments. In "complete" mode a call is sent in the first chunk where its
+// arguments are valid JSON, or once a later call starts or the choice
+// finishes; in "final" mode all calls are sent with the finishing chunk.
+// Chunks left with nothing to say are dropped.
+type toolCallAssembler struct {
+	final bool
+	// pending holds the calls not sent yet, by choice and call index
+	pending map[int]map[int]*models.ToolCall
+	// last is the latest chunk, whose ID and model label a closing chunk
+	last models.ChatCompletionStreamResponse
+}
+
+func newToolCallAssembler(mode string) *toolCallAssembler {
+	return &toolCallAssembler{final: mode == assembleFinal, pending: make(map[int]map[int]*models.ToolCall)}
+}
+
+// process returns line with its tool call deltas replaced by the calls
+// ready to send, or nil to drop it
+func (a *toolCallAssembler) process(line []byte) []byte {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return line
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return line
+	}
+
+	// Rewrite only the tool calls, keeping fields the gateway does not model
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return line
+	}
+	var choices []map[string]json.RawMessage
+	if err := json.Unmarshal(chunk["choices"], &choices); err != nil || len(choices) == 0 {
+		return line
+	}
+	json.Unmarshal(payload, &a.last)
+
+	rewritten, empty := false, true
+	for _, choice := range choices {
+		var index int
+		json.Unmarshal(choice["index"], &index)
+		var delta map[string]json.RawMessage
+		json.Unmarshal(choice["delta"], &delta)
+		finished := len(choice["finish_reason"]) > 0 && !bytes.Equal(choice["finish_reason"], []byte("null"))
+
+		var calls []models.ToolCall
+		if raw, ok := delta["tool_calls"]; ok {
+			json.Unmarshal(raw, &calls)
+			delete(delta, "tool_calls")
+			rewritten = true
+		}
+		if len(calls) == 0 && !(finished && len(a.pending[index]) > 0) {
+			if len(delta) > 0 || finished {
+				empty = false
+			}
+			if rewritten {
+				choice["delta"], _ = json.Marshal(delta)
+			}
+			continue
+		}
+
+		if ready := a.merge(index, calls, finished); len(ready) > 0 {
+			delta["tool_calls"], _ = json.Marshal(ready)
+		}
+		choice["delta"], _ = json.Marshal(delta)
+		rewritten = true
+		if len(delta) > 0 || finished {
+			empty = false
+		}
+	}
+	if !rewritten {
+		return line
+	}
+	if empty && len(chunk["usage"]) == 0 {
+		return nil
+	}
+
+	chunk["choices"], _ = json.Marshal(choices)
+	return encodeDataLine(chunk)
+}
+
+// merge adds the deltas of a choice's calls and returns the calls ready to send
+func (a *toolCallAssembler) merge(choice int, deltas []models.ToolCall, finished bool) []models.ToolCall {
+	pending := a.pending[choice]
+	if pending == nil {
+		pending = make(map[int]*models.ToolCall)
+		a.pending[choice] = pending
+	}
+
+	started := -1
+	for i, d := range deltas {
+		index := i
+		if d.Index != nil {
+			index = *d.Index
+		}
+		call, ok := pending[index]
+		if !ok {
+			call = &models.ToolCall{Index: &index}
+			pending[index] = call
+			started = max(started, index)
+		}
+		if d.ID != "" {
+			call.ID = d.ID
+		}
+		if d.Type != "" {
+			call.Type = d.Type
+		}
+		if d.Function.Name != "" {
+			call.Function.Name = d.Function.Name
+		}
+		call.Function.Arguments += d.Function.Arguments
+	}
+
+	var ready []models.ToolCall
+	for index, call := range pending {
+		complete := finished
+		if !a.final {
+			args := strings.TrimSpace(call.Function.Arguments)
+			complete = complete || index < started || (strings.HasPrefix(args, "{") && json.Valid([]byte(args)))
+		}
+		if complete {
+			ready = append(ready, *call)
+			delete(pending, index)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return *ready[i].Index < *ready[j].Index })
+	return ready
+}
+
+// flush returns a chunk with the calls still pending when the stream ended
+// without finishing their choices, or nil
+func (a *toolCallAssembler) flush() []byte {
+	chunk := models.ChatCompletionStreamResponse{
+		ID:      a.last.ID,
+		Object:  "chat.completion.chunk",
+		Created: a.last.Created,
+		Model:   a.last.Model,
+	}
+	indexes := make([]int, 0, len(a.pending))
+	for index := range a.pending {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		if ready := a.merge(index, nil, true); len(ready) > 0 {
+			chunk.Choices = append(chunk.Choices, models.ChatCompletionStreamChoice{
+				Index: index,
+				Delta: models.ChatMessageDelta{ToolCalls: ready},
+			})
+		}
+	}
+	if len(chunk.Choices) == 0 {
+		return nil
+	}
+	return append(encodeDataLine(chunk), '\n')
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// toolCallStream is an OpenAI stream calling two tools, with the first
+// call's arguments split across chunks and the second's cut short, so only
+// the finishing chunk completes it
+var toolCallStream = []string{
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":""}}]},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"get_time","arguments":"{\"tz\":"}}]},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"CET\""}}]},"finish_reason":null}]}`,
+	`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	`data: [DONE]`,
+}
+
+// assembledCalls returns the tool calls of each chunk forwarded by a
+func assembledCalls(t *testing.T, a *toolCallAssembler, lines []string) [][]models.ToolCall {
+	t.Helper()
+	var out [][]models.ToolCall
+	for _, line := range lines {
+		forwarded := a.process([]byte(line + "\n"))
+		if forwarded == nil || strings.Contains(string(forwarded), "[DONE]") {
+			continue
+		}
+		var chunk models.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(string(forwarded)), "data: ")), &chunk); err != nil {
+			t.Fatalf("forwarded %q: %v", forwarded, err)
+		}
+		out = append(out, chunk.Choices[0].Delta.ToolCalls)
+	}
+	return out
+}
+
+func TestToolCallAssembler_Complete(t *testing.T) {
+	chunks := assembledCalls(t, newToolCallAssembler(assembleComplete), toolCallStream)
+
+	// The role chunk, the first call once its arguments parse, and the
+	// second with the finishing chunk; the fragments are dropped
+	if len(chunks) != 3 || chunks[0] != nil {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	first, second := chunks[1], chunks[2]
+	if len(first) != 1 || first[0].ID != "call_a" || first[0].Function.Name != "get_weather" || first[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("first call = %+v", first)
+	}
+	if len(second) != 1 || second[0].ID != "call_b" || *second[0].Index != 1 || second[0].Function.Arguments != `{"tz":"CET"` {
+		t.Errorf("second call = %+v", second)
+	}
+}
+
+func TestToolCallAssembler_Final(t *testing.T) {
+	a := newToolCallAssembler(assembleFinal)
+	chunks := assembledCalls(t, a, toolCallStream)
+	if len(chunks) != 2 || len(chunks[1]) != 2 || chunks[1][0].ID != "call_a" || chunks[1][1].ID != "call_b" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if out := a.flush(); out != nil {
+		t.Errorf("flush() after the finishing chunk = %q", out)
+	}
+}
+
+func TestToolCallAssembler_FlushUnfinished(t *testing.T) {
+	a := newToolCallAssembler(assembleFinal)
+	assembledCalls(t, a, toolCallStream[:4])
+	out := string(a.flush())
+	if !strings.HasPrefix(out, "data: ") || !strings.Contains(out, `"id":"c1"`) || !strings.Contains(out, `"arguments":"{\"city\":\"Paris\"}"`) {
+		t.Errorf("flush() = %q", out)
+	}
+}
+
+func TestToolCallAssembler_PassThrough(t *testing.T) {
+	a := newToolCallAssembler(assembleComplete)
+	for _, line := range []string{
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":null}],"x_provider":1}`,
+		`: keep-alive`,
+		``,
+	} {
+		if out := a.process([]byte(line + "\n")); string(out) != line+"\n" {
+			t.Errorf("process(%q) = %q", line, out)
+		}
+	}
+}
+
+func TestHandler_toolCallAssemblyMode(t *testing.T) {
+	h := &Handler{config: &config.Config{ToolCallAssembly: config.ToolCallAssemblyConfig{Mode: "complete"}}}
+	for header, want := range map[string]string{"": "complete", "final": "final", "OFF": "off", "bogus": "complete"} {
+		r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		r.Header.Set(toolCallAssemblyHeader, header)
+		if got := h.toolCallAssemblyMode(r); got != want {
+			t.Errorf("mode with header %q = %q, want %q", header, got, want)
+		}
+	}
+}
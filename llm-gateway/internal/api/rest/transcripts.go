@@ -0,0 +1,279 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Transcript statuses
+const (
+	transcriptInProgress = "in_progress"
+	transcriptCompleted  = "completed"
+	transcriptIncomplete = "incomplete"
+)
+
+// transcript assembles the chunks of a stream, as sent to the client, into
+// the complete chat response. Its process and finish methods are no-ops on nil.
+type transcript struct {
+	id string
+	// tenant and user own the transcript; only the tenant can fetch it
+	tenant string
+	user   string
+
+	mu        sync.Mutex
+	status    string
+	createdAt time.Time
+	expiresAt time.Time
+	resp      models.ChatCompletionResponse
+	// choices and calls hold the choices and their tool calls by index
+	choices map[int]*models.ChatCompletionChoice
+	calls   map[int]map[int]*models.ToolCall
+}
+
+// transcriptView is the body of GET /v1/responses/{id}
+type transcriptView struct {
+	ID        string                         `json:"id"`
+	Object    string                         `json:"object"`
+	Status    string                         `json:"status"`
+	CreatedAt time.Time                      `json:"created_at"`
+	ExpiresAt *time.Time                     `json:"expires_at,omitempty"`
+	Response  *models.ChatCompletionResponse `json:"response"`
+}
+
+// process adds an SSE line sent to the client
+func (t *transcript) process(line []byte) {
+	if t == nil {
+		return
+	}
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return
+	}
+	var chunk models.ChatCompletionStreamResponse
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resp.ID == "" {
+		t.resp.ID, t.resp.Created, t.resp.Model = chunk.ID, chunk.Created, chunk.Model
+		t.resp.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.Usage != nil {
+		t.resp.Usage = *chunk.Usage
+	}
+	for _, delta := range chunk.Choices {
+		choice, ok := t.choices[delta.Index]
+		if !ok {
+			choice = &models.ChatCompletionChoice{Index: delta.Index, Message: models.ChatMessage{Role: "assistant"}}
+			t.choices[delta.Index] = choice
+		}
+		if delta.Delta.Role != "" {
+			choice.Message.Role = delta.Delta.Role
+		}
+		choice.Message.Content += delta.Delta.Content
+		choice.Citations = append(choice.Citations, delta.Citations...)
+		if delta.FinishReason != nil {
+			choice.FinishReason = *delta.FinishReason
+		}
+		for _, call := range delta.Delta.ToolCalls {
+			t.addToolCall(delta.Index, call)
+		}
+	}
+}
+
+// addToolCall merges a tool call delta; t.mu must be held
+func (t *transcript) addToolCall(choice int, delta models.ToolCall) {
+	calls, ok := t.calls[choice]
+	if !ok {
+		calls = make(map[int]*models.ToolCall)
+		t.calls[choice] = calls
+	}
+	index := len(calls)
+	if delta.Index != nil {
+		index = *delta.Index
+	}
+	call, ok := calls[index]
+	if !ok {
+		call = &models.ToolCall{}
+		calls[index] = call
+	}
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Type != "" {
+		call.Type = delta.Type
+	}
+	if delta.Function.Name != "" {
+		call.Function.Name = delta.Function.Name
+	}
+	call.Function.Arguments += delta.Function.Arguments
+}
+
+// finish marks the stream ended, completed unless it was cut short
+func (t *transcript) finish(completed bool, ttl time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = transcriptIncomplete
+	if completed {
+		t.status = transcriptCompleted
+	}
+	t.expiresAt = time.Now().UTC().Add(ttl)
+}
+
+// view returns the transcript assembled so far
+func (t *transcript) view() transcriptView {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	resp := t.resp
+	resp.Object = "chat.completion"
+	resp.Choices = make([]models.ChatCompletionChoice, 0, len(t.choices))
+	for index, choice := range t.choices {
+		c := *choice
+		if calls := t.calls[index]; len(calls) > 0 {
+			order := make([]int, 0, len(calls))
+			for i := range calls {
+				order = append(order, i)
+			}
+			sort.Ints(order)
+			c.Message.ToolCalls = make([]models.ToolCall, 0, len(calls))
+			for _, i := range order {
+				c.Message.ToolCalls = append(c.Message.ToolCalls, *calls[i])
+			}
+		}
+		resp.Choices = append(resp.Choices, c)
+	}
+	sort.Slice(resp.Choices, func(i, j int) bool { return resp.Choices[i].Index < resp.Choices[j].Index })
+
+	view := transcriptView{ID: t.id, Object: "chat.completion.transcript", Status: t.status, CreatedAt: t.createdAt, Response: &resp}
+	if !t.expiresAt.IsZero() {
+		expiresAt := t.expiresAt
+		view.ExpiresAt = &expiresAt
+	}
+	return view
+}
+
+// expired reports whether a finished transcript is past its TTL
+func (t *transcript) expired(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.expiresAt.IsZero() && now.After(t.expiresAt)
+}
+
+// transcriptStore keeps the transcripts of assembled streams by request ID
+type transcriptStore struct {
+	cfg config.TranscriptsConfig
+
+	mu      sync.Mutex
+	entries map[string]*transcript
+	order   []string
+}
+
+func newTranscriptStore(cfg config.TranscriptsConfig) *transcriptStore {
+	return &transcriptStore{cfg: cfg, entries: make(map[string]*transcript)}
+}
+
+// start begins the transcript of a stream, dropping expired transcripts and,
+// past max_entries, the oldest
+func (s *transcriptStore) start(id, tenant, user string) *transcript {
+	t := &transcript{
+		id:        id,
+		tenant:    tenant,
+		user:      user,
+		status:    transcriptInProgress,
+		createdAt: time.Now().UTC(),
+		choices:   make(map[int]*models.ChatCompletionChoice),
+		calls:     make(map[int]map[int]*models.ToolCall),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	kept := s.order[:0]
+	for _, existing := range s.order {
+		if s.entries[existing].expired(now) {
+			delete(s.entries, existing)
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	s.order = kept
+	if _, ok := s.entries[id]; !ok {
+		s.order = append(s.order, id)
+	}
+	s.entries[id] = t
+	for len(s.order) > s.cfg.MaxEntries {
+		delete(s.entries, s.order[0])
+		s.order = s.order[1:]
+	}
+	return t
+}
+
+// get returns tenant's transcript of request id
+func (s *transcriptStore) get(id, tenant string) (*transcript, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.entries[id]
+	if !ok || t.tenant != tenant || t.expired(time.Now()) {
+		return nil, false
+	}
+	return t, true
+}
+
+// DeleteUser drops the transcripts of user's requests, for deletion requests
+func (s *transcriptStore) DeleteUser(user string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if t := s.entries[id]; t.user == user || t.tenant == user {
+			delete(s.entries, id)
+			deleted++
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+	return deleted
+}
+
+// GetResponse handles GET /v1/responses/{id}: the message assembled from a
+// stream requested with assemble: true, complete or so far. Request IDs
+// containing a slash must be escaped as %2F.
+func (h *Handler) GetResponse(w http.ResponseWriter, r *http.Request) {
+	if h.transcripts == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Stream transcripts are not enabled")
+		return
+	}
+	id, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid response ID")
+		return
+	}
+	// Transcripts of other tenants are reported as missing
+	t, ok := h.transcripts.get(id, middleware.TenantID(r))
+	if !ok {
+		h.writeError(w, http.StatusNotFound, "not_found", "No response "+id+", or it has expired")
+		return
+	}
+	writeJSON(w, http.StatusOK, t.view())
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+const transcriptToolCallStream = `data: {"id":"chatcmpl-7","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}
+
+data: {"id":"chatcmpl-7","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"tea\"}"}}]}}]}
+
+data: {"id":"chatcmpl-7","model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`
+
+func TestHandler_AssembledStreamTranscript(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Transcripts = config.TranscriptsConfig{Enabled: true, TTL: time.Hour, MaxEntries: 10, MaxDuration: time.Minute}
+	h := NewHandler(cfg, nil)
+	router := chi.NewRouter()
+	router.Get("/v1/responses/{id}", h.GetResponse)
+	fetch := func(id, tenant string) (*httptest.ResponseRecorder, transcriptView) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/responses/"+id, nil)
+		req.Header.Set(middleware.TenantHeader, tenant)
+		req = req.WithContext(middleware.WithTrustedTenant(req.Context()))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var view transcriptView
+		json.NewDecoder(rr.Body).Decode(&view)
+		return rr, view
+	}
+	stream := func(id, body string, ctx context.Context) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set(middleware.TenantHeader, "acme")
+		req = req.WithContext(context.WithValue(middleware.WithTrustedTenant(ctx), chimiddleware.RequestIDKey, id))
+		rr := httptest.NewRecorder()
+		h.handleStreamingResponse(rr, req, &sseProvider{body: body}, &models.ChatCompletionRequest{Model: "gpt-4o", Stream: true, Assemble: true, User: "alice"})
+		return rr
+	}
+
+	rr := stream("host/req-1", openAIUsageStream, context.Background())
+	if rr.Header().Get("X-Request-ID") != "host/req-1" {
+		t.Errorf("X-Request-ID = %q", rr.Header().Get("X-Request-ID"))
+	}
+	rr, view := fetch("host%2Freq-1", "acme")
+	if rr.Code != http.StatusOK || view.Status != transcriptCompleted || view.ExpiresAt == nil {
+		t.Fatalf("fetch: %d %+v", rr.Code, view)
+	}
+	choice := view.Response.Choices[0]
+	if choice.Message.Content != "Hello" || choice.FinishReason != "stop" || view.Response.Usage.TotalTokens != 15 {
+		t.Errorf("response = %+v", view.Response)
+	}
+	if rr, _ := fetch("host%2Freq-1", "other"); rr.Code != http.StatusNotFound {
+		t.Errorf("other tenant: %d, want 404", rr.Code)
+	}
+
+	// The client went away before the stream ended: it is still assembled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream("req-2", transcriptToolCallStream, ctx)
+	_, view = fetch("req-2", "acme")
+	if view.Status != transcriptCompleted || len(view.Response.Choices) != 1 {
+		t.Fatalf("disconnected: %+v", view)
+	}
+	calls := view.Response.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Name != "lookup" || calls[0].Function.Arguments != `{"q":"tea"}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+
+	if n := h.transcripts.DeleteUser("alice"); n != 2 {
+		t.Errorf("DeleteUser() = %d, want 2", n)
+	}
+	if rr, _ := fetch("req-2", "acme"); rr.Code != http.StatusNotFound {
+		t.Errorf("deleted transcript: %d, want 404", rr.Code)
+	}
+}
+
+func TestTranscriptStore_Eviction(t *testing.T) {
+	s := newTranscriptStore(config.TranscriptsConfig{MaxEntries: 2})
+	for _, id := range []string{"a", "b", "c"} {
+		s.start(id, "acme", "").finish(true, time.Hour)
+	}
+	if _, ok := s.get("a", "acme"); ok {
+		t.Error("oldest transcript should be evicted past max_entries")
+	}
+	s.start("d", "acme", "").finish(true, -time.Second)
+	if _, ok := s.get("d", "acme"); ok {
+		t.Error("expired transcript should not be returned")
+	}
+	if _, ok := s.get("c", "acme"); !ok {
+		t.Error("transcript c should be kept")
+	}
+}
+
+func TestChatCompletions_AssembleRequiresTranscripts(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil)
+	rr := httptest.NewRecorder()
+	h.ChatCompletions(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"assemble":true,"messages":[{"role":"user","content":"hi"}]}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 when transcripts are disabled", rr.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// translatePrompt instructs the translation model; %s is the language name
+const translatePrompt = "You translate assistant responses. Translate the user's message into %s. " +
+	"Keep the meaning, tone and formatting, including Markdown. Do not translate code, URLs or " +
+	"placeholders in square brackets such as [EMAIL_1]. Reply with the translation only."
+
+// Translation outcomes, as counted in llm_gateway_translations_total
+const (
+	translationTranslated = "translated"
+	translationSkipped    = "skipped"
+	translationFailed     = "failed"
+)
+
+// translationHeader reports a translation that could not be made
+const translationHeader = "X-Translation"
+
+var errEmptyTranslation = errors.New("translation model returned an empty translation")
+
+type translationContextKey struct{}
+
+// translationFrom returns the language the request asked its response to be
+// translated into, or ""
+func translationFrom(ctx context.Context) string {
+	lang, _ := ctx.Value(translationContextKey{}).(string)
+	return lang
+}
+
+// applyTranslateTo checks a request's translate_to and moves it to the
+// request context, so it is not sent to the provider. It writes a 400 and
+// returns false if translation is disabled, the language unknown or the
+// response streamed.
+func (h *Handler) applyTranslateTo(w http.ResponseWriter, r *http.Request, req *models.ChatCompletionRequest) (*http.Request, bool) {
+	lang := strings.ToLower(strings.TrimSpace(req.TranslateTo))
+	req.TranslateTo = ""
+	if lang == "" {
+		return r, true
+	}
+	if !h.config.Translation.Enabled {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "translate_to is not enabled on this gateway")
+		return r, false
+	}
+	if languageNames[lang] == "" {
+		codes := make([]string, 0, len(languageNames))
+		for code := range languageNames {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "translate_to must be one of "+strings.Join(codes, ", "))
+		return r, false
+	}
+	if req.Stream {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "translate_to is not supported for streamed responses")
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), translationContextKey{}, lang)), true
+}
+
+// translateChatResponse sets the translation of each choice's message when
+// the request asked for one. Messages already in the language are used as
+// they are. If a translation fails, the response is sent without
+// translations and X-Translation: failed. Translations go through the output
+// filters; it returns false if one blocked the response.
+func (h *Handler) translateChatResponse(w http.ResponseWriter, r *http.Request, resp *models.ChatCompletionResponse) bool {
+	lang := translationFrom(r.Context())
+	if lang == "" {
+		return true
+	}
+	metrics := observability.GetMetrics()
+
+	translations := make([]string, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		content := choice.Message.Content
+		if content == "" || detectLanguage(content, h.config.Language.MinChars) == lang {
+			metrics.RecordTranslation(lang, translationSkipped)
+			translations[i] = content
+			continue
+		}
+		translated, err := h.translate(r, lang, content)
+		if err != nil {
+			metrics.RecordTranslation(lang, translationFailed)
+			w.Header().Set(translationHeader, translationFailed)
+			logger.Warn().
+				Err(err).
+				Str("request_id", chimiddleware.GetReqID(r.Context())).
+				Str("language", lang).
+				Str("model", h.config.Translation.Model).
+				Msg("Response translation failed, returning the original only")
+			return true
+		}
+		metrics.RecordTranslation(lang, translationTranslated)
+		translations[i] = translated
+	}
+
+	for i, translated := range translations {
+		if h.outputFilter != nil {
+			if rule := h.outputFilter.abortRule(translated); rule != "" {
+				h.writeOutputFiltered(w, r, rule)
+				return false
+			}
+			translated = h.outputFilter.mask(translated)
+		}
+		resp.Choices[i].Translation = &models.Translation{Language: lang, Content: translated}
+	}
+	return true
+}
+
+// translate asks the translation model for text in lang. The tokens are
+// counted as the request's own.
+func (h *Handler) translate(r *http.Request, lang, text string) (string, error) {
+	ctx := r.Context()
+	cfg := h.config.Translation
+	provider, err := h.proxyRouter.GetProviderForRequest(ctx, cfg.Model)
+	if err != nil {
+		return "", err
+	}
+
+	temperature := 0.0
+	req := &models.ChatCompletionRequest{
+		Model: cfg.Model,
+		Messages: []models.ChatMessage{
+			{Role: "system", Content: fmt.Sprintf(translatePrompt, languageNames[lang])},
+			{Role: "user", Content: text},
+		},
+		Temperature: &temperature,
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	resp, err := provider.ChatCompletion(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	h.recordUsage(r.Context(), provider.Name(), cfg.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", errEmptyTranslation
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
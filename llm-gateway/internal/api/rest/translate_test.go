@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// translationTestHandler serves chat completions from a backend that answers
+// in English, and in Spanish when asked to translate; failTranslation makes
+// translation calls fail
+func translationTestHandler(t *testing.T, cfg *config.Config, failTranslation bool) *Handler {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		content := "The weather is nice today and you can go for a walk in the park."
+		if strings.HasPrefix(req.Messages[0].Content, "You translate") {
+			if failTranslation {
+				http.Error(w, `{"error":{"message":"overloaded","type":"server_error"}}`, http.StatusInternalServerError)
+				return
+			}
+			if !strings.Contains(req.Messages[0].Content, "Spanish") || req.Model != "gpt-4o-mini" {
+				t.Errorf("translation request = %+v", req)
+			}
+			content = "Hoy hace buen tiempo y puedes dar un paseo por el parque."
+		}
+		json.NewEncoder(w).Encode(models.ChatCompletionResponse{
+			Model:   req.Model,
+			Choices: []models.ChatCompletionChoice{{Message: models.ChatMessage{Role: "assistant", Content: content}, FinishReason: "stop"}},
+			Usage:   models.Usage{PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20},
+		})
+	}))
+	t.Cleanup(backend.Close)
+
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: backend.URL}))
+	return NewHandler(cfg, proxy.NewRouter(registry, cfg))
+}
+
+func postChat(h *Handler, body string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.ChatCompletions(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	return rr
+}
+
+func TestChatCompletions_TranslateTo(t *testing.T) {
+	cfg := &config.Config{Translation: config.TranslationConfig{Enabled: true, Model: "gpt-4o-mini", Timeout: 5 * time.Second}}
+	h := translationTestHandler(t, cfg, false)
+
+	rr := postChat(h, `{"model":"gpt-4o","translate_to":"es","messages":[{"role":"user","content":"What should I do today?"}]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp models.ChatCompletionResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	choice := resp.Choices[0]
+	if !strings.HasPrefix(choice.Message.Content, "The weather") {
+		t.Errorf("original content = %q, want it kept", choice.Message.Content)
+	}
+	if choice.Translation == nil || choice.Translation.Language != "es" || !strings.HasPrefix(choice.Translation.Content, "Hoy hace") {
+		t.Errorf("translation = %+v, want the Spanish translation", choice.Translation)
+	}
+}
+
+func TestChatCompletions_TranslateToFailure(t *testing.T) {
+	cfg := &config.Config{Translation: config.TranslationConfig{Enabled: true, Model: "gpt-4o-mini", Timeout: 5 * time.Second}}
+	h := translationTestHandler(t, cfg, true)
+
+	rr := postChat(h, `{"model":"gpt-4o","translate_to":"es","messages":[{"role":"user","content":"What should I do today?"}]}`)
+	if rr.Code != http.StatusOK || rr.Header().Get(translationHeader) != translationFailed {
+		t.Fatalf("status = %d, %s = %q, want the original with a failure header", rr.Code, translationHeader, rr.Header().Get(translationHeader))
+	}
+	var resp models.ChatCompletionResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Choices[0].Translation != nil || !strings.HasPrefix(resp.Choices[0].Message.Content, "The weather") {
+		t.Errorf("choice = %+v, want the original without a translation", resp.Choices[0])
+	}
+}
+
+func TestChatCompletions_TranslateToRejected(t *testing.T) {
+	enabled := &config.Config{Translation: config.TranslationConfig{Enabled: true, Model: "gpt-4o-mini"}}
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		body string
+	}{
+		{"disabled", &config.Config{}, `{"model":"gpt-4o","translate_to":"es","messages":[{"role":"user","content":"Hi"}]}`},
+		{"unknown language", enabled, `{"model":"gpt-4o","translate_to":"tlh","messages":[{"role":"user","content":"Hi"}]}`},
+		{"stream", enabled, `{"model":"gpt-4o","translate_to":"es","stream":true,"messages":[{"role":"user","content":"Hi"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := translationTestHandler(t, tt.cfg, false)
+			if rr := postChat(h, tt.body); rr.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+// usageExportHandler serves GET /analytics/v1/usage: usage aggregated per
+// tenant and day (or per day with group_by=day), without groups under the
+// k-anonymity threshold. Query parameters:
+//
+//	from, to   days to export (YYYY-MM-DD, default today)
+//	group_by   "tenant" (default) or "day"
+//	min_users  raise the k-anonymity threshold above usage_export.min_users
+//	format     "json" (default) or "csv"
+func usageExportHandler(usage *middleware.UsageTracker, cfg config.UsageExportConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		today := time.Now().UTC().Format("2006-01-02")
+		opts := middleware.UsageExportOptions{
+			From:             q.Get("from"),
+			To:               q.Get("to"),
+			GroupBy:          q.Get("group_by"),
+			MinUsers:         cfg.MinUsers,
+			Epsilon:          cfg.Epsilon,
+			TokenSensitivity: cfg.TokenSensitivity,
+			NoiseKey:         cfg.NoiseKey,
+		}
+		if opts.From == "" {
+			opts.From = today
+		}
+		if opts.To == "" {
+			opts.To = today
+		}
+		for _, day := range []string{opts.From, opts.To} {
+			if _, err := time.Parse("2006-01-02", day); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", "invalid day: "+day)
+				return
+			}
+		}
+		switch opts.GroupBy {
+		case "":
+			opts.GroupBy = "tenant"
+		case "tenant", "day":
+		default:
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "group_by must be tenant or day")
+			return
+		}
+		if s := q.Get("min_users"); s != "" {
+			k, err := strconv.Atoi(s)
+			if err != nil || k < cfg.MinUsers {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request",
+					"min_users must be a number of at least "+strconv.Itoa(cfg.MinUsers))
+				return
+			}
+			opts.MinUsers = k
+		}
+
+		export := usage.Export(opts)
+		if q.Get("format") == "csv" {
+			writeUsageCSV(w, export)
+			return
+		}
+		writeJSON(w, http.StatusOK, export)
+	}
+}
+
+// writeUsageCSV writes an export as CSV with a header row
+func writeUsageCSV(w http.ResponseWriter, export *middleware.UsageExport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage-`+export.From+`-`+export.To+`.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"day", "tenant", "requests", "errors", "prompt_tokens", "completion_tokens", "end_users"})
+	for _, row := range export.Data {
+		cw.Write([]string{
+			row.Day,
+			row.Tenant,
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.Errors, 10),
+			strconv.FormatInt(row.PromptTokens, 10),
+			strconv.FormatInt(row.CompletionTokens, 10),
+			strconv.FormatInt(row.EndUsers, 10),
+		})
+	}
+	cw.Flush()
+}
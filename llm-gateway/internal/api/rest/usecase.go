@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Use cases requests are classified into
+const (
+	useCaseCode       = "code"
+	useCaseExtraction = "extraction"
+	useCaseChat       = "chat"
+	useCaseEmbedding  = "embedding"
+)
+
+// maxUseCaseSample bounds the number of bytes of prompt inspected per request
+const maxUseCaseSample = 8000
+
+// useCaseKeywords are prompt words and phrases that point to a use case
+var useCaseKeywords = map[string][]string{
+	useCaseCode: {
+		"code", "function", "bug", "compile", "stack trace", "traceback", "exception", "refactor",
+		"unit test", "regex", "sql query", "python", "golang", "javascript", "typescript", "rust",
+		"java", "c++", "api endpoint", "implement", "debug",
+	},
+	useCaseExtraction: {
+		"extract", "parse", "as json", "in json", "json object", "json schema", "valid json",
+		"fields", "key-value", "entities", "classify", "categorize", "tag the", "structured",
+	},
+}
+
+// codeMarkers are fragments of source code rather than prose; words like
+// "return" or "class" are left out as they are common in prose too
+var codeMarkers = []string{
+	"```", "func ", "def ", "#include", "=> ", "();", ") {", "):\n", "{\n", "};",
+}
+
+// useCaseRequest is the part of a request the classifier looks at
+type useCaseRequest struct {
+	model          string
+	text           string
+	jsonOutput     bool
+	forcedFunction bool
+	embedding      bool
+}
+
+// classifyUseCase tags a request as code assistance, structured extraction or
+// chat. Requests are scored on code markers and keywords in the prompt, code
+// models, and structured output settings; chat is the fallback.
+func classifyUseCase(req useCaseRequest, extraKeywords map[string][]string) string {
+	if req.embedding {
+		return useCaseEmbedding
+	}
+	text := req.text
+	if len(text) > maxUseCaseSample {
+		text = text[:maxUseCaseSample]
+	}
+	lower := strings.ToLower(text)
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(lower, isNonWord) {
+		words[word] = true
+	}
+
+	scores := make(map[string]int)
+	for useCase, keywords := range useCaseKeywords {
+		for _, keyword := range append(keywords, extraKeywords[useCase]...) {
+			keyword = strings.ToLower(keyword)
+			// Single words must match whole words ("rust" is not in "trust")
+			if words[keyword] || (strings.ContainsFunc(keyword, isNonWord) && strings.Contains(lower, keyword)) {
+				scores[useCase]++
+			}
+		}
+	}
+	for _, marker := range codeMarkers {
+		if strings.Contains(text, marker) {
+			scores[useCaseCode] += 2
+		}
+	}
+	if strings.Contains(strings.ToLower(req.model), "code") {
+		scores[useCaseCode] += 3
+	}
+	if req.jsonOutput || req.forcedFunction {
+		scores[useCaseExtraction] += 3
+	}
+
+	// One stray keyword is not enough to leave chat
+	best, bestScore := useCaseChat, 1
+	for _, useCase := range []string{useCaseCode, useCaseExtraction} {
+		if scores[useCase] > bestScore {
+			best, bestScore = useCase, scores[useCase]
+		}
+	}
+	return best
+}
+
+func isNonWord(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// chatUseCaseRequest builds the classifier input for a chat request
+func chatUseCaseRequest(req *models.ChatCompletionRequest) useCaseRequest {
+	var b strings.Builder
+	for _, msg := range req.Messages {
+		if b.Len() >= maxUseCaseSample {
+			break
+		}
+		if msg.Role == "system" || msg.Role == "user" {
+			b.WriteString(msg.Text())
+			b.WriteByte('\n')
+		}
+	}
+	c := useCaseRequest{model: req.Model, text: b.String()}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != "" && req.ResponseFormat.Type != "text" {
+		c.jsonOutput = true
+	}
+	// A function the model must call is how many clients ask for structured output
+	if choice, ok := req.ToolChoice.(map[string]interface{}); ok && choice["type"] == "function" {
+		c.forcedFunction = true
+	}
+	if choice, ok := req.FunctionCall.(map[string]interface{}); ok && choice["name"] != nil {
+		c.forcedFunction = true
+	}
+	return c
+}
+
+// applyUseCase classifies a request and attributes it to the use case in
+// metrics and per-tenant usage. It never changes how the request is served.
+func (h *Handler) applyUseCase(r *http.Request, req useCaseRequest) {
+	if !h.config.UseCase.Enabled {
+		return
+	}
+	useCase := classifyUseCase(req, h.config.UseCase.Keywords)
+	middleware.SetUseCase(r.Context(), useCase)
+	if span := observability.SpanFromContext(r.Context()); span != nil {
+		span.SetAttribute("llm.request.use_case", useCase)
+	}
+}
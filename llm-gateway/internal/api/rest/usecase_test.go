@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestClassifyUseCase(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   useCaseRequest
+		extra map[string][]string
+		want  string
+	}{
+		{"chat", useCaseRequest{text: "What should I cook for dinner tonight?"}, nil, useCaseChat},
+		{"code block", useCaseRequest{text: "Why does this fail?\n```go\nfunc main() {\n}\n```"}, nil, useCaseCode},
+		{"code keywords", useCaseRequest{text: "Refactor this python function to fix the bug"}, nil, useCaseCode},
+		{"code model", useCaseRequest{model: "qwen2.5-coder", text: "Write a fibonacci"}, nil, useCaseCode},
+		{"keyword inside word", useCaseRequest{text: "I trust my friends, do you think that's wise?"}, nil, useCaseChat},
+		{"single keyword", useCaseRequest{text: "Is debugging fun? Tell me about the worst bug you know"}, nil, useCaseChat},
+		{"extraction", useCaseRequest{text: "Extract the names and dates from this email and return them as JSON"}, nil, useCaseExtraction},
+		{"json output", useCaseRequest{text: "Summarize this invoice", jsonOutput: true}, nil, useCaseExtraction},
+		{"forced function", useCaseRequest{text: "Here is the receipt", forcedFunction: true}, nil, useCaseExtraction},
+		{"extra keywords", useCaseRequest{text: "Please triage this ticket and label it"},
+			map[string][]string{useCaseExtraction: {"triage", "label it"}}, useCaseExtraction},
+		{"embedding", useCaseRequest{text: "def main():", embedding: true}, nil, useCaseEmbedding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyUseCase(tt.req, tt.extra); got != tt.want {
+				t.Errorf("classifyUseCase(%+v) = %q, want %q", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatUseCaseRequest(t *testing.T) {
+	req := &models.ChatCompletionRequest{
+		Model: "gpt-4o",
+		Messages: []models.ChatMessage{
+			{Role: "system", Content: "You extract fields."},
+			{Role: "assistant", Content: "```go\nfunc main() {}\n```"},
+			{Role: "user", Content: "Invoice #42"},
+		},
+		ResponseFormat: &models.ResponseFormat{Type: "json_object"},
+	}
+
+	c := chatUseCaseRequest(req)
+	if c.text != "You extract fields.\nInvoice #42\n" {
+		t.Errorf("text = %q, want system and user messages only", c.text)
+	}
+	if !c.jsonOutput || c.forcedFunction {
+		t.Errorf("jsonOutput = %v, forcedFunction = %v", c.jsonOutput, c.forcedFunction)
+	}
+
+	req.ResponseFormat = nil
+	req.ToolChoice = map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "record"}}
+	if c := chatUseCaseRequest(req); c.jsonOutput || !c.forcedFunction {
+		t.Errorf("tool_choice function: jsonOutput = %v, forcedFunction = %v", c.jsonOutput, c.forcedFunction)
+	}
+}
@@ -0,0 +1,258 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/compat"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// requestTypes are the request structs of the JSON API endpoints, by path,
+// for strict unknown-field checks
+var requestTypes = map[string]reflect.Type{
+	"/v1/chat/completions": reflect.TypeOf(models.ChatCompletionRequest{}),
+	"/v1/completions":      reflect.TypeOf(models.CompletionRequest{}),
+	"/v1/embeddings":       reflect.TypeOf(models.EmbeddingRequest{}),
+	"/v1/lint":             reflect.TypeOf(models.ChatCompletionRequest{}),
+	"/v1/messages":         reflect.TypeOf(models.AnthropicMessageRequest{}),
+}
+
+// requestLimitError is a request rejected by the request limits
+type requestLimitError struct {
+	status  int
+	code    string
+	param   string
+	message string
+}
+
+// requestLimits returns a middleware that bounds API request bodies and
+// checks their structure before the handlers decode them: bodies over
+// max_request_bytes are refused with 413 without being read past the limit,
+// and JSON bodies are checked for their message count, their total content
+// length and, with unknown_fields.strict, unknown fields. Rejections are
+// OpenAI-style errors. Bodies that are not JSON objects are left for the
+// handlers to report.
+func requestLimits(cfg config.RequestLimitsConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := cfg.MaxRequestBytes
+			if limit > 0 && r.ContentLength > limit {
+				writeRequestLimitError(w, r, tooLargeError(limit))
+				return
+			}
+			var reader io.Reader = r.Body
+			if limit > 0 {
+				reader = io.LimitReader(r.Body, limit+1)
+			}
+			body, err := io.ReadAll(reader)
+			r.Body.Close()
+			if err != nil {
+				writeRequestLimitError(w, r, &requestLimitError{http.StatusBadRequest, "invalid_body", "", "Failed to read request body"})
+				return
+			}
+			if limit > 0 && int64(len(body)) > limit {
+				writeRequestLimitError(w, r, tooLargeError(limit))
+				return
+			}
+			if limitErr := checkRequestStructure(cfg, r.URL.Path, body); limitErr != nil {
+				writeRequestLimitError(w, r, limitErr)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tooLargeError(limit int64) *requestLimitError {
+	return &requestLimitError{http.StatusRequestEntityTooLarge, "request_too_large", "",
+		fmt.Sprintf("Request body exceeds the %d byte limit", limit)}
+}
+
+// resolvedRequestLimits checks the structure of bodies the blob resolver
+// expanded once more, since requestLimits saw their blob references rather
+// than the contents. Their size is bounded by blobs.max_request_bytes.
+func resolvedRequestLimits(cfg config.RequestLimitsConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !middleware.BlobsResolved(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				writeRequestLimitError(w, r, &requestLimitError{http.StatusBadRequest, "invalid_body", "", "Failed to read request body"})
+				return
+			}
+			if limitErr := checkRequestStructure(cfg, r.URL.Path, body); limitErr != nil {
+				writeRequestLimitError(w, r, limitErr)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkRequestStructure checks a JSON request body against the structural
+// limits, returning the first one it breaks. Unknown fields are checked when
+// unknown field tracking is strict.
+func checkRequestStructure(cfg config.RequestLimitsConfig, path string, body []byte) *requestLimitError {
+	strict := compat.Default().Strict()
+	if !strict && cfg.MaxMessages <= 0 && cfg.MaxContentChars <= 0 {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	var messages []map[string]json.RawMessage
+	if raw, ok := fields["messages"]; ok {
+		json.Unmarshal(raw, &messages)
+	}
+
+	if strict {
+		if t, ok := requestTypes[strings.TrimSuffix(path, "/")]; ok {
+			if name := unknownField(fields, knownFields(t)); name != "" {
+				return unknownFieldError(name)
+			}
+			if _, ok := fields["messages"]; ok {
+				known := knownFields(reflect.TypeOf(models.ChatMessage{}))
+				for i, message := range messages {
+					if name := unknownField(message, known); name != "" {
+						return unknownFieldError(fmt.Sprintf("messages[%d].%s", i, name))
+					}
+				}
+			}
+		}
+	}
+
+	if cfg.MaxMessages > 0 && len(messages) > cfg.MaxMessages {
+		return &requestLimitError{http.StatusBadRequest, "too_many_messages", "messages",
+			fmt.Sprintf("Request has %d messages, more than the limit of %d", len(messages), cfg.MaxMessages)}
+	}
+
+	if cfg.MaxContentChars > 0 {
+		total := 0
+		for _, message := range messages {
+			total += contentChars(message["content"])
+		}
+		for _, name := range []string{"system", "prompt", "input"} {
+			total += contentChars(fields[name])
+		}
+		if total > cfg.MaxContentChars {
+			return &requestLimitError{http.StatusBadRequest, "content_too_long", "messages",
+				fmt.Sprintf("Request content is %d characters, more than the limit of %d", total, cfg.MaxContentChars)}
+		}
+	}
+	return nil
+}
+
+// unknownField returns a field of object that is not in known, if any; names
+// match case-insensitively, as they do when decoding
+func unknownField(object map[string]json.RawMessage, known map[string]bool) string {
+	var unknown []string
+	for name := range object {
+		if !known[strings.ToLower(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return ""
+	}
+	// Report the same field for the same request
+	sort.Strings(unknown)
+	return unknown[0]
+}
+
+// unsupportedFieldCode is the error code of requests rejected for fields
+// that would be ignored, whether the gateway or the provider ignores them
+const unsupportedFieldCode = "unsupported_field"
+
+func unknownFieldError(param string) *requestLimitError {
+	return &requestLimitError{http.StatusBadRequest, unsupportedFieldCode, param,
+		"Unrecognized request argument supplied: " + param}
+}
+
+// contentChars counts the characters of a content value: a string, a list of
+// strings, or a list of parts with text
+func contentChars(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return utf8.RuneCountInString(text)
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		return 0
+	}
+	total := 0
+	for _, item := range items {
+		var part struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(item, &text) == nil {
+			total += utf8.RuneCountInString(text)
+		} else if json.Unmarshal(item, &part) == nil {
+			total += utf8.RuneCountInString(part.Text)
+		}
+	}
+	return total
+}
+
+// writeRequestLimitError writes an OpenAI-style error for a rejected
+// request; a rejected unknown field is counted for the ignored fields report
+func writeRequestLimitError(w http.ResponseWriter, r *http.Request, err *requestLimitError) {
+	if err.code == unsupportedFieldCode {
+		compat.Default().Observe(middleware.TenantID(r), r.URL.Path, "", compat.KindUnknown, []string{err.param})
+	}
+	logger.Info().
+		Str("request_id", chimiddleware.GetReqID(r.Context())).
+		Str("path", r.URL.Path).
+		Str("code", err.code).
+		Str("param", err.param).
+		Msg("Request rejected by request limits")
+	err.write(w)
+}
+
+// write writes e as an OpenAI-style error
+func (e *requestLimitError) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error: models.APIError{
+			Message: e.message,
+			Type:    "invalid_request_error",
+			Param:   e.param,
+			Code:    e.code,
+		},
+	})
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestRequestLimits(t *testing.T) {
+	cfg := config.RequestLimitsConfig{
+		Enabled:         true,
+		MaxRequestBytes: 200,
+		MaxMessages:     2,
+		MaxContentChars: 20,
+	}
+	// Unknown fields are rejected when unknown field tracking is strict
+	tracker := enableFieldTracking(t, true)
+	var received string
+	handler := requestLimits(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantParam  string
+	}{
+		{
+			name:       "within limits",
+			path:       "/v1/chat/completions",
+			body:       `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "too large",
+			path:       "/v1/chat/completions",
+			body:       `{"model":"gpt-4o","messages":[{"role":"user","content":"` + strings.Repeat("a", 200) + `"}]}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "request_too_large",
+		},
+		{
+			name:       "unknown top-level field",
+			path:       "/v1/chat/completions",
+			body:       `{"model":"gpt-4o","temprature":0.2,"messages":[{"role":"user","content":"Hi"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "unsupported_field",
+			wantParam:  "temprature",
+		},
+		{
+			name:       "unknown message field",
+			path:       "/v1/messages/",
+			body:       `{"model":"claude-3","max_tokens":10,"messages":[{"role":"user","content":"Hi","cache":true}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "unsupported_field",
+			wantParam:  "messages[0].cache",
+		},
+		{
+			name:       "too many messages",
+			path:       "/v1/chat/completions",
+			body:       `{"model":"gpt-4o","messages":[{"role":"user","content":"a"},{"role":"assistant","content":"b"},{"role":"user","content":"c"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "too_many_messages",
+			wantParam:  "messages",
+		},
+		{
+			name:       "content too long",
+			path:       "/v1/messages",
+			body:       `{"model":"claude-3","max_tokens":10,"system":"ünïcödé counts as characters","messages":[{"role":"user","content":"Hi"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "content_too_long",
+			wantParam:  "messages",
+		},
+		{
+			name:       "malformed JSON is left to the handler",
+			path:       "/v1/chat/completions",
+			body:       `{"model":`,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if received != tt.body {
+					t.Errorf("handler read %q, want the original body", received)
+				}
+				return
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Type != "invalid_request_error" || resp.Error.Code != tt.wantCode || resp.Error.Param != tt.wantParam {
+				t.Errorf("error = %+v, want code %q and param %q", resp.Error, tt.wantCode, tt.wantParam)
+			}
+		})
+	}
+	if fields, _ := tracker.Report(); len(fields) != 2 {
+		t.Errorf("report = %+v, want the two rejected unknown fields", fields)
+	}
+}
+
+func TestRequestLimits_ChunkedBodyOverLimit(t *testing.T) {
+	handler := requestLimits(config.RequestLimitsConfig{Enabled: true, MaxRequestBytes: 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("an oversized body reached the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(strings.Repeat("x", 100)))
+	req.ContentLength = -1
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rr.Code)
+	}
+}
@@ -0,0 +1,280 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/websocket"
+)
+
+const (
+	// wsMaxRequestBytes bounds the chat request a WebSocket client sends when
+	// request_limits sets no smaller limit
+	wsMaxRequestBytes = 10 << 20
+	// wsCloseTimeout is how long the client has to acknowledge the close
+	wsCloseTimeout = time.Second
+)
+
+// wsClientMessage is a control message sent by a client during a stream
+type wsClientMessage struct {
+	Type string `json:"type"`
+}
+
+// ChatCompletionsWebSocket handles GET /v1/chat/completions/ws. The client
+// sends one chat completion request as a text message; the response is
+// streamed as JSON messages: {"type":"chunk","data":<chunk>} per chunk,
+// {"type":"<event>","data":...} for named SSE events such as citations,
+// {"type":"error","error":...} on failure, then {"type":"done"}, and the
+// gateway closes the connection. A {"type":"cancel"} message, or the client
+// closing the connection, cancels the provider stream and ends the response
+// with {"type":"cancelled"}. The request is held to the request limits: a
+// larger message closes the connection with 1009, and a structural limit is
+// reported as an error message.
+func (h *Handler) ChatCompletionsWebSocket(w http.ResponseWriter, r *http.Request) {
+	var limits config.RequestLimitsConfig
+	if h.config != nil {
+		limits = h.config.RequestLimits
+	}
+	maxRequest := int64(wsMaxRequestBytes)
+	if limits.Enabled && limits.MaxRequestBytes > 0 && limits.MaxRequestBytes < maxRequest {
+		maxRequest = limits.MaxRequestBytes
+	}
+
+	conn, err := websocket.Upgrade(w, r, maxRequest)
+	if err != nil {
+		if errors.Is(err, websocket.ErrHandshake) {
+			w.Header().Set("Upgrade", "websocket")
+			h.writeError(w, http.StatusUpgradeRequired, "invalid_request", err.Error())
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "websocket_unsupported", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	requestID := chimiddleware.GetReqID(r.Context())
+	op, body, err := conn.ReadMessage()
+	if err != nil {
+		logger.Debug().Err(err).Str("request_id", requestID).Msg("WebSocket closed before a request was sent")
+		return
+	}
+	if op != websocket.OpText {
+		conn.WriteClose(websocket.CloseUnsupportedData, "expected a JSON text message")
+		return
+	}
+	if limits.Enabled {
+		if limitErr := checkRequestStructure(limits, "/v1/chat/completions", body); limitErr != nil {
+			out := newWSStreamWriter(conn, &atomic.Bool{})
+			writeRequestLimitError(out, r, limitErr)
+			out.finish()
+			conn.WriteClose(websocket.CloseNormal, "")
+			return
+		}
+	}
+	body, err = streamingRequestBody(body)
+	if err != nil {
+		writeWSMessage(conn, map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": "invalid_request", "message": "Failed to parse request: " + err.Error()},
+		})
+		conn.WriteClose(websocket.CloseNormal, "")
+		return
+	}
+
+	// Cancel the provider stream when the client asks to or goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	var cancelled atomic.Bool
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				if ctx.Err() == nil {
+					cancelled.Store(true)
+				}
+				cancel()
+				return
+			}
+			var msg wsClientMessage
+			if json.Unmarshal(data, &msg) == nil && msg.Type == "cancel" {
+				cancelled.Store(true)
+				cancel()
+			}
+		}
+	}()
+
+	req := r.Clone(ctx)
+	req.Method = http.MethodPost
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	out := newWSStreamWriter(conn, &cancelled)
+	h.ChatCompletions(out, req)
+	out.finish()
+	cancel()
+
+	// Wait for the client to acknowledge the close before dropping the connection
+	conn.WriteClose(websocket.CloseNormal, "")
+	select {
+	case <-readerDone:
+	case <-time.After(wsCloseTimeout):
+	}
+	logger.Debug().
+		Str("request_id", requestID).
+		Bool("cancelled", cancelled.Load()).
+		Msg("WebSocket chat stream ended")
+}
+
+// streamingRequestBody returns a chat request with stream set, keeping the
+// client's other fields as sent
+func streamingRequestBody(body []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	fields["stream"] = json.RawMessage("true")
+	return json.Marshal(fields)
+}
+
+// writeWSMessage sends v as a JSON text message
+func writeWSMessage(conn *websocket.Conn, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.OpText, data)
+}
+
+// wsStreamWriter is the http.ResponseWriter the chat handler streams to for a
+// WebSocket client. It turns SSE events into JSON messages as they are
+// written; a response that is not a stream, such as an error written before
+// streaming began, is sent once complete.
+type wsStreamWriter struct {
+	conn *websocket.Conn
+	// cancelled is set once the client cancelled the stream; the errors the
+	// cancellation causes are not sent
+	cancelled *atomic.Bool
+	header    http.Header
+	status    int
+	// sse is set once the response turned out to be an event stream
+	sse     bool
+	pending []byte
+	// event is the name of the SSE event being read, if any
+	event string
+	done  bool
+	body  bytes.Buffer
+}
+
+func newWSStreamWriter(conn *websocket.Conn, cancelled *atomic.Bool) *wsStreamWriter {
+	return &wsStreamWriter{conn: conn, cancelled: cancelled, header: make(http.Header)}
+}
+
+func (s *wsStreamWriter) Header() http.Header {
+	return s.header
+}
+
+func (s *wsStreamWriter) WriteHeader(status int) {
+	if s.status != 0 {
+		return
+	}
+	s.status = status
+	s.sse = strings.HasPrefix(s.header.Get("Content-Type"), "text/event-stream")
+}
+
+func (s *wsStreamWriter) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.WriteHeader(http.StatusOK)
+	}
+	if !s.sse {
+		return s.body.Write(p)
+	}
+	s.pending = append(s.pending, p...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := s.pending[:i]
+		s.pending = s.pending[i+1:]
+		if err := s.line(bytes.TrimSpace(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush is a no-op: each event is sent as soon as its line is complete
+func (s *wsStreamWriter) Flush() {}
+
+// line sends one SSE line as a message
+func (s *wsStreamWriter) line(line []byte) error {
+	if len(line) == 0 {
+		s.event = ""
+		return nil
+	}
+	if event, ok := bytes.CutPrefix(line, []byte("event:")); ok {
+		s.event = string(bytes.TrimSpace(event))
+		return nil
+	}
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		// Comments such as keep-alives
+		return nil
+	}
+	data = bytes.TrimSpace(data)
+	if string(data) == "[DONE]" {
+		s.done = true
+		return nil
+	}
+	if !json.Valid(data) {
+		return nil
+	}
+
+	var probe struct {
+		Error json.RawMessage `json:"error"`
+	}
+	switch {
+	case s.event != "":
+		return writeWSMessage(s.conn, map[string]interface{}{"type": s.event, "data": json.RawMessage(data)})
+	case json.Unmarshal(data, &probe) == nil && probe.Error != nil:
+		if s.cancelled.Load() {
+			return nil
+		}
+		return writeWSMessage(s.conn, map[string]interface{}{"type": "error", "error": probe.Error})
+	}
+	return writeWSMessage(s.conn, map[string]interface{}{"type": "chunk", "data": json.RawMessage(data)})
+}
+
+// finish sends the end of the response: the error or response that was not
+// streamed, then "cancelled" or "done"
+func (s *wsStreamWriter) finish() {
+	if !s.sse && s.body.Len() > 0 {
+		var resp struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(s.body.Bytes(), &resp) == nil && resp.Error != nil && s.status >= http.StatusBadRequest {
+			writeWSMessage(s.conn, map[string]interface{}{"type": "error", "status": s.status, "error": resp.Error})
+		} else if json.Valid(s.body.Bytes()) {
+			writeWSMessage(s.conn, map[string]interface{}{"type": "response", "data": json.RawMessage(s.body.Bytes())})
+		}
+	}
+	switch {
+	case s.cancelled.Load():
+		writeWSMessage(s.conn, map[string]string{"type": "cancelled"})
+	case s.done:
+		writeWSMessage(s.conn, map[string]string{"type": "done"})
+	}
+}
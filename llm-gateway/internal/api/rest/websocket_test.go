@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/internal/websocket"
+)
+
+// wsTestClient opens a WebSocket to url and exchanges raw frames
+type wsTestClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, url string) *wsTestClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /v1/chat/completions/ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake = %v, %v", resp, err)
+	}
+	return &wsTestClient{conn: conn, br: br}
+}
+
+// sendText writes a masked text frame
+func (c *wsTestClient) sendText(payload string) {
+	frame := []byte{0x81, 0x80}
+	switch n := len(payload); {
+	case n <= 125:
+		frame[1] |= byte(n)
+	default:
+		frame[1] |= 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	}
+	frame = append(frame, 0, 0, 0, 0) // a zero mask leaves the payload as is
+	c.conn.Write(append(frame, payload...))
+}
+
+// messages reads text messages until the server closes the connection
+func (c *wsTestClient) messages(t *testing.T, each func(map[string]interface{})) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.br, header[:]); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		length := int(header[1] & 0x7F)
+		if length == 126 {
+			var ext [2]byte
+			io.ReadFull(c.br, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, length)
+		io.ReadFull(c.br, payload)
+		if header[0]&0x0F == websocket.OpClose {
+			return out
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("message %q: %v", payload, err)
+		}
+		out = append(out, msg)
+		if each != nil {
+			each(msg)
+		}
+	}
+}
+
+// wsTestServer serves the WebSocket endpoint in front of an OpenAI backend
+func wsTestServer(t *testing.T, backend http.HandlerFunc) *httptest.Server {
+	return wsTestServerWithConfig(t, &config.Config{}, backend)
+}
+
+func wsTestServerWithConfig(t *testing.T, cfg *config.Config, backend http.HandlerFunc) *httptest.Server {
+	upstream := httptest.NewServer(backend)
+	t.Cleanup(upstream.Close)
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: upstream.URL}))
+	h := NewHandler(cfg, proxy.NewRouter(registry, cfg))
+	server := httptest.NewServer(http.HandlerFunc(h.ChatCompletionsWebSocket))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sseChunk(w http.ResponseWriter, content string) {
+	fmt.Fprintf(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"finish_reason\":null}]}\n\n", content)
+	w.(http.Flusher).Flush()
+}
+
+func TestChatCompletionsWebSocket_Streams(t *testing.T) {
+	server := wsTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		sseChunk(w, "Hello")
+		sseChunk(w, " world")
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+
+	client := dialWS(t, server.URL)
+	client.sendText(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`)
+	msgs := client.messages(t, nil)
+
+	var content strings.Builder
+	for _, msg := range msgs[:len(msgs)-1] {
+		if msg["type"] != "chunk" {
+			t.Fatalf("message %v, want a chunk", msg)
+		}
+		choices := msg["data"].(map[string]interface{})["choices"].([]interface{})
+		delta := choices[0].(map[string]interface{})["delta"].(map[string]interface{})
+		content.WriteString(delta["content"].(string))
+	}
+	if content.String() != "Hello world" {
+		t.Errorf("streamed content = %q", content.String())
+	}
+	if last := msgs[len(msgs)-1]; last["type"] != "done" {
+		t.Errorf("last message = %v, want done", last)
+	}
+}
+
+func TestChatCompletionsWebSocket_Cancel(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	server := wsTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		sseChunk(w, "Once upon a time")
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			t.Error("provider stream was not cancelled")
+		}
+	})
+
+	client := dialWS(t, server.URL)
+	client.sendText(`{"model":"gpt-4o","messages":[{"role":"user","content":"Tell me a story"}]}`)
+	msgs := client.messages(t, func(msg map[string]interface{}) {
+		if msg["type"] == "chunk" {
+			client.sendText(`{"type":"cancel"}`)
+		}
+	})
+
+	if last := msgs[len(msgs)-1]; last["type"] != "cancelled" {
+		t.Errorf("messages = %v, want to end with cancelled", msgs)
+	}
+	for _, msg := range msgs {
+		if msg["type"] == "error" {
+			t.Errorf("cancellation should not be reported as an error: %v", msg)
+		}
+	}
+	<-upstreamDone
+}
+
+func TestChatCompletionsWebSocket_InvalidRequest(t *testing.T) {
+	server := wsTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("invalid request reached the provider")
+	})
+
+	client := dialWS(t, server.URL)
+	client.sendText(`{"model":"gpt-4o","messages":[]}`)
+	msgs := client.messages(t, nil)
+	if len(msgs) != 1 || msgs[0]["type"] != "error" || msgs[0]["status"] != float64(http.StatusBadRequest) {
+		t.Errorf("messages = %v, want one 400 error", msgs)
+	}
+}
+
+func TestChatCompletionsWebSocket_RequestLimits(t *testing.T) {
+	cfg := &config.Config{RequestLimits: config.RequestLimitsConfig{Enabled: true, MaxRequestBytes: 150, MaxMessages: 1}}
+	server := wsTestServerWithConfig(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a request over the limits reached the provider")
+	})
+
+	client := dialWS(t, server.URL)
+	client.sendText(`{"model":"gpt-4o","messages":[{"role":"user","content":"a"},{"role":"user","content":"b"}]}`)
+	msgs := client.messages(t, nil)
+	if len(msgs) != 1 || msgs[0]["type"] != "error" || msgs[0]["status"] != float64(http.StatusBadRequest) {
+		t.Fatalf("messages = %v, want one 400 error", msgs)
+	}
+	if apiErr, _ := msgs[0]["error"].(map[string]interface{}); apiErr["code"] != "too_many_messages" {
+		t.Errorf("error = %v, want too_many_messages", msgs[0]["error"])
+	}
+
+	// A larger message closes the connection unread
+	client = dialWS(t, server.URL)
+	client.sendText(`{"model":"gpt-4o","messages":[{"role":"user","content":"` + strings.Repeat("a", 200) + `"}]}`)
+	if msgs := client.messages(t, nil); len(msgs) != 0 {
+		t.Errorf("messages = %v, want the connection closed", msgs)
+	}
+}
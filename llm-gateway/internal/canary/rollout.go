@@ -0,0 +1,370 @@
+// Package canary rolls out configuration changes to a share of traffic
+// first. While a rollout is in progress, each API request is assigned to the
+// canary or the stable cohort; the cohorts' error rates and latencies are
+// compared, and the change is rolled back as soon as the canary regresses or
+// promoted once the window ends with enough traffic to compare.
+package canary
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the canary module logger; its level can be set via log.modules.canary
+var logger = observability.ModuleLogger("canary")
+
+// maxEvents is the number of rollout events kept for the admin API
+const maxEvents = 20
+
+// ErrNoRollout is returned when promoting or rolling back without a rollout in progress
+var ErrNoRollout = errors.New("no canary rollout in progress")
+
+// Rollout actions recorded in events
+const (
+	ActionStarted    = "started"
+	ActionExtended   = "extended"
+	ActionPromoted   = "promoted"
+	ActionRolledBack = "rolled_back"
+)
+
+// CohortStats summarizes the requests of one cohort
+type CohortStats struct {
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	ErrorRate     float64 `json:"error_rate"`
+	MeanLatencyMs float64 `json:"mean_latency_ms"`
+}
+
+// Event records a rollout starting or ending
+type Event struct {
+	Time    time.Time    `json:"time"`
+	Version string       `json:"version"`
+	Action  string       `json:"action"`
+	Reason  string       `json:"reason,omitempty"`
+	Canary  *CohortStats `json:"canary,omitempty"`
+	Stable  *CohortStats `json:"stable,omitempty"`
+}
+
+// cohort accumulates the outcome of one cohort's requests
+type cohort struct {
+	requests int64
+	errors   int64
+	latency  time.Duration
+}
+
+func (c *cohort) stats() *CohortStats {
+	s := &CohortStats{Requests: c.requests, Errors: c.errors}
+	if c.requests > 0 {
+		s.ErrorRate = float64(c.errors) / float64(c.requests)
+		s.MeanLatencyMs = float64(c.latency.Milliseconds()) / float64(c.requests)
+	}
+	return s
+}
+
+// rollout is a change being canaried
+type rollout struct {
+	version  string
+	started  time.Time
+	ends     time.Time
+	promote  func()
+	rollback func()
+	timer    *time.Timer
+	canary   cohort
+	stable   cohort
+}
+
+// Rollout runs canary rollouts, one at a time
+type Rollout struct {
+	cfg config.CanaryConfig
+
+	mu     sync.Mutex
+	active *rollout
+	events []Event
+}
+
+// New creates a rollout controller from configuration, or returns nil if canary rollouts are disabled
+func New(cfg config.CanaryConfig) *Rollout {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Rollout{cfg: cfg}
+}
+
+// Begin starts canarying a change: promote applies it to all traffic and
+// rollback discards it. The caller is expected to have made the change
+// visible to canary requests (see InCanary). A rollout still in progress is
+// rolled back first.
+func (r *Rollout) Begin(version string, promote, rollback func()) {
+	if err := r.Rollback("superseded by " + version); err != nil && !errors.Is(err, ErrNoRollout) {
+		logger.Warn().Err(err).Msg("Failed to roll back superseded canary")
+	}
+
+	now := time.Now()
+	ro := &rollout{version: version, started: now, ends: now.Add(r.cfg.Window), promote: promote, rollback: rollback}
+	r.mu.Lock()
+	r.active = ro
+	ro.timer = time.AfterFunc(r.cfg.Window, func() { r.windowEnded(ro) })
+	r.recordEvent(Event{Time: ro.started, Version: version, Action: ActionStarted})
+	r.mu.Unlock()
+
+	logger.Info().
+		Str("version", version).
+		Float64("percent", r.cfg.Percent).
+		Dur("window", r.cfg.Window).
+		Msg("Canary rollout started")
+	observability.LogAudit(context.Background(), "canary.start", version, map[string]interface{}{
+		"percent": r.cfg.Percent,
+		"window":  r.cfg.Window.String(),
+	})
+}
+
+// Promote applies the change in progress to all traffic
+func (r *Rollout) Promote(reason string) error {
+	return r.finish(nil, ActionPromoted, reason)
+}
+
+// Rollback discards the change in progress
+func (r *Rollout) Rollback(reason string) error {
+	return r.finish(nil, ActionRolledBack, reason)
+}
+
+// windowEnded promotes ro unless it already ended. A change is only promoted
+// once both cohorts had enough traffic to compare; until then the window is
+// extended, up to MaxWindow, after which the change is rolled back.
+func (r *Rollout) windowEnded(ro *rollout) {
+	r.mu.Lock()
+	if r.active != ro {
+		r.mu.Unlock()
+		return
+	}
+	if ro.canary.requests >= r.cfg.MinRequests && ro.stable.requests >= r.cfg.MinRequests {
+		r.mu.Unlock()
+		r.finish(ro, ActionPromoted, "window ended without regression")
+		return
+	}
+	if next := ro.ends.Add(r.cfg.Window); !next.After(ro.started.Add(r.cfg.MaxWindow)) {
+		ro.ends = next
+		ro.timer.Reset(r.cfg.Window)
+		event := Event{
+			Time:    time.Now(),
+			Version: ro.version,
+			Action:  ActionExtended,
+			Reason:  "too little traffic to compare",
+			Canary:  ro.canary.stats(),
+			Stable:  ro.stable.stats(),
+		}
+		r.recordEvent(event)
+		r.mu.Unlock()
+
+		logger.Info().
+			Str("version", ro.version).
+			Time("ends", next).
+			Interface("canary", event.Canary).
+			Interface("stable", event.Stable).
+			Msg("Canary window extended")
+		return
+	}
+	r.mu.Unlock()
+	r.finish(ro, ActionRolledBack, "too little traffic to compare by max_window")
+}
+
+// finish ends the rollout in progress (if only is set, only if it is still
+// that rollout) by promoting or rolling it back
+func (r *Rollout) finish(only *rollout, action, reason string) error {
+	if r == nil {
+		return ErrNoRollout
+	}
+	r.mu.Lock()
+	ro := r.active
+	if ro == nil || (only != nil && ro != only) {
+		r.mu.Unlock()
+		return ErrNoRollout
+	}
+	r.active = nil
+	ro.timer.Stop()
+	event := Event{
+		Time:    time.Now(),
+		Version: ro.version,
+		Action:  action,
+		Reason:  reason,
+		Canary:  ro.canary.stats(),
+		Stable:  ro.stable.stats(),
+	}
+	r.recordEvent(event)
+	r.mu.Unlock()
+
+	if action == ActionPromoted {
+		ro.promote()
+	} else {
+		ro.rollback()
+	}
+
+	entry := logger.Info()
+	if action == ActionRolledBack {
+		entry = logger.Warn()
+	}
+	entry.Str("version", ro.version).
+		Str("reason", reason).
+		Interface("canary", event.Canary).
+		Interface("stable", event.Stable).
+		Msg("Canary rollout " + action)
+	observability.LogAudit(context.Background(), "canary."+action, ro.version, map[string]interface{}{
+		"reason": reason,
+		"canary": event.Canary,
+		"stable": event.Stable,
+	})
+	return nil
+}
+
+// recordEvent keeps the last maxEvents events. Callers hold r.mu.
+func (r *Rollout) recordEvent(event Event) {
+	if len(r.events) >= maxEvents {
+		r.events = append(r.events[:0], r.events[1:]...)
+	}
+	r.events = append(r.events, event)
+}
+
+type canaryKey struct{}
+
+// InCanary reports whether the request in ctx was assigned to the canary cohort
+func InCanary(ctx context.Context) bool {
+	in, _ := ctx.Value(canaryKey{}).(bool)
+	return in
+}
+
+// Middleware assigns each request to a cohort while a rollout is in progress
+// and records its outcome
+func (r *Rollout) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if r == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.mu.Lock()
+			ro := r.active
+			r.mu.Unlock()
+			if ro == nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			inCanary := rand.Float64()*100 < r.cfg.Percent
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(sw, req.WithContext(context.WithValue(req.Context(), canaryKey{}, inCanary)))
+			r.record(ro, inCanary, sw.status, time.Since(start))
+		})
+	}
+}
+
+// record adds a request outcome to ro and rolls it back if the canary regressed
+func (r *Rollout) record(ro *rollout, inCanary bool, status int, latency time.Duration) {
+	r.mu.Lock()
+	c := &ro.stable
+	if inCanary {
+		c = &ro.canary
+	}
+	c.requests++
+	if status >= 500 {
+		c.errors++
+	}
+	c.latency += latency
+	reason := r.regression(ro)
+	r.mu.Unlock()
+
+	if reason != "" {
+		r.finish(ro, ActionRolledBack, reason)
+	}
+}
+
+// regression returns why the canary of ro regressed against the stable
+// cohort, or "" if it did not (or there is too little traffic to tell).
+// Callers hold r.mu.
+func (r *Rollout) regression(ro *rollout) string {
+	if ro.canary.requests < r.cfg.MinRequests || ro.stable.requests < r.cfg.MinRequests {
+		return ""
+	}
+	canary, stable := ro.canary.stats(), ro.stable.stats()
+	if canary.ErrorRate-stable.ErrorRate > r.cfg.MaxErrorRateIncrease {
+		return "error rate regressed"
+	}
+	if stable.MeanLatencyMs > 0 && canary.MeanLatencyMs > stable.MeanLatencyMs*r.cfg.MaxLatencyRatio {
+		return "latency regressed"
+	}
+	return ""
+}
+
+// Stats returns the rollout in progress and recent rollout events
+func (r *Rollout) Stats() map[string]interface{} {
+	if r == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := map[string]interface{}{
+		"enabled": true,
+		"percent": r.cfg.Percent,
+		"window":  r.cfg.Window.String(),
+		"events":  append([]Event(nil), r.events...),
+	}
+	if ro := r.active; ro != nil {
+		stats["active"] = map[string]interface{}{
+			"version": ro.version,
+			"started": ro.started,
+			"ends":    ro.ends,
+			"canary":  ro.canary.stats(),
+			"stable":  ro.stable.stats(),
+		}
+	}
+	return stats
+}
+
+// statusWriter captures the response status for cohort stats
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher for streaming responses
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// defaultRollout is the process-wide rollout controller used by the control
+// plane syncer, API router and admin endpoints
+var defaultRollout atomic.Pointer[Rollout]
+
+// SetDefault sets the process-wide rollout controller
+func SetDefault(r *Rollout) {
+	defaultRollout.Store(r)
+}
+
+// Default returns the process-wide rollout controller (nil when canary rollouts are disabled)
+func Default() *Rollout {
+	return defaultRollout.Load()
+}
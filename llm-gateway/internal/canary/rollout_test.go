@@ -0,0 +1,123 @@
+package canary
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func testConfig() config.CanaryConfig {
+	return config.CanaryConfig{
+		Enabled:              true,
+		Percent:              50,
+		Window:               time.Hour,
+		MaxWindow:            time.Hour,
+		MinRequests:          20,
+		MaxErrorRateIncrease: 0.05,
+		MaxLatencyRatio:      2,
+	}
+}
+
+func TestRollout_RollsBackOnErrors(t *testing.T) {
+	r := New(testConfig())
+	var promoted, rolledBack atomic.Bool
+	r.Begin("v2", func() { promoted.Store(true) }, func() { rolledBack.Store(true) })
+
+	// Canary requests fail, stable ones succeed
+	handler := r.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if InCanary(req.Context()) {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	for i := 0; i < 200 && !rolledBack.Load(); i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	if !rolledBack.Load() || promoted.Load() {
+		t.Fatalf("rolledBack = %v, promoted = %v, want a rollback", rolledBack.Load(), promoted.Load())
+	}
+	events := r.Stats()["events"].([]Event)
+	if last := events[len(events)-1]; last.Action != ActionRolledBack || last.Reason != "error rate regressed" {
+		t.Errorf("last event = %+v", last)
+	}
+	if r.Stats()["active"] != nil {
+		t.Error("rollout still active after rollback")
+	}
+}
+
+func TestRollout_PromotesAfterWindow(t *testing.T) {
+	cfg := testConfig()
+	cfg.Window = 50 * time.Millisecond
+	cfg.MaxWindow = cfg.Window
+	r := New(cfg)
+	promoted := make(chan struct{})
+	r.Begin("v2", func() { close(promoted) }, func() { t.Error("rolled back") })
+
+	handler := r.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	for range 200 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	select {
+	case <-promoted:
+	case <-time.After(time.Second):
+		t.Fatal("not promoted after the window")
+	}
+	if err := r.Promote("again"); err != ErrNoRollout {
+		t.Errorf("Promote after the window = %v, want ErrNoRollout", err)
+	}
+}
+
+func TestRollout_TooLittleTrafficExtendsThenRollsBack(t *testing.T) {
+	cfg := testConfig()
+	cfg.Window = 20 * time.Millisecond
+	cfg.MaxWindow = 40 * time.Millisecond
+	r := New(cfg)
+	rolledBack := make(chan struct{})
+	r.Begin("v2", func() { t.Error("promoted without traffic") }, func() { close(rolledBack) })
+
+	select {
+	case <-rolledBack:
+	case <-time.After(time.Second):
+		t.Fatal("not rolled back after max_window")
+	}
+	var actions []string
+	for _, e := range r.Stats()["events"].([]Event) {
+		actions = append(actions, e.Action)
+	}
+	if len(actions) != 3 || actions[1] != ActionExtended || actions[2] != ActionRolledBack {
+		t.Errorf("actions = %v, want started, extended, rolled_back", actions)
+	}
+}
+
+func TestRollout_Superseded(t *testing.T) {
+	r := New(testConfig())
+	var first atomic.Bool
+	r.Begin("v2", func() { t.Error("superseded rollout promoted") }, func() { first.Store(true) })
+	r.Begin("v3", func() {}, func() {})
+
+	if !first.Load() {
+		t.Error("superseded rollout not rolled back")
+	}
+	if active := r.Stats()["active"].(map[string]interface{}); active["version"] != "v3" {
+		t.Errorf("active = %v", active)
+	}
+}
+
+func TestInCanary_OutsideRollout(t *testing.T) {
+	var r *Rollout
+	handler := r.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if InCanary(req.Context()) {
+			t.Error("request in canary without a rollout")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if InCanary(context.Background()) {
+		t.Error("background context in canary")
+	}
+}
@@ -0,0 +1,63 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_TimersFireInOrder(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	f := NewFake(start)
+	late := f.NewTimer(2 * time.Second)
+	early := f.NewTimer(time.Second)
+
+	f.Advance(time.Second)
+	select {
+	case at := <-early.C():
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("fired at %v", at)
+		}
+	default:
+		t.Fatal("timer due after 1s did not fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("timer due after 2s fired early")
+	default:
+	}
+
+	if !late.Stop() {
+		t.Error("Stop() of a pending timer = false")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-late.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if got := f.Since(start); got != time.Hour+time.Second {
+		t.Errorf("Since() = %v", got)
+	}
+}
+
+func TestFake_ZeroTimerFiresImmediately(t *testing.T) {
+	f := NewFake(time.Now())
+	select {
+	case <-f.NewTimer(0).C():
+	default:
+		t.Error("timer for 0 did not fire")
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	f := NewFake(time.Now())
+	done := make(chan struct{})
+	go func() {
+		<-f.NewTimer(time.Minute).C()
+		close(done)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	<-done
+}
@@ -0,0 +1,155 @@
+// Package compat reports request fields that are silently ignored, per
+// client: fields the gateway does not know, and fields the chosen provider
+// does not support. Client teams use the report to find parameters that never
+// took effect instead of debugging why they do nothing. State is kept per
+// replica.
+package compat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the compat module logger; its level can be set via log.modules.compat
+var logger = observability.ModuleLogger("compat")
+
+// Field kinds
+const (
+	// KindUnknown is a field the gateway does not know; it never reaches a provider
+	KindUnknown = "unknown"
+	// KindUnsupported is a known field the chosen provider drops
+	KindUnsupported = "unsupported"
+)
+
+// Field is an ignored field seen in a client's requests
+type Field struct {
+	// ID identifies the entry: client, endpoint, provider and field
+	ID       string `json:"id"`
+	Client   string `json:"client"`
+	Field    string `json:"field"`
+	Kind     string `json:"kind"`
+	Endpoint string `json:"endpoint"`
+	// Provider is the provider that dropped an unsupported field
+	Provider  string    `json:"provider,omitempty"`
+	Requests  int64     `json:"requests"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type fieldKey struct {
+	client, field, kind, endpoint, provider string
+}
+
+// Tracker counts ignored fields per client
+type Tracker struct {
+	cfg config.UnknownFieldsConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	fields map[fieldKey]*Field
+	// untracked counts observations of new fields once max_entries was reached
+	untracked int64
+}
+
+// New creates a tracker from configuration, or returns nil if disabled
+func New(cfg config.UnknownFieldsConfig) *Tracker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Tracker{
+		cfg:    cfg,
+		now:    time.Now,
+		fields: make(map[fieldKey]*Field),
+	}
+}
+
+// Strict reports whether requests with ignored fields are rejected
+func (t *Tracker) Strict() bool {
+	return t != nil && t.cfg.Strict
+}
+
+// Observe records that a request of client to endpoint carried fields of
+// kind; provider is the provider that dropped unsupported fields
+func (t *Tracker) Observe(client, endpoint, provider, kind string, fields []string) {
+	if t == nil || len(fields) == 0 {
+		return
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range fields {
+		key := fieldKey{client: client, field: name, kind: kind, endpoint: endpoint, provider: provider}
+		if f, ok := t.fields[key]; ok {
+			f.Requests++
+			f.LastSeen = now
+			continue
+		}
+		if len(t.fields) >= t.cfg.MaxEntries {
+			t.untracked++
+			continue
+		}
+		t.fields[key] = &Field{
+			ID:        strings.Join([]string{client, endpoint, provider, name}, ":"),
+			Client:    client,
+			Field:     name,
+			Kind:      kind,
+			Endpoint:  endpoint,
+			Provider:  provider,
+			Requests:  1,
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		logger.Info().
+			Str("client", client).
+			Str("field", name).
+			Str("kind", kind).
+			Str("endpoint", endpoint).
+			Str("provider", provider).
+			Msg("Client sent an ignored request field")
+	}
+}
+
+// Report returns the ignored fields seen so far, by client and field, and
+// the observations not tracked because max_entries was reached
+func (t *Tracker) Report() ([]Field, int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fields := make([]Field, 0, len(t.fields))
+	for _, f := range t.fields {
+		fields = append(fields, *f)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		return a.Provider < b.Provider
+	})
+	return fields, t.untracked
+}
+
+// defaultTracker is the process-wide tracker used by the API handlers and admin endpoints
+var defaultTracker atomic.Pointer[Tracker]
+
+// SetDefault sets the process-wide tracker
+func SetDefault(t *Tracker) {
+	defaultTracker.Store(t)
+}
+
+// Default returns the process-wide tracker (nil when disabled)
+func Default() *Tracker {
+	return defaultTracker.Load()
+}
@@ -0,0 +1,45 @@
+package compat
+
+import (
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestTracker_CountsPerClientAndField(t *testing.T) {
+	tracker := New(config.UnknownFieldsConfig{Enabled: true, MaxEntries: 3})
+
+	tracker.Observe("team-a", "/v1/chat/completions", "", KindUnknown, []string{"metadata"})
+	tracker.Observe("team-a", "/v1/chat/completions", "", KindUnknown, []string{"metadata"})
+	tracker.Observe("team-a", "/v1/chat/completions", "anthropic", KindUnsupported, []string{"logit_bias"})
+	tracker.Observe("team-b", "/v1/chat/completions", "", KindUnknown, []string{"metadata"})
+	// The report is full: new pairs are only counted
+	tracker.Observe("team-c", "/v1/chat/completions", "", KindUnknown, []string{"metadata", "store"})
+	tracker.Observe("team-b", "/v1/chat/completions", "", KindUnknown, []string{"metadata"})
+
+	fields, untracked := tracker.Report()
+	if len(fields) != 3 || untracked != 2 {
+		t.Fatalf("report = %+v, untracked %d", fields, untracked)
+	}
+	if f := fields[0]; f.Client != "team-a" || f.Field != "logit_bias" || f.Kind != KindUnsupported || f.Provider != "anthropic" || f.Requests != 1 {
+		t.Errorf("fields[0] = %+v", f)
+	}
+	if f := fields[1]; f.Field != "metadata" || f.Requests != 2 || f.ID != "team-a:/v1/chat/completions::metadata" {
+		t.Errorf("fields[1] = %+v", f)
+	}
+	if f := fields[2]; f.Client != "team-b" || f.Requests != 2 {
+		t.Errorf("fields[2] = %+v", f)
+	}
+}
+
+func TestTracker_Disabled(t *testing.T) {
+	tracker := New(config.UnknownFieldsConfig{Strict: true})
+	if tracker != nil {
+		t.Fatal("New() should return nil when disabled")
+	}
+	// A nil tracker is safe to use
+	tracker.Observe("team-a", "/v1/embeddings", "", KindUnknown, []string{"dimensions"})
+	if tracker.Strict() {
+		t.Error("a nil tracker is never strict")
+	}
+}
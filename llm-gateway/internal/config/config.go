@@ -2,10 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/username/llm-gateway/internal/redisconn"
 )
 
 // Config holds all configuration for the gateway
@@ -13,26 +21,235 @@ type Config struct {
 	Version       string              `mapstructure:"version"`
 	Server        ServerConfig        `mapstructure:"server"`
 	Log           LogConfig           `mapstructure:"log"`
+	AccessLog     AccessLogConfig     `mapstructure:"access_log"`
 	Providers     ProvidersConfig     `mapstructure:"providers"`
+	Routing       RoutingConfig       `mapstructure:"routing"`
 	RateLimit     RateLimitConfig     `mapstructure:"rate_limit"`
+	StreamLimit   StreamLimitConfig   `mapstructure:"stream_limit"`
 	Reliability   ReliabilityConfig   `mapstructure:"reliability"`
 	Cache         CacheConfig         `mapstructure:"cache"`
 	Performance   PerformanceConfig   `mapstructure:"performance"`
 	Observability ObservabilityConfig `mapstructure:"observability"`
+	Admin         AdminConfig         `mapstructure:"admin"`
+	Maintenance   MaintenanceConfig   `mapstructure:"maintenance"`
+	Mirror        MirrorConfig        `mapstructure:"mirror"`
+	Blobs         BlobConfig          `mapstructure:"blobs"`
+	// Degraded serves chat completions from the response cache only, even
+	// past the cache TTL, while providers cannot be reached
+	Degraded DegradedConfig `mapstructure:"degraded"`
+	// ResponseLimits caps generated output per route
+	ResponseLimits ResponseLimitsConfig `mapstructure:"response_limits"`
+	LoopDetection  LoopDetectionConfig  `mapstructure:"loop_detection"`
+	Language       LanguageConfig       `mapstructure:"language"`
+	UseCase        UseCaseConfig        `mapstructure:"use_case"`
+	// Styles are versioned system prompt presets requests can reference by name
+	Styles StylesConfig `mapstructure:"styles"`
+	// ProviderOverride lets debug callers force a backend per request
+	ProviderOverride ProviderOverrideConfig `mapstructure:"provider_override"`
+	// LeaderElection picks one replica to run singleton background jobs
+	LeaderElection LeaderElectionConfig `mapstructure:"leader_election"`
+	// ControlPlane pulls routing rules, provider keys and budgets from a central URL
+	ControlPlane ControlPlaneConfig `mapstructure:"control_plane"`
+	// Retention bounds how long stored records (audit events, usage) are kept
+	Retention RetentionConfig `mapstructure:"retention"`
+	// Privacy holds settings for per-user data deletion requests
+	Privacy PrivacyConfig `mapstructure:"privacy"`
+	// UsageExport serves aggregated, anonymized usage to analytics consumers
+	UsageExport UsageExportConfig `mapstructure:"usage_export"`
+	// PromptStats reports prompts repeated across requests and their cost
+	PromptStats PromptStatsConfig `mapstructure:"prompt_stats"`
+	// Pricing prices tokens for cost and spend reporting
+	Pricing PricingConfig `mapstructure:"pricing"`
+	// ModelCatalog overrides the built-in model metadata listed by /v1/models
+	ModelCatalog ModelCatalogConfig `mapstructure:"model_catalog"`
+	// SLAReports generates periodic per-provider SLA reports
+	SLAReports SLAReportsConfig `mapstructure:"sla_reports"`
+	// Notifications delivers gateway events (quota warnings) to a webhook
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	// QuotaWarnings warns callers nearing their token budget or rate limit
+	QuotaWarnings QuotaWarningsConfig `mapstructure:"quota_warnings"`
+	// ParameterPresets defines named generation parameter presets requests can select
+	ParameterPresets ParameterPresetsConfig `mapstructure:"parameter_presets"`
+	// ConversationCompression summarizes the older turns of long conversations
+	ConversationCompression ConversationCompressionConfig `mapstructure:"conversation_compression"`
+	// Translation translates responses for requests that set translate_to
+	Translation TranslationConfig `mapstructure:"translation"`
+	// PromptLint checks chat prompts for common mistakes
+	PromptLint PromptLintConfig `mapstructure:"prompt_lint"`
+	// Citations controls how provider citations are passed to clients
+	Citations CitationsConfig `mapstructure:"citations"`
+	// ToolCallAssembly sends streamed tool calls whole instead of as argument deltas
+	ToolCallAssembly ToolCallAssemblyConfig `mapstructure:"tool_call_assembly"`
+	// Transcripts keeps the assembled message of streams requested with
+	// assemble: true, for clients that lost part of the stream
+	Transcripts TranscriptsConfig `mapstructure:"transcripts"`
+	// JWTAuth authenticates API callers with access tokens from an identity provider
+	JWTAuth JWTAuthConfig `mapstructure:"jwt_auth"`
+	// OutputFilters masks or blocks banned patterns in model output (DLP)
+	OutputFilters OutputFiltersConfig `mapstructure:"output_filters"`
+	// Fallbacks retries failed requests on equivalent models of other providers
+	Fallbacks FallbacksConfig `mapstructure:"fallbacks"`
+	// Overflow serves requests over a model's outbound quota with a cheaper model instead of 429s
+	Overflow OverflowConfig `mapstructure:"overflow"`
+	// CallHeaders reports each call's provider, model, latency, tokens and cost in response headers
+	CallHeaders CallHeadersConfig `mapstructure:"call_headers"`
+	// PromptSecrets detects credentials in prompts before they are sent to a provider
+	PromptSecrets PromptSecretsConfig `mapstructure:"prompt_secrets"`
+	// Guardrails checks prompts and completions against per-key policies
+	Guardrails GuardrailsConfig `mapstructure:"guardrails"`
+	// PII masks personal data in prompts before they are sent to a provider
+	PII PIIConfig `mapstructure:"pii"`
+	// RequestLimits bounds API request bodies and checks their structure before decoding
+	RequestLimits RequestLimitsConfig `mapstructure:"request_limits"`
+	// Integrity checks request body checksums and sends response body checksums
+	Integrity IntegrityConfig `mapstructure:"integrity"`
+	// ErrorMessages localizes the messages of API errors by Accept-Language
+	ErrorMessages ErrorMessagesConfig `mapstructure:"error_messages"`
+	// AbuseDetection flags API keys behaving like abusers and restricts them
+	AbuseDetection AbuseDetectionConfig `mapstructure:"abuse_detection"`
+	// UnknownFields reports request fields the gateway or the provider ignores
+	UnknownFields UnknownFieldsConfig `mapstructure:"unknown_fields"`
+	// APIKeys manages client API keys with quotas, allowed models and expiry
+	APIKeys APIKeysConfig `mapstructure:"api_keys"`
+	// Cost tracks spend per API key, provider and model and enforces spend budgets
+	Cost CostConfig `mapstructure:"cost"`
+	// ConfigReload re-reads the config file at runtime and applies safe changes
+	ConfigReload ConfigReloadConfig `mapstructure:"config_reload"`
+}
+
+// ConfigReloadConfig holds runtime reloading of the config file. Rate
+// limits, the cache TTL, provider API keys and log levels are applied
+// without a restart; other changes are logged as needing one.
+type ConfigReloadConfig struct {
+	// Enabled reloads the config file on SIGHUP
+	Enabled bool `mapstructure:"enabled"`
+	// Watch also reloads it whenever the file changes
+	Watch bool `mapstructure:"watch"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port         int              `mapstructure:"port"`
+	AdminPort    int              `mapstructure:"admin_port"` // Listener for health/metrics/admin routes (0 = share Port)
+	ReadTimeout  time.Duration    `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration    `mapstructure:"write_timeout"`
+	IdleTimeout  time.Duration    `mapstructure:"idle_timeout"`
+	TLSCertFile  string           `mapstructure:"tls_cert_file"`
+	TLSKeyFile   string           `mapstructure:"tls_key_file"`
+	HTTP2        HTTP2Config      `mapstructure:"http2"`
+	UnixSocket   UnixSocketConfig `mapstructure:"unix_socket"`
+	// TrustedProxies are the IPs or CIDRs of the proxies whose X-Forwarded-For
+	// is honored; when empty, forwarding headers are ignored
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// Middleware lists the global middleware by name, outermost first
+	// (DefaultMiddleware when empty); middleware left out are not used
+	Middleware []string `mapstructure:"middleware"`
+	// RequestIDFormat is the format of the gateway's request IDs:
+	// "sequential" (host prefix and counter), "uuidv4", or "uuidv7" and
+	// "ulid", which sort by time
+	RequestIDFormat string `mapstructure:"request_id_format"`
+	// ResponseIDFormat is the format of the IDs the gateway gives responses,
+	// stream chunks and tool calls: "uuid" (random) or "sequential" (a
+	// scrambled counter, cheaper at high stream rates)
+	ResponseIDFormat string `mapstructure:"response_id_format"`
+}
+
+// Global middleware names, for server.middleware
+const (
+	MiddlewareRequestID      = "request_id"
+	MiddlewareRealIP         = "real_ip"
+	MiddlewareLogger         = "logger"
+	MiddlewareAccessLog      = "access_log"
+	MiddlewareRecoverer      = "recoverer"
+	MiddlewareTimeout        = "timeout"
+	MiddlewareErrorMessages  = "error_messages"
+	MiddlewareJWTAuth        = "jwt_auth"
+	MiddlewareAPIKeys        = "api_keys"
+	MiddlewareAbuse          = "abuse_detection"
+	MiddlewareRateLimit      = "rate_limit"
+	MiddlewareStreamLimit    = "stream_limit"
+	MiddlewareCORS           = "cors"
+	MiddlewareObservability  = "observability"
+	MiddlewareFlightRecorder = "flight_recorder"
+	MiddlewareCompression    = "compression"
+	MiddlewareIntegrity      = "integrity"
+)
+
+// DefaultMiddleware is the global middleware order used unless
+// server.middleware is set
+var DefaultMiddleware = []string{
+	MiddlewareRequestID,
+	MiddlewareRealIP,
+	MiddlewareLogger,
+	MiddlewareAccessLog,
+	MiddlewareRecoverer,
+	MiddlewareTimeout,
+	MiddlewareErrorMessages,
+	MiddlewareJWTAuth,
+	MiddlewareAPIKeys,
+	MiddlewareAbuse,
+	MiddlewareRateLimit,
+	MiddlewareStreamLimit,
+	MiddlewareCORS,
+	MiddlewareObservability,
+	MiddlewareFlightRecorder,
+	MiddlewareCompression,
+	MiddlewareIntegrity,
+}
+
+// MiddlewareOrder returns the global middleware to use, outermost first
+func (s ServerConfig) MiddlewareOrder() []string {
+	if len(s.Middleware) == 0 {
+		return DefaultMiddleware
+	}
+	return s.Middleware
+}
+
+// UnixSocketConfig holds settings for serving the API on a Unix domain socket
+// in addition to TCP, e.g. for sidecar deployments
+type UnixSocketConfig struct {
+	Path string `mapstructure:"path"`
+	// Mode is the octal file mode applied to the socket, e.g. "0660"
+	Mode string `mapstructure:"mode"`
+}
+
+// HTTP2Config holds inbound HTTP/2 settings
+type HTTP2Config struct {
+	// Enabled negotiates HTTP/2 over TLS via ALPN (requires tls_cert_file/tls_key_file)
+	Enabled bool `mapstructure:"enabled"`
+	// H2C accepts cleartext HTTP/2 with prior knowledge, as spoken by service mesh sidecars
+	H2C                  bool `mapstructure:"h2c"`
+	MaxConcurrentStreams int  `mapstructure:"max_concurrent_streams"`
 }
 
 // LogConfig holds logging configuration
 type LogConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
+	Level    string `mapstructure:"level"`
+	Format   string `mapstructure:"format"`
+	Output   string `mapstructure:"output"`    // stdout, stderr, file
+	FilePath string `mapstructure:"file_path"` // Path when output is file
+
+	// Rotation and buffering for file output
+	MaxSizeMB      int           `mapstructure:"max_size_mb"`
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
+	MaxBackups     int           `mapstructure:"max_backups"`
+	Compress       bool          `mapstructure:"compress"`
+	Async          bool          `mapstructure:"async"`
+	BufferSize     int           `mapstructure:"buffer_size"`
+	OverflowPolicy string        `mapstructure:"overflow_policy"` // drop, block
+
+	// Per-module level overrides, e.g. providers: debug, cache: warn
+	Modules map[string]string `mapstructure:"modules"`
+}
+
+// AccessLogConfig holds configuration for the classic access log
+type AccessLogConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Format         string        `mapstructure:"format"` // common, combined, json
+	Output         string        `mapstructure:"output"` // stdout, stderr or a file path
+	MaxSizeMB      int           `mapstructure:"max_size_mb"`
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
+	MaxBackups     int           `mapstructure:"max_backups"`
 }
 
 // ProvidersConfig holds all LLM provider configurations
@@ -41,27 +258,204 @@ type ProvidersConfig struct {
 	OpenAI    OpenAIConfig    `mapstructure:"openai"`
 	Anthropic AnthropicConfig `mapstructure:"anthropic"`
 	Ollama    OllamaConfig    `mapstructure:"ollama"`
+
+	// CredentialFailoverThreshold is the number of consecutive 401s before flipping to the standby key
+	CredentialFailoverThreshold int `mapstructure:"credential_failover_threshold"`
+	// KeyCooldown is how long a rate-limited API key is skipped when no Retry-After is sent
+	KeyCooldown time.Duration `mapstructure:"key_cooldown"`
+	// OutboundLimits caps requests sent to each provider, keyed by provider name
+	OutboundLimits map[string]OutboundLimitConfig `mapstructure:"outbound_limits"`
+	// ModelCacheTTL is how long fetched model lists and model-to-provider routing are reused
+	ModelCacheTTL time.Duration `mapstructure:"model_cache_ttl"`
+	// Priority orders providers that claim the same model; unlisted providers follow by name
+	Priority []string `mapstructure:"priority"`
+	// Standby providers are only routed to for a model when every other
+	// provider claiming it is drained or has an open circuit breaker
+	Standby []string `mapstructure:"standby"`
+	// Balancing spreads a model's requests over the providers claiming it
+	// instead of sending them all to the first by priority; the first rule
+	// whose pattern matches the model applies
+	Balancing []BalancingRule `mapstructure:"balancing"`
+	// TTFTSLOs shift part of a model's streams to a fallback provider while
+	// its provider keeps missing a time-to-first-token target
+	TTFTSLOs []TTFTSLORule `mapstructure:"ttft_slos"`
+	// Remote registers out-of-process providers, keyed by provider name
+	Remote map[string]RemoteProviderConfig `mapstructure:"remote"`
+}
+
+// BalancingRule balances the models matching a pattern across the providers claiming them
+type BalancingRule struct {
+	// Models is a model name or path.Match pattern, e.g. "llama3*"
+	Models string `mapstructure:"models"`
+	// Strategy is "weighted" (weighted round-robin, the default) or "least_latency"
+	Strategy string `mapstructure:"strategy"`
+	// Weights are relative shares per provider for weighted; unlisted providers weigh 1
+	Weights map[string]int `mapstructure:"weights"`
+}
+
+// TTFTSLORule is a time-to-first-token objective for the streams of the
+// models matching a pattern
+type TTFTSLORule struct {
+	// Models is a model name or path.Match pattern, e.g. "gpt-4o*"
+	Models string `mapstructure:"models"`
+	// Target is the first-token latency the provider must stay within
+	Target time.Duration `mapstructure:"target"`
+	// Percentile of the rolling window compared with the target (default 0.9)
+	Percentile float64 `mapstructure:"percentile"`
+	// Window is the number of recent streams the percentile is taken over (default 20)
+	Window int `mapstructure:"window"`
+	// Fallback is the provider that takes traffic while the target is missed;
+	// it must serve the same model names
+	Fallback string `mapstructure:"fallback"`
+	// Shift is the fraction of the model's traffic sent to the fallback
+	// (default 0.5); the rest keeps measuring the provider's recovery
+	Shift float64 `mapstructure:"shift"`
+}
+
+// RoutingConfig is the model routing table. Aliases are resolved first;
+// models and rules then send models to a provider ahead of the providers
+// claiming them. Runtime routes (e.g. from the control plane) take
+// precedence. The table is applied again on config reload.
+type RoutingConfig struct {
+	// Aliases map a model name clients may send to the model it stands for,
+	// e.g. default-chat: gpt-4o-mini
+	Aliases map[string]string `mapstructure:"aliases"`
+	// Models send a model name to a provider
+	Models map[string]string `mapstructure:"models"`
+	// Rules send the models matching a pattern to a provider; the first
+	// matching rule wins
+	Rules []RoutingRule `mapstructure:"rules"`
+}
+
+// RoutingRule sends the models matching a pattern to a provider
+type RoutingRule struct {
+	// Models is a model name or path.Match pattern, e.g. "claude-*"
+	Models string `mapstructure:"models"`
+	// Provider is the name of the provider serving the models
+	Provider string `mapstructure:"provider"`
+}
+
+// RemoteProviderConfig registers a provider implemented out of process, e.g.
+// a Python wrapper around a bespoke model, speaking the gRPC protocol of
+// proto/remote_provider.proto
+type RemoteProviderConfig struct {
+	// Address is host:port, or unix:///path/to.sock for a local shim
+	Address string `mapstructure:"address"`
+	// TLS dials the address with TLS instead of cleartext HTTP/2
+	TLS     bool          `mapstructure:"tls"`
+	Timeout time.Duration `mapstructure:"timeout"` // 0 = 60s
+	// Models served by the provider; empty asks it with ListModels
+	Models []string `mapstructure:"models"`
+	// Command starts the provider as a subprocess of the gateway, e.g. ["python3", "shim.py"]
+	Command []string `mapstructure:"command"`
+	// HealthInterval is how often the provider's Health method is polled (0 = 10s)
+	HealthInterval time.Duration `mapstructure:"health_interval"`
+}
+
+// OutboundLimitConfig holds a provider's contracted upstream quota. Requests
+// over quota are queued for up to max_wait rather than sent upstream.
+type OutboundLimitConfig struct {
+	RequestsPerSec float64       `mapstructure:"requests_per_sec"`
+	TokensPerMin   int           `mapstructure:"tokens_per_min"`
+	MaxQueue       int           `mapstructure:"max_queue"`
+	MaxWait        time.Duration `mapstructure:"max_wait"`
 }
 
 // OpenAIConfig holds OpenAI-specific configuration
 type OpenAIConfig struct {
-	APIKey  string        `mapstructure:"api_key"`
-	BaseURL string        `mapstructure:"base_url"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	APIKey        string        `mapstructure:"api_key"`
+	StandbyAPIKey string        `mapstructure:"standby_api_key"`
+	APIKeys       []string      `mapstructure:"api_keys"` // Additional keys sharing load with api_key
+	BaseURL       string        `mapstructure:"base_url"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	// Discovery resolves OpenAI-compatible replicas (e.g. vLLM) instead of base_url
+	Discovery DiscoveryConfig `mapstructure:"discovery"`
+	// Regions replace base_url with regional base URLs (e.g. two egress regions
+	// or Azure OpenAI deployments), with latency-based selection and failover
+	Regions []RegionConfig `mapstructure:"regions"`
+	// APIVersion pins Azure OpenAI's api-version query parameter; empty for
+	// api.openai.com
+	APIVersion string `mapstructure:"api_version"`
 }
 
 // AnthropicConfig holds Anthropic-specific configuration
 type AnthropicConfig struct {
-	APIKey  string        `mapstructure:"api_key"`
-	BaseURL string        `mapstructure:"base_url"`
-	Timeout time.Duration `mapstructure:"timeout"`
-	Version string        `mapstructure:"version"`
+	APIKey        string        `mapstructure:"api_key"`
+	StandbyAPIKey string        `mapstructure:"standby_api_key"`
+	APIKeys       []string      `mapstructure:"api_keys"` // Additional keys sharing load with api_key
+	BaseURL       string        `mapstructure:"base_url"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	// Version pins the anthropic-version header
+	Version string `mapstructure:"version"`
+	// Regions replace base_url with regional base URLs, with latency-based
+	// selection and failover
+	Regions []RegionConfig `mapstructure:"regions"`
+}
+
+// RegionConfig is one regional base URL of a SaaS provider
+type RegionConfig struct {
+	Name    string `mapstructure:"name"`
+	BaseURL string `mapstructure:"base_url"`
 }
 
 // OllamaConfig holds Ollama-specific configuration
 type OllamaConfig struct {
 	BaseURL string        `mapstructure:"base_url"`
 	Timeout time.Duration `mapstructure:"timeout"`
+
+	// PrefetchModels are compared against the local model list at startup
+	PrefetchModels []string      `mapstructure:"prefetch_models"`
+	AutoPull       bool          `mapstructure:"auto_pull"`
+	PullTimeout    time.Duration `mapstructure:"pull_timeout"`
+
+	// Instances are additional Ollama replicas polled via /api/ps for loaded models
+	Instances    []string      `mapstructure:"instances"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Discovery resolves the replica set instead of base_url and instances
+	Discovery DiscoveryConfig `mapstructure:"discovery"`
+
+	// EmbedBatchSize is the number of inputs sent to /api/embed at once
+	EmbedBatchSize int `mapstructure:"embed_batch_size"`
+	// EmbedConcurrency bounds the embedding calls one request makes at a time
+	EmbedConcurrency int `mapstructure:"embed_concurrency"`
+
+	// ModelRefreshInterval refreshes the /api/tags model list in the
+	// background, so model lookups are served from memory; 0 fetches it on
+	// demand once providers.model_cache_ttl has passed
+	ModelRefreshInterval time.Duration `mapstructure:"model_refresh_interval"`
+}
+
+// DiscoveryConfig resolves a provider's endpoints from service discovery, so
+// replica sets can scale without config edits
+type DiscoveryConfig struct {
+	// Type is "dns_srv", "consul" or "kubernetes"; empty uses the static base_url
+	Type string `mapstructure:"type"`
+	// Service is the SRV name, Consul service or Kubernetes Endpoints name
+	Service string `mapstructure:"service"`
+	// Scheme and Path build each endpoint's base URL: scheme://host:port/path
+	Scheme          string                    `mapstructure:"scheme"`
+	Path            string                    `mapstructure:"path"`
+	RefreshInterval time.Duration             `mapstructure:"refresh_interval"`
+	Consul          ConsulDiscoveryConfig     `mapstructure:"consul"`
+	Kubernetes      KubernetesDiscoveryConfig `mapstructure:"kubernetes"`
+}
+
+// ConsulDiscoveryConfig holds the Consul agent used for discovery
+type ConsulDiscoveryConfig struct {
+	Address string `mapstructure:"address"`
+	Token   string `mapstructure:"token"`
+	Tag     string `mapstructure:"tag"`
+}
+
+// KubernetesDiscoveryConfig holds the Kubernetes API used for discovery
+type KubernetesDiscoveryConfig struct {
+	// APIServer defaults to the in-cluster address from KUBERNETES_SERVICE_HOST/PORT
+	APIServer string `mapstructure:"api_server"`
+	Namespace string `mapstructure:"namespace"`
+	// PortName selects the Endpoints port by name (default: the first port)
+	PortName  string `mapstructure:"port_name"`
+	TokenFile string `mapstructure:"token_file"`
+	CAFile    string `mapstructure:"ca_file"`
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -70,6 +464,27 @@ type RateLimitConfig struct {
 	RequestsPerMin  int           `mapstructure:"requests_per_min"`
 	BurstSize       int           `mapstructure:"burst_size"`
 	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	// Tiers override the limits for callers whose access token names the tier
+	// (see jwt_auth.tier_claim)
+	Tiers map[string]RateLimitTier `mapstructure:"tiers"`
+	// FingerprintUserAgent limits anonymous callers by IP and User-Agent, so
+	// clients sharing a NAT address get separate buckets
+	FingerprintUserAgent bool `mapstructure:"fingerprint_user_agent"`
+}
+
+// RateLimitTier holds the rate limits of a caller tier
+type RateLimitTier struct {
+	RequestsPerMin int `mapstructure:"requests_per_min"`
+	BurstSize      int `mapstructure:"burst_size"`
+	// QueueWeight is the tier's share of the request queue's workers under
+	// performance.queue.fair_queuing, relative to other tiers (default 1)
+	QueueWeight int `mapstructure:"queue_weight"`
+}
+
+// StreamLimitConfig caps simultaneous streaming responses per API key
+type StreamLimitConfig struct {
+	Enabled   bool `mapstructure:"enabled"`
+	MaxPerKey int  `mapstructure:"max_per_key"`
 }
 
 // ReliabilityConfig holds reliability feature configuration
@@ -85,6 +500,23 @@ type CircuitBreakerConfig struct {
 	SuccessThreshold    int           `mapstructure:"success_threshold"`
 	Timeout             time.Duration `mapstructure:"timeout"`
 	MaxHalfOpenRequests int           `mapstructure:"max_half_open_requests"`
+	// Mode is "consecutive", opening after failure_threshold failures in a
+	// row, or "sliding_window", opening when the failure rate over the
+	// window reaches failure_rate_threshold
+	Mode string `mapstructure:"mode"`
+	// WindowType is "count", the last window_size requests, or "time", the
+	// requests of the last window_duration
+	WindowType     string        `mapstructure:"window_type"`
+	WindowSize     int           `mapstructure:"window_size"`
+	WindowDuration time.Duration `mapstructure:"window_duration"`
+	// FailureRateThreshold is the failure percentage that opens the circuit
+	FailureRateThreshold float64 `mapstructure:"failure_rate_threshold"`
+	// MinimumRequests is the number of requests the window needs before its
+	// failure rate can open the circuit
+	MinimumRequests int `mapstructure:"minimum_requests"`
+	// Providers override these settings per provider name; fields left unset
+	// are inherited, and enabled cannot be overridden
+	Providers map[string]CircuitBreakerConfig `mapstructure:"providers"`
 }
 
 // RetryConfig holds retry settings
@@ -94,6 +526,25 @@ type RetryConfig struct {
 	InitialBackoff    time.Duration `mapstructure:"initial_backoff"`
 	MaxBackoff        time.Duration `mapstructure:"max_backoff"`
 	BackoffMultiplier float64       `mapstructure:"backoff_multiplier"`
+	// MinAttemptTime is the least time left before the request deadline worth
+	// starting an attempt with; 0 disables deadline budgeting
+	MinAttemptTime time.Duration `mapstructure:"min_attempt_time"`
+	// MaxConcurrentRetries caps the retries in flight at once to each
+	// provider; 0 means no cap
+	MaxConcurrentRetries int `mapstructure:"max_concurrent_retries"`
+	// Budget caps retries, across all providers, at a share of requests
+	Budget RetryBudgetConfig `mapstructure:"budget"`
+}
+
+// RetryBudgetConfig holds the global retry budget settings
+type RetryBudgetConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Percent is the most retries allowed, as a percentage of the requests
+	// made over the window
+	Percent float64       `mapstructure:"percent"`
+	Window  time.Duration `mapstructure:"window"`
+	// MinRetries are allowed per window however few requests were made
+	MinRetries int `mapstructure:"min_retries"`
 }
 
 // CacheConfig holds caching configuration
@@ -103,6 +554,29 @@ type CacheConfig struct {
 	MaxEntries int           `mapstructure:"max_entries"`
 	Backend    string        `mapstructure:"backend"` // "memory" or "redis"
 	Redis      RedisConfig   `mapstructure:"redis"`
+	// KeyPrefix namespaces the Redis backend's keys, so instances sharing a
+	// database share entries and Clear removes only these
+	KeyPrefix string `mapstructure:"key_prefix"`
+	// PoolSize is the number of idle Redis connections kept open
+	PoolSize int `mapstructure:"pool_size"`
+	// Timeout bounds each Redis call
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Similarity also serves cached responses for prompts close in meaning
+	Similarity CacheSimilarityConfig `mapstructure:"similarity"`
+}
+
+// CacheSimilarityConfig matches cached responses by prompt embedding. On an
+// exact miss, the normalized prompt is embedded and the response of the most
+// similar cached prompt with the same model and parameters is returned.
+type CacheSimilarityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Model is the embedding model; Provider forces a provider, otherwise the model is routed
+	Model    string `mapstructure:"model"`
+	Provider string `mapstructure:"provider"`
+	// Threshold is the cosine similarity a cached prompt must reach
+	Threshold float64 `mapstructure:"threshold"`
+	// Timeout bounds each embedding request
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 // RedisConfig holds Redis connection configuration
@@ -141,12 +615,17 @@ type QueueConfig struct {
 	MaxWaitTime     time.Duration `mapstructure:"max_wait_time"`
 	WorkerCount     int           `mapstructure:"worker_count"`
 	PriorityEnabled bool          `mapstructure:"priority_enabled"`
+	// FairQueuing shares the workers between tenants by the queue_weight of
+	// their rate_limit.tiers, so one tenant's backlog cannot starve the others
+	FairQueuing bool `mapstructure:"fair_queuing"`
 }
 
 // ObservabilityConfig holds observability settings
 type ObservabilityConfig struct {
-	Metrics MetricsObsConfig `mapstructure:"metrics"`
-	Tracing TracingConfig    `mapstructure:"tracing"`
+	Metrics        MetricsObsConfig     `mapstructure:"metrics"`
+	Tracing        TracingConfig        `mapstructure:"tracing"`
+	FlightRecorder FlightRecorderConfig `mapstructure:"flight_recorder"`
+	TimeSeries     TimeSeriesConfig     `mapstructure:"timeseries"`
 }
 
 // MetricsObsConfig holds metrics configuration
@@ -162,10 +641,808 @@ type TracingConfig struct {
 	ServiceName  string  `mapstructure:"service_name"`
 	SamplingRate float64 `mapstructure:"sampling_rate"`
 	ExporterType string  `mapstructure:"exporter_type"`
+	// ExporterAddress is the OTLP/HTTP collector for the otlp and jaeger
+	// exporters, e.g. http://tempo:4318
+	ExporterAddress string `mapstructure:"exporter_address"`
+	// ExporterHeaders are sent with every export request, e.g. for collector auth
+	ExporterHeaders map[string]string `mapstructure:"exporter_headers"`
+}
+
+// FlightRecorderConfig holds settings for the in-memory request flight recorder
+type FlightRecorderConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Size is the number of recent requests kept in memory
+	Size int `mapstructure:"size"`
+	// The buffer is dumped to the audit log when the 5xx rate over the last
+	// Window requests reaches ErrorRateThreshold
+	ErrorRateThreshold float64       `mapstructure:"error_rate_threshold"`
+	Window             int           `mapstructure:"window"`
+	MinRequests        int           `mapstructure:"min_requests"`
+	Cooldown           time.Duration `mapstructure:"cooldown"`
+	// CaptureBodies keeps each request's body, the requests sent to providers
+	// and the streamed chunks, for request bundles; they may hold personal data
+	CaptureBodies bool `mapstructure:"capture_bodies"`
+	// MaxCaptureBytes bounds each captured body and a request's stream chunks
+	MaxCaptureBytes int `mapstructure:"max_capture_bytes"`
+}
+
+// TimeSeriesConfig holds settings for the in-memory time series behind
+// /admin/v1/timeseries
+type TimeSeriesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Retention is how far back the 1s and 10s series go
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	APIKeys []string `mapstructure:"api_keys"`
+}
+
+// MaintenanceConfig holds maintenance mode settings
+type MaintenanceConfig struct {
+	// Enabled starts the gateway in maintenance mode (new API requests get 503)
+	Enabled    bool          `mapstructure:"enabled"`
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+	Message    string        `mapstructure:"message"`
+	// DrainedProviders are providers that receive no new traffic at startup
+	DrainedProviders []string `mapstructure:"drained_providers"`
+}
+
+// DegradedConfig holds degraded mode settings. In degraded mode, cacheable
+// chat completions are served from the response cache, even past its TTL,
+// and other requests get a 503.
+type DegradedConfig struct {
+	// Enabled starts the gateway in degraded mode; it can be switched at
+	// /admin/v1/degraded
+	Enabled bool `mapstructure:"enabled"`
+	// Auto serves a model's requests in degraded mode while every provider
+	// that could serve it is drained or has an open circuit breaker
+	Auto bool `mapstructure:"auto"`
+	// MaxStale is how long cached responses are kept past cache.ttl for
+	// degraded mode (0 serves fresh responses only)
+	MaxStale time.Duration `mapstructure:"max_stale"`
+	// RetryAfter is sent with the 503 of requests the cache cannot serve
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// MirrorConfig holds settings for copying sampled production traffic to a staging gateway
+type MirrorConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	TargetURL     string        `mapstructure:"target_url"`
+	SampleRate    float64       `mapstructure:"sample_rate"`
+	APIKey        string        `mapstructure:"api_key"` // Replaces the client's credentials
+	Timeout       time.Duration `mapstructure:"timeout"`
+	MaxBodyBytes  int64         `mapstructure:"max_body_bytes"`
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+}
+
+// BlobConfig holds settings for resolving {"$blob": ref} message content
+type BlobConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// S3Endpoint is an S3-compatible endpoint serving s3://bucket/key refs path-style
+	S3Endpoint string `mapstructure:"s3_endpoint"`
+	// S3AllowedPrefixes are the buckets ("bucket") or key prefixes
+	// ("bucket/prompts/") s3:// refs may read; required with an S3 endpoint
+	S3AllowedPrefixes []string      `mapstructure:"s3_allowed_prefixes"`
+	FetchTimeout      time.Duration `mapstructure:"fetch_timeout"`
+	MaxBlobBytes      int64         `mapstructure:"max_blob_bytes"`
+	MaxRequestBytes   int64         `mapstructure:"max_request_bytes"`
+	CacheTTL          time.Duration `mapstructure:"cache_ttl"`
+	CacheEntries      int           `mapstructure:"cache_entries"`
+	// FileTTL is how long blobs uploaded to /v1/files can be referenced
+	FileTTL time.Duration `mapstructure:"file_ttl"`
+	// MaxFiles caps stored uploads; more are refused until some expire
+	MaxFiles int `mapstructure:"max_files"`
+}
+
+// ResponseLimitsConfig holds maximum response sizes, per route path with a default
+type ResponseLimitsConfig struct {
+	Enabled bool                         `mapstructure:"enabled"`
+	Default ResponseLimitRule            `mapstructure:"default"`
+	Routes  map[string]ResponseLimitRule `mapstructure:"routes"`
+}
+
+// ResponseLimitRule limits generated content and sets what happens when it is exceeded
+type ResponseLimitRule struct {
+	MaxBytes  int64 `mapstructure:"max_bytes"`
+	MaxTokens int   `mapstructure:"max_tokens"`
+	// Policy is "reject", "truncate" (finish_reason length) or "terminate" (end the stream)
+	Policy string `mapstructure:"policy"`
+}
+
+// LoopDetectionConfig holds settings for detecting degenerate repetition in streamed output
+type LoopDetectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// NgramSize is the number of words in a repeated phrase
+	NgramSize int `mapstructure:"ngram_size"`
+	// Window is the number of recent words searched for repeats
+	Window int `mapstructure:"window"`
+	// MaxRepeats is how often one phrase may occur within the window
+	MaxRepeats int `mapstructure:"max_repeats"`
+	// Terminate ends the stream with finish_reason "loop_detected"; otherwise loops are only logged
+	Terminate bool `mapstructure:"terminate"`
+}
+
+// LanguageConfig holds settings for detecting the primary language of prompts
+type LanguageConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinChars is the number of letters needed before a language is reported ("und" below it)
+	MinChars int `mapstructure:"min_chars"`
+	// Routes maps a language code (e.g. "ja") to the model that serves its traffic
+	Routes map[string]string `mapstructure:"routes"`
+	// Enforcement checks that chat responses are in the required language
+	Enforcement LanguageEnforcementConfig `mapstructure:"enforcement"`
+}
+
+// LanguageEnforcementConfig holds response language policies, per route path with a default
+type LanguageEnforcementConfig struct {
+	Enabled bool                      `mapstructure:"enabled"`
+	Default LanguagePolicy            `mapstructure:"default"`
+	Routes  map[string]LanguagePolicy `mapstructure:"routes"`
+}
+
+// LanguagePolicy sets the language responses on a route must be in
+type LanguagePolicy struct {
+	// Required is a language code (e.g. "de"), "prompt" for the language of the prompt, or empty for no check
+	Required string `mapstructure:"required"`
+	// Retry asks again once with an explicit instruction when a response is in
+	// another language; otherwise mismatches are only reported
+	Retry bool `mapstructure:"retry"`
+}
+
+// UseCaseConfig holds settings for classifying requests by use case (code,
+// extraction, chat, embedding) for metrics and usage reporting
+type UseCaseConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Keywords adds prompt keywords per use case ("code" or "extraction") to the built-in ones
+	Keywords map[string][]string `mapstructure:"keywords"`
+}
+
+// PromptStatsConfig holds settings for cross-request prompt deduplication statistics
+type PromptStatsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is how far back repeats are counted
+	Window time.Duration `mapstructure:"window"`
+	// MaxPrompts bounds the distinct prompts tracked; the least recently seen are dropped
+	MaxPrompts int `mapstructure:"max_prompts"`
+	// Distance is the largest simhash Hamming distance (of 64 bits) at which
+	// prompts count as repeats; 0 only groups identical prompts
+	Distance int `mapstructure:"distance"`
+	// SampleChars is the length of the prompt sample shown per group (0 shows none)
+	SampleChars int `mapstructure:"sample_chars"`
+}
+
+// SLAReportsConfig holds settings for periodic per-provider SLA reports
+type SLAReportsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Periods lists the reports to generate: "daily", "weekly" or both
+	Periods []string `mapstructure:"periods"`
+	// MaxReports bounds the reports kept for the admin API, oldest dropped first
+	MaxReports int `mapstructure:"max_reports"`
+	// WebhookURL receives each report as JSON when set
+	WebhookURL string `mapstructure:"webhook_url"`
+	// WebhookSecret signs webhook bodies (X-Signature: sha256=<hex HMAC>) when set
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	// Email sends each report as a plain text summary when SMTP is configured
+	Email SLAEmailConfig `mapstructure:"email"`
+	// Timeout bounds each delivery and store call
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Store is "memory" (per replica) or "redis" (shared by all replicas,
+	// with reports generated and delivered by the leader)
+	Store string      `mapstructure:"store"`
+	Redis RedisConfig `mapstructure:"redis"`
+	// KeyPrefix namespaces the redis store's keys
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// SLAEmailConfig holds the SMTP settings for emailed SLA reports
+type SLAEmailConfig struct {
+	// SMTPAddr is the server as host:port; empty disables email
+	SMTPAddr string   `mapstructure:"smtp_addr"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// NotificationsConfig holds settings for the notification sink
+type NotificationsConfig struct {
+	// WebhookURL receives each event as JSON; empty disables notifications
+	WebhookURL string `mapstructure:"webhook_url"`
+	// WebhookSecret signs webhook bodies (X-Signature: sha256=<hex HMAC>) when set
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	// Timeout bounds each delivery
+	Timeout time.Duration `mapstructure:"timeout"`
+	// QueueSize bounds the events waiting for delivery; newer events are dropped when full
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+// QuotaWarningsConfig holds settings for soft quota warnings
+type QuotaWarningsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Thresholds are the percentages of a tenant's daily token budget or a
+	// client's rate limit burst at which callers are warned
+	Thresholds []float64 `mapstructure:"thresholds"`
+}
+
+// ToolCallAssemblyConfig holds settings for assembling the tool calls of
+// streamed chat completions for clients that cannot merge deltas
+type ToolCallAssemblyConfig struct {
+	// Mode applies to streams whose request has no X-Tool-Call-Assembly
+	// header: "off" forwards deltas as they arrive, "complete" sends each call
+	// once its arguments are complete, "final" sends all calls with the
+	// chunk finishing the choice
+	Mode string `mapstructure:"mode"`
+}
+
+// TranscriptsConfig holds settings for keeping the assembled final message of
+// streams, retrievable from /v1/responses/{id}
+type TranscriptsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long an assembled message can be fetched after its stream ends
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxEntries bounds the messages kept; the oldest are dropped first
+	MaxEntries int `mapstructure:"max_entries"`
+	// MaxDuration bounds how long a stream is still read from the provider
+	// after its client disconnects
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+}
+
+// CitationsConfig holds settings for citations returned by web search models
+type CitationsConfig struct {
+	// StreamEvents sends the citations of streamed responses as dedicated
+	// "citations" SSE events
+	StreamEvents bool `mapstructure:"stream_events"`
+}
+
+// PromptSecretsConfig holds settings for detecting credentials, such as
+// cloud access keys or private keys, in inbound prompts
+type PromptSecretsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Action is "block" (reject the request) or "redact" (replace each secret
+	// with a placeholder before the prompt is sent)
+	Action string `mapstructure:"action"`
+	// Detectors limits scanning to these built-in detectors (all when empty)
+	Detectors []string `mapstructure:"detectors"`
+	// Patterns adds detectors, by name, as regular expressions (RE2 syntax)
+	Patterns map[string]string `mapstructure:"patterns"`
+	// OverrideKeys are the API keys holding the "security" scope; their
+	// prompts are passed through unchanged, with detections still audited
+	OverrideKeys []string `mapstructure:"override_keys"`
+}
+
+// GuardrailsConfig holds the guardrail policies prompts and completions are
+// checked against. A caller's policy is the one its managed key names, else
+// the one listing its API key or key ID, else the default policy.
+type GuardrailsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Policies are the guardrail policies by name
+	Policies map[string]GuardrailPolicy `mapstructure:"policies"`
+	// DefaultPolicy applies to callers without a policy; empty checks nothing
+	DefaultPolicy string `mapstructure:"default_policy"`
+	// Moderation is the moderation endpoint policies can send text to
+	Moderation ModerationConfig `mapstructure:"moderation"`
+}
+
+// GuardrailPolicy is one set of guardrail checks
+type GuardrailPolicy struct {
+	// Keys are the API keys and managed key IDs the policy applies to
+	Keys []string `mapstructure:"keys"`
+	// MaxPromptChars rejects prompts longer than this many characters (0 for no limit)
+	MaxPromptChars int `mapstructure:"max_prompt_chars"`
+	// Denylist holds the patterns and keywords prompts and completions must not contain
+	Denylist []GuardrailRule `mapstructure:"denylist"`
+	// ModerateInput sends prompts to the moderation endpoint and rejects flagged ones
+	ModerateInput bool `mapstructure:"moderate_input"`
+	// ModerateOutput sends completions to the moderation endpoint and blocks flagged ones
+	ModerateOutput bool `mapstructure:"moderate_output"`
+	// Categories limits moderation to these categories (any flagged category when empty)
+	Categories []string `mapstructure:"categories"`
+}
+
+// GuardrailRule is one denylist entry of a guardrail policy
+type GuardrailRule struct {
+	// Name labels the rule in responses, logs and metrics
+	Name string `mapstructure:"name"`
+	// Pattern is a regular expression (RE2 syntax)
+	Pattern string `mapstructure:"pattern"`
+	// Keywords are matched literally, ignoring case
+	Keywords []string `mapstructure:"keywords"`
+	// Action is "block" (reject the request or response) or "redact" (replace matches)
+	Action string `mapstructure:"action"`
+	// Apply is "input" (prompts), "output" (completions) or "both" (the default)
+	Apply string `mapstructure:"apply"`
+	// Replacement is the text redacted matches are replaced with
+	Replacement string `mapstructure:"replacement"`
+}
+
+// ModerationConfig holds the moderation endpoint guardrails call: OpenAI's,
+// or a local classifier serving the same API
+type ModerationConfig struct {
+	// URL of the /v1/moderations endpoint
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"`
+	// Model is sent as the moderation model when set, e.g. omni-moderation-latest
+	Model   string        `mapstructure:"model"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	// FailClosed rejects text that could not be moderated; by default it is let through
+	FailClosed bool `mapstructure:"fail_closed"`
+}
+
+// PIIConfig holds settings for masking personal data, such as email
+// addresses and card numbers, in chat prompts. Each value is replaced with a
+// numbered placeholder like [EMAIL_1] before the prompt leaves the gateway.
+type PIIConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Detectors limits masking to these detectors: email, phone, credit_card
+	// and national_id (all when empty)
+	Detectors []string `mapstructure:"detectors"`
+	// Restore puts the original values back where the response repeats a
+	// placeholder, so the client sees its own data
+	Restore bool `mapstructure:"restore"`
+}
+
+// ErrorMessagesConfig holds the localization of API error messages. The
+// message of an error response is replaced with the catalog's message for
+// its code in the language of the request's Accept-Language header; the
+// error type and code stay the same.
+type ErrorMessagesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Messages adds to or overrides the built-in messages, by language tag
+	// (e.g. "es" or "pt-br") and then by error code
+	Messages map[string]map[string]string `mapstructure:"messages"`
+}
+
+// RequestLimitsConfig holds the limits API request bodies are checked
+// against before the handlers decode them
+type RequestLimitsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxRequestBytes rejects larger bodies with 413 (0 for no limit)
+	MaxRequestBytes int64 `mapstructure:"max_request_bytes"`
+	// MaxMessages bounds the messages of a request (0 for no limit)
+	MaxMessages int `mapstructure:"max_messages"`
+	// MaxContentChars bounds the characters of all messages, system, prompt
+	// and input text of a request (0 for no limit)
+	MaxContentChars int `mapstructure:"max_content_chars"`
+}
+
+// IntegrityConfig holds end-to-end body checksums. A request may carry an
+// X-Content-SHA256 header with the hex SHA-256 of its body, which is checked
+// before the request is handled; every response carries the same header with
+// the hash of its body (as a trailer for streams).
+type IntegrityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Require rejects requests that have a body but no X-Content-SHA256 header
+	Require bool `mapstructure:"require"`
+}
+
+// AbuseDetectionConfig holds the per-key anomaly signals that flag abuse,
+// such as leaked keys or model scanning. Each signal is evaluated per window.
+type AbuseDetectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is the period signals are counted over
+	Window time.Duration `mapstructure:"window"`
+	// SpikeFactor flags a key whose requests in a window exceed this multiple
+	// of its average over earlier windows (0 disables the signal)
+	SpikeFactor float64 `mapstructure:"spike_factor"`
+	// SpikeMinRequests is the fewest requests in a window counted as a spike
+	SpikeMinRequests int `mapstructure:"spike_min_requests"`
+	// RefusalRate flags a key whose requests are refused at this rate or more,
+	// e.g. by content filters (0 disables the signal)
+	RefusalRate float64 `mapstructure:"refusal_rate"`
+	// RefusalMinRequests is the fewest requests in a window the refusal rate is judged on
+	RefusalMinRequests int `mapstructure:"refusal_min_requests"`
+	// MaxIPs flags a key used from more client IPs in a window (0 disables the signal)
+	MaxIPs int `mapstructure:"max_ips"`
+	// MaxModels flags a key requesting more distinct models in a window, as
+	// when enumerating model names (0 disables the signal)
+	MaxModels int `mapstructure:"max_models"`
+	// RestrictedTier is the rate_limit tier flagged keys are moved to (none
+	// only reports them)
+	RestrictedTier string `mapstructure:"restricted_tier"`
+	// RestrictAfter is how many signals a key raises in one window before it is restricted
+	RestrictAfter int `mapstructure:"restrict_after"`
+	// RestrictFor is how long a restriction lasts
+	RestrictFor time.Duration `mapstructure:"restrict_for"`
+}
+
+// UnknownFieldsConfig holds the tracking of request fields that are silently
+// ignored: fields the gateway does not know, and fields the chosen provider
+// does not support (e.g. logit_bias sent to Anthropic)
+type UnknownFieldsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Strict rejects requests carrying such fields with 400 unsupported_field;
+	// with request_limits, unknown fields in messages are rejected too
+	Strict bool `mapstructure:"strict"`
+	// MaxEntries bounds the client and field pairs kept for the report
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// APIKeysConfig holds the managed client API keys. Keys are created through
+// the admin API and kept in a store; each carries its tenant, quotas, allowed
+// models and expiry.
+type APIKeysConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Required rejects API requests without a valid managed key, except from
+	// callers identified by a JWT access token
+	Required bool `mapstructure:"required"`
+	// Store is "file", "sqlite" or "redis"
+	Store string `mapstructure:"store"`
+	// Path is the JSON file of the file store or the database of the sqlite store
+	Path  string      `mapstructure:"path"`
+	Redis RedisConfig `mapstructure:"redis"`
+	// RedisKey is the hash holding the keys in the redis store
+	RedisKey string `mapstructure:"redis_key"`
+	// RefreshInterval reloads the keys from the store, so replicas sharing a
+	// store pick up each other's changes
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	// StreamReservation holds the estimated tokens of streams against their
+	// key's token quotas while they run
+	StreamReservation StreamReservationConfig `mapstructure:"stream_reservation"`
+}
+
+// StreamReservationConfig reserves an estimate of a stream's tokens when it
+// starts, replacing it with the tokens used once it ends
+type StreamReservationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DefaultCompletionTokens is reserved for the completion of requests
+	// without max_tokens
+	DefaultCompletionTokens int `mapstructure:"default_completion_tokens"`
+	// Margin is the share of its reservation a stream may run over before it
+	// can be aborted
+	Margin float64 `mapstructure:"margin"`
+	// NearLimit is the share of a token quota, used and reserved, from which
+	// streams over their reservation plus margin are aborted
+	NearLimit float64 `mapstructure:"near_limit"`
+}
+
+// CostConfig holds spend tracking, priced with the pricing table, and the
+// spend budgets enforced on it
+type CostConfig struct {
+	Enabled bool                `mapstructure:"enabled"`
+	Budgets []SpendBudgetConfig `mapstructure:"budgets"`
+	// History is how long hourly spend per tenant is kept for forecasts and
+	// the normal burn anomalies are measured against (default 7 days)
+	History time.Duration `mapstructure:"history"`
+	// Anomaly flags tenants whose hourly spend jumps far above their normal burn
+	Anomaly SpendAnomalyConfig `mapstructure:"anomaly"`
+}
+
+// SpendAnomalyConfig flags a tenant whose spend in the current hour reaches
+// factor times its average hourly spend over cost.history
+type SpendAnomalyConfig struct {
+	Enabled bool    `mapstructure:"enabled"`
+	Factor  float64 `mapstructure:"factor"`
+	// MinSpend is the hourly spend in USD below which nothing is flagged
+	MinSpend float64 `mapstructure:"min_spend"`
+	// MinHistory is how long a tenant must have been seen before its spend
+	// is compared with its baseline
+	MinHistory time.Duration `mapstructure:"min_history"`
+}
+
+// SpendBudgetConfig limits the spend of an API key, provider or model
+type SpendBudgetConfig struct {
+	// Scope is "key", "provider" or "model"
+	Scope string `mapstructure:"scope"`
+	// ID is the key ID (or masked key), provider or model the budget applies
+	// to; "*" gives each of them a budget of its own
+	ID string `mapstructure:"id"`
+	// Limit is the spend allowed per period, in USD
+	Limit float64 `mapstructure:"limit"`
+	// Period is "day" or "month" (UTC)
+	Period string `mapstructure:"period"`
+	// Action is "reject" (429 once exhausted) or "warn" (X-Quota-Warning)
+	Action string `mapstructure:"action"`
+}
+
+// FallbacksConfig holds provider fallback chains
+type FallbacksConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Chains maps a model to the models tried in order when its provider fails,
+	// each as "provider/model" or a model name routed as usual
+	Chains map[string][]string `mapstructure:"chains"`
+}
+
+// OverflowConfig holds the models serving the overflow of models whose
+// providers.outbound_limits are exhausted
+type OverflowConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Routes maps a model to the model serving its overflow, as
+	// "provider/model" or a model name routed as usual
+	Routes map[string]string `mapstructure:"routes"`
+	// Tiers override routes for callers of a rate_limit tier
+	Tiers map[string]OverflowTierConfig `mapstructure:"tiers"`
+}
+
+// OverflowTierConfig holds the overflow policy of a caller tier
+type OverflowTierConfig struct {
+	// Disabled turns overflow off for the tier, whose callers get 429s
+	Disabled bool `mapstructure:"disabled"`
+	// Routes replace the overflow routes of the same models for the tier
+	Routes map[string]string `mapstructure:"routes"`
+}
+
+// CallHeadersConfig holds the X-LLM-* response headers describing each call
+type CallHeadersConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// OutputFiltersConfig holds the DLP rules applied to model output
+type OutputFiltersConfig struct {
+	Enabled bool               `mapstructure:"enabled"`
+	Rules   []OutputFilterRule `mapstructure:"rules"`
+	// Window is how many bytes of streamed output are held back so matches
+	// spanning chunks are caught; it bounds the longest match found in streams
+	Window int `mapstructure:"window"`
+}
+
+// OutputFilterRule is one banned pattern in model output
+type OutputFilterRule struct {
+	// Name labels the rule in logs and metrics
+	Name string `mapstructure:"name"`
+	// Pattern is a regular expression (RE2 syntax)
+	Pattern string `mapstructure:"pattern"`
+	// Keywords are matched literally, ignoring case
+	Keywords []string `mapstructure:"keywords"`
+	// Action is "mask" (replace matches) or "abort" (reject the response or end the stream)
+	Action string `mapstructure:"action"`
+	// Replacement is the text masked matches are replaced with
+	Replacement string `mapstructure:"replacement"`
+}
+
+// JWTAuthConfig holds settings for validating JWT access tokens, such as those
+// clients obtain from an OAuth2/OIDC identity provider with the PKCE flow
+type JWTAuthConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// JWKSURL serves the identity provider's signing keys as a JWK set
+	JWKSURL string `mapstructure:"jwks_url"`
+	// JWKSRefresh is how long fetched keys are used before they are fetched again
+	JWKSRefresh time.Duration `mapstructure:"jwks_refresh"`
+	// Timeout bounds a JWKS fetch
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Issuer must match the token's iss claim when set
+	Issuer string `mapstructure:"issuer"`
+	// Audiences must include one of the token's aud values when set
+	Audiences []string `mapstructure:"audiences"`
+	// ClockSkew is the tolerance applied to the exp and nbf claims
+	ClockSkew time.Duration `mapstructure:"clock_skew"`
+	// UserClaim names the claim holding the user ID
+	UserClaim string `mapstructure:"user_claim"`
+	// TierClaim names the claim selecting the caller's rate_limit tier; empty
+	// applies the default limits to every caller
+	TierClaim string `mapstructure:"tier_claim"`
+}
+
+// ConversationCompressionConfig holds settings for summarizing long conversations
+type ConversationCompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is the estimated prompt tokens above which older turns are summarized
+	Threshold int `mapstructure:"threshold"`
+	// KeepRecent is the minimum number of latest messages sent as they are
+	KeepRecent int `mapstructure:"keep_recent"`
+	// Model is the (cheap) model that writes the summaries
+	Model string `mapstructure:"model"`
+	// MaxSummaryTokens caps the length of a summary
+	MaxSummaryTokens int `mapstructure:"max_summary_tokens"`
+	// Timeout bounds a summarization call; the conversation is sent as is when it fails
+	Timeout time.Duration `mapstructure:"timeout"`
+	// CacheEntries bounds the summaries kept to extend on the next turn
+	CacheEntries int `mapstructure:"cache_entries"`
+}
+
+// TranslationConfig holds the translation of chat responses into the
+// language a request names in translate_to. The translation, written by a
+// (cheap) model, is returned next to the original message.
+type TranslationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Model is the model that translates responses
+	Model string `mapstructure:"model"`
+	// Timeout bounds a translation call; the response is sent untranslated when it fails
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// PromptLintConfig holds the prompt linter, which checks chat prompts for
+// common mistakes with gateway-side heuristics. Enabled serves POST /v1/lint;
+// Annotate also lints every chat completion request.
+type PromptLintConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Annotate adds the warnings to chat completion responses and reports
+	// their rules in the X-Prompt-Warnings header
+	Annotate bool `mapstructure:"annotate"`
+	// MaxContextChars is the prompt size above which the context is reported as large
+	MaxContextChars int `mapstructure:"max_context_chars"`
+	// RepeatedMinChars is the length from which a paragraph sent more than
+	// once is reported as redundant
+	RepeatedMinChars int `mapstructure:"repeated_min_chars"`
+}
+
+// ParameterPresetsConfig holds named generation parameter presets, per route
+// path with defaults for every route
+type ParameterPresetsConfig struct {
+	Enabled bool                       `mapstructure:"enabled"`
+	Presets map[string]ParameterPreset `mapstructure:"presets"`
+	// Routes maps a route path (e.g. /v1/chat/completions) to presets that
+	// replace the default preset of the same name on that route
+	Routes map[string]map[string]ParameterPreset `mapstructure:"routes"`
+}
+
+// ParameterPreset holds the generation parameters a preset expands to; unset
+// parameters are left to the request
+type ParameterPreset struct {
+	Temperature      *float64 `mapstructure:"temperature"`
+	TopP             *float64 `mapstructure:"top_p"`
+	PresencePenalty  *float64 `mapstructure:"presence_penalty"`
+	FrequencyPenalty *float64 `mapstructure:"frequency_penalty"`
+}
+
+// PricingConfig maps a model ("*" for any other) to its token prices
+type PricingConfig map[string]TokenPriceConfig
+
+// TokenPriceConfig is a model's price per million prompt and completion tokens
+type TokenPriceConfig struct {
+	Prompt     float64 `mapstructure:"prompt"`
+	Completion float64 `mapstructure:"completion"`
+}
+
+// ModelCatalogConfig maps a model to metadata overriding the built-in catalog
+type ModelCatalogConfig map[string]ModelMetadataConfig
+
+// ModelMetadataConfig is a model's metadata; zero limits and unset
+// capabilities keep the built-in values
+type ModelMetadataConfig struct {
+	ContextWindow   int   `mapstructure:"context_window"`
+	MaxOutputTokens int   `mapstructure:"max_output_tokens"`
+	Vision          *bool `mapstructure:"vision"`
+	Tools           *bool `mapstructure:"tools"`
+	JSONMode        *bool `mapstructure:"json_mode"`
+}
+
+// StylesConfig holds settings for server-managed system prompt presets
+type StylesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// File persists presets across restarts; empty keeps them in memory only
+	File string `mapstructure:"file"`
+}
+
+// ProviderOverrideConfig controls the X-Provider and X-Provider-Base-URL request headers
+type ProviderOverrideConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DebugKeys are the API keys holding the "debug" scope; only they may override
+	DebugKeys []string `mapstructure:"debug_keys"`
+	// AllowedBaseURLs are the URL prefixes accepted in X-Provider-Base-URL
+	// (none allows provider overrides only). Provider credentials are sent to these URLs.
+	AllowedBaseURLs []string `mapstructure:"allowed_base_urls"`
+}
+
+// LeaderElectionConfig holds settings for electing the replica that runs
+// singleton background jobs, such as Ollama model pulls
+type LeaderElectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Backend is "redis" or "kubernetes" (a coordination.k8s.io Lease)
+	Backend string `mapstructure:"backend"`
+	// Name is the Redis key or Lease name shared by all replicas
+	Name string `mapstructure:"name"`
+	// Identity of this replica (default: hostname plus a random suffix)
+	Identity      string                `mapstructure:"identity"`
+	LeaseDuration time.Duration         `mapstructure:"lease_duration"`
+	RenewInterval time.Duration         `mapstructure:"renew_interval"`
+	Redis         RedisConfig           `mapstructure:"redis"`
+	Kubernetes    KubernetesLeaseConfig `mapstructure:"kubernetes"`
+}
+
+// KubernetesLeaseConfig holds the Kubernetes API used for leader election
+type KubernetesLeaseConfig struct {
+	// APIServer defaults to the in-cluster address from KUBERNETES_SERVICE_HOST/PORT
+	APIServer string `mapstructure:"api_server"`
+	Namespace string `mapstructure:"namespace"`
+	TokenFile string `mapstructure:"token_file"`
+	CAFile    string `mapstructure:"ca_file"`
+}
+
+// ControlPlaneConfig holds settings for pulling routing rules, provider keys and
+// tenant budgets from a central control plane, so a fleet of gateways stays in sync
+type ControlPlaneConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	URL     string `mapstructure:"url"`
+	// Token is sent as a bearer token to the control plane
+	Token string `mapstructure:"token"`
+	// SigningKey is the shared HMAC-SHA256 key payloads must be signed with
+	SigningKey   string        `mapstructure:"signing_key"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	// CacheFile keeps the last verified payload, applied at startup if the
+	// control plane cannot be reached
+	CacheFile string `mapstructure:"cache_file"`
+	// MaxPayloadAge rejects fetched payloads whose issued_at is older than this (0 disables)
+	MaxPayloadAge time.Duration `mapstructure:"max_payload_age"`
+	// Canary serves new payloads to a share of traffic before promoting them
+	Canary CanaryConfig `mapstructure:"canary"`
+}
+
+// CanaryConfig holds settings for rolling out a new control plane payload to a
+// share of traffic first, rolling it back automatically if it regresses
+type CanaryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Percent of API requests served with the new routes and budgets during the window
+	Percent float64 `mapstructure:"percent"`
+	// Window is how long the canary runs before the payload is promoted
+	Window time.Duration `mapstructure:"window"`
+	// MaxWindow bounds how far the window is extended, a window at a time, while
+	// either cohort has fewer than MinRequests; the payload is then rolled back
+	MaxWindow time.Duration `mapstructure:"max_window"`
+	// MinRequests is the number of requests each cohort needs before it is compared
+	MinRequests int64 `mapstructure:"min_requests"`
+	// MaxErrorRateIncrease rolls back when the canary 5xx rate exceeds the stable rate by more than this
+	MaxErrorRateIncrease float64 `mapstructure:"max_error_rate_increase"`
+	// MaxLatencyRatio rolls back when the canary mean latency exceeds the stable mean by this factor
+	MaxLatencyRatio float64 `mapstructure:"max_latency_ratio"`
+}
+
+// RetentionConfig holds how long stored records are kept before being purged
+type RetentionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// PurgeInterval is how often expired and deleted records are purged
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+	// DeleteGrace is how long records deleted on request stay (hidden) before being purged
+	DeleteGrace time.Duration `mapstructure:"delete_grace"`
+	// Stores maps a store ("audit", "usage") to how long its records are kept; 0 keeps them
+	Stores map[string]time.Duration `mapstructure:"stores"`
+	// Tenants overrides Stores per tenant, e.g. tenants.acme.usage: 8760h
+	Tenants map[string]map[string]time.Duration `mapstructure:"tenants"`
+}
+
+// PrivacyConfig holds settings for per-user data deletion requests
+type PrivacyConfig struct {
+	// SigningKey is the HMAC-SHA256 key deletion reports are signed with;
+	// deletion requests are refused while it is unset
+	SigningKey string `mapstructure:"signing_key"`
+}
+
+// UsageExportConfig holds settings for the aggregated usage export. Groups with
+// fewer than MinUsers distinct end users are suppressed, and with Epsilon set
+// Laplace noise is added to every count.
+type UsageExportConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// APIKeys may read the export only; admin keys are accepted as well
+	APIKeys []string `mapstructure:"api_keys"`
+	// MinUsers is the k-anonymity threshold; callers may raise it, not lower it
+	MinUsers int `mapstructure:"min_users"`
+	// Epsilon is the differential privacy budget per count (0 disables noise)
+	Epsilon float64 `mapstructure:"epsilon"`
+	// TokenSensitivity bounds the tokens one request is assumed to add to a
+	// token count, scaling the noise on token counts
+	TokenSensitivity int64 `mapstructure:"token_sensitivity"`
+	// NoiseKey seeds the noise so a count always gets the same noise, on every
+	// replica; required with Epsilon so repeated exports cannot be averaged
+	NoiseKey string `mapstructure:"noise_key"`
 }
 
 // Load reads configuration from file and environment variables
 func Load() (*Config, error) {
+	v, err := newViper()
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal config
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+
+	// Validate required fields
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// newViper returns a viper instance with defaults, environment bindings and
+// the config file (if any) loaded
+func newViper() (*viper.Viper, error) {
 	v := viper.New()
 
 	// Set config name and paths
@@ -179,9 +1456,10 @@ func Load() (*Config, error) {
 	setDefaults(v)
 
 	// Enable environment variable override
-	v.SetEnvPrefix("LLM_GATEWAY")
+	v.SetEnvPrefix(envPrefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
+	bindEnvs(v)
 
 	// Read config file (optional - env vars can override everything)
 	if err := v.ReadInConfig(); err != nil {
@@ -191,18 +1469,7 @@ func Load() (*Config, error) {
 		// Config file not found is OK - we use defaults and env vars
 	}
 
-	// Unmarshal config
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
-	}
-
-	// Validate required fields
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
-	}
-
-	return &cfg, nil
+	return v, nil
 }
 
 // setDefaults sets default values for all configuration options
@@ -212,16 +1479,43 @@ func setDefaults(v *viper.Viper) {
 
 	// Server defaults
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.admin_port", 0)
+	v.SetDefault("server.http2.enabled", true)
+	v.SetDefault("server.http2.h2c", false)
+	v.SetDefault("server.http2.max_concurrent_streams", 250)
+	v.SetDefault("server.unix_socket.path", "")
+	v.SetDefault("server.unix_socket.mode", "0660")
 	v.SetDefault("server.read_timeout", "30s")
 	v.SetDefault("server.write_timeout", "120s") // Longer for streaming
 	v.SetDefault("server.idle_timeout", "120s")
+	v.SetDefault("server.request_id_format", "sequential")
+	v.SetDefault("server.response_id_format", "uuid")
 
 	// Log defaults
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.format", "json")
+	v.SetDefault("log.output", "stdout")
+	v.SetDefault("log.max_size_mb", 100)
+	v.SetDefault("log.rotate_interval", "24h")
+	v.SetDefault("log.max_backups", 7)
+	v.SetDefault("log.compress", true)
+	v.SetDefault("log.async", true)
+	v.SetDefault("log.buffer_size", 4096)
+	v.SetDefault("log.overflow_policy", "block")
+
+	// Access log defaults
+	v.SetDefault("access_log.enabled", false)
+	v.SetDefault("access_log.format", "combined")
+	v.SetDefault("access_log.output", "stdout")
+	v.SetDefault("access_log.max_size_mb", 100)
+	v.SetDefault("access_log.rotate_interval", "24h")
+	v.SetDefault("access_log.max_backups", 7)
 
 	// Provider defaults
 	v.SetDefault("providers.default", "openai")
+	v.SetDefault("providers.credential_failover_threshold", 3)
+	v.SetDefault("providers.key_cooldown", "30s")
+	v.SetDefault("providers.model_cache_ttl", "1m")
 	v.SetDefault("providers.openai.base_url", "https://api.openai.com/v1")
 	v.SetDefault("providers.openai.timeout", "60s")
 	v.SetDefault("providers.anthropic.base_url", "https://api.anthropic.com")
@@ -229,12 +1523,35 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("providers.anthropic.version", "2023-06-01")
 	v.SetDefault("providers.ollama.base_url", "http://localhost:11434")
 	v.SetDefault("providers.ollama.timeout", "120s")
+	v.SetDefault("providers.ollama.prefetch_models", []string{})
+	v.SetDefault("providers.ollama.auto_pull", false)
+	v.SetDefault("providers.ollama.pull_timeout", "30m")
+	v.SetDefault("providers.ollama.instances", []string{})
+	v.SetDefault("providers.ollama.poll_interval", "10s")
+	v.SetDefault("providers.ollama.embed_batch_size", 64)
+	v.SetDefault("providers.ollama.embed_concurrency", 4)
+	v.SetDefault("providers.ollama.model_refresh_interval", "30s")
+	for _, provider := range []string{"openai", "ollama"} {
+		prefix := "providers." + provider + ".discovery."
+		v.SetDefault(prefix+"type", "")
+		v.SetDefault(prefix+"scheme", "http")
+		v.SetDefault(prefix+"refresh_interval", "30s")
+		v.SetDefault(prefix+"consul.address", "http://127.0.0.1:8500")
+		v.SetDefault(prefix+"kubernetes.namespace", "default")
+		v.SetDefault(prefix+"kubernetes.token_file", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+		v.SetDefault(prefix+"kubernetes.ca_file", "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+	}
 
 	// Rate limit defaults
 	v.SetDefault("rate_limit.enabled", false)
 	v.SetDefault("rate_limit.requests_per_min", 60)
 	v.SetDefault("rate_limit.burst_size", 10)
 	v.SetDefault("rate_limit.cleanup_interval", "1m")
+	v.SetDefault("rate_limit.fingerprint_user_agent", false)
+
+	// Stream limit defaults
+	v.SetDefault("stream_limit.enabled", false)
+	v.SetDefault("stream_limit.max_per_key", 10)
 
 	// Reliability defaults - Circuit Breaker
 	v.SetDefault("reliability.circuit_breaker.enabled", true)
@@ -242,6 +1559,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("reliability.circuit_breaker.success_threshold", 3)
 	v.SetDefault("reliability.circuit_breaker.timeout", "30s")
 	v.SetDefault("reliability.circuit_breaker.max_half_open_requests", 1)
+	v.SetDefault("reliability.circuit_breaker.mode", "consecutive")
+	v.SetDefault("reliability.circuit_breaker.window_type", "count")
+	v.SetDefault("reliability.circuit_breaker.window_size", 100)
+	v.SetDefault("reliability.circuit_breaker.window_duration", "60s")
+	v.SetDefault("reliability.circuit_breaker.failure_rate_threshold", 50.0)
+	v.SetDefault("reliability.circuit_breaker.minimum_requests", 10)
 
 	// Reliability defaults - Retry
 	v.SetDefault("reliability.retry.enabled", true)
@@ -249,6 +1572,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("reliability.retry.initial_backoff", "500ms")
 	v.SetDefault("reliability.retry.max_backoff", "30s")
 	v.SetDefault("reliability.retry.backoff_multiplier", 2.0)
+	v.SetDefault("reliability.retry.min_attempt_time", "1s")
+	v.SetDefault("reliability.retry.max_concurrent_retries", 0)
+	v.SetDefault("reliability.retry.budget.enabled", false)
+	v.SetDefault("reliability.retry.budget.percent", 20.0)
+	v.SetDefault("reliability.retry.budget.window", "10s")
+	v.SetDefault("reliability.retry.budget.min_retries", 10)
 
 	// Cache defaults
 	v.SetDefault("cache.enabled", false)
@@ -257,6 +1586,13 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("cache.backend", "memory")
 	v.SetDefault("cache.redis.address", "localhost:6379")
 	v.SetDefault("cache.redis.db", 0)
+	v.SetDefault("cache.key_prefix", "llm-gateway:")
+	v.SetDefault("cache.pool_size", 10)
+	v.SetDefault("cache.timeout", "2s")
+	v.SetDefault("cache.similarity.enabled", false)
+	v.SetDefault("cache.similarity.model", "text-embedding-3-small")
+	v.SetDefault("cache.similarity.threshold", 0.95)
+	v.SetDefault("cache.similarity.timeout", "2s")
 
 	// Performance defaults - Connection Pool
 	v.SetDefault("performance.connection_pool.max_idle_conns", 100)
@@ -275,6 +1611,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("performance.queue.max_wait_time", "30s")
 	v.SetDefault("performance.queue.worker_count", 10)
 	v.SetDefault("performance.queue.priority_enabled", true)
+	v.SetDefault("performance.queue.fair_queuing", false)
 
 	// Observability defaults - Metrics
 	v.SetDefault("observability.metrics.enabled", true)
@@ -286,6 +1623,269 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("observability.tracing.service_name", "llm-gateway")
 	v.SetDefault("observability.tracing.sampling_rate", 1.0)
 	v.SetDefault("observability.tracing.exporter_type", "console")
+
+	// Flight recorder defaults
+	v.SetDefault("observability.flight_recorder.enabled", false)
+	v.SetDefault("observability.flight_recorder.size", 200)
+	v.SetDefault("observability.flight_recorder.error_rate_threshold", 0.2)
+	v.SetDefault("observability.flight_recorder.window", 50)
+	v.SetDefault("observability.flight_recorder.min_requests", 20)
+	v.SetDefault("observability.flight_recorder.cooldown", "5m")
+	v.SetDefault("observability.flight_recorder.capture_bodies", false)
+	v.SetDefault("observability.flight_recorder.max_capture_bytes", 65536)
+	v.SetDefault("observability.timeseries.enabled", true)
+	v.SetDefault("observability.timeseries.retention", "30m")
+
+	// Admin API defaults
+	v.SetDefault("admin.enabled", false)
+	v.SetDefault("admin.api_keys", []string{})
+
+	// Maintenance defaults
+	v.SetDefault("maintenance.enabled", false)
+	v.SetDefault("maintenance.retry_after", "5m")
+	v.SetDefault("maintenance.message", "The gateway is undergoing scheduled maintenance")
+	v.SetDefault("maintenance.drained_providers", []string{})
+
+	// Degraded mode defaults
+	v.SetDefault("degraded.enabled", false)
+	v.SetDefault("degraded.auto", false)
+	v.SetDefault("degraded.max_stale", "0s")
+	v.SetDefault("degraded.retry_after", "30s")
+
+	// Mirror defaults
+	v.SetDefault("mirror.enabled", false)
+	v.SetDefault("mirror.sample_rate", 0.01)
+	v.SetDefault("mirror.timeout", "30s")
+	v.SetDefault("mirror.max_body_bytes", 1048576) // 1MB
+	v.SetDefault("mirror.max_concurrent", 16)
+
+	// Response limit defaults
+	v.SetDefault("response_limits.enabled", false)
+	v.SetDefault("response_limits.default.max_bytes", 0)
+	v.SetDefault("response_limits.default.max_tokens", 0)
+	v.SetDefault("response_limits.default.policy", "truncate")
+
+	// Loop detection defaults
+	v.SetDefault("loop_detection.enabled", false)
+	v.SetDefault("loop_detection.ngram_size", 4)
+	v.SetDefault("loop_detection.window", 200)
+	v.SetDefault("loop_detection.max_repeats", 8)
+	v.SetDefault("loop_detection.terminate", true)
+
+	// Language detection defaults
+	v.SetDefault("language.enabled", false)
+	v.SetDefault("language.min_chars", 20)
+	v.SetDefault("language.enforcement.enabled", false)
+	v.SetDefault("language.enforcement.default.required", "prompt")
+	v.SetDefault("language.enforcement.default.retry", true)
+
+	// Use case classification defaults
+	v.SetDefault("use_case.enabled", false)
+
+	// Style preset defaults
+	v.SetDefault("styles.enabled", false)
+
+	// Leader election defaults
+	v.SetDefault("leader_election.enabled", false)
+	v.SetDefault("leader_election.backend", "redis")
+	v.SetDefault("leader_election.name", "llm-gateway-leader")
+	v.SetDefault("leader_election.lease_duration", "15s")
+	v.SetDefault("leader_election.renew_interval", "5s")
+	v.SetDefault("leader_election.redis.address", "localhost:6379")
+	v.SetDefault("leader_election.kubernetes.namespace", "default")
+	v.SetDefault("leader_election.kubernetes.token_file", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	v.SetDefault("leader_election.kubernetes.ca_file", "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+
+	// Provider override defaults
+	v.SetDefault("provider_override.enabled", false)
+
+	// Retention defaults
+	v.SetDefault("retention.enabled", false)
+	v.SetDefault("retention.purge_interval", "1h")
+	v.SetDefault("retention.delete_grace", "24h")
+	v.SetDefault("retention.stores.audit", "720h")  // 30 days
+	v.SetDefault("retention.stores.usage", "2160h") // 90 days
+
+	// Usage export defaults
+	v.SetDefault("usage_export.enabled", false)
+	v.SetDefault("usage_export.api_keys", []string{})
+	v.SetDefault("usage_export.min_users", 5)
+	v.SetDefault("usage_export.epsilon", 0.0)
+	v.SetDefault("usage_export.token_sensitivity", 4096)
+
+	// Prompt statistics defaults
+	v.SetDefault("prompt_stats.enabled", false)
+	v.SetDefault("prompt_stats.window", "24h")
+	v.SetDefault("prompt_stats.max_prompts", 10000)
+	v.SetDefault("prompt_stats.distance", 3)
+	v.SetDefault("prompt_stats.sample_chars", 200)
+
+	// SLA report defaults
+	v.SetDefault("sla_reports.enabled", false)
+	v.SetDefault("sla_reports.periods", []string{"daily", "weekly"})
+	v.SetDefault("sla_reports.max_reports", 100)
+	v.SetDefault("sla_reports.timeout", "10s")
+	v.SetDefault("sla_reports.store", "memory")
+	v.SetDefault("sla_reports.redis.address", "localhost:6379")
+	v.SetDefault("sla_reports.key_prefix", "llm-gateway:sla:")
+
+	// Citation defaults
+	v.SetDefault("citations.stream_events", true)
+
+	// Tool call assembly defaults
+	v.SetDefault("tool_call_assembly.mode", "off")
+
+	// Stream transcript defaults
+	v.SetDefault("transcripts.enabled", false)
+	v.SetDefault("transcripts.ttl", "1h")
+	v.SetDefault("transcripts.max_entries", 10000)
+	v.SetDefault("transcripts.max_duration", "10m")
+
+	// Fallback defaults
+	v.SetDefault("fallbacks.enabled", false)
+
+	// Overflow defaults
+	v.SetDefault("overflow.enabled", false)
+
+	// Call header defaults
+	v.SetDefault("call_headers.enabled", false)
+
+	// Prompt secret detection defaults
+	v.SetDefault("prompt_secrets.enabled", false)
+	v.SetDefault("prompt_secrets.action", "block")
+
+	// Guardrail defaults
+	v.SetDefault("guardrails.enabled", false)
+	v.SetDefault("guardrails.moderation.url", "https://api.openai.com/v1/moderations")
+	v.SetDefault("guardrails.moderation.timeout", "5s")
+	v.SetDefault("guardrails.moderation.fail_closed", false)
+
+	// PII masking defaults
+	v.SetDefault("pii.enabled", false)
+	v.SetDefault("pii.restore", true)
+
+	// Request limit defaults
+	v.SetDefault("request_limits.enabled", true)
+	v.SetDefault("request_limits.max_request_bytes", 10485760) // 10MB
+	v.SetDefault("request_limits.max_messages", 0)
+	v.SetDefault("request_limits.max_content_chars", 0)
+
+	// Error message localization defaults
+	v.SetDefault("error_messages.enabled", false)
+
+	// Integrity defaults
+	v.SetDefault("integrity.enabled", false)
+	v.SetDefault("integrity.require", false)
+
+	// Abuse detection defaults
+	v.SetDefault("abuse_detection.enabled", false)
+	v.SetDefault("abuse_detection.window", "5m")
+	v.SetDefault("abuse_detection.spike_factor", 10)
+	v.SetDefault("abuse_detection.spike_min_requests", 100)
+	v.SetDefault("abuse_detection.refusal_rate", 0.5)
+	v.SetDefault("abuse_detection.refusal_min_requests", 20)
+	v.SetDefault("abuse_detection.max_ips", 20)
+	v.SetDefault("abuse_detection.max_models", 10)
+	v.SetDefault("abuse_detection.restrict_after", 1)
+	v.SetDefault("abuse_detection.restrict_for", "1h")
+
+	// Unknown field tracking defaults
+	v.SetDefault("unknown_fields.enabled", false)
+	v.SetDefault("unknown_fields.strict", false)
+	v.SetDefault("unknown_fields.max_entries", 1000)
+
+	// Managed API key defaults
+	v.SetDefault("api_keys.enabled", false)
+	v.SetDefault("api_keys.required", false)
+	v.SetDefault("api_keys.store", "file")
+	v.SetDefault("api_keys.path", "api_keys.json")
+	v.SetDefault("api_keys.redis.address", "localhost:6379")
+	v.SetDefault("api_keys.redis_key", "llm-gateway:api-keys")
+	v.SetDefault("api_keys.refresh_interval", "30s")
+	v.SetDefault("config_reload.enabled", false)
+	v.SetDefault("config_reload.watch", false)
+	v.SetDefault("api_keys.stream_reservation.enabled", false)
+	v.SetDefault("api_keys.stream_reservation.default_completion_tokens", 1024)
+	v.SetDefault("api_keys.stream_reservation.margin", 0.2)
+	v.SetDefault("api_keys.stream_reservation.near_limit", 0.9)
+
+	// Cost tracking defaults
+	v.SetDefault("cost.enabled", false)
+	v.SetDefault("cost.history", "168h")
+	v.SetDefault("cost.anomaly.enabled", false)
+	v.SetDefault("cost.anomaly.factor", 3.0)
+	v.SetDefault("cost.anomaly.min_spend", 1.0)
+	v.SetDefault("cost.anomaly.min_history", "24h")
+
+	// Output filter defaults
+	v.SetDefault("output_filters.enabled", false)
+	v.SetDefault("output_filters.window", 256)
+
+	// JWT auth defaults
+	v.SetDefault("jwt_auth.enabled", false)
+	v.SetDefault("jwt_auth.jwks_refresh", "15m")
+	v.SetDefault("jwt_auth.timeout", "5s")
+	v.SetDefault("jwt_auth.clock_skew", "1m")
+	v.SetDefault("jwt_auth.user_claim", "sub")
+
+	// Conversation compression defaults
+	v.SetDefault("conversation_compression.enabled", false)
+	v.SetDefault("conversation_compression.threshold", 8000)
+	v.SetDefault("conversation_compression.keep_recent", 6)
+	v.SetDefault("conversation_compression.max_summary_tokens", 500)
+	v.SetDefault("conversation_compression.timeout", "30s")
+	v.SetDefault("conversation_compression.cache_entries", 1000)
+
+	// Translation defaults
+	v.SetDefault("translation.enabled", false)
+	v.SetDefault("translation.timeout", "30s")
+
+	// Prompt lint defaults
+	v.SetDefault("prompt_lint.enabled", false)
+	v.SetDefault("prompt_lint.annotate", false)
+	v.SetDefault("prompt_lint.max_context_chars", 100000)
+	v.SetDefault("prompt_lint.repeated_min_chars", 200)
+
+	// Parameter preset defaults
+	v.SetDefault("parameter_presets.enabled", false)
+
+	// Notification defaults
+	v.SetDefault("notifications.timeout", "5s")
+	v.SetDefault("notifications.queue_size", 1000)
+
+	// Quota warning defaults
+	v.SetDefault("quota_warnings.enabled", false)
+	v.SetDefault("quota_warnings.thresholds", []float64{80, 95})
+
+	// Canary rollout defaults
+	v.SetDefault("control_plane.canary.enabled", false)
+	v.SetDefault("control_plane.canary.percent", 5.0)
+	v.SetDefault("control_plane.canary.window", "10m")
+	v.SetDefault("control_plane.canary.max_window", "1h")
+	v.SetDefault("control_plane.canary.min_requests", 50)
+	v.SetDefault("control_plane.canary.max_error_rate_increase", 0.05)
+	v.SetDefault("control_plane.canary.max_latency_ratio", 1.5)
+
+	// Privacy defaults
+	v.SetDefault("privacy.signing_key", "")
+
+	// Control plane defaults
+	v.SetDefault("control_plane.enabled", false)
+	v.SetDefault("control_plane.poll_interval", "30s")
+	v.SetDefault("control_plane.timeout", "10s")
+	v.SetDefault("control_plane.max_payload_age", "24h")
+
+	// Blob reference defaults
+	v.SetDefault("blobs.enabled", false)
+	v.SetDefault("blobs.s3_endpoint", "")
+	v.SetDefault("blobs.s3_allowed_prefixes", []string{})
+	v.SetDefault("blobs.fetch_timeout", "30s")
+	v.SetDefault("blobs.max_blob_bytes", 8388608)     // 8MB
+	v.SetDefault("blobs.max_request_bytes", 33554432) // 32MB
+	v.SetDefault("blobs.cache_ttl", "10m")
+	v.SetDefault("blobs.cache_entries", 100)
+	v.SetDefault("blobs.file_ttl", "24h")
+	v.SetDefault("blobs.max_files", 1000)
 }
 
 // Validate checks if the configuration is valid
@@ -293,7 +1893,9 @@ func (c *Config) Validate() error {
 	// Check if at least one provider is configured
 	hasProvider := c.Providers.OpenAI.APIKey != "" ||
 		c.Providers.Anthropic.APIKey != "" ||
-		c.Providers.Ollama.BaseURL != ""
+		c.Providers.Ollama.BaseURL != "" ||
+		c.Providers.Ollama.Discovery.Type != "" ||
+		len(c.Providers.Remote) > 0
 
 	if !hasProvider {
 		// Allow running without providers for health check testing
@@ -304,6 +1906,811 @@ func (c *Config) Validate() error {
 	if c.Server.Port < 1 || c.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
+	if c.Server.AdminPort != 0 {
+		if c.Server.AdminPort < 1 || c.Server.AdminPort > 65535 {
+			return fmt.Errorf("invalid server admin port: %d", c.Server.AdminPort)
+		}
+		if c.Server.AdminPort == c.Server.Port {
+			return fmt.Errorf("server.admin_port must differ from server.port")
+		}
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return fmt.Errorf("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		if cidrErr != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid server.trusted_proxies: %s (must be an IP or CIDR)", proxy)
+		}
+	}
+	switch c.Server.RequestIDFormat {
+	case "", "sequential", "uuidv4", "uuidv7", "ulid":
+	default:
+		return fmt.Errorf("invalid server.request_id_format: %q (must be sequential, uuidv4, uuidv7 or ulid)", c.Server.RequestIDFormat)
+	}
+	switch c.Server.ResponseIDFormat {
+	case "", "uuid", "sequential":
+	default:
+		return fmt.Errorf("invalid server.response_id_format: %q (must be uuid or sequential)", c.Server.ResponseIDFormat)
+	}
+	if len(c.Server.Middleware) > 0 {
+		seen := make(map[string]bool, len(c.Server.Middleware))
+		for _, name := range c.Server.Middleware {
+			if !slices.Contains(DefaultMiddleware, name) {
+				return fmt.Errorf("invalid server.middleware: unknown middleware %q (must be one of %s)", name, strings.Join(DefaultMiddleware, ", "))
+			}
+			if seen[name] {
+				return fmt.Errorf("invalid server.middleware: %q is listed twice", name)
+			}
+			seen[name] = true
+		}
+		// Authentication cannot be switched off by leaving it out of the chain
+		if c.JWTAuth.Enabled && !seen[MiddlewareJWTAuth] {
+			return fmt.Errorf("invalid server.middleware: jwt_auth is enabled but not listed")
+		}
+		if c.APIKeys.Enabled && !seen[MiddlewareAPIKeys] {
+			return fmt.Errorf("invalid server.middleware: api_keys is enabled but not listed")
+		}
+		if c.Integrity.Enabled && !seen[MiddlewareIntegrity] {
+			return fmt.Errorf("invalid server.middleware: integrity is enabled but not listed")
+		}
+		if c.ErrorMessages.Enabled && !seen[MiddlewareErrorMessages] {
+			return fmt.Errorf("invalid server.middleware: error_messages is enabled but not listed")
+		}
+	}
+	if c.Server.UnixSocket.Path != "" {
+		if _, err := c.Server.UnixSocket.FileMode(); err != nil {
+			return fmt.Errorf("invalid server.unix_socket.mode: %s", c.Server.UnixSocket.Mode)
+		}
+	}
+
+	for name, tier := range c.RateLimit.Tiers {
+		if tier.RequestsPerMin < 1 || tier.BurstSize < 1 {
+			return fmt.Errorf("invalid rate_limit.tiers.%s: requests_per_min and burst_size must be at least 1", name)
+		}
+		if tier.QueueWeight < 0 {
+			return fmt.Errorf("invalid rate_limit.tiers.%s.queue_weight: %d", name, tier.QueueWeight)
+		}
+	}
+	if q := c.Performance.Queue; q.Enabled && (q.WorkerCount < 1 || q.MaxQueueSize < 1 || q.MaxWaitTime <= 0) {
+		return fmt.Errorf("invalid performance.queue: worker_count and max_queue_size must be at least 1 and max_wait_time positive")
+	}
+
+	// Validate JWT auth
+	if ja := c.JWTAuth; ja.Enabled {
+		if ja.JWKSURL == "" {
+			return fmt.Errorf("jwt_auth.jwks_url is required when jwt_auth is enabled")
+		}
+		if ja.UserClaim == "" {
+			return fmt.Errorf("jwt_auth.user_claim is required when jwt_auth is enabled")
+		}
+		if ja.ClockSkew < 0 || ja.JWKSRefresh <= 0 {
+			return fmt.Errorf("invalid jwt_auth: clock_skew must not be negative and jwks_refresh must be positive")
+		}
+	}
+
+	if c.StreamLimit.Enabled && c.StreamLimit.MaxPerKey < 1 {
+		return fmt.Errorf("stream_limit.max_per_key must be at least 1")
+	}
+
+	// Validate circuit breaker modes, with each provider's overrides
+	if cb := c.Reliability.CircuitBreaker; cb.Enabled {
+		if err := cb.validate(); err != nil {
+			return fmt.Errorf("invalid reliability.circuit_breaker: %w", err)
+		}
+		for name := range cb.Providers {
+			if err := cb.For(name).validate(); err != nil {
+				return fmt.Errorf("invalid reliability.circuit_breaker.providers.%s: %w", name, err)
+			}
+		}
+	}
+
+	// Validate retry limits
+	if c.Reliability.Retry.MaxConcurrentRetries < 0 {
+		return fmt.Errorf("invalid reliability.retry.max_concurrent_retries: must not be negative")
+	}
+	if budget := c.Reliability.Retry.Budget; budget.Enabled {
+		if budget.Percent < 0 || budget.Percent > 100 {
+			return fmt.Errorf("invalid reliability.retry.budget.percent: must be between 0 and 100")
+		}
+		if budget.Window < time.Second {
+			return fmt.Errorf("invalid reliability.retry.budget.window: must be at least 1s")
+		}
+		if budget.MinRetries < 0 {
+			return fmt.Errorf("invalid reliability.retry.budget.min_retries: must not be negative")
+		}
+	}
+
+	if c.Degraded.MaxStale < 0 || c.Degraded.RetryAfter < 0 {
+		return fmt.Errorf("invalid degraded: max_stale and retry_after must not be negative")
+	}
+
+	// Validate outbound provider limits
+	for name, limit := range c.Providers.OutboundLimits {
+		if limit.RequestsPerSec < 0 || limit.TokensPerMin < 0 || limit.MaxQueue < 0 || limit.MaxWait < 0 {
+			return fmt.Errorf("invalid providers.outbound_limits.%s: values must not be negative", name)
+		}
+	}
+
+	// Validate remote providers
+	for name, remote := range c.Providers.Remote {
+		switch name {
+		case "openai", "anthropic", "ollama":
+			return fmt.Errorf("invalid providers.remote.%s: name is taken by a built-in provider", name)
+		}
+		if remote.Address == "" {
+			return fmt.Errorf("providers.remote.%s.address is required", name)
+		}
+		if remote.Timeout < 0 || remote.HealthInterval < 0 {
+			return fmt.Errorf("invalid providers.remote.%s: durations must not be negative", name)
+		}
+	}
+
+	// Validate standby providers
+	for _, name := range c.Providers.Standby {
+		if name == c.Providers.Default {
+			return fmt.Errorf("invalid providers.standby: %s is the default provider", name)
+		}
+	}
+	for i, rule := range c.Providers.Balancing {
+		if _, err := path.Match(rule.Models, ""); err != nil || rule.Models == "" {
+			return fmt.Errorf("invalid providers.balancing[%d].models: bad model pattern %q", i, rule.Models)
+		}
+		if rule.Strategy != "" && rule.Strategy != "weighted" && rule.Strategy != "least_latency" {
+			return fmt.Errorf("invalid providers.balancing[%d].strategy: %q (must be weighted or least_latency)", i, rule.Strategy)
+		}
+		for name, weight := range rule.Weights {
+			if weight < 1 {
+				return fmt.Errorf("invalid providers.balancing[%d].weights.%s: %d (must be at least 1)", i, name, weight)
+			}
+		}
+	}
+
+	// Validate the routing table
+	for alias, model := range c.Routing.Aliases {
+		if model == "" {
+			return fmt.Errorf("invalid routing.aliases.%s: model is required", alias)
+		}
+		if _, chained := c.Routing.Aliases[strings.ToLower(model)]; chained {
+			return fmt.Errorf("invalid routing.aliases.%s: %s is itself an alias", alias, model)
+		}
+	}
+	for model, provider := range c.Routing.Models {
+		if provider == "" {
+			return fmt.Errorf("invalid routing.models.%s: provider is required", model)
+		}
+	}
+	for i, rule := range c.Routing.Rules {
+		if _, err := path.Match(rule.Models, ""); err != nil || rule.Models == "" {
+			return fmt.Errorf("invalid routing.rules[%d].models: bad model pattern %q", i, rule.Models)
+		}
+		if rule.Provider == "" {
+			return fmt.Errorf("invalid routing.rules[%d].provider: provider is required", i)
+		}
+	}
+
+	for i, rule := range c.Providers.TTFTSLOs {
+		switch {
+		case rule.Models == "":
+			return fmt.Errorf("invalid providers.ttft_slos[%d].models: must be set", i)
+		case rule.Target <= 0:
+			return fmt.Errorf("invalid providers.ttft_slos[%d].target: %s (must be positive)", i, rule.Target)
+		case rule.Percentile < 0 || rule.Percentile > 1:
+			return fmt.Errorf("invalid providers.ttft_slos[%d].percentile: %v (must be between 0 and 1)", i, rule.Percentile)
+		case rule.Window < 0:
+			return fmt.Errorf("invalid providers.ttft_slos[%d].window: %d (must not be negative)", i, rule.Window)
+		case rule.Fallback == "":
+			return fmt.Errorf("invalid providers.ttft_slos[%d].fallback: must be set", i)
+		case rule.Shift < 0 || rule.Shift >= 1:
+			return fmt.Errorf("invalid providers.ttft_slos[%d].shift: %v (must be at least 0 and below 1)", i, rule.Shift)
+		}
+		if _, err := path.Match(rule.Models, ""); err != nil {
+			return fmt.Errorf("invalid providers.ttft_slos[%d].models: bad model pattern %q", i, rule.Models)
+		}
+	}
+
+	// Validate flight recorder
+	if c.Observability.FlightRecorder.Enabled {
+		threshold := c.Observability.FlightRecorder.ErrorRateThreshold
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("observability.flight_recorder.error_rate_threshold must be in (0, 1]")
+		}
+		if fr := c.Observability.FlightRecorder; fr.CaptureBodies && fr.MaxCaptureBytes < 1 {
+			return fmt.Errorf("invalid observability.flight_recorder.max_capture_bytes: %d (must be positive)", fr.MaxCaptureBytes)
+		}
+	}
+
+	// Validate time series
+	if ts := c.Observability.TimeSeries; ts.Enabled && (ts.Retention < time.Minute || ts.Retention > 24*time.Hour) {
+		return fmt.Errorf("invalid observability.timeseries.retention: %s (must be between 1m and 24h)", ts.Retention)
+	}
+
+	// Validate per-module log levels
+	for module, level := range c.Log.Modules {
+		switch level {
+		case "trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled":
+		default:
+			return fmt.Errorf("invalid log.modules.%s level: %s", module, level)
+		}
+	}
+
+	// Validate log file output
+	if c.Log.Output == "file" {
+		if c.Log.FilePath == "" {
+			return fmt.Errorf("log.file_path is required when log.output is file")
+		}
+		if c.Log.OverflowPolicy != "drop" && c.Log.OverflowPolicy != "block" {
+			return fmt.Errorf("invalid log.overflow_policy: %s", c.Log.OverflowPolicy)
+		}
+	}
+
+	// Validate access log format
+	if c.AccessLog.Enabled {
+		switch c.AccessLog.Format {
+		case "common", "combined", "json":
+		default:
+			return fmt.Errorf("invalid access_log.format: %s", c.AccessLog.Format)
+		}
+	}
+
+	// Validate mirroring
+	if c.Mirror.Enabled {
+		if c.Mirror.TargetURL == "" {
+			return fmt.Errorf("mirror.target_url is required when mirroring is enabled")
+		}
+		if c.Mirror.SampleRate < 0 || c.Mirror.SampleRate > 1 {
+			return fmt.Errorf("mirror.sample_rate must be between 0 and 1: %v", c.Mirror.SampleRate)
+		}
+	}
+
+	// Validate response limits
+	if c.ResponseLimits.Enabled {
+		rules := map[string]ResponseLimitRule{"default": c.ResponseLimits.Default}
+		for route, rule := range c.ResponseLimits.Routes {
+			rules["routes."+route] = rule
+		}
+		for name, rule := range rules {
+			switch rule.Policy {
+			case "", "reject", "truncate", "terminate":
+			default:
+				return fmt.Errorf("invalid response_limits.%s.policy: %s", name, rule.Policy)
+			}
+			if rule.MaxBytes < 0 || rule.MaxTokens < 0 {
+				return fmt.Errorf("invalid response_limits.%s: limits must not be negative", name)
+			}
+		}
+	}
+
+	// Validate tool call assembly
+	switch c.ToolCallAssembly.Mode {
+	case "", "off", "complete", "final":
+	default:
+		return fmt.Errorf("invalid tool_call_assembly.mode: %s (must be off, complete or final)", c.ToolCallAssembly.Mode)
+	}
+
+	// Validate stream transcripts
+	if t := c.Transcripts; t.Enabled {
+		if t.TTL <= 0 || t.MaxDuration <= 0 {
+			return fmt.Errorf("invalid transcripts: ttl and max_duration must be positive")
+		}
+		if t.MaxEntries < 1 {
+			return fmt.Errorf("invalid transcripts.max_entries: %d (must be at least 1)", t.MaxEntries)
+		}
+	}
+
+	// Validate loop detection
+	if c.LoopDetection.Enabled {
+		ld := c.LoopDetection
+		if ld.NgramSize < 1 || ld.MaxRepeats < 2 || ld.Window < ld.NgramSize*ld.MaxRepeats {
+			return fmt.Errorf("invalid loop_detection: need ngram_size >= 1, max_repeats >= 2 and window >= ngram_size * max_repeats")
+		}
+	}
+
+	// Validate language detection
+	if c.Language.Enabled {
+		if c.Language.MinChars < 1 {
+			return fmt.Errorf("invalid language.min_chars: %d", c.Language.MinChars)
+		}
+		for lang, model := range c.Language.Routes {
+			if model == "" {
+				return fmt.Errorf("invalid language.routes.%s: model must not be empty", lang)
+			}
+		}
+		if c.Language.Enforcement.Enabled {
+			if err := c.Language.Enforcement.Default.validate(); err != nil {
+				return fmt.Errorf("invalid language.enforcement.default.required: %w", err)
+			}
+			for route, policy := range c.Language.Enforcement.Routes {
+				if err := policy.validate(); err != nil {
+					return fmt.Errorf("invalid language.enforcement.routes.%s.required: %w", route, err)
+				}
+			}
+		}
+	}
+
+	// Validate provider regions
+	for name, regions := range map[string][]RegionConfig{"openai": c.Providers.OpenAI.Regions, "anthropic": c.Providers.Anthropic.Regions} {
+		seen := make(map[string]bool, len(regions))
+		for i, r := range regions {
+			if r.Name == "" || r.BaseURL == "" {
+				return fmt.Errorf("invalid providers.%s.regions[%d]: name and base_url are required", name, i)
+			}
+			if seen[r.Name] {
+				return fmt.Errorf("invalid providers.%s.regions: duplicate region %q", name, r.Name)
+			}
+			seen[r.Name] = true
+		}
+	}
+	if len(c.Providers.OpenAI.Regions) > 0 && c.Providers.OpenAI.Discovery.Type != "" {
+		return fmt.Errorf("invalid providers.openai: regions and discovery cannot both be set")
+	}
+
+	// Validate the trace exporter
+	if t := c.Observability.Tracing; t.Enabled && (t.ExporterType == "otlp" || t.ExporterType == "jaeger") && t.ExporterAddress == "" {
+		return fmt.Errorf("observability.tracing.exporter_address is required for the %s exporter", t.ExporterType)
+	}
+
+	if c.Providers.Ollama.ModelRefreshInterval < 0 {
+		return fmt.Errorf("invalid providers.ollama.model_refresh_interval: %s", c.Providers.Ollama.ModelRefreshInterval)
+	}
+
+	// Validate service discovery
+	for name, d := range map[string]DiscoveryConfig{"openai": c.Providers.OpenAI.Discovery, "ollama": c.Providers.Ollama.Discovery} {
+		if d.Type == "" {
+			continue
+		}
+		switch d.Type {
+		case "dns_srv", "consul", "kubernetes":
+		default:
+			return fmt.Errorf("invalid providers.%s.discovery.type: %s", name, d.Type)
+		}
+		if d.Service == "" {
+			return fmt.Errorf("providers.%s.discovery.service is required", name)
+		}
+		if d.RefreshInterval <= 0 {
+			return fmt.Errorf("invalid providers.%s.discovery.refresh_interval: %s", name, d.RefreshInterval)
+		}
+	}
+
+	// Validate leader election
+	if le := c.LeaderElection; le.Enabled {
+		if le.Backend != "redis" && le.Backend != "kubernetes" {
+			return fmt.Errorf("invalid leader_election.backend: %s (must be redis or kubernetes)", le.Backend)
+		}
+		if le.Name == "" {
+			return fmt.Errorf("leader_election.name is required")
+		}
+		if le.RenewInterval <= 0 || le.RenewInterval >= le.LeaseDuration {
+			return fmt.Errorf("leader_election.renew_interval must be positive and shorter than lease_duration")
+		}
+	}
+
+	// Validate retention
+	if rc := c.Retention; rc.Enabled {
+		if rc.PurgeInterval <= 0 {
+			return fmt.Errorf("invalid retention.purge_interval: %s", rc.PurgeInterval)
+		}
+		if rc.DeleteGrace < 0 {
+			return fmt.Errorf("invalid retention.delete_grace: %s", rc.DeleteGrace)
+		}
+	}
+
+	// Validate usage export
+	if ue := c.UsageExport; ue.Enabled {
+		if ue.MinUsers < 1 {
+			return fmt.Errorf("invalid usage_export.min_users: %d (must be at least 1)", ue.MinUsers)
+		}
+		if ue.Epsilon < 0 {
+			return fmt.Errorf("invalid usage_export.epsilon: %g", ue.Epsilon)
+		}
+		if ue.Epsilon > 0 && ue.TokenSensitivity <= 0 {
+			return fmt.Errorf("invalid usage_export.token_sensitivity: %d", ue.TokenSensitivity)
+		}
+		if ue.Epsilon > 0 && ue.NoiseKey == "" {
+			return fmt.Errorf("usage_export.noise_key is required with usage_export.epsilon")
+		}
+	}
+
+	// Validate prompt statistics
+	if ps := c.PromptStats; ps.Enabled {
+		if ps.Window <= 0 {
+			return fmt.Errorf("invalid prompt_stats.window: %s", ps.Window)
+		}
+		if ps.MaxPrompts < 1 {
+			return fmt.Errorf("invalid prompt_stats.max_prompts: %d (must be at least 1)", ps.MaxPrompts)
+		}
+		if ps.Distance < 0 || ps.Distance > 32 {
+			return fmt.Errorf("invalid prompt_stats.distance: %d (must be between 0 and 32)", ps.Distance)
+		}
+	}
+
+	// Validate SLA reports
+	if sr := c.SLAReports; sr.Enabled {
+		for _, period := range sr.Periods {
+			if period != "daily" && period != "weekly" {
+				return fmt.Errorf("invalid sla_reports.periods: %q (must be daily or weekly)", period)
+			}
+		}
+		if sr.MaxReports < 1 {
+			return fmt.Errorf("invalid sla_reports.max_reports: %d (must be at least 1)", sr.MaxReports)
+		}
+		if sr.Email.SMTPAddr != "" && (sr.Email.From == "" || len(sr.Email.To) == 0) {
+			return fmt.Errorf("sla_reports.email.from and sla_reports.email.to are required when smtp_addr is set")
+		}
+		switch sr.Store {
+		case "", "memory":
+		case "redis":
+			if sr.Redis.Address == "" {
+				return fmt.Errorf("sla_reports.redis.address is required for the redis store")
+			}
+		default:
+			return fmt.Errorf("invalid sla_reports.store: %s (must be memory or redis)", sr.Store)
+		}
+	}
+
+	// Validate model metadata
+	for model, meta := range c.ModelCatalog {
+		if meta.ContextWindow < 0 || meta.MaxOutputTokens < 0 {
+			return fmt.Errorf("invalid model_catalog.%s: token limits must not be negative", model)
+		}
+		if meta.ContextWindow > 0 && meta.MaxOutputTokens > meta.ContextWindow {
+			return fmt.Errorf("invalid model_catalog.%s.max_output_tokens: %d (exceeds context_window %d)", model, meta.MaxOutputTokens, meta.ContextWindow)
+		}
+	}
+
+	// Validate fallback chains
+	if c.Fallbacks.Enabled {
+		for model, chain := range c.Fallbacks.Chains {
+			for i, target := range chain {
+				if strings.TrimSpace(target) == "" || strings.HasSuffix(target, "/") {
+					return fmt.Errorf("invalid fallbacks.chains.%s[%d]: %q", model, i, target)
+				}
+			}
+		}
+	}
+
+	// Validate overflow routes
+	if c.Overflow.Enabled {
+		if err := validateOverflowRoutes("overflow.routes", c.Overflow.Routes); err != nil {
+			return err
+		}
+		for tier, tierCfg := range c.Overflow.Tiers {
+			if _, ok := c.RateLimit.Tiers[tier]; !ok {
+				return fmt.Errorf("invalid overflow.tiers.%s: not in rate_limit.tiers", tier)
+			}
+			if err := validateOverflowRoutes("overflow.tiers."+tier+".routes", tierCfg.Routes); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate output filters
+	if of := c.OutputFilters; of.Enabled {
+		if of.Window < 1 {
+			return fmt.Errorf("invalid output_filters.window: %d (must be at least 1)", of.Window)
+		}
+		names := make(map[string]bool)
+		for i, rule := range of.Rules {
+			if rule.Name == "" || names[rule.Name] {
+				return fmt.Errorf("invalid output_filters.rules[%d]: name must be set and unique", i)
+			}
+			names[rule.Name] = true
+			if rule.Pattern == "" && len(rule.Keywords) == 0 {
+				return fmt.Errorf("invalid output_filters.rules[%d]: pattern or keywords required", i)
+			}
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid output_filters.rules[%d].pattern: %w", i, err)
+			}
+			switch rule.Action {
+			case "", "mask", "abort":
+			default:
+				return fmt.Errorf("invalid output_filters.rules[%d].action: %s", i, rule.Action)
+			}
+		}
+	}
+
+	// Validate cache similarity matching
+	if sim := c.Cache.Similarity; sim.Enabled {
+		if sim.Model == "" {
+			return fmt.Errorf("cache.similarity.model is required")
+		}
+		if sim.Threshold <= 0 || sim.Threshold > 1 {
+			return fmt.Errorf("invalid cache.similarity.threshold: %v (must be above 0 and at most 1)", sim.Threshold)
+		}
+	}
+
+	// Validate prompt secret detection
+	if ps := c.PromptSecrets; ps.Enabled {
+		switch ps.Action {
+		case "block", "redact":
+		default:
+			return fmt.Errorf("invalid prompt_secrets.action: %s (must be block or redact)", ps.Action)
+		}
+		for name, pattern := range ps.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid prompt_secrets.patterns.%s: %w", name, err)
+			}
+		}
+	}
+
+	// Validate guardrails
+	if g := c.Guardrails; g.Enabled {
+		if _, ok := g.Policies[g.DefaultPolicy]; g.DefaultPolicy != "" && !ok {
+			return fmt.Errorf("invalid guardrails.default_policy: %s (not in guardrails.policies)", g.DefaultPolicy)
+		}
+		moderated := false
+		for name, policy := range g.Policies {
+			if policy.MaxPromptChars < 0 {
+				return fmt.Errorf("invalid guardrails.policies.%s.max_prompt_chars: %d (must not be negative)", name, policy.MaxPromptChars)
+			}
+			for i, rule := range policy.Denylist {
+				if rule.Pattern == "" && len(rule.Keywords) == 0 {
+					return fmt.Errorf("invalid guardrails.policies.%s.denylist[%d]: pattern or keywords required", name, i)
+				}
+				if _, err := regexp.Compile(rule.Pattern); err != nil {
+					return fmt.Errorf("invalid guardrails.policies.%s.denylist[%d].pattern: %w", name, i, err)
+				}
+				switch rule.Action {
+				case "block", "redact":
+				default:
+					return fmt.Errorf("invalid guardrails.policies.%s.denylist[%d].action: %s (must be block or redact)", name, i, rule.Action)
+				}
+				switch rule.Apply {
+				case "", "input", "output", "both":
+				default:
+					return fmt.Errorf("invalid guardrails.policies.%s.denylist[%d].apply: %s (must be input, output or both)", name, i, rule.Apply)
+				}
+			}
+			moderated = moderated || policy.ModerateInput || policy.ModerateOutput
+		}
+		if moderated {
+			if g.Moderation.URL == "" {
+				return fmt.Errorf("guardrails.moderation.url is required when a policy moderates")
+			}
+			if g.Moderation.Timeout <= 0 {
+				return fmt.Errorf("invalid guardrails.moderation.timeout: %s", g.Moderation.Timeout)
+			}
+		}
+	}
+
+	// Validate PII masking
+	if pii := c.PII; pii.Enabled {
+		for _, name := range pii.Detectors {
+			switch name {
+			case "email", "phone", "credit_card", "national_id":
+			default:
+				return fmt.Errorf("invalid pii.detectors: %s (must be email, phone, credit_card or national_id)", name)
+			}
+		}
+	}
+
+	// Validate request limits
+	if rl := c.RequestLimits; rl.Enabled {
+		if rl.MaxRequestBytes < 0 || rl.MaxMessages < 0 || rl.MaxContentChars < 0 {
+			return fmt.Errorf("invalid request_limits: max_request_bytes, max_messages and max_content_chars must not be negative")
+		}
+		if c.Blobs.Enabled && rl.MaxRequestBytes > 0 && rl.MaxRequestBytes < c.Blobs.MaxBlobBytes {
+			return fmt.Errorf("invalid request_limits.max_request_bytes: %d is below blobs.max_blob_bytes, so uploads would be refused", rl.MaxRequestBytes)
+		}
+	}
+
+	// Validate error message localization
+	if em := c.ErrorMessages; em.Enabled {
+		for lang, byCode := range em.Messages {
+			for code, message := range byCode {
+				if strings.TrimSpace(message) == "" {
+					return fmt.Errorf("invalid error_messages.messages.%s.%s: message must not be empty", lang, code)
+				}
+			}
+		}
+	}
+
+	// Validate abuse detection
+	if ad := c.AbuseDetection; ad.Enabled {
+		if ad.Window <= 0 {
+			return fmt.Errorf("invalid abuse_detection.window: %s", ad.Window)
+		}
+		if ad.SpikeFactor < 0 || ad.SpikeMinRequests < 0 || ad.RefusalMinRequests < 0 || ad.MaxIPs < 0 || ad.MaxModels < 0 {
+			return fmt.Errorf("invalid abuse_detection: thresholds must not be negative")
+		}
+		if ad.RefusalRate < 0 || ad.RefusalRate > 1 {
+			return fmt.Errorf("invalid abuse_detection.refusal_rate: %v (must be between 0 and 1)", ad.RefusalRate)
+		}
+		if ad.RestrictedTier != "" {
+			if _, ok := c.RateLimit.Tiers[ad.RestrictedTier]; !ok {
+				return fmt.Errorf("invalid abuse_detection.restricted_tier: %s (not in rate_limit.tiers)", ad.RestrictedTier)
+			}
+			if ad.RestrictAfter < 1 {
+				return fmt.Errorf("invalid abuse_detection.restrict_after: %d (must be at least 1)", ad.RestrictAfter)
+			}
+			if ad.RestrictFor <= 0 {
+				return fmt.Errorf("invalid abuse_detection.restrict_for: %s", ad.RestrictFor)
+			}
+		}
+	}
+
+	// Validate unknown field tracking
+	if uf := c.UnknownFields; uf.Enabled && uf.MaxEntries < 1 {
+		return fmt.Errorf("invalid unknown_fields.max_entries: %d (must be at least 1)", uf.MaxEntries)
+	}
+
+	// Validate managed API keys
+	if ak := c.APIKeys; ak.Enabled {
+		switch ak.Store {
+		case "file", "sqlite":
+			if ak.Path == "" {
+				return fmt.Errorf("api_keys.path is required for the %s store", ak.Store)
+			}
+		case "redis":
+			if ak.Redis.Address == "" || ak.RedisKey == "" {
+				return fmt.Errorf("api_keys.redis.address and api_keys.redis_key are required for the redis store")
+			}
+		default:
+			return fmt.Errorf("invalid api_keys.store: %s (must be file, sqlite or redis)", ak.Store)
+		}
+		if ak.RefreshInterval <= 0 {
+			return fmt.Errorf("invalid api_keys.refresh_interval: %s (must be positive)", ak.RefreshInterval)
+		}
+		if sr := ak.StreamReservation; sr.Enabled {
+			switch {
+			case sr.DefaultCompletionTokens <= 0:
+				return fmt.Errorf("invalid api_keys.stream_reservation.default_completion_tokens: %d (must be positive)", sr.DefaultCompletionTokens)
+			case sr.Margin < 0:
+				return fmt.Errorf("invalid api_keys.stream_reservation.margin: %v (must not be negative)", sr.Margin)
+			case sr.NearLimit < 0 || sr.NearLimit > 1:
+				return fmt.Errorf("invalid api_keys.stream_reservation.near_limit: %v (must be between 0 and 1)", sr.NearLimit)
+			}
+		}
+	}
+
+	// Validate spend budgets
+	if c.Cost.Enabled {
+		for i, b := range c.Cost.Budgets {
+			switch {
+			case b.Scope != "key" && b.Scope != "provider" && b.Scope != "model":
+				return fmt.Errorf("invalid cost.budgets[%d].scope: %s (must be key, provider or model)", i, b.Scope)
+			case b.ID == "":
+				return fmt.Errorf("cost.budgets[%d].id is required", i)
+			case b.Limit <= 0:
+				return fmt.Errorf("invalid cost.budgets[%d].limit: %v (must be positive)", i, b.Limit)
+			case b.Period != "day" && b.Period != "month":
+				return fmt.Errorf("invalid cost.budgets[%d].period: %s (must be day or month)", i, b.Period)
+			case b.Action != "reject" && b.Action != "warn":
+				return fmt.Errorf("invalid cost.budgets[%d].action: %s (must be reject or warn)", i, b.Action)
+			}
+		}
+		if c.Cost.History != 0 && c.Cost.History < time.Hour {
+			return fmt.Errorf("invalid cost.history: %s (must be at least 1h)", c.Cost.History)
+		}
+		if a := c.Cost.Anomaly; a.Enabled {
+			switch {
+			case a.Factor <= 1:
+				return fmt.Errorf("invalid cost.anomaly.factor: %v (must be greater than 1)", a.Factor)
+			case a.MinSpend < 0:
+				return fmt.Errorf("invalid cost.anomaly.min_spend: %v (must not be negative)", a.MinSpend)
+			case a.MinHistory < 0 || (c.Cost.History != 0 && a.MinHistory > c.Cost.History):
+				return fmt.Errorf("invalid cost.anomaly.min_history: %s (must be between 0 and cost.history)", a.MinHistory)
+			}
+		}
+	}
+
+	// Validate response translation
+	if tr := c.Translation; tr.Enabled && tr.Model == "" {
+		return fmt.Errorf("translation.model is required")
+	}
+
+	// Validate the prompt linter
+	if pl := c.PromptLint; pl.Enabled {
+		if pl.MaxContextChars <= 0 {
+			return fmt.Errorf("invalid prompt_lint.max_context_chars: %d", pl.MaxContextChars)
+		}
+		if pl.RepeatedMinChars <= 0 {
+			return fmt.Errorf("invalid prompt_lint.repeated_min_chars: %d", pl.RepeatedMinChars)
+		}
+	}
+
+	// Validate conversation compression
+	if cc := c.ConversationCompression; cc.Enabled {
+		if cc.Model == "" {
+			return fmt.Errorf("conversation_compression.model is required")
+		}
+		if cc.Threshold < 1 {
+			return fmt.Errorf("invalid conversation_compression.threshold: %d (must be at least 1)", cc.Threshold)
+		}
+		if cc.KeepRecent < 1 {
+			return fmt.Errorf("invalid conversation_compression.keep_recent: %d (must be at least 1)", cc.KeepRecent)
+		}
+	}
+
+	// Validate parameter presets
+	if pp := c.ParameterPresets; pp.Enabled {
+		presets := make(map[string]ParameterPreset)
+		for name, preset := range pp.Presets {
+			presets["presets."+name] = preset
+		}
+		for route, routePresets := range pp.Routes {
+			for name, preset := range routePresets {
+				presets["routes."+route+"."+name] = preset
+			}
+		}
+		for name, preset := range presets {
+			if err := preset.validate(); err != nil {
+				return fmt.Errorf("invalid parameter_presets.%s: %w", name, err)
+			}
+		}
+	}
+
+	// Validate notifications
+	if n := c.Notifications; n.WebhookURL != "" && n.QueueSize < 1 {
+		return fmt.Errorf("invalid notifications.queue_size: %d (must be at least 1)", n.QueueSize)
+	}
+
+	// Validate quota warnings
+	if qw := c.QuotaWarnings; qw.Enabled {
+		if len(qw.Thresholds) == 0 {
+			return fmt.Errorf("quota_warnings.thresholds is required")
+		}
+		for _, threshold := range qw.Thresholds {
+			if threshold <= 0 || threshold >= 100 {
+				return fmt.Errorf("invalid quota_warnings.thresholds: %v (must be between 0 and 100)", threshold)
+			}
+		}
+	}
+
+	// Validate control plane
+	if cp := c.ControlPlane; cp.Enabled {
+		if cp.URL == "" {
+			return fmt.Errorf("control_plane.url is required")
+		}
+		if cp.SigningKey == "" {
+			return fmt.Errorf("control_plane.signing_key is required, payloads must be signed")
+		}
+		if cp.PollInterval <= 0 {
+			return fmt.Errorf("invalid control_plane.poll_interval: %s", cp.PollInterval)
+		}
+		if cp.MaxPayloadAge < 0 {
+			return fmt.Errorf("invalid control_plane.max_payload_age: %s", cp.MaxPayloadAge)
+		}
+		if cc := cp.Canary; cc.Enabled {
+			if cc.Percent <= 0 || cc.Percent > 100 {
+				return fmt.Errorf("invalid control_plane.canary.percent: %g (must be in (0, 100])", cc.Percent)
+			}
+			if cc.Window <= 0 {
+				return fmt.Errorf("invalid control_plane.canary.window: %s", cc.Window)
+			}
+			if cc.MaxWindow < cc.Window {
+				return fmt.Errorf("invalid control_plane.canary.max_window: %s (must be at least window)", cc.MaxWindow)
+			}
+			if cc.MaxLatencyRatio < 1 {
+				return fmt.Errorf("invalid control_plane.canary.max_latency_ratio: %g (must be at least 1)", cc.MaxLatencyRatio)
+			}
+		}
+	}
+
+	// Validate use case keywords
+	for useCase := range c.UseCase.Keywords {
+		if useCase != "code" && useCase != "extraction" {
+			return fmt.Errorf("invalid use_case.keywords: unknown use case %q (must be code or extraction)", useCase)
+		}
+	}
+
+	// Validate provider overrides
+	if c.ProviderOverride.Enabled && len(c.ProviderOverride.DebugKeys) == 0 {
+		return fmt.Errorf("provider_override.debug_keys must not be empty when provider overrides are enabled")
+	}
+
+	// Validate blob references
+	if c.Blobs.Enabled {
+		if c.Blobs.MaxBlobBytes <= 0 || c.Blobs.MaxRequestBytes <= 0 {
+			return fmt.Errorf("blobs.max_blob_bytes and blobs.max_request_bytes must be positive")
+		}
+		if c.Blobs.FileTTL <= 0 {
+			return fmt.Errorf("blobs.file_ttl must be positive")
+		}
+		if c.Blobs.S3Endpoint != "" && len(c.Blobs.S3AllowedPrefixes) == 0 {
+			return fmt.Errorf("blobs.s3_allowed_prefixes must list the buckets or prefixes s3:// references may read")
+		}
+	}
 
 	return nil
 }
@@ -318,6 +2725,148 @@ func (c *Config) GetProviderConfig(name string) interface{} {
 	case "ollama":
 		return c.Providers.Ollama
 	default:
+		if remote, ok := c.Providers.Remote[name]; ok {
+			return remote
+		}
+		return nil
+	}
+}
+
+// Cost returns the price of tokens of model, using the "*" entry for models
+// without their own (0 if there is none)
+func (p PricingConfig) Cost(model string, promptTokens, completionTokens int64) float64 {
+	price, ok := p[model]
+	if !ok {
+		price = p["*"]
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6
+}
+
+// FileMode parses Mode as an octal permission, defaulting to 0660
+func (u UnixSocketConfig) FileMode() (os.FileMode, error) {
+	if u.Mode == "" {
+		return 0660, nil
+	}
+	mode, err := strconv.ParseUint(u.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode: %q", u.Mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Options returns client options that dial a connection per call, for
+// infrequent callers such as the key store and the leader lock
+func (r RedisConfig) Options() redisconn.Options {
+	return redisconn.Options{Address: r.Address, Password: r.Password, DB: r.DB}
+}
+
+// validate checks the preset's parameters are within the ranges providers accept
+func (p ParameterPreset) validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	for _, penalty := range []*float64{p.PresencePenalty, p.FrequencyPenalty} {
+		if penalty != nil && (*penalty < -2 || *penalty > 2) {
+			return fmt.Errorf("penalties must be between -2 and 2")
+		}
+	}
+	return nil
+}
+
+// validate checks the required language is a two-letter code, "prompt" or empty
+func (p LanguagePolicy) validate() error {
+	if p.Required == "" || p.Required == "prompt" {
 		return nil
 	}
+	if len(p.Required) != 2 || p.Required[0] < 'a' || p.Required[0] > 'z' || p.Required[1] < 'a' || p.Required[1] > 'z' {
+		return fmt.Errorf("%q must be a lowercase ISO 639-1 code or \"prompt\"", p.Required)
+	}
+	return nil
+}
+
+// validateOverflowRoutes checks each overflow route names a model other
+// than the one it takes the overflow of
+func validateOverflowRoutes(key string, routes map[string]string) error {
+	for model, target := range routes {
+		target = strings.TrimSpace(target)
+		if target == "" || strings.HasSuffix(target, "/") || strings.EqualFold(target, model) {
+			return fmt.Errorf("invalid %s.%s: %q", key, model, target)
+		}
+	}
+	return nil
+}
+
+// For returns the circuit breaker settings of a provider: these settings,
+// with the provider's override applied
+func (c CircuitBreakerConfig) For(provider string) CircuitBreakerConfig {
+	merged := c
+	merged.Providers = nil
+	o, ok := c.Providers[strings.ToLower(provider)]
+	if !ok {
+		return merged
+	}
+	if o.FailureThreshold != 0 {
+		merged.FailureThreshold = o.FailureThreshold
+	}
+	if o.SuccessThreshold != 0 {
+		merged.SuccessThreshold = o.SuccessThreshold
+	}
+	if o.Timeout != 0 {
+		merged.Timeout = o.Timeout
+	}
+	if o.MaxHalfOpenRequests != 0 {
+		merged.MaxHalfOpenRequests = o.MaxHalfOpenRequests
+	}
+	if o.Mode != "" {
+		merged.Mode = o.Mode
+	}
+	if o.WindowType != "" {
+		merged.WindowType = o.WindowType
+	}
+	if o.WindowSize != 0 {
+		merged.WindowSize = o.WindowSize
+	}
+	if o.WindowDuration != 0 {
+		merged.WindowDuration = o.WindowDuration
+	}
+	if o.FailureRateThreshold != 0 {
+		merged.FailureRateThreshold = o.FailureRateThreshold
+	}
+	if o.MinimumRequests != 0 {
+		merged.MinimumRequests = o.MinimumRequests
+	}
+	return merged
+}
+
+// validate checks the mode and, for a sliding window, its settings
+func (c CircuitBreakerConfig) validate() error {
+	switch c.Mode {
+	case "", "consecutive":
+		return nil
+	case "sliding_window":
+	default:
+		return fmt.Errorf("mode must be consecutive or sliding_window, got %q", c.Mode)
+	}
+	switch c.WindowType {
+	case "", "count":
+		if c.WindowSize < 1 {
+			return fmt.Errorf("window_size must be at least 1")
+		}
+	case "time":
+		if c.WindowDuration < time.Second {
+			return fmt.Errorf("window_duration must be at least 1s")
+		}
+	default:
+		return fmt.Errorf("window_type must be count or time, got %q", c.WindowType)
+	}
+	if c.FailureRateThreshold <= 0 || c.FailureRateThreshold > 100 {
+		return fmt.Errorf("failure_rate_threshold must be above 0 and at most 100")
+	}
+	if c.MinimumRequests < 1 {
+		return fmt.Errorf("minimum_requests must be at least 1")
+	}
+	return nil
 }
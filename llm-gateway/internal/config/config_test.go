@@ -42,6 +42,384 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid config with a remote provider",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					Remote: map[string]RemoteProviderConfig{"bespoke": {Address: "localhost:50051"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "remote provider without address",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					Remote: map[string]RemoteProviderConfig{"bespoke": {Command: []string{"python3", "shim.py"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote provider named like a built-in one",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					Remote: map[string]RemoteProviderConfig{"openai": {Address: "localhost:50051"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid spend budget",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Cost: CostConfig{Enabled: true, Budgets: []SpendBudgetConfig{
+					{Scope: "key", ID: "*", Limit: 10, Period: "day", Action: "reject"},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "spend budget with unknown period",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Cost: CostConfig{Enabled: true, Budgets: []SpendBudgetConfig{
+					{Scope: "provider", ID: "openai", Limit: 10, Period: "week", Action: "warn"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "spend anomaly factor not above one",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Cost: CostConfig{Enabled: true, History: 168 * time.Hour, Anomaly: SpendAnomalyConfig{
+					Enabled: true, Factor: 1, MinSpend: 1, MinHistory: 24 * time.Hour,
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ttft slo without fallback",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					OpenAI:   OpenAIConfig{APIKey: "sk-test"},
+					TTFTSLOs: []TTFTSLORule{{Models: "gpt-4o*", Target: 2 * time.Second}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "guardrails default policy not defined",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Guardrails: GuardrailsConfig{Enabled: true, DefaultPolicy: "strict", Policies: map[string]GuardrailPolicy{
+					"standard": {MaxPromptChars: 10000},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "guardrails denylist action invalid",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Guardrails: GuardrailsConfig{Enabled: true, Policies: map[string]GuardrailPolicy{
+					"standard": {Denylist: []GuardrailRule{{Name: "codenames", Keywords: []string{"bluebird"}, Action: "mask"}}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pii detector unknown",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				PII:       PIIConfig{Enabled: true, Detectors: []string{"email", "passport"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "trusted proxy invalid",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, TrustedProxies: []string{"10.0.0.0/8", "proxy.internal"}},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "middleware unknown",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, Middleware: []string{"request_id", "gzip"}},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "middleware without enabled jwt auth",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, Middleware: []string{"request_id", "rate_limit"}},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				JWTAuth:   JWTAuthConfig{Enabled: true, JWKSURL: "https://idp.example.com/jwks", UserClaim: "sub", JWKSRefresh: time.Minute},
+			},
+			wantErr: true,
+		},
+		{
+			name: "middleware without enabled integrity",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, Middleware: []string{"request_id", "compression"}},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Integrity: IntegrityConfig{Enabled: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "request limits below blob size",
+			config: Config{
+				Server:        ServerConfig{Port: 8080},
+				Providers:     ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				RequestLimits: RequestLimitsConfig{Enabled: true, MaxRequestBytes: 1 << 20},
+				Blobs:         BlobConfig{Enabled: true, MaxBlobBytes: 8 << 20, MaxRequestBytes: 32 << 20, FileTTL: time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3 blobs without allowed prefixes",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Blobs:     BlobConfig{Enabled: true, S3Endpoint: "http://minio:9000", MaxBlobBytes: 8 << 20, MaxRequestBytes: 32 << 20, FileTTL: time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error message empty",
+			config: Config{
+				Server:        ServerConfig{Port: 8080},
+				Providers:     ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				ErrorMessages: ErrorMessagesConfig{Enabled: true, Messages: map[string]map[string]string{"es": {"maintenance": " "}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "translation without model",
+			config: Config{
+				Server:      ServerConfig{Port: 8080},
+				Providers:   ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Translation: TranslationConfig{Enabled: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative ollama model refresh interval",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{Ollama: OllamaConfig{BaseURL: "http://localhost:11434", ModelRefreshInterval: -time.Second}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "chained routing alias",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Routing:   RoutingConfig{Aliases: map[string]string{"default-chat": "fast-chat", "fast-chat": "gpt-4o-mini"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "routing rule with bad pattern",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Routing:   RoutingConfig{Rules: []RoutingRule{{Models: "gpt-[", Provider: "openai"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "prompt lint without context limit",
+			config: Config{
+				Server:     ServerConfig{Port: 8080},
+				Providers:  ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				PromptLint: PromptLintConfig{Enabled: true, RepeatedMinChars: 200},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative tier queue weight",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				RateLimit: RateLimitConfig{Tiers: map[string]RateLimitTier{"batch": {RequestsPerMin: 10, BurstSize: 1, QueueWeight: -1}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sliding window circuit breaker without failure rate threshold",
+			config: Config{
+				Server:      ServerConfig{Port: 8080},
+				Providers:   ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Reliability: ReliabilityConfig{CircuitBreaker: CircuitBreakerConfig{Enabled: true, Mode: "sliding_window", WindowSize: 100, MinimumRequests: 10}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown circuit breaker mode for a provider",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Reliability: ReliabilityConfig{CircuitBreaker: CircuitBreakerConfig{
+					Enabled:   true,
+					Mode:      "consecutive",
+					Providers: map[string]CircuitBreakerConfig{"ollama": {Mode: "rate"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown request ID format",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, RequestIDFormat: "snowflake"},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown response ID format",
+			config: Config{
+				Server:    ServerConfig{Port: 8080, ResponseIDFormat: "uuidv7"},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative degraded max stale",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Degraded:  DegradedConfig{Auto: true, MaxStale: -time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry budget above 100 percent",
+			config: Config{
+				Server:      ServerConfig{Port: 8080},
+				Providers:   ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Reliability: ReliabilityConfig{Retry: RetryConfig{Budget: RetryBudgetConfig{Enabled: true, Percent: 150, Window: 10 * time.Second}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overflow route to the same model",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Overflow:  OverflowConfig{Enabled: true, Routes: map[string]string{"gpt-4o": "GPT-4o"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overflow tier not in rate limit tiers",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Overflow:  OverflowConfig{Enabled: true, Tiers: map[string]OverflowTierConfig{"premium": {Disabled: true}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "time series retention under a minute",
+			config: Config{
+				Server:        ServerConfig{Port: 8080},
+				Providers:     ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Observability: ObservabilityConfig{TimeSeries: TimeSeriesConfig{Enabled: true, Retention: 10 * time.Second}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "model catalog max output above context window",
+			config: Config{
+				Server:       ServerConfig{Port: 8080},
+				Providers:    ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				ModelCatalog: ModelCatalogConfig{"my-model": {ContextWindow: 4096, MaxOutputTokens: 8192}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stream reservation near limit above one",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				APIKeys: APIKeysConfig{
+					Enabled: true, Store: "file", Path: "keys.json", RefreshInterval: time.Minute,
+					StreamReservation: StreamReservationConfig{Enabled: true, DefaultCompletionTokens: 1024, Margin: 0.2, NearLimit: 1.5},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "default provider on standby",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{Default: "openai", OpenAI: OpenAIConfig{APIKey: "sk-test"}, Standby: []string{"openai"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid required response language",
+			config: Config{
+				Server:    ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Language: LanguageConfig{Enabled: true, MinChars: 20, Enforcement: LanguageEnforcementConfig{
+					Enabled: true,
+					Default: LanguagePolicy{Required: "prompt"},
+					Routes:  map[string]LanguagePolicy{"/v1/chat/completions": {Required: "German"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid balancing strategy",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					OpenAI:    OpenAIConfig{APIKey: "sk-test"},
+					Balancing: []BalancingRule{{Models: "gpt-*", Strategy: "random"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate provider region",
+			config: Config{
+				Server: ServerConfig{Port: 8080},
+				Providers: ProvidersConfig{
+					OpenAI: OpenAIConfig{APIKey: "sk-test", Regions: []RegionConfig{
+						{Name: "us", BaseURL: "https://us.example.com/v1"},
+						{Name: "us", BaseURL: "https://eu.example.com/v1"},
+					}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "otlp exporter without address",
+			config: Config{
+				Server:        ServerConfig{Port: 8080},
+				Providers:     ProvidersConfig{OpenAI: OpenAIConfig{APIKey: "sk-test"}},
+				Observability: ObservabilityConfig{Tracing: TracingConfig{Enabled: true, ExporterType: "otlp"}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid port - zero",
 			config: Config{
@@ -70,6 +448,20 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "invalid unix socket mode",
+			config: Config{
+				Server: ServerConfig{Port: 8080, UnixSocket: UnixSocketConfig{Path: "/tmp/gw.sock", Mode: "rw"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tls cert without key",
+			config: Config{
+				Server: ServerConfig{Port: 8443, TLSCertFile: "cert.pem"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -243,3 +635,52 @@ func TestRetryConfig_Settings(t *testing.T) {
 		t.Errorf("expected backoff multiplier 2.0, got %f", cfg.BackoffMultiplier)
 	}
 }
+
+func TestDiff(t *testing.T) {
+	a := &Config{Log: LogConfig{Level: "info", Modules: map[string]string{"proxy": "debug"}}}
+	a.RateLimit.BurstSize = 10
+	b := *a
+	b.Log.Modules = map[string]string{"proxy": "debug"}
+
+	if changed := Diff(a, &b); len(changed) != 0 {
+		t.Errorf("equal configs: %v", changed)
+	}
+
+	b.RateLimit.BurstSize = 20
+	b.Log.Modules = map[string]string{"proxy": "warn"}
+	b.Server.Port = 9090
+	changed := Diff(a, &b)
+	want := []string{"log.modules", "rate_limit.burst_size", "server.port"}
+	if len(changed) != len(want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	for i := range want {
+		if changed[i] != want[i] {
+			t.Errorf("changed = %v, want %v", changed, want)
+		}
+	}
+}
+
+func TestCircuitBreakerConfigFor(t *testing.T) {
+	cfg := CircuitBreakerConfig{
+		Enabled:              true,
+		FailureThreshold:     5,
+		Mode:                 "consecutive",
+		WindowSize:           100,
+		FailureRateThreshold: 50,
+		Providers: map[string]CircuitBreakerConfig{
+			"ollama": {Mode: "sliding_window", FailureRateThreshold: 25},
+		},
+	}
+
+	got := cfg.For("Ollama")
+	if got.Mode != "sliding_window" || got.FailureRateThreshold != 25 {
+		t.Errorf("For(Ollama) = %+v, want the override applied", got)
+	}
+	if got.FailureThreshold != 5 || got.WindowSize != 100 || !got.Enabled || got.Providers != nil {
+		t.Errorf("For(Ollama) = %+v, want unset fields inherited", got)
+	}
+	if got := cfg.For("openai"); got.Mode != "consecutive" {
+		t.Errorf("For(openai).Mode = %q, want consecutive", got.Mode)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix is prepended to every environment variable override
+const envPrefix = "LLM_GATEWAY"
+
+// Sources of a configuration value
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceUnset   = "unset"
+)
+
+// KeyInfo describes one configuration setting
+type KeyInfo struct {
+	Path    string      `json:"path"`
+	Type    string      `json:"type"`
+	Default interface{} `json:"default"`
+	Source  string      `json:"source"`
+	// EnvVar is empty for maps, which can only be set in the config file
+	EnvVar string `json:"env_var,omitempty"`
+}
+
+// EnvVar returns the environment variable that overrides a config path
+func EnvVar(path string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// Keys lists every configuration setting with its type, default, the source
+// of its current value and its environment variable
+func Keys() ([]KeyInfo, error) {
+	v, err := newViper()
+	if err != nil {
+		return nil, err
+	}
+	defaults := viper.New()
+	setDefaults(defaults)
+
+	var keys []KeyInfo
+	for _, leaf := range configLeaves() {
+		info := KeyInfo{
+			Path:    leaf.path,
+			Type:    leaf.typeName(),
+			Default: defaults.Get(leaf.path),
+			Source:  SourceUnset,
+		}
+		if leaf.envSettable() {
+			info.EnvVar = EnvVar(leaf.path)
+		}
+
+		switch {
+		case info.EnvVar != "" && envSet(info.EnvVar):
+			info.Source = SourceEnv
+		case v.InConfig(leaf.path):
+			info.Source = SourceFile
+		case defaults.IsSet(leaf.path):
+			info.Source = SourceDefault
+		}
+		keys = append(keys, info)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Path < keys[j].Path })
+	return keys, nil
+}
+
+func envSet(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+// configLeaf is a setting of the Config struct: a scalar, list or map
+type configLeaf struct {
+	path string
+	typ  reflect.Type
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (l configLeaf) typeName() string {
+	switch {
+	case l.typ == durationType:
+		return "duration"
+	case l.typ.Kind() == reflect.Map:
+		return "map"
+	default:
+		return l.typ.String()
+	}
+}
+
+// envSettable reports whether the setting can be given as one environment
+// variable; lists are comma-separated, maps are not supported
+func (l configLeaf) envSettable() bool {
+	return l.typ.Kind() != reflect.Map
+}
+
+// configLeaves walks the Config struct by mapstructure tag and returns its settings
+func configLeaves() []configLeaf {
+	var leaves []configLeaf
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			path, ok := fieldPath(prefix, field)
+			if !ok {
+				continue
+			}
+
+			if field.Type.Kind() == reflect.Struct {
+				walk(path, field.Type)
+				continue
+			}
+			leaves = append(leaves, configLeaf{path: path, typ: field.Type})
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return leaves
+}
+
+// fieldPath returns the config path of a struct field under prefix, or false
+// for fields that are not settings
+func fieldPath(prefix string, field reflect.StructField) (string, bool) {
+	name := field.Tag.Get("mapstructure")
+	if name == "-" || !field.IsExported() {
+		return "", false
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	if prefix != "" {
+		return prefix + "." + name, true
+	}
+	return name, true
+}
+
+// bindEnvs binds the environment variable of every setting, so settings
+// without a default can also be set from the environment
+func bindEnvs(v *viper.Viper) {
+	for _, leaf := range configLeaves() {
+		if leaf.envSettable() {
+			v.BindEnv(leaf.path)
+		}
+	}
+}
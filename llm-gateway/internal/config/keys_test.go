@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("server.http2.max_concurrent_streams"); got != "LLM_GATEWAY_SERVER_HTTP2_MAX_CONCURRENT_STREAMS" {
+		t.Errorf("EnvVar = %q", got)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	t.Setenv("LLM_GATEWAY_SERVER_PORT", "9090")
+
+	keys, err := Keys()
+	if err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	byPath := make(map[string]KeyInfo, len(keys))
+	for _, key := range keys {
+		byPath[key.Path] = key
+	}
+
+	tests := []struct {
+		path   string
+		typ    string
+		source string
+		envVar string
+	}{
+		{"server.port", "int", SourceEnv, "LLM_GATEWAY_SERVER_PORT"},
+		{"server.read_timeout", "duration", SourceDefault, "LLM_GATEWAY_SERVER_READ_TIMEOUT"},
+		{"admin.api_keys", "[]string", SourceDefault, "LLM_GATEWAY_ADMIN_API_KEYS"},
+		{"server.tls_cert_file", "string", SourceUnset, "LLM_GATEWAY_SERVER_TLS_CERT_FILE"},
+		{"language.routes", "map", SourceUnset, ""},
+	}
+	for _, tt := range tests {
+		key, ok := byPath[tt.path]
+		if !ok {
+			t.Errorf("%s not listed", tt.path)
+			continue
+		}
+		if key.Type != tt.typ || key.Source != tt.source || key.EnvVar != tt.envVar {
+			t.Errorf("%s = %+v, want type %s, source %s, env %q", tt.path, key, tt.typ, tt.source, tt.envVar)
+		}
+	}
+
+	if def := byPath["server.port"].Default; def != 8080 {
+		t.Errorf("server.port default = %v, want 8080", def)
+	}
+}
+
+func TestLoad_EnvWithoutDefault(t *testing.T) {
+	// Settings without a default are still read from the environment
+	t.Setenv("LLM_GATEWAY_PROVIDER_OVERRIDE_DEBUG_KEYS", "key-a,key-b")
+
+	v, err := newViper()
+	if err != nil {
+		t.Fatalf("newViper: %v", err)
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := cfg.ProviderOverride.DebugKeys; len(got) != 2 || got[0] != "key-a" || got[1] != "key-b" {
+		t.Errorf("debug_keys = %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Diff returns the paths of the settings that differ between two
+// configurations, sorted, e.g. "rate_limit.burst_size". Lists and maps are
+// compared whole.
+func Diff(a, b *Config) []string {
+	var changed []string
+	var walk func(prefix string, a, b reflect.Value)
+	walk = func(prefix string, a, b reflect.Value) {
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			path, ok := fieldPath(prefix, field)
+			if !ok {
+				continue
+			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(path, a.Field(i), b.Field(i))
+				continue
+			}
+			if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+				changed = append(changed, path)
+			}
+		}
+	}
+	walk("", reflect.ValueOf(*a), reflect.ValueOf(*b))
+	sort.Strings(changed)
+	return changed
+}
+
+// WatchFile calls onChange whenever the config file in use is written. It
+// reports whether a config file is in use; without one there is nothing to
+// watch.
+func WatchFile(onChange func()) (bool, error) {
+	v, err := newViper()
+	if err != nil {
+		return false, err
+	}
+	if v.ConfigFileUsed() == "" {
+		return false, nil
+	}
+	v.OnConfigChange(func(fsnotify.Event) { onChange() })
+	v.WatchConfig()
+	return true, nil
+}
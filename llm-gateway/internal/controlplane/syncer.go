@@ -0,0 +1,430 @@
+// Package controlplane keeps a gateway in sync with a central control plane,
+// periodically pulling routing rules, provider keys and tenant budgets.
+package controlplane
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/canary"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the controlplane module logger; its level can be set via log.modules.controlplane
+var logger = observability.ModuleLogger("controlplane")
+
+// SignatureHeader carries the payload signature: "sha256=" + hex(HMAC-SHA256(signing_key, body))
+const SignatureHeader = "X-Signature"
+
+// maxPayloadBytes bounds the payload read from the control plane
+const maxPayloadBytes = 10 << 20
+
+var errBadSignature = errors.New("payload signature is missing or invalid")
+
+var errNoIssuedAt = errors.New("payload has no issued_at")
+
+// Payload is the state distributed by the control plane
+type Payload struct {
+	// Version identifies the payload in logs and admin output
+	Version string `json:"version"`
+	// IssuedAt is when the control plane signed the payload. A payload must be
+	// newer than the last one accepted, so an old payload cannot be replayed.
+	IssuedAt time.Time `json:"issued_at"`
+	// Routes maps a model to the provider serving it
+	Routes map[string]string `json:"routes,omitempty"`
+	// ProviderKeys maps a provider to the API key it should use
+	ProviderKeys map[string]string `json:"provider_keys,omitempty"`
+	// Budgets maps a tenant ("*" for any other tenant) to its daily token budget
+	Budgets map[string]int64 `json:"budgets,omitempty"`
+}
+
+// cachedPayload is the CacheFile format. The body is kept byte for byte (base64)
+// with its signature, so it is verified again when loaded.
+type cachedPayload struct {
+	ETag      string `json:"etag"`
+	Signature string `json:"signature"`
+	Body      []byte `json:"body"`
+}
+
+// Syncer polls the control plane and applies each new payload. Unchanged
+// payloads are skipped via ETag/If-None-Match; on errors the last applied
+// payload stays in effect. With canary rollouts enabled, a new payload is
+// first served to a share of traffic and only applied once promoted.
+type Syncer struct {
+	cfg     config.ControlPlaneConfig
+	client  *http.Client
+	rollout *canary.Rollout
+
+	mu         sync.Mutex
+	onUpdate   []func(*Payload)
+	onCanary   []func(*Payload)
+	current    *Payload
+	candidate  *Payload
+	issuedAt   time.Time
+	etag       string
+	source     string
+	lastSync   time.Time
+	lastChange time.Time
+	lastErr    string
+	updates    int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a syncer from configuration, or returns nil if the control plane is disabled
+func New(cfg config.ControlPlaneConfig) *Syncer {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Syncer{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		rollout: canary.New(cfg.Canary),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Rollout returns the canary rollout controller (nil when canary rollouts are disabled)
+func (s *Syncer) Rollout() *canary.Rollout {
+	if s == nil {
+		return nil
+	}
+	return s.rollout
+}
+
+// OnUpdate registers fn to run with every newly applied payload. If a payload
+// is already applied, fn runs with it straight away.
+func (s *Syncer) OnUpdate(fn func(*Payload)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.onUpdate = append(s.onUpdate, fn)
+	current := s.current
+	s.mu.Unlock()
+
+	if current != nil {
+		fn(current)
+	}
+}
+
+// OnCanary registers fn to run with each payload entering a canary rollout,
+// for requests in the canary cohort, and with nil once the rollout ends
+func (s *Syncer) OnCanary(fn func(*Payload)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.onCanary = append(s.onCanary, fn)
+	s.mu.Unlock()
+}
+
+// Start applies the cached payload, then syncs once so state is current
+// before traffic arrives, then keeps polling in the background
+func (s *Syncer) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if s.cfg.CacheFile != "" {
+		if err := s.loadCache(); err != nil && !os.IsNotExist(err) {
+			logger.Warn().Err(err).Str("file", s.cfg.CacheFile).Msg("Ignoring control plane cache")
+		}
+	}
+	s.Sync(ctx)
+	go s.loop()
+}
+
+// Stop stops polling
+func (s *Syncer) Stop() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Syncer) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.PollInterval)
+			s.Sync(ctx)
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Sync fetches the payload and applies it if it changed
+func (s *Syncer) Sync(ctx context.Context) error {
+	err := s.fetch(ctx)
+
+	s.mu.Lock()
+	s.lastSync = time.Now()
+	if err != nil {
+		if s.lastErr == "" {
+			logger.Warn().Err(err).Msg("Control plane sync failed, keeping current state")
+		}
+		s.lastErr = err.Error()
+	} else {
+		s.lastErr = ""
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Syncer) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+	s.mu.Lock()
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	s.mu.Unlock()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("control plane request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	default:
+		return fmt.Errorf("control plane returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPayloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read payload: %w", err)
+	}
+	return s.apply(body, resp.Header.Get(SignatureHeader), resp.Header.Get("ETag"), "control_plane")
+}
+
+// apply verifies and decodes a payload. The first payload (and the cached
+// one) is committed straight away; later ones go through a canary rollout
+// first if enabled. Payloads not newer than the last accepted one, including
+// a rolled back one, are not applied again.
+func (s *Syncer) apply(body []byte, signature, etag, source string) error {
+	if !Verify(s.cfg.SigningKey, body, signature) {
+		return errBadSignature
+	}
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fmt.Errorf("failed to decode payload: %w", err)
+	}
+	if payload.IssuedAt.IsZero() {
+		return errNoIssuedAt
+	}
+	// The cache holds the last payload applied, however old; others must be fresh
+	if age := time.Since(payload.IssuedAt); source != "cache" && s.cfg.MaxPayloadAge > 0 && age > s.cfg.MaxPayloadAge {
+		return fmt.Errorf("payload issued %s ago, more than max_payload_age %s", age.Round(time.Second), s.cfg.MaxPayloadAge)
+	}
+
+	s.mu.Lock()
+	if !payload.IssuedAt.After(s.issuedAt) {
+		last := s.issuedAt
+		s.mu.Unlock()
+		if payload.IssuedAt.Equal(last) {
+			return nil
+		}
+		return fmt.Errorf("payload issued at %s is older than the last accepted one (%s)",
+			payload.IssuedAt.Format(time.RFC3339), last.Format(time.RFC3339))
+	}
+	s.issuedAt = payload.IssuedAt
+	// The ETag is taken even for a canary, so a rolled back payload is not fetched again
+	s.etag = etag
+	canaried := s.rollout != nil && s.current != nil && source != "cache"
+	var callbacks []func(*Payload)
+	if canaried {
+		s.candidate = &payload
+		callbacks = append(callbacks, s.onCanary...)
+	}
+	s.mu.Unlock()
+
+	if !canaried {
+		s.commit(&payload, body, signature, etag, source)
+		return nil
+	}
+
+	logger.Info().Str("version", payload.Version).Msg("Canarying control plane payload")
+	for _, fn := range callbacks {
+		fn(&payload)
+	}
+	s.rollout.Begin(payload.Version, func() {
+		s.commit(&payload, body, signature, etag, source)
+		s.endCanary(&payload)
+	}, func() {
+		s.endCanary(&payload)
+	})
+	return nil
+}
+
+// endCanary clears the canary state of p, unless a newer payload replaced it
+func (s *Syncer) endCanary(p *Payload) {
+	s.mu.Lock()
+	if s.candidate != p {
+		s.mu.Unlock()
+		return
+	}
+	s.candidate = nil
+	callbacks := append([]func(*Payload){}, s.onCanary...)
+	s.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(nil)
+	}
+}
+
+// commit makes payload the applied state, hands it to the update callbacks
+// and caches it
+func (s *Syncer) commit(payload *Payload, body []byte, signature, etag, source string) {
+	s.mu.Lock()
+	s.current = payload
+	s.source = source
+	s.lastChange = time.Now()
+	s.updates++
+	callbacks := append([]func(*Payload){}, s.onUpdate...)
+	s.mu.Unlock()
+
+	logger.Info().
+		Str("version", payload.Version).
+		Str("source", source).
+		Int("routes", len(payload.Routes)).
+		Int("provider_keys", len(payload.ProviderKeys)).
+		Int("budgets", len(payload.Budgets)).
+		Msg("Applying control plane payload")
+	observability.LogAudit(context.Background(), "control_plane.apply", payload.Version, map[string]interface{}{
+		"source": source,
+		"etag":   etag,
+	})
+
+	for _, fn := range callbacks {
+		fn(payload)
+	}
+
+	if s.cfg.CacheFile != "" && source != "cache" {
+		if err := s.saveCache(body, signature, etag); err != nil {
+			logger.Warn().Err(err).Str("file", s.cfg.CacheFile).Msg("Failed to write control plane cache")
+		}
+	}
+}
+
+func (s *Syncer) loadCache() error {
+	data, err := os.ReadFile(s.cfg.CacheFile)
+	if err != nil {
+		return err
+	}
+	var cached cachedPayload
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return fmt.Errorf("failed to decode cache: %w", err)
+	}
+	return s.apply(cached.Body, cached.Signature, cached.ETag, "cache")
+}
+
+// saveCache writes the payload via a temporary file, so a crash never leaves a partial cache
+func (s *Syncer) saveCache(body []byte, signature, etag string) error {
+	data, err := json.Marshal(cachedPayload{ETag: etag, Signature: signature, Body: body})
+	if err != nil {
+		return err
+	}
+	tmp := s.cfg.CacheFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.cfg.CacheFile)
+}
+
+// Stats returns the applied payload version and sync status
+func (s *Syncer) Stats() map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := map[string]interface{}{
+		"enabled":       true,
+		"url":           s.cfg.URL,
+		"poll_interval": s.cfg.PollInterval.String(),
+		"updates":       s.updates,
+	}
+	if s.current != nil {
+		stats["version"] = s.current.Version
+		stats["issued_at"] = s.current.IssuedAt
+		stats["etag"] = s.etag
+		stats["source"] = s.source
+		stats["routes"] = len(s.current.Routes)
+		stats["provider_keys"] = len(s.current.ProviderKeys)
+		stats["budgets"] = len(s.current.Budgets)
+	}
+	if s.candidate != nil {
+		stats["canary_version"] = s.candidate.Version
+	}
+	if !s.lastSync.IsZero() {
+		stats["last_sync"] = s.lastSync
+	}
+	if !s.lastChange.IsZero() {
+		stats["last_change"] = s.lastChange
+	}
+	if s.lastErr != "" {
+		stats["error"] = s.lastErr
+	}
+	return stats
+}
+
+// Sign returns the signature header value for body
+func Sign(key string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body
+func Verify(key string, body []byte, signature string) bool {
+	if key == "" || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(key, body)), []byte(signature))
+}
+
+// defaultSyncer is the process-wide syncer used by the API router and admin endpoints
+var defaultSyncer atomic.Pointer[Syncer]
+
+// SetDefault sets the process-wide syncer
+func SetDefault(s *Syncer) {
+	defaultSyncer.Store(s)
+}
+
+// Default returns the process-wide syncer (nil when the control plane is disabled)
+func Default() *Syncer {
+	return defaultSyncer.Load()
+}
@@ -0,0 +1,240 @@
+package controlplane
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/canary"
+	"github.com/username/llm-gateway/internal/config"
+)
+
+const testKey = "test-signing-key"
+
+// fakeControlPlane serves body with an ETag, honouring If-None-Match
+func fakeControlPlane(t *testing.T, body *atomic.Value, signature func([]byte) string) (*httptest.Server, *atomic.Int64) {
+	var notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer cp-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b := body.Load().([]byte)
+		etag := `"` + Sign("etag", b)[7:19] + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set(SignatureHeader, signature(b))
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &notModified
+}
+
+// issued returns the issued_at field for a payload signed n minutes after base
+func issued(n int) string {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	return `"issued_at":"` + base.Add(time.Duration(n)*time.Minute).Format(time.RFC3339) + `"`
+}
+
+func newTestSyncer(url, cacheFile string) *Syncer {
+	return New(config.ControlPlaneConfig{
+		Enabled:       true,
+		URL:           url,
+		Token:         "cp-token",
+		SigningKey:    testKey,
+		PollInterval:  time.Hour,
+		Timeout:       5 * time.Second,
+		CacheFile:     cacheFile,
+		MaxPayloadAge: 24 * time.Hour,
+	})
+}
+
+func TestSyncer_AppliesAndCachesByETag(t *testing.T) {
+	var body atomic.Value
+	body.Store([]byte(`{"version":"1",` + issued(1) + `,"routes":{"gpt-4o":"openai"},"budgets":{"*":1000}}`))
+	srv, notModified := fakeControlPlane(t, &body, func(b []byte) string { return Sign(testKey, b) })
+
+	s := newTestSyncer(srv.URL, "")
+	var applied []*Payload
+	s.OnUpdate(func(p *Payload) { applied = append(applied, p) })
+
+	ctx := context.Background()
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Routes["gpt-4o"] != "openai" || applied[0].Budgets["*"] != 1000 {
+		t.Fatalf("applied = %+v", applied)
+	}
+
+	// Unchanged payload: 304, nothing re-applied
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(applied) != 1 || notModified.Load() != 1 {
+		t.Errorf("applied %d times, %d not-modified responses", len(applied), notModified.Load())
+	}
+
+	body.Store([]byte(`{"version":"2",` + issued(2) + `}`))
+	s.Sync(ctx)
+	if len(applied) != 2 || applied[1].Version != "2" {
+		t.Errorf("new payload not applied: %+v", applied)
+	}
+
+	// Callbacks registered late get the current payload straight away
+	var late *Payload
+	s.OnUpdate(func(p *Payload) { late = p })
+	if late == nil || late.Version != "2" {
+		t.Errorf("late callback got %+v", late)
+	}
+}
+
+func TestSyncer_RejectsBadSignature(t *testing.T) {
+	var body atomic.Value
+	body.Store([]byte(`{"version":"1",` + issued(1) + `}`))
+	srv, _ := fakeControlPlane(t, &body, func(b []byte) string { return Sign("wrong-key", b) })
+
+	s := newTestSyncer(srv.URL, "")
+	applied := 0
+	s.OnUpdate(func(*Payload) { applied++ })
+
+	if err := s.Sync(context.Background()); err == nil {
+		t.Fatal("Sync accepted a payload with a bad signature")
+	}
+	if applied != 0 || s.Stats()["error"] == nil {
+		t.Errorf("applied = %d, stats = %v", applied, s.Stats())
+	}
+}
+
+func TestSyncer_StartsFromCache(t *testing.T) {
+	var body atomic.Value
+	body.Store([]byte("{\n  \"version\": \"7\",\n  " + issued(1) + "\n}"))
+	srv, _ := fakeControlPlane(t, &body, func(b []byte) string { return Sign(testKey, b) })
+	cacheFile := filepath.Join(t.TempDir(), "control-plane.json")
+
+	if err := newTestSyncer(srv.URL, cacheFile).Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	srv.Close()
+
+	// Control plane down: the cached payload is verified and applied
+	s := newTestSyncer(srv.URL, cacheFile)
+	var version string
+	s.OnUpdate(func(p *Payload) { version = p.Version })
+	s.Start(context.Background())
+	defer s.Stop()
+
+	stats := s.Stats()
+	if version != "7" || stats["source"] != "cache" || stats["error"] == nil {
+		t.Errorf("version = %q, stats = %v", version, stats)
+	}
+}
+
+func TestSyncer_RejectsReplayedAndStalePayloads(t *testing.T) {
+	var body atomic.Value
+	body.Store([]byte(`{"version":"2",` + issued(2) + `}`))
+	srv, _ := fakeControlPlane(t, &body, func(b []byte) string { return Sign(testKey, b) })
+
+	s := newTestSyncer(srv.URL, "")
+	var applied []string
+	s.OnUpdate(func(p *Payload) { applied = append(applied, p.Version) })
+	ctx := context.Background()
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	// A validly signed but older payload is a replay
+	body.Store([]byte(`{"version":"1",` + issued(1) + `}`))
+	if err := s.Sync(ctx); err == nil {
+		t.Error("Sync accepted an older payload")
+	}
+	// So is one without issued_at, or issued too long ago
+	body.Store([]byte(`{"version":"3"}`))
+	if err := s.Sync(ctx); err == nil {
+		t.Error("Sync accepted a payload without issued_at")
+	}
+	body.Store([]byte(`{"version":"3",` + issued(-25*60) + `}`))
+	if err := s.Sync(ctx); err == nil {
+		t.Error("Sync accepted a payload older than max_payload_age")
+	}
+	if len(applied) != 1 || s.Stats()["version"] != "2" {
+		t.Errorf("applied = %v, stats = %v, want only version 2", applied, s.Stats())
+	}
+
+	body.Store([]byte(`{"version":"3",` + issued(3) + `}`))
+	if err := s.Sync(ctx); err != nil || len(applied) != 2 {
+		t.Errorf("newer payload: err = %v, applied = %v", err, applied)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"version":"1"}`)
+	if !Verify(testKey, body, Sign(testKey, body)) {
+		t.Error("valid signature rejected")
+	}
+	if Verify(testKey, []byte(`{"version":"2"}`), Sign(testKey, body)) {
+		t.Error("signature accepted for a different body")
+	}
+	if Verify("", body, Sign("", body)) {
+		t.Error("signature accepted without a signing key")
+	}
+}
+
+func TestNilSyncer(t *testing.T) {
+	var s *Syncer
+	s.OnUpdate(func(*Payload) { t.Error("callback run on nil syncer") })
+	s.Start(context.Background())
+	s.Stop()
+	if s.Stats()["enabled"] != false {
+		t.Error("nil syncer should report disabled")
+	}
+}
+
+func TestSyncer_CanariesNewPayloads(t *testing.T) {
+	var body atomic.Value
+	body.Store([]byte(`{"version":"1",` + issued(1) + `}`))
+	srv, _ := fakeControlPlane(t, &body, func(b []byte) string { return Sign(testKey, b) })
+
+	s := newTestSyncer(srv.URL, "")
+	s.cfg.Canary = config.CanaryConfig{Enabled: true, Percent: 10, Window: time.Hour, MinRequests: 10, MaxLatencyRatio: 2}
+	s.rollout = canary.New(s.cfg.Canary)
+	var applied, canaried []string
+	s.OnUpdate(func(p *Payload) { applied = append(applied, p.Version) })
+	s.OnCanary(func(p *Payload) {
+		if p == nil {
+			canaried = append(canaried, "-")
+			return
+		}
+		canaried = append(canaried, p.Version)
+	})
+
+	// The first payload has nothing to compare against and applies straight away
+	ctx := context.Background()
+	s.Sync(ctx)
+	body.Store([]byte(`{"version":"2",` + issued(2) + `}`))
+	s.Sync(ctx)
+	if len(applied) != 1 || len(canaried) != 1 || canaried[0] != "2" {
+		t.Fatalf("applied = %v, canaried = %v, want version 2 canaried only", applied, canaried)
+	}
+
+	// A rolled back payload is not fetched again
+	s.Rollout().Rollback("test")
+	s.Sync(ctx)
+	if len(applied) != 1 || len(canaried) != 2 || canaried[1] != "-" {
+		t.Fatalf("after rollback applied = %v, canaried = %v", applied, canaried)
+	}
+
+	body.Store([]byte(`{"version":"3",` + issued(3) + `}`))
+	s.Sync(ctx)
+	s.Rollout().Promote("test")
+	if len(applied) != 2 || applied[1] != "3" || canaried[len(canaried)-1] != "-" {
+		t.Errorf("after promotion applied = %v, canaried = %v", applied, canaried)
+	}
+}
@@ -0,0 +1,377 @@
+// Package cost prices the tokens of each request with the pricing table and
+// tracks spend per API key, provider and model for the current UTC day and
+// month. Spend budgets on any of them either reject requests once exhausted
+// or warn callers with an X-Quota-Warning header. Spend is kept per replica.
+package cost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/notify"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the cost module logger; its level can be set via log.modules.cost
+var logger = observability.ModuleLogger("cost")
+
+// Budget scopes
+const (
+	ScopeKey      = "key"
+	ScopeProvider = "provider"
+	ScopeModel    = "model"
+)
+
+// anonymous is the key of callers presenting no credentials
+const anonymous = "anonymous"
+
+// Spend is the cost and tokens of the requests in one period
+type Spend struct {
+	USD              float64 `json:"usd"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+}
+
+func (s *Spend) add(o Spend) {
+	s.USD += o.USD
+	s.Requests += o.Requests
+	s.PromptTokens += o.PromptTokens
+	s.CompletionTokens += o.CompletionTokens
+}
+
+// totals is spend in the current UTC day and month
+type totals struct {
+	day, month string
+	today      Spend
+	thisMonth  Spend
+}
+
+// roll starts fresh totals for periods that have passed
+func (t *totals) roll(now time.Time) {
+	now = now.UTC()
+	if day := now.Format(time.DateOnly); day != t.day {
+		t.day, t.today = day, Spend{}
+	}
+	if month := now.Format("2006-01"); month != t.month {
+		t.month, t.thisMonth = month, Spend{}
+	}
+}
+
+// period returns the spend of a budget period, "day" or "month"
+func (t *totals) period(period string) Spend {
+	if period == "month" {
+		return t.thisMonth
+	}
+	return t.today
+}
+
+// window names the current instance of a budget period
+func (t *totals) window(period string) string {
+	if period == "month" {
+		return t.month
+	}
+	return t.day
+}
+
+// Row is the spend of one API key on one provider and model
+type Row struct {
+	Key      string `json:"key"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Today    Spend  `json:"today"`
+	Month    Spend  `json:"month"`
+}
+
+// BudgetStatus is a budget with the spend counted against it
+type BudgetStatus struct {
+	config.SpendBudgetConfig
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+}
+
+// KeyUsage is the spend of one API key, as reported by /v1/usage
+type KeyUsage struct {
+	Key     string         `json:"key"`
+	Today   Spend          `json:"today"`
+	Month   Spend          `json:"month"`
+	Rows    []Row          `json:"by_model"`
+	Budgets []BudgetStatus `json:"budgets"`
+}
+
+// BudgetError is returned by Admit for a request over a reject budget
+type BudgetError struct {
+	Budget config.SpendBudgetConfig
+	// ID is the key, provider or model over budget
+	ID    string
+	Spent float64
+	// RetryAfter is when the budget's period resets
+	RetryAfter time.Duration
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s spend budget of $%.2f per %s exceeded for %s", e.Budget.Scope, e.Budget.Limit, e.Budget.Period, e.ID)
+}
+
+// Engine prices requests and tracks spend against the configured budgets
+type Engine struct {
+	pricing config.PricingConfig
+	budgets []config.SpendBudgetConfig
+	history time.Duration
+	anomaly config.SpendAnomalyConfig
+	now     func() time.Time
+
+	mu sync.Mutex
+	// scopes holds the totals of each key, provider and model, by "scope:id"
+	scopes map[string]*totals
+	rows   map[rowKey]*totals
+	// exceeded holds the period in which each budget and ID was last reported exceeded
+	exceeded map[string]string
+	// tenants holds each tenant's hourly spend for forecasts
+	tenants   map[string]*tenantSpend
+	anomalies []Anomaly
+}
+
+type rowKey struct {
+	key, provider, model string
+}
+
+// New creates an engine from configuration, or returns nil if cost tracking
+// is disabled
+func New(cfg config.CostConfig, pricing config.PricingConfig) *Engine {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(pricing) == 0 {
+		logger.Warn().Msg("Cost tracking enabled without a pricing table, spend stays zero")
+	}
+	history := cfg.History
+	if history <= 0 {
+		history = defaultHistory
+	}
+	return &Engine{
+		pricing:  pricing,
+		budgets:  cfg.Budgets,
+		history:  history,
+		anomaly:  cfg.Anomaly,
+		now:      time.Now,
+		scopes:   make(map[string]*totals),
+		rows:     make(map[rowKey]*totals),
+		exceeded: make(map[string]string),
+		tenants:  make(map[string]*tenantSpend),
+	}
+}
+
+// totalsLocked returns the rolled totals of scope and id; e.mu must be held
+func (e *Engine) totalsLocked(scope, id string, now time.Time) *totals {
+	t, ok := e.scopes[scope+":"+id]
+	if !ok {
+		t = &totals{}
+		e.scopes[scope+":"+id] = t
+	}
+	t.roll(now)
+	return t
+}
+
+// Record prices a request's tokens and adds them to the spend of the
+// caller's key, the provider, the model and the tenant's hourly spend,
+// returning the cost in USD
+func (e *Engine) Record(ctx context.Context, provider, model string, promptTokens, completionTokens int) float64 {
+	if e == nil {
+		return 0
+	}
+	key := KeyFromContext(ctx)
+	spend := Spend{
+		USD:              e.pricing.Cost(model, int64(promptTokens), int64(completionTokens)),
+		Requests:         1,
+		PromptTokens:     int64(promptTokens),
+		CompletionTokens: int64(completionTokens),
+	}
+
+	now := e.now()
+	e.mu.Lock()
+	for scope, id := range map[string]string{ScopeKey: key, ScopeProvider: provider, ScopeModel: model} {
+		t := e.totalsLocked(scope, id, now)
+		t.today.add(spend)
+		t.thisMonth.add(spend)
+	}
+	row, ok := e.rows[rowKey{key, provider, model}]
+	if !ok {
+		row = &totals{}
+		e.rows[rowKey{key, provider, model}] = row
+	}
+	row.roll(now)
+	row.today.add(spend)
+	row.thisMonth.add(spend)
+	anomaly := e.recordTenantLocked(TenantFromContext(ctx), spend.USD, now)
+	e.mu.Unlock()
+
+	if anomaly != nil {
+		reportAnomaly(anomaly)
+	}
+	observability.GetMetrics().RecordSpend(provider, model, key, spend.USD)
+	return spend.USD
+}
+
+// Admit checks the budgets applying to a request for model on provider by
+// the caller in ctx. It returns a *BudgetError if a reject budget is
+// exhausted; exhausted warn budgets add an X-Quota-Warning header to h.
+func (e *Engine) Admit(ctx context.Context, h http.Header, provider, model string) error {
+	if e == nil || len(e.budgets) == 0 {
+		return nil
+	}
+	ids := map[string]string{ScopeKey: KeyFromContext(ctx), ScopeProvider: provider, ScopeModel: model}
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, b := range e.budgets {
+		id := ids[b.Scope]
+		if b.ID != "*" && b.ID != id {
+			continue
+		}
+		t := e.totalsLocked(b.Scope, id, now)
+		spent := t.period(b.Period).USD
+		if spent < b.Limit {
+			continue
+		}
+
+		e.reportExceededLocked(i, id, t.window(b.Period), b, spent)
+		if b.Action == "reject" {
+			return &BudgetError{Budget: b, ID: id, Spent: spent, RetryAfter: untilReset(now, b.Period)}
+		}
+		h.Add(middleware.QuotaWarningHeader, "spend_"+b.Scope+
+			"; threshold=100"+
+			"; used="+strconv.FormatFloat(math.Round(spent/b.Limit*1000)/10, 'f', -1, 64)+
+			"; period="+b.Period)
+	}
+	return nil
+}
+
+// reportExceededLocked logs a budget found exhausted and emits a
+// "spend.budget_exceeded" event, once per budget, ID and period; e.mu must be held
+func (e *Engine) reportExceededLocked(i int, id, window string, b config.SpendBudgetConfig, spent float64) {
+	name := strconv.Itoa(i) + "/" + id
+	if e.exceeded[name] == window {
+		return
+	}
+	e.exceeded[name] = window
+
+	logger.Warn().
+		Str("scope", b.Scope).
+		Str("id", id).
+		Str("period", b.Period).
+		Str("action", b.Action).
+		Float64("limit", b.Limit).
+		Float64("spent", spent).
+		Msg("Spend budget exceeded")
+
+	notify.Default().Emit(notify.Event{
+		Type: "spend.budget_exceeded",
+		Data: map[string]interface{}{
+			"scope":  b.Scope,
+			"id":     id,
+			"period": b.Period,
+			"action": b.Action,
+			"limit":  b.Limit,
+			"spent":  math.Round(spent*100) / 100,
+		},
+	})
+}
+
+// untilReset returns the time left in the current UTC day or month
+func untilReset(now time.Time, period string) time.Duration {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if period == "month" {
+		next = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return next.Sub(now)
+}
+
+// Usage returns the spend of key, broken down by provider and model, with
+// the key budgets applying to it
+func (e *Engine) Usage(key string) KeyUsage {
+	usage := KeyUsage{Key: key, Rows: []Row{}, Budgets: []BudgetStatus{}}
+	if e == nil {
+		return usage
+	}
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := e.totalsLocked(ScopeKey, key, now)
+	usage.Today, usage.Month = t.today, t.thisMonth
+	for _, row := range e.rowsLocked(now) {
+		if row.Key == key {
+			usage.Rows = append(usage.Rows, row)
+		}
+	}
+	for _, b := range e.budgets {
+		if b.Scope != ScopeKey || (b.ID != "*" && b.ID != key) {
+			continue
+		}
+		spent := t.period(b.Period).USD
+		usage.Budgets = append(usage.Budgets, BudgetStatus{
+			SpendBudgetConfig: b,
+			Spent:             spent,
+			Remaining:         max(b.Limit-spent, 0),
+		})
+	}
+	return usage
+}
+
+// Rows returns the spend of every key, provider and model with spend this month
+func (e *Engine) Rows() []Row {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rowsLocked(e.now())
+}
+
+// rowsLocked returns the rows with spend this month, sorted; e.mu must be held
+func (e *Engine) rowsLocked(now time.Time) []Row {
+	rows := make([]Row, 0, len(e.rows))
+	for k, t := range e.rows {
+		t.roll(now)
+		if t.thisMonth.Requests == 0 {
+			delete(e.rows, k)
+			continue
+		}
+		rows = append(rows, Row{Key: k.key, Provider: k.provider, Model: k.model, Today: t.today, Month: t.thisMonth})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Key != rows[j].Key {
+			return rows[i].Key < rows[j].Key
+		}
+		if rows[i].Provider != rows[j].Provider {
+			return rows[i].Provider < rows[j].Provider
+		}
+		return rows[i].Model < rows[j].Model
+	})
+	return rows
+}
+
+// defaultEngine is the process-wide engine used by the handlers and endpoints
+var defaultEngine atomic.Pointer[Engine]
+
+// SetDefault sets the process-wide engine
+func SetDefault(e *Engine) {
+	defaultEngine.Store(e)
+}
+
+// Default returns the process-wide engine (nil when cost tracking is disabled)
+func Default() *Engine {
+	return defaultEngine.Load()
+}
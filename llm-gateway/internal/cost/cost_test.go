@@ -0,0 +1,137 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+// pricing charges $1 per million prompt and $2 per million completion tokens
+var pricing = config.PricingConfig{"*": {Prompt: 1, Completion: 2}}
+
+func withKey(key string) context.Context {
+	return context.WithValue(context.Background(), keyContextKey{}, key)
+}
+
+func TestNew_Disabled(t *testing.T) {
+	if e := New(config.CostConfig{}, pricing); e != nil {
+		t.Fatal("New() with cost tracking disabled returned an engine")
+	}
+	var e *Engine
+	if err := e.Admit(context.Background(), http.Header{}, "openai", "gpt-4o"); err != nil {
+		t.Errorf("nil Admit() = %v", err)
+	}
+	if usd := e.Record(context.Background(), "openai", "gpt-4o", 10, 10); usd != 0 {
+		t.Errorf("nil Record() = %v", usd)
+	}
+}
+
+func TestEngine_RecordAndUsage(t *testing.T) {
+	e := New(config.CostConfig{Enabled: true, Budgets: []config.SpendBudgetConfig{
+		{Scope: ScopeKey, ID: "*", Limit: 10, Period: "month", Action: "reject"},
+		{Scope: ScopeKey, ID: "other", Limit: 1, Period: "day", Action: "reject"},
+	}}, pricing)
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	if usd := e.Record(withKey("key_a"), "openai", "gpt-4o", 1_000_000, 500_000); usd != 2 {
+		t.Errorf("Record() = %v, want 2", usd)
+	}
+	e.Record(withKey("key_a"), "anthropic", "claude", 1_000_000, 0)
+	e.Record(withKey("key_b"), "openai", "gpt-4o", 1_000_000, 0)
+
+	usage := e.Usage("key_a")
+	if usage.Today.USD != 3 || usage.Month.Requests != 2 || len(usage.Rows) != 2 || usage.Rows[0].Provider != "anthropic" {
+		t.Errorf("Usage() = %+v", usage)
+	}
+	if len(usage.Budgets) != 1 || usage.Budgets[0].Spent != 3 || usage.Budgets[0].Remaining != 7 {
+		t.Errorf("Usage().Budgets = %+v", usage.Budgets)
+	}
+	if rows := e.Rows(); len(rows) != 3 {
+		t.Errorf("Rows() = %+v", rows)
+	}
+
+	// A new month starts from zero
+	now = now.Add(2 * time.Hour)
+	if usage := e.Usage("key_a"); usage.Month.USD != 0 || len(usage.Rows) != 0 {
+		t.Errorf("Usage() in the next month = %+v", usage)
+	}
+}
+
+func TestEngine_Admit(t *testing.T) {
+	e := New(config.CostConfig{Enabled: true, Budgets: []config.SpendBudgetConfig{
+		{Scope: ScopeProvider, ID: "openai", Limit: 1, Period: "day", Action: "warn"},
+		{Scope: ScopeKey, ID: "*", Limit: 2, Period: "day", Action: "reject"},
+	}}, pricing)
+	now := time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	ctx := withKey("key_a")
+
+	h := http.Header{}
+	if err := e.Admit(ctx, h, "openai", "gpt-4o"); err != nil || h.Get(middleware.QuotaWarningHeader) != "" {
+		t.Fatalf("Admit() under budget = %v, header %q", err, h.Get(middleware.QuotaWarningHeader))
+	}
+
+	// Over the provider's warn budget: admitted with a warning
+	e.Record(ctx, "openai", "gpt-4o", 1_500_000, 0)
+	if err := e.Admit(ctx, h, "openai", "gpt-4o"); err != nil {
+		t.Fatalf("Admit() over a warn budget = %v", err)
+	}
+	if got := h.Get(middleware.QuotaWarningHeader); got != "spend_provider; threshold=100; used=150; period=day" {
+		t.Errorf("warning = %q", got)
+	}
+
+	// Over the key's reject budget: rejected until midnight, other keys are not
+	e.Record(ctx, "anthropic", "claude", 1_000_000, 0)
+	var budgetErr *BudgetError
+	if err := e.Admit(ctx, http.Header{}, "anthropic", "claude"); !errors.As(err, &budgetErr) ||
+		budgetErr.ID != "key_a" || budgetErr.RetryAfter != 6*time.Hour {
+		t.Errorf("Admit() over a reject budget = %v", err)
+	}
+	if err := e.Admit(withKey("key_b"), http.Header{}, "anthropic", "claude"); err != nil {
+		t.Errorf("Admit() for another key = %v", err)
+	}
+
+	now = now.Add(6 * time.Hour)
+	if err := e.Admit(ctx, http.Header{}, "anthropic", "claude"); err != nil {
+		t.Errorf("Admit() the next day = %v", err)
+	}
+}
+
+func TestMiddleware_Key(t *testing.T) {
+	e := New(config.CostConfig{Enabled: true}, pricing)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		auth string
+		want string
+	}{
+		{"managed key", context.WithValue(context.Background(), middleware.KeyIDContextKey, "key_1"), "Bearer gw-secret", "key_1"},
+		{"user", context.WithValue(context.Background(), middleware.UserIDContextKey, "alice"), "Bearer eyJhbGciOi.x.y", "user:alice"},
+		{"api key", context.Background(), "Bearer sk-abcdefghijklmnop", "sk-abcdefghi***"},
+		{"anonymous", context.Background(), "", anonymous},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := e.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = KeyFromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader("{}")).WithContext(tt.ctx)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
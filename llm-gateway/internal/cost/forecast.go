@@ -0,0 +1,227 @@
+package cost
+
+import (
+	"math"
+	"time"
+
+	"github.com/username/llm-gateway/internal/notify"
+)
+
+const (
+	// defaultHistory is how long hourly spend is kept when cost.history is unset
+	defaultHistory = 7 * 24 * time.Hour
+	// recentHours is the number of full hours the recent burn rate is averaged over
+	recentHours = 3
+	// maxAnomalies is the number of recent anomalies kept for the admin API
+	maxAnomalies = 100
+)
+
+// tenantSpend is a tenant's spend by hour, kept for cost.history
+type tenantSpend struct {
+	// hours holds spend in USD by Unix hour
+	hours map[int64]float64
+	// since is when the tenant was first seen by this replica
+	since  time.Time
+	totals totals
+	// flagged is the hour the tenant was last flagged in
+	flagged int64
+}
+
+// Forecast projects a tenant's spend to the end of the UTC day and month
+type Forecast struct {
+	Tenant string  `json:"tenant"`
+	Today  float64 `json:"today"`
+	Month  float64 `json:"month"`
+	// CurrentHour is the spend in the current clock hour so far
+	CurrentHour float64 `json:"current_hour"`
+	// RecentHourly is the average hourly spend over the last 3 full hours
+	RecentHourly float64 `json:"recent_hourly"`
+	// HourlyBaseline is the average hourly spend over cost.history
+	HourlyBaseline float64 `json:"hourly_baseline"`
+	// HistoryHours is the number of full hours the baseline covers
+	HistoryHours  int     `json:"history_hours"`
+	ForecastDay   float64 `json:"forecast_day"`
+	ForecastMonth float64 `json:"forecast_month"`
+}
+
+// Anomaly is an hour in which a tenant spent far more than its normal burn
+type Anomaly struct {
+	ID     string    `json:"id"`
+	Tenant string    `json:"tenant"`
+	Hour   time.Time `json:"hour"`
+	// Spend is the spend in the hour when it was flagged
+	Spend          float64 `json:"spend"`
+	HourlyBaseline float64 `json:"hourly_baseline"`
+	// Ratio is Spend over HourlyBaseline, omitted if the tenant spent
+	// nothing before
+	Ratio      float64   `json:"ratio,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// recordTenantLocked adds usd to the tenant's hourly spend and returns an
+// anomaly if the current hour is newly over the anomaly threshold; e.mu
+// must be held
+func (e *Engine) recordTenantLocked(tenant string, usd float64, now time.Time) *Anomaly {
+	t, ok := e.tenants[tenant]
+	if !ok {
+		t = &tenantSpend{hours: make(map[int64]float64), since: now}
+		e.tenants[tenant] = t
+	}
+	t.totals.roll(now)
+	t.totals.today.USD += usd
+	t.totals.thisMonth.USD += usd
+
+	hour := now.Unix() / 3600
+	t.hours[hour] += usd
+	for h := range t.hours {
+		if h <= hour-int64(e.history/time.Hour) {
+			delete(t.hours, h)
+		}
+	}
+
+	a := e.anomaly
+	if !a.Enabled || t.flagged == hour || now.Sub(t.since) < a.MinHistory {
+		return nil
+	}
+	baseline, full := e.baselineLocked(t, hour)
+	spend := t.hours[hour]
+	if full == 0 || spend < a.MinSpend || spend < a.Factor*baseline {
+		return nil
+	}
+	t.flagged = hour
+	anomaly := Anomaly{
+		ID:             tenant + "/" + time.Unix(hour*3600, 0).UTC().Format(time.RFC3339),
+		Tenant:         tenant,
+		Hour:           time.Unix(hour*3600, 0).UTC(),
+		Spend:          round4(spend),
+		HourlyBaseline: round4(baseline),
+		DetectedAt:     now.UTC(),
+	}
+	if baseline > 0 {
+		anomaly.Ratio = math.Round(spend/baseline*10) / 10
+	}
+	e.anomalies = append(e.anomalies, anomaly)
+	if len(e.anomalies) > maxAnomalies {
+		e.anomalies = e.anomalies[len(e.anomalies)-maxAnomalies:]
+	}
+	return &anomaly
+}
+
+// baselineLocked returns a tenant's average spend per full hour before hour,
+// over cost.history or since it was first seen, and the number of full hours
+// that covers; e.mu must be held
+func (e *Engine) baselineLocked(t *tenantSpend, hour int64) (float64, int) {
+	start := max(t.since.Unix()/3600, hour-int64(e.history/time.Hour))
+	full := int(hour - start)
+	if full <= 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for h, usd := range t.hours {
+		if h >= start && h < hour {
+			sum += usd
+		}
+	}
+	return sum / float64(full), full
+}
+
+// reportAnomaly logs an anomaly and emits it as a "spend.anomaly" event
+func reportAnomaly(a *Anomaly) {
+	logger.Warn().
+		Str("tenant", a.Tenant).
+		Time("hour", a.Hour).
+		Float64("spend", a.Spend).
+		Float64("hourly_baseline", a.HourlyBaseline).
+		Msg("Spend anomaly: hourly spend far above normal")
+
+	notify.Default().Emit(notify.Event{
+		Type: "spend.anomaly",
+		Data: map[string]interface{}{
+			"tenant":          a.Tenant,
+			"hour":            a.Hour,
+			"spend":           a.Spend,
+			"hourly_baseline": a.HourlyBaseline,
+			"ratio":           a.Ratio,
+		},
+	})
+}
+
+// Forecasts returns the spend forecast of every tenant with spend this
+// month or within cost.history. The current hour's burn is projected from
+// the last 3 full hours to the end of the day, and later days from the
+// baseline once a day of history is known.
+func (e *Engine) Forecasts() []Forecast {
+	if e == nil {
+		return nil
+	}
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hour := now.Unix() / 3600
+	utc := now.UTC()
+	midnight := time.Date(utc.Year(), utc.Month(), utc.Day()+1, 0, 0, 0, 0, time.UTC)
+	restOfDay := midnight.Sub(utc).Hours()
+	daysLeft := untilReset(now, "month").Hours()/24 - restOfDay/24
+
+	forecasts := make([]Forecast, 0, len(e.tenants))
+	for tenant, t := range e.tenants {
+		t.totals.roll(now)
+		for h := range t.hours {
+			if h <= hour-int64(e.history/time.Hour) {
+				delete(t.hours, h)
+			}
+		}
+		if len(t.hours) == 0 && t.totals.thisMonth.USD == 0 {
+			delete(e.tenants, tenant)
+			continue
+		}
+
+		baseline, full := e.baselineLocked(t, hour)
+		recent := 0.0
+		if n := min(full, recentHours); n > 0 {
+			for h := hour - int64(n); h < hour; h++ {
+				recent += t.hours[h]
+			}
+			recent /= float64(n)
+		}
+		// Without a full hour yet, the current hour's spend is the best guess
+		rate := recent
+		if full == 0 {
+			rate = t.hours[hour]
+		}
+		daily := rate * 24
+		if full >= 24 {
+			daily = baseline * 24
+		}
+
+		f := Forecast{
+			Tenant:         tenant,
+			Today:          round4(t.totals.today.USD),
+			Month:          round4(t.totals.thisMonth.USD),
+			CurrentHour:    round4(t.hours[hour]),
+			RecentHourly:   round4(recent),
+			HourlyBaseline: round4(baseline),
+			HistoryHours:   full,
+			ForecastDay:    round4(t.totals.today.USD + rate*restOfDay),
+		}
+		f.ForecastMonth = round4(t.totals.thisMonth.USD + rate*restOfDay + daily*daysLeft)
+		forecasts = append(forecasts, f)
+	}
+	return forecasts
+}
+
+// Anomalies returns the most recent spend anomalies, oldest first
+func (e *Engine) Anomalies() []Anomaly {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Anomaly(nil), e.anomalies...)
+}
+
+// round4 rounds a dollar amount to 4 decimal places
+func round4(usd float64) float64 {
+	return math.Round(usd*1e4) / 1e4
+}
@@ -0,0 +1,78 @@
+package cost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func withTenant(tenant string) context.Context {
+	return context.WithValue(context.Background(), tenantContextKey{}, tenant)
+}
+
+func TestEngine_ForecastAndAnomaly(t *testing.T) {
+	e := New(config.CostConfig{Enabled: true, Anomaly: config.SpendAnomalyConfig{
+		Enabled: true, Factor: 3, MinSpend: 1, MinHistory: 2 * time.Hour,
+	}}, pricing)
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	ctx := withTenant("acme")
+
+	// $1 an hour for 4 hours: 1M prompt tokens each
+	for i := 0; i < 4; i++ {
+		e.Record(ctx, "openai", "gpt-4o", 1_000_000, 0)
+		now = now.Add(time.Hour)
+	}
+	if a := e.Anomalies(); len(a) != 0 {
+		t.Fatalf("steady burn flagged: %+v", a)
+	}
+
+	forecasts := e.Forecasts()
+	if len(forecasts) != 1 {
+		t.Fatalf("Forecasts() = %+v", forecasts)
+	}
+	f := forecasts[0]
+	if f.Tenant != "acme" || f.Today != 4 || f.HourlyBaseline != 1 || f.RecentHourly != 1 || f.HistoryHours != 4 {
+		t.Errorf("forecast = %+v", f)
+	}
+	// 4 spent, 20 hours left at $1 an hour
+	if f.ForecastDay != 24 {
+		t.Errorf("ForecastDay = %v, want 24", f.ForecastDay)
+	}
+
+	// $4 in one hour is 4x the baseline
+	e.Record(ctx, "openai", "gpt-4o", 4_000_000, 0)
+	e.Record(ctx, "openai", "gpt-4o", 1_000_000, 0)
+	anomalies := e.Anomalies()
+	if len(anomalies) != 1 {
+		t.Fatalf("Anomalies() = %+v, want one per hour", anomalies)
+	}
+	if a := anomalies[0]; a.Tenant != "acme" || a.Spend != 4 || a.HourlyBaseline != 1 || a.Ratio != 4 {
+		t.Errorf("anomaly = %+v", a)
+	}
+
+	// Other tenants are judged on their own history
+	e.Record(withTenant("new"), "openai", "gpt-4o", 5_000_000, 0)
+	if n := len(e.Anomalies()); n != 1 {
+		t.Errorf("tenant without min_history flagged: %d anomalies", n)
+	}
+}
+
+func TestEngine_ForecastWithoutHistory(t *testing.T) {
+	e := New(config.CostConfig{Enabled: true}, pricing)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	e.Record(withTenant("acme"), "openai", "gpt-4o", 2_000_000, 0)
+
+	f := e.Forecasts()[0]
+	// The current hour's $2 is taken as the rate for the remaining 12 hours
+	if f.HistoryHours != 0 || f.ForecastDay != 26 {
+		t.Errorf("forecast = %+v", f)
+	}
+	var nilEngine *Engine
+	if nilEngine.Forecasts() != nil || nilEngine.Anomalies() != nil {
+		t.Error("nil engine should have no forecasts or anomalies")
+	}
+}
@@ -0,0 +1,65 @@
+package cost
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+type (
+	keyContextKey    struct{}
+	tenantContextKey struct{}
+)
+
+// KeyFromContext returns the key the request's spend is counted against:
+// the managed key ID, "user:<id>" for identified callers, the masked API key
+// or "anonymous"
+func KeyFromContext(ctx context.Context) string {
+	if key, ok := ctx.Value(keyContextKey{}).(string); ok {
+		return key
+	}
+	return callerKey(ctx, middleware.GetAPIKey(ctx))
+}
+
+// TenantFromContext returns the tenant the request's spend is forecast for:
+// the authenticated user, the X-Tenant-ID header, or "anonymous"
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenant
+	}
+	if userID := middleware.GetUserID(ctx); userID != "" {
+		return userID
+	}
+	return anonymous
+}
+
+// callerKey returns the key for the caller in ctx presenting apiKey
+func callerKey(ctx context.Context, apiKey string) string {
+	if id := middleware.GetKeyID(ctx); id != "" {
+		return id
+	}
+	if userID := middleware.GetUserID(ctx); userID != "" {
+		return "user:" + userID
+	}
+	if apiKey != "" {
+		return middleware.MaskAPIKey(apiKey)
+	}
+	return anonymous
+}
+
+// Middleware identifies the key and tenant each API request's spend is
+// counted against, including keys presented when authentication is off. It
+// must run after the managed key and JWT middlewares.
+func (e *Engine) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if e == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), keyContextKey{}, callerKey(r.Context(), middleware.RequestAPIKey(r)))
+			ctx = context.WithValue(ctx, tenantContextKey{}, middleware.TenantID(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
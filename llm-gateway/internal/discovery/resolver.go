@@ -0,0 +1,211 @@
+// Package discovery resolves provider endpoints from DNS SRV records, Consul or
+// the Kubernetes Endpoints API and keeps them up to date.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/kubeapi"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the discovery module logger; its level can be set via log.modules.discovery
+var logger = observability.ModuleLogger("discovery")
+
+// errNoEndpoints is returned when a service resolves to an empty endpoint list
+var errNoEndpoints = errors.New("no endpoints found")
+
+// Resolver returns the current base URLs of a service
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// NewResolver creates the resolver for a discovery configuration
+func NewResolver(cfg config.DiscoveryConfig) (Resolver, error) {
+	switch cfg.Type {
+	case "dns_srv":
+		return &dnsSRVResolver{cfg: cfg, lookupSRV: net.DefaultResolver.LookupSRV}, nil
+	case "consul":
+		return &consulResolver{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "kubernetes":
+		return newKubernetesResolver(cfg)
+	default:
+		return nil, fmt.Errorf("unknown discovery type: %s", cfg.Type)
+	}
+}
+
+// endpointURL builds a base URL from a discovered host and port
+func endpointURL(cfg config.DiscoveryConfig, host string, port int) string {
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + strings.TrimSuffix(cfg.Path, "/")
+}
+
+// dedupe sorts base URLs and removes duplicates so results can be compared
+func dedupe(urls []string) []string {
+	sort.Strings(urls)
+	out := urls[:0]
+	for i, u := range urls {
+		if i == 0 || u != urls[i-1] {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// dnsSRVResolver resolves a full SRV name such as _http._tcp.vllm.example.com
+type dnsSRVResolver struct {
+	cfg       config.DiscoveryConfig
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+func (r *dnsSRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	_, records, err := r.lookupSRV(ctx, "", "", r.cfg.Service)
+	if err != nil {
+		return nil, fmt.Errorf("SRV lookup failed: %w", err)
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, srv := range records {
+		urls = append(urls, endpointURL(r.cfg, strings.TrimSuffix(srv.Target, "."), int(srv.Port)))
+	}
+	return dedupe(urls), nil
+}
+
+// consulResolver returns the instances of a service passing their Consul health checks
+type consulResolver struct {
+	cfg    config.DiscoveryConfig
+	client *http.Client
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+func (r *consulResolver) Resolve(ctx context.Context) ([]string, error) {
+	query := url.Values{"passing": {"true"}}
+	if r.cfg.Consul.Tag != "" {
+		query.Set("tag", r.cfg.Consul.Tag)
+	}
+	endpoint := strings.TrimSuffix(r.cfg.Consul.Address, "/") + "/v1/health/service/" + url.PathEscape(r.cfg.Service) + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if r.cfg.Consul.Token != "" {
+		req.Header.Set("X-Consul-Token", r.cfg.Consul.Token)
+	}
+
+	var entries []consulServiceEntry
+	if err := getJSON(r.client, req, &entries); err != nil {
+		return nil, fmt.Errorf("consul query failed: %w", err)
+	}
+
+	urls := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		urls = append(urls, endpointURL(r.cfg, host, entry.Service.Port))
+	}
+	return dedupe(urls), nil
+}
+
+// kubernetesResolver returns the ready addresses of a Kubernetes Endpoints object
+type kubernetesResolver struct {
+	cfg    config.DiscoveryConfig
+	client *kubeapi.Client
+}
+
+type kubernetesEndpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP string `json:"ip"`
+		} `json:"addresses"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+func newKubernetesResolver(cfg config.DiscoveryConfig) (*kubernetesResolver, error) {
+	client, err := kubeapi.NewClient(cfg.Kubernetes.APIServer, cfg.Kubernetes.TokenFile, cfg.Kubernetes.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &kubernetesResolver{cfg: cfg, client: client}, nil
+}
+
+func (r *kubernetesResolver) Resolve(ctx context.Context) ([]string, error) {
+	path := fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s",
+		url.PathEscape(r.cfg.Kubernetes.Namespace), url.PathEscape(r.cfg.Service))
+
+	resp, err := r.client.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes endpoints query failed: %w", err)
+	}
+	var eps kubernetesEndpoints
+	if err := decodeJSON(resp, &eps); err != nil {
+		return nil, fmt.Errorf("kubernetes endpoints query failed: %w", err)
+	}
+
+	var urls []string
+	for _, subset := range eps.Subsets {
+		port := 0
+		for _, p := range subset.Ports {
+			if r.cfg.Kubernetes.PortName == "" || p.Name == r.cfg.Kubernetes.PortName {
+				port = p.Port
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		// Only ready addresses are listed under "addresses"
+		for _, addr := range subset.Addresses {
+			urls = append(urls, endpointURL(r.cfg, addr.IP, port))
+		}
+	}
+	return dedupe(urls), nil
+}
+
+// getJSON performs req and decodes a 200 response into v
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(resp, v)
+}
+
+// decodeJSON decodes a 200 response into v and closes the body
+func decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
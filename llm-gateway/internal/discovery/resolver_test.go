@@ -0,0 +1,140 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestDNSSRVResolver(t *testing.T) {
+	r := &dnsSRVResolver{
+		cfg: config.DiscoveryConfig{Service: "_http._tcp.vllm.example.com", Scheme: "http", Path: "/v1"},
+		lookupSRV: func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if name != "_http._tcp.vllm.example.com" {
+				t.Errorf("looked up %q", name)
+			}
+			return "", []*net.SRV{
+				{Target: "vllm-1.example.com.", Port: 8000},
+				{Target: "vllm-0.example.com.", Port: 8000},
+			}, nil
+		},
+	}
+
+	got, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	want := []string{"http://vllm-0.example.com:8000/v1", "http://vllm-1.example.com:8000/v1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
+
+func TestConsulResolver(t *testing.T) {
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/ollama" || r.URL.Query().Get("passing") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			t.Error("missing consul token")
+		}
+		w.Write([]byte(`[
+			{"Node":{"Address":"10.0.0.1"},"Service":{"Address":"","Port":11434}},
+			{"Node":{"Address":"10.0.0.9"},"Service":{"Address":"10.0.0.2","Port":11434}}
+		]`))
+	}))
+	defer consul.Close()
+
+	resolver, err := NewResolver(config.DiscoveryConfig{
+		Type:    "consul",
+		Service: "ollama",
+		Consul:  config.ConsulDiscoveryConfig{Address: consul.URL, Token: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+
+	got, err := resolver.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	want := []string{"http://10.0.0.1:11434", "http://10.0.0.2:11434"}
+	if !slices.Equal(got, want) {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
+
+func TestKubernetesResolver(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/llm/endpoints/vllm" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"subsets":[{
+			"addresses":[{"ip":"10.1.0.5"},{"ip":"10.1.0.4"}],
+			"notReadyAddresses":[{"ip":"10.1.0.6"}],
+			"ports":[{"name":"metrics","port":9090},{"name":"http","port":8000}]
+		}]}`))
+	}))
+	defer api.Close()
+
+	resolver, err := NewResolver(config.DiscoveryConfig{
+		Type:       "kubernetes",
+		Service:    "vllm",
+		Kubernetes: config.KubernetesDiscoveryConfig{APIServer: api.URL, Namespace: "llm", PortName: "http"},
+	})
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+
+	got, err := resolver.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	want := []string{"http://10.1.0.4:8000", "http://10.1.0.5:8000"}
+	if !slices.Equal(got, want) {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
+
+type fakeResolver struct {
+	endpoints []string
+	err       error
+}
+
+func (f *fakeResolver) Resolve(ctx context.Context) ([]string, error) {
+	return f.endpoints, f.err
+}
+
+func TestWatcher_KeepsLastKnownEndpoints(t *testing.T) {
+	resolver := &fakeResolver{endpoints: []string{"http://a:1", "http://b:1"}}
+	var updates [][]string
+	w := NewWatcher("ollama", resolver, 0, func(baseURLs []string) {
+		updates = append(updates, baseURLs)
+	})
+
+	w.Refresh(context.Background())
+	w.Refresh(context.Background()) // unchanged: no update
+
+	resolver.endpoints, resolver.err = nil, errors.New("consul down")
+	w.Refresh(context.Background())
+	resolver.err = nil
+	w.Refresh(context.Background()) // empty result is treated as a failure
+
+	resolver.endpoints = []string{"http://b:1", "http://c:1"}
+	w.Refresh(context.Background())
+
+	if len(updates) != 2 || !slices.Equal(updates[1], []string{"http://b:1", "http://c:1"}) {
+		t.Errorf("updates = %v", updates)
+	}
+	stats := w.Stats()
+	if stats["changes"] != int64(2) || stats["error"] != nil {
+		t.Errorf("stats = %v", stats)
+	}
+}
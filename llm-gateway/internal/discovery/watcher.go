@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Watcher periodically resolves a service and pushes endpoint list changes to
+// a provider. On resolution errors, or when no endpoints are found, the last
+// known list is kept so a discovery outage does not take the provider down.
+type Watcher struct {
+	name     string
+	resolver Resolver
+	interval time.Duration
+	update   func(baseURLs []string)
+
+	mu          sync.RWMutex
+	endpoints   []string
+	lastRefresh time.Time
+	lastErr     string
+	changes     int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatcher creates a watcher that calls update whenever the resolved endpoints of name change
+func NewWatcher(name string, resolver Resolver, interval time.Duration, update func(baseURLs []string)) *Watcher {
+	return &Watcher{
+		name:     name,
+		resolver: resolver,
+		interval: interval,
+		update:   update,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start resolves the endpoints once, so the provider is populated before
+// traffic arrives, then keeps refreshing them in the background
+func (w *Watcher) Start(ctx context.Context) {
+	w.Refresh(ctx)
+	go w.loop()
+}
+
+// Stop stops background refreshes
+func (w *Watcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+			w.Refresh(ctx)
+			cancel()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Refresh resolves the endpoints and applies them if they changed
+func (w *Watcher) Refresh(ctx context.Context) {
+	endpoints, err := w.resolver.Resolve(ctx)
+	if err == nil && len(endpoints) == 0 {
+		err = errNoEndpoints
+	}
+
+	w.mu.Lock()
+	w.lastRefresh = time.Now()
+	if err != nil {
+		if w.lastErr == "" {
+			logger.Warn().Err(err).Str("provider", w.name).Msg("Service discovery failed, keeping last known endpoints")
+		}
+		w.lastErr = err.Error()
+		w.mu.Unlock()
+		return
+	}
+	w.lastErr = ""
+	changed := !slices.Equal(endpoints, w.endpoints)
+	if changed {
+		w.endpoints = endpoints
+		w.changes++
+	}
+	w.mu.Unlock()
+
+	if changed {
+		logger.Info().Str("provider", w.name).Strs("endpoints", endpoints).Msg("Discovered provider endpoints")
+		w.update(endpoints)
+	}
+}
+
+// Stats returns the discovered endpoints and refresh status
+func (w *Watcher) Stats() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"endpoints":        append([]string(nil), w.endpoints...),
+		"refresh_interval": w.interval.String(),
+		"changes":          w.changes,
+	}
+	if !w.lastRefresh.IsZero() {
+		stats["last_refresh"] = w.lastRefresh
+	}
+	if w.lastErr != "" {
+		stats["error"] = w.lastErr
+	}
+	return stats
+}
@@ -0,0 +1,272 @@
+// Package guardrails checks prompts and completions against per-key
+// policies: denylisted patterns and keywords, a prompt length limit and a
+// moderation endpoint, blocking or redacting what fails them.
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/keys"
+	"github.com/username/llm-gateway/internal/middleware"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the guardrails module logger; its level can be set via log.modules.guardrails
+var logger = observability.ModuleLogger("guardrails")
+
+// Checks
+const (
+	CheckDenylist     = "denylist"
+	CheckPromptLength = "max_prompt_chars"
+	CheckModeration   = "moderation"
+)
+
+// Actions
+const (
+	ActionBlock  = "block"
+	ActionRedact = "redact"
+)
+
+// Directions
+const (
+	Input  = "input"
+	Output = "output"
+)
+
+// defaultReplacement replaces redacted matches unless a rule sets its own
+const defaultReplacement = "[REDACTED]"
+
+// Violation is a failed check that blocks a prompt or completion
+type Violation struct {
+	Policy    string
+	Check     string
+	Direction string
+	// Rule names the denylist rule, or the flagged moderation categories
+	Rule string
+}
+
+func (v *Violation) Error() string {
+	what := "prompt"
+	if v.Direction == Output {
+		what = "response"
+	}
+	switch v.Check {
+	case CheckPromptLength:
+		return fmt.Sprintf("The prompt exceeds the length allowed by guardrail policy %q", v.Policy)
+	case CheckModeration:
+		return fmt.Sprintf("The %s was flagged by moderation (%s) under guardrail policy %q", what, v.Rule, v.Policy)
+	}
+	return fmt.Sprintf("The %s matches denylist rule %q of guardrail policy %q", what, v.Rule, v.Policy)
+}
+
+// rule is a compiled denylist rule
+type rule struct {
+	name          string
+	re            *regexp.Regexp
+	action        string
+	input, output bool
+	replacement   string
+}
+
+// Policy is a compiled guardrail policy
+type Policy struct {
+	name           string
+	keys           []string
+	rules          []rule
+	maxPromptChars int
+	moderateInput  bool
+	moderateOutput bool
+	categories     []string
+	moderator      *moderator
+}
+
+// Name returns the policy name
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// Engine holds the guardrail policies and selects one per request
+type Engine struct {
+	policies      map[string]*Policy
+	defaultPolicy string
+}
+
+// New compiles the configured policies, or returns nil if guardrails are disabled
+func New(cfg config.GuardrailsConfig) (*Engine, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	mod := newModerator(cfg.Moderation)
+	e := &Engine{policies: make(map[string]*Policy, len(cfg.Policies)), defaultPolicy: cfg.DefaultPolicy}
+	for name, pc := range cfg.Policies {
+		p := &Policy{
+			name:           name,
+			keys:           pc.Keys,
+			maxPromptChars: pc.MaxPromptChars,
+			moderateInput:  pc.ModerateInput,
+			moderateOutput: pc.ModerateOutput,
+			categories:     pc.Categories,
+			moderator:      mod,
+		}
+		for _, rc := range pc.Denylist {
+			var parts []string
+			if rc.Pattern != "" {
+				parts = append(parts, "(?:"+rc.Pattern+")")
+			}
+			for _, keyword := range rc.Keywords {
+				parts = append(parts, "(?i:"+regexp.QuoteMeta(keyword)+")")
+			}
+			re, err := regexp.Compile(strings.Join(parts, "|"))
+			if err != nil {
+				return nil, fmt.Errorf("guardrail policy %q rule %q: %w", name, rc.Name, err)
+			}
+			r := rule{name: rc.Name, re: re, action: rc.Action, replacement: rc.Replacement}
+			r.input = rc.Apply != Output
+			r.output = rc.Apply != Input
+			if r.action == "" {
+				r.action = ActionBlock
+			}
+			if r.replacement == "" {
+				r.replacement = defaultReplacement
+			}
+			p.rules = append(p.rules, r)
+		}
+		e.policies[name] = p
+	}
+	return e, nil
+}
+
+// PolicyFor returns the policy of the caller in ctx presenting apiKey: the
+// one its managed key names, else the one listing its key ID or API key,
+// else the default policy. It returns nil if no policy applies.
+func (e *Engine) PolicyFor(ctx context.Context, apiKey string) *Policy {
+	if e == nil {
+		return nil
+	}
+	if k := keys.FromContext(ctx); k != nil && k.Guardrails != "" {
+		// Policy names are lowercase, as configuration keys are
+		if p, ok := e.policies[strings.ToLower(k.Guardrails)]; ok {
+			return p
+		}
+		logger.Warn().Str("key_id", k.ID).Str("policy", k.Guardrails).Msg("Managed key names an unknown guardrail policy, using the default")
+	}
+	keyID := middleware.GetKeyID(ctx)
+	for _, p := range e.policies {
+		if (keyID != "" && slices.Contains(p.keys, keyID)) || (apiKey != "" && slices.Contains(p.keys, apiKey)) {
+			return p
+		}
+	}
+	return e.policies[e.defaultPolicy]
+}
+
+// CheckInput checks prompt texts, redacting denylisted matches in place. It
+// returns the violation that blocks the request, if any.
+func (p *Policy) CheckInput(ctx context.Context, texts []*string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if p.maxPromptChars > 0 {
+		chars := 0
+		for _, text := range texts {
+			chars += utf8.RuneCountInString(*text)
+		}
+		if chars > p.maxPromptChars {
+			return p.violation(CheckPromptLength, Input, "", ActionBlock)
+		}
+	}
+	if v := p.denylist(texts, Input); v != nil {
+		return v
+	}
+	if p.moderateInput {
+		return p.moderate(ctx, texts, Input)
+	}
+	return nil
+}
+
+// CheckOutput checks completion texts, redacting denylisted matches in
+// place. It returns the violation that blocks the response, if any.
+func (p *Policy) CheckOutput(ctx context.Context, texts []*string) *Violation {
+	if p == nil {
+		return nil
+	}
+	if v := p.denylist(texts, Output); v != nil {
+		return v
+	}
+	if p.moderateOutput {
+		return p.moderate(ctx, texts, Output)
+	}
+	return nil
+}
+
+// ChecksOutput reports whether completions need checking under the policy
+func (p *Policy) ChecksOutput() bool {
+	if p == nil {
+		return false
+	}
+	if p.moderateOutput {
+		return true
+	}
+	for _, r := range p.rules {
+		if r.output {
+			return true
+		}
+	}
+	return false
+}
+
+// denylist applies the denylist rules for direction to texts
+func (p *Policy) denylist(texts []*string, direction string) *Violation {
+	for _, r := range p.rules {
+		if (direction == Input && !r.input) || (direction == Output && !r.output) {
+			continue
+		}
+		for _, text := range texts {
+			if !r.re.MatchString(*text) {
+				continue
+			}
+			if r.action == ActionBlock {
+				return p.violation(CheckDenylist, direction, r.name, ActionBlock)
+			}
+			*text = r.re.ReplaceAllLiteralString(*text, r.replacement)
+			p.violation(CheckDenylist, direction, r.name, ActionRedact)
+		}
+	}
+	return nil
+}
+
+// moderate sends texts to the moderation endpoint
+func (p *Policy) moderate(ctx context.Context, texts []*string, direction string) *Violation {
+	inputs := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if *text != "" {
+			inputs = append(inputs, *text)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	categories, err := p.moderator.flagged(ctx, inputs, p.categories)
+	if err != nil {
+		logger.Warn().Err(err).Str("policy", p.name).Str("direction", direction).Bool("fail_closed", p.moderator.failClosed).Msg("Moderation failed")
+		if p.moderator.failClosed {
+			return p.violation(CheckModeration, direction, "unavailable", ActionBlock)
+		}
+		return nil
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	return p.violation(CheckModeration, direction, strings.Join(categories, ", "), ActionBlock)
+}
+
+// violation counts a failed check and returns it
+func (p *Policy) violation(check, direction, rule, action string) *Violation {
+	observability.GetMetrics().RecordGuardrailViolation(p.name, check, direction, action)
+	return &Violation{Policy: p.name, Check: check, Direction: direction, Rule: rule}
+}
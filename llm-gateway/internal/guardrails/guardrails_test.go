@@ -0,0 +1,116 @@
+package guardrails
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+func newEngine(t *testing.T, moderationURL string) *Engine {
+	t.Helper()
+	e, err := New(config.GuardrailsConfig{
+		Enabled:       true,
+		DefaultPolicy: "standard",
+		Policies: map[string]config.GuardrailPolicy{
+			"standard": {
+				MaxPromptChars: 20,
+				Denylist: []config.GuardrailRule{
+					{Name: "codenames", Keywords: []string{"bluebird"}, Action: ActionBlock, Apply: Input},
+					{Name: "emails", Pattern: `[a-z]+@corp\.example`, Action: ActionRedact},
+				},
+			},
+			"strict": {
+				Keys:           []string{"key_1"},
+				ModerateInput:  true,
+				ModerateOutput: true,
+				Categories:     []string{"violence"},
+			},
+		},
+		Moderation: config.ModerationConfig{URL: moderationURL, Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestPolicy_InputChecks(t *testing.T) {
+	policy := newEngine(t, "").PolicyFor(context.Background(), "sk-anything")
+	if policy.Name() != "standard" {
+		t.Fatalf("policy = %s, want the default", policy.Name())
+	}
+
+	text := "mail bob@corp.example"
+	if v := policy.CheckInput(context.Background(), []*string{&text}); v == nil || v.Check != CheckPromptLength {
+		t.Errorf("long prompt: %v", v)
+	}
+	text = "ask bob@corp.example"
+	if v := policy.CheckInput(context.Background(), []*string{&text}); v != nil || text != "ask [REDACTED]" {
+		t.Errorf("redact: %v %q", v, text)
+	}
+	text = "Project Bluebird"
+	if v := policy.CheckInput(context.Background(), []*string{&text}); v == nil || v.Rule != "codenames" {
+		t.Errorf("keyword: %v", v)
+	}
+	// The codename rule applies to prompts only
+	if v := policy.CheckOutput(context.Background(), []*string{&text}); v != nil {
+		t.Errorf("input-only rule on output: %v", v)
+	}
+}
+
+func TestPolicy_Moderation(t *testing.T) {
+	var got moderationRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		flagged := got.Input[0] == "attack"
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"flagged": flagged, "categories": map[string]bool{"violence": flagged, "harassment": true}},
+		}})
+	}))
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), middleware.KeyIDContextKey, "key_1")
+	policy := newEngine(t, server.URL).PolicyFor(ctx, "")
+	if policy.Name() != "strict" {
+		t.Fatalf("policy = %s, want the key's", policy.Name())
+	}
+	text := "attack"
+	v := policy.CheckInput(ctx, []*string{&text})
+	if v == nil || v.Check != CheckModeration || v.Rule != "violence" {
+		t.Errorf("flagged: %v", v)
+	}
+	text = "hello"
+	if v := policy.CheckOutput(ctx, []*string{&text}); v != nil {
+		t.Errorf("not flagged: %v", v)
+	}
+
+	// Unreachable moderation lets text through unless fail_closed
+	server.Close()
+	if v := policy.CheckInput(ctx, []*string{&text}); v != nil {
+		t.Errorf("fail open: %v", v)
+	}
+	policy.moderator.failClosed = true
+	if v := policy.CheckInput(ctx, []*string{&text}); v == nil || v.Rule != "unavailable" {
+		t.Errorf("fail closed: %v", v)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	e, err := New(config.GuardrailsConfig{})
+	if e != nil || err != nil {
+		t.Fatalf("New() = %v, %v", e, err)
+	}
+	if p := e.PolicyFor(context.Background(), "sk-key"); p != nil {
+		t.Errorf("nil engine returned policy %s", p.Name())
+	}
+	text := "anything"
+	if v := (*Policy)(nil).CheckInput(context.Background(), []*string{&text}); v != nil {
+		t.Errorf("nil policy: %v", v)
+	}
+}
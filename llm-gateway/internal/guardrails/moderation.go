@@ -0,0 +1,98 @@
+package guardrails
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// moderator calls an OpenAI-compatible /v1/moderations endpoint
+type moderator struct {
+	url        string
+	apiKey     string
+	model      string
+	failClosed bool
+	client     *http.Client
+}
+
+func newModerator(cfg config.ModerationConfig) *moderator {
+	return &moderator{
+		url:        cfg.URL,
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		failClosed: cfg.FailClosed,
+		client:     &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type moderationRequest struct {
+	Input []string `json:"input"`
+	Model string   `json:"model,omitempty"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// flagged moderates inputs and returns the flagged categories, limited to
+// only if set, in name order. A result flagged without categories counts as
+// "flagged".
+func (m *moderator) flagged(ctx context.Context, inputs []string, only []string) ([]string, error) {
+	body, err := json.Marshal(moderationRequest{Input: inputs, Model: m.model})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("moderation endpoint returned %s", resp.Status)
+	}
+	var result moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode moderation response: %w", err)
+	}
+
+	found := make(map[string]bool)
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		matched := false
+		for category, hit := range r.Categories {
+			if hit && (len(only) == 0 || slices.Contains(only, category)) {
+				found[category], matched = true, true
+			}
+		}
+		if !matched && len(only) == 0 {
+			found["flagged"] = true
+		}
+	}
+	categories := make([]string, 0, len(found))
+	for category := range found {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
@@ -0,0 +1,251 @@
+// Package i18n localizes the user-facing messages of API errors. The
+// language is negotiated from the request's Accept-Language header against a
+// catalog of messages by language and error code; the error type and code
+// are left as they are, so clients can keep matching on them while showing
+// the message to their users.
+package i18n
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+// Catalog holds user-facing error messages by language and error code
+type Catalog struct {
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates a catalog of the built-in messages with the configured
+// ones added, which take precedence
+func NewCatalog(cfg config.ErrorMessagesConfig) *Catalog {
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	for _, source := range []map[string]map[string]string{messages, cfg.Messages} {
+		for lang, byCode := range source {
+			lang = strings.ToLower(lang)
+			if c.messages[lang] == nil {
+				c.messages[lang] = make(map[string]string)
+			}
+			for code, message := range byCode {
+				c.messages[lang][code] = message
+			}
+		}
+	}
+	return c
+}
+
+// Languages returns the languages the catalog has messages in, sorted
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Lookup returns the message for code in the language acceptLanguage
+// prefers most among those that have one, and that language. A regional
+// tag such as pt-BR falls back to its base language.
+func (c *Catalog) Lookup(acceptLanguage, code string) (lang, message string, ok bool) {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		for _, candidate := range []string{tag, baseLanguage(tag)} {
+			if message, ok := c.message(candidate, code); ok {
+				return candidate, message, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// message returns the message for code in lang, or the shared message of
+// its group
+func (c *Catalog) message(lang, code string) (string, bool) {
+	byCode := c.messages[lang]
+	if message, ok := byCode[code]; ok {
+		return message, true
+	}
+	if slices.Contains(modelUnavailableCodes, code) {
+		message, ok := byCode["model_unavailable"]
+		return message, ok
+	}
+	return "", false
+}
+
+// parseAcceptLanguage returns the lowercased language tags of an
+// Accept-Language header, most preferred first, without the wildcard and
+// tags refused with q=0
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// baseLanguage returns the primary subtag of a language tag, e.g. pt for pt-br
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+// Middleware replaces the message of JSON error responses with the catalog's
+// message for their code in the caller's language, setting Content-Language.
+// Requests without Accept-Language, errors the catalog has no message for,
+// and streamed or compressed responses are passed through unchanged.
+func (c *Catalog) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acceptLanguage := r.Header.Get("Accept-Language")
+			if acceptLanguage == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			lw := &localizingWriter{ResponseWriter: w, catalog: c, acceptLanguage: acceptLanguage}
+			next.ServeHTTP(lw, r)
+			lw.finish()
+		})
+	}
+}
+
+// localizingWriter holds back JSON error responses to localize their message
+type localizingWriter struct {
+	http.ResponseWriter
+	catalog        *Catalog
+	acceptLanguage string
+
+	wroteHeader bool
+	// buffering is set for an error response being held back
+	buffering bool
+	status    int
+	body      bytes.Buffer
+}
+
+func (w *localizingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status >= http.StatusBadRequest && isJSON(w.Header().Get("Content-Type")) && w.Header().Get("Content-Encoding") == "" {
+		w.buffering = true
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *localizingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends an error held back as it is: a flushed response is a stream
+func (w *localizingWriter) Flush() {
+	if w.buffering {
+		w.buffering = false
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *localizingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish sends the error held back, localized if the catalog has its message
+func (w *localizingWriter) finish() {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	if localized, lang, ok := w.localize(body); ok {
+		body = localized
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Del("Content-Length")
+		// Keep the body checksum right for callers checking it
+		if w.Header().Get(middleware.ContentSHA256Header) != "" {
+			sum := sha256.Sum256(body)
+			w.Header().Set(middleware.ContentSHA256Header, hex.EncodeToString(sum[:]))
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// localize returns body with the error message replaced, and its language
+func (w *localizingWriter) localize(body []byte) ([]byte, string, bool) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, "", false
+	}
+	var apiErr map[string]json.RawMessage
+	if err := json.Unmarshal(resp["error"], &apiErr); err != nil {
+		return nil, "", false
+	}
+	var code string
+	if json.Unmarshal(apiErr["code"], &code) != nil || code == "" {
+		// Errors written with only a type use it as their code
+		json.Unmarshal(apiErr["type"], &code)
+	}
+	lang, message, ok := w.catalog.Lookup(w.acceptLanguage, code)
+	if !ok {
+		return nil, "", false
+	}
+
+	apiErr["message"], _ = json.Marshal(message)
+	resp["error"], _ = json.Marshal(apiErr)
+	localized, err := json.Marshal(resp)
+	if err != nil {
+		return nil, "", false
+	}
+	return append(localized, '\n'), lang, true
+}
+
+// isJSON reports whether a Content-Type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
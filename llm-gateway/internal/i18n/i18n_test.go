@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestCatalog_Lookup(t *testing.T) {
+	catalog := NewCatalog(config.ErrorMessagesConfig{
+		Messages: map[string]map[string]string{
+			"pt-br": {"rate_limit_exceeded": "Você está enviando solicitações rápido demais."},
+			"es":    {"maintenance": "Estamos de mantenimiento."},
+		},
+	})
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		code           string
+		wantLang       string
+		wantMessage    string
+	}{
+		{"exact tag", "pt-BR", "rate_limit_exceeded", "pt-br", "Você está enviando solicitações rápido demais."},
+		{"regional tag falls back to base", "de-AT,de;q=0.8", "rate_limit_exceeded", "de", messages["de"]["rate_limit_exceeded"]},
+		{"quality order", "fr;q=0.5, ja;q=0.9", "quota_exceeded", "ja", messages["ja"]["quota_exceeded"]},
+		{"next language when the first lacks the code", "pt-BR, es;q=0.8", "budget_exceeded", "es", messages["es"]["budget_exceeded"]},
+		{"configured message overrides built-in", "es", "maintenance", "es", "Estamos de mantenimiento."},
+		{"model unavailable group", "zh-CN", "circuit_open", "zh", messages["zh"]["model_unavailable"]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, message, ok := catalog.Lookup(tt.acceptLanguage, tt.code)
+			if !ok || lang != tt.wantLang || message != tt.wantMessage {
+				t.Errorf("Lookup = %q, %q, %v, want %q, %q", lang, message, ok, tt.wantLang, tt.wantMessage)
+			}
+		})
+	}
+
+	for _, header := range []string{"", "*", "ko", "fr;q=0"} {
+		if _, _, ok := catalog.Lookup(header, "rate_limit_exceeded"); ok {
+			t.Errorf("Lookup(%q) should find no message", header)
+		}
+	}
+	if _, _, ok := catalog.Lookup("fr", "invalid_request"); ok {
+		t.Error("codes without a message should not be localized")
+	}
+}
+
+func TestCatalog_Middleware(t *testing.T) {
+	handler := NewCatalog(config.ErrorMessagesConfig{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": "Rate limit exceeded. Please retry after some time.",
+				"type":    "rate_limit_error",
+				"code":    "rate_limit_exceeded",
+			},
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Accept-Language", "fr-CA,fr;q=0.9,en;q=0.5")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("status and headers should be kept, got %d %v", rr.Code, rr.Header())
+	}
+	if rr.Header().Get("Content-Language") != "fr" {
+		t.Errorf("Content-Language = %q, want fr", rr.Header().Get("Content-Language"))
+	}
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Message != messages["fr"]["rate_limit_exceeded"] {
+		t.Errorf("message = %q, want the French message", resp.Error.Message)
+	}
+	if resp.Error.Type != "rate_limit_error" || resp.Error.Code != "rate_limit_exceeded" {
+		t.Errorf("type and code must not change, got %q %q", resp.Error.Type, resp.Error.Code)
+	}
+}
+
+func TestCatalog_MiddlewarePassesThrough(t *testing.T) {
+	body := `{"error":{"message":"Failed to parse request body","type":"invalid_request"}}` + "\n"
+	handler := NewCatalog(config.ErrorMessagesConfig{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Accept-Language", "de")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Body.String() != body || rr.Header().Get("Content-Language") != "" {
+		t.Errorf("an error without a catalog message should be unchanged, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+package i18n
+
+// modelUnavailableCodes are the error codes of a model that cannot serve
+// requests for now, which share one message
+var modelUnavailableCodes = []string{
+	"circuit_open",
+	"circuit_half_open",
+	"provider_draining",
+	"provider_unavailable",
+	"upstream_quota_exceeded",
+}
+
+// messages are the built-in user-facing messages by language, then by
+// error code (or error type for errors without a code)
+var messages = map[string]map[string]string{
+	"en": {
+		"rate_limit_exceeded":              "You're sending requests too quickly. Please wait a moment and try again.",
+		"concurrent_stream_limit_exceeded": "Too many responses are in progress at once. Please wait for one to finish and try again.",
+		"quota_exceeded":                   "You've reached your usage quota. Please try again later or contact your administrator.",
+		"budget_exceeded":                  "Your token budget for this period has been used up. Please try again in the next period or contact your administrator.",
+		"spend_limit_exceeded":             "Your spending limit has been reached. Please contact your administrator to raise it.",
+		"model_unavailable":                "This model is temporarily unavailable. Please try again in a few minutes.",
+		"model_not_allowed":                "This model isn't available for your account.",
+		"maintenance":                      "The service is undergoing maintenance. Please try again later.",
+	},
+	"es": {
+		"rate_limit_exceeded":              "Estás enviando solicitudes demasiado rápido. Espera un momento y vuelve a intentarlo.",
+		"concurrent_stream_limit_exceeded": "Hay demasiadas respuestas en curso a la vez. Espera a que termine alguna y vuelve a intentarlo.",
+		"quota_exceeded":                   "Has alcanzado tu cuota de uso. Vuelve a intentarlo más tarde o contacta con tu administrador.",
+		"budget_exceeded":                  "Has agotado tu presupuesto de tokens para este periodo. Vuelve a intentarlo en el próximo periodo o contacta con tu administrador.",
+		"spend_limit_exceeded":             "Se ha alcanzado tu límite de gasto. Contacta con tu administrador para aumentarlo.",
+		"model_unavailable":                "Este modelo no está disponible temporalmente. Vuelve a intentarlo en unos minutos.",
+		"model_not_allowed":                "Este modelo no está disponible para tu cuenta.",
+		"maintenance":                      "El servicio está en mantenimiento. Vuelve a intentarlo más tarde.",
+	},
+	"fr": {
+		"rate_limit_exceeded":              "Vous envoyez des requêtes trop rapidement. Veuillez patienter un instant puis réessayer.",
+		"concurrent_stream_limit_exceeded": "Trop de réponses sont en cours en même temps. Veuillez attendre qu'une se termine puis réessayer.",
+		"quota_exceeded":                   "Vous avez atteint votre quota d'utilisation. Veuillez réessayer plus tard ou contacter votre administrateur.",
+		"budget_exceeded":                  "Votre budget de jetons pour cette période est épuisé. Veuillez réessayer lors de la prochaine période ou contacter votre administrateur.",
+		"spend_limit_exceeded":             "Votre plafond de dépenses est atteint. Veuillez contacter votre administrateur pour le relever.",
+		"model_unavailable":                "Ce modèle est temporairement indisponible. Veuillez réessayer dans quelques minutes.",
+		"model_not_allowed":                "Ce modèle n'est pas disponible pour votre compte.",
+		"maintenance":                      "Le service est en maintenance. Veuillez réessayer plus tard.",
+	},
+	"de": {
+		"rate_limit_exceeded":              "Sie senden Anfragen zu schnell. Bitte warten Sie einen Moment und versuchen Sie es erneut.",
+		"concurrent_stream_limit_exceeded": "Zu viele Antworten laufen gleichzeitig. Bitte warten Sie, bis eine abgeschlossen ist, und versuchen Sie es erneut.",
+		"quota_exceeded":                   "Sie haben Ihr Nutzungskontingent erreicht. Bitte versuchen Sie es später erneut oder wenden Sie sich an Ihren Administrator.",
+		"budget_exceeded":                  "Ihr Token-Budget für diesen Zeitraum ist aufgebraucht. Bitte versuchen Sie es im nächsten Zeitraum erneut oder wenden Sie sich an Ihren Administrator.",
+		"spend_limit_exceeded":             "Ihr Ausgabenlimit ist erreicht. Bitte wenden Sie sich an Ihren Administrator, um es zu erhöhen.",
+		"model_unavailable":                "Dieses Modell ist vorübergehend nicht verfügbar. Bitte versuchen Sie es in einigen Minuten erneut.",
+		"model_not_allowed":                "Dieses Modell ist für Ihr Konto nicht verfügbar.",
+		"maintenance":                      "Der Dienst wird gerade gewartet. Bitte versuchen Sie es später erneut.",
+	},
+	"ja": {
+		"rate_limit_exceeded":              "リクエストの送信が速すぎます。しばらく待ってから再度お試しください。",
+		"concurrent_stream_limit_exceeded": "同時に処理中の応答が多すぎます。いずれかが完了してから再度お試しください。",
+		"quota_exceeded":                   "利用上限に達しました。時間をおいて再度お試しいただくか、管理者にお問い合わせください。",
+		"budget_exceeded":                  "この期間のトークン予算を使い切りました。次の期間に再度お試しいただくか、管理者にお問い合わせください。",
+		"spend_limit_exceeded":             "利用金額の上限に達しました。上限の引き上げについては管理者にお問い合わせください。",
+		"model_unavailable":                "このモデルは一時的に利用できません。数分後に再度お試しください。",
+		"model_not_allowed":                "このモデルはお使いのアカウントでは利用できません。",
+		"maintenance":                      "現在メンテナンス中です。時間をおいて再度お試しください。",
+	},
+	"zh": {
+		"rate_limit_exceeded":              "请求发送过于频繁，请稍后再试。",
+		"concurrent_stream_limit_exceeded": "同时进行的响应过多，请等待其中一个完成后再试。",
+		"quota_exceeded":                   "您已达到使用配额，请稍后再试或联系管理员。",
+		"budget_exceeded":                  "本周期的令牌预算已用完，请在下一周期再试或联系管理员。",
+		"spend_limit_exceeded":             "已达到您的支出上限，请联系管理员提高上限。",
+		"model_unavailable":                "该模型暂时不可用，请几分钟后再试。",
+		"model_not_allowed":                "您的账户无法使用该模型。",
+		"maintenance":                      "服务正在维护中，请稍后再试。",
+	},
+}
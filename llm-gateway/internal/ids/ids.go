@@ -0,0 +1,64 @@
+// Package ids generates the short IDs of responses, stream chunks and tool
+// calls. They only need to tell a client's responses apart, so besides
+// random IDs cut from a UUIDv4 they can be a counter scrambled with a
+// per-process random key, which costs an atomic increment instead of reading
+// crypto/rand.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// Formats of the IDs New returns
+const (
+	// FormatUUID is the first 8 hex characters of a UUIDv4
+	FormatUUID = "uuid"
+	// FormatSequential is a counter scrambled with a per-process key, as 16
+	// hex characters. The IDs of one process are unique and do not reveal
+	// how many came before; those of other instances and restarts, whose
+	// keys differ, are not guaranteed to be.
+	FormatSequential = "sequential"
+)
+
+var (
+	sequential atomic.Bool
+	counter    atomic.Uint64
+	// key scrambles the counter, so that each process numbers its IDs in
+	// its own order
+	key = newKey()
+)
+
+// newKey returns a random 64-bit key
+func newKey() uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// scramble maps n one-to-one onto 64 bits with the splitmix64 finalizer
+// after adding the key, so consecutive counters give unrelated IDs
+func scramble(n uint64) uint64 {
+	n += key
+	n = (n ^ (n >> 30)) * 0xbf58476d1ce4e5b9
+	n = (n ^ (n >> 27)) * 0x94d049bb133111eb
+	return n ^ (n >> 31)
+}
+
+// SetFormat selects the format of the IDs New returns; formats other than
+// sequential select uuid
+func SetFormat(format string) {
+	sequential.Store(format == FormatSequential)
+}
+
+// New returns an ID in the selected format
+func New() string {
+	if sequential.Load() {
+		return fmt.Sprintf("%016x", scramble(counter.Add(1)))
+	}
+	return uuid.New().String()[:8]
+}
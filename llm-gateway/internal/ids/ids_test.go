@@ -0,0 +1,31 @@
+package ids
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	defer SetFormat(FormatUUID)
+
+	if id := New(); len(id) != 8 {
+		t.Errorf("uuid ID = %q, want 8 characters", id)
+	}
+
+	SetFormat(FormatSequential)
+	seen := make(map[string]bool)
+	shared := 0
+	previous := ""
+	for range 1000 {
+		id := New()
+		if len(id) != 16 || seen[id] {
+			t.Fatalf("sequential ID %q: want 16 characters, not seen before", id)
+		}
+		seen[id] = true
+		// Consecutive IDs should not read as a count
+		if previous != "" && id[:8] == previous[:8] {
+			shared++
+		}
+		previous = id
+	}
+	if shared > 0 {
+		t.Errorf("%d consecutive sequential IDs share their first 8 characters", shared)
+	}
+}
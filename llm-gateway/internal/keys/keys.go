@@ -0,0 +1,525 @@
+// Package keys manages client API keys. Each key identifies a tenant and
+// carries per-minute and per-day request and token quotas, the models it may
+// use, an optional rate limit tier and an expiry. Keys live in a pluggable
+// store (a JSON file, SQLite or Redis) and are managed through the admin API;
+// only a hash of each secret is stored. Quota counters are kept per replica.
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the keys module logger; its level can be set via log.modules.keys
+var logger = observability.ModuleLogger("keys")
+
+// secretPrefix starts every generated secret, so leaked keys are easy to spot
+const secretPrefix = "gw-"
+
+var (
+	// ErrNotFound is returned for a key ID or secret that does not exist
+	ErrNotFound = errors.New("api key not found")
+	// ErrInvalid is returned for invalid key settings
+	ErrInvalid = errors.New("invalid api key")
+	// ErrExists is returned when importing a secret that is already in use
+	ErrExists = errors.New("api key already exists")
+	// ErrExpired is returned when authenticating with an expired key
+	ErrExpired = errors.New("api key expired")
+	// ErrDisabled is returned when authenticating with a disabled key
+	ErrDisabled = errors.New("api key disabled")
+)
+
+// Quota limits a key's requests and tokens per minute and per UTC day; zero
+// means unlimited
+type Quota struct {
+	RequestsPerMinute int64 `json:"requests_per_minute,omitempty"`
+	RequestsPerDay    int64 `json:"requests_per_day,omitempty"`
+	TokensPerMinute   int64 `json:"tokens_per_minute,omitempty"`
+	TokensPerDay      int64 `json:"tokens_per_day,omitempty"`
+}
+
+// Key is a managed API key. Keys are never modified in place: updates
+// replace them, so a *Key handed out stays consistent.
+type Key struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Tenant is the caller the key identifies for usage, budgets and logs
+	Tenant string `json:"tenant"`
+	// Hash is the SHA-256 of the secret; the secret itself is not kept
+	Hash string `json:"hash"`
+	// Hint is the start of the secret, to recognize the key in listings
+	Hint  string `json:"hint"`
+	Quota Quota  `json:"quota"`
+	// Models are the models the key may use, as exact names or globs such
+	// as "gpt-4o*"; empty allows every model
+	Models []string `json:"models,omitempty"`
+	// Tier selects a rate_limit.tiers entry and Guardrails a
+	// guardrails.policies entry for the key's requests
+	Tier       string     `json:"tier,omitempty"`
+	Guardrails string     `json:"guardrails,omitempty"`
+	Disabled   bool       `json:"disabled,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// AllowsModel reports whether the key may use model; a nil key allows every model
+func (k *Key) AllowsModel(model string) bool {
+	if k == nil || len(k.Models) == 0 {
+		return true
+	}
+	for _, pattern := range k.Models {
+		if ok, _ := path.Match(pattern, model); ok || pattern == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at now
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Settings are the fields of a key set on creation and update. Nil fields
+// are left unchanged by an update.
+type Settings struct {
+	Name   *string  `json:"name"`
+	Tenant *string  `json:"tenant"`
+	Quota  *Quota   `json:"quota"`
+	Models []string `json:"models"`
+	Tier   *string  `json:"tier"`
+	// Guardrails set to "" returns the key to the policy chosen by config
+	Guardrails *string `json:"guardrails"`
+	Disabled   *bool   `json:"disabled"`
+	// ExpiresAt set to the zero time removes the expiry
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// apply sets the non-nil settings on k
+func (s Settings) apply(k *Key) {
+	if s.Name != nil {
+		k.Name = *s.Name
+	}
+	if s.Tenant != nil {
+		k.Tenant = *s.Tenant
+	}
+	if s.Quota != nil {
+		k.Quota = *s.Quota
+	}
+	if s.Models != nil {
+		k.Models = s.Models
+	}
+	if s.Tier != nil {
+		k.Tier = *s.Tier
+	}
+	if s.Guardrails != nil {
+		k.Guardrails = *s.Guardrails
+	}
+	if s.Disabled != nil {
+		k.Disabled = *s.Disabled
+	}
+	if s.ExpiresAt != nil {
+		k.ExpiresAt = s.ExpiresAt
+		if s.ExpiresAt.IsZero() {
+			k.ExpiresAt = nil
+		}
+	}
+}
+
+// validate checks a key's settings
+func validate(k *Key) error {
+	if strings.TrimSpace(k.Tenant) == "" {
+		return fmt.Errorf("%w: tenant is required", ErrInvalid)
+	}
+	q := k.Quota
+	if q.RequestsPerMinute < 0 || q.RequestsPerDay < 0 || q.TokensPerMinute < 0 || q.TokensPerDay < 0 {
+		return fmt.Errorf("%w: quotas must not be negative", ErrInvalid)
+	}
+	for _, pattern := range k.Models {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("%w: bad model pattern %q", ErrInvalid, pattern)
+		}
+	}
+	return nil
+}
+
+// Usage is a key's consumption in the current minute and UTC day on this replica
+type Usage struct {
+	RequestsThisMinute int64 `json:"requests_this_minute"`
+	RequestsToday      int64 `json:"requests_today"`
+	TokensThisMinute   int64 `json:"tokens_this_minute"`
+	TokensToday        int64 `json:"tokens_today"`
+	// TokensReserved is held by running streams (see Reserve) and counts
+	// against both token quotas until they end
+	TokensReserved int64 `json:"tokens_reserved"`
+}
+
+// counters are a key's usage with the windows they belong to
+type counters struct {
+	minute time.Time
+	day    time.Time
+	Usage
+}
+
+// roll starts fresh counters for windows that have passed
+func (c *counters) roll(now time.Time) {
+	if minute := now.Truncate(time.Minute); !minute.Equal(c.minute) {
+		c.minute = minute
+		c.RequestsThisMinute, c.TokensThisMinute = 0, 0
+	}
+	if day := now.UTC().Truncate(24 * time.Hour); !day.Equal(c.day) {
+		c.day = day
+		c.RequestsToday, c.TokensToday = 0, 0
+	}
+}
+
+// QuotaError is returned by Admit for a key over one of its quotas
+type QuotaError struct {
+	// Quota is the exceeded quota, e.g. "tokens_per_day"
+	Quota string
+	Limit int64
+	// RetryAfter is when the quota's window resets
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("api key quota %s exceeded (limit %d)", e.Quota, e.Limit)
+}
+
+// Manager holds the keys of a store in memory for authentication and counts
+// their usage against their quotas
+type Manager struct {
+	store   Store
+	refresh time.Duration
+	now     func() time.Time
+	// reservation configures the reservations of streams
+	reservation config.StreamReservationConfig
+
+	mu     sync.RWMutex
+	keys   map[string]*Key
+	byHash map[string]*Key
+	usage  map[string]*counters
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a manager from configuration and loads its keys, or returns
+// nil if managed keys are disabled
+func New(cfg config.APIKeysConfig) (*Manager, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	store, err := newStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m := NewWithStore(store, cfg.RefreshInterval)
+	m.reservation = cfg.StreamReservation
+	if err := m.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	logger.Info().Str("store", cfg.Store).Int("keys", len(m.keys)).Msg("Loaded API keys")
+	return m, nil
+}
+
+// NewWithStore creates a manager over store, reloading it every refresh once
+// started. Call Reload to load the keys.
+func NewWithStore(store Store, refresh time.Duration) *Manager {
+	return &Manager{
+		store:   store,
+		refresh: refresh,
+		now:     time.Now,
+		keys:    make(map[string]*Key),
+		byHash:  make(map[string]*Key),
+		usage:   make(map[string]*counters),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start begins reloading the keys from the store periodically
+func (m *Manager) Start() {
+	if m == nil {
+		return
+	}
+	go m.loop()
+}
+
+// Stop stops reloading and closes the store
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+	if err := m.store.Close(); err != nil {
+		logger.Warn().Err(err).Msg("Failed to close API key store")
+	}
+}
+
+func (m *Manager) loop() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), m.refresh)
+			if err := m.Reload(ctx); err != nil {
+				// Keep serving the keys last loaded until the store recovers
+				logger.Error().Err(err).Msg("Failed to reload API keys")
+			}
+			cancel()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Reload replaces the keys in memory with those in the store
+func (m *Manager) Reload(ctx context.Context) error {
+	list, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*Key, len(list))
+	byHash := make(map[string]*Key, len(list))
+	for i := range list {
+		k := &list[i]
+		keys[k.ID] = k
+		byHash[k.Hash] = k
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys, m.byHash = keys, byHash
+	for id := range m.usage {
+		if _, ok := keys[id]; !ok {
+			delete(m.usage, id)
+		}
+	}
+	return nil
+}
+
+// Authenticate returns the key a secret belongs to. Unknown secrets return
+// ErrNotFound; expired and disabled keys return the key with ErrExpired or
+// ErrDisabled.
+func (m *Manager) Authenticate(secret string) (*Key, error) {
+	m.mu.RLock()
+	k, ok := m.byHash[hashSecret(secret)]
+	m.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return nil, ErrNotFound
+	case k.Disabled:
+		return k, ErrDisabled
+	case k.Expired(m.now()):
+		return k, ErrExpired
+	}
+	return k, nil
+}
+
+// Admit counts a request against a key's quotas. It returns a *QuotaError,
+// without counting the request, if the key has used up a request quota or
+// reached a token quota with its used and reserved tokens.
+func (m *Manager) Admit(k *Key) error {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.counters(k.ID)
+	c.roll(now)
+	q := k.Quota
+	nextMinute := c.minute.Add(time.Minute).Sub(now)
+	nextDay := c.day.Add(24 * time.Hour).Sub(now)
+	switch {
+	case q.RequestsPerMinute > 0 && c.RequestsThisMinute >= q.RequestsPerMinute:
+		return &QuotaError{Quota: "requests_per_minute", Limit: q.RequestsPerMinute, RetryAfter: nextMinute}
+	case q.RequestsPerDay > 0 && c.RequestsToday >= q.RequestsPerDay:
+		return &QuotaError{Quota: "requests_per_day", Limit: q.RequestsPerDay, RetryAfter: nextDay}
+	case q.TokensPerMinute > 0 && c.TokensThisMinute+c.TokensReserved >= q.TokensPerMinute:
+		return &QuotaError{Quota: "tokens_per_minute", Limit: q.TokensPerMinute, RetryAfter: nextMinute}
+	case q.TokensPerDay > 0 && c.TokensToday+c.TokensReserved >= q.TokensPerDay:
+		return &QuotaError{Quota: "tokens_per_day", Limit: q.TokensPerDay, RetryAfter: nextDay}
+	}
+	c.RequestsThisMinute++
+	c.RequestsToday++
+	return nil
+}
+
+// AddTokens counts tokens a key's request used. Token quotas are checked
+// before each request, so the request reaching a quota completes and the
+// following ones are rejected.
+func (m *Manager) AddTokens(id string, tokens int64) {
+	if tokens <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters(id)
+	c.roll(m.now())
+	c.TokensThisMinute += tokens
+	c.TokensToday += tokens
+}
+
+// Usage returns a key's consumption in the current windows
+func (m *Manager) Usage(id string) Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.usage[id]
+	if !ok {
+		return Usage{}
+	}
+	c.roll(m.now())
+	return c.Usage
+}
+
+// counters returns a key's counters, creating them; callers hold m.mu
+func (m *Manager) counters(id string) *counters {
+	c, ok := m.usage[id]
+	if !ok {
+		c = &counters{}
+		m.usage[id] = c
+	}
+	return c
+}
+
+// List returns the keys sorted by ID
+func (m *Manager) List() []Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]Key, 0, len(m.keys))
+	for _, k := range m.keys {
+		list = append(list, *k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Get returns a key by ID
+func (m *Manager) Get(id string) (Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return *k, nil
+}
+
+// Create adds a key with settings and returns it with its secret, which is
+// not retrievable later. An existing secret can be imported by passing it;
+// otherwise one is generated.
+func (m *Manager) Create(ctx context.Context, settings Settings, secret string) (Key, string, error) {
+	if secret == "" {
+		secret = secretPrefix + randomHex(24)
+	}
+	now := m.now().UTC()
+	k := &Key{
+		ID:        "key_" + randomHex(8),
+		Hash:      hashSecret(secret),
+		Hint:      secret[:min(len(secret), len(secretPrefix)+4)] + "...",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	settings.apply(k)
+	if err := validate(k); err != nil {
+		return Key{}, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byHash[k.Hash]; ok {
+		return Key{}, "", ErrExists
+	}
+	if err := m.store.Put(ctx, *k); err != nil {
+		return Key{}, "", err
+	}
+	m.keys[k.ID] = k
+	m.byHash[k.Hash] = k
+	return *k, secret, nil
+}
+
+// Update changes a key's settings
+func (m *Manager) Update(ctx context.Context, id string, settings Settings) (Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	k := *old
+	settings.apply(&k)
+	k.UpdatedAt = m.now().UTC()
+	if err := validate(&k); err != nil {
+		return Key{}, err
+	}
+	if err := m.store.Put(ctx, k); err != nil {
+		return Key{}, err
+	}
+	m.keys[id] = &k
+	m.byHash[k.Hash] = &k
+	return k, nil
+}
+
+// Delete removes a key
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := m.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	delete(m.keys, id)
+	delete(m.byHash, k.Hash)
+	delete(m.usage, id)
+	return nil
+}
+
+// hashSecret returns the hex SHA-256 of a secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("keys: crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// defaultManager is the process-wide manager used by the middleware, handlers and admin endpoints
+var defaultManager atomic.Pointer[Manager]
+
+// SetDefault sets the process-wide manager
+func SetDefault(m *Manager) {
+	defaultManager.Store(m)
+}
+
+// Default returns the process-wide manager (nil when managed keys are disabled)
+func Default() *Manager {
+	return defaultManager.Load()
+}
@@ -0,0 +1,206 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+func newTestManager(t *testing.T) (*Manager, *time.Time) {
+	t.Helper()
+	m := NewWithStore(NewFileStore(filepath.Join(t.TempDir(), "keys.json")), time.Minute)
+	now := time.Date(2026, 3, 2, 10, 0, 30, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestManager_CreateAuthenticatePersist(t *testing.T) {
+	m, now := newTestManager(t)
+	ctx := context.Background()
+
+	if _, _, err := m.Create(ctx, Settings{}, ""); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Create() without tenant error = %v, want ErrInvalid", err)
+	}
+	k, secret, err := m.Create(ctx, Settings{Tenant: ptr("acme"), Models: []string{"gpt-4o*"}, ExpiresAt: ptr(now.Add(time.Hour))}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Hash == hashSecret("") || k.Hint != secret[:7]+"..." {
+		t.Errorf("key = %+v", k)
+	}
+
+	got, err := m.Authenticate(secret)
+	if err != nil || got.ID != k.ID || got.Tenant != "acme" {
+		t.Fatalf("Authenticate() = %+v, %v", got, err)
+	}
+	if !got.AllowsModel("gpt-4o-mini") || got.AllowsModel("claude-3-5-haiku-20241022") {
+		t.Error("model globs not applied")
+	}
+	if _, err := m.Authenticate("gw-unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown secret error = %v", err)
+	}
+	if _, _, err := m.Create(ctx, Settings{Tenant: ptr("other")}, secret); !errors.Is(err, ErrExists) {
+		t.Errorf("importing a used secret error = %v", err)
+	}
+
+	// A second manager on the same store sees the key and its updates
+	other := NewWithStore(m.store, time.Minute)
+	if _, err := m.Update(ctx, k.ID, Settings{Disabled: ptr(true)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Authenticate(secret); !errors.Is(err, ErrDisabled) {
+		t.Errorf("disabled key error = %v", err)
+	}
+
+	m.Update(ctx, k.ID, Settings{Disabled: ptr(false)})
+	*now = now.Add(2 * time.Hour)
+	if _, err := m.Authenticate(secret); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired key error = %v", err)
+	}
+	// The zero time removes the expiry
+	m.Update(ctx, k.ID, Settings{ExpiresAt: &time.Time{}})
+	if _, err := m.Authenticate(secret); err != nil {
+		t.Errorf("key without expiry error = %v", err)
+	}
+
+	if err := m.Delete(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	other.Reload(ctx)
+	if len(other.List()) != 0 {
+		t.Errorf("keys after delete = %+v", other.List())
+	}
+}
+
+func TestManager_Quotas(t *testing.T) {
+	m, now := newTestManager(t)
+	k, _, err := m.Create(context.Background(), Settings{
+		Tenant: ptr("acme"),
+		Quota:  &Quota{RequestsPerMinute: 2, TokensPerDay: 100},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Admit(&k); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	var quotaErr *QuotaError
+	if err := m.Admit(&k); !errors.As(err, &quotaErr) || quotaErr.Quota != "requests_per_minute" || quotaErr.RetryAfter != 30*time.Second {
+		t.Fatalf("third request error = %v", err)
+	}
+
+	// A new minute resets the request count; the day's tokens carry over
+	*now = now.Add(time.Minute)
+	m.AddTokens(k.ID, 120)
+	if err := m.Admit(&k); !errors.As(err, &quotaErr) || quotaErr.Quota != "tokens_per_day" {
+		t.Fatalf("request over token quota error = %v", err)
+	}
+	if usage := m.Usage(k.ID); usage.RequestsToday != 2 || usage.TokensToday != 120 || usage.RequestsThisMinute != 0 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if err := m.Admit(&k); err != nil {
+		t.Errorf("request on the next day: %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m, _ := newTestManager(t)
+	ctx := context.Background()
+	_, secret, _ := m.Create(ctx, Settings{Tenant: ptr("acme"), Tier: ptr("premium"), Quota: &Quota{TokensPerMinute: 10}}, "")
+	disabled, disabledSecret, _ := m.Create(ctx, Settings{Tenant: ptr("acme"), Disabled: ptr(true)}, "")
+
+	var seen http.Header
+	handler := m.Middleware()(m.Enforce(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = http.Header{
+			"Tenant": {middleware.TenantID(r)},
+			"Tier":   {middleware.GetRateLimitTier(r.Context())},
+			"Key":    {middleware.GetKeyID(r.Context())},
+		}
+		middleware.AddTokenUsage(r.Context(), 8, 4)
+	})))
+	do := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	if rr := do(secret); rr.Code != http.StatusOK || seen.Get("Tenant") != "acme" || seen.Get("Tier") != "premium" || seen.Get("Key") == "" {
+		t.Fatalf("valid key: %d, context %v", rr.Code, seen)
+	}
+	// The first request used 12 tokens, over the per-minute quota
+	if rr := do(secret); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "30" {
+		t.Errorf("over quota: %d %s", rr.Code, rr.Body)
+	}
+	if rr := do(disabledSecret); rr.Code != http.StatusUnauthorized {
+		t.Errorf("disabled key %s: %d", disabled.ID, rr.Code)
+	}
+	if rr := do(""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("missing key with required: %d", rr.Code)
+	}
+	// Unknown keys are not rejected by Middleware, so other credentials pass through it
+	if rr := do("some-admin-key"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key on an API route: %d", rr.Code)
+	}
+}
+
+func TestManager_StreamReservation(t *testing.T) {
+	m, _ := newTestManager(t)
+	m.reservation = config.StreamReservationConfig{Enabled: true, DefaultCompletionTokens: 100, Margin: 0.5, NearLimit: 0.9}
+	k, _, _ := m.Create(context.Background(), Settings{Tenant: ptr("acme"), Quota: &Quota{TokensPerDay: 1000}}, "")
+	unlimited, _, _ := m.Create(context.Background(), Settings{Tenant: ptr("acme")}, "")
+
+	if r := m.Reserve(&unlimited, 10, 0); r != nil {
+		t.Errorf("reservation for a key without token quotas = %+v", r)
+	}
+	r := m.Reserve(&k, 20, 0)
+	if r.Tokens() != 120 || m.Usage(k.ID).TokensReserved != 120 {
+		t.Fatalf("reserved %d, usage %+v", r.Tokens(), m.Usage(k.ID))
+	}
+
+	// Within the margin, or over it far from the quota, the stream goes on
+	if r.Exceeded(170) || r.Exceeded(200) {
+		t.Error("stream aborted away from the quota")
+	}
+	// Reservations count against the quota, so other streams bring the key near it
+	other := m.Reserve(&k, 0, 700)
+	if !r.Exceeded(200) {
+		t.Error("stream over its reservation near the quota not aborted")
+	}
+	if err := m.Admit(&k); err != nil {
+		t.Errorf("request under the quota: %v", err)
+	}
+	m.Reserve(&k, 0, 200)
+	var quotaErr *QuotaError
+	if err := m.Admit(&k); !errors.As(err, &quotaErr) || quotaErr.Quota != "tokens_per_day" {
+		t.Errorf("request with the quota reserved: %v", err)
+	}
+
+	// Releasing twice returns the tokens once
+	other.Release()
+	other.Release()
+	r.Release()
+	if usage := m.Usage(k.ID); usage.TokensReserved != 200 {
+		t.Errorf("reserved after release = %d, want 200", usage.TokensReserved)
+	}
+}
@@ -0,0 +1,130 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/username/llm-gateway/internal/middleware"
+)
+
+type keyContextKey struct{}
+
+// authErrorContextKey holds why a presented managed key was rejected
+type authErrorContextKey struct{}
+
+// FromContext returns the managed key the request was authenticated with, or nil
+func FromContext(ctx context.Context) *Key {
+	k, _ := ctx.Value(keyContextKey{}).(*Key)
+	return k
+}
+
+// Middleware identifies callers presenting a managed key: the key, its ID,
+// its tenant (as the user ID) and its rate limit tier are added to the
+// request context. It must run before abuse detection and rate limiting. It
+// never rejects a request itself, as other credentials such as admin keys
+// use the same headers; Enforce does that on the API routes.
+func (m *Manager) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := middleware.RequestAPIKey(r)
+			if secret == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			k, err := m.Authenticate(secret)
+			if errors.Is(err, ErrNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				logger.Warn().
+					Err(err).
+					Str("key_id", k.ID).
+					Str("ip", r.RemoteAddr).
+					Msg("Rejected API key")
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authErrorContextKey{}, err)))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), keyContextKey{}, k)
+			ctx = context.WithValue(ctx, middleware.APIKeyContextKey, secret)
+			ctx = context.WithValue(ctx, middleware.KeyIDContextKey, k.ID)
+			ctx = context.WithValue(ctx, middleware.UserIDContextKey, k.Tenant)
+			if k.Tier != "" {
+				ctx = context.WithValue(ctx, middleware.RateLimitTierContextKey, k.Tier)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Enforce returns a middleware for the API routes. It rejects requests
+// presenting an expired or disabled key, and requests over their key's
+// quotas; with required, it also rejects requests without a managed key
+// unless a JWT access token identified the caller. The tokens of admitted
+// requests are counted against their key's token quotas.
+func (m *Manager) Enforce(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := FromContext(r.Context())
+			if k == nil {
+				if err, ok := r.Context().Value(authErrorContextKey{}).(error); ok {
+					writeError(w, http.StatusUnauthorized, "invalid_api_key", "Invalid API key: "+err.Error())
+					return
+				}
+				if required && middleware.GetUserID(r.Context()) == "" {
+					writeError(w, http.StatusUnauthorized, "missing_api_key", "API key is required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := m.Admit(k); err != nil {
+				var quotaErr *QuotaError
+				if errors.As(err, &quotaErr) {
+					logger.Warn().
+						Str("key_id", k.ID).
+						Str("tenant", k.Tenant).
+						Str("quota", quotaErr.Quota).
+						Msg("API key quota exceeded")
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+				}
+				writeError(w, http.StatusTooManyRequests, "quota_exceeded", err.Error())
+				return
+			}
+
+			tokens := new(atomic.Int64)
+			next.ServeHTTP(w, r.WithContext(middleware.WithTokenCounter(r.Context(), tokens)))
+			m.AddTokens(k.ID, tokens.Load())
+		})
+	}
+}
+
+// writeError writes an OpenAI-style error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	errType := "authentication_error"
+	if status == http.StatusTooManyRequests {
+		errType = "rate_limit_error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+			"code":    code,
+		},
+	})
+}
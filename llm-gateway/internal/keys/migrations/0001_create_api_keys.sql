@@ -0,0 +1,2 @@
+-- API keys, one row per key with the key as JSON
+CREATE TABLE IF NOT EXISTS api_keys (id TEXT PRIMARY KEY, data TEXT NOT NULL);
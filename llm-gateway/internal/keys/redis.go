@@ -0,0 +1,62 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// RedisStore keeps keys in a Redis hash, one field per key ID with the key
+// as JSON, so replicas share keys and each write touches only its own field.
+// It dials a connection per call, as key changes and reloads are infrequent.
+type RedisStore struct {
+	client *redisconn.Client
+	hash   string
+}
+
+// NewRedisStore creates a store in the hash named hash
+func NewRedisStore(cfg config.RedisConfig, hash string) *RedisStore {
+	return &RedisStore{client: redisconn.New(cfg.Options()), hash: hash}
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]Key, error) {
+	reply, err := s.client.Do(ctx, "HGETALL", s.hash)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected HGETALL reply %v", reply)
+	}
+
+	var list []Key
+	// The reply alternates field names and values
+	for i := 1; i < len(fields); i += 2 {
+		data, _ := fields[i].(string)
+		var k Key
+		if err := json.Unmarshal([]byte(data), &k); err != nil {
+			return nil, fmt.Errorf("failed to decode api key %v: %w", fields[i-1], err)
+		}
+		list = append(list, k)
+	}
+	return list, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, k Key) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do(ctx, "HSET", s.hash, k.ID, string(data))
+	return err
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Do(ctx, "HDEL", s.hash, id)
+	return err
+}
+
+func (s *RedisStore) Close() error { return s.client.Close() }
@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"sync/atomic"
+)
+
+// Reservation holds the estimated tokens of a stream against its key's token
+// quotas while the stream runs, so concurrent streams cannot together blow a
+// quota that each was admitted under. Its methods are no-ops on nil.
+type Reservation struct {
+	m        *Manager
+	key      *Key
+	tokens   int64
+	released atomic.Bool
+}
+
+// Reserve reserves a stream's prompt tokens and its completion, maxTokens or
+// stream_reservation.default_completion_tokens if zero, for key k. It
+// returns nil if reservations are disabled or k has no token quota.
+func (m *Manager) Reserve(k *Key, promptTokens, maxTokens int64) *Reservation {
+	if m == nil || k == nil || !m.reservation.Enabled {
+		return nil
+	}
+	if k.Quota.TokensPerMinute == 0 && k.Quota.TokensPerDay == 0 {
+		return nil
+	}
+	if maxTokens <= 0 {
+		maxTokens = int64(m.reservation.DefaultCompletionTokens)
+	}
+	r := &Reservation{m: m, key: k, tokens: promptTokens + maxTokens}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters(k.ID)
+	c.roll(m.now())
+	c.TokensReserved += r.tokens
+	return r
+}
+
+// Tokens returns the reserved tokens
+func (r *Reservation) Tokens() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.tokens
+}
+
+// Exceeded reports whether a stream that has used tokens so far must be
+// aborted: it ran past its reservation plus margin while its key's used and
+// reserved tokens, with the overrun, reach near_limit of a token quota
+func (r *Reservation) Exceeded(used int64) bool {
+	if r == nil || float64(used) <= float64(r.tokens)*(1+r.m.reservation.Margin) {
+		return false
+	}
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters(r.key.ID)
+	c.roll(m.now())
+
+	held := c.TokensReserved + used - r.tokens
+	near := func(windowTokens, limit int64) bool {
+		return limit > 0 && float64(windowTokens+held) >= m.reservation.NearLimit*float64(limit)
+	}
+	return near(c.TokensThisMinute, r.key.Quota.TokensPerMinute) || near(c.TokensToday, r.key.Quota.TokensPerDay)
+}
+
+// Release returns the reserved tokens once the stream ends. The tokens it
+// actually used are counted by Enforce, which reconciles the reservation.
+func (r *Reservation) Release() {
+	if r == nil || !r.released.CompareAndSwap(false, true) {
+		return
+	}
+	m := r.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.usage[r.key.ID]; ok {
+		c.TokensReserved = max(c.TokensReserved-r.tokens, 0)
+	}
+}
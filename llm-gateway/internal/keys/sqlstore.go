@@ -0,0 +1,87 @@
+package keys
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/username/llm-gateway/internal/migrate"
+)
+
+// sqliteDriver is the database/sql driver name of the sqlite store. The
+// gateway does not link a driver itself: builds using the sqlite store
+// register one under this name, e.g. with a blank import of modernc.org/sqlite.
+const sqliteDriver = "sqlite"
+
+// migrations are the schema changes of the keys database, applied by
+// NewSQLStore
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// SQLStore keeps keys in a SQLite table, one row per key with the key as
+// JSON. Replicas on the same database share keys; each write touches only
+// its own row.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore opens the database dsn with driver and applies its pending
+// migrations
+func NewSQLStore(driver, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open api key database: %w", err)
+	}
+	list, err := migrate.Load(migrations, "migrations")
+	if err == nil {
+		err = migrate.Run(context.Background(), db, "keys", list, migrate.DefaultOptions())
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate api key database: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) List(ctx context.Context) ([]Key, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Key
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var k Key
+		if err := json.Unmarshal([]byte(data), &k); err != nil {
+			return nil, fmt.Errorf("failed to decode api key: %w", err)
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+func (s *SQLStore) Put(ctx context.Context, k Key) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT OR REPLACE INTO api_keys (id, data) VALUES (?, ?)`, k.ID, string(data))
+	return err
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
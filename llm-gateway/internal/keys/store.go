@@ -0,0 +1,126 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// Store persists keys. Replicas sharing a store see each other's changes on
+// their next reload.
+type Store interface {
+	// List returns every key
+	List(ctx context.Context) ([]Key, error)
+	// Put adds or replaces a key by ID
+	Put(ctx context.Context, k Key) error
+	// Delete removes a key; deleting a missing key is not an error
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+// newStore creates the store configured by api_keys.store
+func newStore(cfg config.APIKeysConfig) (Store, error) {
+	switch cfg.Store {
+	case "file":
+		return NewFileStore(cfg.Path), nil
+	case "sqlite":
+		return NewSQLStore(sqliteDriver, cfg.Path)
+	case "redis":
+		return NewRedisStore(cfg.Redis, cfg.RedisKey), nil
+	default:
+		return nil, fmt.Errorf("unknown api key store %q", cfg.Store)
+	}
+}
+
+// FileStore keeps keys in a JSON file, rewritten via a temporary file on
+// every change so a crash never leaves a partial file. It suits a single
+// replica; replicas sharing the file over a network volume may lose
+// concurrent changes.
+type FileStore struct {
+	file string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a store in file, which is created on the first change
+func NewFileStore(file string) *FileStore {
+	return &FileStore{file: file}
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *FileStore) Put(ctx context.Context, k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.read()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range list {
+		if list[i].ID == k.ID {
+			list[i], replaced = k, true
+		}
+	}
+	if !replaced {
+		list = append(list, k)
+	}
+	return s.write(list)
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := list[:0]
+	for _, k := range list {
+		if k.ID != id {
+			kept = append(kept, k)
+		}
+	}
+	return s.write(kept)
+}
+
+func (s *FileStore) Close() error { return nil }
+
+// read loads the file; a missing file holds no keys. Callers hold s.mu.
+func (s *FileStore) read() ([]Key, error) {
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Key
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", s.file, err)
+	}
+	return list, nil
+}
+
+// write replaces the file; callers hold s.mu
+func (s *FileStore) write(list []Key) error {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
@@ -0,0 +1,106 @@
+package keys
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/migrate"
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// fakeRedis serves HSET, HGETALL and HDEL on a single in-memory hash
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	hash := map[string]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			reply, err := redisconn.ReadReply(r)
+			args, _ := reply.([]interface{})
+			if err != nil || len(args) < 2 {
+				conn.Close()
+				continue
+			}
+			switch args[0] {
+			case "HSET":
+				hash[args[2].(string)] = args[3].(string)
+				fmt.Fprint(conn, ":1\r\n")
+			case "HDEL":
+				delete(hash, args[2].(string))
+				fmt.Fprint(conn, ":1\r\n")
+			case "HGETALL":
+				fields := make([]string, 0, len(hash))
+				for f := range hash {
+					fields = append(fields, f)
+				}
+				sort.Strings(fields)
+				fmt.Fprintf(conn, "*%d\r\n", 2*len(fields))
+				for _, f := range fields {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n$%d\r\n%s\r\n", len(f), f, len(hash[f]), hash[f])
+				}
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command %v\r\n", args[0])
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"file":  NewFileStore(filepath.Join(t.TempDir(), "keys.json")),
+		"redis": NewRedisStore(config.RedisConfig{Address: fakeRedis(t)}, "llm-gateway:api-keys"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if list, err := store.List(ctx); err != nil || len(list) != 0 {
+				t.Fatalf("List() of an empty store = %v, %v", list, err)
+			}
+			for _, id := range []string{"a", "b"} {
+				if err := store.Put(ctx, Key{ID: id, Tenant: "acme", Quota: Quota{RequestsPerDay: 5}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store.Put(ctx, Key{ID: "a", Tenant: "globex"})
+			if err := store.Delete(ctx, "b"); err != nil {
+				t.Fatal(err)
+			}
+
+			list, err := store.List(ctx)
+			if err != nil || len(list) != 1 || list[0].ID != "a" || list[0].Tenant != "globex" {
+				t.Errorf("List() = %+v, %v", list, err)
+			}
+		})
+	}
+}
+
+func TestSQLStore_MigrationsLoad(t *testing.T) {
+	list, err := migrate.Load(migrations, "migrations")
+	if err != nil || len(list) == 0 {
+		t.Fatalf("embedded migrations = %v, %v", list, err)
+	}
+}
+
+func TestNewSQLStore_NoDriver(t *testing.T) {
+	if _, err := NewSQLStore("no-such-driver", "keys.db"); err == nil {
+		t.Error("NewSQLStore() with an unregistered driver succeeded")
+	}
+}
@@ -0,0 +1,74 @@
+// Package kubeapi is a minimal client for the Kubernetes API server, used for
+// endpoint discovery and leader election without the full client-go dependency.
+package kubeapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client sends authenticated requests to the Kubernetes API server
+type Client struct {
+	apiServer  string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for apiServer, defaulting to the in-cluster
+// address from KUBERNETES_SERVICE_HOST/PORT. The service account token is read
+// from tokenFile and the server certificate verified against caFile; either
+// may be empty or missing when running outside a cluster.
+func NewClient(apiServer, tokenFile, caFile string) (*Client, error) {
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("kubernetes api_server is not set and not running in a cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		if ca, err := os.ReadFile(caFile); err == nil {
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(ca)
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read kubernetes CA file: %w", err)
+		}
+	}
+
+	return &Client{
+		apiServer:  strings.TrimSuffix(apiServer, "/"),
+		tokenFile:  tokenFile,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}, nil
+}
+
+// Do sends a request for path (e.g. /api/v1/namespaces/default/endpoints/x)
+func (c *Client) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.apiServer+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// Service account tokens are rotated, so read the token on every call
+	if c.tokenFile != "" {
+		if token, err := os.ReadFile(c.tokenFile); err == nil {
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		}
+	}
+	return c.httpClient.Do(req)
+}
@@ -0,0 +1,235 @@
+// Package leader elects one gateway replica to run singleton background jobs,
+// using a Redis lock or a Kubernetes Lease.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/kubeapi"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the leader module logger; its level can be set via log.modules.leader
+var logger = observability.ModuleLogger("leader")
+
+// Lock is a lease that at most one holder owns at a time
+type Lock interface {
+	// TryAcquire takes the lease for ttl, or renews it if holder already owns it
+	TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if holder owns it
+	Release(ctx context.Context, holder string) error
+}
+
+// Elector campaigns for leadership and tracks whether this replica is leader.
+// A nil Elector always reports leadership, so callers need no special case
+// for single-replica deployments.
+type Elector struct {
+	lock          Lock
+	identity      string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	leader      atomic.Bool
+	mu          sync.Mutex
+	onElected   []func()
+	onDemoted   []func()
+	transitions int64
+	lastErr     string
+	leaderSince time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates an elector from configuration, or returns nil if leader election is disabled
+func New(cfg config.LeaderElectionConfig) (*Elector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var lock Lock
+	switch cfg.Backend {
+	case "redis":
+		lock = newRedisLock(cfg.Redis, cfg.Name)
+	case "kubernetes":
+		client, err := kubeapi.NewClient(cfg.Kubernetes.APIServer, cfg.Kubernetes.TokenFile, cfg.Kubernetes.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		lock = newKubernetesLock(client, cfg.Kubernetes.Namespace, cfg.Name)
+	default:
+		return nil, fmt.Errorf("unknown leader election backend: %s", cfg.Backend)
+	}
+
+	identity := cfg.Identity
+	if identity == "" {
+		hostname, _ := os.Hostname()
+		identity = hostname + "-" + uuid.NewString()[:8]
+	}
+	return NewElector(lock, identity, cfg.LeaseDuration, cfg.RenewInterval), nil
+}
+
+// NewElector creates an elector campaigning for lock as identity
+func NewElector(lock Lock, identity string, leaseDuration, renewInterval time.Duration) *Elector {
+	return &Elector{
+		lock:          lock,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+		renewInterval: renewInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// IsLeader reports whether this replica should run singleton jobs
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	return e.leader.Load()
+}
+
+// OnElected registers fn to run each time this replica becomes leader
+func (e *Elector) OnElected(fn func()) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onElected = append(e.onElected, fn)
+}
+
+// OnDemoted registers fn to run each time this replica loses leadership
+func (e *Elector) OnDemoted(fn func()) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onDemoted = append(e.onDemoted, fn)
+}
+
+// Start makes a first campaign attempt, so leadership is known before
+// background jobs start, then keeps campaigning and renewing in the background
+func (e *Elector) Start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.campaign(ctx)
+	go e.loop()
+}
+
+// Stop stops campaigning and releases the lease if held, so another replica
+// can take over without waiting for it to expire
+func (e *Elector) Stop(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+
+	if e.leader.Swap(false) {
+		if err := e.lock.Release(ctx, e.identity); err != nil {
+			logger.Warn().Err(err).Msg("Failed to release leader lease")
+		}
+	}
+}
+
+func (e *Elector) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), e.renewInterval)
+			e.campaign(ctx)
+			cancel()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// campaign acquires or renews the lease and updates leadership. Leadership is
+// given up on errors, as the lease may expire before the next renewal.
+func (e *Elector) campaign(ctx context.Context) {
+	acquired, err := e.lock.TryAcquire(ctx, e.identity, e.leaseDuration)
+
+	e.mu.Lock()
+	if err != nil {
+		if e.lastErr == "" {
+			logger.Warn().Err(err).Msg("Leader election failed")
+		}
+		e.lastErr = err.Error()
+		acquired = false
+	} else {
+		e.lastErr = ""
+	}
+
+	wasLeader := e.leader.Swap(acquired)
+	var callbacks []func()
+	switch {
+	case acquired && !wasLeader:
+		e.transitions++
+		e.leaderSince = time.Now()
+		callbacks = append(callbacks, e.onElected...)
+		logger.Info().Str("identity", e.identity).Msg("Became leader")
+	case !acquired && wasLeader:
+		e.transitions++
+		e.leaderSince = time.Time{}
+		callbacks = append(callbacks, e.onDemoted...)
+		logger.Warn().Str("identity", e.identity).Msg("Lost leadership")
+	}
+	e.mu.Unlock()
+
+	for _, fn := range callbacks {
+		go fn()
+	}
+}
+
+// Stats returns the election state of this replica
+func (e *Elector) Stats() map[string]interface{} {
+	if e == nil {
+		return map[string]interface{}{"enabled": false, "leader": true}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats := map[string]interface{}{
+		"enabled":        true,
+		"identity":       e.identity,
+		"leader":         e.leader.Load(),
+		"lease_duration": e.leaseDuration.String(),
+		"transitions":    e.transitions,
+	}
+	if !e.leaderSince.IsZero() {
+		stats["leader_since"] = e.leaderSince
+	}
+	if e.lastErr != "" {
+		stats["error"] = e.lastErr
+	}
+	return stats
+}
+
+// defaultElector is the process-wide elector used by admin endpoints
+var defaultElector atomic.Pointer[Elector]
+
+// SetDefault sets the process-wide elector
+func SetDefault(e *Elector) {
+	defaultElector.Store(e)
+}
+
+// Default returns the process-wide elector (nil when leader election is disabled)
+func Default() *Elector {
+	return defaultElector.Load()
+}
@@ -0,0 +1,109 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLock is an in-memory Lock shared by several electors
+type fakeLock struct {
+	mu     sync.Mutex
+	holder string
+	err    error
+}
+
+func (l *fakeLock) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return false, l.err
+	}
+	if l.holder == "" || l.holder == holder {
+		l.holder = holder
+		return true, nil
+	}
+	return false, nil
+}
+
+func (l *fakeLock) Release(ctx context.Context, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == holder {
+		l.holder = ""
+	}
+	return nil
+}
+
+func TestElector_OneLeader(t *testing.T) {
+	lock := &fakeLock{}
+	a := NewElector(lock, "a", time.Minute, time.Hour)
+	b := NewElector(lock, "b", time.Minute, time.Hour)
+
+	elected := make(chan struct{}, 1)
+	b.OnElected(func() { elected <- struct{}{} })
+
+	a.Start(context.Background())
+	b.Start(context.Background())
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("leaders: a=%v b=%v, want only a", a.IsLeader(), b.IsLeader())
+	}
+
+	// Stopping the leader releases the lease for the next campaign
+	a.Stop(context.Background())
+	if a.IsLeader() {
+		t.Error("a still leader after Stop")
+	}
+	b.campaign(context.Background())
+	if !b.IsLeader() {
+		t.Fatal("b did not take over")
+	}
+	select {
+	case <-elected:
+	case <-time.After(time.Second):
+		t.Error("OnElected callback not run")
+	}
+	b.Stop(context.Background())
+}
+
+func TestElector_ErrorGivesUpLeadership(t *testing.T) {
+	lock := &fakeLock{}
+	e := NewElector(lock, "a", time.Minute, time.Hour)
+	demoted := make(chan struct{}, 1)
+	e.OnDemoted(func() { demoted <- struct{}{} })
+	e.campaign(context.Background())
+	if !e.IsLeader() {
+		t.Fatal("not leader after first campaign")
+	}
+
+	lock.err = errors.New("connection refused")
+	e.campaign(context.Background())
+	if e.IsLeader() {
+		t.Error("still leader after a failed renewal")
+	}
+	select {
+	case <-demoted:
+	case <-time.After(time.Second):
+		t.Error("OnDemoted callback not run")
+	}
+	stats := e.Stats()
+	if stats["error"] != "connection refused" || stats["transitions"] != int64(2) {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestElector_NilIsLeader(t *testing.T) {
+	var e *Elector
+	e.Start(context.Background())
+	e.OnElected(func() {})
+	e.OnDemoted(func() {})
+	if !e.IsLeader() {
+		t.Error("nil elector should report leadership")
+	}
+	if stats := e.Stats(); stats["enabled"] != false {
+		t.Errorf("stats = %v", stats)
+	}
+	e.Stop(context.Background())
+}
@@ -0,0 +1,155 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/username/llm-gateway/internal/kubeapi"
+)
+
+// leaseTimeFormat is the MicroTime format used by coordination.k8s.io Leases
+const leaseTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// lease is the subset of a coordination.k8s.io/v1 Lease used for election
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+}
+
+// expired reports whether the lease's holder has stopped renewing it
+func (l *lease) expired(now time.Time) bool {
+	if l.Spec.HolderIdentity == "" {
+		return true
+	}
+	renewed, err := time.Parse(leaseTimeFormat, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// kubernetesLock is a coordination.k8s.io Lease. Updates carry the observed
+// resourceVersion, so concurrent takeovers are rejected by the API server.
+type kubernetesLock struct {
+	client    *kubeapi.Client
+	namespace string
+	name      string
+}
+
+func newKubernetesLock(client *kubeapi.Client, namespace, name string) *kubernetesLock {
+	return &kubernetesLock{client: client, namespace: namespace, name: name}
+}
+
+func (l *kubernetesLock) path() string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", url.PathEscape(l.namespace))
+}
+
+func (l *kubernetesLock) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	current, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if current == nil {
+		created := &lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: l.name, Namespace: l.namespace},
+		}
+		l.hold(created, holder, ttl, now, true)
+		return l.write(ctx, http.MethodPost, l.path(), created)
+	}
+
+	if current.Spec.HolderIdentity != holder && !current.expired(now) {
+		return false, nil
+	}
+	l.hold(current, holder, ttl, now, current.Spec.HolderIdentity != holder)
+	return l.write(ctx, http.MethodPut, l.path()+"/"+url.PathEscape(l.name), current)
+}
+
+func (l *kubernetesLock) Release(ctx context.Context, holder string) error {
+	current, err := l.get(ctx)
+	if err != nil || current == nil || current.Spec.HolderIdentity != holder {
+		return err
+	}
+	current.Spec.HolderIdentity = ""
+	_, err = l.write(ctx, http.MethodPut, l.path()+"/"+url.PathEscape(l.name), current)
+	return err
+}
+
+// hold marks holder as the owner of the lease until now+ttl
+func (l *kubernetesLock) hold(le *lease, holder string, ttl time.Duration, now time.Time, acquired bool) {
+	le.Spec.HolderIdentity = holder
+	le.Spec.LeaseDurationSeconds = int((ttl + time.Second - 1) / time.Second)
+	le.Spec.RenewTime = now.UTC().Format(leaseTimeFormat)
+	if acquired {
+		le.Spec.AcquireTime = le.Spec.RenewTime
+	}
+}
+
+// get returns the lease, or nil if it does not exist yet
+func (l *kubernetesLock) get(ctx context.Context) (*lease, error) {
+	resp, err := l.client.Do(ctx, http.MethodGet, l.path()+"/"+url.PathEscape(l.name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("lease get failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var le lease
+		if err := json.NewDecoder(resp.Body).Decode(&le); err != nil {
+			return nil, fmt.Errorf("failed to decode lease: %w", err)
+		}
+		return &le, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("lease get returned status %d", resp.StatusCode)
+	}
+}
+
+// write creates or updates the lease; a conflict means another replica won
+func (l *kubernetesLock) write(ctx context.Context, method, path string, le *lease) (bool, error) {
+	body, err := json.Marshal(le)
+	if err != nil {
+		return false, err
+	}
+	resp, err := l.client.Do(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("lease update failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	default:
+		return false, fmt.Errorf("lease update returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/kubeapi"
+)
+
+// fakeLeaseServer stores one Lease and enforces resourceVersion on updates
+func fakeLeaseServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	var stored *lease
+	version := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(stored)
+		case http.MethodPost, http.MethodPut:
+			var le lease
+			json.NewDecoder(r.Body).Decode(&le)
+			if (r.Method == http.MethodPost && stored != nil) ||
+				(r.Method == http.MethodPut && (stored == nil || le.Metadata.ResourceVersion != stored.Metadata.ResourceVersion)) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			version++
+			le.Metadata.ResourceVersion = strconv.Itoa(version)
+			stored = &le
+			json.NewEncoder(w).Encode(stored)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestKubernetesLock(t *testing.T) {
+	srv := fakeLeaseServer(t)
+	client, err := kubeapi.NewClient(srv.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newKubernetesLock(client, "default", "llm-gateway-leader")
+	ctx := context.Background()
+
+	if ok, err := l.TryAcquire(ctx, "a", 15*time.Second); !ok || err != nil {
+		t.Fatalf("create: ok=%v err=%v", ok, err)
+	}
+	if ok, err := l.TryAcquire(ctx, "b", 15*time.Second); ok || err != nil {
+		t.Fatalf("held lease taken: ok=%v err=%v", ok, err)
+	}
+	if ok, err := l.TryAcquire(ctx, "a", 15*time.Second); !ok || err != nil {
+		t.Fatalf("renew: ok=%v err=%v", ok, err)
+	}
+
+	if err := l.Release(ctx, "a"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if ok, err := l.TryAcquire(ctx, "b", 15*time.Second); !ok || err != nil {
+		t.Fatalf("takeover after release: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestLease_Expired(t *testing.T) {
+	now := time.Now()
+	le := &lease{Spec: leaseSpec{
+		HolderIdentity:       "a",
+		LeaseDurationSeconds: 15,
+		RenewTime:            now.Add(-10 * time.Second).UTC().Format(leaseTimeFormat),
+	}}
+	if le.expired(now) {
+		t.Error("lease renewed 10s ago expired")
+	}
+	if !le.expired(now.Add(10 * time.Second)) {
+		t.Error("lease renewed 20s ago not expired")
+	}
+
+	le.Spec.HolderIdentity = ""
+	if !le.expired(now) {
+		t.Error("released lease not expired")
+	}
+}
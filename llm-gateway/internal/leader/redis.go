@@ -0,0 +1,50 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// acquireScript takes the lock if it is free or renews it if we already hold it
+const acquireScript = `
+local holder = redis.call('GET', KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`
+
+// releaseScript deletes the lock only if we hold it
+const releaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// redisLock is a lease held as a Redis key with a TTL. It dials a connection
+// per call, as lock operations are infrequent.
+type redisLock struct {
+	client *redisconn.Client
+	key    string
+}
+
+func newRedisLock(cfg config.RedisConfig, key string) *redisLock {
+	return &redisLock{client: redisconn.New(cfg.Options()), key: key}
+}
+
+func (l *redisLock) TryAcquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	reply, err := l.client.Do(ctx, "EVAL", acquireScript, "1", l.key, holder, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+func (l *redisLock) Release(ctx context.Context, holder string) error {
+	_, err := l.client.Do(ctx, "EVAL", releaseScript, "1", l.key, holder)
+	return err
+}
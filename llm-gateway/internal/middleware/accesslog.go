@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// clfTimeFormat is the timestamp layout used by the common/combined log formats
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog writes one line per request in a classic access-log format
+type AccessLog struct {
+	mu     sync.Mutex
+	format string
+	out    io.Writer
+	closer io.Closer
+}
+
+// accessLogEntry is the JSON-lines record, using nginx-style field names
+type accessLogEntry struct {
+	Time          string  `json:"time_local"`
+	RemoteAddr    string  `json:"remote_addr"`
+	RemoteUser    string  `json:"remote_user"`
+	Request       string  `json:"request"`
+	Method        string  `json:"request_method"`
+	URI           string  `json:"request_uri"`
+	Protocol      string  `json:"server_protocol"`
+	Status        int     `json:"status"`
+	BodyBytesSent int     `json:"body_bytes_sent"`
+	Referer       string  `json:"http_referer"`
+	UserAgent     string  `json:"http_user_agent"`
+	RequestTime   float64 `json:"request_time"`
+	RequestID     string  `json:"request_id,omitempty"`
+}
+
+// NewAccessLog creates an access log writing to stdout, stderr or a rotating file
+func NewAccessLog(cfg config.AccessLogConfig) (*AccessLog, error) {
+	a := &AccessLog{format: cfg.Format}
+	if a.format == "" {
+		a.format = "combined"
+	}
+
+	switch cfg.Output {
+	case "", "stdout":
+		a.out = os.Stdout
+	case "stderr":
+		a.out = os.Stderr
+	default:
+		file, err := observability.NewRotatingFile(observability.RotateConfig{
+			Path:       cfg.Output,
+			MaxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+			Interval:   cfg.RotateInterval,
+			MaxBackups: cfg.MaxBackups,
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.out = file
+		a.closer = file
+	}
+
+	return a, nil
+}
+
+// Middleware returns a middleware that writes an access log line after each request
+func (a *AccessLog) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := wrapResponseWriter(w)
+
+			next.ServeHTTP(wrapped, r)
+
+			a.write(r, wrapped.status, wrapped.bytes, start)
+		})
+	}
+}
+
+func (a *AccessLog) write(r *http.Request, status, bytes int, start time.Time) {
+	var line []byte
+	if a.format == "json" {
+		line = a.formatJSON(r, status, bytes, start)
+	} else {
+		line = []byte(a.formatCLF(r, status, bytes, start))
+	}
+
+	a.mu.Lock()
+	a.out.Write(line)
+	a.mu.Unlock()
+}
+
+// formatCLF renders the common or combined log format
+func (a *AccessLog) formatCLF(r *http.Request, status, bytes int, start time.Time) string {
+	size := "-"
+	if bytes > 0 {
+		size = strconv.Itoa(bytes)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		remoteHost(r),
+		clfValue(remoteUser(r)),
+		start.Format(clfTimeFormat),
+		r.Method, r.URL.RequestURI(), r.Proto,
+		status, size,
+	)
+
+	if a.format == "combined" {
+		line += fmt.Sprintf(" %q %q", clfValue(r.Referer()), clfValue(r.UserAgent()))
+	}
+	return line + "\n"
+}
+
+// formatJSON renders a JSON-lines record
+func (a *AccessLog) formatJSON(r *http.Request, status, bytes int, start time.Time) []byte {
+	entry := accessLogEntry{
+		Time:          start.Format(clfTimeFormat),
+		RemoteAddr:    remoteHost(r),
+		RemoteUser:    remoteUser(r),
+		Request:       r.Method + " " + r.URL.RequestURI() + " " + r.Proto,
+		Method:        r.Method,
+		URI:           r.URL.RequestURI(),
+		Protocol:      r.Proto,
+		Status:        status,
+		BodyBytesSent: bytes,
+		Referer:       r.Referer(),
+		UserAgent:     r.UserAgent(),
+		RequestTime:   time.Since(start).Seconds(),
+		RequestID:     middleware.GetReqID(r.Context()),
+	}
+
+	line, _ := json.Marshal(entry)
+	return append(line, '\n')
+}
+
+// Close closes the access log file, if any
+func (a *AccessLog) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// remoteHost returns the client address without the port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// remoteUser returns the basic-auth user name, if any
+func remoteUser(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	return ""
+}
+
+// clfValue returns "-" for empty values as the common log format requires
+func clfValue(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
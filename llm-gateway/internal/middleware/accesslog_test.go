@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func serveAccessLog(t *testing.T, format string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	a, err := NewAccessLog(config.AccessLogConfig{Format: format, Output: path})
+	if err != nil {
+		t.Fatalf("NewAccessLog() error = %v", err)
+	}
+
+	handler := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions?stream=false", nil)
+	req.RemoteAddr = "10.0.0.7:51234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read access log: %v", err)
+	}
+	return string(data)
+}
+
+func TestAccessLog_Combined(t *testing.T) {
+	line := serveAccessLog(t, "combined")
+
+	pattern := `^10\.0\.0\.7 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"POST /v1/chat/completions\?stream=false HTTP/1\.1" 201 5 "-" "curl/8\.0"\n$`
+	if !regexp.MustCompile(pattern).MatchString(line) {
+		t.Errorf("combined line = %q, does not match %s", line, pattern)
+	}
+}
+
+func TestAccessLog_Common(t *testing.T) {
+	line := serveAccessLog(t, "common")
+
+	if !strings.HasSuffix(line, `"POST /v1/chat/completions?stream=false HTTP/1.1" 201 5`+"\n") {
+		t.Errorf("common line = %q, want no referer or user agent", line)
+	}
+}
+
+func TestAccessLog_JSON(t *testing.T) {
+	line := serveAccessLog(t, "json")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("json line = %q, error = %v", line, err)
+	}
+	if entry["remote_addr"] != "10.0.0.7" || entry["remote_user"] != "alice" {
+		t.Errorf("remote fields = %v/%v", entry["remote_addr"], entry["remote_user"])
+	}
+	if entry["status"] != float64(201) || entry["body_bytes_sent"] != float64(5) {
+		t.Errorf("status/bytes = %v/%v, want 201/5", entry["status"], entry["body_bytes_sent"])
+	}
+	if entry["request_uri"] != "/v1/chat/completions?stream=false" {
+		t.Errorf("request_uri = %v", entry["request_uri"])
+	}
+}
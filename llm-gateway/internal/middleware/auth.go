@@ -4,8 +4,6 @@ import (
 	"context"
 	"net/http"
 	"strings"
-
-	"github.com/rs/zerolog/log"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -16,6 +14,10 @@ const (
 	APIKeyContextKey contextKey = "api_key"
 	// UserIDContextKey is the context key for the user ID
 	UserIDContextKey contextKey = "user_id"
+	// RateLimitTierContextKey is the context key for the caller's rate limit tier
+	RateLimitTierContextKey contextKey = "rate_limit_tier"
+	// KeyIDContextKey is the context key for the ID of the caller's managed API key
+	KeyIDContextKey contextKey = "key_id"
 )
 
 // AuthConfig holds authentication configuration
@@ -28,6 +30,8 @@ type AuthConfig struct {
 	HeaderName string
 	// Prefix is the expected prefix (default: Bearer)
 	Prefix string
+	// TrustTenantHeader lets authenticated callers name a tenant with X-Tenant-ID
+	TrustTenantHeader bool
 }
 
 // DefaultAuthConfig returns default authentication configuration
@@ -60,7 +64,7 @@ func Auth(config AuthConfig) func(next http.Handler) http.Handler {
 			// Validate API key
 			userID, valid := config.ValidKeys[apiKey]
 			if !valid {
-				log.Warn().
+				logger.Warn().
 					Str("ip", r.RemoteAddr).
 					Msg("Invalid API key attempted")
 				writeAuthError(w, "invalid_api_key", "Invalid API key")
@@ -70,6 +74,9 @@ func Auth(config AuthConfig) func(next http.Handler) http.Handler {
 			// Add API key and user ID to context
 			ctx := context.WithValue(r.Context(), APIKeyContextKey, apiKey)
 			ctx = context.WithValue(ctx, UserIDContextKey, userID)
+			if config.TrustTenantHeader {
+				ctx = WithTrustedTenant(ctx)
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -116,6 +123,23 @@ func GetAPIKey(ctx context.Context) string {
 	return ""
 }
 
+// RequestAPIKey returns the caller's API key: the authenticated key if auth
+// ran, otherwise the key presented in the request headers
+func RequestAPIKey(r *http.Request) string {
+	if key := GetAPIKey(r.Context()); key != "" {
+		return key
+	}
+	return extractAPIKey(r, DefaultAuthConfig())
+}
+
+// MaskAPIKey hides all but the start of an API key for logging
+func MaskAPIKey(key string) string {
+	if len(key) > 12 {
+		return key[:12] + "***"
+	}
+	return key
+}
+
 // GetUserID retrieves the user ID from the request context
 func GetUserID(ctx context.Context) string {
 	if userID, ok := ctx.Value(UserIDContextKey).(string); ok {
@@ -124,6 +148,14 @@ func GetUserID(ctx context.Context) string {
 	return ""
 }
 
+// GetKeyID retrieves the ID of the caller's managed API key from the request context
+func GetKeyID(ctx context.Context) string {
+	if id, ok := ctx.Value(KeyIDContextKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
 // APIKeyValidator is a function type for validating API keys externally
 type APIKeyValidator func(apiKey string) (userID string, valid bool)
 
@@ -142,7 +174,7 @@ func AuthWithValidator(validator APIKeyValidator) func(next http.Handler) http.H
 
 			userID, valid := validator(apiKey)
 			if !valid {
-				log.Warn().
+				logger.Warn().
 					Str("ip", r.RemoteAddr).
 					Msg("Invalid API key attempted")
 				writeAuthError(w, "invalid_api_key", "Invalid API key")
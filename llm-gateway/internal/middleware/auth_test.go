@@ -265,6 +265,33 @@ func TestAuthMiddleware_ValidAPIKey(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_TrustTenantHeader(t *testing.T) {
+	for _, trust := range []bool{false, true} {
+		config := DefaultAuthConfig()
+		config.Enabled = true
+		config.ValidKeys = map[string]string{"admin-key": "admin"}
+		config.TrustTenantHeader = trust
+
+		var tenant string
+		handler := Auth(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant = TenantID(r)
+		}))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer admin-key")
+		req.Header.Set(TenantHeader, "acme")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		want := "admin"
+		if trust {
+			want = "acme"
+		}
+		if tenant != want {
+			t.Errorf("TrustTenantHeader=%v: tenant = %s, want %s", trust, tenant, want)
+		}
+	}
+}
+
 func TestAuthWithValidator(t *testing.T) {
 	validator := func(apiKey string) (string, bool) {
 		if apiKey == "custom-valid-key" {
@@ -0,0 +1,397 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/performance"
+)
+
+const (
+	// blobRefKey marks message content that should be replaced by a blob's contents
+	blobRefKey = "$blob"
+	// fileIDPrefix identifies blobs uploaded via POST /v1/files
+	fileIDPrefix = "file-"
+)
+
+// blobsResolvedContextKey marks requests whose body had blob references inlined
+type blobsResolvedContextKey struct{}
+
+// BlobsResolved reports whether blob references were inlined into the body
+// of r, so checks made on the body before did not see the blobs' contents
+func BlobsResolved(r *http.Request) bool {
+	resolved, _ := r.Context().Value(blobsResolvedContextKey{}).(bool)
+	return resolved
+}
+
+// blobError is a client-facing blob resolution failure
+type blobError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *blobError) Error() string {
+	return e.message
+}
+
+// BlobResolver replaces {"$blob": "<ref>"} message content with the referenced
+// blob before the request reaches a handler, so large prompts are uploaded
+// once instead of travelling through every hop. A ref is either an S3 URI
+// (s3://bucket/key) or the ID of a blob uploaded to POST /v1/files, which
+// only the uploading tenant can reference.
+type BlobResolver struct {
+	s3Endpoint      string
+	s3Allowed       []string
+	maxBlobBytes    int64
+	maxRequestBytes int64
+	cacheTTL        time.Duration
+	fileTTL         time.Duration
+	client          *http.Client
+
+	cache performance.CacheBackend
+	// files never evicts, so uploads stay referenceable until they expire;
+	// they are keyed by fileKey
+	files *performance.MemoryBackend
+
+	// Statistics
+	resolved  int64
+	cacheHits int64
+	failed    int64
+	uploaded  int64
+}
+
+// NewBlobResolver creates a blob resolver from config
+func NewBlobResolver(cfg config.BlobConfig) *BlobResolver {
+	timeout := cfg.FetchTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &BlobResolver{
+		s3Endpoint:      strings.TrimSuffix(cfg.S3Endpoint, "/"),
+		s3Allowed:       cfg.S3AllowedPrefixes,
+		maxBlobBytes:    cfg.MaxBlobBytes,
+		maxRequestBytes: cfg.MaxRequestBytes,
+		cacheTTL:        cfg.CacheTTL,
+		fileTTL:         cfg.FileTTL,
+		client:          &http.Client{Timeout: timeout},
+		cache:           performance.NewMemoryBackend(cfg.CacheEntries),
+		files:           performance.NewMemoryBackend(cfg.MaxFiles),
+	}
+}
+
+// Middleware returns a middleware that inlines blob references in JSON request
+// bodies. Bodies larger than the per-request limit are refused with 413.
+func (b *BlobResolver) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reader := r.Body
+			if b.maxRequestBytes > 0 {
+				reader = http.MaxBytesReader(w, r.Body, b.maxRequestBytes)
+			}
+			body, err := io.ReadAll(reader)
+			r.Body.Close()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeBlobError(w, &blobError{http.StatusRequestEntityTooLarge, "request_too_large",
+						fmt.Sprintf("Request body exceeds the %d byte limit", b.maxRequestBytes)})
+					return
+				}
+				writeBlobError(w, &blobError{http.StatusBadRequest, "invalid_request", "Failed to read request body"})
+				return
+			}
+
+			// Cheap check so requests without references are passed through untouched
+			if bytes.Contains(body, []byte(`"`+blobRefKey+`"`)) {
+				resolved, err := b.resolveBody(r.Context(), TenantID(r), body)
+				if err != nil {
+					atomic.AddInt64(&b.failed, 1)
+					var blobErr *blobError
+					if !errors.As(err, &blobErr) {
+						blobErr = &blobError{http.StatusBadRequest, "invalid_request", err.Error()}
+					}
+					logger.Warn().Err(err).Str("path", r.URL.Path).Msg("Blob reference resolution failed")
+					writeBlobError(w, blobErr)
+					return
+				}
+				body = resolved
+				r = r.WithContext(context.WithValue(r.Context(), blobsResolvedContextKey{}, true))
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolveBody replaces blob references in messages[].content, system and
+// prompt, for a request of tenant
+func (b *BlobResolver) resolveBody(ctx context.Context, tenant string, body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload map[string]interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		// Let the handler report malformed JSON as usual
+		return body, nil
+	}
+
+	var total int64
+	resolve := func(value interface{}) (interface{}, error) {
+		ref, ok := blobRef(value)
+		if !ok {
+			return value, nil
+		}
+		data, err := b.Resolve(ctx, tenant, ref)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(data))
+		if b.maxRequestBytes > 0 && total > b.maxRequestBytes {
+			return nil, &blobError{http.StatusRequestEntityTooLarge, "blob_too_large",
+				fmt.Sprintf("Resolved blobs exceed the %d byte per-request limit", b.maxRequestBytes)}
+		}
+		return string(data), nil
+	}
+
+	for _, field := range []string{"prompt", "system"} {
+		if value, ok := payload[field]; ok {
+			resolved, err := resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			payload[field] = resolved
+		}
+	}
+
+	if messages, ok := payload["messages"].([]interface{}); ok {
+		for _, m := range messages {
+			msg, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			resolved, err := resolve(msg["content"])
+			if err != nil {
+				return nil, err
+			}
+			msg["content"] = resolved
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// blobRef returns the reference if value is a {"$blob": "<ref>"} object
+func blobRef(value interface{}) (string, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return "", false
+	}
+	ref, ok := obj[blobRefKey].(string)
+	return ref, ok
+}
+
+// fileKey is the storage key of a file uploaded by tenant; a file looked up
+// for another tenant is not found
+func fileKey(tenant, id string) string {
+	sum := sha256.Sum256([]byte(tenant))
+	return hex.EncodeToString(sum[:8]) + ":" + id
+}
+
+// Resolve returns the contents of a blob reference for a request of tenant,
+// fetching and caching S3 objects. Uploaded files resolve only for the
+// tenant that uploaded them.
+func (b *BlobResolver) Resolve(ctx context.Context, tenant, ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, fileIDPrefix) {
+		data, err := b.files.Get(ctx, fileKey(tenant, ref))
+		if err != nil {
+			return nil, &blobError{http.StatusBadRequest, "blob_not_found", "Unknown or expired file: " + ref}
+		}
+		atomic.AddInt64(&b.resolved, 1)
+		return data, nil
+	}
+
+	if !strings.HasPrefix(ref, "s3://") {
+		return nil, &blobError{http.StatusBadRequest, "invalid_blob_reference",
+			"Blob reference must be an s3:// URI or an uploaded file ID: " + ref}
+	}
+
+	if data, err := b.cache.Get(ctx, ref); err == nil {
+		atomic.AddInt64(&b.cacheHits, 1)
+		atomic.AddInt64(&b.resolved, 1)
+		return data, nil
+	}
+
+	data, err := b.fetchS3(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	b.cache.Set(ctx, ref, data, b.cacheTTL)
+	atomic.AddInt64(&b.resolved, 1)
+	return data, nil
+}
+
+// fetchS3 downloads s3://bucket/key from the configured S3-compatible endpoint
+func (b *BlobResolver) fetchS3(ctx context.Context, ref string) ([]byte, error) {
+	if b.s3Endpoint == "" {
+		return nil, &blobError{http.StatusBadRequest, "invalid_blob_reference", "S3 blob references are not enabled"}
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(ref, "s3://"), "/")
+	if bucket == "" || key == "" || strings.Contains(ref, "..") {
+		return nil, &blobError{http.StatusBadRequest, "invalid_blob_reference", "Invalid S3 reference: " + ref}
+	}
+	if !b.s3RefAllowed(bucket, key) {
+		return nil, &blobError{http.StatusForbidden, "blob_not_allowed", "S3 reference is outside the allowed buckets: " + ref}
+	}
+
+	// Path-style URL; every key segment is escaped so refs cannot leave the endpoint
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	target := b.s3Endpoint + "/" + url.PathEscape(bucket) + "/" + strings.Join(segments, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, &blobError{http.StatusBadGateway, "blob_fetch_failed", "Failed to fetch " + ref + ": " + err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &blobError{http.StatusBadRequest, "blob_not_found", "Blob not found: " + ref}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &blobError{http.StatusBadGateway, "blob_fetch_failed",
+			fmt.Sprintf("Failed to fetch %s: status %d", ref, resp.StatusCode)}
+	}
+
+	return b.readLimited(resp.Body, ref)
+}
+
+// s3RefAllowed reports whether bucket/key is within an allowed bucket or prefix
+func (b *BlobResolver) s3RefAllowed(bucket, key string) bool {
+	for _, allowed := range b.s3Allowed {
+		allowedBucket, prefix, _ := strings.Cut(allowed, "/")
+		if allowedBucket == bucket && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLimited reads at most maxBlobBytes, failing if the blob is larger
+func (b *BlobResolver) readLimited(r io.Reader, ref string) ([]byte, error) {
+	if b.maxBlobBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, b.maxBlobBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > b.maxBlobBytes {
+		return nil, &blobError{http.StatusRequestEntityTooLarge, "blob_too_large",
+			fmt.Sprintf("Blob %s exceeds the %d byte limit", ref, b.maxBlobBytes)}
+	}
+	return data, nil
+}
+
+// UploadHandler handles POST /v1/files: the raw request body is stored for
+// the caller's tenant, which can then reference it as {"$blob": "<id>"}
+// until it expires
+func (b *BlobResolver) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := b.readLimited(r.Body, "upload")
+	if err != nil {
+		var blobErr *blobError
+		if !errors.As(err, &blobErr) {
+			blobErr = &blobError{http.StatusBadRequest, "invalid_request", "Failed to read request body"}
+		}
+		writeBlobError(w, blobErr)
+		return
+	}
+	if len(data) == 0 {
+		writeBlobError(w, &blobError{http.StatusBadRequest, "invalid_request", "File content is required"})
+		return
+	}
+
+	id := fileIDPrefix + uuid.New().String()
+	now := time.Now()
+	if !b.files.Add(r.Context(), fileKey(TenantID(r), id), data, b.fileTTL) {
+		writeBlobError(w, &blobError{http.StatusInsufficientStorage, "file_limit_reached",
+			"File storage is full; retry once earlier uploads expire"})
+		return
+	}
+	atomic.AddInt64(&b.uploaded, 1)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"object":     "file",
+		"bytes":      len(data),
+		"created_at": now.Unix(),
+		"expires_at": now.Add(b.fileTTL).Unix(),
+	})
+}
+
+// DeleteUser removes the files uploaded by user, for deletion requests,
+// returning the number of files deleted. Uploads carry no "user" field, so
+// they are erased with the tenant that uploaded them, such as an
+// authenticated user.
+func (b *BlobResolver) DeleteUser(user string) int {
+	deleted, _ := b.files.DeleteMatching(context.Background(), fileKey(user, "")+"*")
+	return deleted
+}
+
+// GetStats returns blob resolution statistics
+func (b *BlobResolver) GetStats() map[string]interface{} {
+	files := b.files.Stats()
+	return map[string]interface{}{
+		"resolved":    atomic.LoadInt64(&b.resolved),
+		"cache_hits":  atomic.LoadInt64(&b.cacheHits),
+		"failed":      atomic.LoadInt64(&b.failed),
+		"uploaded":    atomic.LoadInt64(&b.uploaded),
+		"files":       files.EntryCount,
+		"files_bytes": files.SizeBytes,
+	}
+}
+
+// writeBlobError writes a blob resolution error response
+func writeBlobError(w http.ResponseWriter, err *blobError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": err.message,
+			"type":    "invalid_request_error",
+			"code":    err.code,
+		},
+	})
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func testBlobConfig(s3Endpoint string) config.BlobConfig {
+	return config.BlobConfig{
+		Enabled:           true,
+		S3Endpoint:        s3Endpoint,
+		S3AllowedPrefixes: []string{"prompts/long/", "b"},
+		FetchTimeout:      5 * time.Second,
+		MaxBlobBytes:      64,
+		MaxRequestBytes:   120,
+		CacheTTL:          time.Minute,
+		CacheEntries:      10,
+		FileTTL:           time.Hour,
+		MaxFiles:          10,
+	}
+}
+
+// echoBody returns a handler that captures the (resolved) request body
+func echoBody(body *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		*body = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestBlobResolver_ResolvesS3AndCaches(t *testing.T) {
+	var fetches int64
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&fetches, 1)
+		if r.URL.Path != "/prompts/long/doc.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("a very long document"))
+	}))
+	defer s3.Close()
+
+	b := NewBlobResolver(testBlobConfig(s3.URL))
+	var got string
+	handler := b.Middleware()(echoBody(&got))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(
+			`{"model":"gpt-4o","max_tokens":100,"messages":[{"role":"user","content":{"$blob":"s3://prompts/long/doc.txt"}}]}`))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	var payload struct {
+		MaxTokens int `json:"max_tokens"`
+		Messages  []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(got), &payload); err != nil {
+		t.Fatalf("resolved body is not valid JSON: %v", err)
+	}
+	if payload.Messages[0].Content != "a very long document" || payload.MaxTokens != 100 {
+		t.Errorf("unexpected resolved body: %s", got)
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want 1 (second request should hit the cache)", fetches)
+	}
+}
+
+func TestBlobResolver_UploadedFile(t *testing.T) {
+	b := NewBlobResolver(testBlobConfig(""))
+
+	rr := httptest.NewRecorder()
+	b.UploadHandler(rr, httptest.NewRequest(http.MethodPost, "/v1/files", strings.NewReader("uploaded prompt")))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d", rr.Code)
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(rr.Body).Decode(&file)
+
+	var got string
+	handler := b.Middleware()(echoBody(&got))
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(
+		`{"model":"gpt-4o","prompt":{"$blob":"`+file.ID+`"}}`))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || !strings.Contains(got, `"prompt":"uploaded prompt"`) {
+		t.Errorf("status = %d, body = %s", rr.Code, got)
+	}
+}
+
+func TestBlobResolver_FilesAreTenantScoped(t *testing.T) {
+	b := NewBlobResolver(testBlobConfig(""))
+	asUser := func(r *http.Request, user string) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), UserIDContextKey, user))
+	}
+
+	rr := httptest.NewRecorder()
+	b.UploadHandler(rr, asUser(httptest.NewRequest(http.MethodPost, "/v1/files", strings.NewReader("private prompt")), "user-a"))
+	var file struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(rr.Body).Decode(&file)
+
+	var got string
+	handler := b.Middleware()(echoBody(&got))
+	resolve := func(user string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(
+			`{"model":"gpt-4o","prompt":{"$blob":"`+file.ID+`"}}`))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, asUser(req, user))
+		return rr.Code
+	}
+
+	if code := resolve("user-b"); code != http.StatusBadRequest {
+		t.Errorf("another tenant's reference status = %d, want 400", code)
+	}
+	if code := resolve("user-a"); code != http.StatusOK || !strings.Contains(got, "private prompt") {
+		t.Errorf("uploader's reference status = %d, body = %s", code, got)
+	}
+
+	// Deletion requests erase the user's uploads
+	if deleted := b.DeleteUser("user-b"); deleted != 0 {
+		t.Errorf("DeleteUser(user-b) = %d, want 0", deleted)
+	}
+	if deleted := b.DeleteUser("user-a"); deleted != 1 {
+		t.Errorf("DeleteUser(user-a) = %d, want 1", deleted)
+	}
+	if code := resolve("user-a"); code != http.StatusBadRequest {
+		t.Errorf("erased upload status = %d, want 400", code)
+	}
+}
+
+func TestBlobResolver_Errors(t *testing.T) {
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 64)))
+	}))
+	defer s3.Close()
+
+	b := NewBlobResolver(testBlobConfig(s3.URL))
+	handler := b.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"unknown file", `{"messages":[{"role":"user","content":{"$blob":"file-missing"}}]}`, http.StatusBadRequest},
+		{"unsupported scheme", `{"messages":[{"role":"user","content":{"$blob":"http://internal/secret"}}]}`, http.StatusBadRequest},
+		{"request limit", `{"messages":[{"role":"user","content":{"$blob":"s3://b/one"}},{"role":"user","content":{"$blob":"s3://b/two"}}]}`, http.StatusRequestEntityTooLarge},
+		{"bucket not allowed", `{"messages":[{"role":"user","content":{"$blob":"s3://secrets/key"}}]}`, http.StatusForbidden},
+		{"prefix not allowed", `{"messages":[{"role":"user","content":{"$blob":"s3://prompts/other/doc.txt"}}]}`, http.StatusForbidden},
+		{"body limit", `{"messages":[{"role":"user","content":"` + strings.Repeat("x", 120) + `"}]}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rr.Code, tt.status, rr.Body.String())
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	b.UploadHandler(rr, httptest.NewRequest(http.MethodPost, "/v1/files", strings.NewReader(strings.Repeat("x", 65))))
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload status = %d, want 413", rr.Code)
+	}
+}
+
+func TestBlobResolver_PassesThroughPlainRequests(t *testing.T) {
+	b := NewBlobResolver(testBlobConfig(""))
+	var got string
+	handler := b.Middleware()(echoBody(&got))
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if got != body {
+		t.Errorf("body was modified: %s", got)
+	}
+}
+
+func TestBlobResolver_FullFileStorageRefusesUploads(t *testing.T) {
+	cfg := testBlobConfig("")
+	cfg.MaxFiles = 2
+	b := NewBlobResolver(cfg)
+
+	upload := func() (int, string) {
+		rr := httptest.NewRecorder()
+		b.UploadHandler(rr, httptest.NewRequest(http.MethodPost, "/v1/files", strings.NewReader("prompt")))
+		var file struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(rr.Body).Decode(&file)
+		return rr.Code, file.ID
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		code, id := upload()
+		if code != http.StatusCreated {
+			t.Fatalf("upload %d status = %d", i, code)
+		}
+		ids = append(ids, id)
+	}
+	if code, _ := upload(); code != http.StatusInsufficientStorage {
+		t.Errorf("upload over max_files status = %d, want 507", code)
+	}
+
+	// Earlier uploads were not evicted before their expiry
+	for _, id := range ids {
+		if _, err := b.Resolve(context.Background(), "anonymous", id); err != nil {
+			t.Errorf("Resolve(%s) error = %v", id, err)
+		}
+	}
+}
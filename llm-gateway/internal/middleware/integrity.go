@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/websocket"
+)
+
+// ContentSHA256Header carries the hex SHA-256 of a request or response body
+const ContentSHA256Header = "X-Content-SHA256"
+
+// Integrity checks request bodies against the checksum the caller sent and
+// sends the checksum of each response body, so callers can detect payloads
+// corrupted or altered between them and the gateway
+type Integrity struct {
+	require bool
+}
+
+// NewIntegrity creates the checksum middleware from config
+func NewIntegrity(cfg config.IntegrityConfig) *Integrity {
+	return &Integrity{require: cfg.Require}
+}
+
+// Middleware verifies X-Content-SHA256 on requests and sets it on responses.
+// Responses are buffered to hash them before the headers are sent; once a
+// handler flushes (a stream), the hash is sent as a trailer instead.
+// WebSocket upgrades are passed through.
+func (i *Integrity) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if websocket.IsUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !i.verifyRequest(w, r) {
+				return
+			}
+
+			iw := &integrityWriter{ResponseWriter: w, hash: sha256.New()}
+			next.ServeHTTP(iw, r)
+			iw.finish()
+		})
+	}
+}
+
+// verifyRequest checks the request body against its X-Content-SHA256 header,
+// writing an error and returning false if it does not match
+func (i *Integrity) verifyRequest(w http.ResponseWriter, r *http.Request) bool {
+	header := strings.TrimSpace(r.Header.Get(ContentSHA256Header))
+	if header == "" {
+		if i.require && r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+			writeIntegrityError(w, "checksum_required", ContentSHA256Header+" header is required for requests with a body")
+			return false
+		}
+		return true
+	}
+
+	expected, err := hex.DecodeString(header)
+	if err != nil || len(expected) != sha256.Size {
+		writeIntegrityError(w, "invalid_checksum", ContentSHA256Header+" must be the hex-encoded SHA-256 of the body")
+		return false
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			writeIntegrityError(w, "invalid_request", "Failed to read request body")
+			return false
+		}
+	}
+	sum := sha256.Sum256(body)
+	if !bytes.Equal(sum[:], expected) {
+		actual := hex.EncodeToString(sum[:])
+		logger.Warn().
+			Str("request_id", chimiddleware.GetReqID(r.Context())).
+			Str("path", r.URL.Path).
+			Str("expected", header).
+			Str("actual", actual).
+			Int("body_bytes", len(body)).
+			Msg("Request body checksum mismatch")
+		observability.LogAudit(r.Context(), "request.checksum_mismatch", r.URL.Path, map[string]interface{}{
+			"request_id": chimiddleware.GetReqID(r.Context()),
+			"expected":   strings.ToLower(header),
+			"actual":     actual,
+		})
+		writeIntegrityError(w, "checksum_mismatch", "Request body does not match "+ContentSHA256Header)
+		return false
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return true
+}
+
+// writeIntegrityError writes a checksum error response
+func writeIntegrityError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"type":    code,
+			"message": message,
+		},
+	})
+}
+
+// integrityWriter hashes the response body, holding it back until the hash
+// can be sent as a header, or streaming it with the hash as a trailer once
+// the handler flushes
+type integrityWriter struct {
+	http.ResponseWriter
+	hash   hash.Hash
+	status int
+	body   bytes.Buffer
+	// streaming is set once the handler flushed; the headers have been sent
+	streaming bool
+}
+
+func (w *integrityWriter) WriteHeader(status int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *integrityWriter) Write(p []byte) (int, error) {
+	w.hash.Write(p)
+	if w.streaming {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.body.Write(p)
+}
+
+// Flush switches to streaming: the headers and the body so far are sent,
+// with the hash announced as a trailer
+func (w *integrityWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.Header().Del(ContentSHA256Header)
+		w.Header().Del("Content-Length")
+		w.Header().Add("Trailer", ContentSHA256Header)
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body = bytes.Buffer{}
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *integrityWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish sends the hash, with the buffered response unless it was streamed
+func (w *integrityWriter) finish() {
+	sum := hex.EncodeToString(w.hash.Sum(nil))
+	w.Header().Set(ContentSHA256Header, sum)
+	if w.streaming {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIntegrity_VerifiesRequestBody(t *testing.T) {
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`
+	var received string
+	handler := NewIntegrity(config.IntegrityConfig{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+	}))
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     int
+	}{
+		{"matching", sha256Hex(body), http.StatusOK},
+		{"matching upper case", strings.ToUpper(sha256Hex(body)), http.StatusOK},
+		{"no header", "", http.StatusOK},
+		{"mismatch", sha256Hex(body + " "), http.StatusBadRequest},
+		{"malformed", "not-a-hash", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			if tt.checksum != "" {
+				req.Header.Set(ContentSHA256Header, tt.checksum)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.want, rr.Body.String())
+			}
+			if tt.want == http.StatusOK && received != body {
+				t.Errorf("handler read %q, want the original body", received)
+			}
+		})
+	}
+}
+
+func TestIntegrity_RequireChecksum(t *testing.T) {
+	handler := NewIntegrity(config.IntegrityConfig{Require: true}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`)))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "checksum_required") {
+		t.Errorf("unsigned body = %d %s, want checksum_required", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("request without a body = %d, want 200", rr.Code)
+	}
+}
+
+func TestIntegrity_ResponseHeader(t *testing.T) {
+	handler := NewIntegrity(config.IntegrityConfig{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":`)
+		io.WriteString(w, `"1"}`)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rr.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", rr.Code)
+	}
+	if got := rr.Header().Get(ContentSHA256Header); got != sha256Hex(`{"id":"1"}`) {
+		t.Errorf("%s = %q, want the body hash", ContentSHA256Header, got)
+	}
+}
+
+func TestIntegrity_StreamTrailer(t *testing.T) {
+	server := httptest.NewServer(NewIntegrity(config.IntegrityConfig{}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: [DONE]\n\n")
+	})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get(ContentSHA256Header) != "" {
+		t.Error("a stream cannot carry its hash as a header")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if got := resp.Trailer.Get(ContentSHA256Header); got != sha256Hex(string(body)) {
+		t.Errorf("trailer %s = %q, want the hash of %q", ContentSHA256Header, got, body)
+	}
+}
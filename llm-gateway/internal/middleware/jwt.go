@@ -0,0 +1,462 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// jwksRetryInterval limits refetches of the JWK set, for stale keys as well
+// as tokens signed with an unknown key, so neither forged key IDs nor an
+// unreachable identity provider turn every request into a fetch
+const jwksRetryInterval = 30 * time.Second
+
+// Token validation errors; their messages are returned to callers
+var (
+	errMalformedToken   = errors.New("malformed token")
+	errUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	errUnknownKey       = errors.New("unknown signing key")
+	errInvalidSignature = errors.New("invalid signature")
+	errTokenExpired     = errors.New("token expired")
+	errTokenNotYetValid = errors.New("token not yet valid")
+	errInvalidIssuer    = errors.New("invalid issuer")
+	errInvalidAudience  = errors.New("invalid audience")
+	errMissingUserClaim = errors.New("token has no user claim")
+)
+
+// JWTIdentity is the caller a valid token identifies
+type JWTIdentity struct {
+	UserID string
+	// Tier is the rate limit tier named by jwt_auth.tier_claim, if any
+	Tier   string
+	Claims map[string]interface{}
+}
+
+// JWTAuth validates JWT access tokens against the signing keys an identity
+// provider publishes at its JWKS endpoint. Keys are fetched on first use,
+// refreshed every jwks_refresh, and refetched early when a token names a key
+// that is not known yet (key rotation).
+type JWTAuth struct {
+	cfg    config.JWTAuthConfig
+	client *http.Client
+	now    func() time.Time
+	// fetches makes concurrent callers share one JWKS fetch
+	fetches singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetched     time.Time
+	lastAttempt time.Time
+}
+
+// NewJWTAuth creates a JWT validator from config
+func NewJWTAuth(cfg config.JWTAuthConfig) *JWTAuth {
+	return &JWTAuth{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// jwtAuthErrorContextKey holds why a presented token was rejected
+const jwtAuthErrorContextKey contextKey = "jwt_auth_error"
+
+// Middleware identifies callers presenting a JWT bearer token: the token's
+// user ID and rate limit tier are added to the request context. It runs
+// before rate limiting and never rejects a request itself; RequireUser does
+// that on the routes that need a caller.
+func (a *JWTAuth) Middleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := extractAPIKey(r, DefaultAuthConfig())
+			if strings.Count(token, ".") != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := a.Validate(r.Context(), token)
+			if err != nil {
+				logger.Warn().
+					Err(err).
+					Str("ip", r.RemoteAddr).
+					Msg("Invalid access token")
+				ctx := context.WithValue(r.Context(), jwtAuthErrorContextKey, err)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDContextKey, identity.UserID)
+			if identity.Tier != "" {
+				ctx = context.WithValue(ctx, RateLimitTierContextKey, identity.Tier)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireUser returns a middleware that rejects requests JWTAuth did not
+// identify a caller for
+func RequireUser() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetUserID(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err, ok := r.Context().Value(jwtAuthErrorContextKey).(error); ok {
+				writeAuthError(w, "invalid_token", "Invalid access token: "+err.Error())
+				return
+			}
+			writeAuthError(w, "missing_token", "An access token is required")
+		})
+	}
+}
+
+// GetRateLimitTier retrieves the caller's rate limit tier from the request context
+func GetRateLimitTier(ctx context.Context) string {
+	if tier, ok := ctx.Value(RateLimitTierContextKey).(string); ok {
+		return tier
+	}
+	return ""
+}
+
+// Validate checks a token's signature and its exp, nbf, iss and aud claims,
+// and returns the caller it identifies
+func (a *JWTAuth) Validate(ctx context.Context, token string) (*JWTIdentity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errMalformedToken
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	identity := &JWTIdentity{UserID: claimString(claims[a.cfg.UserClaim]), Claims: claims}
+	if identity.UserID == "" {
+		return nil, errMissingUserClaim
+	}
+	if a.cfg.TierClaim != "" {
+		identity.Tier = claimString(claims[a.cfg.TierClaim])
+	}
+	return identity, nil
+}
+
+// checkClaims validates the registered time, issuer and audience claims
+func (a *JWTAuth) checkClaims(claims map[string]interface{}) error {
+	now := a.now()
+	skew := a.cfg.ClockSkew
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errTokenExpired
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(skew)) {
+		return errTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return errTokenNotYetValid
+	}
+
+	if a.cfg.Issuer != "" && claims["iss"] != a.cfg.Issuer {
+		return errInvalidIssuer
+	}
+	if len(a.cfg.Audiences) > 0 && !audienceMatches(claims["aud"], a.cfg.Audiences) {
+		return errInvalidAudience
+	}
+	return nil
+}
+
+// audienceMatches reports whether the aud claim (a string or a list of
+// strings) includes one of the accepted audiences
+func audienceMatches(aud interface{}, accepted []string) bool {
+	var values []string
+	switch v := aud.(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	for _, value := range values {
+		for _, a := range accepted {
+			if value == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimString returns a string or numeric claim as a string
+func claimString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// decodeSegment decodes a base64url JSON token segment into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature checks signature over signed with key for the JWS algorithm alg
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var h hash.Hash
+	var hashFunc crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		h, hashFunc = sha256.New(), crypto.SHA256
+	case "384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	default:
+		return errUnsupportedAlg
+	}
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, hashFunc, digest, signature) != nil {
+			return errInvalidSignature
+		}
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPSS(pub, hashFunc, digest, signature, nil) != nil {
+			return errInvalidSignature
+		}
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errInvalidSignature
+		}
+		// JWS ECDSA signatures are r and s as fixed-size big-endian integers
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errInvalidSignature
+		}
+	default:
+		return errUnsupportedAlg
+	}
+	return nil
+}
+
+// key returns the signing key with ID kid (or the only key, for tokens
+// without one), fetching the JWK set when it is stale or lacks the key. The
+// fetch runs without a.mu held and is shared by concurrent callers, which
+// wait for it until their own ctx ends.
+func (a *JWTAuth) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	stale := a.keys == nil || a.now().Sub(a.fetched) >= a.cfg.JWKSRefresh
+	key, ok := a.lookup(kid)
+	a.mu.Unlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	select {
+	case <-a.fetches.DoChan("jwks", func() (interface{}, error) {
+		a.refresh()
+		return nil, nil
+	}):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, errUnknownKey
+}
+
+// refresh fetches the JWK set, at most once per jwksRetryInterval. The fetch
+// is not tied to any one request, so it is bounded by jwt_auth.timeout only.
+func (a *JWTAuth) refresh() {
+	a.mu.Lock()
+	now := a.now()
+	if !a.lastAttempt.IsZero() && now.Sub(a.lastAttempt) < jwksRetryInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastAttempt = now
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+	defer cancel()
+	keys, err := a.fetchKeys(ctx)
+	if err != nil {
+		// Keep validating with the previous keys until the endpoint recovers
+		logger.Error().Err(err).Str("jwks_url", a.cfg.JWKSURL).Msg("Failed to fetch JWKS")
+		return
+	}
+	a.mu.Lock()
+	a.keys, a.fetched = keys, now
+	a.mu.Unlock()
+	logger.Debug().Int("keys", len(keys)).Msg("Fetched JWKS")
+}
+
+// lookup finds a key by ID; callers hold a.mu
+func (a *JWTAuth) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is a public key of a JWK set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys downloads the JWK set and returns its signing keys by ID
+func (a *JWTAuth) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logger.Warn().Err(err).Str("kid", jwk.Kid).Msg("Skipping unusable JWKS key")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA or EC JWK
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,395 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// testIssuer serves a JWK set for an RSA and an EC key and signs tokens with them
+type testIssuer struct {
+	t       *testing.T
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	server  *httptest.Server
+	fetches atomic.Int32
+	// rotated adds a second RSA key to the set
+	rotated atomic.Bool
+	newKey  *rsa.PrivateKey
+	// down makes the JWKS endpoint fail
+	down atomic.Bool
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := &testIssuer{t: t, rsaKey: rsaKey, ecKey: ecKey, newKey: newKey}
+	ti.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ti.fetches.Add(1)
+		if ti.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b64 := base64.RawURLEncoding.EncodeToString
+		keys := []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+		}
+		if ti.rotated.Load() {
+			keys = append(keys, map[string]string{"kty": "RSA", "kid": "rsa-2", "n": b64(newKey.N.Bytes()), "e": "AQAB"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(ti.server.Close)
+	return ti
+}
+
+func (ti *testIssuer) config() config.JWTAuthConfig {
+	return config.JWTAuthConfig{
+		Enabled:     true,
+		JWKSURL:     ti.server.URL,
+		JWKSRefresh: time.Hour,
+		Timeout:     time.Second,
+		Issuer:      "https://idp.example.com",
+		Audiences:   []string{"llm-gateway"},
+		ClockSkew:   time.Minute,
+		UserClaim:   "sub",
+		TierClaim:   "tier",
+	}
+}
+
+// sign returns a token with claims signed by kid's key
+func (ti *testIssuer) sign(alg, kid string, claims map[string]interface{}) string {
+	ti.t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch {
+	case alg == "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, ti.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case kid == "rsa-2":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, ti.newKey, crypto.SHA256, digest[:])
+	default:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, ti.rsaKey, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		ti.t.Fatal(err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub":  "user-42",
+		"iss":  "https://idp.example.com",
+		"aud":  []string{"other", "llm-gateway"},
+		"exp":  now.Add(time.Hour).Unix(),
+		"nbf":  now.Add(-time.Minute).Unix(),
+		"tier": "pro",
+	}
+}
+
+func TestJWTAuth_Validate(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+	ctx := context.Background()
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		kid := "rsa-1"
+		if alg == "ES256" {
+			kid = "ec-1"
+		}
+		identity, err := auth.Validate(ctx, ti.sign(alg, kid, validClaims()))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", alg, err)
+		}
+		if identity.UserID != "user-42" || identity.Tier != "pro" {
+			t.Errorf("%s: identity = %+v", alg, identity)
+		}
+	}
+	if ti.fetches.Load() != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", ti.fetches.Load())
+	}
+}
+
+func TestJWTAuth_RejectsInvalidTokens(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+	now := time.Now()
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tampered := ti.sign("RS256", "rsa-1", validClaims())
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", ti.sign("RS256", "rsa-1", with("exp", now.Add(-2*time.Minute).Unix())), errTokenExpired},
+		{"no expiry", ti.sign("RS256", "rsa-1", with("exp", nil)), errTokenExpired},
+		{"not yet valid", ti.sign("RS256", "rsa-1", with("nbf", now.Add(2*time.Minute).Unix())), errTokenNotYetValid},
+		{"wrong issuer", ti.sign("RS256", "rsa-1", with("iss", "https://evil.example.com")), errInvalidIssuer},
+		{"wrong audience", ti.sign("RS256", "rsa-1", with("aud", "other")), errInvalidAudience},
+		{"no subject", ti.sign("RS256", "rsa-1", with("sub", nil)), errMissingUserClaim},
+		{"tampered signature", tampered, errInvalidSignature},
+		{"key of another type", ti.sign("ES256", "rsa-1", validClaims()), errInvalidSignature},
+		{"unknown key", ti.sign("RS256", "missing", validClaims()), errUnknownKey},
+		{"encryption key", ti.sign("RS256", "enc-1", validClaims()), errUnknownKey},
+		{"malformed", "a.b", errMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := auth.Validate(context.Background(), tt.token); err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("alg none", func(t *testing.T) {
+		token := ti.sign("RS256", "rsa-1", validClaims())
+		parts := strings.Split(token, ".")
+		none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa-1"}`))
+		if _, err := auth.Validate(context.Background(), none+"."+parts[1]+"."); err != errUnsupportedAlg {
+			t.Errorf("err = %v, want %v", err, errUnsupportedAlg)
+		}
+	})
+}
+
+func TestJWTAuth_ClockSkew(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+
+	// Expired 30s ago, within the one minute tolerance
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	if _, err := auth.Validate(context.Background(), ti.sign("RS256", "rsa-1", claims)); err != nil {
+		t.Errorf("token within clock skew rejected: %v", err)
+	}
+}
+
+func TestJWTAuth_KeyRotation(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+	ctx := context.Background()
+
+	if _, err := auth.Validate(ctx, ti.sign("RS256", "rsa-1", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed with a key published after the last fetch triggers a
+	// refetch, once the retry interval has passed
+	ti.rotated.Store(true)
+	later := time.Now().Add(jwksRetryInterval)
+	auth.now = func() time.Time { return later }
+	if _, err := auth.Validate(ctx, ti.sign("RS256", "rsa-2", validClaims())); err != nil {
+		t.Fatalf("token signed with rotated key rejected: %v", err)
+	}
+	if ti.fetches.Load() != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", ti.fetches.Load())
+	}
+
+	// Unknown keys do not refetch again within the retry interval
+	auth.Validate(ctx, ti.sign("RS256", "missing", validClaims()))
+	if ti.fetches.Load() != 2 {
+		t.Errorf("JWKS refetched for unknown key within retry interval")
+	}
+}
+
+func TestJWTAuth_ConcurrentCallersShareFetch(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+	token := ti.sign("RS256", "rsa-1", validClaims())
+
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := auth.Validate(context.Background(), token); err != nil {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() != 0 || ti.fetches.Load() != 1 {
+		t.Errorf("%d validations failed, JWKS fetched %d times, want none and 1", failed.Load(), ti.fetches.Load())
+	}
+}
+
+func TestJWTAuth_StaleRefreshRateLimited(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+	ctx := context.Background()
+	token := ti.sign("RS256", "rsa-1", validClaims())
+	if _, err := auth.Validate(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once stale, a failing endpoint is retried at most every retry interval,
+	// and the previous keys keep validating meanwhile
+	ti.down.Store(true)
+	later := time.Now().Add(time.Hour)
+	auth.now = func() time.Time { return later }
+	for range 3 {
+		if _, err := auth.Validate(ctx, token); err != nil {
+			t.Fatalf("token rejected while the JWKS endpoint is down: %v", err)
+		}
+	}
+	if ti.fetches.Load() != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", ti.fetches.Load())
+	}
+
+	later = later.Add(jwksRetryInterval)
+	auth.Validate(ctx, token)
+	if ti.fetches.Load() != 3 {
+		t.Errorf("JWKS fetched %d times after the retry interval, want 3", ti.fetches.Load())
+	}
+}
+
+func TestJWTAuth_MiddlewareAndRequireUser(t *testing.T) {
+	ti := newTestIssuer(t)
+	auth := NewJWTAuth(ti.config())
+
+	var gotUser, gotTier string
+	handler := auth.Middleware()(RequireUser()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = GetUserID(r.Context())
+		gotTier = GetRateLimitTier(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+		wantType   string
+	}{
+		{"valid token", "Bearer " + ti.sign("RS256", "rsa-1", validClaims()), http.StatusOK, ""},
+		{"expired token", "Bearer " + ti.sign("RS256", "rsa-1", expired), http.StatusUnauthorized, "invalid_token"},
+		{"api key", "Bearer sk-not-a-jwt", http.StatusUnauthorized, "missing_token"},
+		{"no credentials", "", http.StatusUnauthorized, "missing_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if gotUser != "user-42" || gotTier != "pro" {
+					t.Errorf("user = %q, tier = %q", gotUser, gotTier)
+				}
+				return
+			}
+			var body struct {
+				Error struct {
+					Type string `json:"type"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid error body %q: %v", rec.Body.String(), err)
+			}
+			if body.Error.Type != tt.wantType {
+				t.Errorf("error type = %q, want %q", body.Error.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Tiers(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{
+		RequestsPerMin:  0,
+		BurstSize:       2,
+		CleanupInterval: time.Minute,
+		Tiers:           map[string]config.RateLimitTier{"pro": {RequestsPerMin: 0, BurstSize: 5}},
+	})
+	defer rl.Stop()
+
+	handler := rl.RateLimit()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(user, tier string) int {
+		req := httptest.NewRequest("GET", "/v1/models", nil)
+		ctx := context.WithValue(req.Context(), UserIDContextKey, user)
+		if tier != "" {
+			ctx = context.WithValue(ctx, RateLimitTierContextKey, tier)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		return rec.Code
+	}
+
+	allowed := func(user, tier string) int {
+		n := 0
+		for i := 0; i < 10; i++ {
+			if send(user, tier) == http.StatusOK {
+				n++
+			}
+		}
+		return n
+	}
+	if n := allowed("alice", "pro"); n != 5 {
+		t.Errorf("pro tier allowed %d requests, want 5", n)
+	}
+	if n := allowed("bob", ""); n != 2 {
+		t.Errorf("default limits allowed %d requests, want 2", n)
+	}
+	if n := allowed("carol", "unknown"); n != 2 {
+		t.Errorf("unknown tier allowed %d requests, want 2", n)
+	}
+}
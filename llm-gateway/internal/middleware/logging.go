@@ -7,8 +7,13 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"github.com/username/llm-gateway/internal/observability"
 )
 
+// logger is the middleware module logger; its level can be set via log.modules.middleware
+var logger = observability.ModuleLogger("middleware")
+
 // responseWriter wraps http.ResponseWriter to capture status code and bytes written
 type responseWriter struct {
 	http.ResponseWriter
@@ -61,20 +66,26 @@ func Logger() func(next http.Handler) http.Handler {
 			// Get request ID from chi middleware
 			requestID := middleware.GetReqID(r.Context())
 
-			// Process request
+			// Process request, collecting its provider attempts
+			r = r.WithContext(observability.WithAttemptTimeline(r.Context()))
 			next.ServeHTTP(wrapped, r)
 
 			// Calculate duration
 			duration := time.Since(start)
 
 			// Build log event
-			event := log.Info()
+			event := logger.Info()
 
 			// Adjust log level based on status code
 			if wrapped.status >= 500 {
-				event = log.Error()
+				event = logger.Error()
 			} else if wrapped.status >= 400 {
-				event = log.Warn()
+				event = logger.Warn()
+			}
+
+			// Correlate with the providers' own request IDs
+			if ids := observability.UpstreamRequestIDs(r.Context()); len(ids) > 0 {
+				event = event.Strs("upstream_request_ids", ids)
 			}
 
 			// Log the request
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// MaintenanceMode gates inbound API traffic during planned maintenance windows
+type MaintenanceMode struct {
+	mu         sync.RWMutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+	since      time.Time
+}
+
+// NewMaintenanceMode creates a maintenance switch from config
+func NewMaintenanceMode(cfg config.MaintenanceConfig) *MaintenanceMode {
+	m := &MaintenanceMode{
+		message:    cfg.Message,
+		retryAfter: cfg.RetryAfter,
+	}
+	if m.retryAfter <= 0 {
+		m.retryAfter = 5 * time.Minute
+	}
+	if cfg.Enabled {
+		m.enabled = true
+		m.since = time.Now()
+	}
+	return m
+}
+
+// Enable turns on maintenance mode. Empty message or zero retryAfter keep the current values.
+func (m *MaintenanceMode) Enable(message string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message != "" {
+		m.message = message
+	}
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+	if !m.enabled {
+		m.enabled = true
+		m.since = time.Now()
+	}
+
+	logger.Warn().
+		Str("message", m.message).
+		Dur("retry_after", m.retryAfter).
+		Msg("Maintenance mode enabled")
+}
+
+// Disable turns off maintenance mode
+func (m *MaintenanceMode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.enabled {
+		logger.Info().
+			Dur("duration", time.Since(m.since)).
+			Msg("Maintenance mode disabled")
+	}
+	m.enabled = false
+	m.since = time.Time{}
+}
+
+// Enabled reports whether maintenance mode is active
+func (m *MaintenanceMode) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled
+}
+
+// Middleware returns a middleware that rejects requests while maintenance mode is active
+func (m *MaintenanceMode) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.mu.RLock()
+			enabled, message, retryAfter := m.enabled, m.message, m.retryAfter
+			m.mu.RUnlock()
+
+			if !enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			response := map[string]interface{}{
+				"error": map[string]interface{}{
+					"message": message,
+					"type":    "service_unavailable",
+					"code":    "maintenance",
+				},
+			}
+			json.NewEncoder(w).Encode(response)
+		})
+	}
+}
+
+// Status returns the current maintenance state
+func (m *MaintenanceMode) Status() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"enabled":     m.enabled,
+		"message":     m.message,
+		"retry_after": m.retryAfter.String(),
+	}
+	if m.enabled {
+		status["since"] = m.since.UTC().Format(time.RFC3339)
+	}
+	return status
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestNewMaintenanceMode(t *testing.T) {
+	m := NewMaintenanceMode(config.MaintenanceConfig{
+		Enabled:    true,
+		RetryAfter: 2 * time.Minute,
+		Message:    "upgrading",
+	})
+
+	if !m.Enabled() {
+		t.Error("maintenance mode should be enabled from config")
+	}
+	if m.retryAfter != 2*time.Minute {
+		t.Errorf("retryAfter = %v, want 2m", m.retryAfter)
+	}
+}
+
+func TestNewMaintenanceMode_DefaultRetryAfter(t *testing.T) {
+	m := NewMaintenanceMode(config.MaintenanceConfig{})
+
+	if m.Enabled() {
+		t.Error("maintenance mode should be disabled by default")
+	}
+	if m.retryAfter != 5*time.Minute {
+		t.Errorf("retryAfter = %v, want 5m", m.retryAfter)
+	}
+}
+
+func TestMaintenanceMiddleware_Disabled(t *testing.T) {
+	m := NewMaintenanceMode(config.MaintenanceConfig{})
+
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 when maintenance disabled", rr.Code)
+	}
+}
+
+func TestMaintenanceMiddleware_Enabled(t *testing.T) {
+	m := NewMaintenanceMode(config.MaintenanceConfig{})
+	m.Enable("planned upgrade", 90*time.Second)
+
+	called := false
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	if called {
+		t.Error("next handler should not be called in maintenance mode")
+	}
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Retry-After = %s, want 90", got)
+	}
+}
+
+func TestMaintenanceMode_EnableDisable(t *testing.T) {
+	m := NewMaintenanceMode(config.MaintenanceConfig{Message: "default message"})
+
+	m.Enable("", 0)
+	status := m.Status()
+	if status["enabled"] != true {
+		t.Error("status should report enabled")
+	}
+	if status["message"] != "default message" {
+		t.Errorf("message = %v, want default message to be kept", status["message"])
+	}
+	if _, ok := status["since"]; !ok {
+		t.Error("status should include since when enabled")
+	}
+
+	m.Disable()
+	if m.Enabled() {
+		t.Error("maintenance mode should be disabled")
+	}
+	if _, ok := m.Status()["since"]; ok {
+		t.Error("status should not include since when disabled")
+	}
+}
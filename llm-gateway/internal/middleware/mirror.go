@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// mirroredHeaders are the only client headers copied to the staging gateway.
+// Everything else (cookies, auth, forwarding info) is scrubbed.
+var mirroredHeaders = []string{
+	"Content-Type",
+	"Accept",
+	"User-Agent",
+}
+
+// Mirror asynchronously copies a sample of requests to a staging gateway
+type Mirror struct {
+	targetURL    string
+	sampleRate   float64
+	apiKey       string
+	maxBodyBytes int64
+	client       *http.Client
+	slots        chan struct{}
+
+	// Statistics
+	mirrored  int64
+	dropped   int64
+	failed    int64
+	oversized int64
+}
+
+// NewMirror creates a request mirror from config
+func NewMirror(cfg config.MirrorConfig) *Mirror {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = 16
+	}
+
+	return &Mirror{
+		targetURL:    strings.TrimSuffix(cfg.TargetURL, "/"),
+		sampleRate:   cfg.SampleRate,
+		apiKey:       cfg.APIKey,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		client:       &http.Client{Timeout: timeout},
+		slots:        make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Middleware returns a middleware that mirrors sampled requests without affecting the response
+func (m *Mirror) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.sampleRate <= 0 || rand.Float64() >= m.sampleRate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, ok := m.captureBody(r)
+			if ok {
+				m.dispatch(r, body)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// captureBody reads the request body for mirroring and restores it for the real handler.
+// Bodies larger than maxBodyBytes are not mirrored.
+func (m *Mirror) captureBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+
+	limit := m.maxBodyBytes
+	if limit <= 0 {
+		limit = 1 << 20
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), r.Body))
+		return nil, false
+	}
+
+	if int64(len(buf)) > limit {
+		// Hand the already-read prefix back to the handler along with the rest
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), r.Body))
+		atomic.AddInt64(&m.oversized, 1)
+		return nil, false
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+	return buf, true
+}
+
+// dispatch sends the mirrored request in the background, dropping it if all slots are busy
+func (m *Mirror) dispatch(r *http.Request, body []byte) {
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		atomic.AddInt64(&m.dropped, 1)
+		return
+	}
+
+	mirrorReq, err := http.NewRequestWithContext(context.Background(), r.Method, m.targetURL+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		<-m.slots
+		atomic.AddInt64(&m.failed, 1)
+		return
+	}
+
+	for _, name := range mirroredHeaders {
+		if v := r.Header.Get(name); v != "" {
+			mirrorReq.Header.Set(name, v)
+		}
+	}
+	if m.apiKey != "" {
+		mirrorReq.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	mirrorReq.Header.Set("X-Mirrored-Request", "true")
+	if reqID := middleware.GetReqID(r.Context()); reqID != "" {
+		mirrorReq.Header.Set("X-Request-ID", reqID)
+	}
+
+	go func() {
+		defer func() { <-m.slots }()
+
+		resp, err := m.client.Do(mirrorReq)
+		if err != nil {
+			atomic.AddInt64(&m.failed, 1)
+			logger.Debug().Err(err).Str("path", mirrorReq.URL.Path).Msg("Mirrored request failed")
+			return
+		}
+		// Drain the body (streams included) so the staging gateway sees a full request lifecycle
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		atomic.AddInt64(&m.mirrored, 1)
+		if resp.StatusCode >= 500 {
+			logger.Debug().
+				Int("status", resp.StatusCode).
+				Str("path", mirrorReq.URL.Path).
+				Msg("Staging gateway returned server error for mirrored request")
+		}
+	}()
+}
+
+// GetStats returns mirroring statistics
+func (m *Mirror) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"target_url":  m.targetURL,
+		"sample_rate": m.sampleRate,
+		"mirrored":    atomic.LoadInt64(&m.mirrored),
+		"dropped":     atomic.LoadInt64(&m.dropped),
+		"failed":      atomic.LoadInt64(&m.failed),
+		"oversized":   atomic.LoadInt64(&m.oversized),
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestMirror_CopiesScrubbedRequest(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer staging.Close()
+
+	m := NewMirror(config.MirrorConfig{
+		Enabled:    true,
+		TargetURL:  staging.URL,
+		SampleRate: 1.0,
+		APIKey:     "staging-key",
+		Timeout:    5 * time.Second,
+	})
+
+	var handlerBody string
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handlerBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer prod-secret")
+	req.Header.Set("Cookie", "session=abc")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if handlerBody != `{"model":"gpt-4o"}` {
+		t.Errorf("handler body = %q, want original body", handlerBody)
+	}
+
+	select {
+	case got := <-received:
+		if got.URL.Path != "/v1/chat/completions" {
+			t.Errorf("mirrored path = %s, want /v1/chat/completions", got.URL.Path)
+		}
+		if auth := got.Header.Get("Authorization"); auth != "Bearer staging-key" {
+			t.Errorf("Authorization = %s, want staging key", auth)
+		}
+		if got.Header.Get("Cookie") != "" {
+			t.Error("Cookie header should be scrubbed")
+		}
+		if got.Header.Get("X-Mirrored-Request") != "true" {
+			t.Error("mirrored request should be marked")
+		}
+		if body := <-bodies; body != `{"model":"gpt-4o"}` {
+			t.Errorf("mirrored body = %q", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
+
+func TestMirror_ZeroSampleRate(t *testing.T) {
+	m := NewMirror(config.MirrorConfig{TargetURL: "http://127.0.0.1:1", SampleRate: 0})
+
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader("{}")))
+
+	stats := m.GetStats()
+	if stats["mirrored"].(int64) != 0 || stats["failed"].(int64) != 0 {
+		t.Errorf("no request should be mirrored at sample rate 0: %v", stats)
+	}
+}
+
+func TestMirror_OversizedBodyNotMirrored(t *testing.T) {
+	m := NewMirror(config.MirrorConfig{
+		TargetURL:    "http://127.0.0.1:1",
+		SampleRate:   1.0,
+		MaxBodyBytes: 4,
+	})
+
+	var handlerBody string
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handlerBody = string(b)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader("0123456789")))
+
+	if handlerBody != "0123456789" {
+		t.Errorf("handler body = %q, want full body restored", handlerBody)
+	}
+	if m.GetStats()["oversized"].(int64) != 1 {
+		t.Error("oversized counter should be incremented")
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/username/llm-gateway/internal/notify"
+)
+
+// QuotaWarningHeader warns callers nearing a quota, e.g.
+// "budget; threshold=80; used=82.5"
+const QuotaWarningHeader = "X-Quota-Warning"
+
+// Quotas callers are warned about
+const (
+	quotaBudget    = "budget"
+	quotaRateLimit = "rate_limit"
+)
+
+// sortedThresholds returns a sorted copy of warning thresholds (percentages)
+func sortedThresholds(thresholds []float64) []float64 {
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// reachedThreshold returns the highest threshold used (a percentage) has
+// reached, or 0 if it is below all of them
+func reachedThreshold(thresholds []float64, used float64) float64 {
+	reached := 0.0
+	for _, threshold := range thresholds {
+		if used >= threshold {
+			reached = threshold
+		}
+	}
+	return reached
+}
+
+// setQuotaWarning adds a warning header for quota at threshold
+func setQuotaWarning(h http.Header, quota string, threshold, used float64) {
+	h.Add(QuotaWarningHeader, quota+
+		"; threshold="+strconv.FormatFloat(threshold, 'f', -1, 64)+
+		"; used="+strconv.FormatFloat(math.Round(used*10)/10, 'f', -1, 64))
+}
+
+// emitQuotaWarning logs a quota crossing a threshold and emits a
+// "quota.warning" event to the notification sink. subject names who the quota
+// belongs to, e.g. "tenant" or "client".
+func emitQuotaWarning(quota, subject, id string, threshold, used float64, limit int64) {
+	logger.Warn().
+		Str("quota", quota).
+		Str(subject, id).
+		Float64("threshold", threshold).
+		Float64("used_percent", used).
+		Msg("Quota warning threshold crossed")
+
+	notify.Default().Emit(notify.Event{
+		Type: "quota.warning",
+		Data: map[string]interface{}{
+			"quota":        quota,
+			subject:        id,
+			"threshold":    threshold,
+			"used_percent": math.Round(used*10) / 10,
+			"limit":        limit,
+		},
+	})
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/notify"
+)
+
+// captureEvents routes notifications to a test webhook and returns a function
+// that stops the sink and returns the events delivered
+func captureEvents(t *testing.T) func() []notify.Event {
+	t.Helper()
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	sink := notify.New(config.NotificationsConfig{WebhookURL: server.URL, Timeout: time.Second, QueueSize: 10})
+	notify.SetDefault(sink)
+	t.Cleanup(func() {
+		notify.SetDefault(nil)
+		server.Close()
+	})
+	return func() []notify.Event {
+		sink.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		return events
+	}
+}
+
+func TestUsageTracker_QuotaWarnings(t *testing.T) {
+	events := captureEvents(t)
+	u := NewUsageTracker()
+	u.SetBudgets(map[string]int64{"acme": 100})
+	u.SetQuotaWarnings([]float64{95, 80})
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddTokenUsage(r.Context(), 20, 10)
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set(TenantHeader, "acme")
+		req = req.WithContext(WithTrustedTenant(req.Context()))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 30, 60 and 90 tokens used after each request; warnings reflect usage before it
+	for i := 0; i < 3; i++ {
+		if warning := send().Header().Get(QuotaWarningHeader); warning != "" {
+			t.Errorf("request %d: unexpected warning %q", i+1, warning)
+		}
+	}
+	if warning := send().Header().Get(QuotaWarningHeader); warning != "budget; threshold=80; used=90" {
+		t.Errorf("warning = %q", warning)
+	}
+	if code := send().Code; code != http.StatusTooManyRequests {
+		t.Errorf("over budget status = %d, want 429", code)
+	}
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %+v", got)
+	}
+	for i, threshold := range []float64{80, 95} {
+		if got[i].Type != "quota.warning" || got[i].Data["tenant"] != "acme" || got[i].Data["threshold"] != threshold {
+			t.Errorf("event %d = %+v", i, got[i])
+		}
+	}
+}
+
+func TestRateLimiter_QuotaWarnings(t *testing.T) {
+	events := captureEvents(t)
+	// No refill, so usage grows by exactly 10% per request
+	rl := NewRateLimiter(config.RateLimitConfig{RequestsPerMin: 0, BurstSize: 10, CleanupInterval: time.Minute})
+	defer rl.Stop()
+	rl.SetQuotaWarnings([]float64{80, 95})
+	handler := rl.RateLimit()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var warnings []string
+	for i := 0; i < 11; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		warnings = append(warnings, rec.Header().Get(QuotaWarningHeader))
+	}
+	if warnings[6] != "" {
+		t.Errorf("unexpected warning at 70%%: %q", warnings[6])
+	}
+	if warnings[7] != "rate_limit; threshold=80; used=80" {
+		t.Errorf("warning at 80%% = %q", warnings[7])
+	}
+	if warnings[9] != "rate_limit; threshold=95; used=100" {
+		t.Errorf("warning at 100%% = %q", warnings[9])
+	}
+
+	got := events()
+	if len(got) != 2 || got[0].Data["threshold"] != 80.0 || got[1].Data["threshold"] != 95.0 {
+		t.Errorf("expected events at 80 and 95, got %+v", got)
+	}
+}
+
+func TestRateLimiter_QuotaWarningRearms(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{RequestsPerMin: 0, BurstSize: 10, CleanupInterval: time.Minute})
+	defer rl.Stop()
+	rl.SetQuotaWarnings([]float64{80})
+
+	for i := 0; i < 7; i++ {
+		rl.consume("client")
+	}
+	if _, _, crossed := rl.consume("client"); crossed != 80 {
+		t.Fatalf("crossed = %v, want 80", crossed)
+	}
+	if _, _, crossed := rl.consume("client"); crossed != 0 {
+		t.Errorf("threshold crossed twice without dropping below it")
+	}
+
+	// Refilled below the threshold, the next crossing warns again
+	bucket := rl.getBucket("client", 10)
+	bucket.mu.Lock()
+	bucket.tokens = 5
+	bucket.mu.Unlock()
+	rl.consume("client")
+	rl.consume("client")
+	if _, _, crossed := rl.consume("client"); crossed != 80 {
+		t.Errorf("crossed after refill = %v, want 80", crossed)
+	}
+}
@@ -2,13 +2,13 @@ package middleware
 
 import (
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"net/http"
 	"sync"
 	"time"
 
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/rs/zerolog/log"
-
+	"github.com/username/llm-gateway/internal/clock"
 	"github.com/username/llm-gateway/internal/config"
 )
 
@@ -20,23 +20,36 @@ type RateLimiter struct {
 	burstSize       int
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
+	// warnThresholds are the percentages of the burst at which callers are warned, sorted
+	warnThresholds []float64
+	// tiers override the limits for callers whose access token names a tier
+	tiers map[string]config.RateLimitTier
+	// fingerprintUserAgent adds a User-Agent hash to the ID of anonymous callers
+	fingerprintUserAgent bool
+	// clock refills the buckets; tests replace it with a fake
+	clock clock.Clock
 }
 
 // tokenBucket represents a single client's rate limit bucket
 type tokenBucket struct {
 	tokens     float64
 	lastRefill time.Time
-	mu         sync.Mutex
+	// warned is the highest warning threshold crossed since usage last dropped below it
+	warned float64
+	mu     sync.Mutex
 }
 
 // NewRateLimiter creates a new rate limiter from config
 func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
 	rl := &RateLimiter{
-		buckets:         make(map[string]*tokenBucket),
-		requestsPerMin:  cfg.RequestsPerMin,
-		burstSize:       cfg.BurstSize,
-		cleanupInterval: cfg.CleanupInterval,
-		stopCleanup:     make(chan struct{}),
+		buckets:              make(map[string]*tokenBucket),
+		requestsPerMin:       cfg.RequestsPerMin,
+		burstSize:            cfg.BurstSize,
+		cleanupInterval:      cfg.CleanupInterval,
+		stopCleanup:          make(chan struct{}),
+		tiers:                cfg.Tiers,
+		fingerprintUserAgent: cfg.FingerprintUserAgent,
+		clock:                clock.Real,
 	}
 
 	// Start cleanup goroutine to prevent memory leaks
@@ -49,14 +62,22 @@ func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
 func (rl *RateLimiter) RateLimit() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get client identifier (API key > IP address)
+			// Get client identifier (API key > user > IP address)
 			clientID := rl.getClientID(r)
+			limits := rl.limits(GetRateLimitTier(r.Context()))
 
 			// Check rate limit
-			if !rl.allow(clientID) {
+			allowed, used, crossed := rl.consumeWithin(clientID, limits)
+			if !allowed {
 				rl.writeRateLimitError(w, clientID)
 				return
 			}
+			if threshold := reachedThreshold(rl.thresholds(), used); threshold > 0 {
+				setQuotaWarning(w.Header(), quotaRateLimit, threshold, used)
+			}
+			if crossed > 0 {
+				emitQuotaWarning(quotaRateLimit, "client", clientID, crossed, used, int64(limits.BurstSize))
+			}
 
 			next.ServeHTTP(w, r)
 		})
@@ -66,51 +87,120 @@ func (rl *RateLimiter) RateLimit() func(http.Handler) http.Handler {
 // getClientID extracts client identifier from request
 func (rl *RateLimiter) getClientID(r *http.Request) string {
 	// Priority: API Key > X-Forwarded-For > Remote Address
+	// Managed keys are limited by ID, as their secrets can share a prefix
+	if keyID := GetKeyID(r.Context()); keyID != "" {
+		return "key:" + keyID
+	}
 	if apiKey := r.Context().Value(APIKeyContextKey); apiKey != nil {
 		if key, ok := apiKey.(string); ok && key != "" {
 			return "key:" + key[:min(8, len(key))] + "***" // Partially mask for logging
 		}
 	}
 
-	// Use request ID for tracking
-	if reqID := middleware.GetReqID(r.Context()); reqID != "" {
-		// Fall back to IP-based limiting
+	// Callers identified by an access token are limited per user
+	if userID := GetUserID(r.Context()); userID != "" {
+		return "user:" + userID
 	}
 
-	// Use IP address
-	return "ip:" + r.RemoteAddr
+	// Anonymous callers are limited by IP, without the source port, which
+	// differs per connection; RemoteAddr holds the client behind trusted
+	// proxies (see RealIP)
+	clientID := "ip:" + remoteIP(r)
+	if rl.fingerprintUserAgent {
+		hash := fnv.New32a()
+		hash.Write([]byte(r.UserAgent()))
+		clientID += fmt.Sprintf("#ua:%08x", hash.Sum32())
+	}
+	return clientID
+}
+
+// SetLimits replaces the default and tier limits, e.g. on a config reload.
+// Buckets keep their tokens, capped at the new burst size on their next request.
+func (rl *RateLimiter) SetLimits(cfg config.RateLimitConfig) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.requestsPerMin = cfg.RequestsPerMin
+	rl.burstSize = cfg.BurstSize
+	rl.tiers = cfg.Tiers
+}
+
+// SetQuotaWarnings sets the percentages of the burst at which callers are
+// warned; nil disables warnings
+func (rl *RateLimiter) SetQuotaWarnings(thresholds []float64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.warnThresholds = sortedThresholds(thresholds)
+}
+
+func (rl *RateLimiter) thresholds() []float64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.warnThresholds
+}
+
+// limits returns the limits of a caller tier, or the default limits for
+// callers without a known tier
+func (rl *RateLimiter) limits(tier string) config.RateLimitTier {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	if limits, ok := rl.tiers[tier]; ok && tier != "" {
+		return limits
+	}
+	return config.RateLimitTier{RequestsPerMin: rl.requestsPerMin, BurstSize: rl.burstSize}
 }
 
 // allow checks if a request should be allowed based on token bucket
 func (rl *RateLimiter) allow(clientID string) bool {
-	bucket := rl.getBucket(clientID)
+	allowed, _, _ := rl.consume(clientID)
+	return allowed
+}
+
+// consume takes a token from the client's bucket under the default limits
+func (rl *RateLimiter) consume(clientID string) (bool, float64, float64) {
+	return rl.consumeWithin(clientID, rl.limits(""))
+}
+
+// consumeWithin takes a token from the client's bucket if one is left. It
+// returns whether the request is allowed, the percentage of the burst in use,
+// and the warning threshold the request crossed (0 if none). A threshold is
+// crossed again only after usage has dropped below it.
+func (rl *RateLimiter) consumeWithin(clientID string, limits config.RateLimitTier) (bool, float64, float64) {
+	thresholds := rl.thresholds()
+	bucket := rl.getBucket(clientID, limits.BurstSize)
 
 	bucket.mu.Lock()
 	defer bucket.mu.Unlock()
 
 	// Refill tokens based on time passed
-	now := time.Now()
+	now := rl.clock.Now()
 	elapsed := now.Sub(bucket.lastRefill).Seconds()
-	tokensPerSecond := float64(rl.requestsPerMin) / 60.0
+	tokensPerSecond := float64(limits.RequestsPerMin) / 60.0
 
 	// Add new tokens (capped at burst size)
 	bucket.tokens += elapsed * tokensPerSecond
-	if bucket.tokens > float64(rl.burstSize) {
-		bucket.tokens = float64(rl.burstSize)
+	if bucket.tokens > float64(limits.BurstSize) {
+		bucket.tokens = float64(limits.BurstSize)
 	}
 	bucket.lastRefill = now
 
 	// Check if we have enough tokens
-	if bucket.tokens >= 1.0 {
-		bucket.tokens -= 1.0
-		return true
+	if bucket.tokens < 1.0 {
+		return false, 100, 0
 	}
+	bucket.tokens -= 1.0
 
-	return false
+	used := (1 - bucket.tokens/float64(limits.BurstSize)) * 100
+	threshold := reachedThreshold(thresholds, used)
+	crossed := 0.0
+	if threshold > bucket.warned {
+		crossed = threshold
+	}
+	bucket.warned = threshold
+	return true, used, crossed
 }
 
 // getBucket gets or creates a token bucket for the client
-func (rl *RateLimiter) getBucket(clientID string) *tokenBucket {
+func (rl *RateLimiter) getBucket(clientID string, burstSize int) *tokenBucket {
 	rl.mu.RLock()
 	bucket, exists := rl.buckets[clientID]
 	rl.mu.RUnlock()
@@ -129,8 +219,8 @@ func (rl *RateLimiter) getBucket(clientID string) *tokenBucket {
 	}
 
 	bucket = &tokenBucket{
-		tokens:     float64(rl.burstSize), // Start with full bucket
-		lastRefill: time.Now(),
+		tokens:     float64(burstSize), // Start with full bucket
+		lastRefill: rl.clock.Now(),
 	}
 	rl.buckets[clientID] = bucket
 
@@ -157,7 +247,7 @@ func (rl *RateLimiter) removeStale() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	staleThreshold := time.Now().Add(-5 * time.Minute)
+	staleThreshold := rl.clock.Now().Add(-5 * time.Minute)
 	staleCount := 0
 
 	for clientID, bucket := range rl.buckets {
@@ -170,7 +260,7 @@ func (rl *RateLimiter) removeStale() {
 	}
 
 	if staleCount > 0 {
-		log.Debug().
+		logger.Debug().
 			Int("removed", staleCount).
 			Int("remaining", len(rl.buckets)).
 			Msg("Rate limiter cleanup completed")
@@ -184,14 +274,15 @@ func (rl *RateLimiter) Stop() {
 
 // writeRateLimitError writes a rate limit exceeded error response
 func (rl *RateLimiter) writeRateLimitError(w http.ResponseWriter, clientID string) {
-	log.Warn().
+	requestsPerMin := rl.limits("").RequestsPerMin
+	logger.Warn().
 		Str("client_id", clientID).
-		Int("requests_per_min", rl.requestsPerMin).
+		Int("requests_per_min", requestsPerMin).
 		Msg("Rate limit exceeded")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Retry-After", "60")
-	w.Header().Set("X-RateLimit-Limit", string(rune(rl.requestsPerMin)))
+	w.Header().Set("X-RateLimit-Limit", string(rune(requestsPerMin)))
 	w.Header().Set("X-RateLimit-Remaining", "0")
 	w.WriteHeader(http.StatusTooManyRequests)
 
@@ -212,9 +303,9 @@ func (rl *RateLimiter) GetStats() map[string]interface{} {
 	defer rl.mu.RUnlock()
 
 	return map[string]interface{}{
-		"active_clients":    len(rl.buckets),
-		"requests_per_min":  rl.requestsPerMin,
-		"burst_size":        rl.burstSize,
-		"cleanup_interval":  rl.cleanupInterval.String(),
+		"active_clients":   len(rl.buckets),
+		"requests_per_min": rl.requestsPerMin,
+		"burst_size":       rl.burstSize,
+		"cleanup_interval": rl.cleanupInterval.String(),
 	}
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/username/llm-gateway/internal/clock"
 	"github.com/username/llm-gateway/internal/config"
 )
 
@@ -59,6 +61,21 @@ func TestRateLimiter_Allow(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_SetLimits(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{Enabled: true, RequestsPerMin: 60, BurstSize: 2, CleanupInterval: time.Minute})
+	defer rl.Stop()
+
+	rl.SetLimits(config.RateLimitConfig{RequestsPerMin: 60, BurstSize: 4})
+	for i := 0; i < 4; i++ {
+		if !rl.allow("reloaded-client") {
+			t.Errorf("request %d should be allowed within the reloaded burst", i+1)
+		}
+	}
+	if rl.allow("reloaded-client") {
+		t.Error("request after the reloaded burst should be denied")
+	}
+}
+
 func TestRateLimiter_TokenRefill(t *testing.T) {
 	cfg := config.RateLimitConfig{
 		Enabled:         true,
@@ -69,6 +86,8 @@ func TestRateLimiter_TokenRefill(t *testing.T) {
 
 	rl := NewRateLimiter(cfg)
 	defer rl.Stop()
+	fake := clock.NewFake(time.Now())
+	rl.clock = fake
 
 	clientID := "test-refill"
 
@@ -82,8 +101,8 @@ func TestRateLimiter_TokenRefill(t *testing.T) {
 		t.Error("second request should be denied")
 	}
 
-	// Wait for refill (100ms should add ~1 token at 10/sec)
-	time.Sleep(150 * time.Millisecond)
+	// 100ms adds 1 token at 10/sec
+	fake.Advance(100 * time.Millisecond)
 
 	// Should be allowed after refill
 	if !rl.allow(clientID) {
@@ -196,8 +215,31 @@ func TestRateLimiter_GetClientID_WithIP(t *testing.T) {
 
 	clientID := rl.getClientID(req)
 
-	if clientID != "ip:192.168.1.100:54321" {
-		t.Errorf("getClientID = %s, want ip:192.168.1.100:54321", clientID)
+	// The source port differs per connection, so it is not part of the ID
+	if clientID != "ip:192.168.1.100" {
+		t.Errorf("getClientID = %s, want ip:192.168.1.100", clientID)
+	}
+}
+
+func TestRateLimiter_GetClientID_UserAgentFingerprint(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{RequestsPerMin: 60, BurstSize: 10, CleanupInterval: time.Minute, FingerprintUserAgent: true})
+	defer rl.Stop()
+
+	request := func(port, userAgent string) *http.Request {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "203.0.113.7:" + port
+		req.Header.Set("User-Agent", userAgent)
+		return req
+	}
+	curl := rl.getClientID(request("1111", "curl/8.0"))
+	if !strings.HasPrefix(curl, "ip:203.0.113.7#ua:") {
+		t.Errorf("getClientID = %s, want an IP and User-Agent fingerprint", curl)
+	}
+	if again := rl.getClientID(request("2222", "curl/8.0")); again != curl {
+		t.Errorf("same client on another port = %s, want %s", again, curl)
+	}
+	if other := rl.getClientID(request("1111", "python-requests/2.31")); other == curl {
+		t.Errorf("clients with different User-Agents share the ID %s", other)
 	}
 }
 
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets a request's RemoteAddr to the client address reported by its
+// proxies. X-Forwarded-For is honored only on connections from one of the
+// trusted proxies (IPs or CIDRs), and the client is the last address in the
+// chain that is not itself a trusted proxy; X-Real-IP is ignored. Without
+// trusted proxies, forwarding headers are ignored and the peer address is used,
+// so clients cannot pick the address they are rate limited by.
+func RealIP(trusted []string) func(http.Handler) http.Handler {
+	proxies := parseTrustedProxies(trusted)
+	if len(proxies) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrustedProxy(remoteIP(r), proxies) {
+				// Trusted proxies may also name the tenant (see TenantID)
+				r = r.WithContext(WithTrustedTenant(r.Context()))
+			}
+			if client, ok := forwardedClient(r, proxies); ok {
+				r.RemoteAddr = client
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseTrustedProxies parses IPs and CIDRs, skipping invalid entries (the
+// configuration is validated on load)
+func parseTrustedProxies(trusted []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range trusted {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// forwardedClient returns the client address of a request that came through
+// trusted proxies, walking X-Forwarded-For from the nearest hop
+func forwardedClient(r *http.Request, proxies []netip.Prefix) (string, bool) {
+	if !isTrustedProxy(remoteIP(r), proxies) {
+		return "", false
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		client = hop
+		if !isTrustedProxy(hop, proxies) {
+			break
+		}
+	}
+	return client, client != ""
+}
+
+// isTrustedProxy reports whether ip is within one of the trusted proxies
+func isTrustedProxy(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP of a request's RemoteAddr, without its port
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
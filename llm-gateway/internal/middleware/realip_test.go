@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "198.51.100.4:5000", "", "198.51.100.4:5000"},
+		{"spoofed header from untrusted peer", "198.51.100.4:5000", "1.2.3.4", "198.51.100.4:5000"},
+		{"client behind trusted proxy", "10.0.0.2:6000", "203.0.113.7", "203.0.113.7"},
+		{"client spoofing behind trusted proxies", "10.0.0.2:6000", "1.2.3.4, 203.0.113.7, 10.0.0.9", "203.0.113.7"},
+		{"invalid hop ends the chain", "10.0.0.2:6000", "garbage, 203.0.113.7", "203.0.113.7"},
+		{"trusted proxy without header", "10.0.0.2:6000", "", "10.0.0.2:6000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIP([]string{"10.0.0.0/8", "192.0.2.1"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			req.Header.Set("X-Real-IP", "9.9.9.9")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIP_TrustedProxyNamesTenant(t *testing.T) {
+	var tenant string
+	handler := RealIP([]string{"10.0.0.0/8"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = TenantID(r)
+	}))
+
+	for remoteAddr, want := range map[string]string{
+		"10.0.0.2:6000":     "acme",
+		"198.51.100.4:5000": "anonymous",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(TenantHeader, "acme")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if tenant != want {
+			t.Errorf("tenant from %s = %s, want %s", remoteAddr, tenant, want)
+		}
+	}
+}
+
+func TestRealIP_NoTrustedProxiesIgnoresHeaders(t *testing.T) {
+	var got string
+	handler := RealIP(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "198.51.100.4:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("X-Real-IP", "9.9.9.9")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "198.51.100.4:5000" {
+		t.Errorf("RemoteAddr = %s, want the peer address", got)
+	}
+}
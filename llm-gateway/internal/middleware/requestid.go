@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the request ID, from the client and back to it
+const requestIDHeader = "X-Request-Id"
+
+// crockford is the Crockford base32 alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// RequestID returns middleware that gives each request an ID in the
+// server.request_id_format format, and echoes it in X-Request-Id. An ID sent
+// by the client in X-Request-Id is kept. The ID is stored where chi's
+// GetReqID finds it, so logs and records are unchanged by the format.
+func RequestID(format string) func(http.Handler) http.Handler {
+	echo := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(requestIDHeader, middleware.GetReqID(r.Context()))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	generate := newRequestIDGenerator(format)
+	if generate == nil {
+		return func(next http.Handler) http.Handler {
+			return middleware.RequestID(echo(next))
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		next = echo(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if id == "" {
+				id = generate()
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, id)))
+		})
+	}
+}
+
+// newRequestIDGenerator returns the ID generator of a format, or nil for
+// chi's sequential IDs
+func newRequestIDGenerator(format string) func() string {
+	switch format {
+	case "uuidv4":
+		return uuid.NewString
+	case "uuidv7":
+		return func() string {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return uuid.NewString()
+			}
+			return id.String()
+		}
+	case "ulid":
+		return func() string { return newULID(time.Now()) }
+	}
+	return nil
+}
+
+// newULID returns a ULID: 48 bits of Unix milliseconds and 80 random bits,
+// as 26 Crockford base32 characters that sort by time
+func newULID(now time.Time) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(now.UnixMilli())<<16)
+	rand.Read(b[6:])
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+func TestRequestID_Formats(t *testing.T) {
+	ulidPattern := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	tests := []struct {
+		format string
+		valid  func(id string) bool
+	}{
+		{"sequential", func(id string) bool { return regexp.MustCompile(`/\w+-0*\d+$`).MatchString(id) }},
+		{"uuidv4", func(id string) bool { u, err := uuid.Parse(id); return err == nil && u.Version() == 4 }},
+		{"uuidv7", func(id string) bool { u, err := uuid.Parse(id); return err == nil && u.Version() == 7 }},
+		{"ulid", ulidPattern.MatchString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got string
+			handler := RequestID(tt.format)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.GetReqID(r.Context())
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+			if !tt.valid(got) {
+				t.Errorf("request ID %q is not a %s ID", got, tt.format)
+			}
+			if echoed := rec.Header().Get("X-Request-Id"); echoed != got {
+				t.Errorf("X-Request-Id = %q, want %q", echoed, got)
+			}
+		})
+	}
+}
+
+func TestRequestID_KeepsClientID(t *testing.T) {
+	var got string
+	handler := RequestID("ulid")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetReqID(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "client-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "client-123" {
+		t.Errorf("request ID = %q, want the client's", got)
+	}
+}
+
+func TestNewULID_SortsByTime(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	earlier, later := newULID(start), newULID(start.Add(time.Millisecond))
+	if earlier >= later {
+		t.Errorf("ULID %s does not sort before %s, a millisecond later", earlier, later)
+	}
+	// The first 10 characters encode the timestamp
+	if newULID(start)[:10] != earlier[:10] {
+		t.Errorf("ULIDs of the same millisecond have different time parts")
+	}
+	if got := newULID(time.UnixMilli(0))[:10]; got != "0000000000" {
+		t.Errorf("time part of the epoch = %s, want 0000000000", got)
+	}
+}
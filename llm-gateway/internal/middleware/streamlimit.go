@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+const (
+	// StreamLimitHeader reports the per-key concurrent stream limit
+	StreamLimitHeader = "X-Concurrent-Streams-Limit"
+	// StreamCountHeader reports the streams the key currently has open
+	StreamCountHeader = "X-Concurrent-Streams"
+)
+
+type streamLimiterContextKey struct{}
+
+// StreamLimiter caps the number of simultaneous streaming responses per API key
+type StreamLimiter struct {
+	mu        sync.Mutex
+	open      map[string]int
+	maxPerKey int
+}
+
+// NewStreamLimiter creates a new per-key stream limiter from config
+func NewStreamLimiter(cfg config.StreamLimitConfig) *StreamLimiter {
+	return &StreamLimiter{
+		open:      make(map[string]int),
+		maxPerKey: cfg.MaxPerKey,
+	}
+}
+
+// Middleware makes the limiter available to handlers via AcquireStream.
+// Whether a request streams is only known once its body is parsed.
+func (sl *StreamLimiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), streamLimiterContextKey{}, sl)))
+		})
+	}
+}
+
+// AcquireStream reserves a stream slot for the request's API key. If the key
+// is at its limit it writes a 429 response and returns false; otherwise the
+// caller must call release when the stream ends.
+func AcquireStream(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	sl, found := r.Context().Value(streamLimiterContextKey{}).(*StreamLimiter)
+	if !found {
+		return func() {}, true
+	}
+
+	key := streamKey(r)
+	sl.mu.Lock()
+	count := sl.open[key]
+	if count >= sl.maxPerKey {
+		sl.mu.Unlock()
+		sl.writeLimitError(w, key, count)
+		return nil, false
+	}
+	sl.open[key] = count + 1
+	sl.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sl.mu.Lock()
+			defer sl.mu.Unlock()
+			if sl.open[key] <= 1 {
+				delete(sl.open, key)
+			} else {
+				sl.open[key]--
+			}
+		})
+	}, true
+}
+
+// streamKey identifies the client: the authenticated API key, the presented
+// API key, or the client IP (without the port, which differs per connection)
+func streamKey(r *http.Request) string {
+	if key := RequestAPIKey(r); key != "" {
+		return "key:" + key
+	}
+	return "ip:" + remoteIP(r)
+}
+
+// writeLimitError writes a concurrent stream limit exceeded error response
+func (sl *StreamLimiter) writeLimitError(w http.ResponseWriter, key string, count int) {
+	logger.Warn().
+		Str("client_id", MaskAPIKey(key)).
+		Int("open_streams", count).
+		Int("max_per_key", sl.maxPerKey).
+		Msg("Concurrent stream limit exceeded")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", "1")
+	w.Header().Set(StreamLimitHeader, strconv.Itoa(sl.maxPerKey))
+	w.Header().Set(StreamCountHeader, strconv.Itoa(count))
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": "Too many concurrent streams for this API key (" + strconv.Itoa(count) + " open, limit " + strconv.Itoa(sl.maxPerKey) + "). Close a stream and retry.",
+			"type":    "rate_limit_error",
+			"code":    "concurrent_stream_limit_exceeded",
+		},
+	})
+}
+
+// GetStats returns the number of keys with open streams and the total open streams
+func (sl *StreamLimiter) GetStats() map[string]interface{} {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	total := 0
+	for _, count := range sl.open {
+		total += count
+	}
+	return map[string]interface{}{
+		"max_per_key":  sl.maxPerKey,
+		"active_keys":  len(sl.open),
+		"open_streams": total,
+	}
+}
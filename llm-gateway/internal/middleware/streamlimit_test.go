@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// acquireHandler tries to open a stream and parks the release func in releases
+func acquireHandler(releases *[]func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := AcquireStream(w, r)
+		if !ok {
+			return
+		}
+		*releases = append(*releases, release)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func streamRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	return req
+}
+
+func TestStreamLimiter_PerKeyLimit(t *testing.T) {
+	sl := NewStreamLimiter(config.StreamLimitConfig{Enabled: true, MaxPerKey: 2})
+	var releases []func()
+	handler := sl.Middleware()(acquireHandler(&releases))
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, streamRequest("sk-client-a"))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("stream %d: status = %d, want 200", i, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, streamRequest("sk-client-a"))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rr.Code)
+	}
+	if rr.Header().Get(StreamCountHeader) != "2" || rr.Header().Get(StreamLimitHeader) != "2" {
+		t.Errorf("unexpected headers: %v", rr.Header())
+	}
+
+	// Another key is unaffected
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, streamRequest("sk-client-b"))
+	if rr.Code != http.StatusOK {
+		t.Errorf("other key status = %d, want 200", rr.Code)
+	}
+
+	// Releasing a stream frees a slot; releasing twice is harmless
+	releases[0]()
+	releases[0]()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, streamRequest("sk-client-a"))
+	if rr.Code != http.StatusOK {
+		t.Errorf("status after release = %d, want 200", rr.Code)
+	}
+
+	if stats := sl.GetStats(); stats["open_streams"] != 3 {
+		t.Errorf("open_streams = %v, want 3", stats["open_streams"])
+	}
+}
+
+func TestAcquireStream_NoLimiter(t *testing.T) {
+	release, ok := AcquireStream(httptest.NewRecorder(), streamRequest("sk-client-a"))
+	if !ok {
+		t.Fatal("expected stream to be allowed without a limiter")
+	}
+	release()
+}
+
+func TestStreamLimiter_AnonymousClientsCountedByIP(t *testing.T) {
+	sl := NewStreamLimiter(config.StreamLimitConfig{Enabled: true, MaxPerKey: 1})
+	var releases []func()
+	handler := sl.Middleware()(acquireHandler(&releases))
+
+	send := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send("198.51.100.4:5000"); code != http.StatusOK {
+		t.Fatalf("first stream status = %d, want 200", code)
+	}
+	// A new connection from the same client has another port but the same limit
+	if code := send("198.51.100.4:5001"); code != http.StatusTooManyRequests {
+		t.Errorf("second connection status = %d, want 429", code)
+	}
+	if code := send("198.51.100.5:5000"); code != http.StatusOK {
+		t.Errorf("other client status = %d, want 200", code)
+	}
+}
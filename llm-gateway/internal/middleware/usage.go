@@ -0,0 +1,465 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/analytics"
+	"github.com/username/llm-gateway/internal/canary"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// TenantHeader lets trusted proxies and admin callers name the tenant of a request
+const TenantHeader = "X-Tenant-ID"
+
+// tenantUsage holds one tenant's counters for one day
+type tenantUsage struct {
+	Requests         int64 `json:"requests"`
+	Errors           int64 `json:"errors"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	// SummarizationPromptTokens and SummarizationCompletionTokens count the
+	// tokens spent summarizing long conversations, apart from the requests' own
+	SummarizationPromptTokens     int64 `json:"summarization_prompt_tokens"`
+	SummarizationCompletionTokens int64 `json:"summarization_completion_tokens"`
+	// endUsers counts requests per end user (the request's "user" field)
+	endUsers map[string]int64
+	// useCaseTokens counts tokens per request use case (see SetUseCase)
+	useCaseTokens map[string]int64
+	// warned is the highest budget warning threshold crossed today
+	warned float64
+	// deletedAt is set when the tenant's data was deleted; the counters are
+	// hidden and purged once the deletion grace period ends
+	deletedAt time.Time
+}
+
+// requestUsage collects token counts and the end user reported by handlers for one request
+type requestUsage struct {
+	promptTokens                  int64
+	completionTokens              int64
+	summarizationPromptTokens     int64
+	summarizationCompletionTokens int64
+	endUser                       atomic.Pointer[string]
+	useCase                       atomic.Pointer[string]
+}
+
+type usageContextKey struct{}
+
+// tokenCounterContextKey holds a counter AddTokenUsage also adds to
+type tokenCounterContextKey struct{}
+
+// WithTokenCounter returns a context in which AddTokenUsage also adds the
+// request's tokens to counter, e.g. to count them against an API key's quota
+func WithTokenCounter(ctx context.Context, counter *atomic.Int64) context.Context {
+	return context.WithValue(ctx, tokenCounterContextKey{}, counter)
+}
+
+// AddTokenUsage records tokens used by the current request for per-tenant usage
+// and prompt statistics
+func AddTokenUsage(ctx context.Context, promptTokens, completionTokens int) {
+	analytics.AddTokens(ctx, promptTokens, completionTokens)
+	if counter, ok := ctx.Value(tokenCounterContextKey{}).(*atomic.Int64); ok {
+		counter.Add(int64(promptTokens + completionTokens))
+	}
+	usage, ok := ctx.Value(usageContextKey{}).(*requestUsage)
+	if !ok {
+		return
+	}
+	atomic.AddInt64(&usage.promptTokens, int64(promptTokens))
+	atomic.AddInt64(&usage.completionTokens, int64(completionTokens))
+}
+
+// AddSummarizationUsage records tokens the gateway spent summarizing the
+// current request's conversation. They are counted apart from the request's
+// own tokens and do not count against its tenant's budget.
+func AddSummarizationUsage(ctx context.Context, promptTokens, completionTokens int) {
+	usage, ok := ctx.Value(usageContextKey{}).(*requestUsage)
+	if !ok {
+		return
+	}
+	atomic.AddInt64(&usage.summarizationPromptTokens, int64(promptTokens))
+	atomic.AddInt64(&usage.summarizationCompletionTokens, int64(completionTokens))
+}
+
+// SetEndUser records the end user the current request was made for (the
+// OpenAI "user" field or Anthropic metadata.user_id), so data kept about the
+// request can be deleted on that user's request
+func SetEndUser(ctx context.Context, user string) {
+	if user == "" {
+		return
+	}
+	if usage, ok := ctx.Value(usageContextKey{}).(*requestUsage); ok {
+		usage.endUser.Store(&user)
+	}
+	observability.SetFlightEndUser(ctx, user)
+}
+
+// SetUseCase records the use case the current request was classified as
+// (chat, code, extraction...) for per-tenant usage and use case metrics
+func SetUseCase(ctx context.Context, useCase string) {
+	if usage, ok := ctx.Value(usageContextKey{}).(*requestUsage); ok && useCase != "" {
+		usage.useCase.Store(&useCase)
+	}
+}
+
+// UsageTracker counts requests, errors and tokens per tenant for the current UTC day
+type UsageTracker struct {
+	mu      sync.Mutex
+	day     string
+	tenants map[string]*tenantUsage
+	// history holds earlier days by day; nil unless KeepHistory was called
+	history map[string]map[string]*tenantUsage
+	budgets map[string]int64
+	// canaryBudgets replace budgets for requests in the canary cohort, if set
+	canaryBudgets map[string]int64
+	// warnThresholds are the budget percentages callers are warned at, sorted
+	warnThresholds []float64
+	now            func() time.Time
+}
+
+// NewUsageTracker creates a new per-tenant usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		tenants: make(map[string]*tenantUsage),
+		now:     time.Now,
+	}
+}
+
+// Middleware returns a middleware that attributes each request to its tenant
+func (u *UsageTracker) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usage := &requestUsage{}
+			tenant := TenantID(r)
+			budget, used := u.budgetUsage(tenant, canary.InCanary(r.Context()))
+			if budget > 0 && used >= budget {
+				writeBudgetError(w, tenant, budget)
+				u.record(tenant, http.StatusTooManyRequests, usage, 0)
+				return
+			}
+			u.warnBudget(w.Header(), budget, used)
+			wrapped := wrapResponseWriter(w)
+
+			next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), usageContextKey{}, usage)))
+
+			u.record(tenant, wrapped.status, usage, budget)
+		})
+	}
+}
+
+// record adds a request to tenant's usage, warning once per day for each
+// threshold of budget (the budget the request was admitted under) it crosses
+func (u *UsageTracker) record(tenant string, status int, usage *requestUsage, budget int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+	t, ok := u.tenants[tenant]
+	if !ok || !t.deletedAt.IsZero() {
+		t = &tenantUsage{}
+		u.tenants[tenant] = t
+	}
+
+	t.Requests++
+	if status >= 400 {
+		t.Errors++
+	}
+	tokens := atomic.LoadInt64(&usage.promptTokens) + atomic.LoadInt64(&usage.completionTokens)
+	t.PromptTokens += atomic.LoadInt64(&usage.promptTokens)
+	t.CompletionTokens += atomic.LoadInt64(&usage.completionTokens)
+	t.SummarizationPromptTokens += atomic.LoadInt64(&usage.summarizationPromptTokens)
+	t.SummarizationCompletionTokens += atomic.LoadInt64(&usage.summarizationCompletionTokens)
+	if useCase := usage.useCase.Load(); useCase != nil {
+		if t.useCaseTokens == nil {
+			t.useCaseTokens = make(map[string]int64)
+		}
+		t.useCaseTokens[*useCase] += tokens
+		observability.GetMetrics().RecordUseCase(*useCase, tokens)
+	}
+	if user := usage.endUser.Load(); user != nil {
+		if t.endUsers == nil {
+			t.endUsers = make(map[string]int64)
+		}
+		t.endUsers[*user]++
+	}
+
+	if budget > 0 && len(u.warnThresholds) > 0 {
+		used := float64(t.PromptTokens+t.CompletionTokens) / float64(budget) * 100
+		if threshold := reachedThreshold(u.warnThresholds, used); threshold > t.warned {
+			t.warned = threshold
+			emitQuotaWarning(quotaBudget, "tenant", tenant, threshold, used, budget)
+		}
+	}
+}
+
+// SetQuotaWarnings sets the percentages of a tenant's daily token budget at
+// which callers are warned; nil disables warnings
+func (u *UsageTracker) SetQuotaWarnings(thresholds []float64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.warnThresholds = sortedThresholds(thresholds)
+}
+
+// warnBudget sets the quota warning header when a tenant's usage has reached
+// a warning threshold of its budget
+func (u *UsageTracker) warnBudget(h http.Header, budget, used int64) {
+	if budget <= 0 {
+		return
+	}
+	u.mu.Lock()
+	thresholds := u.warnThresholds
+	u.mu.Unlock()
+	percent := float64(used) / float64(budget) * 100
+	if threshold := reachedThreshold(thresholds, percent); threshold > 0 {
+		setQuotaWarning(h, quotaBudget, threshold, percent)
+	}
+}
+
+// SetBudgets replaces the daily token budgets per tenant; "*" applies to
+// tenants without their own entry and a budget <= 0 means unlimited
+func (u *UsageTracker) SetBudgets(budgets map[string]int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.budgets = budgets
+}
+
+// SetCanaryBudgets sets the budgets used instead of the daily budgets for
+// requests in the canary cohort; nil clears them
+func (u *UsageTracker) SetCanaryBudgets(budgets map[string]int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.canaryBudgets = budgets
+}
+
+// budgetUsage returns tenant's token budget (0 for unlimited) and the tokens it
+// has used today
+func (u *UsageTracker) budgetUsage(tenant string, inCanary bool) (int64, int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	budgets := u.budgets
+	if inCanary && u.canaryBudgets != nil {
+		budgets = u.canaryBudgets
+	}
+	budget, ok := budgets[tenant]
+	if !ok {
+		budget = budgets["*"]
+	}
+	if budget <= 0 {
+		return 0, 0
+	}
+	u.rollover()
+	t, ok := u.tenants[tenant]
+	if !ok || !t.deletedAt.IsZero() {
+		return budget, 0
+	}
+	return budget, t.PromptTokens + t.CompletionTokens
+}
+
+// writeBudgetError rejects a request from a tenant over its daily token budget
+func writeBudgetError(w http.ResponseWriter, tenant string, budget int64) {
+	logger.Warn().
+		Str("tenant", tenant).
+		Int64("daily_tokens", budget).
+		Msg("Tenant token budget exceeded")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": "Daily token budget exceeded for tenant " + tenant,
+			"type":    "rate_limit_error",
+			"code":    "budget_exceeded",
+		},
+	})
+}
+
+// rollover starts fresh counters when the UTC day changes, moving the previous
+// day to the history if one is kept. Callers hold u.mu.
+func (u *UsageTracker) rollover() {
+	day := u.now().UTC().Format("2006-01-02")
+	if day != u.day {
+		if u.history != nil && len(u.tenants) > 0 {
+			u.history[u.day] = u.tenants
+		}
+		u.day = day
+		u.tenants = make(map[string]*tenantUsage)
+	}
+}
+
+// KeepHistory keeps earlier days' usage instead of discarding it at midnight.
+// The history grows until it is purged, so it is only kept when retention
+// manages the tracker.
+func (u *UsageTracker) KeepHistory() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.history == nil {
+		u.history = make(map[string]map[string]*tenantUsage)
+	}
+}
+
+// Today returns the current day and per-tenant usage, busiest tenants first
+func (u *UsageTracker) Today() (string, []map[string]interface{}) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+	return u.day, usageRows(u.day, u.tenants)
+}
+
+// Day returns per-tenant usage for day (YYYY-MM-DD), busiest tenants first.
+// Days before today are only available while history is kept.
+func (u *UsageTracker) Day(day string) []map[string]interface{} {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+	if day == u.day {
+		return usageRows(day, u.tenants)
+	}
+	return usageRows(day, u.history[day])
+}
+
+func usageRows(day string, tenants map[string]*tenantUsage) []map[string]interface{} {
+	usage := make([]map[string]interface{}, 0, len(tenants))
+	for tenant, t := range tenants {
+		if !t.deletedAt.IsZero() {
+			continue
+		}
+		useCaseTokens := make(map[string]int64, len(t.useCaseTokens))
+		for useCase, tokens := range t.useCaseTokens {
+			useCaseTokens[useCase] = tokens
+		}
+		usage = append(usage, map[string]interface{}{
+			"day":                day,
+			"tenant":             tenant,
+			"requests":           t.Requests,
+			"errors":             t.Errors,
+			"prompt_tokens":      t.PromptTokens,
+			"completion_tokens":  t.CompletionTokens,
+			"total_tokens":       t.PromptTokens + t.CompletionTokens,
+			"end_users":          len(t.endUsers),
+			"tokens_by_use_case": useCaseTokens,
+
+			"summarization_prompt_tokens":     t.SummarizationPromptTokens,
+			"summarization_completion_tokens": t.SummarizationCompletionTokens,
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i]["requests"].(int64) > usage[j]["requests"].(int64)
+	})
+	return usage
+}
+
+// Purge drops days that ended before their tenant's cutoff (zero keeps them)
+// and deleted counters whose deletion is older than deletedBefore. It returns
+// the number of tenant-days dropped.
+func (u *UsageTracker) Purge(cutoff func(tenant string) time.Time, deletedBefore time.Time) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.rollover()
+	purged := 0
+	purge := func(day string, tenants map[string]*tenantUsage) {
+		start, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return
+		}
+		end := start.Add(24 * time.Hour)
+		for tenant, t := range tenants {
+			c := cutoff(tenant)
+			if (!c.IsZero() && end.Before(c)) || (!t.deletedAt.IsZero() && t.deletedAt.Before(deletedBefore)) {
+				delete(tenants, tenant)
+				purged++
+			}
+		}
+	}
+
+	purge(u.day, u.tenants)
+	for day, tenants := range u.history {
+		purge(day, tenants)
+		if len(tenants) == 0 {
+			delete(u.history, day)
+		}
+	}
+	return purged
+}
+
+// DeleteTenant hides all of tenant's usage until the next purge after the
+// grace period. It returns the number of tenant-days deleted.
+func (u *UsageTracker) DeleteTenant(tenant string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.now()
+	deleted := 0
+	mark := func(tenants map[string]*tenantUsage) {
+		if t, ok := tenants[tenant]; ok && t.deletedAt.IsZero() {
+			t.deletedAt = now
+			deleted++
+		}
+	}
+	mark(u.tenants)
+	for _, tenants := range u.history {
+		mark(tenants)
+	}
+	return deleted
+}
+
+// DeleteUser removes the end user from every tenant-day, returning the number
+// of tenant-days the user appeared in. The tenant totals are kept; they do not
+// identify the user.
+func (u *UsageTracker) DeleteUser(user string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	deleted := 0
+	scrub := func(tenants map[string]*tenantUsage) {
+		for _, t := range tenants {
+			if _, ok := t.endUsers[user]; ok {
+				delete(t.endUsers, user)
+				deleted++
+			}
+		}
+	}
+	scrub(u.tenants)
+	for _, tenants := range u.history {
+		scrub(tenants)
+	}
+	return deleted
+}
+
+// TenantID returns the tenant for a request: the X-Tenant-ID header of
+// callers trusted to name a tenant (see WithTrustedTenant), the authenticated
+// user, or "anonymous". The header of other callers is ignored, so clients
+// cannot bill their usage to another tenant.
+func TenantID(r *http.Request) string {
+	if tenant := r.Header.Get(TenantHeader); tenant != "" && TenantTrusted(r.Context()) {
+		return tenant
+	}
+	if userID := GetUserID(r.Context()); userID != "" {
+		return userID
+	}
+	return "anonymous"
+}
+
+// trustedTenantKey marks requests whose X-Tenant-ID header is trusted
+type trustedTenantKey struct{}
+
+// WithTrustedTenant marks a request's X-Tenant-ID header as trusted. RealIP
+// sets it for connections from trusted proxies and Auth for callers
+// authenticated with TrustTenantHeader, such as admin keys.
+func WithTrustedTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedTenantKey{}, true)
+}
+
+// TenantTrusted reports whether a request's X-Tenant-ID header is trusted
+func TenantTrusted(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustedTenantKey{}).(bool)
+	return trusted
+}
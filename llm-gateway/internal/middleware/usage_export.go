@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// processNoiseKey seeds export noise when no NoiseKey is set
+var processNoiseKey = func() []byte {
+	key := make([]byte, 32)
+	crand.Read(key)
+	return key
+}()
+
+// UsageExportOptions selects and anonymizes exported usage
+type UsageExportOptions struct {
+	// From and To bound the exported days (YYYY-MM-DD, inclusive)
+	From, To string
+	// GroupBy is "tenant" for a row per tenant and day, or "day" for a row per day
+	GroupBy string
+	// MinUsers suppresses groups with fewer distinct end users
+	MinUsers int
+	// Epsilon adds Laplace noise with scale 1/Epsilon to counts and
+	// TokenSensitivity/Epsilon to token counts; 0 exports exact counts
+	Epsilon          float64
+	TokenSensitivity int64
+	// NoiseKey seeds the noise from the group and metric, so repeated exports
+	// of a count get the same noise and cannot be averaged out. Without it
+	// the key is random per process.
+	NoiseKey string
+}
+
+// UsageAggregate is one exported usage group
+type UsageAggregate struct {
+	Day              string `json:"day"`
+	Tenant           string `json:"tenant,omitempty"`
+	Requests         int64  `json:"requests"`
+	Errors           int64  `json:"errors"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	EndUsers         int64  `json:"end_users"`
+}
+
+// UsageExport is aggregated usage safe to hand to analytics consumers
+type UsageExport struct {
+	Object   string           `json:"object"`
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	GroupBy  string           `json:"group_by"`
+	MinUsers int              `json:"min_users"`
+	Noise    bool             `json:"noise"`
+	Data     []UsageAggregate `json:"data"`
+	// Suppressed is the number of groups left out for having too few end users
+	Suppressed int `json:"suppressed"`
+}
+
+// Export aggregates the kept usage by opts. Only groups with at least
+// MinUsers distinct end users are included; requests without an end user
+// count towards a group's totals but not towards its users.
+func (u *UsageTracker) Export(opts UsageExportOptions) *UsageExport {
+	type group struct {
+		agg   UsageAggregate
+		users map[string]struct{}
+	}
+	groups := make(map[[2]string]*group)
+
+	u.mu.Lock()
+	u.rollover()
+	days := map[string]map[string]*tenantUsage{u.day: u.tenants}
+	for day, tenants := range u.history {
+		days[day] = tenants
+	}
+	for day, tenants := range days {
+		if (opts.From != "" && day < opts.From) || (opts.To != "" && day > opts.To) {
+			continue
+		}
+		for tenant, t := range tenants {
+			if !t.deletedAt.IsZero() {
+				continue
+			}
+			key := [2]string{day, ""}
+			if opts.GroupBy != "day" {
+				key[1] = tenant
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{agg: UsageAggregate{Day: key[0], Tenant: key[1]}, users: make(map[string]struct{})}
+				groups[key] = g
+			}
+			g.agg.Requests += t.Requests
+			g.agg.Errors += t.Errors
+			g.agg.PromptTokens += t.PromptTokens
+			g.agg.CompletionTokens += t.CompletionTokens
+			for user := range t.endUsers {
+				g.users[user] = struct{}{}
+			}
+		}
+	}
+	u.mu.Unlock()
+
+	export := &UsageExport{
+		Object:   "usage_export",
+		From:     opts.From,
+		To:       opts.To,
+		GroupBy:  opts.GroupBy,
+		MinUsers: opts.MinUsers,
+		Noise:    opts.Epsilon > 0,
+		Data:     make([]UsageAggregate, 0, len(groups)),
+	}
+	key := processNoiseKey
+	if opts.NoiseKey != "" {
+		key = []byte(opts.NoiseKey)
+	}
+	for _, g := range groups {
+		if len(g.users) < opts.MinUsers {
+			export.Suppressed++
+			continue
+		}
+		agg := g.agg
+		agg.EndUsers = int64(len(g.users))
+		if opts.Epsilon > 0 {
+			countScale := 1 / opts.Epsilon
+			tokenScale := float64(opts.TokenSensitivity) / opts.Epsilon
+			noise := func(metric string, count int64, scale float64) int64 {
+				return addNoise(count, scale, noiseSource(key, agg.Day, agg.Tenant, metric))
+			}
+			agg.Requests = noise("requests", agg.Requests, countScale)
+			agg.Errors = noise("errors", agg.Errors, countScale)
+			agg.EndUsers = noise("end_users", agg.EndUsers, countScale)
+			agg.PromptTokens = noise("prompt_tokens", agg.PromptTokens, tokenScale)
+			agg.CompletionTokens = noise("completion_tokens", agg.CompletionTokens, tokenScale)
+		}
+		export.Data = append(export.Data, agg)
+	}
+	sort.Slice(export.Data, func(i, j int) bool {
+		if export.Data[i].Day != export.Data[j].Day {
+			return export.Data[i].Day < export.Data[j].Day
+		}
+		return export.Data[i].Tenant < export.Data[j].Tenant
+	})
+	return export
+}
+
+// noiseSource returns the random source for one metric of a group, seeded
+// from an HMAC so the noise is fixed per tenant, day and metric but cannot be
+// predicted without key
+func noiseSource(key []byte, day, tenant, metric string) *rand.Rand {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(day + "\x00" + tenant + "\x00" + metric))
+	sum := mac.Sum(nil)
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
+
+// addNoise adds Laplace(0, scale) noise drawn from src to a count, rounded and kept non-negative
+func addNoise(count int64, scale float64, src *rand.Rand) int64 {
+	// A Laplace sample is an exponential sample with a random sign
+	noise := scale * src.ExpFloat64()
+	if src.IntN(2) == 0 {
+		noise = -noise
+	}
+	return max(0, count+int64(math.Round(noise)))
+}
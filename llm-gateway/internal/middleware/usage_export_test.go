@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker_Export(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	u := NewUsageTracker()
+	u.now = func() time.Time { return now }
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetEndUser(r.Context(), r.URL.Query().Get("user"))
+		AddTokenUsage(r.Context(), 10, 5)
+	}))
+	send := func(tenant, user string) {
+		req := httptest.NewRequest("POST", "/?user="+user, nil)
+		req.Header.Set(TenantHeader, tenant)
+		req = req.WithContext(WithTrustedTenant(req.Context()))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, user := range []string{"a", "b", "c", "a"} {
+		send("acme", user)
+	}
+	send("small", "a")
+	send("small", "d")
+
+	export := u.Export(UsageExportOptions{GroupBy: "tenant", MinUsers: 3})
+	if len(export.Data) != 1 || export.Suppressed != 1 {
+		t.Fatalf("export = %+v, want acme only", export)
+	}
+	if row := export.Data[0]; row.Tenant != "acme" || row.Requests != 4 || row.EndUsers != 3 || row.PromptTokens != 40 {
+		t.Errorf("acme row = %+v", row)
+	}
+
+	// Per day, users are counted once across tenants
+	export = u.Export(UsageExportOptions{GroupBy: "day", MinUsers: 4})
+	if len(export.Data) != 1 || export.Data[0].Requests != 6 || export.Data[0].EndUsers != 4 || export.Data[0].Tenant != "" {
+		t.Errorf("day export = %+v", export)
+	}
+
+	noisy := UsageExportOptions{GroupBy: "day", MinUsers: 1, Epsilon: 0.01, TokenSensitivity: 100, NoiseKey: "k"}
+	export = u.Export(noisy)
+	if !export.Noise || export.Data[0].Requests < 0 {
+		t.Errorf("noisy export = %+v", export)
+	}
+	// Exporting again returns the same noise, so it cannot be averaged out
+	for range 5 {
+		if again := u.Export(noisy); again.Data[0] != export.Data[0] {
+			t.Fatalf("noise changed between exports: %+v, then %+v", export.Data[0], again.Data[0])
+		}
+	}
+	noisy.NoiseKey = "other"
+	if other := u.Export(noisy); other.Data[0] == export.Data[0] {
+		t.Errorf("different noise keys gave the same noise: %+v", other.Data[0])
+	}
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker_PerTenant(t *testing.T) {
+	u := NewUsageTracker()
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		AddTokenUsage(r.Context(), 10, 5)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path, tenant string, userID string, trusted bool) {
+		req := httptest.NewRequest("POST", path, nil)
+		if tenant != "" {
+			req.Header.Set(TenantHeader, tenant)
+		}
+		if trusted {
+			req = req.WithContext(WithTrustedTenant(req.Context()))
+		}
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), UserIDContextKey, userID))
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("/ok", "acme", "", true)
+	send("/ok", "acme", "", true)
+	send("/fail", "acme", "", true)
+	send("/ok", "ignored", "user-1", false) // authenticated user wins over an untrusted header
+	send("/ok", "spoofed", "", false)       // untrusted callers cannot name a tenant
+	send("/ok", "", "", false)
+
+	_, tenants := u.Today()
+	byTenant := make(map[string]map[string]interface{})
+	for _, t := range tenants {
+		byTenant[t["tenant"].(string)] = t
+	}
+
+	acme := byTenant["acme"]
+	if acme["requests"] != int64(3) || acme["errors"] != int64(1) || acme["prompt_tokens"] != int64(20) {
+		t.Errorf("acme usage = %v", acme)
+	}
+	if tenants[0]["tenant"] != "acme" {
+		t.Errorf("busiest tenant should come first, got %v", tenants[0]["tenant"])
+	}
+	if byTenant["user-1"] == nil || byTenant["anonymous"]["requests"] != int64(2) || byTenant["ignored"] != nil || byTenant["spoofed"] != nil {
+		t.Errorf("unexpected tenants: %v", tenants)
+	}
+}
+
+func TestUsageTracker_ResetsDaily(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	u := NewUsageTracker()
+	u.now = func() time.Time { return now }
+
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if day, tenants := u.Today(); day != "2024-05-01" || len(tenants) != 1 {
+		t.Fatalf("Today() = %s, %v", day, tenants)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if day, tenants := u.Today(); day != "2024-05-02" || len(tenants) != 0 {
+		t.Errorf("after midnight Today() = %s, %v, want a fresh day", day, tenants)
+	}
+}
+
+func TestUsageTracker_Budgets(t *testing.T) {
+	u := NewUsageTracker()
+	u.SetBudgets(map[string]int64{"acme": 20, "*": 100})
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddTokenUsage(r.Context(), 10, 5)
+	}))
+
+	send := func(tenant string) int {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set(TenantHeader, tenant)
+		req = req.WithContext(WithTrustedTenant(req.Context()))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The request that crosses the budget is served; the next one is not
+	if send("acme") != http.StatusOK || send("acme") != http.StatusOK {
+		t.Fatal("requests within budget rejected")
+	}
+	if code := send("acme"); code != http.StatusTooManyRequests {
+		t.Errorf("over budget status = %d, want 429", code)
+	}
+	if code := send("other"); code != http.StatusOK {
+		t.Errorf("tenant under the default budget status = %d", code)
+	}
+
+	u.SetBudgets(nil)
+	if code := send("acme"); code != http.StatusOK {
+		t.Errorf("status after clearing budgets = %d", code)
+	}
+}
+
+func TestUsageTracker_Retention(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	u := NewUsageTracker()
+	u.now = func() time.Time { return now }
+	u.KeepHistory()
+
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(tenant string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(TenantHeader, tenant)
+		req = req.WithContext(WithTrustedTenant(req.Context()))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("acme")
+	send("other")
+	now = now.Add(24 * time.Hour)
+	send("acme")
+
+	if rows := u.Day("2024-05-01"); len(rows) != 2 || rows[0]["day"] != "2024-05-01" {
+		t.Fatalf("Day(2024-05-01) = %v, want yesterday kept", rows)
+	}
+
+	if deleted := u.DeleteTenant("acme"); deleted != 2 {
+		t.Errorf("deleted %d tenant-days, want 2", deleted)
+	}
+	if _, tenants := u.Today(); len(tenants) != 0 {
+		t.Errorf("Today() = %v, want acme hidden", tenants)
+	}
+
+	// Only yesterday has ended before the cutoff; acme is still in its grace period
+	cutoff := func(string) time.Time { return now }
+	if purged := u.Purge(cutoff, now.Add(-time.Hour)); purged != 2 {
+		t.Errorf("purged %d, want both tenants' first day", purged)
+	}
+	if purged := u.Purge(cutoff, now.Add(time.Hour)); purged != 1 || len(u.history) != 0 {
+		t.Errorf("purged %d, history = %v, want acme's deleted day gone", purged, u.history)
+	}
+}
+
+func TestUsageTracker_DeleteUser(t *testing.T) {
+	u := NewUsageTracker()
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetEndUser(r.Context(), r.URL.Query().Get("user"))
+	}))
+	for _, user := range []string{"alice", "alice", "bob"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/?user="+user, nil))
+	}
+
+	if _, tenants := u.Today(); tenants[0]["end_users"] != 2 {
+		t.Fatalf("usage = %v, want 2 end users", tenants)
+	}
+	if deleted := u.DeleteUser("alice"); deleted != 1 {
+		t.Errorf("deleted %d tenant-days, want 1", deleted)
+	}
+	if _, tenants := u.Today(); tenants[0]["end_users"] != 1 || tenants[0]["requests"] != int64(3) {
+		t.Errorf("usage = %v, want alice gone and the totals kept", tenants)
+	}
+}
+
+func TestUsageTracker_UseCase(t *testing.T) {
+	u := NewUsageTracker()
+	handler := u.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUseCase(r.Context(), r.URL.Query().Get("use_case"))
+		AddTokenUsage(r.Context(), 10, 5)
+	}))
+
+	for _, path := range []string{"/?use_case=code", "/?use_case=code", "/?use_case=chat", "/"} {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set(TenantHeader, "acme")
+		req = req.WithContext(WithTrustedTenant(req.Context()))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	_, tenants := u.Today()
+	if len(tenants) != 1 {
+		t.Fatalf("tenants = %v", tenants)
+	}
+	byUseCase := tenants[0]["tokens_by_use_case"].(map[string]int64)
+	if byUseCase["code"] != 30 || byUseCase["chat"] != 15 || len(byUseCase) != 2 {
+		t.Errorf("tokens_by_use_case = %v, want code 30 and chat 15", byUseCase)
+	}
+	if tenants[0]["total_tokens"] != int64(60) {
+		t.Errorf("total_tokens = %v, want unclassified requests counted", tenants[0]["total_tokens"])
+	}
+}
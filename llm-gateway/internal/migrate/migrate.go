@@ -0,0 +1,262 @@
+// Package migrate applies the embedded SQL migrations of the gateway's
+// database-backed subsystems at startup, so upgrades never need manual
+// schema changes. Migrations are forward-only: each runs once, in version
+// order, and is recorded in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the migrate module logger; its level can be set via log.modules.migrate
+var logger = observability.ModuleLogger("migrate")
+
+// Migration is one forward-only schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// checksum identifies the migration's SQL, so edits to an applied migration
+// are caught rather than silently skipped
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load reads the migrations in dir of fsys, usually an embed.FS. Files are
+// named <version>_<name>.sql, e.g. 0001_create_api_keys.sql; other files are
+// ignored. Versions must be positive and unique.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := map[int]string{}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(file, ".sql") {
+			continue
+		}
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version < 1 || name == "" {
+			return nil, fmt.Errorf("invalid migration file name %q: want <version>_<name>.sql", file)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d", other, file, version)
+		}
+		seen[version] = file
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", file, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Options tune how Run waits for other replicas
+type Options struct {
+	// LockTimeout is how long to wait for another replica's migration lock
+	LockTimeout time.Duration
+	// StaleLockAfter is the age after which a lock is taken over, its holder
+	// having presumably died mid-migration
+	StaleLockAfter time.Duration
+	// PollInterval is how often a held lock is tried again
+	PollInterval time.Duration
+}
+
+// DefaultOptions returns the options used at startup
+func DefaultOptions() Options {
+	return Options{
+		LockTimeout:    time.Minute,
+		StaleLockAfter: 10 * time.Minute,
+		PollInterval:   250 * time.Millisecond,
+	}
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	name     string
+	checksum string
+}
+
+// Run brings the schema of a subsystem up to date on db: it takes the
+// schema's migration lock, so replicas starting together migrate once, and
+// applies each pending migration in its own transaction. It fails if an
+// applied migration has changed, or the database has migrations newer than
+// this build knows.
+func Run(ctx context.Context, db *sql.DB, schema string, migrations []Migration, opts Options) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		schema TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TEXT NOT NULL,
+		PRIMARY KEY (schema, version))`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		schema TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		acquired_at INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations_lock table: %w", err)
+	}
+
+	owner := lockOwner()
+	if err := acquireLock(ctx, db, schema, owner, opts); err != nil {
+		return err
+	}
+	defer func() {
+		// The request context may be done; the lock must still go
+		if _, err := db.ExecContext(context.Background(), `DELETE FROM schema_migrations_lock WHERE schema = ? AND owner = ?`, schema, owner); err != nil {
+			logger.Warn().Err(err).Str("schema", schema).Msg("Failed to release migration lock")
+		}
+	}()
+
+	applied, err := appliedMigrations(ctx, db, schema)
+	if err != nil {
+		return err
+	}
+	todo, err := pending(schema, migrations, applied)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range todo {
+		start := time.Now()
+		if err := apply(ctx, db, schema, m); err != nil {
+			return fmt.Errorf("migration %s %d_%s failed: %w", schema, m.Version, m.Name, err)
+		}
+		logger.Info().
+			Str("schema", schema).
+			Int("version", m.Version).
+			Str("name", m.Name).
+			Dur("duration", time.Since(start)).
+			Msg("Applied migration")
+	}
+	return nil
+}
+
+// pending returns the migrations not yet applied, checking the applied ones
+// against this build's
+func pending(schema string, migrations []Migration, applied map[int]appliedMigration) ([]Migration, error) {
+	known := make(map[int]bool, len(migrations))
+	var todo []Migration
+	for _, m := range migrations {
+		known[m.Version] = true
+		row, ok := applied[m.Version]
+		if !ok {
+			todo = append(todo, m)
+			continue
+		}
+		if row.checksum != m.checksum() {
+			return nil, fmt.Errorf("applied migration %s %d_%s has been changed; add a new migration instead", schema, m.Version, row.name)
+		}
+	}
+	for version, row := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database has migration %s %d_%s, unknown to this gateway version; downgrades are not supported", schema, version, row.name)
+		}
+	}
+	return todo, nil
+}
+
+// appliedMigrations reads the schema's rows of schema_migrations
+func appliedMigrations(ctx context.Context, db *sql.DB, schema string) (map[int]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, name, checksum FROM schema_migrations WHERE schema = ?`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.name, &row.checksum); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a migration and records it in one transaction, so a failed
+// migration leaves neither changes nor a record behind
+func apply(ctx context.Context, db *sql.DB, schema string, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (schema, version, name, checksum, applied_at) VALUES (?, ?, ?, ?, ?)`,
+		schema, m.Version, m.Name, m.checksum(), time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// acquireLock takes the schema's migration lock, waiting up to LockTimeout
+// for another replica to finish and taking over locks older than
+// StaleLockAfter
+func acquireLock(ctx context.Context, db *sql.DB, schema, owner string, opts Options) error {
+	deadline := time.Now().Add(opts.LockTimeout)
+	for {
+		now := time.Now()
+		if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations_lock WHERE schema = ? AND acquired_at < ?`,
+			schema, now.Add(-opts.StaleLockAfter).Unix()); err != nil {
+			return fmt.Errorf("failed to clear stale migration lock: %w", err)
+		}
+		// Fails on the primary key while another replica holds the lock
+		if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations_lock (schema, owner, acquired_at) VALUES (?, ?, ?)`,
+			schema, owner, now.Unix()); err == nil {
+			return nil
+		}
+
+		if now.After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the %s migration lock", opts.LockTimeout, schema)
+		}
+		logger.Info().Str("schema", schema).Msg("Waiting for another replica's migrations")
+
+		timer := time.NewTimer(opts.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// lockOwner identifies this process in the lock table
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return host + "/" + uuid.NewString()
+}
@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.sql":    {Data: []byte("CREATE INDEX idx ON t (a);")},
+		"migrations/0001_create_table.sql": {Data: []byte("CREATE TABLE t (a TEXT);")},
+		"migrations/README.md":             {Data: []byte("not a migration")},
+	}
+	got, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[0].Name != "create_table" || got[1].Version != 2 {
+		t.Errorf("Load() = %+v, want versions 1 and 2 in order", got)
+	}
+}
+
+func TestLoad_InvalidNames(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version": {"m/create_table.sql": {}},
+		"no name":    {"m/0001.sql": {}},
+		"version 0":  {"m/0000_init.sql": {}},
+		"duplicate":  {"m/0001_a.sql": {}, "m/1_b.sql": {}},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys, "m"); err == nil {
+			t.Errorf("%s: Load() succeeded", name)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "create_table", SQL: "CREATE TABLE t (a TEXT);"},
+		{Version: 2, Name: "add_index", SQL: "CREATE INDEX idx ON t (a);"},
+	}
+	applied := map[int]appliedMigration{
+		1: {name: "create_table", checksum: migrations[0].checksum()},
+	}
+
+	todo, err := pending("test", migrations, applied)
+	if err != nil || len(todo) != 1 || todo[0].Version != 2 {
+		t.Fatalf("pending() = %+v, %v, want migration 2", todo, err)
+	}
+
+	changed := map[int]appliedMigration{1: {name: "create_table", checksum: "edited"}}
+	if _, err := pending("test", migrations, changed); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("pending() with an edited migration error = %v", err)
+	}
+
+	newer := map[int]appliedMigration{3: {name: "from_the_future"}}
+	if _, err := pending("test", migrations, newer); err == nil || !strings.Contains(err.Error(), "downgrades") {
+		t.Errorf("pending() with an unknown applied migration error = %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package notify delivers gateway events, such as quota warnings, to the
+// notification webhook. Events are queued and posted in the background, so
+// emitting one never delays a request.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the notify module logger; its level can be set via log.modules.notify
+var logger = observability.ModuleLogger("notify")
+
+// Event is one notification
+type Event struct {
+	// Type names the event, e.g. "quota.warning"
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Instance string                 `json:"instance,omitempty"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// Sink queues events and posts them to the notification webhook
+type Sink struct {
+	cfg      config.NotificationsConfig
+	client   *http.Client
+	instance string
+
+	// mu guards closing events against concurrent Emit calls
+	mu      sync.RWMutex
+	closed  bool
+	events  chan Event
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// New creates a sink from configuration and starts delivering events. It
+// returns nil if no webhook is configured.
+func New(cfg config.NotificationsConfig) *Sink {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	instance, _ := os.Hostname()
+	s := &Sink{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		instance: instance,
+		events:   make(chan Event, cfg.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// Emit queues an event for delivery. Events are dropped while the queue is full.
+func (s *Sink) Emit(event Event) {
+	if s == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	event.Instance = s.instance
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+		logger.Warn().Str("type", event.Type).Msg("Notification queue full, dropping event")
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full
+func (s *Sink) Dropped() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.dropped.Load()
+}
+
+// Stop delivers the queued events and stops the sink
+func (s *Sink) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+func (s *Sink) loop() {
+	defer close(s.done)
+	for event := range s.events {
+		if err := s.post(event); err != nil {
+			logger.Warn().Err(err).Str("type", event.Type).Msg("Failed to deliver notification")
+			continue
+		}
+		logger.Debug().Str("type", event.Type).Msg("Delivered notification")
+	}
+}
+
+// post sends one event as JSON, signed with the webhook secret if set
+func (s *Sink) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.cfg.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// defaultSink is the process-wide sink events are emitted to
+var defaultSink atomic.Pointer[Sink]
+
+// SetDefault sets the process-wide sink
+func SetDefault(s *Sink) {
+	defaultSink.Store(s)
+}
+
+// Default returns the process-wide sink (nil when notifications are disabled)
+func Default() *Sink {
+	return defaultSink.Load()
+}
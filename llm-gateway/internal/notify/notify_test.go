@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	s := New(config.NotificationsConfig{})
+	if s != nil {
+		t.Fatal("expected nil sink without a webhook")
+	}
+	s.Emit(Event{Type: "test"})
+	s.Stop()
+}
+
+func TestSink_DeliversSignedEvents(t *testing.T) {
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	deliveries := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{body, r.Header.Get("X-Signature")}
+	}))
+	defer server.Close()
+
+	s := New(config.NotificationsConfig{WebhookURL: server.URL, WebhookSecret: "s3cret", Timeout: time.Second, QueueSize: 10})
+	s.Emit(Event{Type: "quota.warning", Data: map[string]interface{}{"tenant": "acme"}})
+	s.Stop()
+
+	d := <-deliveries
+	var event Event
+	if err := json.Unmarshal(d.body, &event); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if event.Type != "quota.warning" || event.Data["tenant"] != "acme" || event.Time.IsZero() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(d.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); d.signature != want {
+		t.Errorf("signature = %q, want %q", d.signature, want)
+	}
+
+	// Events emitted after Stop are ignored
+	s.Emit(Event{Type: "late"})
+}
+
+func TestSink_DropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	s := New(config.NotificationsConfig{WebhookURL: server.URL, Timeout: 5 * time.Second, QueueSize: 1})
+	// One event may be in flight and one queued; the rest are dropped
+	for i := 0; i < 5; i++ {
+		s.Emit(Event{Type: "test"})
+	}
+	if s.Dropped() < 3 {
+		t.Errorf("dropped = %d, want at least 3", s.Dropped())
+	}
+	close(release)
+	s.Stop()
+}
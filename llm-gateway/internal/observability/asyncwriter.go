@@ -0,0 +1,113 @@
+package observability
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Overflow policies for AsyncWriter when its buffer is full
+const (
+	OverflowDrop  = "drop"  // discard the write and count it
+	OverflowBlock = "block" // wait for the background writer to catch up
+)
+
+// AsyncWriter buffers writes in memory and flushes them to the underlying
+// writer on a background goroutine, so callers never wait on disk I/O
+type AsyncWriter struct {
+	out   io.Writer
+	queue chan []byte
+	block bool
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	written uint64
+	dropped uint64
+}
+
+// NewAsyncWriter creates an AsyncWriter holding up to bufferSize pending writes
+func NewAsyncWriter(out io.Writer, bufferSize int, policy string) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = 4096
+	}
+
+	w := &AsyncWriter{
+		out:   out,
+		queue: make(chan []byte, bufferSize),
+		block: policy == OverflowBlock,
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for p := range w.queue {
+		w.out.Write(p)
+		atomic.AddUint64(&w.written, 1)
+	}
+}
+
+// Write queues p for the background writer. Under the drop policy a full
+// buffer discards p; it is still reported as written so loggers don't retry.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	// Callers such as zerolog reuse their buffers after Write returns
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	if w.block {
+		w.queue <- buf
+		return len(p), nil
+	}
+
+	select {
+	case w.queue <- buf:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Close flushes pending writes and closes the underlying writer if it is an io.Closer
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stats returns the number of flushed, dropped and pending writes
+func (w *AsyncWriter) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"written":  atomic.LoadUint64(&w.written),
+		"dropped":  atomic.LoadUint64(&w.dropped),
+		"pending":  len(w.queue),
+		"capacity": cap(w.queue),
+	}
+}
+
+// Dropped returns the number of writes discarded because the buffer was full
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
@@ -0,0 +1,80 @@
+package observability
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// gatedWriter blocks every write until release is closed
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func TestAsyncWriter_FlushesOnClose(t *testing.T) {
+	out := &gatedWriter{release: make(chan struct{})}
+	close(out.release)
+
+	w := NewAsyncWriter(out, 16, OverflowBlock)
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("x"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if out.buf.Len() != 100 {
+		t.Errorf("flushed %d bytes, want 100", out.buf.Len())
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("Write after Close should fail")
+	}
+}
+
+func TestAsyncWriter_DropsWhenFull(t *testing.T) {
+	out := &gatedWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(out, 2, OverflowDrop)
+
+	// One write may be held by the background goroutine, two fill the buffer
+	for i := 0; i < 10; i++ {
+		if n, err := w.Write([]byte("x")); err != nil || n != 1 {
+			t.Fatalf("Write() = %d, %v; drop policy should never fail", n, err)
+		}
+	}
+
+	if dropped := w.Dropped(); dropped < 7 {
+		t.Errorf("Dropped() = %d, want at least 7", dropped)
+	}
+
+	close(out.release)
+	w.Close()
+
+	if got := uint64(out.buf.Len()) + w.Dropped(); got != 10 {
+		t.Errorf("written + dropped = %d, want 10", got)
+	}
+}
+
+func TestAsyncWriter_CopiesCallerBuffer(t *testing.T) {
+	out := &gatedWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(out, 4, OverflowBlock)
+
+	buf := []byte("first")
+	w.Write(buf)
+	copy(buf, "XXXXX")
+
+	close(out.release)
+	w.Close()
+
+	if out.buf.String() != "first" {
+		t.Errorf("flushed %q, want the bytes as they were at Write time", out.buf.String())
+	}
+}
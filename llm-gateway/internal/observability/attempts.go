@@ -0,0 +1,181 @@
+package observability
+
+import (
+	"context"
+	"sync"
+)
+
+// attemptTimeline collects the provider attempts made for one request
+type attemptTimeline struct {
+	mu       sync.Mutex
+	attempts []ProviderAttempt
+	fallback *Fallback
+}
+
+// Fallback is the provider and model of a fallback chain that served a
+// request, or of the overflow route that served it over its model's quota
+type Fallback struct {
+	Provider string
+	Model    string
+	Overflow bool
+}
+
+type attemptTimelineKey struct{}
+
+// WithAttemptTimeline returns a context that collects provider attempts,
+// so they can be reported if the request fails. A context that already
+// collects them is returned as is, so outer middleware see the attempts too.
+func WithAttemptTimeline(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(attemptTimelineKey{}).(*attemptTimeline); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptTimelineKey{}, &attemptTimeline{})
+}
+
+// ProviderAttempts returns the attempts recorded in ctx so far
+func ProviderAttempts(ctx context.Context) []ProviderAttempt {
+	timeline, ok := ctx.Value(attemptTimelineKey{}).(*attemptTimeline)
+	if !ok {
+		return nil
+	}
+	timeline.mu.Lock()
+	defer timeline.mu.Unlock()
+	return append([]ProviderAttempt(nil), timeline.attempts...)
+}
+
+// UpstreamRequestIDs returns the request IDs providers gave the attempts
+// recorded in ctx so far, for correlating with the providers' support
+func UpstreamRequestIDs(ctx context.Context) []string {
+	var ids []string
+	for _, attempt := range ProviderAttempts(ctx) {
+		if attempt.UpstreamRequestID != "" {
+			ids = append(ids, attempt.UpstreamRequestID)
+		}
+	}
+	return ids
+}
+
+// upstreamIDs holds the request ID a provider gave one attempt
+type upstreamIDs struct {
+	mu        sync.Mutex
+	requestID string
+}
+
+type upstreamIDsKey struct{}
+
+// WithUpstreamIDs returns a context for one provider attempt, which collects
+// the request ID the provider gives it
+func WithUpstreamIDs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, upstreamIDsKey{}, &upstreamIDs{})
+}
+
+// WithUpstreamIDsFrom returns ctx collecting upstream IDs for the attempt of
+// attemptCtx, for calls that outlive the attempt such as streams
+func WithUpstreamIDsFrom(ctx, attemptCtx context.Context) context.Context {
+	if ids, ok := attemptCtx.Value(upstreamIDsKey{}).(*upstreamIDs); ok {
+		return context.WithValue(ctx, upstreamIDsKey{}, ids)
+	}
+	return ctx
+}
+
+// RecordUpstreamRequestID notes the request ID a provider gave the attempt
+// in ctx, such as OpenAI's x-request-id header
+func RecordUpstreamRequestID(ctx context.Context, id string) {
+	if ids, ok := ctx.Value(upstreamIDsKey{}).(*upstreamIDs); ok && id != "" {
+		ids.mu.Lock()
+		ids.requestID = id
+		ids.mu.Unlock()
+	}
+}
+
+// UpstreamRequestID returns the request ID recorded for the attempt in ctx
+func UpstreamRequestID(ctx context.Context) string {
+	ids, ok := ctx.Value(upstreamIDsKey{}).(*upstreamIDs)
+	if !ok {
+		return ""
+	}
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	return ids.requestID
+}
+
+// RecordFallback notes that a fallback served the request, on the timeline in
+// ctx and the current span
+func RecordFallback(ctx context.Context, fallback Fallback) {
+	if timeline, ok := ctx.Value(attemptTimelineKey{}).(*attemptTimeline); ok {
+		timeline.mu.Lock()
+		timeline.fallback = &fallback
+		timeline.mu.Unlock()
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		span.SetAttribute("llm.fallback.provider", fallback.Provider)
+		span.SetAttribute("llm.fallback.model", fallback.Model)
+	}
+}
+
+// FallbackUsed returns the fallback recorded in ctx, if any
+func FallbackUsed(ctx context.Context) (Fallback, bool) {
+	timeline, ok := ctx.Value(attemptTimelineKey{}).(*attemptTimeline)
+	if !ok {
+		return Fallback{}, false
+	}
+	timeline.mu.Lock()
+	defer timeline.mu.Unlock()
+	if timeline.fallback == nil {
+		return Fallback{}, false
+	}
+	return *timeline.fallback, true
+}
+
+// addAttempt appends an attempt to the timeline in ctx and to the current span
+func addAttempt(ctx context.Context, attempt ProviderAttempt) {
+	if timeline, ok := ctx.Value(attemptTimelineKey{}).(*attemptTimeline); ok {
+		timeline.mu.Lock()
+		timeline.attempts = append(timeline.attempts, attempt)
+		timeline.mu.Unlock()
+	}
+
+	if span := SpanFromContext(ctx); span != nil {
+		attrs := map[string]interface{}{
+			"provider":    attempt.Provider,
+			"operation":   attempt.Operation,
+			"attempt":     attempt.Attempt,
+			"duration_ms": attempt.Duration.Milliseconds(),
+		}
+		if attempt.Status != 0 {
+			attrs["status"] = attempt.Status
+		}
+		if attempt.UpstreamRequestID != "" {
+			attrs["upstream_request_id"] = attempt.UpstreamRequestID
+		}
+		if attempt.ResponseID != "" {
+			attrs["response_id"] = attempt.ResponseID
+		}
+		if attempt.ErrorClass != "" {
+			attrs["error_class"] = attempt.ErrorClass
+			attrs["error"] = attempt.Error
+		}
+		span.AddEvent("provider.attempt", attrs)
+	}
+}
+
+var (
+	attemptObserversMu sync.RWMutex
+	attemptObservers   []func(ProviderAttempt)
+)
+
+// OnProviderAttempt registers fn to receive every provider attempt made by
+// any request, e.g. for per-provider reports
+func OnProviderAttempt(fn func(ProviderAttempt)) {
+	attemptObserversMu.Lock()
+	defer attemptObserversMu.Unlock()
+	attemptObservers = append(attemptObservers, fn)
+}
+
+func notifyAttemptObservers(attempt ProviderAttempt) {
+	attemptObserversMu.RLock()
+	defer attemptObserversMu.RUnlock()
+	for _, fn := range attemptObservers {
+		fn(attempt)
+	}
+}
@@ -0,0 +1,145 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditBufferSize is the number of recent audit events kept for the admin API
+const auditBufferSize = 1000
+
+// AuditBuffer keeps the most recent audit events in memory; the log remains
+// the durable record
+type AuditBuffer struct {
+	mu      sync.Mutex
+	records []auditEntry
+	lastID  int64
+}
+
+// auditEntry is a buffered audit event with its retention state
+type auditEntry struct {
+	AuditLog
+	// tenant owns the event: details["tenant"] if set, otherwise the actor
+	tenant string
+	// deletedAt is set when the tenant's data was deleted; the event is hidden
+	// and purged once the deletion grace period ends
+	deletedAt time.Time
+}
+
+// defaultAuditBuffer receives every event passed to LogAudit
+var defaultAuditBuffer = &AuditBuffer{}
+
+// DefaultAuditBuffer returns the buffer that receives every event passed to LogAudit
+func DefaultAuditBuffer() *AuditBuffer {
+	return defaultAuditBuffer
+}
+
+// recordAudit adds an audit event to the in-memory buffer
+func recordAudit(ctx context.Context, action, resource string, details map[string]interface{}) {
+	record := AuditLog{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Resource:  resource,
+		TraceID:   TraceID(ctx),
+		Details:   details,
+	}
+	if actor, ok := details["actor"].(string); ok {
+		record.Actor = actor
+	}
+	defaultAuditBuffer.add(record)
+}
+
+func (b *AuditBuffer) add(record AuditLog) {
+	entry := auditEntry{AuditLog: record, tenant: record.Actor}
+	if tenant, ok := record.Details["tenant"].(string); ok {
+		entry.tenant = tenant
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	entry.ID = b.lastID
+	if len(b.records) >= auditBufferSize {
+		b.records = append(b.records[:0], b.records[1:]...)
+	}
+	b.records = append(b.records, entry)
+}
+
+// Records returns the buffered audit events, oldest first, skipping deleted ones
+func (b *AuditBuffer) Records() []AuditLog {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	records := make([]AuditLog, 0, len(b.records))
+	for _, e := range b.records {
+		if e.deletedAt.IsZero() {
+			records = append(records, e.AuditLog)
+		}
+	}
+	return records
+}
+
+// Purge drops events older than their tenant's cutoff (zero keeps them) and
+// deleted events whose deletion is older than deletedBefore. It returns the
+// number of events dropped.
+func (b *AuditBuffer) Purge(cutoff func(tenant string) time.Time, deletedBefore time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.records[:0]
+	for _, e := range b.records {
+		if c := cutoff(e.tenant); !c.IsZero() && e.Timestamp.Before(c) {
+			continue
+		}
+		if !e.deletedAt.IsZero() && e.deletedAt.Before(deletedBefore) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	purged := len(b.records) - len(kept)
+	b.records = kept
+	return purged
+}
+
+// DeleteTenant hides every event of tenant until the next purge after the
+// grace period. It returns the number of events deleted.
+func (b *AuditBuffer) DeleteTenant(tenant string) int {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	deleted := 0
+	for i := range b.records {
+		if e := &b.records[i]; e.tenant == tenant && e.deletedAt.IsZero() {
+			e.deletedAt = now
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// DeleteUser drops every event whose details mention the end user (as an
+// "end_user" field at any depth, e.g. in flight recorder dumps), returning the
+// number of events dropped
+func (b *AuditBuffer) DeleteUser(user string) int {
+	quoted, _ := json.Marshal(user)
+	marker := `"end_user":` + string(quoted)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.records[:0]
+	for _, e := range b.records {
+		if details, err := json.Marshal(e.Details); err == nil && strings.Contains(string(details), marker) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	deleted := len(b.records) - len(kept)
+	b.records = kept
+	return deleted
+}
+
+// AuditRecords returns the recent audit events, oldest first
+func AuditRecords() []AuditLog {
+	return defaultAuditBuffer.Records()
+}
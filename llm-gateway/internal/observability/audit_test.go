@@ -0,0 +1,60 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAuditRecords(t *testing.T) {
+	before := len(AuditRecords())
+	LogAudit(context.Background(), "provider.drain", "provider", map[string]interface{}{"actor": "ops", "provider": "openai"})
+	LogAudit(context.Background(), "provider.undrain", "provider", nil)
+
+	records := AuditRecords()
+	if len(records) != before+2 {
+		t.Fatalf("records = %d, want %d", len(records), before+2)
+	}
+	first, second := records[len(records)-2], records[len(records)-1]
+	if first.Action != "provider.drain" || first.Actor != "ops" || second.ID != first.ID+1 {
+		t.Errorf("records = %+v, %+v", first, second)
+	}
+}
+
+func TestAuditBuffer_Retention(t *testing.T) {
+	b := &AuditBuffer{}
+	b.add(AuditLog{Timestamp: time.Now().Add(-2 * time.Hour), Action: "old", Actor: "ops"})
+	b.add(AuditLog{Timestamp: time.Now(), Action: "request", Details: map[string]interface{}{"tenant": "acme"}})
+	b.add(AuditLog{Timestamp: time.Now(), Action: "new", Actor: "ops"})
+
+	if deleted := b.DeleteTenant("acme"); deleted != 1 || len(b.Records()) != 2 {
+		t.Fatalf("deleted = %d, records = %+v", deleted, b.Records())
+	}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	cutoff := func(string) time.Time { return hourAgo }
+	if purged := b.Purge(cutoff, hourAgo); purged != 1 {
+		t.Errorf("purged %d, want only the expired event while acme is in its grace period", purged)
+	}
+	if purged := b.Purge(cutoff, time.Now().Add(time.Minute)); purged != 1 {
+		t.Errorf("purged %d, want acme's deleted event after the grace period", purged)
+	}
+	if records := b.Records(); len(records) != 1 || records[0].Action != "new" {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestAuditBuffer_DeleteUser(t *testing.T) {
+	b := &AuditBuffer{}
+	b.add(AuditLog{Action: "flight_recorder.dump", Details: map[string]interface{}{
+		"requests": []*FlightRecord{{Path: "/v1/chat/completions", EndUser: "user-1"}},
+	}})
+	b.add(AuditLog{Action: "other", Details: map[string]interface{}{"end_user": "user-10"}})
+
+	if deleted := b.DeleteUser("user-1"); deleted != 1 {
+		t.Errorf("deleted %d, want the dump mentioning user-1 only", deleted)
+	}
+	if records := b.Records(); len(records) != 1 || records[0].Action != "other" {
+		t.Errorf("records = %+v", records)
+	}
+}
@@ -0,0 +1,131 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// capturedHeaderDenylist holds headers whose values are redacted, as they carry credentials
+var capturedHeaderDenylist = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// CapturedRequest is the inbound request of a flight record, as the client sent it
+type CapturedRequest struct {
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+	// Truncated is set when the body exceeded the capture limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// CapturedProviderRequest is a request the gateway made to a provider on
+// behalf of a flight record, after routing, fallbacks and transformations
+type CapturedProviderRequest struct {
+	Provider  string          `json:"provider"`
+	Operation string          `json:"operation"`
+	Body      json.RawMessage `json:"body"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// redactMargin is read past the capture limit so a secret crossing the cut
+// is still recognized and redacted whole
+const redactMargin = 1024
+
+// captureRequest reads up to limit bytes of r's body into a CapturedRequest,
+// leaving the body intact for the handler. redact, if set, is applied before
+// the body is cut at limit.
+func captureRequest(r *http.Request, limit int, redact func(string) string) *CapturedRequest {
+	captured := &CapturedRequest{Headers: http.Header{}}
+	for name, values := range r.Header {
+		if capturedHeaderDenylist[http.CanonicalHeaderKey(name)] || strings.Contains(strings.ToLower(name), "secret") {
+			captured.Headers[name] = []string{"[redacted]"}
+			continue
+		}
+		captured.Headers[name] = values
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return captured
+	}
+
+	margin := 1
+	if redact != nil {
+		margin = redactMargin
+	}
+	head, _ := io.ReadAll(io.LimitReader(r.Body, int64(limit+margin)))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	captured.Body, captured.Truncated = cut(string(head), limit, redact)
+	return captured
+}
+
+// cut applies redact, if set, to text and cuts the result at limit bytes,
+// reporting whether it was cut
+func cut(text string, limit int, redact func(string) string) (string, bool) {
+	if redact != nil {
+		text = redact(text)
+	}
+	if len(text) > limit {
+		return text[:limit], true
+	}
+	return text, false
+}
+
+// CaptureProviderRequest adds a provider request to the flight record in
+// ctx, if the recorder captures bodies
+func CaptureProviderRequest(ctx context.Context, provider, operation string, req interface{}) {
+	record, ok := ctx.Value(flightRecordKey{}).(*FlightRecord)
+	if !ok || record.captureLimit == 0 {
+		return
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	captured := CapturedProviderRequest{Provider: provider, Operation: operation}
+	text, truncated := cut(string(body), record.captureLimit, record.redact)
+	captured.Body, captured.Truncated = json.RawMessage(text), truncated
+	if truncated || !json.Valid(captured.Body) {
+		// Truncated or redacted-through JSON is kept as a string so the
+		// record stays valid JSON
+		captured.Body, _ = json.Marshal(text)
+	}
+
+	record.mu.Lock()
+	record.ProviderRequests = append(record.ProviderRequests, captured)
+	record.mu.Unlock()
+}
+
+// CaptureStreamChunk adds a chunk sent to the client to the flight record in
+// ctx, if the recorder captures bodies. Chunks past the capture limit are
+// counted but not kept.
+func CaptureStreamChunk(ctx context.Context, chunk []byte) {
+	record, ok := ctx.Value(flightRecordKey{}).(*FlightRecord)
+	if !ok || record.captureLimit == 0 {
+		return
+	}
+	text := strings.TrimSpace(string(chunk))
+	if text == "" {
+		return
+	}
+	if record.redact != nil {
+		text = record.redact(text)
+	}
+
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	if record.streamBytes+len(text) > record.captureLimit {
+		record.StreamTruncated = true
+		return
+	}
+	record.streamBytes += len(text)
+	record.StreamChunks = append(record.StreamChunks, text)
+}
@@ -0,0 +1,118 @@
+package observability
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelValueEscaper escapes label values in series keys, so distinct label
+// sets never share a key
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsCollector exposes the gateway's metrics to a prometheus registry.
+// Series are created as they are first recorded, so the collector is
+// unchecked: it describes nothing up front and builds each series'
+// descriptor at collection time.
+type metricsCollector struct {
+	m *Metrics
+}
+
+func (c metricsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.collect(&exposition{ch: ch})
+}
+
+// exposition sends metric families to a registry scrape; the registry
+// encodes them in the format the scraper negotiated
+type exposition struct {
+	ch chan<- prometheus.Metric
+}
+
+// desc returns the descriptor of one series, naming its labels in order
+func desc(name, help string, labels map[string]string) (*prometheus.Desc, []string) {
+	names := sortedKeys(labels)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = labels[name]
+	}
+	return prometheus.NewDesc(name, help, names, nil), values
+}
+
+// counters sends a counter family
+func (e *exposition) counters(name, help string, lc *LabeledCounter) {
+	lc.each(func(labels map[string]string, c *Counter) {
+		d, values := desc(name, help, labels)
+		e.ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(c.Value()), values...)
+	})
+}
+
+// gauges sends a family of float series; typ is a gauge, or a counter for
+// float totals such as spend
+func (e *exposition) gauges(name string, typ prometheus.ValueType, help string, lg *LabeledGauge) {
+	lg.each(func(labels map[string]string, g *Gauge) {
+		d, values := desc(name, help, labels)
+		e.ch <- prometheus.MustNewConstMetric(d, typ, g.Value(), values...)
+	})
+}
+
+// gauge sends an unlabeled gauge
+func (e *exposition) gauge(name, help string, g *Gauge) {
+	d, _ := desc(name, help, nil)
+	e.ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, g.Value())
+}
+
+// histograms sends a histogram family with cumulative buckets and the trace
+// exemplars of its buckets, which only OpenMetrics scrapes carry
+func (e *exposition) histograms(name, help string, lh *LabeledHistogram) {
+	lh.each(func(labels map[string]string, hist *Histogram) {
+		d, values := desc(name, help, labels)
+		buckets, counts, sum, count := hist.Values()
+		cumulative := make(map[float64]uint64, len(buckets))
+		total := uint64(0)
+		for i, bound := range buckets {
+			total += uint64(counts[i])
+			cumulative[bound] = total
+		}
+		metric := prometheus.MustNewConstHistogram(d, uint64(count), sum, cumulative, values...)
+
+		var exemplars []prometheus.Exemplar
+		for _, ex := range hist.Exemplars() {
+			if ex != nil {
+				exemplars = append(exemplars, prometheus.Exemplar{
+					Value:     ex.Value,
+					Labels:    prometheus.Labels{"trace_id": ex.TraceID},
+					Timestamp: ex.Timestamp,
+				})
+			}
+		}
+		if len(exemplars) > 0 {
+			if withExemplars, err := prometheus.NewMetricWithExemplars(metric, exemplars...); err == nil {
+				metric = withExemplars
+			}
+		}
+		e.ch <- metric
+	})
+}
+
+// labelsToKey encodes labels sorted by name, so that the same labels always
+// map to the same series
+func labelsToKey(labels map[string]string) string {
+	names := sortedKeys(labels)
+	pairs := make([]string, 0, len(names))
+	for _, k := range names {
+		pairs = append(pairs, k+`="`+labelValueEscaper.Replace(labels[k])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,336 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// FlightRecorderConfig holds configuration for the in-memory request flight recorder
+type FlightRecorderConfig struct {
+	Enabled bool
+	// Size is the number of recent requests kept in the ring buffer
+	Size int
+	// ErrorRateThreshold triggers a dump when the share of 5xx responses among the
+	// last Window requests reaches it
+	ErrorRateThreshold float64
+	Window             int
+	// MinRequests is the number of requests required before the error rate is evaluated
+	MinRequests int
+	// Cooldown is the minimum time between automatic dumps
+	Cooldown time.Duration
+	// CaptureBodies keeps the request body, the requests sent to providers and
+	// the streamed chunks of each record, up to MaxCaptureBytes each
+	CaptureBodies   bool
+	MaxCaptureBytes int
+}
+
+// DefaultFlightRecorderConfig returns sensible defaults
+func DefaultFlightRecorderConfig() FlightRecorderConfig {
+	return FlightRecorderConfig{
+		Enabled:            false,
+		Size:               200,
+		ErrorRateThreshold: 0.2,
+		Window:             50,
+		MinRequests:        20,
+		Cooldown:           5 * time.Minute,
+		MaxCaptureBytes:    64 << 10,
+	}
+}
+
+// ProviderAttempt is one call to an upstream provider made while serving a request
+type ProviderAttempt struct {
+	Provider     string        `json:"provider"`
+	Operation    string        `json:"operation"`
+	Attempt      int           `json:"attempt"`
+	Duration     time.Duration `json:"duration"`
+	Status       int           `json:"status,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ErrorClass   string        `json:"error_class,omitempty"`
+	BreakerState string        `json:"breaker_state,omitempty"`
+	// UpstreamRequestID is the provider's ID for the call, from its request
+	// ID header (x-request-id for OpenAI, request-id for Anthropic)
+	UpstreamRequestID string `json:"upstream_request_id,omitempty"`
+	// ResponseID is the ID of the provider's response, such as chatcmpl-...
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// FlightRecord holds the debug details of one request
+type FlightRecord struct {
+	RequestID string            `json:"request_id,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	EndUser   string            `json:"end_user,omitempty"`
+	Status    int               `json:"status"`
+	Start     time.Time         `json:"start"`
+	Duration  time.Duration     `json:"duration"`
+	Attempts  []ProviderAttempt `json:"attempts,omitempty"`
+
+	// Captured bodies, when the recorder captures them
+	Request          *CapturedRequest          `json:"request,omitempty"`
+	ProviderRequests []CapturedProviderRequest `json:"provider_requests,omitempty"`
+	StreamChunks     []string                  `json:"stream_chunks,omitempty"`
+	StreamTruncated  bool                      `json:"stream_truncated,omitempty"`
+
+	mu sync.Mutex
+	// captureLimit is the capture limit in bytes, 0 when bodies are not captured
+	captureLimit int
+	streamBytes  int
+	redact       func(string) string
+}
+
+type flightRecordKey struct{}
+
+// RecordProviderAttempt adds a provider attempt to the flight record and
+// attempt timeline in ctx, if any, and as an event on the current span
+func RecordProviderAttempt(ctx context.Context, attempt ProviderAttempt) {
+	addAttempt(ctx, attempt)
+	notifyAttemptObservers(attempt)
+
+	if record, ok := ctx.Value(flightRecordKey{}).(*FlightRecord); ok {
+		record.mu.Lock()
+		record.Attempts = append(record.Attempts, attempt)
+		record.mu.Unlock()
+	}
+}
+
+// SetFlightEndUser records the end user a request was made for on the flight record in ctx, if any
+func SetFlightEndUser(ctx context.Context, user string) {
+	if record, ok := ctx.Value(flightRecordKey{}).(*FlightRecord); ok {
+		record.mu.Lock()
+		record.EndUser = user
+		record.mu.Unlock()
+	}
+}
+
+// FlightRecorder keeps detailed debug info for the last N requests and dumps it
+// to the audit log when the error rate crosses a threshold
+type FlightRecorder struct {
+	config FlightRecorderConfig
+
+	mu       sync.Mutex
+	records  []*FlightRecord
+	next     int
+	count    int
+	lastDump time.Time
+	dumps    int64
+
+	// stateFunc returns subsystem state (e.g. circuit breakers) to include in dumps
+	stateFunc func() map[string]interface{}
+	// redact, if set, removes sensitive data from captured bodies
+	redact func(string) string
+}
+
+var (
+	globalFlightRecorder *FlightRecorder
+	flightRecorderOnce   sync.Once
+)
+
+// NewFlightRecorder creates a new flight recorder
+func NewFlightRecorder(config FlightRecorderConfig) *FlightRecorder {
+	defaults := DefaultFlightRecorderConfig()
+	if config.Size <= 0 {
+		config.Size = defaults.Size
+	}
+	if config.Window <= 0 || config.Window > config.Size {
+		config.Window = config.Size
+	}
+	if config.MinRequests > config.Window {
+		config.MinRequests = config.Window
+	}
+	if config.MaxCaptureBytes <= 0 {
+		config.MaxCaptureBytes = defaults.MaxCaptureBytes
+	}
+
+	return &FlightRecorder{
+		config:  config,
+		records: make([]*FlightRecord, config.Size),
+	}
+}
+
+// InitGlobalFlightRecorder initializes the global flight recorder instance
+func InitGlobalFlightRecorder(config FlightRecorderConfig) *FlightRecorder {
+	flightRecorderOnce.Do(func() {
+		globalFlightRecorder = NewFlightRecorder(config)
+	})
+	return globalFlightRecorder
+}
+
+// GetFlightRecorder returns the global flight recorder, or nil if it is not enabled
+func GetFlightRecorder() *FlightRecorder {
+	return globalFlightRecorder
+}
+
+// SetStateFunc sets the function whose output is attached to every dump
+func (fr *FlightRecorder) SetStateFunc(fn func() map[string]interface{}) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.stateFunc = fn
+}
+
+// SetRedactor sets the function applied to captured bodies and stream chunks
+// before they are kept, so a record holds no more than the gateway would
+// send on: credentials and personal data the prompt scanners remove
+func (fr *FlightRecorder) SetRedactor(fn func(string) string) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.redact = fn
+}
+
+// Middleware returns a middleware that records every request in the ring buffer
+func (fr *FlightRecorder) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record := &FlightRecord{
+				RequestID: middleware.GetReqID(r.Context()),
+				TraceID:   TraceID(r.Context()),
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Start:     time.Now(),
+			}
+			if fr.config.CaptureBodies {
+				fr.mu.Lock()
+				record.redact = fr.redact
+				fr.mu.Unlock()
+				record.captureLimit = fr.config.MaxCaptureBytes
+				record.Request = captureRequest(r, fr.config.MaxCaptureBytes, record.redact)
+			}
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), flightRecordKey{}, record)))
+
+			record.mu.Lock()
+			record.Status = rw.status
+			record.Duration = time.Since(record.Start)
+			record.mu.Unlock()
+
+			fr.add(r.Context(), record)
+		})
+	}
+}
+
+// add stores a finished record and dumps the buffer if the error rate is too high
+func (fr *FlightRecorder) add(ctx context.Context, record *FlightRecord) {
+	fr.mu.Lock()
+	fr.records[fr.next] = record
+	fr.next = (fr.next + 1) % len(fr.records)
+	if fr.count < len(fr.records) {
+		fr.count++
+	}
+
+	rate, evaluated := fr.errorRate()
+	trigger := evaluated &&
+		rate >= fr.config.ErrorRateThreshold &&
+		time.Since(fr.lastDump) >= fr.config.Cooldown
+	if trigger {
+		fr.lastDump = time.Now()
+	}
+	fr.mu.Unlock()
+
+	if trigger {
+		fr.Dump(ctx, "error_rate_threshold", rate)
+	}
+}
+
+// errorRate returns the share of 5xx responses among the last Window requests. Callers hold fr.mu.
+func (fr *FlightRecorder) errorRate() (float64, bool) {
+	window := fr.config.Window
+	if fr.count < window {
+		window = fr.count
+	}
+	if window == 0 || window < fr.config.MinRequests {
+		return 0, false
+	}
+
+	errors := 0
+	for i := 1; i <= window; i++ {
+		record := fr.records[(fr.next-i+len(fr.records))%len(fr.records)]
+		if record.Status >= 500 {
+			errors++
+		}
+	}
+	return float64(errors) / float64(window), true
+}
+
+// Records returns the buffered requests, oldest first
+func (fr *FlightRecorder) Records() []*FlightRecord {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	records := make([]*FlightRecord, 0, fr.count)
+	start := (fr.next - fr.count + len(fr.records)) % len(fr.records)
+	for i := 0; i < fr.count; i++ {
+		records = append(records, fr.records[(start+i)%len(fr.records)])
+	}
+	return records
+}
+
+// Record returns the buffered request with the given ID, or nil
+func (fr *FlightRecorder) Record(requestID string) *FlightRecord {
+	for _, record := range fr.Records() {
+		if record.RequestID == requestID {
+			return record
+		}
+	}
+	return nil
+}
+
+// DeleteUser removes the end user from the buffered requests, returning the
+// number of records scrubbed. The records stay without their captured
+// bodies, as they hold no other user data.
+func (fr *FlightRecorder) DeleteUser(user string) int {
+	scrubbed := 0
+	for _, record := range fr.Records() {
+		record.mu.Lock()
+		if record.EndUser == user {
+			record.EndUser = ""
+			record.Request, record.ProviderRequests, record.StreamChunks = nil, nil, nil
+			scrubbed++
+		}
+		record.mu.Unlock()
+	}
+	return scrubbed
+}
+
+// Dump writes the buffered requests and subsystem state to the audit log
+func (fr *FlightRecorder) Dump(ctx context.Context, reason string, errorRate float64) {
+	fr.mu.Lock()
+	fr.dumps++
+	stateFunc := fr.stateFunc
+	fr.mu.Unlock()
+
+	details := map[string]interface{}{
+		"reason":     reason,
+		"error_rate": errorRate,
+		"requests":   fr.Records(),
+	}
+	if stateFunc != nil {
+		details["state"] = stateFunc()
+	}
+
+	LogAudit(ctx, "flight_recorder.dump", "flight_recorder", details)
+}
+
+// Status returns the recorder configuration and current error rate
+func (fr *FlightRecorder) Status() map[string]interface{} {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	rate, _ := fr.errorRate()
+	status := map[string]interface{}{
+		"size":                 len(fr.records),
+		"buffered":             fr.count,
+		"window":               fr.config.Window,
+		"error_rate":           rate,
+		"error_rate_threshold": fr.config.ErrorRateThreshold,
+		"dumps":                fr.dumps,
+	}
+	if !fr.lastDump.IsZero() {
+		status["last_dump"] = fr.lastDump
+	}
+	return status
+}
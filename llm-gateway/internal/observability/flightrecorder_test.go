@@ -0,0 +1,178 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlightRecorder_RecordsAttempts(t *testing.T) {
+	fr := NewFlightRecorder(FlightRecorderConfig{Enabled: true, Size: 3, ErrorRateThreshold: 1, Cooldown: time.Hour})
+
+	handler := fr.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordProviderAttempt(r.Context(), ProviderAttempt{Provider: "openai", Attempt: 1, Error: "rate limited"})
+		RecordProviderAttempt(r.Context(), ProviderAttempt{Provider: "openai", Attempt: 2})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/chat/completions", nil))
+	}
+
+	records := fr.Records()
+	if len(records) != 3 {
+		t.Fatalf("Records() = %d, want ring buffer size 3", len(records))
+	}
+	if got := records[2].Attempts; len(got) != 2 || got[0].Error != "rate limited" {
+		t.Errorf("attempts = %+v, want both provider attempts", got)
+	}
+	if records[2].Status != http.StatusOK {
+		t.Errorf("status = %d, want 200", records[2].Status)
+	}
+}
+
+func TestFlightRecorder_DumpsOnErrorRate(t *testing.T) {
+	fr := NewFlightRecorder(FlightRecorderConfig{
+		Enabled:            true,
+		Size:               20,
+		Window:             10,
+		MinRequests:        10,
+		ErrorRateThreshold: 0.5,
+		Cooldown:           time.Hour,
+	})
+
+	status := http.StatusOK
+	handler := fr.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	serve := func(n int) {
+		for i := 0; i < n; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/models", nil))
+		}
+	}
+
+	serve(6)
+	status = http.StatusBadGateway
+	serve(4)
+	if dumps := fr.Status()["dumps"]; dumps != int64(0) {
+		t.Fatalf("dumps = %v below threshold, want 0", dumps)
+	}
+
+	serve(1) // 5 of the last 10 failed
+	if dumps := fr.Status()["dumps"]; dumps != int64(1) {
+		t.Fatalf("dumps = %v at threshold, want 1", dumps)
+	}
+
+	serve(5) // still failing, but within the cooldown
+	if dumps := fr.Status()["dumps"]; dumps != int64(1) {
+		t.Errorf("dumps = %v during cooldown, want 1", dumps)
+	}
+}
+
+func TestRecordProviderAttempt_TimelineAndSpan(t *testing.T) {
+	span := &Span{Name: "request"}
+	ctx := WithAttemptTimeline(ContextWithSpan(context.Background(), span))
+
+	RecordProviderAttempt(ctx, ProviderAttempt{Provider: "openai", Attempt: 1, Status: 503, Error: "unavailable", ErrorClass: "upstream_error"})
+	RecordProviderAttempt(ctx, ProviderAttempt{Provider: "openai", Attempt: 2, Duration: 1500 * time.Millisecond})
+
+	attempts := ProviderAttempts(ctx)
+	if len(attempts) != 2 || attempts[0].ErrorClass != "upstream_error" {
+		t.Fatalf("ProviderAttempts = %+v", attempts)
+	}
+	if len(span.Events) != 2 || span.Events[0].Name != "provider.attempt" ||
+		span.Events[0].Attributes["status"] != 503 || span.Events[1].Attributes["duration_ms"] != int64(1500) {
+		t.Errorf("span events = %+v", span.Events)
+	}
+
+	if ProviderAttempts(context.Background()) != nil {
+		t.Error("attempts reported without a timeline")
+	}
+}
+
+func TestFlightRecorder_CapturesBodies(t *testing.T) {
+	fr := NewFlightRecorder(FlightRecorderConfig{Enabled: true, Size: 3, ErrorRateThreshold: 1, CaptureBodies: true, MaxCaptureBytes: 20})
+
+	var body []byte
+	handler := fr.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		SetFlightEndUser(r.Context(), "alice")
+		CaptureProviderRequest(r.Context(), "openai", "chat_completion", map[string]string{"model": "gpt-4o"})
+		CaptureProviderRequest(r.Context(), "openai", "chat_completion", map[string]string{"prompt": strings.Repeat("x", 32)})
+		CaptureStreamChunk(r.Context(), []byte("data: {\"a\":1}\n"))
+		CaptureStreamChunk(r.Context(), []byte("\n"))
+		CaptureStreamChunk(r.Context(), []byte("data: [DONE]\n"))
+	}))
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("X-Request-Id", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if string(body) != `{"model":"gpt-4o","messages":[]}` {
+		t.Errorf("handler read body %q, want it intact", body)
+	}
+	record := fr.Records()[0]
+	if record.Request == nil || record.Request.Body != `{"model":"gpt-4o","m` || !record.Request.Truncated ||
+		record.Request.Headers.Get("Authorization") != "[redacted]" || record.Request.Headers.Get("X-Request-Id") != "req-1" {
+		t.Errorf("request = %+v", record.Request)
+	}
+	if got := record.ProviderRequests; len(got) != 2 || string(got[0].Body) != `{"model":"gpt-4o"}` || got[0].Truncated || !got[1].Truncated {
+		t.Errorf("provider requests = %+v", got)
+	}
+	if got := record.StreamChunks; len(got) != 1 || got[0] != `data: {"a":1}` || !record.StreamTruncated {
+		t.Errorf("stream = %q, truncated %v", got, record.StreamTruncated)
+	}
+
+	// Deleting the end user's data drops the captures
+	if fr.DeleteUser("alice") != 1 || record.Request != nil || record.ProviderRequests != nil || record.StreamChunks != nil {
+		t.Errorf("captures kept after DeleteUser: %+v", record)
+	}
+}
+
+func TestFlightRecorder_RedactsCaptures(t *testing.T) {
+	fr := NewFlightRecorder(FlightRecorderConfig{Enabled: true, Size: 3, ErrorRateThreshold: 1, CaptureBodies: true, MaxCaptureBytes: 40})
+	fr.SetRedactor(func(s string) string { return strings.ReplaceAll(s, "sk-live-0123456789", "[REDACTED]") })
+
+	handler := fr.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		CaptureProviderRequest(r.Context(), "openai", "chat_completion", map[string]string{"prompt": "key sk-live-0123456789"})
+		CaptureStreamChunk(r.Context(), []byte("data: sk-live-0123456789\n"))
+	}))
+	// The secret crosses the capture limit
+	body := `{"prompt":"` + strings.Repeat("x", 25) + ` sk-live-0123456789"}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+
+	record := fr.Records()[0]
+	data, _ := json.Marshal(record)
+	if strings.Contains(string(data), "sk-live") {
+		t.Errorf("record keeps the secret: %s", data)
+	}
+	if !record.Request.Truncated || !strings.HasSuffix(record.Request.Body, "x [RE") {
+		t.Errorf("request = %+v", record.Request)
+	}
+	if got := record.ProviderRequests; len(got) != 1 || string(got[0].Body) != `{"prompt":"key [REDACTED]"}` {
+		t.Errorf("provider requests = %+v", got)
+	}
+	if got := record.StreamChunks; len(got) != 1 || got[0] != "data: [REDACTED]" {
+		t.Errorf("stream = %q", got)
+	}
+}
+
+func TestTracer_SpansForTrace(t *testing.T) {
+	tracer := NewTracer(TracingConfig{Enabled: true, SamplingRate: 1})
+	ctx, root := tracer.StartSpan(context.Background(), "request")
+	_, child := tracer.StartSpan(ctx, "provider")
+	child.End()
+	root.End()
+	_, other := tracer.StartSpan(context.Background(), "other")
+	other.End()
+
+	spans := tracer.SpansForTrace(root.Context.TraceID)
+	if len(spans) != 2 || spans[0].Name != "request" || spans[1].ParentID != root.Context.SpanID {
+		t.Errorf("SpansForTrace() = %+v", spans)
+	}
+}
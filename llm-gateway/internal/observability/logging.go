@@ -2,6 +2,7 @@ package observability
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -18,6 +19,15 @@ type LoggingConfig struct {
 	Output     string // stdout, stderr, file
 	FilePath   string // Path when output is file
 	TimeFormat string // Time format for logs
+	// File rotation (output=file)
+	MaxSizeMB      int           // Rotate when the file exceeds this size (0 = no limit)
+	RotateInterval time.Duration // Rotate when the file is older than this (0 = no limit)
+	MaxBackups     int           // Rotated files to keep (0 = keep all)
+	Compress       bool          // Gzip rotated files
+	// Async buffering (output=file)
+	Async          bool
+	BufferSize     int    // Pending writes held in memory
+	OverflowPolicy string // drop, block
 	// Include fields
 	IncludeTimestamp bool
 	IncludeCaller    bool
@@ -34,6 +44,13 @@ func DefaultLoggingConfig() LoggingConfig {
 		Format:           "json",
 		Output:           "stdout",
 		TimeFormat:       time.RFC3339Nano,
+		MaxSizeMB:        100,
+		RotateInterval:   24 * time.Hour,
+		MaxBackups:       7,
+		Compress:         true,
+		Async:            true,
+		BufferSize:       4096,
+		OverflowPolicy:   OverflowBlock,
 		IncludeTimestamp: true,
 		IncludeCaller:    false,
 		IncludeHostname:  false,
@@ -46,6 +63,7 @@ func DefaultLoggingConfig() LoggingConfig {
 type Logger struct {
 	config LoggingConfig
 	logger zerolog.Logger
+	closer io.Closer
 }
 
 // NewLogger creates a new logger with the given configuration
@@ -61,22 +79,8 @@ func NewLogger(config LoggingConfig) *Logger {
 	zerolog.TimeFieldFormat = config.TimeFormat
 
 	// Create output writer
-	var output io.Writer
-	switch config.Output {
-	case "stderr":
-		output = os.Stderr
-	case "file":
-		if config.FilePath != "" {
-			file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-			if err != nil {
-				output = os.Stdout
-			} else {
-				output = file
-			}
-		} else {
-			output = os.Stdout
-		}
-	default:
+	output, closer, err := NewLogOutput(config)
+	if err != nil {
 		output = os.Stdout
 	}
 
@@ -105,9 +109,51 @@ func NewLogger(config LoggingConfig) *Logger {
 	return &Logger{
 		config: config,
 		logger: logger,
+		closer: closer,
+	}
+}
+
+// NewLogOutput returns the writer for config.Output. For file output it is a
+// rotating file, optionally behind an async buffer; the returned closer flushes
+// and closes it and is nil for stdout/stderr.
+func NewLogOutput(config LoggingConfig) (io.Writer, io.Closer, error) {
+	switch config.Output {
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "file":
+		if config.FilePath == "" {
+			return nil, nil, fmt.Errorf("log file path is required when output is file")
+		}
+
+		file, err := NewRotatingFile(RotateConfig{
+			Path:       config.FilePath,
+			MaxSize:    int64(config.MaxSizeMB) * 1024 * 1024,
+			Interval:   config.RotateInterval,
+			MaxBackups: config.MaxBackups,
+			Compress:   config.Compress,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !config.Async {
+			return file, file, nil
+		}
+		async := NewAsyncWriter(file, config.BufferSize, config.OverflowPolicy)
+		return async, async, nil
+	default:
+		return os.Stdout, nil, nil
 	}
 }
 
+// Close flushes buffered log output and closes the log file, if any
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
 // WithContext returns a logger with trace context fields
 func (l *Logger) WithContext(ctx context.Context) zerolog.Logger {
 	span := SpanFromContext(ctx)
@@ -295,13 +341,14 @@ var CtxLog = &ContextLogger{}
 
 // AuditLog represents an audit log entry
 type AuditLog struct {
+	ID          int64                  `json:"id"`
 	Timestamp   time.Time              `json:"timestamp"`
 	Action      string                 `json:"action"`
 	Actor       string                 `json:"actor,omitempty"`
 	Resource    string                 `json:"resource"`
 	ResourceID  string                 `json:"resource_id,omitempty"`
 	TraceID     string                 `json:"trace_id,omitempty"`
-	Status      string                 `json:"status"` // success, failure
+	Status      string                 `json:"status,omitempty"` // success, failure
 	Details     map[string]interface{} `json:"details,omitempty"`
 }
 
@@ -323,6 +370,8 @@ func LogAudit(ctx context.Context, action, resource string, details map[string]i
 	}
 
 	event.Msg("Audit log")
+
+	recordAudit(ctx, action, resource, details)
 }
 
 // LogProviderRequest logs a provider API request
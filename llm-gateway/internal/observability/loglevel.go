@@ -0,0 +1,236 @@
+package observability
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// logLevels holds the runtime global level and per-module overrides. zerolog's
+// global level is kept at the most verbose of these so module loggers can log
+// below the global level; levelHook then filters each event.
+type logLevels struct {
+	mu      sync.RWMutex
+	global  zerolog.Level
+	modules map[string]zerolog.Level
+}
+
+var levels = &logLevels{
+	global:  zerolog.InfoLevel,
+	modules: make(map[string]zerolog.Level),
+}
+
+var (
+	moduleLoggersMu sync.Mutex
+	moduleLoggers   = make(map[string]*ModuleLog)
+
+	// globalLevel is the level of the root logger's hook
+	globalLevel atomic.Int32
+)
+
+func init() {
+	globalLevel.Store(int32(zerolog.InfoLevel))
+}
+
+// ModuleLog is the logger of one subsystem. Its events carry a "module" field
+// and are filtered by that module's level, falling back to the global level.
+// The logger and level are read atomically, so InitLogLevels and
+// UpdateLogLevels can change them while other goroutines log.
+type ModuleLog struct {
+	module string
+	level  atomic.Int32
+	logger atomic.Pointer[zerolog.Logger]
+}
+
+// Debug starts a debug event
+func (m *ModuleLog) Debug() *zerolog.Event { return m.logger.Load().Debug() }
+
+// Info starts an info event
+func (m *ModuleLog) Info() *zerolog.Event { return m.logger.Load().Info() }
+
+// Warn starts a warn event
+func (m *ModuleLog) Warn() *zerolog.Event { return m.logger.Load().Warn() }
+
+// Error starts an error event
+func (m *ModuleLog) Error() *zerolog.Event { return m.logger.Load().Error() }
+
+// Logger returns the module's current logger
+func (m *ModuleLog) Logger() zerolog.Logger { return *m.logger.Load() }
+
+// setBase rebuilds the module's logger on top of base
+func (m *ModuleLog) setBase(base zerolog.Logger) {
+	logger := base.With().Str("module", m.module).Logger().Hook(levelHook{level: &m.level})
+	m.logger.Store(&logger)
+}
+
+// effective returns the level for module ("" is the global level). Callers hold l.mu.
+func (l *logLevels) effective(module string) zerolog.Level {
+	if level, ok := l.modules[module]; ok {
+		return level
+	}
+	return l.global
+}
+
+// update changes the levels with set and publishes the result to zerolog's
+// global level, the root hook and every module logger
+func (l *logLevels) update(set func(l *logLevels)) {
+	moduleLoggersMu.Lock()
+	defer moduleLoggersMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	set(l)
+
+	min := l.global
+	for _, level := range l.modules {
+		if level < min {
+			min = level
+		}
+	}
+	zerolog.SetGlobalLevel(min)
+	globalLevel.Store(int32(l.global))
+	for module, logger := range moduleLoggers {
+		logger.level.Store(int32(l.effective(module)))
+	}
+}
+
+// levelHook discards events below the level it points to
+type levelHook struct {
+	level *atomic.Int32
+}
+
+func (h levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level != zerolog.NoLevel && level < zerolog.Level(h.level.Load()) {
+		e.Discard()
+	}
+}
+
+// InitLogLevels sets the global and per-module levels and returns base wrapped
+// with the global level filter. Module loggers are rebuilt on top of base.
+func InitLogLevels(base zerolog.Logger, global string, modules map[string]string) (zerolog.Logger, error) {
+	parsedGlobal, err := parseLevel(global)
+	if err != nil {
+		return base, err
+	}
+
+	moduleLevels := make(map[string]zerolog.Level, len(modules))
+	for module, level := range modules {
+		parsed, err := parseLevel(level)
+		if err != nil {
+			return base, fmt.Errorf("module %s: %w", module, err)
+		}
+		moduleLevels[module] = parsed
+	}
+
+	levels.update(func(l *logLevels) {
+		l.global = parsedGlobal
+		l.modules = moduleLevels
+	})
+
+	// Point module loggers at the new output; package-level references see it on their next event
+	moduleLoggersMu.Lock()
+	for _, logger := range moduleLoggers {
+		logger.setBase(base)
+	}
+	moduleLoggersMu.Unlock()
+
+	return base.Hook(levelHook{level: &globalLevel}), nil
+}
+
+// ModuleLogger returns the logger for a subsystem
+func ModuleLogger(module string) *ModuleLog {
+	moduleLoggersMu.Lock()
+	defer moduleLoggersMu.Unlock()
+
+	if logger, ok := moduleLoggers[module]; ok {
+		return logger
+	}
+	logger := &ModuleLog{module: module}
+	levels.mu.RLock()
+	logger.level.Store(int32(levels.effective(module)))
+	levels.mu.RUnlock()
+	logger.setBase(log.Logger)
+	moduleLoggers[module] = logger
+	return logger
+}
+
+// UpdateLogLevels changes levels at runtime. An empty global level leaves it
+// unchanged and an empty module level removes that module's override. Nothing
+// is applied if any level is invalid.
+func UpdateLogLevels(global string, modules map[string]string) error {
+	var parsedGlobal zerolog.Level
+	if global != "" {
+		parsed, err := parseLevel(global)
+		if err != nil {
+			return err
+		}
+		parsedGlobal = parsed
+	}
+
+	moduleLevels := make(map[string]zerolog.Level, len(modules))
+	for module, level := range modules {
+		if module == "" {
+			return fmt.Errorf("module name is required")
+		}
+		if level == "" {
+			continue
+		}
+		parsed, err := parseLevel(level)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		moduleLevels[module] = parsed
+	}
+
+	levels.update(func(l *logLevels) {
+		if global != "" {
+			l.global = parsedGlobal
+		}
+		for module, level := range modules {
+			if level == "" {
+				delete(l.modules, module)
+			} else {
+				l.modules[module] = moduleLevels[module]
+			}
+		}
+	})
+	return nil
+}
+
+// LogLevelStatus returns the global level, module overrides and known modules
+func LogLevelStatus() map[string]interface{} {
+	levels.mu.RLock()
+	overrides := make(map[string]string, len(levels.modules))
+	for module, level := range levels.modules {
+		overrides[module] = level.String()
+	}
+	global := levels.global.String()
+	levels.mu.RUnlock()
+
+	moduleLoggersMu.Lock()
+	known := make([]string, 0, len(moduleLoggers))
+	for module := range moduleLoggers {
+		known = append(known, module)
+	}
+	moduleLoggersMu.Unlock()
+	sort.Strings(known)
+
+	return map[string]interface{}{
+		"level":         global,
+		"modules":       overrides,
+		"known_modules": known,
+	}
+}
+
+// parseLevel parses a zerolog level name, rejecting empty and unknown names
+func parseLevel(level string) (zerolog.Level, error) {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil || level == "" {
+		return zerolog.NoLevel, fmt.Errorf("invalid log level: %q", level)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,116 @@
+package observability
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogLevels_PerModule(t *testing.T) {
+	var buf bytes.Buffer
+	providers := ModuleLogger("providers")
+	cache := ModuleLogger("cache")
+
+	root, err := InitLogLevels(zerolog.New(&buf), "info", map[string]string{
+		"providers": "debug",
+		"cache":     "warn",
+	})
+	if err != nil {
+		t.Fatalf("InitLogLevels() error = %v", err)
+	}
+	defer InitLogLevels(zerolog.New(&bytes.Buffer{}), "info", nil)
+
+	root.Debug().Msg("root-debug")
+	root.Info().Msg("root-info")
+	providers.Debug().Msg("providers-debug")
+	cache.Info().Msg("cache-info")
+	cache.Warn().Msg("cache-warn")
+
+	out := buf.String()
+	for _, want := range []string{"root-info", "providers-debug", "cache-warn", `"module":"providers"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"root-debug", "cache-info"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output should not contain %s:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestUpdateLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	providers := ModuleLogger("providers")
+	root, _ := InitLogLevels(zerolog.New(&buf), "info", nil)
+	defer InitLogLevels(zerolog.New(&bytes.Buffer{}), "info", nil)
+
+	if err := UpdateLogLevels("warn", map[string]string{"providers": "debug"}); err != nil {
+		t.Fatalf("UpdateLogLevels() error = %v", err)
+	}
+	root.Info().Msg("root-info")
+	providers.Debug().Msg("providers-debug")
+
+	if strings.Contains(buf.String(), "root-info") || !strings.Contains(buf.String(), "providers-debug") {
+		t.Errorf("unexpected output after update:\n%s", buf.String())
+	}
+
+	// Invalid levels are rejected without applying anything
+	if err := UpdateLogLevels("debug", map[string]string{"cache": "loud"}); err == nil {
+		t.Error("UpdateLogLevels() should reject unknown levels")
+	}
+	status := LogLevelStatus()
+	if status["level"] != "warn" {
+		t.Errorf("level = %v, want warn to be unchanged", status["level"])
+	}
+
+	// An empty module level removes the override
+	UpdateLogLevels("", map[string]string{"providers": ""})
+	buf.Reset()
+	providers.Debug().Msg("providers-debug")
+	if buf.Len() != 0 {
+		t.Errorf("providers debug should follow the global level once the override is removed:\n%s", buf.String())
+	}
+}
+
+func TestLogLevels_ReconfigureWhileLogging(t *testing.T) {
+	logger := ModuleLogger("concurrent")
+	defer InitLogLevels(zerolog.New(&bytes.Buffer{}), "info", nil)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Debug().Msg("tick")
+					logger.Info().Msg("tick")
+				}
+			}
+		}()
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < 50; i++ {
+		InitLogLevels(zerolog.New(io.Discard), "info", nil)
+		UpdateLogLevels("", map[string]string{"concurrent": "debug"})
+	}
+	close(stop)
+	wg.Wait()
+
+	InitLogLevels(zerolog.New(&buf), "info", map[string]string{"concurrent": "warn"})
+	logger.Info().Msg("dropped")
+	logger.Warn().Msg("kept")
+	if out := buf.String(); strings.Contains(out, "dropped") || !strings.Contains(out, "kept") {
+		t.Errorf("output after reconfiguring = %s", out)
+	}
+}
@@ -6,15 +6,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 )
 
 // MetricsConfig holds configuration for metrics collection
 type MetricsConfig struct {
-	Enabled      bool
-	Path         string
-	Namespace    string
-	Subsystem    string
+	Enabled          bool
+	Path             string
+	Namespace        string
+	Subsystem        string
 	HistogramBuckets []float64
 }
 
@@ -91,37 +93,65 @@ func (g *Gauge) Value() float64 {
 	return g.value
 }
 
+// Exemplar links an observed value to the trace that produced it
+type Exemplar struct {
+	TraceID   string
+	Value     float64
+	Timestamp time.Time
+}
+
 // Histogram tracks distribution of values
 type Histogram struct {
-	mu      sync.RWMutex
-	buckets []float64
-	counts  []int64
-	sum     float64
-	count   int64
+	mu        sync.RWMutex
+	buckets   []float64
+	counts    []int64
+	exemplars []*Exemplar // latest exemplar per bucket
+	sum       float64
+	count     int64
 }
 
 func NewHistogram(buckets []float64) *Histogram {
 	return &Histogram{
-		buckets: buckets,
-		counts:  make([]int64, len(buckets)+1), // +1 for +Inf bucket
+		buckets:   buckets,
+		counts:    make([]int64, len(buckets)+1), // +1 for +Inf bucket
+		exemplars: make([]*Exemplar, len(buckets)+1),
 	}
 }
 
 func (h *Histogram) Observe(v float64) {
+	h.ObserveWithExemplar(v, "")
+}
+
+// ObserveWithExemplar records a value and, if traceID is set, keeps it as the bucket's exemplar
+func (h *Histogram) ObserveWithExemplar(v float64, traceID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	h.sum += v
 	h.count++
 
-	for i, bucket := range h.buckets {
+	i := len(h.buckets) // +Inf bucket
+	for j, bucket := range h.buckets {
 		if v <= bucket {
-			h.counts[i]++
-			return
+			i = j
+			break
 		}
 	}
-	// +Inf bucket
-	h.counts[len(h.buckets)]++
+	h.counts[i]++
+
+	if traceID != "" {
+		h.exemplars[i] = &Exemplar{TraceID: traceID, Value: v, Timestamp: time.Now()}
+	}
+}
+
+// Exemplars returns the latest exemplar per bucket (nil where none was recorded)
+func (h *Histogram) Exemplars() []*Exemplar {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	exemplars := make([]*Exemplar, len(h.exemplars))
+	copy(exemplars, h.exemplars)
+	return exemplars
 }
 
 func (h *Histogram) Values() (buckets []float64, counts []int64, sum float64, count int64) {
@@ -140,11 +170,14 @@ func (h *Histogram) Values() (buckets []float64, counts []int64, sum float64, co
 type LabeledCounter struct {
 	mu       sync.RWMutex
 	counters map[string]*Counter
+	// labels holds each series' labels by key
+	labels map[string]map[string]string
 }
 
 func NewLabeledCounter() *LabeledCounter {
 	return &LabeledCounter{
 		counters: make(map[string]*Counter),
+		labels:   make(map[string]map[string]string),
 	}
 }
 
@@ -159,6 +192,7 @@ func (lc *LabeledCounter) WithLabels(labels map[string]string) *Counter {
 	}
 
 	c := &Counter{}
+	lc.labels[key] = labels
 	lc.counters[key] = c
 	return c
 }
@@ -174,16 +208,35 @@ func (lc *LabeledCounter) All() map[string]*Counter {
 	return result
 }
 
+// each calls fn with every series and its labels, in key order
+func (lc *LabeledCounter) each(fn func(labels map[string]string, c *Counter)) {
+	lc.mu.RLock()
+	keys := sortedKeys(lc.counters)
+	series := make([]*Counter, len(keys))
+	labels := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		series[i], labels[i] = lc.counters[key], lc.labels[key]
+	}
+	lc.mu.RUnlock()
+
+	for i := range keys {
+		fn(labels[i], series[i])
+	}
+}
+
 // LabeledHistogram is a histogram with labels
 type LabeledHistogram struct {
 	mu         sync.RWMutex
 	histograms map[string]*Histogram
-	buckets    []float64
+	// labels holds each series' labels by key
+	labels  map[string]map[string]string
+	buckets []float64
 }
 
 func NewLabeledHistogram(buckets []float64) *LabeledHistogram {
 	return &LabeledHistogram{
 		histograms: make(map[string]*Histogram),
+		labels:     make(map[string]map[string]string),
 		buckets:    buckets,
 	}
 }
@@ -199,6 +252,7 @@ func (lh *LabeledHistogram) WithLabels(labels map[string]string) *Histogram {
 	}
 
 	h := NewHistogram(lh.buckets)
+	lh.labels[key] = labels
 	lh.histograms[key] = h
 	return h
 }
@@ -214,33 +268,111 @@ func (lh *LabeledHistogram) All() map[string]*Histogram {
 	return result
 }
 
-func labelsToKey(labels map[string]string) string {
-	// Simple label encoding for map key
-	key := ""
-	for k, v := range labels {
-		key += k + "=" + v + ","
+// each calls fn with every series and its labels, in key order
+func (lh *LabeledHistogram) each(fn func(labels map[string]string, h *Histogram)) {
+	lh.mu.RLock()
+	keys := sortedKeys(lh.histograms)
+	series := make([]*Histogram, len(keys))
+	labels := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		series[i], labels[i] = lh.histograms[key], lh.labels[key]
+	}
+	lh.mu.RUnlock()
+
+	for i := range keys {
+		fn(labels[i], series[i])
+	}
+}
+
+// LabeledGauge is a gauge with labels, used for float totals such as spend
+type LabeledGauge struct {
+	mu     sync.RWMutex
+	gauges map[string]*Gauge
+	// labels holds each series' labels by key
+	labels map[string]map[string]string
+}
+
+func NewLabeledGauge() *LabeledGauge {
+	return &LabeledGauge{
+		gauges: make(map[string]*Gauge),
+		labels: make(map[string]map[string]string),
+	}
+}
+
+func (lg *LabeledGauge) WithLabels(labels map[string]string) *Gauge {
+	key := labelsToKey(labels)
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	if g, ok := lg.gauges[key]; ok {
+		return g
+	}
+
+	g := &Gauge{}
+	lg.labels[key] = labels
+	lg.gauges[key] = g
+	return g
+}
+
+func (lg *LabeledGauge) All() map[string]*Gauge {
+	lg.mu.RLock()
+	defer lg.mu.RUnlock()
+
+	result := make(map[string]*Gauge, len(lg.gauges))
+	for k, v := range lg.gauges {
+		result[k] = v
+	}
+	return result
+}
+
+// each calls fn with every series and its labels, in key order
+func (lg *LabeledGauge) each(fn func(labels map[string]string, g *Gauge)) {
+	lg.mu.RLock()
+	keys := sortedKeys(lg.gauges)
+	series := make([]*Gauge, len(keys))
+	labels := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		series[i], labels[i] = lg.gauges[key], lg.labels[key]
+	}
+	lg.mu.RUnlock()
+
+	for i := range keys {
+		fn(labels[i], series[i])
 	}
-	return key
 }
 
 // Metrics holds all application metrics
 type Metrics struct {
 	config MetricsConfig
+	// registry gathers the metrics for scrapes
+	registry *prometheus.Registry
 
 	// HTTP metrics
-	RequestsTotal      *LabeledCounter
-	RequestDuration    *LabeledHistogram
-	RequestsInFlight   *Gauge
-	ResponseSizeBytes  *LabeledHistogram
+	RequestsTotal     *LabeledCounter
+	RequestDuration   *LabeledHistogram
+	RequestsInFlight  *Gauge
+	ResponseSizeBytes *LabeledHistogram
 
 	// Provider metrics
 	ProviderRequestsTotal   *LabeledCounter
 	ProviderRequestDuration *LabeledHistogram
 	ProviderErrors          *LabeledCounter
+	ProviderKeyRequests     *LabeledCounter
 
 	// Circuit breaker metrics
-	CircuitBreakerState   *LabeledCounter // state changes
-	CircuitBreakerOpen    *LabeledCounter
+	CircuitBreakerState *LabeledCounter // state changes
+	CircuitBreakerOpen  *LabeledCounter
+	// Sliding window circuit breakers: requests in the window and their failure rate
+	CircuitBreakerWindowRequests    *LabeledGauge
+	CircuitBreakerWindowFailureRate *LabeledGauge
+
+	// Retry metrics: retryable failures not retried, by provider and reason
+	RetriesSuppressed *LabeledCounter
+
+	// UpstreamUnknownFields counts upstream response fields the adapters do
+	// not decode, by provider, API version and field path
+	UpstreamUnknownFields *LabeledCounter
 
 	// Rate limiter metrics
 	RateLimitedRequests *LabeledCounter
@@ -248,11 +380,72 @@ type Metrics struct {
 	// Cache metrics
 	CacheHits   *LabeledCounter
 	CacheMisses *LabeledCounter
+	// DegradedRequests counts requests answered in degraded mode, by mode and outcome
+	DegradedRequests *LabeledCounter
 
 	// Token usage metrics
 	TokensPrompt     *LabeledCounter
 	TokensCompletion *LabeledCounter
 	TokensTotal      *LabeledCounter
+
+	// Prompt language metrics
+	RequestsByLanguage *LabeledCounter
+	// LanguageEnforcement counts responses in the wrong language by required
+	// language and action: report, retry, corrected or failed
+	LanguageEnforcement *LabeledCounter
+	// Translations counts responses translated on request by language and outcome
+	Translations *LabeledCounter
+
+	// Use case metrics
+	RequestsByUseCase *LabeledCounter
+	TokensByUseCase   *LabeledCounter
+
+	// Style preset metrics
+	RequestsByStyle *LabeledCounter
+
+	// Output filter (DLP) metrics
+	OutputFilterHits *LabeledCounter
+
+	// Fallback chain metrics
+	FallbackRequests *LabeledCounter
+	// OverflowRequests counts requests over a model's outbound quota served
+	// by its overflow model
+	OverflowRequests *LabeledCounter
+
+	// Standby provider metrics
+	StandbyActivations *LabeledCounter
+	StandbyRequests    *LabeledCounter
+
+	// Provider region metrics
+	RegionRequests    *LabeledCounter
+	RegionFailovers   *LabeledCounter
+	RegionLatencyMs   *LabeledGauge
+	RegionBreakerOpen *LabeledGauge
+
+	// Prompt secret detection metrics
+	PromptSecretDetections *LabeledCounter
+
+	// Guardrail metrics
+	GuardrailViolations *LabeledCounter
+
+	// PII masking metrics
+	PIIMasked *LabeledCounter
+
+	// Prompt lint metrics
+	PromptLintWarnings *LabeledCounter
+
+	// Request queue metrics
+	QueueDepth *LabeledGauge
+
+	// Abuse detection metrics
+	AbuseSignals *LabeledCounter
+
+	// Cost metrics
+	SpendUSD    *LabeledGauge
+	KeySpendUSD *LabeledGauge
+
+	// ConfigGeneration counts the configurations loaded, starting at 1
+	ConfigGeneration *Gauge
 }
 
 var (
@@ -280,10 +473,19 @@ func NewMetrics(config MetricsConfig) *Metrics {
 		ProviderRequestsTotal:   NewLabeledCounter(),
 		ProviderRequestDuration: NewLabeledHistogram(buckets),
 		ProviderErrors:          NewLabeledCounter(),
+		ProviderKeyRequests:     NewLabeledCounter(),
 
 		// Circuit breaker metrics
-		CircuitBreakerState: NewLabeledCounter(),
-		CircuitBreakerOpen:  NewLabeledCounter(),
+		CircuitBreakerState:             NewLabeledCounter(),
+		CircuitBreakerOpen:              NewLabeledCounter(),
+		CircuitBreakerWindowRequests:    NewLabeledGauge(),
+		CircuitBreakerWindowFailureRate: NewLabeledGauge(),
+
+		// Retry metrics
+		RetriesSuppressed: NewLabeledCounter(),
+
+		// Upstream schema metrics
+		UpstreamUnknownFields: NewLabeledCounter(),
 
 		// Rate limiter metrics
 		RateLimitedRequests: NewLabeledCounter(),
@@ -292,11 +494,69 @@ func NewMetrics(config MetricsConfig) *Metrics {
 		CacheHits:   NewLabeledCounter(),
 		CacheMisses: NewLabeledCounter(),
 
+		// Degraded mode metrics
+		DegradedRequests: NewLabeledCounter(),
+
 		// Token metrics
 		TokensPrompt:     NewLabeledCounter(),
 		TokensCompletion: NewLabeledCounter(),
 		TokensTotal:      NewLabeledCounter(),
+
+		// Language metrics
+		RequestsByLanguage:  NewLabeledCounter(),
+		LanguageEnforcement: NewLabeledCounter(),
+		Translations:        NewLabeledCounter(),
+
+		// Use case metrics
+		RequestsByUseCase: NewLabeledCounter(),
+		TokensByUseCase:   NewLabeledCounter(),
+
+		// Style metrics
+		RequestsByStyle: NewLabeledCounter(),
+
+		// Output filter metrics
+		OutputFilterHits: NewLabeledCounter(),
+
+		// Fallback metrics
+		FallbackRequests: NewLabeledCounter(),
+		OverflowRequests: NewLabeledCounter(),
+
+		// Standby metrics
+		StandbyActivations: NewLabeledCounter(),
+		StandbyRequests:    NewLabeledCounter(),
+
+		// Region metrics
+		RegionRequests:    NewLabeledCounter(),
+		RegionFailovers:   NewLabeledCounter(),
+		RegionLatencyMs:   NewLabeledGauge(),
+		RegionBreakerOpen: NewLabeledGauge(),
+
+		// Prompt secret detection metrics
+		PromptSecretDetections: NewLabeledCounter(),
+
+		// Guardrail metrics
+		GuardrailViolations: NewLabeledCounter(),
+
+		// PII masking metrics
+		PIIMasked: NewLabeledCounter(),
+
+		// Prompt lint metrics
+		PromptLintWarnings: NewLabeledCounter(),
+
+		// Request queue metrics
+		QueueDepth: NewLabeledGauge(),
+
+		// Abuse detection metrics
+		AbuseSignals: NewLabeledCounter(),
+
+		// Cost metrics
+		SpendUSD:    NewLabeledGauge(),
+		KeySpendUSD: NewLabeledGauge(),
+
+		ConfigGeneration: &Gauge{},
 	}
+	m.registry = prometheus.NewRegistry()
+	m.registry.MustRegister(metricsCollector{m})
 
 	log.Info().
 		Str("namespace", config.Namespace).
@@ -324,6 +584,11 @@ func GetMetrics() *Metrics {
 
 // RecordRequest records an HTTP request
 func (m *Metrics) RecordRequest(method, path string, statusCode int, duration time.Duration, responseSize int64) {
+	m.RecordRequestWithTrace(method, path, statusCode, duration, responseSize, "")
+}
+
+// RecordRequestWithTrace records an HTTP request, attaching traceID as a duration exemplar
+func (m *Metrics) RecordRequestWithTrace(method, path string, statusCode int, duration time.Duration, responseSize int64, traceID string) {
 	labels := map[string]string{
 		"method": method,
 		"path":   path,
@@ -331,12 +596,17 @@ func (m *Metrics) RecordRequest(method, path string, statusCode int, duration ti
 	}
 
 	m.RequestsTotal.WithLabels(labels).Inc()
-	m.RequestDuration.WithLabels(labels).Observe(duration.Seconds())
+	m.RequestDuration.WithLabels(labels).ObserveWithExemplar(duration.Seconds(), traceID)
 	m.ResponseSizeBytes.WithLabels(labels).Observe(float64(responseSize))
 }
 
 // RecordProviderRequest records a provider API call
 func (m *Metrics) RecordProviderRequest(provider, operation string, success bool, duration time.Duration) {
+	m.RecordProviderRequestWithTrace(provider, operation, success, duration, "")
+}
+
+// RecordProviderRequestWithTrace records a provider API call, attaching traceID as a duration exemplar
+func (m *Metrics) RecordProviderRequestWithTrace(provider, operation string, success bool, duration time.Duration, traceID string) {
 	labels := map[string]string{
 		"provider":  provider,
 		"operation": operation,
@@ -344,7 +614,7 @@ func (m *Metrics) RecordProviderRequest(provider, operation string, success bool
 	}
 
 	m.ProviderRequestsTotal.WithLabels(labels).Inc()
-	m.ProviderRequestDuration.WithLabels(labels).Observe(duration.Seconds())
+	m.ProviderRequestDuration.WithLabels(labels).ObserveWithExemplar(duration.Seconds(), traceID)
 
 	if !success {
 		m.ProviderErrors.WithLabels(map[string]string{
@@ -354,6 +624,15 @@ func (m *Metrics) RecordProviderRequest(provider, operation string, success bool
 	}
 }
 
+// RecordProviderKeyRequest records a provider call made with a specific (masked) API key
+func (m *Metrics) RecordProviderKeyRequest(provider, key string, statusCode int) {
+	m.ProviderKeyRequests.WithLabels(map[string]string{
+		"provider": provider,
+		"key":      key,
+		"status":   strconv.Itoa(statusCode),
+	}).Inc()
+}
+
 // RecordCircuitBreakerStateChange records circuit breaker state changes
 func (m *Metrics) RecordCircuitBreakerStateChange(provider, fromState, toState string) {
 	m.CircuitBreakerState.WithLabels(map[string]string{
@@ -369,6 +648,23 @@ func (m *Metrics) RecordCircuitBreakerStateChange(provider, fromState, toState s
 	}
 }
 
+// RecordCircuitBreakerWindow records the occupancy and failure rate of a
+// sliding window circuit breaker
+func (m *Metrics) RecordCircuitBreakerWindow(provider string, requests int, failureRate float64) {
+	labels := map[string]string{"provider": provider}
+	m.CircuitBreakerWindowRequests.WithLabels(labels).Set(float64(requests))
+	m.CircuitBreakerWindowFailureRate.WithLabels(labels).Set(failureRate)
+}
+
+// RecordRetrySuppressed records a retryable failure that was not retried:
+// reason is budget, concurrency or retry_after
+func (m *Metrics) RecordRetrySuppressed(provider, reason string) {
+	m.RetriesSuppressed.WithLabels(map[string]string{
+		"provider": provider,
+		"reason":   reason,
+	}).Inc()
+}
+
 // RecordRateLimited records a rate-limited request
 func (m *Metrics) RecordRateLimited(clientID string) {
 	m.RateLimitedRequests.WithLabels(map[string]string{
@@ -390,6 +686,25 @@ func (m *Metrics) RecordCacheMiss(model string) {
 	}).Inc()
 }
 
+// RecordUpstreamUnknownField records a response from provider, pinned to
+// version, carrying a field its adapter does not know
+func (m *Metrics) RecordUpstreamUnknownField(provider, version, field string) {
+	m.UpstreamUnknownFields.WithLabels(map[string]string{
+		"provider": provider,
+		"version":  version,
+		"field":    field,
+	}).Inc()
+}
+
+// RecordDegradedRequest records a request answered in degraded mode: outcome
+// is stale_hit, cache_miss or uncacheable
+func (m *Metrics) RecordDegradedRequest(mode, outcome string) {
+	m.DegradedRequests.WithLabels(map[string]string{
+		"mode":    mode,
+		"outcome": outcome,
+	}).Inc()
+}
+
 // RecordTokenUsage records token usage
 func (m *Metrics) RecordTokenUsage(provider, model string, promptTokens, completionTokens int) {
 	labels := map[string]string{
@@ -402,105 +717,261 @@ func (m *Metrics) RecordTokenUsage(provider, model string, promptTokens, complet
 	m.TokensTotal.WithLabels(labels).Add(int64(promptTokens + completionTokens))
 }
 
-// Handler returns an HTTP handler for metrics endpoint
-func (m *Metrics) Handler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
+// RecordLanguage records the detected primary language of a prompt
+func (m *Metrics) RecordLanguage(language string) {
+	m.RequestsByLanguage.WithLabels(map[string]string{
+		"language": language,
+	}).Inc()
+}
+
+// RecordLanguageEnforcement records an action taken on a response not in the
+// required language
+func (m *Metrics) RecordLanguageEnforcement(language, action string) {
+	m.LanguageEnforcement.WithLabels(map[string]string{
+		"language": language,
+		"action":   action,
+	}).Inc()
+}
 
-		// Write metrics in Prometheus exposition format
-		m.writePrometheusMetrics(w)
+// RecordTranslation records a response translation into language: translated,
+// skipped (already in the language) or failed
+func (m *Metrics) RecordTranslation(language, outcome string) {
+	m.Translations.WithLabels(map[string]string{
+		"language": language,
+		"outcome":  outcome,
+	}).Inc()
+}
+
+// RecordUseCase records a request classified as useCase and the tokens it used
+func (m *Metrics) RecordUseCase(useCase string, tokens int64) {
+	labels := map[string]string{
+		"use_case": useCase,
 	}
+	m.RequestsByUseCase.WithLabels(labels).Inc()
+	m.TokensByUseCase.WithLabels(labels).Add(tokens)
+}
+
+// RecordStyle records a request served with version of a style preset
+func (m *Metrics) RecordStyle(style string, version int) {
+	m.RequestsByStyle.WithLabels(map[string]string{
+		"style":   style,
+		"version": strconv.Itoa(version),
+	}).Inc()
+}
+
+// RecordOutputFilterHit records matches of an output filter rule in model output
+func (m *Metrics) RecordOutputFilterHit(rule, action string, hits int) {
+	m.OutputFilterHits.WithLabels(map[string]string{
+		"rule":   rule,
+		"action": action,
+	}).Add(int64(hits))
+}
+
+// RecordFallback records a request for model served by a fallback
+func (m *Metrics) RecordFallback(model, provider, fallbackModel string) {
+	m.FallbackRequests.WithLabels(map[string]string{
+		"model":          model,
+		"provider":       provider,
+		"fallback_model": fallbackModel,
+	}).Inc()
+}
+
+// RecordOverflow records a request for model, from a caller of tier, served
+// by the overflow model because model's outbound quota was exhausted
+func (m *Metrics) RecordOverflow(model, tier, provider, overflowModel string) {
+	m.OverflowRequests.WithLabels(map[string]string{
+		"model":          model,
+		"tier":           tier,
+		"provider":       provider,
+		"overflow_model": overflowModel,
+	}).Inc()
+}
+
+// RecordStandbyActivation records requests for model starting to go to a
+// standby provider because its primaries are unavailable
+func (m *Metrics) RecordStandbyActivation(provider, model string) {
+	m.StandbyActivations.WithLabels(map[string]string{"provider": provider, "model": model}).Inc()
+}
+
+// RecordStandbyRequest records a request for model routed to a standby provider
+func (m *Metrics) RecordStandbyRequest(provider, model string) {
+	m.StandbyRequests.WithLabels(map[string]string{"provider": provider, "model": model}).Inc()
+}
+
+// RecordRegionRequest records a request sent to one region of a provider
+func (m *Metrics) RecordRegionRequest(provider, region string, success bool) {
+	outcome := "success"
+	if !success {
+		outcome = "failure"
+	}
+	m.RegionRequests.WithLabels(map[string]string{"provider": provider, "region": region, "outcome": outcome}).Inc()
+}
+
+// RecordRegionFailover records a request retried in another region
+func (m *Metrics) RecordRegionFailover(provider, from, to string) {
+	m.RegionFailovers.WithLabels(map[string]string{"provider": provider, "from": from, "to": to}).Inc()
+}
+
+// RecordPromptSecrets records credentials a detector found in a prompt and
+// what was done about them (block, redact or allow)
+func (m *Metrics) RecordPromptSecrets(detector, action string, count int) {
+	m.PromptSecretDetections.WithLabels(map[string]string{
+		"detector": detector,
+		"action":   action,
+	}).Add(int64(count))
+}
+
+// RecordGuardrailViolation records a guardrail check a request or response
+// failed, by direction (input or output) and action (block or redact)
+func (m *Metrics) RecordGuardrailViolation(policy, check, direction, action string) {
+	m.GuardrailViolations.WithLabels(map[string]string{
+		"policy":    policy,
+		"check":     check,
+		"direction": direction,
+		"action":    action,
+	}).Inc()
+}
+
+// RecordPIIMasked records personal data values a detector masked in a prompt
+func (m *Metrics) RecordPIIMasked(detector string, count int) {
+	m.PIIMasked.WithLabels(map[string]string{"detector": detector}).Add(int64(count))
+}
+
+// RecordQueueDepth records the requests a tenant has waiting in the request queue
+func (m *Metrics) RecordQueueDepth(tenant string, depth int) {
+	m.QueueDepth.WithLabels(map[string]string{"tenant": tenant}).Set(float64(depth))
+}
+
+// RecordPromptLintWarning records a prompt lint warning by rule
+func (m *Metrics) RecordPromptLintWarning(rule string) {
+	m.PromptLintWarnings.WithLabels(map[string]string{"rule": rule}).Inc()
+}
+
+// RecordAbuseSignal records an abuse signal raised by an API key and whether
+// the key was restricted
+func (m *Metrics) RecordAbuseSignal(signal string, restricted bool) {
+	m.AbuseSignals.WithLabels(map[string]string{
+		"signal":     signal,
+		"restricted": strconv.FormatBool(restricted),
+	}).Inc()
 }
 
-func (m *Metrics) writePrometheusMetrics(w http.ResponseWriter) {
+// RecordSpend records the cost in USD of a request to provider and model
+// made with an API key
+func (m *Metrics) RecordSpend(provider, model, key string, usd float64) {
+	m.SpendUSD.WithLabels(map[string]string{
+		"provider": provider,
+		"model":    model,
+	}).Add(usd)
+	m.KeySpendUSD.WithLabels(map[string]string{
+		"key": key,
+	}).Add(usd)
+}
+
+// Handler returns an HTTP handler for metrics endpoint. Scrapers asking for
+// OpenMetrics get it, with the trace exemplars of duration histograms;
+// others get the Prometheus text format.
+func (m *Metrics) Handler() http.HandlerFunc {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP
+}
+
+// collect sends every metric family to a registry scrape
+func (m *Metrics) collect(e *exposition) {
 	ns := m.config.Namespace
 	ss := m.config.Subsystem
 
 	// HTTP Request metrics
-	w.Write([]byte("# HELP " + ns + "_" + ss + "_requests_total Total number of HTTP requests\n"))
-	w.Write([]byte("# TYPE " + ns + "_" + ss + "_requests_total counter\n"))
-	for key, counter := range m.RequestsTotal.All() {
-		w.Write([]byte(ns + "_" + ss + "_requests_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
-
-	w.Write([]byte("\n# HELP " + ns + "_" + ss + "_request_duration_seconds HTTP request duration in seconds\n"))
-	w.Write([]byte("# TYPE " + ns + "_" + ss + "_request_duration_seconds histogram\n"))
-	for key, hist := range m.RequestDuration.All() {
-		buckets, counts, sum, count := hist.Values()
-		cumulative := int64(0)
-		for i, bucket := range buckets {
-			cumulative += counts[i]
-			w.Write([]byte(ns + "_" + ss + "_request_duration_seconds_bucket{" + key + "le=\"" + strconv.FormatFloat(bucket, 'f', 3, 64) + "\"} " + strconv.FormatInt(cumulative, 10) + "\n"))
-		}
-		cumulative += counts[len(buckets)]
-		w.Write([]byte(ns + "_" + ss + "_request_duration_seconds_bucket{" + key + "le=\"+Inf\"} " + strconv.FormatInt(cumulative, 10) + "\n"))
-		w.Write([]byte(ns + "_" + ss + "_request_duration_seconds_sum{" + key + "} " + strconv.FormatFloat(sum, 'f', 6, 64) + "\n"))
-		w.Write([]byte(ns + "_" + ss + "_request_duration_seconds_count{" + key + "} " + strconv.FormatInt(count, 10) + "\n"))
-	}
+	e.counters(ns+"_"+ss+"_requests_total", "Total number of HTTP requests", m.RequestsTotal)
+	e.histograms(ns+"_"+ss+"_request_duration_seconds", "HTTP request duration in seconds", m.RequestDuration)
+	e.histograms(ns+"_"+ss+"_response_size_bytes", "HTTP response size in bytes", m.ResponseSizeBytes)
 
-	w.Write([]byte("\n# HELP " + ns + "_" + ss + "_requests_in_flight Current number of requests in flight\n"))
-	w.Write([]byte("# TYPE " + ns + "_" + ss + "_requests_in_flight gauge\n"))
-	w.Write([]byte(ns + "_" + ss + "_requests_in_flight " + strconv.FormatFloat(m.RequestsInFlight.Value(), 'f', 0, 64) + "\n"))
+	e.gauge(ns+"_"+ss+"_requests_in_flight", "Current number of requests in flight", m.RequestsInFlight)
 
 	// Provider metrics
-	w.Write([]byte("\n# HELP " + ns + "_provider_requests_total Total number of provider API requests\n"))
-	w.Write([]byte("# TYPE " + ns + "_provider_requests_total counter\n"))
-	for key, counter := range m.ProviderRequestsTotal.All() {
-		w.Write([]byte(ns + "_provider_requests_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_provider_requests_total", "Total number of provider API requests", m.ProviderRequestsTotal)
+	e.histograms(ns+"_provider_request_duration_seconds", "Provider API request duration in seconds", m.ProviderRequestDuration)
+	e.counters(ns+"_provider_errors_total", "Total number of provider errors", m.ProviderErrors)
 
-	w.Write([]byte("\n# HELP " + ns + "_provider_errors_total Total number of provider errors\n"))
-	w.Write([]byte("# TYPE " + ns + "_provider_errors_total counter\n"))
-	for key, counter := range m.ProviderErrors.All() {
-		w.Write([]byte(ns + "_provider_errors_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_provider_key_requests_total", "Total number of provider API requests per API key", m.ProviderKeyRequests)
 
 	// Circuit breaker metrics
-	w.Write([]byte("\n# HELP " + ns + "_circuit_breaker_state_changes_total Circuit breaker state changes\n"))
-	w.Write([]byte("# TYPE " + ns + "_circuit_breaker_state_changes_total counter\n"))
-	for key, counter := range m.CircuitBreakerState.All() {
-		w.Write([]byte(ns + "_circuit_breaker_state_changes_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_circuit_breaker_state_changes_total", "Circuit breaker state changes", m.CircuitBreakerState)
+	e.gauges(ns+"_circuit_breaker_window_requests", prometheus.GaugeValue, "Requests in the sliding window of a circuit breaker", m.CircuitBreakerWindowRequests)
+	e.gauges(ns+"_circuit_breaker_failure_rate", prometheus.GaugeValue, "Failure percentage over the sliding window of a circuit breaker", m.CircuitBreakerWindowFailureRate)
+
+	// Retry metrics
+	e.counters(ns+"_retries_suppressed_total", "Retryable failures not retried, by reason", m.RetriesSuppressed)
+
+	// Upstream schema metrics
+	e.counters(ns+"_upstream_unknown_fields_total", "Upstream response fields unknown to the provider adapter", m.UpstreamUnknownFields)
 
 	// Rate limiter metrics
-	w.Write([]byte("\n# HELP " + ns + "_rate_limited_requests_total Total number of rate-limited requests\n"))
-	w.Write([]byte("# TYPE " + ns + "_rate_limited_requests_total counter\n"))
-	for key, counter := range m.RateLimitedRequests.All() {
-		w.Write([]byte(ns + "_rate_limited_requests_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_rate_limited_requests_total", "Total number of rate-limited requests", m.RateLimitedRequests)
 
 	// Cache metrics
-	w.Write([]byte("\n# HELP " + ns + "_cache_hits_total Cache hits\n"))
-	w.Write([]byte("# TYPE " + ns + "_cache_hits_total counter\n"))
-	for key, counter := range m.CacheHits.All() {
-		w.Write([]byte(ns + "_cache_hits_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
-
-	w.Write([]byte("\n# HELP " + ns + "_cache_misses_total Cache misses\n"))
-	w.Write([]byte("# TYPE " + ns + "_cache_misses_total counter\n"))
-	for key, counter := range m.CacheMisses.All() {
-		w.Write([]byte(ns + "_cache_misses_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_cache_hits_total", "Cache hits", m.CacheHits)
+	e.counters(ns+"_cache_misses_total", "Cache misses", m.CacheMisses)
+	e.counters(ns+"_degraded_requests_total", "Requests answered in degraded mode", m.DegradedRequests)
 
 	// Token usage metrics
-	w.Write([]byte("\n# HELP " + ns + "_tokens_prompt_total Total prompt tokens used\n"))
-	w.Write([]byte("# TYPE " + ns + "_tokens_prompt_total counter\n"))
-	for key, counter := range m.TokensPrompt.All() {
-		w.Write([]byte(ns + "_tokens_prompt_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_tokens_prompt_total", "Total prompt tokens used", m.TokensPrompt)
+	e.counters(ns+"_tokens_completion_total", "Total completion tokens used", m.TokensCompletion)
 
-	w.Write([]byte("\n# HELP " + ns + "_tokens_completion_total Total completion tokens used\n"))
-	w.Write([]byte("# TYPE " + ns + "_tokens_completion_total counter\n"))
-	for key, counter := range m.TokensCompletion.All() {
-		w.Write([]byte(ns + "_tokens_completion_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	e.counters(ns+"_tokens_total", "Total tokens used", m.TokensTotal)
 
-	w.Write([]byte("\n# HELP " + ns + "_tokens_total Total tokens used\n"))
-	w.Write([]byte("# TYPE " + ns + "_tokens_total counter\n"))
-	for key, counter := range m.TokensTotal.All() {
-		w.Write([]byte(ns + "_tokens_total{" + key + "} " + strconv.FormatInt(counter.Value(), 10) + "\n"))
-	}
+	// Language metrics
+	e.counters(ns+"_requests_by_language_total", "Requests by detected prompt language", m.RequestsByLanguage)
+	e.counters(ns+"_language_enforcement_total", "Responses not in the required language by action", m.LanguageEnforcement)
+	e.counters(ns+"_translations_total", "Responses translated on request by language and outcome", m.Translations)
+
+	// Use case metrics
+	e.counters(ns+"_requests_by_use_case_total", "Requests by classified use case", m.RequestsByUseCase)
+	e.counters(ns+"_tokens_by_use_case_total", "Tokens used by classified use case", m.TokensByUseCase)
+
+	// Style metrics
+	e.counters(ns+"_requests_by_style_total", "Requests by style preset and version", m.RequestsByStyle)
+
+	// Output filter metrics
+	e.counters(ns+"_output_filter_hits_total", "Matches of output filter rules in model output", m.OutputFilterHits)
+
+	// Fallback metrics
+	e.counters(ns+"_fallback_requests_total", "Requests served by a fallback provider or model", m.FallbackRequests)
+	e.counters(ns+"_overflow_requests_total", "Requests over a model's outbound quota served by its overflow model", m.OverflowRequests)
+
+	// Standby metrics
+	e.counters(ns+"_standby_activations_total", "Times a model started routing to a standby provider", m.StandbyActivations)
+	e.counters(ns+"_standby_requests_total", "Requests routed to a standby provider", m.StandbyRequests)
+
+	// Region metrics
+	e.counters(ns+"_provider_region_requests_total", "Requests by provider region and outcome", m.RegionRequests)
+	e.counters(ns+"_provider_region_failovers_total", "Requests retried in another provider region", m.RegionFailovers)
+	e.gauges(ns+"_provider_region_latency_ms", prometheus.GaugeValue, "Moving average of the time to response headers by provider region", m.RegionLatencyMs)
+	e.gauges(ns+"_provider_region_breaker_open", prometheus.GaugeValue, "Whether a provider region's breaker is open (1) or not (0)", m.RegionBreakerOpen)
+
+	// Prompt secret detection metrics
+	e.counters(ns+"_prompt_secrets_detected_total", "Credentials detected in prompts", m.PromptSecretDetections)
+
+	// Guardrail metrics
+	e.counters(ns+"_guardrail_violations_total", "Guardrail checks failed by prompts and completions", m.GuardrailViolations)
+
+	// PII masking metrics
+	e.counters(ns+"_pii_masked_total", "Personal data values masked in prompts", m.PIIMasked)
+
+	// Prompt lint metrics
+	e.counters(ns+"_prompt_lint_warnings_total", "Prompt lint warnings by rule", m.PromptLintWarnings)
+
+	// Request queue metrics
+	e.gauges(ns+"_queue_depth", prometheus.GaugeValue, "Requests waiting in the request queue by tenant", m.QueueDepth)
+
+	// Abuse detection metrics
+	e.counters(ns+"_abuse_signals_total", "Abuse signals raised by API keys", m.AbuseSignals)
+
+	// Cost metrics
+	e.gauges(ns+"_spend_usd_total", prometheus.CounterValue, "Spend in USD by provider and model", m.SpendUSD)
+	e.gauges(ns+"_key_spend_usd_total", prometheus.CounterValue, "Spend in USD by API key", m.KeySpendUSD)
+
+	// Config reload metrics
+	e.gauge(ns+"_config_generation", "Generation of the configuration in use, incremented by each applied reload", m.ConfigGeneration)
 }
 
 // GetStats returns metrics as a map for JSON endpoints
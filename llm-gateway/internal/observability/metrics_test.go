@@ -0,0 +1,101 @@
+package observability
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram_ObserveWithExemplar(t *testing.T) {
+	h := NewHistogram([]float64{0.1, 1})
+
+	h.ObserveWithExemplar(0.05, "trace-a")
+	h.ObserveWithExemplar(0.5, "")
+	h.ObserveWithExemplar(5, "trace-b")
+
+	exemplars := h.Exemplars()
+	if exemplars[0] == nil || exemplars[0].TraceID != "trace-a" {
+		t.Errorf("bucket 0 exemplar = %+v, want trace-a", exemplars[0])
+	}
+	if exemplars[1] != nil {
+		t.Errorf("bucket 1 should have no exemplar, got %+v", exemplars[1])
+	}
+	if exemplars[2] == nil || exemplars[2].TraceID != "trace-b" {
+		t.Errorf("+Inf exemplar = %+v, want trace-b", exemplars[2])
+	}
+
+	_, counts, _, count := h.Values()
+	if count != 3 || counts[0] != 1 || counts[1] != 1 || counts[2] != 1 {
+		t.Errorf("counts = %v (total %d), want one per bucket", counts, count)
+	}
+}
+
+func TestMetricsHandler_Exemplars(t *testing.T) {
+	m := NewMetrics(DefaultMetricsConfig())
+	m.RecordRequestWithTrace("POST", "/v1/chat/completions", 200, 20*time.Millisecond, 10, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	// Plain Prometheus text format has no exemplars
+	rr := httptest.NewRecorder()
+	m.Handler()(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rr.Body.String(), "trace_id") {
+		t.Error("exemplars should only be written for OpenMetrics scrapes")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rr = httptest.NewRecorder()
+	m.Handler()(rr, req)
+
+	body := rr.Body.String()
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Errorf("Content-Type = %s, want OpenMetrics", rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, `le="0.025"} 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.02 `) {
+		t.Errorf("duration bucket should carry the trace exemplar:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("OpenMetrics output must end with # EOF")
+	}
+}
+
+func TestMetricsHandler_Exposition(t *testing.T) {
+	m := NewMetrics(DefaultMetricsConfig())
+	m.RecordRequest("GET", `/v1/"odd"\path`, 200, 20*time.Millisecond, 10)
+	m.RecordCacheHit("gpt-4o")
+	m.RecordProviderRequestWithTrace("openai", "ChatCompletion", true, 2*time.Second, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	rr := httptest.NewRecorder()
+	m.Handler()(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`llm_gateway_http_requests_total{method="GET",path="/v1/\"odd\"\\path",status="200"} 1`,
+		`llm_gateway_http_request_duration_seconds_bucket{method="GET",path="/v1/\"odd\"\\path",status="200",le="0.025"} 1`,
+		`llm_gateway_http_request_duration_seconds_bucket{method="GET",path="/v1/\"odd\"\\path",status="200",le="+Inf"} 1`,
+		`llm_gateway_http_request_duration_seconds_count{method="GET",path="/v1/\"odd\"\\path",status="200"} 1`,
+		`llm_gateway_cache_hits_total{model="gpt-4o"} 1`,
+		`llm_gateway_provider_request_duration_seconds_bucket{operation="ChatCompletion",provider="openai",success="true",le="2.5"} 1`,
+		"# TYPE llm_gateway_cache_hits_total counter",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, ",}") || strings.Contains(body, "\n\n") {
+		t.Error("labels must not end with a comma, and families must not be separated by blank lines")
+	}
+
+	// OpenMetrics names counter families without _total and carries exemplars
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rr = httptest.NewRecorder()
+	m.Handler()(rr, req)
+	body = rr.Body.String()
+	if !strings.Contains(body, "# TYPE llm_gateway_cache_hits counter\n") {
+		t.Error("OpenMetrics counter family should drop the _total suffix")
+	}
+	if !strings.Contains(body, `le="2.5"} 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 2.0 `) {
+		t.Errorf("provider duration bucket should carry the trace exemplar:\n%s", body)
+	}
+}
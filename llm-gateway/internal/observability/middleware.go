@@ -48,12 +48,14 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
-// Hijack implements http.Hijacker
+// Hijack implements http.Hijacker, also through writers that only Unwrap
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	if hijacker, ok := rw.ResponseWriter.(http.Hijacker); ok {
-		return hijacker.Hijack()
-	}
-	return nil, nil, http.ErrNotSupported
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
 // TracingMiddleware adds distributed tracing to requests
@@ -121,7 +123,7 @@ func MetricsMiddleware(metrics *Metrics) func(http.Handler) http.Handler {
 
 			// Record metrics
 			duration := time.Since(start)
-			metrics.RecordRequest(r.Method, r.URL.Path, rw.status, duration, rw.size)
+			metrics.RecordRequestWithTrace(r.Method, r.URL.Path, rw.status, duration, rw.size, SampledTraceID(r.Context()))
 		})
 	}
 }
@@ -0,0 +1,368 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// otlpBatchSize is the most spans sent in one export request
+	otlpBatchSize = 512
+	// otlpQueueSize is the most spans waiting for export; spans beyond it are dropped
+	otlpQueueSize = 4096
+	// otlpFlushInterval is how often queued spans are sent when no batch fills up
+	otlpFlushInterval = 5 * time.Second
+	// otlpMaxAttempts is how often an export request is tried before its spans are dropped
+	otlpMaxAttempts = 4
+	// otlpRetryBackoff is the delay before the first retry, doubled for each retry
+	otlpRetryBackoff = 250 * time.Millisecond
+)
+
+// OTLPExporter sends spans to an OpenTelemetry collector, Tempo or Jaeger
+// over OTLP/HTTP with the JSON encoding. Spans are queued and sent in
+// batches from a background goroutine; failed requests are retried with
+// backoff on network errors, 429 and 5xx responses.
+type OTLPExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	queue   chan *Span
+	flush   chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closing sync.Once
+
+	mu      sync.Mutex
+	dropped int64
+}
+
+// NewOTLPExporter creates an exporter sending to address, a collector base
+// URL such as http://tempo:4318 (/v1/traces is appended when no path is
+// given) or the full traces URL, and starts its export loop
+func NewOTLPExporter(address string, headers map[string]string, serviceName string) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint:    otlpEndpoint(address),
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, otlpQueueSize),
+		flush:       make(chan chan struct{}),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+// otlpEndpoint returns the traces URL for an exporter address
+func otlpEndpoint(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	if u, err := url.Parse(address); err == nil && (u.Path == "" || u.Path == "/") {
+		return strings.TrimSuffix(address, "/") + "/v1/traces"
+	}
+	return address
+}
+
+// Export queues spans for export, dropping them if the queue is full
+func (e *OTLPExporter) Export(spans []*Span) error {
+	for _, span := range spans {
+		select {
+		case <-e.stop:
+			return fmt.Errorf("otlp exporter is shut down")
+		default:
+		}
+		select {
+		case e.queue <- span:
+		default:
+			e.drop(1, "export queue is full")
+		}
+	}
+	return nil
+}
+
+// Shutdown sends the queued spans and stops the export loop, giving up
+// when ctx is done
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.closing.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of spans dropped because the queue was full
+// or the collector kept failing
+func (e *OTLPExporter) Dropped() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+func (e *OTLPExporter) drop(n int, reason string) {
+	e.mu.Lock()
+	e.dropped += int64(n)
+	e.mu.Unlock()
+	log.Warn().Int("spans", n).Str("endpoint", e.endpoint).Str("reason", reason).Msg("Dropped trace spans")
+}
+
+// sync waits until the spans queued so far have been sent
+func (e *OTLPExporter) sync() {
+	ack := make(chan struct{})
+	select {
+	case e.flush <- ack:
+		<-ack
+	case <-e.done:
+	}
+}
+
+func (e *OTLPExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, otlpBatchSize)
+	send := func() {
+		if len(batch) > 0 {
+			e.send(batch)
+			batch = make([]*Span, 0, otlpBatchSize)
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+				if len(batch) == otlpBatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) == otlpBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-e.flush:
+			drain()
+			close(ack)
+		case <-e.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// send exports a batch, retrying with backoff; spans are dropped if every
+// attempt fails or the collector rejects them
+func (e *OTLPExporter) send(spans []*Span) {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		e.drop(len(spans), err.Error())
+		return
+	}
+
+	backoff := otlpRetryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := e.post(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == otlpMaxAttempts {
+			e.drop(len(spans), err.Error())
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-e.stop:
+			// Shutting down: one last attempt without waiting
+			if _, err := e.post(body); err != nil {
+				e.drop(len(spans), err.Error())
+			}
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one export request, reporting whether a failure is worth retrying
+func (e *OTLPExporter) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("collector returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("collector rejected spans: %s", resp.Status)
+	}
+}
+
+// OTLP/JSON message types, as in ExportTraceServiceRequest
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// OTLP span kinds
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+)
+
+// request converts spans to an export request
+func (e *OTLPExporter) request(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           span.Context.TraceID,
+			SpanID:            span.Context.SpanID,
+			ParentSpanID:      span.Context.ParentID,
+			Name:              span.Name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: otlpTime(span.StartTime),
+			EndTimeUnixNano:   otlpTime(span.EndTime),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: int(span.Status.Code), Message: span.Status.Message},
+		}
+		// Spans started from incoming requests carry http.method
+		if _, ok := span.Attributes["http.method"]; ok {
+			s.Kind = otlpKindServer
+		}
+		for _, event := range span.Events {
+			s.Events = append(s.Events, otlpEvent{
+				TimeUnixNano: otlpTime(event.Timestamp),
+				Name:         event.Name,
+				Attributes:   otlpAttributes(event.Attributes),
+			})
+		}
+		span.mu.Unlock()
+		out = append(out, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": e.serviceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "llm-gateway"}, Spans: out}},
+	}}}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpAttributes converts attributes; values other than strings, booleans
+// and numbers are sent as their string form
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, key := range sortedKeys(attrs) {
+		var v otlpAnyValue
+		switch value := attrs[key].(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			s := fmt.Sprint(value)
+			v.IntValue = &s
+		case float32:
+			f := float64(value)
+			v.DoubleValue = &f
+		case float64:
+			v.DoubleValue = &value
+		case time.Duration:
+			s := value.String()
+			v.StringValue = &s
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: key, Value: v})
+	}
+	return out
+}
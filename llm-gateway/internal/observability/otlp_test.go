@@ -0,0 +1,126 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter_ExportsWithRetry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []otlpRequest
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("request %s %v", r.URL.Path, r.Header)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req otlpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = append(received, req)
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(TracingConfig{
+		Enabled:         true,
+		ServiceName:     "gateway-test",
+		SamplingRate:    1,
+		ExporterType:    "otlp",
+		ExporterAddress: collector.URL,
+		ExporterHeaders: map[string]string{"Authorization": "Bearer t"},
+	})
+	ctx, parent := tracer.StartSpan(context.Background(), "parent")
+	_, child := tracer.StartSpan(ctx, "child")
+	child.SetAttribute("tokens", 42)
+	child.SetStatus(StatusError, "upstream failed")
+	child.AddEvent("retry", map[string]interface{}{"attempt": 1})
+	child.End()
+	parent.End()
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 || len(received) != 1 {
+		t.Fatalf("attempts = %d, batches = %d, want a retry after the 503", attempts, len(received))
+	}
+	rs := received[0].ResourceSpans[0]
+	if v := rs.Resource.Attributes[0]; v.Key != "service.name" || *v.Value.StringValue != "gateway-test" {
+		t.Errorf("resource = %+v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[0].Name != "child" || spans[0].ParentSpanID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID {
+		t.Fatalf("spans = %+v", spans)
+	}
+	if spans[0].Status.Code != 2 || len(spans[0].Events) != 1 || spans[0].StartTimeUnixNano == "" {
+		t.Errorf("child = %+v", spans[0])
+	}
+	found := false
+	for _, kv := range spans[0].Attributes {
+		if kv.Key == "tokens" && kv.Value.IntValue != nil && *kv.Value.IntValue == "42" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("tokens attribute missing: %+v", spans[0].Attributes)
+	}
+}
+
+func TestOTLPExporter_ShutdownDrainsQueue(t *testing.T) {
+	var count int
+	var mu sync.Mutex
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		count += len(req.ResourceSpans[0].ScopeSpans[0].Spans)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	e := NewOTLPExporter(collector.URL+"/", nil, "gateway-test")
+	spans := make([]*Span, 3)
+	for i := range spans {
+		spans[i] = &Span{Name: "s", Context: SpanContext{TraceID: generateTraceID(), SpanID: generateSpanID()}, StartTime: time.Now(), EndTime: time.Now()}
+	}
+	e.Export(spans)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 3 {
+		t.Errorf("exported %d spans, want 3", count)
+	}
+	if err := e.Export(spans); err == nil {
+		t.Error("Export after Shutdown should fail")
+	}
+}
+
+func TestOTLPEndpoint(t *testing.T) {
+	for address, want := range map[string]string{
+		"http://tempo:4318":             "http://tempo:4318/v1/traces",
+		"collector:4318":                "http://collector:4318/v1/traces",
+		"https://otlp.example.com/otlp": "https://otlp.example.com/otlp",
+	} {
+		if got := otlpEndpoint(address); got != want {
+			t.Errorf("otlpEndpoint(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
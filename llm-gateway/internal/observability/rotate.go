@@ -0,0 +1,235 @@
+package observability
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp suffix appended to rotated files
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateConfig holds configuration for a rotating log file
+type RotateConfig struct {
+	Path       string
+	MaxSize    int64         // Rotate when the file exceeds this many bytes (0 = no size limit)
+	Interval   time.Duration // Rotate when the file is older than this (0 = no time limit)
+	MaxBackups int           // Rotated files to keep (0 = keep all)
+	Compress   bool          // Gzip rotated files in the background
+}
+
+// RotatingFile is an io.WriteCloser that rotates the underlying file by size and age
+type RotatingFile struct {
+	mu       sync.Mutex
+	config   RotateConfig
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// Compression and pruning of rotated files runs off the write path
+	postMu sync.Mutex
+	postWg sync.WaitGroup
+}
+
+// NewRotatingFile opens (or creates) the file at config.Path for appending
+func NewRotatingFile(config RotateConfig) (*RotatingFile, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("rotating file path is required")
+	}
+
+	rf := &RotatingFile{config: config}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	if dir := filepath.Dir(rf.config.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(rf.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.openedAt = time.Now()
+	return nil
+}
+
+// Write writes p to the file, rotating first if the size or age limit would be exceeded
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) shouldRotate(incoming int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.config.MaxSize > 0 && rf.size+incoming > rf.config.MaxSize {
+		return true
+	}
+	if rf.config.Interval > 0 && time.Since(rf.openedAt) >= rf.config.Interval {
+		return true
+	}
+	return false
+}
+
+// Rotate closes the current file, renames it with a timestamp suffix and opens a new one
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+func (rf *RotatingFile) rotate() error {
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		rf.file = nil
+	}
+
+	backup := rf.config.Path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(rf.config.Path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	if err := rf.open(); err != nil {
+		return err
+	}
+
+	if !rf.config.Compress {
+		rf.pruneBackups()
+		return nil
+	}
+
+	rf.postWg.Add(1)
+	go func() {
+		defer rf.postWg.Done()
+		rf.postMu.Lock()
+		defer rf.postMu.Unlock()
+
+		if err := compressFile(backup); err != nil {
+			// The rotating file is usually the logger's own output, so report on stderr
+			fmt.Fprintf(os.Stderr, "failed to compress rotated log %s: %v\n", backup, err)
+		}
+		rf.pruneBackups()
+	}()
+	return nil
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Write to a temporary name so a partial archive is never mistaken for a backup
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// backups returns rotated files for this path, oldest first
+func (rf *RotatingFile) backups() []string {
+	matches, err := filepath.Glob(rf.config.Path + ".*")
+	if err != nil {
+		return nil
+	}
+
+	prefix := rf.config.Path + "."
+	backups := matches[:0]
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, prefix), ".gz")
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups
+}
+
+func (rf *RotatingFile) pruneBackups() {
+	if rf.config.MaxBackups <= 0 {
+		return
+	}
+
+	backups := rf.backups()
+	for len(backups) > rf.config.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Close closes the underlying file and waits for pending compression
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.postWg.Wait()
+	return err
+}
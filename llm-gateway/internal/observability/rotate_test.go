@@ -0,0 +1,91 @@
+package observability
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile_RotatesBySizeAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := NewRotatingFile(RotateConfig{Path: path, MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	defer rf.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := rf.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		// Backup names have millisecond resolution
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if backups := rf.backups(); len(backups) != 2 {
+		t.Errorf("backups = %v, want 2 kept", backups)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("current file missing: %v", err)
+	}
+	if info.Size() != 10 {
+		t.Errorf("current file size = %d, want 10", info.Size())
+	}
+}
+
+func TestRotatingFile_RotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := NewRotatingFile(RotateConfig{Path: path, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+	defer rf.Close()
+
+	rf.Write([]byte("first\n"))
+	time.Sleep(5 * time.Millisecond)
+	rf.Write([]byte("second\n"))
+
+	if backups := rf.backups(); len(backups) != 1 {
+		t.Fatalf("backups = %v, want 1", backups)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "second\n" {
+		t.Errorf("current file = %q, want only the post-rotation write", data)
+	}
+}
+
+func TestRotatingFile_CompressesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.log")
+	rf, err := NewRotatingFile(RotateConfig{Path: path, MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatalf("NewRotatingFile() error = %v", err)
+	}
+
+	rf.Write([]byte("0123456789"))
+	rf.Write([]byte("abcdefghij"))
+	rf.Close() // waits for background compression
+
+	backups := rf.backups()
+	if len(backups) != 1 || filepath.Ext(backups[0]) != ".gz" {
+		t.Fatalf("backups = %v, want one .gz file", backups)
+	}
+
+	f, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("backup is not gzip: %v", err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != "0123456789" {
+		t.Errorf("decompressed backup = %q, want the rotated content", data)
+	}
+}
@@ -0,0 +1,211 @@
+package observability
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeSeriesResolutions are the bucket widths time series are kept at
+var TimeSeriesResolutions = []time.Duration{time.Second, 10 * time.Second}
+
+// seriesBucket aggregates what happened in one bucket of a ring
+type seriesBucket struct {
+	// slot is the bucket's start in units of the ring's step since the epoch
+	slot       int64
+	requests   int64
+	errors     int64
+	ttftSum    time.Duration
+	ttftCount  int64
+	queueDepth float64
+	sampled    bool
+}
+
+// seriesRing is a ring buffer of buckets of one resolution
+type seriesRing struct {
+	step    time.Duration
+	buckets []seriesBucket
+}
+
+// at returns the bucket for t, clearing it if it last held an older slot
+func (r *seriesRing) at(t time.Time) *seriesBucket {
+	slot := t.UnixNano() / int64(r.step)
+	b := &r.buckets[slot%int64(len(r.buckets))]
+	if b.slot != slot {
+		*b = seriesBucket{slot: slot}
+	}
+	return b
+}
+
+// TimeSeriesPoint is one bucket of a time series. TTFTMs is nil for buckets
+// without streams.
+type TimeSeriesPoint struct {
+	Time       time.Time `json:"time"`
+	RPS        float64   `json:"rps"`
+	ErrorRate  float64   `json:"error_rate"`
+	TTFTMs     *float64  `json:"ttft_ms"`
+	QueueDepth float64   `json:"queue_depth"`
+}
+
+// TimeSeries keeps short-term trends of key gateway metrics in memory:
+// request rate, 5xx error rate, stream time to first token and the peak
+// number of requests waiting on providers, at 1s and 10s resolution. Its
+// methods do nothing on a nil TimeSeries.
+type TimeSeries struct {
+	mu        sync.Mutex
+	retention time.Duration
+	rings     []*seriesRing
+	started   sync.Once
+}
+
+// NewTimeSeries creates time series keeping the last retention of data
+func NewTimeSeries(retention time.Duration) *TimeSeries {
+	ts := &TimeSeries{retention: retention}
+	for _, step := range TimeSeriesResolutions {
+		// One extra bucket holds the bucket in progress
+		n := int(retention/step) + 1
+		ts.rings = append(ts.rings, &seriesRing{step: step, buckets: make([]seriesBucket, n)})
+	}
+	return ts
+}
+
+var (
+	globalTimeSeries *TimeSeries
+	timeSeriesOnce   sync.Once
+)
+
+// InitGlobalTimeSeries initializes the global time series instance
+func InitGlobalTimeSeries(retention time.Duration) *TimeSeries {
+	timeSeriesOnce.Do(func() {
+		globalTimeSeries = NewTimeSeries(retention)
+	})
+	return globalTimeSeries
+}
+
+// GetTimeSeries returns the global time series, or nil if they are not enabled
+func GetTimeSeries() *TimeSeries {
+	return globalTimeSeries
+}
+
+// RecordRequest counts a request that finished with status at now
+func (ts *TimeSeries) RecordRequest(status int, now time.Time) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, ring := range ts.rings {
+		b := ring.at(now)
+		b.requests++
+		if status >= 500 {
+			b.errors++
+		}
+	}
+}
+
+// RecordTTFT records a stream's time to first token, seen at now
+func (ts *TimeSeries) RecordTTFT(ttft time.Duration, now time.Time) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, ring := range ts.rings {
+		b := ring.at(now)
+		b.ttftSum += ttft
+		b.ttftCount++
+	}
+}
+
+// RecordQueueDepth records a sample of the requests waiting on providers;
+// each bucket keeps its peak
+func (ts *TimeSeries) RecordQueueDepth(depth int64, now time.Time) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, ring := range ts.rings {
+		b := ring.at(now)
+		if !b.sampled || float64(depth) > b.queueDepth {
+			b.queueDepth = float64(depth)
+			b.sampled = true
+		}
+	}
+}
+
+// Start samples the queue depth reported by depth every second, for good.
+// Calls after the first do nothing.
+func (ts *TimeSeries) Start(depth func() int64) {
+	if ts == nil {
+		return
+	}
+	ts.started.Do(func() { go ts.sample(depth) })
+}
+
+// sample records the queue depth every second
+func (ts *TimeSeries) sample(depth func() int64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		ts.RecordQueueDepth(depth(), now)
+	}
+}
+
+// Retention returns how far back the time series go
+func (ts *TimeSeries) Retention() time.Duration {
+	return ts.retention
+}
+
+// Points returns the complete buckets of the last window at resolution,
+// oldest first; the bucket in progress is left out
+func (ts *TimeSeries) Points(resolution, window time.Duration, now time.Time) ([]TimeSeriesPoint, error) {
+	var ring *seriesRing
+	for _, r := range ts.rings {
+		if r.step == resolution {
+			ring = r
+		}
+	}
+	if ring == nil {
+		return nil, fmt.Errorf("unsupported resolution %s", resolution)
+	}
+	if window <= 0 || window > ts.retention {
+		window = ts.retention
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	current := now.UnixNano() / int64(ring.step)
+	count := int64(window / ring.step)
+	points := make([]TimeSeriesPoint, 0, count)
+	for slot := current - count; slot < current; slot++ {
+		point := TimeSeriesPoint{Time: time.Unix(0, slot*int64(ring.step)).UTC()}
+		b := ring.buckets[slot%int64(len(ring.buckets))]
+		if b.slot == slot {
+			point.RPS = float64(b.requests) / ring.step.Seconds()
+			if b.requests > 0 {
+				point.ErrorRate = float64(b.errors) / float64(b.requests)
+			}
+			if b.ttftCount > 0 {
+				ms := float64(b.ttftSum.Microseconds()) / 1000 / float64(b.ttftCount)
+				point.TTFTMs = &ms
+			}
+			point.QueueDepth = b.queueDepth
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// Middleware returns a middleware counting every request it wraps
+func (ts *TimeSeries) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			ts.RecordRequest(rw.status, time.Now())
+		})
+	}
+}
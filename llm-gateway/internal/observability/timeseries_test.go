@@ -0,0 +1,101 @@
+package observability
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeSeries_Points(t *testing.T) {
+	ts := NewTimeSeries(time.Minute)
+	start := time.Unix(1_700_000_000, 0)
+
+	// Two seconds of traffic: 4 requests with one 5xx, then 2 requests
+	for _, status := range []int{200, 200, 429, 502} {
+		ts.RecordRequest(status, start)
+	}
+	ts.RecordQueueDepth(3, start)
+	ts.RecordQueueDepth(1, start.Add(500*time.Millisecond))
+	ts.RecordRequest(200, start.Add(time.Second))
+	ts.RecordRequest(200, start.Add(time.Second))
+
+	points, err := ts.Points(time.Second, 5*time.Second, start.Add(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 5 {
+		t.Fatalf("got %d points, want 5", len(points))
+	}
+	first, second := points[3], points[4]
+	if !first.Time.Equal(start) || first.RPS != 4 || first.ErrorRate != 0.25 || first.QueueDepth != 3 {
+		t.Errorf("first second = %+v", first)
+	}
+	if second.RPS != 2 || second.ErrorRate != 0 {
+		t.Errorf("second second = %+v", second)
+	}
+	if points[0].RPS != 0 || points[0].TTFTMs != nil {
+		t.Errorf("idle bucket = %+v", points[0])
+	}
+
+	coarse, err := ts.Points(10*time.Second, time.Minute, start.Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := coarse[len(coarse)-1]; last.RPS != 0.6 || last.QueueDepth != 3 {
+		t.Errorf("10s bucket = %+v", last)
+	}
+}
+
+func TestTimeSeries_LeavesOutBucketInProgress(t *testing.T) {
+	ts := NewTimeSeries(time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	ts.RecordRequest(http.StatusOK, now)
+
+	points, _ := ts.Points(time.Second, 10*time.Second, now)
+	for _, p := range points {
+		if p.RPS != 0 {
+			t.Errorf("bucket in progress reported: %+v", p)
+		}
+	}
+}
+
+func TestTimeSeries_OverwritesExpiredBuckets(t *testing.T) {
+	ts := NewTimeSeries(time.Minute)
+	start := time.Unix(1_700_000_000, 0)
+	ts.RecordRequest(http.StatusOK, start)
+	// The same ring slot a retention later
+	later := start.Add(61 * time.Second)
+	ts.RecordRequest(http.StatusInternalServerError, later)
+
+	points, _ := ts.Points(time.Second, time.Minute, later.Add(time.Second))
+	last := points[len(points)-1]
+	if last.RPS != 1 || last.ErrorRate != 1 {
+		t.Errorf("reused bucket = %+v, want only the later request", last)
+	}
+}
+
+func TestTimeSeries_TTFT(t *testing.T) {
+	ts := NewTimeSeries(time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	ts.RecordTTFT(200*time.Millisecond, now)
+	ts.RecordTTFT(400*time.Millisecond, now)
+
+	points, _ := ts.Points(time.Second, time.Minute, now.Add(time.Second))
+	if ttft := points[len(points)-1].TTFTMs; ttft == nil || *ttft != 300 {
+		t.Errorf("ttft_ms = %v, want 300", ttft)
+	}
+}
+
+func TestTimeSeries_UnsupportedResolution(t *testing.T) {
+	ts := NewTimeSeries(time.Minute)
+	if _, err := ts.Points(time.Minute, time.Minute, time.Now()); err == nil {
+		t.Error("expected an error for a 1m resolution")
+	}
+}
+
+func TestTimeSeries_NilIsNoop(t *testing.T) {
+	var ts *TimeSeries
+	ts.RecordRequest(http.StatusOK, time.Now())
+	ts.RecordTTFT(time.Second, time.Now())
+	ts.RecordQueueDepth(1, time.Now())
+}
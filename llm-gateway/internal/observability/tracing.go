@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,9 +17,11 @@ type TracingConfig struct {
 	Enabled      bool
 	ServiceName  string
 	SamplingRate float64 // 0.0 to 1.0
-	// Exporter configuration (placeholder for OTLP/Jaeger/Zipkin)
-	ExporterType    string // "console", "otlp", "jaeger", "zipkin"
+	// Exporter configuration; "jaeger" sends OTLP to Jaeger's OTLP/HTTP port
+	ExporterType    string // "console", "otlp", "jaeger"
 	ExporterAddress string
+	// ExporterHeaders are sent with every export request, e.g. for collector auth
+	ExporterHeaders map[string]string
 }
 
 // DefaultTracingConfig returns sensible defaults
@@ -70,9 +73,9 @@ const (
 
 // SpanEvent represents an event within a span
 type SpanEvent struct {
-	Name       string
-	Timestamp  time.Time
-	Attributes map[string]interface{}
+	Name       string                 `json:"name"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 // SetAttribute sets a span attribute
@@ -130,12 +133,33 @@ func (s *Span) Duration() time.Duration {
 	return s.EndTime.Sub(s.StartTime)
 }
 
+// recentSpanCount is the number of ended spans kept for request bundles
+const recentSpanCount = 1000
+
 // Tracer creates and manages spans
 type Tracer struct {
 	config   TracingConfig
 	exporter SpanExporter
 	mu       sync.RWMutex
 	spans    []*Span // Buffer for batch export
+
+	// recent is a ring of the last ended spans, looked up by trace ID
+	recent     []*Span
+	recentNext int
+}
+
+// SpanRecord is an ended span as included in request bundles
+type SpanRecord struct {
+	TraceID       string                 `json:"trace_id"`
+	SpanID        string                 `json:"span_id"`
+	ParentID      string                 `json:"parent_id,omitempty"`
+	Name          string                 `json:"name"`
+	Start         time.Time              `json:"start"`
+	Duration      time.Duration          `json:"duration"`
+	Status        StatusCode             `json:"status"`
+	StatusMessage string                 `json:"status_message,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Events        []SpanEvent            `json:"events,omitempty"`
 }
 
 // SpanExporter exports spans to a backend
@@ -176,14 +200,13 @@ func NewTracer(config TracingConfig) *Tracer {
 	var exporter SpanExporter
 
 	switch config.ExporterType {
-	case "otlp":
-		// Placeholder for OTLP exporter
-		log.Warn().Msg("OTLP exporter not yet implemented, falling back to console")
-		exporter = &ConsoleExporter{}
-	case "jaeger":
-		// Placeholder for Jaeger exporter
-		log.Warn().Msg("Jaeger exporter not yet implemented, falling back to console")
-		exporter = &ConsoleExporter{}
+	case "otlp", "jaeger":
+		if config.ExporterAddress == "" {
+			log.Warn().Str("exporter", config.ExporterType).Msg("No exporter address set, falling back to console")
+			exporter = &ConsoleExporter{}
+			break
+		}
+		exporter = NewOTLPExporter(config.ExporterAddress, config.ExporterHeaders, config.ServiceName)
 	case "console":
 		fallthrough
 	default:
@@ -327,6 +350,20 @@ func (t *Tracer) shouldSample() bool {
 func (t *Tracer) export(span *Span) {
 	t.mu.Lock()
 	t.spans = append(t.spans, span)
+	if len(t.recent) < recentSpanCount {
+		t.recent = append(t.recent, span)
+	} else {
+		t.recent[t.recentNext] = span
+		t.recentNext = (t.recentNext + 1) % recentSpanCount
+	}
+
+	// The OTLP exporter batches and sends in the background itself
+	if _, ok := t.exporter.(*OTLPExporter); ok {
+		t.spans = t.spans[:0]
+		t.mu.Unlock()
+		t.exporter.Export([]*Span{span})
+		return
+	}
 
 	// Batch export when buffer is full
 	if len(t.spans) >= 100 {
@@ -340,6 +377,38 @@ func (t *Tracer) export(span *Span) {
 	t.mu.Unlock()
 }
 
+// SpansForTrace returns the recently ended spans of a trace, in start order
+func (t *Tracer) SpansForTrace(traceID string) []SpanRecord {
+	t.mu.RLock()
+	var spans []*Span
+	for _, span := range t.recent {
+		if span.Context.TraceID == traceID {
+			spans = append(spans, span)
+		}
+	}
+	t.mu.RUnlock()
+
+	records := make([]SpanRecord, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		records = append(records, SpanRecord{
+			TraceID:       span.Context.TraceID,
+			SpanID:        span.Context.SpanID,
+			ParentID:      span.Context.ParentID,
+			Name:          span.Name,
+			Start:         span.StartTime,
+			Duration:      span.EndTime.Sub(span.StartTime),
+			Status:        span.Status.Code,
+			StatusMessage: span.Status.Message,
+			Attributes:    span.Attributes,
+			Events:        span.Events,
+		})
+		span.mu.Unlock()
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Start.Before(records[j].Start) })
+	return records
+}
+
 // Flush exports all buffered spans
 func (t *Tracer) Flush() error {
 	t.mu.Lock()
@@ -348,15 +417,23 @@ func (t *Tracer) Flush() error {
 	t.mu.Unlock()
 
 	if len(spans) > 0 {
-		return t.exporter.Export(spans)
+		if err := t.exporter.Export(spans); err != nil {
+			return err
+		}
+	}
+	if otlp, ok := t.exporter.(*OTLPExporter); ok {
+		otlp.sync()
 	}
 	return nil
 }
 
 // Shutdown flushes and shuts down the tracer
 func (t *Tracer) Shutdown(ctx context.Context) error {
-	if err := t.Flush(); err != nil {
-		return err
+	// The OTLP exporter sends the spans it has queued itself, within ctx
+	if _, ok := t.exporter.(*OTLPExporter); !ok {
+		if err := t.Flush(); err != nil {
+			return err
+		}
 	}
 	return t.exporter.Shutdown(ctx)
 }
@@ -425,6 +502,14 @@ func TraceID(ctx context.Context) string {
 	return ""
 }
 
+// SampledTraceID returns the trace ID from context only if the trace is sampled (and thus exported)
+func SampledTraceID(ctx context.Context) string {
+	if span := SpanFromContext(ctx); span != nil && span.Context.Sampled {
+		return span.Context.TraceID
+	}
+	return ""
+}
+
 // SpanID returns the span ID from context for logging
 func SpanID(ctx context.Context) string {
 	if span := SpanFromContext(ctx); span != nil {
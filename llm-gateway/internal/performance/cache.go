@@ -7,16 +7,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/rs/zerolog/log"
-
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/internal/observability"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
+// cacheLogger is the cache module logger; its level can be set via log.modules.cache
+var cacheLogger = observability.ModuleLogger("cache")
+
 var (
 	ErrCacheMiss   = errors.New("cache miss")
 	ErrCacheError  = errors.New("cache error")
@@ -35,6 +40,15 @@ type CacheConfig struct {
 	RedisAddress  string
 	RedisPassword string
 	RedisDB       int
+	// RedisKeyPrefix namespaces the keys of the Redis backend
+	RedisKeyPrefix string
+	// RedisPoolSize is the number of idle Redis connections kept open
+	RedisPoolSize int
+	// RedisTimeout bounds each Redis call
+	RedisTimeout time.Duration
+	// SimilarityThreshold is the cosine similarity above which a cached
+	// response is served for a different prompt, once an embedder is set
+	SimilarityThreshold float64
 }
 
 // DefaultCacheConfig returns sensible defaults
@@ -52,6 +66,9 @@ type CacheBackend interface {
 	Get(ctx context.Context, key string) ([]byte, error)
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// DeleteMatching deletes the keys matching a glob pattern (as in
+	// path.Match), returning how many were deleted
+	DeleteMatching(ctx context.Context, pattern string) (int, error)
 	Clear(ctx context.Context) error
 	Stats() CacheStats
 	Close() error
@@ -74,6 +91,22 @@ type SemanticCache struct {
 	config  CacheConfig
 	mu      sync.RWMutex
 	stats   CacheStats
+	// similarity matches prompts by embedding when an embedder is set
+	similarity atomic.Pointer[similarityIndex]
+	// ttl is config.TTL, which SetTTL changes at runtime
+	ttl atomic.Int64
+	// maxStale is how long responses are kept past the TTL for GetStale
+	maxStale atomic.Int64
+	// clock dates stored responses and similarity vectors; tests replace it
+	// with a fake before SetEmbedder
+	clock clock.Clock
+}
+
+// storedResponse is a cached response kept past the TTL for degraded mode,
+// with the time it stops being fresh
+type storedResponse struct {
+	FreshUntil time.Time       `json:"fresh_until"`
+	Response   json.RawMessage `json:"response"`
 }
 
 // NewSemanticCache creates a new semantic cache with the specified backend
@@ -87,9 +120,9 @@ func NewSemanticCache(config CacheConfig) (*SemanticCache, error) {
 
 	switch config.Backend {
 	case "redis":
-		backend, err = NewRedisBackend(config.RedisAddress, config.RedisPassword, config.RedisDB)
+		backend, err = NewRedisBackend(config)
 		if err != nil {
-			log.Warn().Err(err).Msg("Failed to connect to Redis, falling back to memory cache")
+			cacheLogger.Warn().Err(err).Msg("Failed to connect to Redis, falling back to memory cache")
 			backend = NewMemoryBackend(config.MaxEntries)
 		}
 	case "memory":
@@ -101,9 +134,11 @@ func NewSemanticCache(config CacheConfig) (*SemanticCache, error) {
 	cache := &SemanticCache{
 		backend: backend,
 		config:  config,
+		clock:   clock.Real,
 	}
+	cache.ttl.Store(int64(config.TTL))
 
-	log.Info().
+	cacheLogger.Info().
 		Str("backend", config.Backend).
 		Dur("ttl", config.TTL).
 		Msg("Semantic cache initialized")
@@ -111,28 +146,121 @@ func NewSemanticCache(config CacheConfig) (*SemanticCache, error) {
 	return cache, nil
 }
 
-// GenerateCacheKey creates a deterministic cache key from a chat request
-func (c *SemanticCache) GenerateCacheKey(req *models.ChatCompletionRequest) (string, error) {
+// TTL returns how long responses are cached
+func (c *SemanticCache) TTL() time.Duration {
+	return time.Duration(c.ttl.Load())
+}
+
+// SetTTL changes how long responses stored from now on are cached
+func (c *SemanticCache) SetTTL(ttl time.Duration) {
+	c.ttl.Store(int64(ttl))
+}
+
+// MaxStale returns how long responses are kept past the TTL
+func (c *SemanticCache) MaxStale() time.Duration {
+	return time.Duration(c.maxStale.Load())
+}
+
+// SetMaxStale keeps responses stored from now on for maxStale past the TTL,
+// for GetStale to serve when providers cannot be reached. Get does not
+// return them.
+func (c *SemanticCache) SetMaxStale(maxStale time.Duration) {
+	c.maxStale.Store(int64(maxStale))
+}
+
+// SetEmbedder enables similarity matching: on an exact miss, the prompt is
+// embedded and the response of the closest cached prompt for the same model
+// and parameters is returned if it reaches the similarity threshold
+func (c *SemanticCache) SetEmbedder(embed Embedder) {
+	c.similarity.Store(newSimilarityIndex(embed, c.config.SimilarityThreshold, c.config.MaxEntries, c.clock))
+	cacheLogger.Info().
+		Float64("threshold", c.similarity.Load().threshold).
+		Msg("Semantic cache similarity matching enabled")
+}
+
+type cacheTenantContextKey struct{}
+
+// WithCacheTenant returns a context whose cached responses belong to tenant:
+// they are stored for, and only served to, requests of the same tenant
+func WithCacheTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, cacheTenantContextKey{}, tenant)
+}
+
+func cacheTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(cacheTenantContextKey{}).(string)
+	return tenant
+}
+
+// GenerateCacheKey creates a deterministic cache key from a chat request and
+// the tenant in ctx. Keys start with "llm:chat:<tenant hash>:<user hash>:", so
+// a tenant's or end user's responses can be deleted by pattern.
+func (c *SemanticCache) GenerateCacheKey(ctx context.Context, req *models.ChatCompletionRequest) (string, error) {
+	tenant := cacheTenant(ctx)
+	return hashRequest(req, tenant, "llm:chat:"+ownerHash(tenant)+":"+ownerHash(req.User)+":", true)
+}
+
+// ownerHash shortens a tenant or user for cache keys, without exposing it
+func ownerHash(owner string) string {
+	hash := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(hash[:8])
+}
+
+// similarityScope hashes every field of a request that changes the response
+// except the messages, with the tenant in ctx
+func similarityScope(ctx context.Context, req *models.ChatCompletionRequest) (string, error) {
+	return hashRequest(req, cacheTenant(ctx), "", false)
+}
+
+func hashRequest(req *models.ChatCompletionRequest, tenant, prefix string, withMessages bool) (string, error) {
 	// Don't cache streaming requests
 	if req.Stream {
 		return "", ErrNotCachable
 	}
 
-	// Create a normalized representation of the request
+	// Create a normalized representation of the request, with every field
+	// that changes the response. The tenant and end user keep responses
+	// private to whoever the response was generated for.
 	keyData := struct {
-		Model       string               `json:"model"`
-		Messages    []models.ChatMessage `json:"messages"`
-		Temperature *float64             `json:"temperature,omitempty"`
-		MaxTokens   int                  `json:"max_tokens,omitempty"`
-		TopP        *float64             `json:"top_p,omitempty"`
-		Stop        []string             `json:"stop,omitempty"`
+		Tenant           string                 `json:"tenant"`
+		User             string                 `json:"user,omitempty"`
+		Model            string                 `json:"model"`
+		Messages         []models.ChatMessage   `json:"messages"`
+		Temperature      *float64               `json:"temperature,omitempty"`
+		MaxTokens        int                    `json:"max_tokens,omitempty"`
+		TopP             *float64               `json:"top_p,omitempty"`
+		Stop             []string               `json:"stop,omitempty"`
+		N                int                    `json:"n,omitempty"`
+		PresencePenalty  float64                `json:"presence_penalty,omitempty"`
+		FrequencyPenalty float64                `json:"frequency_penalty,omitempty"`
+		LogitBias        map[string]int         `json:"logit_bias,omitempty"`
+		Functions        []models.Function      `json:"functions,omitempty"`
+		FunctionCall     interface{}            `json:"function_call,omitempty"`
+		Tools            []models.Tool          `json:"tools,omitempty"`
+		ToolChoice       interface{}            `json:"tool_choice,omitempty"`
+		ResponseFormat   *models.ResponseFormat `json:"response_format,omitempty"`
+		Seed             *int                   `json:"seed,omitempty"`
 	}{
-		Model:       req.Model,
-		Messages:    req.Messages,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
-		TopP:        req.TopP,
-		Stop:        req.Stop,
+		Tenant:           tenant,
+		User:             req.User,
+		Model:            req.Model,
+		Messages:         req.Messages,
+		Temperature:      req.Temperature,
+		MaxTokens:        req.MaxTokens,
+		TopP:             req.TopP,
+		Stop:             req.Stop,
+		N:                req.N,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		LogitBias:        req.LogitBias,
+		Functions:        req.Functions,
+		FunctionCall:     req.FunctionCall,
+		Tools:            req.Tools,
+		ToolChoice:       req.ToolChoice,
+		ResponseFormat:   req.ResponseFormat,
+		Seed:             req.Seed,
+	}
+	if !withMessages {
+		keyData.Messages = nil
 	}
 
 	// Sort stop tokens for consistency
@@ -148,46 +276,117 @@ func (c *SemanticCache) GenerateCacheKey(req *models.ChatCompletionRequest) (str
 
 	// Generate SHA-256 hash
 	hash := sha256.Sum256(data)
-	key := "llm:chat:" + hex.EncodeToString(hash[:])
-
-	return key, nil
+	return prefix + hex.EncodeToString(hash[:]), nil
 }
 
 // Get retrieves a cached response
 func (c *SemanticCache) Get(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
-	key, err := c.GenerateCacheKey(req)
+	resp, staleness, err := c.lookup(ctx, req)
+	if err == nil && staleness > 0 {
+		// Past its TTL, kept for degraded mode only
+		err = ErrCacheMiss
+	}
+	return resp, c.count(err)
+}
+
+// GetStale retrieves a cached response even past its TTL, within the
+// MaxStale it was stored with, and returns how long it has been stale
+func (c *SemanticCache) GetStale(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, time.Duration, error) {
+	resp, staleness, err := c.lookup(ctx, req)
+	return resp, staleness, c.count(err)
+}
+
+// count records a cache hit or miss and returns err
+func (c *SemanticCache) count(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if err != nil {
-		return nil, err
+		c.stats.Misses++
+	} else {
+		c.stats.Hits++
+	}
+	return err
+}
+
+// lookup returns the cached response for req, exact or similar, and how long
+// it has been past its TTL
+func (c *SemanticCache) lookup(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, time.Duration, error) {
+	key, err := c.GenerateCacheKey(ctx, req)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	data, err := c.backend.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		if index := c.similarity.Load(); index != nil {
+			data, err = c.getSimilar(ctx, index, key, req)
+		}
+	}
 	if err != nil {
-		c.mu.Lock()
-		c.stats.Misses++
-		c.mu.Unlock()
-		return nil, err
+		return nil, 0, err
 	}
 
+	var stored storedResponse
+	if json.Unmarshal(data, &stored) == nil && stored.Response != nil {
+		data = stored.Response
+	}
 	var resp models.ChatCompletionResponse
 	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cached response: %w", err)
+		return nil, 0, fmt.Errorf("failed to unmarshal cached response: %w", err)
 	}
 
-	c.mu.Lock()
-	c.stats.Hits++
-	c.mu.Unlock()
+	var staleness time.Duration
+	if !stored.FreshUntil.IsZero() {
+		staleness = max(c.clock.Since(stored.FreshUntil), 0)
+	}
 
-	log.Debug().
+	cacheLogger.Debug().
 		Str("key", key).
 		Str("model", req.Model).
+		Dur("staleness", staleness).
 		Msg("Cache hit")
 
-	return &resp, nil
+	return &resp, staleness, nil
+}
+
+// getSimilar returns the cached response of the prompt closest to the
+// request's, remembering the request's embedding for the following Set
+func (c *SemanticCache) getSimilar(ctx context.Context, index *similarityIndex, key string, req *models.ChatCompletionRequest) ([]byte, error) {
+	scope, err := similarityScope(ctx, req)
+	if err != nil {
+		return nil, ErrCacheMiss
+	}
+	vector, err := index.vectorFor(ctx, req)
+	if err != nil {
+		cacheLogger.Debug().Err(err).Str("model", req.Model).Msg("Prompt embedding failed, skipping similarity match")
+		return nil, ErrCacheMiss
+	}
+	index.remember(key, vector)
+
+	match, score, ok := index.nearest(scope, vector)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	data, err := c.backend.Get(ctx, match)
+	if err != nil {
+		// Expired or evicted from the backend
+		index.remove(match)
+		return nil, ErrCacheMiss
+	}
+	index.recordHit()
+
+	cacheLogger.Debug().
+		Str("key", match).
+		Str("model", req.Model).
+		Float64("similarity", score).
+		Msg("Similar prompt cache hit")
+
+	return data, nil
 }
 
 // Set stores a response in the cache
 func (c *SemanticCache) Set(ctx context.Context, req *models.ChatCompletionRequest, resp *models.ChatCompletionResponse) error {
-	key, err := c.GenerateCacheKey(req)
+	key, err := c.GenerateCacheKey(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -197,7 +396,16 @@ func (c *SemanticCache) Set(ctx context.Context, req *models.ChatCompletionReque
 		return fmt.Errorf("failed to marshal response for caching: %w", err)
 	}
 
-	if err := c.backend.Set(ctx, key, data, c.config.TTL); err != nil {
+	ttl := c.TTL()
+	if maxStale := c.MaxStale(); maxStale > 0 {
+		data, err = json.Marshal(storedResponse{FreshUntil: c.clock.Now().Add(ttl), Response: data})
+		if err != nil {
+			return fmt.Errorf("failed to marshal response for caching: %w", err)
+		}
+		ttl += maxStale
+	}
+
+	if err := c.backend.Set(ctx, key, data, ttl); err != nil {
 		return err
 	}
 
@@ -205,7 +413,11 @@ func (c *SemanticCache) Set(ctx context.Context, req *models.ChatCompletionReque
 	c.stats.Sets++
 	c.mu.Unlock()
 
-	log.Debug().
+	if index := c.similarity.Load(); index != nil {
+		c.indexVector(ctx, index, key, req, ttl)
+	}
+
+	cacheLogger.Debug().
 		Str("key", key).
 		Str("model", req.Model).
 		Int("size_bytes", len(data)).
@@ -214,9 +426,26 @@ func (c *SemanticCache) Set(ctx context.Context, req *models.ChatCompletionReque
 	return nil
 }
 
+// indexVector stores the prompt embedding of a cached response, reusing the
+// one computed by the Get that missed
+func (c *SemanticCache) indexVector(ctx context.Context, index *similarityIndex, key string, req *models.ChatCompletionRequest, ttl time.Duration) {
+	scope, err := similarityScope(ctx, req)
+	if err != nil {
+		return
+	}
+	vector, ok := index.takePending(key)
+	if !ok {
+		if vector, err = index.vectorFor(ctx, req); err != nil {
+			cacheLogger.Debug().Err(err).Str("model", req.Model).Msg("Prompt embedding failed, response not indexed")
+			return
+		}
+	}
+	index.add(key, scope, vector, ttl)
+}
+
 // Invalidate removes a specific entry from the cache
 func (c *SemanticCache) Invalidate(ctx context.Context, req *models.ChatCompletionRequest) error {
-	key, err := c.GenerateCacheKey(req)
+	key, err := c.GenerateCacheKey(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -224,6 +453,9 @@ func (c *SemanticCache) Invalidate(ctx context.Context, req *models.ChatCompleti
 	if err := c.backend.Delete(ctx, key); err != nil {
 		return err
 	}
+	if index := c.similarity.Load(); index != nil {
+		index.remove(key)
+	}
 
 	c.mu.Lock()
 	c.stats.Deletes++
@@ -232,8 +464,42 @@ func (c *SemanticCache) Invalidate(ctx context.Context, req *models.ChatCompleti
 	return nil
 }
 
+// DeleteUser removes the responses cached for an end user, for deletion
+// requests, returning the number of entries deleted
+func (c *SemanticCache) DeleteUser(user string) int {
+	return c.deleteMatching("llm:chat:*:" + ownerHash(user) + ":*")
+}
+
+// DeleteTenant removes the responses cached for a tenant, returning the
+// number of entries deleted
+func (c *SemanticCache) DeleteTenant(tenant string) int {
+	return c.deleteMatching("llm:chat:" + ownerHash(tenant) + ":*")
+}
+
+// Purge deletes nothing: cached responses expire after the TTL and MaxStale
+func (c *SemanticCache) Purge(func(tenant string) time.Time, time.Time) int {
+	return 0
+}
+
+func (c *SemanticCache) deleteMatching(pattern string) int {
+	if index := c.similarity.Load(); index != nil {
+		index.removeMatching(pattern)
+	}
+	deleted, err := c.backend.DeleteMatching(context.Background(), pattern)
+	if err != nil {
+		cacheLogger.Error().Err(err).Msg("Failed to delete cached responses")
+	}
+	c.mu.Lock()
+	c.stats.Deletes += int64(deleted)
+	c.mu.Unlock()
+	return deleted
+}
+
 // Clear removes all entries from the cache
 func (c *SemanticCache) Clear(ctx context.Context) error {
+	if index := c.similarity.Load(); index != nil {
+		index.clear()
+	}
 	return c.backend.Clear(ctx)
 }
 
@@ -250,10 +516,10 @@ func (c *SemanticCache) Stats() map[string]interface{} {
 		hitRate = float64(c.stats.Hits) / float64(total) * 100
 	}
 
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"enabled":     c.config.Enabled,
 		"backend":     c.config.Backend,
-		"ttl":         c.config.TTL.String(),
+		"ttl":         c.TTL().String(),
 		"hits":        c.stats.Hits,
 		"misses":      c.stats.Misses,
 		"sets":        c.stats.Sets,
@@ -262,6 +528,18 @@ func (c *SemanticCache) Stats() map[string]interface{} {
 		"entry_count": backendStats.EntryCount,
 		"size_bytes":  backendStats.SizeBytes,
 	}
+	if maxStale := c.MaxStale(); maxStale > 0 {
+		stats["max_stale"] = maxStale.String()
+	}
+	if index := c.similarity.Load(); index != nil {
+		hits, vectors := index.stats()
+		stats["similarity"] = map[string]interface{}{
+			"threshold": index.threshold,
+			"hits":      hits,
+			"vectors":   vectors,
+		}
+	}
+	return stats
 }
 
 // Close closes the cache backend
@@ -269,6 +547,28 @@ func (c *SemanticCache) Close() error {
 	return c.backend.Close()
 }
 
+// HealthCheck checks that the backend can be reached; backends without a
+// health check (memory) are always healthy
+func (c *SemanticCache) HealthCheck(ctx context.Context) error {
+	if checker, ok := c.backend.(interface{ HealthCheck(context.Context) error }); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// defaultCache is the process-wide response cache used by the API handlers
+var defaultCache atomic.Pointer[SemanticCache]
+
+// SetDefaultCache sets the process-wide response cache
+func SetDefaultCache(c *SemanticCache) {
+	defaultCache.Store(c)
+}
+
+// DefaultCache returns the process-wide response cache (nil when disabled)
+func DefaultCache() *SemanticCache {
+	return defaultCache.Load()
+}
+
 // MemoryBackend implements an in-memory cache with LRU eviction
 type MemoryBackend struct {
 	mu         sync.RWMutex
@@ -276,6 +576,8 @@ type MemoryBackend struct {
 	order      []string
 	maxEntries int
 	stats      CacheStats
+	// clock expires entries; tests replace it with a fake
+	clock clock.Clock
 }
 
 type cacheEntry struct {
@@ -293,6 +595,7 @@ func NewMemoryBackend(maxEntries int) *MemoryBackend {
 		entries:    make(map[string]*cacheEntry),
 		order:      make([]string, 0, maxEntries),
 		maxEntries: maxEntries,
+		clock:      clock.Real,
 	}
 
 	// Start cleanup goroutine
@@ -314,7 +617,7 @@ func (b *MemoryBackend) cleanup() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	now := time.Now()
+	now := b.clock.Now()
 	for key, entry := range b.entries {
 		if now.After(entry.expiresAt) {
 			delete(b.entries, key)
@@ -337,7 +640,7 @@ func (b *MemoryBackend) Get(ctx context.Context, key string) ([]byte, error) {
 	entry, ok := b.entries[key]
 	b.mu.RUnlock()
 
-	if !ok || time.Now().After(entry.expiresAt) {
+	if !ok || b.clock.Now().After(entry.expiresAt) {
 		return nil, ErrCacheMiss
 	}
 
@@ -360,13 +663,40 @@ func (b *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl t
 
 	b.entries[key] = &cacheEntry{
 		data:      value,
-		expiresAt: time.Now().Add(ttl),
+		expiresAt: b.clock.Now().Add(ttl),
 	}
 	b.order = append(b.order, key)
 
 	return nil
 }
 
+// Add stores value like Set, but only while the backend is below capacity
+// once expired entries are dropped; it never evicts, and reports false when full
+func (b *MemoryBackend) Add(ctx context.Context, key string, value []byte, ttl time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if len(b.entries) >= b.maxEntries {
+		for k, entry := range b.entries {
+			if now.After(entry.expiresAt) {
+				delete(b.entries, k)
+				b.removeFromOrder(k)
+			}
+		}
+	}
+	if len(b.entries) >= b.maxEntries {
+		return false
+	}
+
+	b.entries[key] = &cacheEntry{
+		data:      value,
+		expiresAt: now.Add(ttl),
+	}
+	b.order = append(b.order, key)
+	return true
+}
+
 func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -377,6 +707,21 @@ func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+func (b *MemoryBackend) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deleted := 0
+	for key := range b.entries {
+		if ok, _ := path.Match(pattern, key); ok {
+			delete(b.entries, key)
+			b.removeFromOrder(key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
 func (b *MemoryBackend) Clear(ctx context.Context) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -407,89 +752,6 @@ func (b *MemoryBackend) Close() error {
 	return nil
 }
 
-// RedisBackend implements a Redis-based cache backend
-type RedisBackend struct {
-	// Note: In production, you would use github.com/redis/go-redis/v9
-	// For now, we provide a placeholder that can be easily integrated
-	address  string
-	password string
-	db       int
-	// client *redis.Client  // Uncomment when adding redis dependency
-}
-
-// NewRedisBackend creates a new Redis cache backend
-func NewRedisBackend(address, password string, db int) (*RedisBackend, error) {
-	// Placeholder implementation - would use go-redis in production
-	// For now, return error to fall back to memory cache
-
-	if address == "" {
-		address = "localhost:6379"
-	}
-
-	backend := &RedisBackend{
-		address:  address,
-		password: password,
-		db:       db,
-	}
-
-	// In production:
-	// client := redis.NewClient(&redis.Options{
-	//     Addr:     address,
-	//     Password: password,
-	//     DB:       db,
-	// })
-	// if err := client.Ping(context.Background()).Err(); err != nil {
-	//     return nil, err
-	// }
-	// backend.client = client
-
-	log.Info().
-		Str("address", address).
-		Int("db", db).
-		Msg("Redis backend initialized (placeholder mode)")
-
-	return backend, nil
-}
-
-func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, error) {
-	// Placeholder - would use Redis GET command
-	// val, err := b.client.Get(ctx, key).Bytes()
-	// if err == redis.Nil {
-	//     return nil, ErrCacheMiss
-	// }
-	// return val, err
-	return nil, ErrCacheMiss
-}
-
-func (b *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	// Placeholder - would use Redis SET with EX
-	// return b.client.Set(ctx, key, value, ttl).Err()
-	return nil
-}
-
-func (b *RedisBackend) Delete(ctx context.Context, key string) error {
-	// Placeholder - would use Redis DEL
-	// return b.client.Del(ctx, key).Err()
-	return nil
-}
-
-func (b *RedisBackend) Clear(ctx context.Context) error {
-	// Placeholder - would use Redis FLUSHDB or pattern delete
-	// return b.client.FlushDB(ctx).Err()
-	return nil
-}
-
-func (b *RedisBackend) Stats() CacheStats {
-	// Placeholder - would query Redis INFO
-	return CacheStats{}
-}
-
-func (b *RedisBackend) Close() error {
-	// Placeholder - would close Redis connection
-	// return b.client.Close()
-	return nil
-}
-
 // CacheMiddleware provides caching at the handler level
 type CacheMiddleware struct {
 	cache *SemanticCache
@@ -522,7 +784,10 @@ func IsCacheable(req *models.ChatCompletionRequest) bool {
 func BuildCacheKeyFromMessages(messages []models.ChatMessage) string {
 	var parts []string
 	for _, msg := range messages {
-		parts = append(parts, fmt.Sprintf("%s:%s", msg.Role, msg.Content))
+		parts = append(parts, fmt.Sprintf("%s:%s", msg.Role, msg.Text()))
+		for _, image := range msg.Images() {
+			parts = append(parts, "image:"+image.URL)
+		}
 	}
 	content := strings.Join(parts, "|")
 	hash := sha256.Sum256([]byte(content))
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/username/llm-gateway/internal/clock"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
@@ -96,7 +97,7 @@ func TestSemanticCache_GenerateCacheKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			key, err := cache.GenerateCacheKey(tt.req)
+			key, err := cache.GenerateCacheKey(context.Background(), tt.req)
 			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("GenerateCacheKey() error = %v, want %v", err, tt.wantErr)
 			}
@@ -124,8 +125,8 @@ func TestSemanticCache_GenerateCacheKey_Deterministic(t *testing.T) {
 		Messages: []models.ChatMessage{{Role: "user", Content: "Hello"}},
 	}
 
-	key1, _ := cache.GenerateCacheKey(req)
-	key2, _ := cache.GenerateCacheKey(req)
+	key1, _ := cache.GenerateCacheKey(context.Background(), req)
+	key2, _ := cache.GenerateCacheKey(context.Background(), req)
 
 	if key1 != key2 {
 		t.Error("same request should generate same key")
@@ -176,6 +177,91 @@ func TestSemanticCache_GetSet(t *testing.T) {
 	}
 }
 
+func TestSemanticCache_ResponsesPrivateToTenantAndUser(t *testing.T) {
+	cache, _ := NewSemanticCache(CacheConfig{Enabled: true, TTL: time.Hour, Backend: "memory"})
+	defer cache.Close()
+
+	acme := WithCacheTenant(context.Background(), "acme")
+	req := &models.ChatCompletionRequest{Model: "gpt-4o-mini", User: "alice", Messages: []models.ChatMessage{{Role: "user", Content: "Hello"}}}
+	if err := cache.Set(acme, req, &models.ChatCompletionResponse{ID: "acme-alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := cache.Get(acme, req); err != nil || got.ID != "acme-alice" {
+		t.Fatalf("Get() for the same tenant and user = %v, %v", got, err)
+	}
+	if _, err := cache.Get(WithCacheTenant(context.Background(), "globex"), req); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() for another tenant error = %v, want ErrCacheMiss", err)
+	}
+	bob := *req
+	bob.User = "bob"
+	if _, err := cache.Get(acme, &bob); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() for another end user error = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestSemanticCache_DeleteUserAndTenant(t *testing.T) {
+	cache, _ := NewSemanticCache(CacheConfig{Enabled: true, TTL: time.Hour, MaxEntries: 100, Backend: "memory"})
+	defer cache.Close()
+
+	request := func(user, content string) *models.ChatCompletionRequest {
+		return &models.ChatCompletionRequest{Model: "gpt-4o-mini", User: user, Messages: []models.ChatMessage{{Role: "user", Content: content}}}
+	}
+	acme := WithCacheTenant(context.Background(), "acme")
+	globex := WithCacheTenant(context.Background(), "globex")
+	cache.Set(acme, request("alice", "a"), &models.ChatCompletionResponse{})
+	cache.Set(acme, request("alice", "b"), &models.ChatCompletionResponse{})
+	cache.Set(globex, request("alice", "a"), &models.ChatCompletionResponse{})
+	cache.Set(acme, request("bob", "a"), &models.ChatCompletionResponse{})
+
+	if n := cache.DeleteUser("alice"); n != 3 {
+		t.Errorf("DeleteUser() = %d, want alice's 3 entries across tenants", n)
+	}
+	if _, err := cache.Get(acme, request("bob", "a")); err != nil {
+		t.Errorf("bob's entry deleted: %v", err)
+	}
+	if n := cache.DeleteTenant("acme"); n != 1 {
+		t.Errorf("DeleteTenant() = %d, want 1", n)
+	}
+	if n := cache.backend.Stats().EntryCount; n != 0 {
+		t.Errorf("%d entries left, want none", n)
+	}
+}
+
+func TestSemanticCache_GetStale(t *testing.T) {
+	cache, _ := NewSemanticCache(CacheConfig{Enabled: true, TTL: 20 * time.Millisecond, Backend: "memory"})
+	defer cache.Close()
+	cache.SetMaxStale(time.Hour)
+	fake := clock.NewFake(time.Now())
+	cache.clock = fake
+	cache.backend.(*MemoryBackend).clock = fake
+
+	ctx := context.Background()
+	req := &models.ChatCompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []models.ChatMessage{{Role: "user", Content: "Hello"}},
+	}
+	if err := cache.Set(ctx, req, &models.ChatCompletionResponse{ID: "stale-id"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if _, staleness, err := cache.GetStale(ctx, req); err != nil || staleness != 0 {
+		t.Fatalf("GetStale() of a fresh entry = %v, %v, want no staleness", staleness, err)
+	}
+	fake.Advance(40 * time.Millisecond)
+
+	if _, err := cache.Get(ctx, req); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() past the TTL error = %v, want ErrCacheMiss", err)
+	}
+	got, staleness, err := cache.GetStale(ctx, req)
+	if err != nil {
+		t.Fatalf("GetStale() past the TTL error = %v", err)
+	}
+	if got.ID != "stale-id" || staleness != 20*time.Millisecond {
+		t.Errorf("GetStale() = %s, staleness %v, want stale-id and 20ms staleness", got.ID, staleness)
+	}
+}
+
 func TestSemanticCache_Invalidate(t *testing.T) {
 	cfg := CacheConfig{
 		Enabled:    true,
@@ -323,6 +409,8 @@ func TestMemoryBackend_SetGet(t *testing.T) {
 
 func TestMemoryBackend_Expiration(t *testing.T) {
 	backend := NewMemoryBackend(100)
+	fake := clock.NewFake(time.Now())
+	backend.clock = fake
 	ctx := context.Background()
 
 	key := "expiring-key"
@@ -337,8 +425,8 @@ func TestMemoryBackend_Expiration(t *testing.T) {
 		t.Fatalf("Get() immediately after Set() error = %v", err)
 	}
 
-	// Wait for expiration
-	time.Sleep(100 * time.Millisecond)
+	// Let the TTL pass
+	fake.Advance(100 * time.Millisecond)
 
 	// Should be expired
 	_, err = backend.Get(ctx, key)
@@ -536,40 +624,17 @@ func TestBuildCacheKeyFromMessages(t *testing.T) {
 	if len(key1) != 32 {
 		t.Errorf("key length = %d, want 32 (16 bytes hex)", len(key1))
 	}
-}
 
-func TestRedisBackend_PlaceholderImplementation(t *testing.T) {
-	backend, err := NewRedisBackend("localhost:6379", "", 0)
-	if err != nil {
-		t.Fatalf("NewRedisBackend() error = %v", err)
+	withImage := func(url string) []models.ChatMessage {
+		return []models.ChatMessage{{Role: "user", Parts: []models.ContentPart{
+			{Type: models.ContentPartText, Text: "Hello"},
+			{Type: models.ContentPartImage, ImageURL: &models.ImageURL{URL: url}},
+		}}}
 	}
-
-	ctx := context.Background()
-
-	// Placeholder implementations should return appropriate defaults
-	_, err = backend.Get(ctx, "key")
-	if !errors.Is(err, ErrCacheMiss) {
-		t.Errorf("Get() error = %v, want ErrCacheMiss", err)
-	}
-
-	if err := backend.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
-		t.Errorf("Set() error = %v", err)
+	if BuildCacheKeyFromMessages(withImage("https://example.com/a.png")) == BuildCacheKeyFromMessages(withImage("https://example.com/b.png")) {
+		t.Error("messages with different images should produce different keys")
 	}
-
-	if err := backend.Delete(ctx, "key"); err != nil {
-		t.Errorf("Delete() error = %v", err)
-	}
-
-	if err := backend.Clear(ctx); err != nil {
-		t.Errorf("Clear() error = %v", err)
-	}
-
-	stats := backend.Stats()
-	if stats.EntryCount != 0 {
-		t.Errorf("Stats().EntryCount = %d, want 0", stats.EntryCount)
-	}
-
-	if err := backend.Close(); err != nil {
-		t.Errorf("Close() error = %v", err)
+	if BuildCacheKeyFromMessages(withImage("https://example.com/a.png")) == key1 {
+		t.Error("a message with an image should not share the key of its text")
 	}
 }
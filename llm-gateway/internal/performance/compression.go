@@ -1,13 +1,13 @@
 package performance
 
 import (
+	"bufio"
 	"compress/gzip"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
-
-	"github.com/rs/zerolog/log"
 )
 
 // CompressionConfig holds configuration for response compression
@@ -196,11 +196,8 @@ func (g *gzipResponseWriter) Close() error {
 }
 
 // Hijack implements http.Hijacker for WebSocket support
-func (g *gzipResponseWriter) Hijack() (interface{}, interface{}, error) {
-	if hijacker, ok := g.ResponseWriter.(http.Hijacker); ok {
-		return hijacker.Hijack()
-	}
-	return nil, nil, http.ErrNotSupported
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(g.ResponseWriter).Hijack()
 }
 
 // Push implements http.Pusher for HTTP/2 push support
@@ -219,7 +216,7 @@ func CompressionMiddleware(config CompressionConfig) func(http.Handler) http.Han
 		}
 	}
 
-	log.Info().
+	logger.Info().
 		Int("level", config.Level).
 		Int("min_size", config.MinSize).
 		Msg("Response compression middleware enabled")
@@ -232,8 +229,9 @@ func CompressionMiddleware(config CompressionConfig) func(http.Handler) http.Han
 				return
 			}
 
-			// Skip compression for SSE (streaming) - it needs special handling
-			if r.Header.Get("Accept") == "text/event-stream" {
+			// Skip compression for SSE (streaming) - it needs special handling -
+			// and for WebSocket upgrades, which take over the connection
+			if r.Header.Get("Accept") == "text/event-stream" || r.Header.Get("Upgrade") != "" {
 				// For SSE, we skip gzip as it interferes with real-time streaming
 				next.ServeHTTP(w, r)
 				return
@@ -0,0 +1,119 @@
+package performance
+
+import (
+	"container/heap"
+	"context"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// tenantQueue holds one tenant's waiting requests, in priority order
+type tenantQueue struct {
+	name   string
+	weight float64
+	pq     priorityQueue
+	// pass is the virtual time of the tenant's next dispatch. Each dispatch
+	// advances it by 1/weight, so tenants with waiting requests get worker
+	// slots in proportion to their weights.
+	pass float64
+}
+
+// before reports whether t's next request goes before o's: higher priority
+// first, then the tenant whose turn comes first, then the older request
+func (t *tenantQueue) before(o *tenantQueue) bool {
+	a, b := t.pq[0], o.pq[0]
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if t.pass != o.pass {
+		return t.pass < o.pass
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// queueTenant identifies the tenant a request is queued for
+type queueTenant struct {
+	name string
+	tier string
+}
+
+type queueTenantContextKey struct{}
+
+// WithTenant returns a context whose queued requests are scheduled as the
+// tenant's, weighted by the queue weight of its rate limit tier
+func WithTenant(ctx context.Context, tenant, tier string) context.Context {
+	return context.WithValue(ctx, queueTenantContextKey{}, queueTenant{name: tenant, tier: tier})
+}
+
+func tenantFromContext(ctx context.Context) queueTenant {
+	tenant, _ := ctx.Value(queueTenantContextKey{}).(queueTenant)
+	return tenant
+}
+
+// pushLocked adds req to its tenant's queue; q.mu must be held. A tenant
+// that had nothing waiting starts at the current virtual time, so idle time
+// earns it no credit over the tenants that kept the workers busy.
+func (q *RequestQueue) pushLocked(req *QueuedRequest, tenant queueTenant) {
+	name, weight := "", 1
+	if q.config.FairQueuing {
+		name = tenant.name
+		if w := q.config.TierWeights[tenant.tier]; w > 0 {
+			weight = w
+		}
+	}
+	req.Tenant = name
+
+	t := q.tenants[name]
+	if t == nil {
+		t = &tenantQueue{name: name}
+		q.tenants[name] = t
+	}
+	t.weight = float64(weight)
+	if len(t.pq) == 0 {
+		t.pass = max(t.pass, q.vclock)
+	}
+	heap.Push(&t.pq, req)
+	q.queued++
+	q.recordDepth(t)
+}
+
+// popLocked removes the next request to process; q.mu must be held and a
+// request waiting
+func (q *RequestQueue) popLocked() *QueuedRequest {
+	var next *tenantQueue
+	for name, t := range q.tenants {
+		if len(t.pq) == 0 {
+			// Forget idle tenants that are not ahead of the virtual time
+			if t.pass <= q.vclock {
+				delete(q.tenants, name)
+			}
+			continue
+		}
+		if next == nil || t.before(next) {
+			next = t
+		}
+	}
+
+	req := heap.Pop(&next.pq).(*QueuedRequest)
+	q.queued--
+	q.vclock = max(q.vclock, next.pass)
+	next.pass += 1 / next.weight
+	q.recordDepth(next)
+	return req
+}
+
+// removeLocked takes a request that is still waiting out of its tenant's
+// queue; q.mu must be held
+func (q *RequestQueue) removeLocked(req *QueuedRequest) {
+	t := q.tenants[req.Tenant]
+	heap.Remove(&t.pq, req.index)
+	q.queued--
+	q.recordDepth(t)
+}
+
+// recordDepth reports the number of requests a tenant has waiting
+func (q *RequestQueue) recordDepth(t *tenantQueue) {
+	if q.config.FairQueuing {
+		observability.GetMetrics().RecordQueueDepth(t.name, len(t.pq))
+	}
+}
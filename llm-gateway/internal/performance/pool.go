@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/username/llm-gateway/internal/observability"
 )
 
+// logger is the performance module logger; its level can be set via log.modules.performance
+var logger = observability.ModuleLogger("performance")
+
 // PoolConfig holds configuration for HTTP connection pooling
 type PoolConfig struct {
 	// MaxIdleConns is the maximum number of idle connections across all hosts
@@ -97,7 +100,7 @@ func NewHTTPClientPool(config PoolConfig) *HTTPClientPool {
 		// No timeout for streaming
 	}
 
-	log.Info().
+	logger.Info().
 		Int("max_idle_conns", config.MaxIdleConns).
 		Int("max_idle_conns_per_host", config.MaxIdleConnsPerHost).
 		Dur("idle_conn_timeout", config.IdleConnTimeout).
@@ -164,7 +167,7 @@ func (p *HTTPClientPool) Close() {
 	if transport, ok := p.defaultClient.Transport.(*http.Transport); ok {
 		transport.CloseIdleConnections()
 	}
-	log.Info().Msg("HTTP connection pool closed")
+	logger.Info().Msg("HTTP connection pool closed")
 }
 
 // Global pool instance for convenience
@@ -1,19 +1,18 @@
 package performance
 
 import (
-	"container/heap"
 	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/username/llm-gateway/internal/clock"
 )
 
 var (
-	ErrQueueFull     = errors.New("request queue is full")
-	ErrQueueClosed   = errors.New("request queue is closed")
+	ErrQueueFull      = errors.New("request queue is full")
+	ErrQueueClosed    = errors.New("request queue is closed")
 	ErrRequestExpired = errors.New("request expired while in queue")
 )
 
@@ -21,9 +20,9 @@ var (
 type Priority int
 
 const (
-	PriorityLow    Priority = 0
-	PriorityNormal Priority = 1
-	PriorityHigh   Priority = 2
+	PriorityLow      Priority = 0
+	PriorityNormal   Priority = 1
+	PriorityHigh     Priority = 2
 	PriorityCritical Priority = 3
 )
 
@@ -39,6 +38,13 @@ type QueueConfig struct {
 	WorkerCount int
 	// PriorityEnabled enables priority-based ordering
 	PriorityEnabled bool
+	// FairQueuing shares the workers between tenants in proportion to their
+	// weights, so one tenant's backlog cannot starve the others; priority
+	// still comes first
+	FairQueuing bool
+	// TierWeights are the tenant weights by rate limit tier; tenants of
+	// other tiers weigh 1
+	TierWeights map[string]int
 }
 
 // DefaultQueueConfig returns sensible defaults
@@ -60,7 +66,9 @@ type QueuedRequest struct {
 	ResultCh  chan QueueResult
 	CreatedAt time.Time
 	Deadline  time.Time
-	index     int // Internal index for heap
+	// Tenant is the tenant the request is scheduled for with fair queuing
+	Tenant string
+	index  int // Internal index for heap
 }
 
 // QueueResult contains the result of processing a queued request
@@ -76,17 +84,25 @@ type RequestProcessor func(ctx context.Context, payload interface{}) (interface{
 type RequestQueue struct {
 	config    QueueConfig
 	processor RequestProcessor
-	pq        priorityQueue
-	mu        sync.Mutex
-	cond      *sync.Cond
-	closed    bool
-	wg        sync.WaitGroup
+	// tenants hold the waiting requests per tenant; without fair queuing
+	// there is a single one
+	tenants map[string]*tenantQueue
+	queued  int
+	// vclock is the virtual time of the last dispatch
+	vclock float64
+	mu     sync.Mutex
+	cond   *sync.Cond
+	closed bool
+	wg     sync.WaitGroup
 
 	// Statistics
 	totalEnqueued  int64
 	totalProcessed int64
 	totalDropped   int64
 	totalExpired   int64
+
+	// clock sets and checks request deadlines; tests replace it with a fake
+	clock clock.Clock
 }
 
 // NewRequestQueue creates a new request queue
@@ -94,29 +110,29 @@ func NewRequestQueue(config QueueConfig, processor RequestProcessor) *RequestQue
 	q := &RequestQueue{
 		config:    config,
 		processor: processor,
-		pq:        make(priorityQueue, 0, config.MaxQueueSize),
+		tenants:   make(map[string]*tenantQueue),
+		clock:     clock.Real,
 	}
 	q.cond = sync.NewCond(&q.mu)
 
-	heap.Init(&q.pq)
-
 	// Start worker goroutines
 	for i := 0; i < config.WorkerCount; i++ {
 		q.wg.Add(1)
 		go q.worker(i)
 	}
 
-	log.Info().
+	logger.Info().
 		Int("max_queue_size", config.MaxQueueSize).
 		Int("worker_count", config.WorkerCount).
 		Dur("max_wait_time", config.MaxWaitTime).
 		Bool("priority_enabled", config.PriorityEnabled).
+		Bool("fair_queuing", config.FairQueuing).
 		Msg("Request queue initialized")
 
 	return q
 }
 
-// Enqueue adds a request to the queue
+// Enqueue adds a request to the queue, as the tenant's set with WithTenant
 func (q *RequestQueue) Enqueue(ctx context.Context, id string, priority Priority, payload interface{}) (interface{}, error) {
 	q.mu.Lock()
 
@@ -126,7 +142,7 @@ func (q *RequestQueue) Enqueue(ctx context.Context, id string, priority Priority
 	}
 
 	// Check queue capacity
-	if len(q.pq) >= q.config.MaxQueueSize {
+	if q.queued >= q.config.MaxQueueSize {
 		q.mu.Unlock()
 		atomic.AddInt64(&q.totalDropped, 1)
 		return nil, ErrQueueFull
@@ -138,12 +154,12 @@ func (q *RequestQueue) Enqueue(ctx context.Context, id string, priority Priority
 		Priority:  priority,
 		Payload:   payload,
 		ResultCh:  make(chan QueueResult, 1),
-		CreatedAt: time.Now(),
-		Deadline:  time.Now().Add(q.config.MaxWaitTime),
+		CreatedAt: q.clock.Now(),
+		Deadline:  q.clock.Now().Add(q.config.MaxWaitTime),
 	}
 
-	// Add to priority queue
-	heap.Push(&q.pq, req)
+	// Add to the tenant's priority queue
+	q.pushLocked(req, tenantFromContext(ctx))
 	atomic.AddInt64(&q.totalEnqueued, 1)
 
 	// Signal a waiting worker
@@ -153,6 +169,16 @@ func (q *RequestQueue) Enqueue(ctx context.Context, id string, priority Priority
 	// Wait for result or context cancellation
 	select {
 	case <-ctx.Done():
+		// A request that gives up leaves the queue rather than holding a
+		// place until a worker pops it
+		q.mu.Lock()
+		if req.index >= 0 {
+			q.removeLocked(req)
+		}
+		q.mu.Unlock()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			atomic.AddInt64(&q.totalExpired, 1)
+		}
 		return nil, ctx.Err()
 	case result := <-req.ResultCh:
 		return result.Result, result.Error
@@ -168,7 +194,7 @@ func (q *RequestQueue) EnqueueAsync(id string, priority Priority, payload interf
 		return nil, ErrQueueClosed
 	}
 
-	if len(q.pq) >= q.config.MaxQueueSize {
+	if q.queued >= q.config.MaxQueueSize {
 		q.mu.Unlock()
 		atomic.AddInt64(&q.totalDropped, 1)
 		return nil, ErrQueueFull
@@ -179,11 +205,11 @@ func (q *RequestQueue) EnqueueAsync(id string, priority Priority, payload interf
 		Priority:  priority,
 		Payload:   payload,
 		ResultCh:  make(chan QueueResult, 1),
-		CreatedAt: time.Now(),
-		Deadline:  time.Now().Add(q.config.MaxWaitTime),
+		CreatedAt: q.clock.Now(),
+		Deadline:  q.clock.Now().Add(q.config.MaxWaitTime),
 	}
 
-	heap.Push(&q.pq, req)
+	q.pushLocked(req, queueTenant{})
 	atomic.AddInt64(&q.totalEnqueued, 1)
 	q.cond.Signal()
 	q.mu.Unlock()
@@ -195,36 +221,36 @@ func (q *RequestQueue) EnqueueAsync(id string, priority Priority, payload interf
 func (q *RequestQueue) worker(id int) {
 	defer q.wg.Done()
 
-	log.Debug().Int("worker_id", id).Msg("Queue worker started")
+	logger.Debug().Int("worker_id", id).Msg("Queue worker started")
 
 	for {
 		q.mu.Lock()
 
 		// Wait for work or shutdown
-		for len(q.pq) == 0 && !q.closed {
+		for q.queued == 0 && !q.closed {
 			q.cond.Wait()
 		}
 
-		if q.closed && len(q.pq) == 0 {
+		if q.closed && q.queued == 0 {
 			q.mu.Unlock()
-			log.Debug().Int("worker_id", id).Msg("Queue worker shutting down")
+			logger.Debug().Int("worker_id", id).Msg("Queue worker shutting down")
 			return
 		}
 
-		// Get highest priority request
-		req := heap.Pop(&q.pq).(*QueuedRequest)
+		// Get the highest priority request of the tenant whose turn it is
+		req := q.popLocked()
 		q.mu.Unlock()
 
 		// Check if request has expired
-		if time.Now().After(req.Deadline) {
+		if q.clock.Now().After(req.Deadline) {
 			atomic.AddInt64(&q.totalExpired, 1)
 			req.ResultCh <- QueueResult{Error: ErrRequestExpired}
 			close(req.ResultCh)
 			continue
 		}
 
-		// Process the request
-		ctx, cancel := context.WithDeadline(context.Background(), req.Deadline)
+		// Process the request within the time left until its deadline
+		ctx, cancel := context.WithTimeout(context.Background(), q.clock.Until(req.Deadline))
 		result, err := q.processor(ctx, req.Payload)
 		cancel()
 
@@ -245,23 +271,29 @@ func (q *RequestQueue) Close() {
 
 	// Wait for workers to finish
 	q.wg.Wait()
-	log.Info().Msg("Request queue closed")
+	logger.Info().Msg("Request queue closed")
 }
 
 // Len returns the current queue length
 func (q *RequestQueue) Len() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	return len(q.pq)
+	return q.queued
 }
 
 // Stats returns queue statistics
 func (q *RequestQueue) Stats() map[string]interface{} {
 	q.mu.Lock()
-	queueLen := len(q.pq)
+	queueLen := q.queued
+	tenants := make(map[string]int)
+	for name, t := range q.tenants {
+		if len(t.pq) > 0 {
+			tenants[name] = len(t.pq)
+		}
+	}
 	q.mu.Unlock()
 
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"enabled":         q.config.Enabled,
 		"queue_length":    queueLen,
 		"max_queue_size":  q.config.MaxQueueSize,
@@ -271,6 +303,10 @@ func (q *RequestQueue) Stats() map[string]interface{} {
 		"total_dropped":   atomic.LoadInt64(&q.totalDropped),
 		"total_expired":   atomic.LoadInt64(&q.totalExpired),
 	}
+	if q.config.FairQueuing {
+		stats["tenant_queue_lengths"] = tenants
+	}
+	return stats
 }
 
 // priorityQueue implements heap.Interface for QueuedRequest
@@ -312,11 +348,11 @@ func (pq *priorityQueue) Pop() interface{} {
 
 // AdaptiveRateLimiter combines rate limiting with queue management
 type AdaptiveRateLimiter struct {
-	queue       *RequestQueue
-	rateLimit   int // Requests per second
-	burstSize   int
-	tokens      chan struct{}
-	refillStop  chan struct{}
+	queue      *RequestQueue
+	rateLimit  int // Requests per second
+	burstSize  int
+	tokens     chan struct{}
+	refillStop chan struct{}
 }
 
 // NewAdaptiveRateLimiter creates a new adaptive rate limiter
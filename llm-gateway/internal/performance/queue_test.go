@@ -0,0 +1,217 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+)
+
+// blockedQueue returns a fair queue with one worker, held by a request until
+// release is called, and the order the later requests are processed in
+func blockedQueue(t *testing.T) (q *RequestQueue, release func(), order func() []string) {
+	t.Helper()
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	q = NewRequestQueue(QueueConfig{
+		MaxQueueSize: 100,
+		MaxWaitTime:  5 * time.Second,
+		WorkerCount:  1,
+		FairQueuing:  true,
+		TierWeights:  map[string]int{"pro": 2},
+	}, func(ctx context.Context, payload interface{}) (interface{}, error) {
+		if payload == "blocker" {
+			close(started)
+			<-gate
+			return nil, nil
+		}
+		mu.Lock()
+		processed = append(processed, payload.(string))
+		mu.Unlock()
+		return nil, nil
+	})
+	t.Cleanup(q.Close)
+
+	if _, err := q.EnqueueAsync("blocker", PriorityNormal, "blocker"); err != nil {
+		t.Fatalf("EnqueueAsync() error = %v", err)
+	}
+	<-started
+
+	return q, func() { close(gate) }, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(processed)
+	}
+}
+
+// enqueue queues payload as the tenant's and waits until it is queued
+func enqueue(t *testing.T, q *RequestQueue, wg *sync.WaitGroup, tenant, tier string, priority Priority, payload string) {
+	t.Helper()
+	want := q.Len() + 1
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := q.Enqueue(WithTenant(context.Background(), tenant, tier), payload, priority, payload); err != nil {
+			t.Errorf("Enqueue(%s) error = %v", payload, err)
+		}
+	}()
+	for q.Len() < want {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestQueue_FairQueuingByWeight(t *testing.T) {
+	q, release, order := blockedQueue(t)
+
+	var wg sync.WaitGroup
+	for _, p := range []string{"a1", "a2", "a3", "a4"} {
+		enqueue(t, q, &wg, "tenant-a", "free", PriorityNormal, p)
+	}
+	for _, p := range []string{"b1", "b2", "b3", "b4"} {
+		enqueue(t, q, &wg, "tenant-b", "pro", PriorityNormal, p)
+	}
+
+	stats := q.Stats()
+	lengths, _ := stats["tenant_queue_lengths"].(map[string]int)
+	if lengths["tenant-a"] != 4 || lengths["tenant-b"] != 4 {
+		t.Errorf("tenant_queue_lengths = %v, want 4 each", stats["tenant_queue_lengths"])
+	}
+
+	release()
+	wg.Wait()
+
+	// tenant-b weighs 2, so it gets two slots for each of tenant-a's, though
+	// tenant-a queued its backlog first
+	want := []string{"a1", "b1", "b2", "a2", "b3", "b4", "a3", "a4"}
+	if got := order(); !slices.Equal(got, want) {
+		t.Errorf("processing order = %v, want %v", got, want)
+	}
+}
+
+func TestRequestQueue_FairQueuingPriorityFirst(t *testing.T) {
+	q, release, order := blockedQueue(t)
+
+	var wg sync.WaitGroup
+	enqueue(t, q, &wg, "tenant-a", "", PriorityNormal, "a1")
+	enqueue(t, q, &wg, "tenant-a", "", PriorityNormal, "a2")
+	enqueue(t, q, &wg, "tenant-b", "", PriorityNormal, "b1")
+	enqueue(t, q, &wg, "tenant-a", "", PriorityCritical, "a3")
+
+	release()
+	wg.Wait()
+
+	want := []string{"a3", "b1", "a1", "a2"}
+	if got := order(); !slices.Equal(got, want) {
+		t.Errorf("processing order = %v, want %v", got, want)
+	}
+}
+
+func TestRequestQueue_WithoutFairQueuingIsFIFO(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	gate := make(chan struct{})
+	q := NewRequestQueue(QueueConfig{MaxQueueSize: 10, MaxWaitTime: 5 * time.Second, WorkerCount: 1}, func(ctx context.Context, payload interface{}) (interface{}, error) {
+		if payload == "blocker" {
+			<-gate
+		}
+		mu.Lock()
+		processed = append(processed, payload.(string))
+		mu.Unlock()
+		return nil, nil
+	})
+	defer q.Close()
+
+	if _, err := q.EnqueueAsync("blocker", PriorityNormal, "blocker"); err != nil {
+		t.Fatalf("EnqueueAsync() error = %v", err)
+	}
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	for _, p := range []string{"a1", "a2", "b1"} {
+		tenant := p[:1]
+		enqueue(t, q, &wg, tenant, "", PriorityNormal, p)
+	}
+	if _, ok := q.Stats()["tenant_queue_lengths"]; ok {
+		t.Error("Stats() reports tenant_queue_lengths without fair queuing")
+	}
+	close(gate)
+	wg.Wait()
+
+	want := []string{"blocker", "a1", "a2", "b1"}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(processed, want) {
+		t.Errorf("processing order = %v, want %v", processed, want)
+	}
+}
+
+func TestRequestQueue_ExpiresPastDeadline(t *testing.T) {
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	q := NewRequestQueue(QueueConfig{MaxQueueSize: 10, MaxWaitTime: 5 * time.Second, WorkerCount: 1}, func(ctx context.Context, payload interface{}) (interface{}, error) {
+		if payload == "blocker" {
+			close(started)
+			<-gate
+		}
+		return payload, nil
+	})
+	defer q.Close()
+	fake := clock.NewFake(time.Now())
+	q.clock = fake
+
+	if _, err := q.EnqueueAsync("blocker", PriorityNormal, "blocker"); err != nil {
+		t.Fatalf("EnqueueAsync() error = %v", err)
+	}
+	<-started
+	expiring, err := q.EnqueueAsync("expiring", PriorityNormal, "expiring")
+	if err != nil {
+		t.Fatalf("EnqueueAsync() error = %v", err)
+	}
+
+	// The worker is busy past the waiting request's deadline
+	fake.Advance(6 * time.Second)
+	close(gate)
+	if result := <-expiring; !errors.Is(result.Error, ErrRequestExpired) {
+		t.Errorf("result error = %v, want ErrRequestExpired", result.Error)
+	}
+}
+
+func TestRequestQueue_RemovesAbandonedRequests(t *testing.T) {
+	q, release, order := blockedQueue(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	abandoned := make(chan error, 1)
+	go func() {
+		_, err := q.Enqueue(ctx, "abandoned", PriorityNormal, "abandoned")
+		abandoned <- err
+	}()
+	for q.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-abandoned; !errors.Is(err, context.Canceled) {
+		t.Errorf("Enqueue() error = %v, want context.Canceled", err)
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len() = %d after the request gave up, want 0", n)
+	}
+
+	release()
+	if _, err := q.Enqueue(context.Background(), "next", PriorityNormal, "next"); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got := order(); !slices.Equal(got, []string{"next"}) {
+		t.Errorf("processing order = %v, want only the request still waiting", got)
+	}
+}
@@ -0,0 +1,207 @@
+package performance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// Redis backend defaults
+const (
+	defaultRedisAddress   = "localhost:6379"
+	defaultRedisKeyPrefix = "llm-gateway:"
+	defaultRedisPoolSize  = 10
+	defaultRedisTimeout   = 2 * time.Second
+	// redisScanCount is the SCAN batch size used by Clear
+	redisScanCount = 500
+)
+
+// RedisBackend stores cache entries in Redis, so every gateway instance
+// shares them. Keys are namespaced with a prefix.
+type RedisBackend struct {
+	client *redisconn.Client
+	prefix string
+
+	hits    atomic.Int64
+	misses  atomic.Int64
+	sets    atomic.Int64
+	deletes atomic.Int64
+}
+
+// NewRedisBackend creates a Redis cache backend and checks the server is reachable
+func NewRedisBackend(config CacheConfig) (*RedisBackend, error) {
+	opts := redisconn.Options{
+		Address:  config.RedisAddress,
+		Password: config.RedisPassword,
+		DB:       config.RedisDB,
+		PoolSize: config.RedisPoolSize,
+		Timeout:  config.RedisTimeout,
+	}
+	if opts.Address == "" {
+		opts.Address = defaultRedisAddress
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultRedisTimeout
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultRedisPoolSize
+	}
+	b := &RedisBackend{client: redisconn.New(opts), prefix: config.RedisKeyPrefix}
+	if b.prefix == "" {
+		b.prefix = defaultRedisKeyPrefix
+	}
+
+	if err := b.HealthCheck(context.Background()); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	cacheLogger.Info().
+		Str("address", opts.Address).
+		Int("db", opts.DB).
+		Str("key_prefix", b.prefix).
+		Int("pool_size", opts.PoolSize).
+		Msg("Redis backend initialized")
+
+	return b, nil
+}
+
+// HealthCheck pings the server
+func (b *RedisBackend) HealthCheck(ctx context.Context) error {
+	replies, err := b.client.Pipeline(ctx, []string{"PING"})
+	if err != nil {
+		return err
+	}
+	if replies[0] != "PONG" {
+		return fmt.Errorf("redis: unexpected PING reply %v", replies[0])
+	}
+	return nil
+}
+
+func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	replies, err := b.client.Pipeline(ctx, []string{"GET", b.prefix + key})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	value, ok := replies[0].(string)
+	if !ok {
+		b.misses.Add(1)
+		return nil, ErrCacheMiss
+	}
+	b.hits.Add(1)
+	return []byte(value), nil
+}
+
+func (b *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cmd := []string{"SET", b.prefix + key, string(value)}
+	if ttl > 0 {
+		cmd = append(cmd, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if _, err := b.client.Pipeline(ctx, cmd); err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	b.sets.Add(1)
+	return nil
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, key string) error {
+	if _, err := b.client.Pipeline(ctx, []string{"DEL", b.prefix + key}); err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheError, err)
+	}
+	b.deletes.Add(1)
+	return nil
+}
+
+// Clear deletes the keys under the backend's prefix. The database may be
+// shared, so it is scanned rather than flushed.
+func (b *RedisBackend) Clear(ctx context.Context) error {
+	_, err := b.DeleteMatching(ctx, "*")
+	return err
+}
+
+// DeleteMatching scans for the keys under the backend's prefix matching the
+// glob pattern and deletes them, returning how many were deleted
+func (b *RedisBackend) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	deleted := 0
+	cursor := "0"
+	for {
+		replies, err := b.client.Pipeline(ctx, []string{"SCAN", cursor, "MATCH", b.prefix + pattern, "COUNT", strconv.Itoa(redisScanCount)})
+		if err != nil {
+			return deleted, fmt.Errorf("%w: %v", ErrCacheError, err)
+		}
+		page, ok := replies[0].([]interface{})
+		if !ok || len(page) != 2 {
+			return deleted, fmt.Errorf("%w: unexpected SCAN reply", ErrCacheError)
+		}
+		cursor, _ = page[0].(string)
+		keys, _ := page[1].([]interface{})
+		if len(keys) > 0 {
+			cmd := []string{"DEL"}
+			for _, key := range keys {
+				if s, ok := key.(string); ok {
+					cmd = append(cmd, s)
+				}
+			}
+			replies, err := b.client.Pipeline(ctx, cmd)
+			if err != nil {
+				return deleted, fmt.Errorf("%w: %v", ErrCacheError, err)
+			}
+			if n, ok := replies[0].(int64); ok {
+				deleted += int(n)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return deleted, nil
+		}
+	}
+}
+
+// Stats returns this instance's hits, misses, sets and deletes with the
+// server's key count, memory use and evictions, read in one pipelined round
+// trip. Server figures cover the whole database and are left zero if the
+// server cannot be reached.
+func (b *RedisBackend) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:    b.hits.Load(),
+		Misses:  b.misses.Load(),
+		Sets:    b.sets.Load(),
+		Deletes: b.deletes.Load(),
+	}
+
+	replies, err := b.client.Pipeline(context.Background(), []string{"DBSIZE"}, []string{"INFO", "memory"}, []string{"INFO", "stats"})
+	if err != nil {
+		cacheLogger.Debug().Err(err).Msg("Failed to read Redis stats")
+		return stats
+	}
+	if n, ok := replies[0].(int64); ok {
+		stats.EntryCount = int(n)
+	}
+	if info, ok := replies[1].(string); ok {
+		stats.SizeBytes = infoField(info, "used_memory")
+	}
+	if info, ok := replies[2].(string); ok {
+		stats.Evictions = infoField(info, "evicted_keys")
+	}
+	return stats
+}
+
+// infoField returns a numeric field of an INFO reply
+func infoField(info, name string) int64 {
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), name+":"); ok {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// Close closes the pooled connections
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
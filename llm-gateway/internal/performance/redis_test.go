@@ -0,0 +1,308 @@
+package performance
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/redisconn"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// fakeRedis is an in-process server for the commands the backend sends
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	data     map[string]string
+	commands []string
+	dials    int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password, data: make(map[string]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.dials++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		reply := f.reply(args, &authed)
+		f.mu.Unlock()
+		conn.Write([]byte(reply))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := redisconn.ReadReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errors.New("not a command")
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		args[i], _ = item.(string)
+	}
+	return args, nil
+}
+
+func bulk(s string) string { return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n" }
+
+func (f *fakeRedis) reply(args []string, authed *bool) string {
+	cmd := strings.ToUpper(args[0])
+	if cmd == "AUTH" {
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	}
+	if !*authed {
+		return "-NOAUTH Authentication required\r\n"
+	}
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "SET":
+		f.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.data[key]; ok {
+				delete(f.data, key)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "SCAN":
+		var keys []string
+		for key := range f.data {
+			if ok, _ := path.Match(args[3], key); ok {
+				keys = append(keys, key)
+			}
+		}
+		out := "*2\r\n" + bulk("0") + "*" + strconv.Itoa(len(keys)) + "\r\n"
+		for _, key := range keys {
+			out += bulk(key)
+		}
+		return out
+	case "DBSIZE":
+		return ":" + strconv.Itoa(len(f.data)) + "\r\n"
+	case "INFO":
+		if args[1] == "memory" {
+			return bulk("# Memory\r\nused_memory:1048576\r\nused_memory_human:1.00M\r\n")
+		}
+		return bulk("# Stats\r\nkeyspace_hits:10\r\nevicted_keys:7\r\n")
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func testRedisBackend(t *testing.T, f *fakeRedis, password string) *RedisBackend {
+	t.Helper()
+	b, err := NewRedisBackend(CacheConfig{
+		RedisAddress:   f.ln.Addr().String(),
+		RedisPassword:  password,
+		RedisDB:        2,
+		RedisKeyPrefix: "test:",
+		RedisPoolSize:  2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestRedisBackend_GetSetDelete(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	b := testRedisBackend(t, f, "secret")
+	ctx := context.Background()
+
+	if _, err := b.Get(ctx, "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Get() error = %v, want ErrCacheMiss", err)
+	}
+	if err := b.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if f.data["test:key"] != "value" {
+		t.Errorf("stored keys = %v, want test:key", f.data)
+	}
+	value, err := b.Get(ctx, "key")
+	if err != nil || string(value) != "value" {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+	if err := b.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get(ctx, "key"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() after Delete error = %v", err)
+	}
+
+	// Connections are reused, so AUTH and SELECT run once per connection
+	if f.dials != 1 {
+		t.Errorf("dials = %d, want 1", f.dials)
+	}
+}
+
+func TestRedisBackend_ClearKeepsOtherKeys(t *testing.T) {
+	f := newFakeRedis(t, "")
+	b := testRedisBackend(t, f, "")
+	ctx := context.Background()
+
+	f.data["other:key"] = "x"
+	for i := 0; i < 3; i++ {
+		b.Set(ctx, fmt.Sprintf("key%d", i), []byte("v"), time.Minute)
+	}
+	if err := b.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.data) != 1 || f.data["other:key"] != "x" {
+		t.Errorf("data after Clear = %v, want only other:key", f.data)
+	}
+}
+
+func TestRedisBackend_StatsPipelined(t *testing.T) {
+	f := newFakeRedis(t, "")
+	b := testRedisBackend(t, f, "")
+	ctx := context.Background()
+
+	b.Set(ctx, "key", []byte("v"), time.Minute)
+	b.Get(ctx, "key")
+	b.Get(ctx, "missing")
+
+	stats := b.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Sets != 1 {
+		t.Errorf("counters = %+v", stats)
+	}
+	if stats.EntryCount != 1 || stats.SizeBytes != 1048576 || stats.Evictions != 7 {
+		t.Errorf("server stats = %+v", stats)
+	}
+}
+
+func TestRedisBackend_Unreachable(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := NewRedisBackend(CacheConfig{RedisAddress: addr, RedisTimeout: time.Second}); err == nil {
+		t.Fatal("NewRedisBackend should fail its health check")
+	}
+
+	// The semantic cache falls back to memory
+	cache, err := NewSemanticCache(CacheConfig{Enabled: true, TTL: time.Hour, Backend: "redis", RedisAddress: addr, RedisTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.backend.(*MemoryBackend); !ok {
+		t.Errorf("backend = %T, want *MemoryBackend", cache.backend)
+	}
+}
+
+func TestRedisBackend_WrongPassword(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	_, err := NewRedisBackend(CacheConfig{RedisAddress: f.ln.Addr().String(), RedisPassword: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("err = %v, want WRONGPASS", err)
+	}
+}
+
+func TestSemanticCache_SharedAcrossInstances(t *testing.T) {
+	f := newFakeRedis(t, "")
+	cfg := CacheConfig{Enabled: true, TTL: time.Hour, Backend: "redis", RedisAddress: f.ln.Addr().String()}
+	first, err := NewSemanticCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, _ := NewSemanticCache(cfg)
+	defer second.Close()
+
+	req := &models.ChatCompletionRequest{Model: "gpt-4o", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+	if err := first.Set(context.Background(), req, &models.ChatCompletionResponse{ID: "chatcmpl-1"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := second.Get(context.Background(), req)
+	if err != nil || resp.ID != "chatcmpl-1" {
+		t.Errorf("second instance Get() = %+v, %v", resp, err)
+	}
+	if err := second.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck() = %v", err)
+	}
+}
+
+func TestSemanticCache_DeleteUserAcrossInstances(t *testing.T) {
+	f := newFakeRedis(t, "")
+	cfg := CacheConfig{Enabled: true, TTL: time.Hour, Backend: "redis", RedisAddress: f.ln.Addr().String()}
+	first, err := NewSemanticCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, _ := NewSemanticCache(cfg)
+	defer second.Close()
+
+	ctx := WithCacheTenant(context.Background(), "acme")
+	alice := &models.ChatCompletionRequest{Model: "gpt-4o", User: "alice", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+	bob := &models.ChatCompletionRequest{Model: "gpt-4o", User: "bob", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+	first.Set(ctx, alice, &models.ChatCompletionResponse{ID: "alice"})
+	first.Set(ctx, bob, &models.ChatCompletionResponse{ID: "bob"})
+
+	// Entries stored by one instance are deleted through another
+	if n := second.DeleteUser("alice"); n != 1 {
+		t.Errorf("DeleteUser() = %d, want 1", n)
+	}
+	if _, err := first.Get(ctx, alice); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() after DeleteUser error = %v, want ErrCacheMiss", err)
+	}
+	if _, err := first.Get(ctx, bob); err != nil {
+		t.Errorf("other user's entry deleted: %v", err)
+	}
+}
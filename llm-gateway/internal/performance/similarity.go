@@ -0,0 +1,228 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// DefaultSimilarityThreshold is the cosine similarity a cached prompt must
+// reach to be served for another prompt
+const DefaultSimilarityThreshold = 0.95
+
+// maxPendingVectors bounds the embeddings computed on a miss and kept until
+// the response is stored
+const maxPendingVectors = 1024
+
+// Embedder returns the embedding of a text
+type Embedder func(ctx context.Context, text string) ([]float64, error)
+
+// similarityIndex finds cached responses whose prompt is close in meaning to
+// a new one. Vectors are held in memory per instance, keyed by the cache key
+// of the response they belong to; the responses stay in the cache backend.
+type similarityIndex struct {
+	embed      Embedder
+	threshold  float64
+	maxEntries int
+	// clock expires vectors, the cache's clock
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*vectorEntry
+	// pending holds embeddings computed by a missed Get for the following Set
+	pending map[string][]float64
+	hits    int64
+}
+
+// vectorEntry is the prompt embedding of one cached response
+type vectorEntry struct {
+	// scope hashes the tenant and every request field but the messages; only
+	// requests of the same tenant and end user, with the same model and
+	// parameters, can share a response
+	scope     string
+	vector    []float64
+	expiresAt time.Time
+}
+
+func newSimilarityIndex(embed Embedder, threshold float64, maxEntries int, clk clock.Clock) *similarityIndex {
+	if threshold <= 0 {
+		threshold = DefaultSimilarityThreshold
+	}
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &similarityIndex{
+		embed:      embed,
+		threshold:  threshold,
+		maxEntries: maxEntries,
+		clock:      clk,
+		entries:    make(map[string]*vectorEntry),
+		pending:    make(map[string][]float64),
+	}
+}
+
+// vectorFor embeds the normalized prompt of a request as a unit vector
+func (s *similarityIndex) vectorFor(ctx context.Context, req *models.ChatCompletionRequest) ([]float64, error) {
+	for _, msg := range req.Messages {
+		// The embedding would only cover the text, not the images
+		if len(msg.Images()) > 0 {
+			return nil, errors.New("prompt has images")
+		}
+	}
+	text := normalizePrompt(req.Messages)
+	if text == "" {
+		return nil, errors.New("empty prompt")
+	}
+	vector, err := s.embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeVector(vector)
+}
+
+// nearest returns the key of the most similar entry in scope, if it reaches the threshold
+func (s *similarityIndex) nearest(scope string, vector []float64) (string, float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	bestKey, best := "", -1.0
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+			continue
+		}
+		if entry.scope != scope || len(entry.vector) != len(vector) {
+			continue
+		}
+		if score := dot(entry.vector, vector); score > best {
+			bestKey, best = key, score
+		}
+	}
+	return bestKey, best, bestKey != "" && best >= s.threshold
+}
+
+// remember keeps the embedding computed for a missed key until it is stored
+func (s *similarityIndex) remember(key string, vector []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) >= maxPendingVectors {
+		s.pending = make(map[string][]float64)
+	}
+	s.pending[key] = vector
+}
+
+// takePending returns and forgets the embedding remembered for key
+func (s *similarityIndex) takePending(key string) ([]float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vector, ok := s.pending[key]
+	delete(s.pending, key)
+	return vector, ok
+}
+
+// add indexes the embedding of a cached response, evicting the entry closest
+// to expiry when full
+func (s *similarityIndex) add(key, scope string, vector []float64, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= s.maxEntries {
+		oldestKey := ""
+		var oldest time.Time
+		for k, entry := range s.entries {
+			if oldestKey == "" || entry.expiresAt.Before(oldest) {
+				oldestKey, oldest = k, entry.expiresAt
+			}
+		}
+		delete(s.entries, oldestKey)
+	}
+	s.entries[key] = &vectorEntry{scope: scope, vector: vector, expiresAt: s.clock.Now().Add(ttl)}
+}
+
+func (s *similarityIndex) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// removeMatching removes the entries whose keys match a glob pattern
+func (s *similarityIndex) removeMatching(pattern string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.entries {
+		if ok, _ := path.Match(pattern, key); ok {
+			delete(s.entries, key)
+		}
+	}
+	for key := range s.pending {
+		if ok, _ := path.Match(pattern, key); ok {
+			delete(s.pending, key)
+		}
+	}
+}
+
+func (s *similarityIndex) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]*vectorEntry)
+	s.pending = make(map[string][]float64)
+}
+
+func (s *similarityIndex) recordHit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits++
+}
+
+func (s *similarityIndex) stats() (hits int64, vectors int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits, len(s.entries)
+}
+
+// normalizePrompt renders messages as "role: content" lines, lowercased with
+// whitespace collapsed, so formatting differences do not affect the embedding
+func normalizePrompt(messages []models.ChatMessage) string {
+	var lines []string
+	for _, msg := range messages {
+		content := strings.Join(strings.Fields(strings.ToLower(msg.Text())), " ")
+		if content == "" {
+			continue
+		}
+		lines = append(lines, msg.Role+": "+content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeVector scales a vector to unit length, so cosine similarity is a dot product
+func normalizeVector(vector []float64) ([]float64, error) {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, errors.New("zero embedding")
+	}
+	norm = math.Sqrt(norm)
+	unit := make([]float64, len(vector))
+	for i, v := range vector {
+		unit[i] = v / norm
+	}
+	return unit, nil
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,175 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// wordEmbedder embeds texts as word counts over a fixed vocabulary, counting calls
+type wordEmbedder struct {
+	vocabulary []string
+	calls      int
+	texts      []string
+}
+
+func (e *wordEmbedder) embed(ctx context.Context, text string) ([]float64, error) {
+	e.calls++
+	e.texts = append(e.texts, text)
+	vector := make([]float64, len(e.vocabulary))
+	for _, word := range strings.Fields(text) {
+		for i, v := range e.vocabulary {
+			if strings.Trim(word, "?.!,") == v {
+				vector[i]++
+			}
+		}
+	}
+	return vector, nil
+}
+
+func chatRequest(model, prompt string) *models.ChatCompletionRequest {
+	return &models.ChatCompletionRequest{Model: model, Messages: []models.ChatMessage{{Role: "user", Content: prompt}}}
+}
+
+func newSimilarityCache(t *testing.T) (*SemanticCache, *wordEmbedder) {
+	t.Helper()
+	cache, err := NewSemanticCache(CacheConfig{Enabled: true, TTL: time.Hour, SimilarityThreshold: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder := &wordEmbedder{vocabulary: []string{"user:", "what", "is", "the", "capital", "of", "france", "germany", "please"}}
+	cache.SetEmbedder(embedder.embed)
+	return cache, embedder
+}
+
+func TestSemanticCache_SimilarPromptHit(t *testing.T) {
+	cache, embedder := newSimilarityCache(t)
+	ctx := context.Background()
+
+	original := chatRequest("gpt-4o", "What is the capital of France?")
+	if _, err := cache.Get(ctx, original); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Get() error = %v, want miss", err)
+	}
+	if err := cache.Set(ctx, original, &models.ChatCompletionResponse{ID: "paris"}); err != nil {
+		t.Fatal(err)
+	}
+	// The embedding computed by the missed Get is reused by Set
+	if embedder.calls != 1 {
+		t.Errorf("embedding calls = %d, want 1", embedder.calls)
+	}
+	if embedder.texts[0] != "user: what is the capital of france?" {
+		t.Errorf("embedded text = %q, want the normalized prompt", embedder.texts[0])
+	}
+
+	resp, err := cache.Get(ctx, chatRequest("gpt-4o", "  what is the capital of France,   please?"))
+	if err != nil || resp.ID != "paris" {
+		t.Fatalf("similar prompt Get() = %+v, %v", resp, err)
+	}
+
+	// Dissimilar prompts, other models and other parameters miss
+	if _, err := cache.Get(ctx, chatRequest("gpt-4o", "What is the capital of Germany?")); err == nil {
+		t.Error("dissimilar prompt should miss")
+	}
+	if _, err := cache.Get(ctx, chatRequest("gpt-4o-mini", "What is the capital of France?")); err == nil {
+		t.Error("another model should miss")
+	}
+	hot := chatRequest("gpt-4o", "What is the capital of France?")
+	hot.MaxTokens = 5
+	if _, err := cache.Get(ctx, hot); err == nil {
+		t.Error("other parameters should miss")
+	}
+
+	stats := cache.Stats()["similarity"].(map[string]interface{})
+	if stats["hits"] != int64(1) || stats["vectors"] != 1 {
+		t.Errorf("similarity stats = %v", stats)
+	}
+}
+
+func TestSemanticCache_SimilarityScopedToTenant(t *testing.T) {
+	cache, _ := newSimilarityCache(t)
+	acme := WithCacheTenant(context.Background(), "acme")
+	globex := WithCacheTenant(context.Background(), "globex")
+
+	if err := cache.Set(acme, chatRequest("gpt-4o", "What is the capital of France?"), &models.ChatCompletionResponse{ID: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	similar := chatRequest("gpt-4o", "what is the capital of France, please?")
+	if _, err := cache.Get(globex, similar); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("similar prompt of another tenant: Get() error = %v, want miss", err)
+	}
+	if err := cache.Set(globex, similar, &models.ChatCompletionResponse{ID: "globex"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each tenant matches only its own responses
+	for ctx, want := range map[context.Context]string{acme: "acme", globex: "globex"} {
+		resp, err := cache.Get(ctx, chatRequest("gpt-4o", "Please, what is the capital of France?"))
+		if err != nil || resp.ID != want {
+			t.Errorf("Get() for %s = %+v, %v", want, resp, err)
+		}
+	}
+	other := chatRequest("gpt-4o", "What is the capital of France?")
+	other.User = "bob"
+	if _, err := cache.Get(acme, other); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("similar prompt of another end user: Get() error = %v, want miss", err)
+	}
+}
+
+func TestSemanticCache_SimilarityFollowsInvalidation(t *testing.T) {
+	cache, _ := newSimilarityCache(t)
+	ctx := context.Background()
+
+	original := chatRequest("gpt-4o", "What is the capital of France?")
+	cache.Set(ctx, original, &models.ChatCompletionResponse{ID: "paris"})
+	if err := cache.Invalidate(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, chatRequest("gpt-4o", "what is the capital of france")); err == nil {
+		t.Error("invalidated response served for a similar prompt")
+	}
+}
+
+func TestSemanticCache_EmbeddingFailureIsMiss(t *testing.T) {
+	cache, err := NewSemanticCache(CacheConfig{Enabled: true, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.SetEmbedder(func(ctx context.Context, text string) ([]float64, error) {
+		return nil, errors.New("provider down")
+	})
+
+	req := chatRequest("gpt-4o", "hello")
+	if _, err := cache.Get(context.Background(), req); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() error = %v, want ErrCacheMiss", err)
+	}
+	if err := cache.Set(context.Background(), req, &models.ChatCompletionResponse{ID: "1"}); err != nil {
+		t.Errorf("Set() should not fail on embedding errors: %v", err)
+	}
+	if resp, err := cache.Get(context.Background(), req); err != nil || resp.ID != "1" {
+		t.Errorf("exact match Get() = %+v, %v", resp, err)
+	}
+}
+
+func TestSimilarityIndex_ExpiresOnCacheClock(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	index := newSimilarityIndex(nil, 0.9, 10, fake)
+	vector := []float64{1, 0}
+
+	index.add("key", "scope", vector, time.Hour)
+	if key, _, ok := index.nearest("scope", vector); !ok || key != "key" {
+		t.Fatalf("nearest() = %q, %v before expiry", key, ok)
+	}
+
+	fake.Advance(2 * time.Hour)
+	if key, _, ok := index.nearest("scope", vector); ok {
+		t.Errorf("nearest() = %q past expiry, want no match", key)
+	}
+	if n := len(index.entries); n != 0 {
+		t.Errorf("%d vectors left past expiry, want none", n)
+	}
+}
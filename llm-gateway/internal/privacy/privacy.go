@@ -0,0 +1,173 @@
+// Package privacy erases the data stored about one end user (the OpenAI
+// "user" field or Anthropic metadata.user_id) and issues a signed report of
+// what was deleted.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the privacy module logger; its level can be set via log.modules.privacy
+var logger = observability.ModuleLogger("privacy")
+
+var (
+	// ErrNoSigningKey is returned when privacy.signing_key is unset
+	ErrNoSigningKey = errors.New("privacy.signing_key is not configured")
+	// ErrNoSubject is returned for a deletion request without a user
+	ErrNoSubject = errors.New("user is required")
+)
+
+// Store holds data that can be attributed to an end user
+type Store interface {
+	// DeleteUser removes everything stored about user, returning the number of
+	// records deleted or scrubbed
+	DeleteUser(user string) int
+}
+
+// Report records the outcome of a deletion request
+type Report struct {
+	Object      string    `json:"object"`
+	ID          string    `json:"id"`
+	Subject     string    `json:"subject"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	// Deleted maps each store to the number of records deleted from it
+	Deleted map[string]int `json:"deleted"`
+	// Signature is "sha256=" + hex(HMAC-SHA256(signing_key, report without signature))
+	Signature string `json:"signature"`
+}
+
+// Eraser deletes an end user's data from every registered store
+type Eraser struct {
+	key string
+
+	mu     sync.Mutex
+	names  []string
+	stores map[string]Store
+}
+
+// New creates an eraser from configuration
+func New(cfg config.PrivacyConfig) *Eraser {
+	return &Eraser{
+		key:    cfg.SigningKey,
+		stores: make(map[string]Store),
+	}
+}
+
+// Register adds a store under name, the key it is reported under
+func (e *Eraser) Register(name string, store Store) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.stores[name]; !ok {
+		e.names = append(e.names, name)
+		sort.Strings(e.names)
+	}
+	e.stores[name] = store
+}
+
+// Stores returns the names of the registered stores
+func (e *Eraser) Stores() []string {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.names...)
+}
+
+// Delete removes user's data from every store and returns the signed report.
+// requestedBy identifies the admin making the request.
+func (e *Eraser) Delete(user, requestedBy string) (*Report, error) {
+	if e.key == "" {
+		return nil, ErrNoSigningKey
+	}
+	user = strings.TrimSpace(user)
+	if user == "" {
+		return nil, ErrNoSubject
+	}
+
+	report := &Report{
+		Object:      "privacy.deletion_report",
+		ID:          "del_" + uuid.NewString(),
+		Subject:     user,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now().UTC(),
+		Deleted:     make(map[string]int),
+	}
+
+	e.mu.Lock()
+	stores := make(map[string]Store, len(e.stores))
+	for name, store := range e.stores {
+		stores[name] = store
+	}
+	e.mu.Unlock()
+
+	for name, store := range stores {
+		report.Deleted[name] = store.DeleteUser(user)
+	}
+	report.CompletedAt = time.Now().UTC()
+	report.Signature = Sign(e.key, report)
+
+	logger.Info().
+		Str("report_id", report.ID).
+		Interface("deleted", report.Deleted).
+		Msg("Deleted end user data")
+	return report, nil
+}
+
+// Sign returns the signature of report, computed over its JSON encoding with
+// an empty signature
+func Sign(key string, report *Report) string {
+	unsigned := *report
+	unsigned.Signature = ""
+	body, _ := json.Marshal(unsigned)
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether report carries a valid signature
+func Verify(key string, report *Report) bool {
+	if key == "" || !strings.HasPrefix(report.Signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(key, report)), []byte(report.Signature))
+}
+
+// SubjectHash returns the SHA-256 of user, to reference a deletion in logs
+// without writing the identifier that was just erased back to them
+func SubjectHash(user string) string {
+	sum := sha256.Sum256([]byte(user))
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultEraser is the process-wide eraser used by the API router and admin endpoints
+var defaultEraser atomic.Pointer[Eraser]
+
+// SetDefault sets the process-wide eraser
+func SetDefault(e *Eraser) {
+	defaultEraser.Store(e)
+}
+
+// Default returns the process-wide eraser
+func Default() *Eraser {
+	return defaultEraser.Load()
+}
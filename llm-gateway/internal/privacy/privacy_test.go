@@ -0,0 +1,52 @@
+package privacy
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+type fakeStore map[string]int
+
+func (s fakeStore) DeleteUser(user string) int {
+	n := s[user]
+	delete(s, user)
+	return n
+}
+
+func TestEraser_Delete(t *testing.T) {
+	e := New(config.PrivacyConfig{SigningKey: "secret"})
+	e.Register("audit", fakeStore{"user-1": 3, "user-2": 1})
+	e.Register("usage", fakeStore{})
+
+	report, err := e.Delete("user-1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted["audit"] != 3 || report.Deleted["usage"] != 0 || report.Subject != "user-1" {
+		t.Errorf("report = %+v", report)
+	}
+
+	// The report verifies after a JSON round trip, and not once altered
+	body, _ := json.Marshal(report)
+	var decoded Report
+	json.Unmarshal(body, &decoded)
+	if !Verify("secret", &decoded) {
+		t.Error("signed report does not verify")
+	}
+	decoded.Deleted["audit"] = 0
+	if Verify("secret", &decoded) {
+		t.Error("altered report verifies")
+	}
+}
+
+func TestEraser_Errors(t *testing.T) {
+	if _, err := New(config.PrivacyConfig{}).Delete("user-1", ""); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("err = %v, want ErrNoSigningKey", err)
+	}
+	if _, err := New(config.PrivacyConfig{SigningKey: "secret"}).Delete("  ", ""); !errors.Is(err, ErrNoSubject) {
+		t.Errorf("err = %v, want ErrNoSubject", err)
+	}
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// Balancing strategies
+const (
+	BalanceWeighted     = "weighted"
+	BalanceLeastLatency = "least_latency"
+)
+
+const (
+	// healthDecay is the weight of the newest call in the moving averages
+	healthDecay = 0.2
+	// minHealth keeps a failing provider a small share of traffic, so its
+	// recovery is noticed
+	minHealth = 0.05
+)
+
+// providerHealth is a moving average of a provider's latency and error rate
+type providerHealth struct {
+	latency   float64 // milliseconds, of successful calls
+	errorRate float64
+	samples   int64
+}
+
+// factor scales a provider's weight by its recent success rate
+func (h *providerHealth) factor() float64 {
+	if h == nil {
+		return 1
+	}
+	return max(1-h.errorRate, minHealth)
+}
+
+// balancer spreads requests for a model over the healthy providers claiming
+// it, by the first matching providers.balancing rule. Weights are scaled by
+// each provider's recent success rate, so a provider returning errors gets
+// less traffic before its circuit breaker opens.
+type balancer struct {
+	rules []config.BalancingRule
+
+	mu     sync.Mutex
+	health map[string]*providerHealth
+	// current holds the smooth weighted round-robin state by model and provider
+	current map[string]map[string]float64
+}
+
+// newBalancer creates a balancer, or returns nil if no rules are configured
+func newBalancer(rules []config.BalancingRule) *balancer {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &balancer{
+		rules:   rules,
+		health:  make(map[string]*providerHealth),
+		current: make(map[string]map[string]float64),
+	}
+}
+
+// rule returns the first rule matching model
+func (b *balancer) rule(model string) (config.BalancingRule, bool) {
+	if b == nil {
+		return config.BalancingRule{}, false
+	}
+	for _, rule := range b.rules {
+		if ok, _ := path.Match(rule.Models, model); ok || rule.Models == model {
+			if rule.Strategy == "" {
+				rule.Strategy = BalanceWeighted
+			}
+			return rule, true
+		}
+	}
+	return config.BalancingRule{}, false
+}
+
+// observe records the outcome of a call to a provider
+func (b *balancer) observe(name string, latency time.Duration, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.health[name]
+	if !ok {
+		h = &providerHealth{}
+		b.health[name] = h
+	}
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	}
+	ms := float64(latency.Microseconds()) / 1000
+	if h.samples == 0 {
+		h.errorRate = failed
+	} else {
+		h.errorRate += healthDecay * (failed - h.errorRate)
+	}
+	if err == nil {
+		if h.latency == 0 {
+			h.latency = ms
+		} else {
+			h.latency += healthDecay * (ms - h.latency)
+		}
+	}
+	h.samples++
+}
+
+// pick chooses a provider for model among candidates, which are available and
+// in priority order. It reports false if no rule applies.
+func (b *balancer) pick(model string, candidates []string) (string, bool) {
+	rule, ok := b.rule(model)
+	if !ok || len(candidates) < 2 {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rule.Strategy == BalanceLeastLatency {
+		return b.fastest(candidates), true
+	}
+
+	// Smooth weighted round-robin: every candidate gains its weight, the
+	// leader is chosen and loses the total
+	current, ok := b.current[model]
+	if !ok {
+		current = make(map[string]float64)
+		b.current[model] = current
+	}
+	best, total := "", 0.0
+	for _, name := range candidates {
+		weight := b.weight(rule, name)
+		current[name] += weight
+		total += weight
+		if best == "" || current[name] > current[best] {
+			best = name
+		}
+	}
+	current[best] -= total
+	return best, true
+}
+
+// weight returns a provider's configured weight scaled by its health; b.mu must be held
+func (b *balancer) weight(rule config.BalancingRule, name string) float64 {
+	weight := 1
+	if w, ok := rule.Weights[name]; ok {
+		weight = w
+	}
+	return float64(weight) * b.health[name].factor()
+}
+
+// fastest returns the candidate with the lowest latency, penalized by its
+// error rate. Candidates without samples are tried first; b.mu must be held.
+func (b *balancer) fastest(candidates []string) string {
+	best, bestScore := "", 0.0
+	for _, name := range candidates {
+		h := b.health[name]
+		if h == nil {
+			return name
+		}
+		score := math.Inf(1) // only failed calls so far
+		if h.latency > 0 {
+			score = h.latency / h.factor()
+		}
+		if best == "" || score < bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
+// explain describes how a rule spreads model over candidates, for
+// ExplainModel, without advancing the round-robin
+func (b *balancer) explain(model string, candidates []string) (string, bool) {
+	rule, ok := b.rule(model)
+	if !ok || len(candidates) < 2 {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	parts := make([]string, 0, len(candidates))
+	for _, name := range candidates {
+		if rule.Strategy == BalanceLeastLatency {
+			latency := 0.0
+			if h := b.health[name]; h != nil {
+				latency = h.latency
+			}
+			parts = append(parts, fmt.Sprintf("%s %.0fms", name, latency))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s weight %.2f", name, b.weight(rule, name)))
+	}
+	return fmt.Sprintf("balancing rule %q spreads requests by %s over %s", rule.Models, rule.Strategy, strings.Join(parts, ", ")), true
+}
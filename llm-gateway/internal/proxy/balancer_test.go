@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestBalancer_WeightedRoundRobin(t *testing.T) {
+	b := newBalancer([]config.BalancingRule{{Models: "llama*", Weights: map[string]int{"a": 3}}})
+	counts := map[string]int{}
+	var order []string
+	for i := 0; i < 8; i++ {
+		name, ok := b.pick("llama3", []string{"a", "b"})
+		if !ok {
+			t.Fatal("expected the rule to apply")
+		}
+		counts[name]++
+		order = append(order, name)
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("counts = %v, want a:6 b:2", counts)
+	}
+	// Smooth: b is interleaved rather than sent in a burst
+	if order[0] != "a" || order[1] != "a" || order[2] != "b" {
+		t.Errorf("order = %v", order)
+	}
+
+	if _, ok := b.pick("gpt-4o", []string{"a", "b"}); ok {
+		t.Error("models without a rule should not be balanced")
+	}
+	if _, ok := b.pick("llama3", []string{"a"}); ok {
+		t.Error("a single candidate should not be balanced")
+	}
+	var none *balancer
+	if _, ok := none.pick("llama3", []string{"a", "b"}); ok {
+		t.Error("nil balancer should not balance")
+	}
+}
+
+func TestBalancer_HealthAdjustsWeights(t *testing.T) {
+	b := newBalancer([]config.BalancingRule{{Models: "shared-model"}})
+	for i := 0; i < 20; i++ {
+		b.observe("a", 10*time.Millisecond, errors.New("upstream error"))
+		b.observe("b", 10*time.Millisecond, nil)
+	}
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		name, _ := b.pick("shared-model", []string{"a", "b"})
+		counts[name]++
+	}
+	if counts["a"] == 0 || counts["a"] > 10 {
+		t.Errorf("counts = %v, want a failing provider to keep a small share", counts)
+	}
+}
+
+func TestBalancer_LeastLatency(t *testing.T) {
+	b := newBalancer([]config.BalancingRule{{Models: "shared-model", Strategy: BalanceLeastLatency}})
+	candidates := []string{"a", "b", "c"}
+
+	b.observe("a", 200*time.Millisecond, nil)
+	if name, _ := b.pick("shared-model", candidates); name != "b" {
+		t.Errorf("pick = %q, want b (not measured yet)", name)
+	}
+	b.observe("b", 50*time.Millisecond, nil)
+	b.observe("c", 0, errors.New("connection refused"))
+	if name, _ := b.pick("shared-model", candidates); name != "b" {
+		t.Errorf("pick = %q, want the fastest", name)
+	}
+	// Errors outweigh a small latency advantage
+	for i := 0; i < 10; i++ {
+		b.observe("b", 50*time.Millisecond, errors.New("upstream error"))
+	}
+	if name, _ := b.pick("shared-model", candidates); name != "a" {
+		t.Errorf("pick = %q, want a once b fails", name)
+	}
+}
+
+func TestRouter_BalancesClaimedModel(t *testing.T) {
+	registry := providers.NewRegistry()
+	openai := &stubProvider{name: "openai", models: []string{"shared-model"}}
+	ollama := &stubProvider{name: "ollama", models: []string{"shared-model"}}
+	registry.Register("openai", openai)
+	registry.Register("ollama", ollama)
+	cfg := &config.Config{}
+	cfg.Providers.Balancing = []config.BalancingRule{{Models: "shared-*", Weights: map[string]int{"openai": 2}}}
+	router := NewRouter(registry, cfg)
+
+	for i := 0; i < 6; i++ {
+		provider, err := router.GetProviderForModel("shared-model")
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "shared-model"})
+	}
+	if len(openai.calls) != 4 || len(ollama.calls) != 2 {
+		t.Errorf("calls: openai %d, ollama %d, want 4 and 2", len(openai.calls), len(ollama.calls))
+	}
+
+	res := router.ExplainModel("shared-model", false)
+	if len(res.Available) != 2 || res.Steps[len(res.Steps)-1] != `balancing rule "shared-*" spreads requests by weighted over ollama weight 1.00, openai weight 2.00` {
+		t.Errorf("resolution = %+v", res)
+	}
+
+	// Drained providers leave the rotation
+	router.DrainProvider("openai")
+	for i := 0; i < 2; i++ {
+		if provider, err := router.GetProviderForModel("shared-model"); err != nil || provider.Name() != "ollama" {
+			t.Errorf("GetProviderForModel() = %v, %v, want ollama", provider, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// modelMetadata is what the catalog knows about a model. Prices are in USD
+// per million tokens.
+type modelMetadata struct {
+	contextWindow   int
+	maxOutputTokens int
+	prompt          float64
+	completion      float64
+	vision          bool
+	tools           bool
+	jsonMode        bool
+}
+
+// builtinCatalog holds the published limits, list prices and capabilities of
+// the models the providers list, by lowercase model ID. model_catalog and
+// pricing override it.
+var builtinCatalog = map[string]modelMetadata{
+	// OpenAI
+	"gpt-4o":                 {contextWindow: 128000, maxOutputTokens: 16384, prompt: 2.5, completion: 10, vision: true, tools: true, jsonMode: true},
+	"gpt-4o-mini":            {contextWindow: 128000, maxOutputTokens: 16384, prompt: 0.15, completion: 0.6, vision: true, tools: true, jsonMode: true},
+	"gpt-4-turbo":            {contextWindow: 128000, maxOutputTokens: 4096, prompt: 10, completion: 30, vision: true, tools: true, jsonMode: true},
+	"gpt-4":                  {contextWindow: 8192, maxOutputTokens: 8192, prompt: 30, completion: 60, tools: true},
+	"gpt-3.5-turbo":          {contextWindow: 16385, maxOutputTokens: 4096, prompt: 0.5, completion: 1.5, tools: true, jsonMode: true},
+	"text-embedding-3-small": {contextWindow: 8191, prompt: 0.02},
+	"text-embedding-3-large": {contextWindow: 8191, prompt: 0.13},
+	"text-embedding-ada-002": {contextWindow: 8191, prompt: 0.1},
+
+	// Anthropic
+	"claude-sonnet-4-20250514":   {contextWindow: 200000, maxOutputTokens: 64000, prompt: 3, completion: 15, vision: true, tools: true},
+	"claude-3-5-sonnet-20241022": {contextWindow: 200000, maxOutputTokens: 8192, prompt: 3, completion: 15, vision: true, tools: true},
+	"claude-3-5-haiku-20241022":  {contextWindow: 200000, maxOutputTokens: 8192, prompt: 0.8, completion: 4, tools: true},
+	"claude-3-opus-20240229":     {contextWindow: 200000, maxOutputTokens: 4096, prompt: 15, completion: 75, vision: true, tools: true},
+	"claude-3-sonnet-20240229":   {contextWindow: 200000, maxOutputTokens: 4096, prompt: 3, completion: 15, vision: true, tools: true},
+	"claude-3-haiku-20240307":    {contextWindow: 200000, maxOutputTokens: 4096, prompt: 0.25, completion: 1.25, vision: true, tools: true},
+
+	// Ollama's default tags; local models cost nothing and have no output cap
+	"llama3.2":  {contextWindow: 131072, tools: true, jsonMode: true},
+	"llama3.1":  {contextWindow: 131072, tools: true, jsonMode: true},
+	"llama3":    {contextWindow: 8192, jsonMode: true},
+	"mistral":   {contextWindow: 32768, tools: true, jsonMode: true},
+	"mixtral":   {contextWindow: 32768, tools: true, jsonMode: true},
+	"codellama": {contextWindow: 16384, jsonMode: true},
+	"phi3":      {contextWindow: 4096, jsonMode: true},
+	"qwen2":     {contextWindow: 32768, tools: true, jsonMode: true},
+	"gemma2":    {contextWindow: 8192, jsonMode: true},
+}
+
+// describeModel adds the model's catalog metadata to m. Models neither the
+// catalog nor the configuration know are returned as they are.
+func (r *Router) describeModel(m models.Model) models.Model {
+	id := strings.ToLower(m.ID)
+	meta, known := builtinCatalog[id]
+
+	if override, ok := r.config.ModelCatalog[id]; ok {
+		known = true
+		if override.ContextWindow > 0 {
+			meta.contextWindow = override.ContextWindow
+		}
+		if override.MaxOutputTokens > 0 {
+			meta.maxOutputTokens = override.MaxOutputTokens
+		}
+		if override.Vision != nil {
+			meta.vision = *override.Vision
+		}
+		if override.Tools != nil {
+			meta.tools = *override.Tools
+		}
+		if override.JSONMode != nil {
+			meta.jsonMode = *override.JSONMode
+		}
+	}
+
+	// The configured price is what the gateway charges, so it wins
+	price, priced := r.config.Pricing[m.ID]
+	if !priced {
+		price, priced = r.config.Pricing[id]
+	}
+	if priced {
+		meta.prompt, meta.completion = price.Prompt, price.Completion
+	}
+
+	if !known && !priced {
+		return m
+	}
+	m.ContextWindow = meta.contextWindow
+	m.MaxOutputTokens = meta.maxOutputTokens
+	m.Pricing = &models.ModelPricing{Prompt: meta.prompt, Completion: meta.completion}
+	if known {
+		m.Capabilities = &models.ModelCapabilities{Vision: meta.vision, Tools: meta.tools, JSONMode: meta.jsonMode}
+	}
+	return m
+}
+
+// Model returns the listed model with the given ID, with its catalog
+// metadata
+func (r *Router) Model(id string) (models.Model, bool) {
+	for _, m := range r.registry.ListAllModels() {
+		if strings.EqualFold(m.ID, id) {
+			return r.describeModel(m), true
+		}
+	}
+	return models.Model{}, false
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func newCatalogRouter(cfg *config.Config) *Router {
+	registry := providers.NewRegistry()
+	registry.Register("openai", providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test"}))
+	return NewRouter(registry, cfg)
+}
+
+func TestModel_BuiltinCatalog(t *testing.T) {
+	router := newCatalogRouter(&config.Config{})
+
+	model, ok := router.Model("gpt-4o")
+	if !ok {
+		t.Fatal("gpt-4o not found")
+	}
+	if model.ContextWindow != 128000 || model.MaxOutputTokens != 16384 {
+		t.Errorf("limits = %d/%d", model.ContextWindow, model.MaxOutputTokens)
+	}
+	if model.Pricing == nil || *model.Pricing != (models.ModelPricing{Prompt: 2.5, Completion: 10}) {
+		t.Errorf("pricing = %+v", model.Pricing)
+	}
+	if model.Capabilities == nil || !model.Capabilities.Vision || !model.Capabilities.Tools || !model.Capabilities.JSONMode {
+		t.Errorf("capabilities = %+v", model.Capabilities)
+	}
+
+	if _, ok := router.Model("claude-3-haiku-20240307"); ok {
+		t.Error("a model no registered provider lists was found")
+	}
+}
+
+func TestModel_ConfigOverrides(t *testing.T) {
+	noVision := false
+	router := newCatalogRouter(&config.Config{
+		ModelCatalog: config.ModelCatalogConfig{"gpt-4o": {MaxOutputTokens: 4096, Vision: &noVision}},
+		Pricing:      config.PricingConfig{"gpt-4o": {Prompt: 2, Completion: 8}},
+	})
+
+	model, _ := router.Model("GPT-4o")
+	if model.ContextWindow != 128000 || model.MaxOutputTokens != 4096 {
+		t.Errorf("limits = %d/%d, want the built-in window and the configured output cap", model.ContextWindow, model.MaxOutputTokens)
+	}
+	if model.Pricing.Prompt != 2 || model.Pricing.Completion != 8 {
+		t.Errorf("pricing = %+v, want the configured prices", model.Pricing)
+	}
+	if model.Capabilities.Vision || !model.Capabilities.Tools {
+		t.Errorf("capabilities = %+v", model.Capabilities)
+	}
+}
+
+func TestListModels_Enriched(t *testing.T) {
+	router := newCatalogRouter(&config.Config{})
+	for _, m := range router.ListModels() {
+		if m.ContextWindow == 0 {
+			t.Errorf("%s listed without catalog metadata", m.ID)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+)
+
+// credentials returns the credential pair for a provider that supports rotation
+func (r *Router) credentials(name string) (*providers.Credentials, error) {
+	provider, found := r.registry.Get(name)
+	if !found {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusNotFound,
+			Code:       "provider_not_found",
+			Message:    "provider not found: " + name,
+		}
+	}
+
+	rotator, ok := provider.(providers.CredentialRotator)
+	if !ok {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusBadRequest,
+			Code:       "credentials_not_supported",
+			Message:    "provider " + name + " does not use API credentials",
+		}
+	}
+	return rotator.Credentials(), nil
+}
+
+// FlipCredentials swaps the active and standby API keys of a provider
+func (r *Router) FlipCredentials(name string) error {
+	creds, err := r.credentials(name)
+	if err != nil {
+		return err
+	}
+	return creds.Flip()
+}
+
+// SetStandbyCredential loads a new standby API key for a provider
+func (r *Router) SetStandbyCredential(name, key string) error {
+	creds, err := r.credentials(name)
+	if err != nil {
+		return err
+	}
+	creds.SetStandby(key)
+	return nil
+}
+
+// SetActiveCredential makes key the active API key of a provider; the previous
+// key becomes the standby
+func (r *Router) SetActiveCredential(name, key, reason string) error {
+	creds, err := r.credentials(name)
+	if err != nil {
+		return err
+	}
+	return creds.Rotate(key, reason)
+}
+
+// CredentialStatus returns the masked credential state and per-key usage of all rotatable providers
+func (r *Router) CredentialStatus() []map[string]interface{} {
+	names := r.registry.List()
+	sort.Strings(names)
+
+	status := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		creds, err := r.credentials(name)
+		if err != nil {
+			continue
+		}
+
+		entry := creds.Status()
+		if provider, ok := r.registry.Get(name); ok {
+			if pooler, ok := provider.(providers.KeyPooler); ok {
+				entry["keys"] = pooler.Keys().Stats()
+			}
+		}
+		status = append(status, entry)
+	}
+	return status
+}
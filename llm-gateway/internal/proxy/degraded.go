@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Degraded modes, as reported in X-Degraded
+const (
+	// DegradedManual is degraded mode switched on by an operator
+	DegradedManual = "manual"
+	// DegradedAuto is degraded mode for a model no provider can serve
+	DegradedAuto = "auto"
+)
+
+// degradedState is the operator's degraded mode switch
+type degradedState struct {
+	mu      sync.RWMutex
+	enabled bool
+	since   time.Time
+}
+
+// SetDegraded switches degraded mode on or off for every model
+func (r *Router) SetDegraded(enabled bool) {
+	r.degraded.mu.Lock()
+	defer r.degraded.mu.Unlock()
+
+	switch {
+	case enabled && !r.degraded.enabled:
+		r.degraded.since = time.Now()
+		logger.Warn().Msg("Degraded mode enabled, serving cached responses only")
+	case !enabled && r.degraded.enabled:
+		logger.Info().
+			Dur("duration", time.Since(r.degraded.since)).
+			Msg("Degraded mode disabled")
+		r.degraded.since = time.Time{}
+	}
+	r.degraded.enabled = enabled
+}
+
+// DegradedMode returns DegradedManual when degraded mode is switched on,
+// DegradedAuto when degraded.auto is set and no provider can serve model,
+// or "" when model's requests go to providers as usual
+func (r *Router) DegradedMode(model string) string {
+	r.degraded.mu.RLock()
+	enabled := r.degraded.enabled
+	r.degraded.mu.RUnlock()
+
+	switch {
+	case enabled:
+		return DegradedManual
+	case r.config.Degraded.Auto && model != "" && r.modelDown(model):
+		return DegradedAuto
+	}
+	return ""
+}
+
+// DegradedStatus returns the degraded mode switch and, with degraded.auto,
+// the known models no provider can serve
+func (r *Router) DegradedStatus() map[string]interface{} {
+	r.degraded.mu.RLock()
+	status := map[string]interface{}{
+		"enabled":   r.degraded.enabled,
+		"auto":      r.config.Degraded.Auto,
+		"max_stale": r.config.Degraded.MaxStale.String(),
+	}
+	if r.degraded.enabled {
+		status["since"] = r.degraded.since.UTC().Format(time.RFC3339)
+	}
+	r.degraded.mu.RUnlock()
+
+	if r.config.Degraded.Auto {
+		down := []string{}
+		for _, model := range r.ListModels() {
+			if r.modelDown(model.ID) {
+				down = append(down, model.ID)
+			}
+		}
+		sort.Strings(down)
+		status["models_down"] = down
+	}
+	return status
+}
+
+// modelDown reports whether no provider can serve model: the provider it
+// resolves to is drained or has an open circuit breaker, and so has every
+// target of its fallback chain
+func (r *Router) modelDown(model string) bool {
+	if !r.resolvedDown(model) {
+		return false
+	}
+	for _, target := range r.fallbacks[strings.ToLower(model)] {
+		if target.provider != "" && !r.providerDown(target.provider) {
+			return false
+		}
+		if target.provider == "" && !r.resolvedDown(target.model) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvedDown reports whether the provider model resolves to cannot take
+// requests. Models no provider serves are not down.
+func (r *Router) resolvedDown(model string) bool {
+	res := r.resolver.Resolve(model, r.modelRoutes.Load())
+	if res.Provider == "" {
+		var providerErr *ProviderError
+		return errors.As(res.err, &providerErr) && providerErr.Code == "provider_draining"
+	}
+	return r.providerDown(res.Provider)
+}
+
+// providerDown reports whether a provider is drained or its circuit breaker
+// rejects requests
+func (r *Router) providerDown(name string) bool {
+	return r.IsDrained(name) || r.breakerRejecting(name)
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import "testing"
+
+func TestRouter_DegradedManual(t *testing.T) {
+	router := newFallbackRouter(&stubProvider{name: "openai", models: []string{"gpt-4o"}}, &stubProvider{name: "anthropic"}, &stubProvider{name: "ollama"})
+	if mode := router.DegradedMode("gpt-4o"); mode != "" {
+		t.Fatalf("DegradedMode() = %q before it is enabled", mode)
+	}
+
+	router.SetDegraded(true)
+	if mode := router.DegradedMode("gpt-4o"); mode != DegradedManual {
+		t.Errorf("DegradedMode() = %q, want %q", mode, DegradedManual)
+	}
+	if status := router.DegradedStatus(); status["enabled"] != true || status["since"] == nil {
+		t.Errorf("DegradedStatus() = %v", status)
+	}
+
+	router.SetDegraded(false)
+	if mode := router.DegradedMode("gpt-4o"); mode != "" {
+		t.Errorf("DegradedMode() = %q after it is disabled", mode)
+	}
+}
+
+func TestRouter_DegradedAuto(t *testing.T) {
+	router := newFallbackRouter(&stubProvider{name: "openai", models: []string{"gpt-4o"}}, &stubProvider{name: "anthropic"}, &stubProvider{name: "ollama"})
+	router.config.Degraded.Auto = true
+
+	if err := router.DrainProvider("openai"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := router.DegradedMode("gpt-4o"); mode != "" {
+		t.Errorf("DegradedMode() = %q with fallbacks up, want none", mode)
+	}
+
+	for _, name := range []string{"anthropic", "ollama"} {
+		if err := router.DrainProvider(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mode := router.DegradedMode("gpt-4o"); mode != DegradedAuto {
+		t.Errorf("DegradedMode() = %q with every provider drained, want %q", mode, DegradedAuto)
+	}
+	if mode := router.DegradedMode("unknown-model"); mode != "" {
+		t.Errorf("DegradedMode() of a model no provider serves = %q, want none", mode)
+	}
+}
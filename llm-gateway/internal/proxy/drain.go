@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// drainState tracks which providers are drained and how many calls are still in flight
+type drainState struct {
+	mu       sync.RWMutex
+	drained  map[string]time.Time
+	inFlight map[string]*int64
+}
+
+func newDrainState() *drainState {
+	return &drainState{
+		drained:  make(map[string]time.Time),
+		inFlight: make(map[string]*int64),
+	}
+}
+
+func (d *drainState) isDrained(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.drained[name]
+	return ok
+}
+
+// counter returns the in-flight counter for a provider, creating it if needed
+func (d *drainState) counter(name string) *int64 {
+	d.mu.RLock()
+	c, ok := d.inFlight[name]
+	d.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok = d.inFlight[name]; ok {
+		return c
+	}
+	c = new(int64)
+	d.inFlight[name] = c
+	return c
+}
+
+// DrainProvider stops routing new requests to a provider; in-flight requests finish normally
+func (r *Router) DrainProvider(name string) error {
+	if _, found := r.registry.Get(name); !found {
+		return providerNotFound(name)
+	}
+
+	r.drain.mu.Lock()
+	if _, ok := r.drain.drained[name]; !ok {
+		r.drain.drained[name] = time.Now()
+	}
+	r.drain.mu.Unlock()
+
+	logger.Warn().
+		Str("provider", name).
+		Int64("in_flight", r.InFlight(name)).
+		Msg("Provider draining")
+	return nil
+}
+
+// UndrainProvider returns a drained provider to rotation
+func (r *Router) UndrainProvider(name string) {
+	r.drain.mu.Lock()
+	_, wasDrained := r.drain.drained[name]
+	delete(r.drain.drained, name)
+	r.drain.mu.Unlock()
+
+	if wasDrained {
+		logger.Info().Str("provider", name).Msg("Provider returned to rotation")
+	}
+}
+
+// IsDrained reports whether a provider is currently drained
+func (r *Router) IsDrained(name string) bool {
+	return r.drain.isDrained(name)
+}
+
+// InFlight returns the number of requests currently being served by a provider
+func (r *Router) InFlight(name string) int64 {
+	return atomic.LoadInt64(r.drain.counter(name))
+}
+
+// DrainStatus returns drain and in-flight information for every provider
+func (r *Router) DrainStatus() []map[string]interface{} {
+	names := r.registry.List()
+	sort.Strings(names)
+
+	r.drain.mu.RLock()
+	defer r.drain.mu.RUnlock()
+
+	status := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		var inFlight int64
+		if c, ok := r.drain.inFlight[name]; ok {
+			inFlight = atomic.LoadInt64(c)
+		}
+		entry := map[string]interface{}{
+			"provider":  name,
+			"drained":   false,
+			"in_flight": inFlight,
+		}
+		if since, ok := r.drain.drained[name]; ok {
+			entry["drained"] = true
+			entry["drained_since"] = since.UTC().Format(time.RFC3339)
+			entry["drain_complete"] = inFlight == 0
+		}
+		status = append(status, entry)
+	}
+	return status
+}
+
+// providerNotFound is returned by operations on a provider that is not configured
+func providerNotFound(name string) error {
+	return &ProviderError{
+		Provider:   name,
+		StatusCode: http.StatusNotFound,
+		Code:       "provider_not_found",
+		Message:    "provider not found: " + name,
+	}
+}
+
+// drainingError is returned when the only provider for a model is drained
+func drainingError(name string) error {
+	return &ProviderError{
+		Provider:   name,
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "provider_draining",
+		Message:    "Provider " + name + " is draining for maintenance and not accepting new requests",
+	}
+}
+
+// trackedProvider counts in-flight calls so drains can report when a provider
+// is idle, captures each request for the flight recorder, reports call
+// outcomes to observe and the first-token latency of streams to firstToken
+type trackedProvider struct {
+	Provider
+	inFlight   *int64
+	observe    func(name string, latency time.Duration, err error)
+	firstToken func(name, model string, ttft time.Duration)
+}
+
+// record reports a completed call to observe. Calls cancelled by the client
+// and rejected as invalid say nothing about the provider's health.
+func (p *trackedProvider) record(ctx context.Context, start time.Time, err error) {
+	if p.observe == nil || ctx.Err() != nil {
+		return
+	}
+	var perr *ProviderError
+	if errors.As(err, &perr) && perr.StatusCode >= 400 && perr.StatusCode < 500 && perr.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	p.observe(p.Name(), time.Since(start), err)
+}
+
+func (p *trackedProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	observability.CaptureProviderRequest(ctx, p.Name(), "chat_completion", req)
+	atomic.AddInt64(p.inFlight, 1)
+	defer atomic.AddInt64(p.inFlight, -1)
+	start := time.Now()
+	resp, err := p.Provider.ChatCompletion(ctx, req)
+	p.record(ctx, start, err)
+	return resp, err
+}
+
+func (p *trackedProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	observability.CaptureProviderRequest(ctx, p.Name(), "chat_completion_stream", req)
+	atomic.AddInt64(p.inFlight, 1)
+	// Streams are timed to their first response
+	start := time.Now()
+	stream, err := p.Provider.ChatCompletionStream(ctx, req)
+	p.record(ctx, start, err)
+	if err != nil {
+		atomic.AddInt64(p.inFlight, -1)
+		return nil, err
+	}
+	// The stream stays in flight until the handler closes it
+	tracked := &trackedStream{ReadCloser: stream, inFlight: p.inFlight}
+	if p.firstToken != nil {
+		name := p.Name()
+		tracked.firstRead = func() { p.firstToken(name, req.Model, time.Since(start)) }
+	}
+	return tracked, nil
+}
+
+func (p *trackedProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	observability.CaptureProviderRequest(ctx, p.Name(), "completion", req)
+	atomic.AddInt64(p.inFlight, 1)
+	defer atomic.AddInt64(p.inFlight, -1)
+	start := time.Now()
+	resp, err := p.Provider.Completion(ctx, req)
+	p.record(ctx, start, err)
+	return resp, err
+}
+
+func (p *trackedProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	observability.CaptureProviderRequest(ctx, p.Name(), "embedding", req)
+	atomic.AddInt64(p.inFlight, 1)
+	defer atomic.AddInt64(p.inFlight, -1)
+	return p.Provider.Embedding(ctx, req)
+}
+
+// trackedStream decrements the in-flight counter exactly once when closed
+// and calls firstRead, if set, when the first bytes arrive
+type trackedStream struct {
+	io.ReadCloser
+	inFlight  *int64
+	once      sync.Once
+	firstRead func()
+}
+
+func (s *trackedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 && s.firstRead != nil {
+		s.firstRead()
+		s.firstRead = nil
+	}
+	return n, err
+}
+
+func (s *trackedStream) Close() error {
+	s.once.Do(func() { atomic.AddInt64(s.inFlight, -1) })
+	return s.ReadCloser.Close()
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestDrainProvider_UnknownProvider(t *testing.T) {
+	router := newResolverRouter()
+
+	err := router.DrainProvider("missing")
+	var perr *ProviderError
+	if !errors.As(err, &perr) || perr.StatusCode != 404 || perr.Code != "provider_not_found" {
+		t.Errorf("err = %v, want provider_not_found", err)
+	}
+	if router.IsDrained("missing") {
+		t.Error("unknown provider was drained")
+	}
+}
+
+func TestDrainProvider_DrainAndUndrain(t *testing.T) {
+	router := newResolverRouter()
+
+	if err := router.DrainProvider("openai"); err != nil {
+		t.Fatal(err)
+	}
+	if !router.IsDrained("openai") || router.IsDrained("ollama") {
+		t.Fatal("only openai should be drained")
+	}
+	_, err := router.GetProvider("openai")
+	var perr *ProviderError
+	if !errors.As(err, &perr) || perr.Code != "provider_draining" || perr.StatusCode != 503 {
+		t.Errorf("GetProvider() err = %v, want provider_draining", err)
+	}
+
+	router.UndrainProvider("openai")
+	if router.IsDrained("openai") {
+		t.Error("openai is still drained")
+	}
+	if _, err := router.GetProvider("openai"); err != nil {
+		t.Errorf("GetProvider() after undrain err = %v", err)
+	}
+}
+
+func TestDrainStatus(t *testing.T) {
+	router := newResolverRouter()
+	router.DrainProvider("ollama")
+
+	status := router.DrainStatus()
+	if len(status) != 3 {
+		t.Fatalf("status = %v, want three providers", status)
+	}
+	// Sorted by name: anthropic, ollama, openai
+	if status[0]["provider"] != "anthropic" || status[0]["drained"] != false {
+		t.Errorf("anthropic status = %v", status[0])
+	}
+	ollama := status[1]
+	if ollama["provider"] != "ollama" || ollama["drained"] != true || ollama["drain_complete"] != true || ollama["drained_since"] == nil {
+		t.Errorf("ollama status = %v", ollama)
+	}
+	if _, ok := status[2]["drained_since"]; ok {
+		t.Errorf("openai status = %v, want no drain fields", status[2])
+	}
+}
+
+func TestDrain_InFlightTracking(t *testing.T) {
+	router := newResolverRouter()
+	provider, err := router.GetProvider("openai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &models.ChatCompletionRequest{Model: "gpt-4o"}
+
+	if _, err := provider.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if n := router.InFlight("openai"); n != 0 {
+		t.Errorf("in flight after a completed call = %d, want 0", n)
+	}
+
+	stream, err := provider.ChatCompletionStream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.DrainProvider("openai")
+	if n := router.InFlight("openai"); n != 1 {
+		t.Errorf("in flight with an open stream = %d, want 1", n)
+	}
+	if status := router.DrainStatus()[2]; status["drain_complete"] != false {
+		t.Errorf("status = %v, want drain incomplete", status)
+	}
+
+	io.ReadAll(stream)
+	stream.Close()
+	stream.Close()
+	if n := router.InFlight("openai"); n != 0 {
+		t.Errorf("in flight after closing the stream twice = %d, want 0", n)
+	}
+	if status := router.DrainStatus()[2]; status["drain_complete"] != true {
+		t.Errorf("status = %v, want drain complete", status)
+	}
+}
+
+func TestDrain_DefaultMustServeModel(t *testing.T) {
+	router := newResolverRouter()
+	router.DrainProvider("openai")
+
+	// anthropic is the default but does not serve gpt-4o
+	_, err := router.GetProviderForModel("gpt-4o")
+	var perr *ProviderError
+	if !errors.As(err, &perr) || perr.Code != "provider_draining" || perr.Provider != "openai" {
+		t.Errorf("err = %v, want openai draining", err)
+	}
+
+	// Another claimant still serves a shared model
+	provider, err := router.GetProviderForModel("shared-model")
+	if err != nil || provider.Name() != "ollama" {
+		t.Errorf("GetProviderForModel() = %v, %v, want ollama", provider, err)
+	}
+}
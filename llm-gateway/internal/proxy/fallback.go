@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// fallbackTarget is one step of a fallback chain: a model, pinned to a
+// provider or routed as usual when provider is empty
+type fallbackTarget struct {
+	provider string
+	model    string
+}
+
+// initFallbacks parses the configured fallback chains. Chain keys are
+// lowercase, as configuration keys are.
+func (r *Router) initFallbacks() {
+	cfg := r.config.Fallbacks
+	if !cfg.Enabled {
+		return
+	}
+	r.fallbacks = make(map[string][]fallbackTarget, len(cfg.Chains))
+	for model, chain := range cfg.Chains {
+		targets := make([]fallbackTarget, 0, len(chain))
+		for _, entry := range chain {
+			targets = append(targets, r.parseFallbackTarget(strings.TrimSpace(entry)))
+		}
+		r.fallbacks[strings.ToLower(model)] = targets
+		logger.Info().
+			Str("model", model).
+			Strs("chain", chain).
+			Msg("Fallback chain configured")
+	}
+}
+
+// parseFallbackTarget splits "provider/model"; entries whose first segment is
+// not a provider name (e.g. "meta-llama/Llama-3-8b") are model names
+func (r *Router) parseFallbackTarget(entry string) fallbackTarget {
+	if name, model, ok := strings.Cut(entry, "/"); ok {
+		if _, found := r.registry.Get(name); found {
+			return fallbackTarget{provider: name, model: model}
+		}
+	}
+	return fallbackTarget{model: entry}
+}
+
+// withFallbacks wraps the provider selected for model with its fallback
+// chain, if one is configured. The primary may have failed to resolve (e.g.
+// it is drained), in which case the chain starts with the first fallback.
+func (r *Router) withFallbacks(model string, primary Provider, err error) (Provider, error) {
+	chain, ok := r.fallbacks[strings.ToLower(model)]
+	if !ok {
+		return primary, err
+	}
+
+	f := &fallbackProvider{router: r, model: model, primary: primary, primaryErr: err, chain: chain}
+	f.Provider = primary
+	if primary == nil {
+		// Answer Name and the other metadata methods for the first usable fallback
+		for _, target := range chain {
+			if p, err := r.resolveFallback(target); err == nil {
+				f.Provider = p
+				break
+			}
+		}
+		if f.Provider == nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// resolveFallback returns the provider serving a fallback target
+func (r *Router) resolveFallback(target fallbackTarget) (Provider, error) {
+	if target.provider != "" {
+		return r.GetProvider(target.provider)
+	}
+	return r.providerForModel(target.model, r.modelRoutes.Load())
+}
+
+// fallbackProvider sends a request to its primary provider and, when that
+// fails with an error another provider might not have, to each model of the
+// fallback chain in turn, with the request's model name remapped. It is
+// created per request; Name reports the provider that served the last call.
+type fallbackProvider struct {
+	Provider
+	router     *Router
+	model      string
+	primary    Provider
+	primaryErr error
+	chain      []fallbackTarget
+	served     string
+}
+
+// Name returns the provider that served the last call, or the primary's name
+func (f *fallbackProvider) Name() string {
+	if f.served != "" {
+		return f.served
+	}
+	return f.Provider.Name()
+}
+
+// shouldFallback reports whether a failed call may succeed elsewhere: not when
+// the client went away or the request itself was rejected as invalid
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		switch status := providerErr.StatusCode; {
+		case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusNotFound,
+			status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+			return true
+		case status >= 400 && status < 500:
+			return false
+		}
+	}
+	return true
+}
+
+// callWithFallbacks runs call on the primary and then on each fallback until
+// one succeeds or an error is not worth falling back on
+func callWithFallbacks[T any](ctx context.Context, f *fallbackProvider, call func(Provider, string) (T, error)) (T, error) {
+	var zero T
+	lastErr := f.primaryErr
+	if f.primary != nil {
+		result, err := call(f.primary, f.model)
+		if err == nil {
+			f.served = f.primary.Name()
+			return result, nil
+		}
+		if !shouldFallback(ctx, err) {
+			return zero, err
+		}
+		lastErr = err
+	}
+
+	for _, target := range f.chain {
+		provider, err := f.router.resolveFallback(target)
+		if err != nil {
+			logger.Debug().Err(err).Str("model", target.model).Msg("Skipping unavailable fallback")
+			continue
+		}
+		logger.Warn().
+			Err(lastErr).
+			Str("model", f.model).
+			Str("fallback_provider", provider.Name()).
+			Str("fallback_model", target.model).
+			Msg("Provider failed, trying fallback")
+
+		result, err := call(provider, target.model)
+		if err == nil {
+			f.served = provider.Name()
+			observability.RecordFallback(ctx, observability.Fallback{Provider: provider.Name(), Model: target.model})
+			observability.GetMetrics().RecordFallback(f.model, provider.Name(), target.model)
+			return result, nil
+		}
+		if !shouldFallback(ctx, err) {
+			return zero, err
+		}
+		lastErr = err
+	}
+	return zero, lastErr
+}
+
+// ChatCompletion performs a chat completion, falling back on failure
+func (f *fallbackProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	return callWithFallbacks(ctx, f, func(p Provider, model string) (*models.ChatCompletionResponse, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.ChatCompletion(ctx, &remapped)
+	})
+}
+
+// ChatCompletionStream starts a streaming chat completion, falling back when
+// the stream cannot be started
+func (f *fallbackProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	return callWithFallbacks(ctx, f, func(p Provider, model string) (io.ReadCloser, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.ChatCompletionStream(ctx, &remapped)
+	})
+}
+
+// Completion performs a legacy completion, falling back on failure
+func (f *fallbackProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	return callWithFallbacks(ctx, f, func(p Provider, model string) (*models.CompletionResponse, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.Completion(ctx, &remapped)
+	})
+}
+
+// Embedding generates embeddings, falling back on failure
+func (f *fallbackProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	return callWithFallbacks(ctx, f, func(p Provider, model string) (*models.EmbeddingResponse, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.Embedding(ctx, &remapped)
+	})
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// stubProvider serves its models, failing every call with err if set
+type stubProvider struct {
+	name   string
+	models []string
+	err    error
+	calls  []string
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	p.calls = append(p.calls, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.ChatCompletionResponse{Model: req.Model}, nil
+}
+
+func (p *stubProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	p.calls = append(p.calls, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return io.NopCloser(strings.NewReader("data: [DONE]\n\n")), nil
+}
+
+func (p *stubProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	p.calls = append(p.calls, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.CompletionResponse{Model: req.Model}, nil
+}
+
+func (p *stubProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	p.calls = append(p.calls, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.EmbeddingResponse{Model: req.Model}, nil
+}
+
+func (p *stubProvider) ListModels() []models.Model { return nil }
+
+func (p *stubProvider) SupportsModel(model string) bool {
+	for _, m := range p.models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *stubProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func upstreamError(name string, status int) error {
+	return &ProviderError{Provider: name, StatusCode: status, Code: "upstream_error", Message: "failed"}
+}
+
+// newFallbackRouter routes gpt-4o to openai, with anthropic and ollama as fallbacks
+func newFallbackRouter(openai, anthropic, ollama *stubProvider) *Router {
+	registry := providers.NewRegistry()
+	registry.Register("openai", openai)
+	registry.Register("anthropic", anthropic)
+	registry.Register("ollama", ollama)
+
+	cfg := &config.Config{}
+	cfg.Fallbacks = config.FallbacksConfig{
+		Enabled: true,
+		Chains: map[string][]string{
+			"gpt-4o": {"anthropic/claude-3-5-sonnet", "ollama/llama3"},
+		},
+	}
+	return NewRouter(registry, cfg)
+}
+
+func TestFallback_UsesNextProviderOnFailure(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: upstreamError("openai", http.StatusServiceUnavailable)}
+	anthropic := &stubProvider{name: "anthropic", err: errors.New("connection refused")}
+	ollama := &stubProvider{name: "ollama"}
+	router := newFallbackRouter(openai, anthropic, ollama)
+
+	ctx := observability.WithAttemptTimeline(context.Background())
+	provider, err := router.GetProviderForRequest(ctx, "gpt-4o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := provider.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("expected the last fallback to serve the request: %v", err)
+	}
+
+	// Each provider is sent its own model name
+	if resp.Model != "llama3" || len(openai.calls) != 1 || anthropic.calls[0] != "claude-3-5-sonnet" {
+		t.Errorf("model = %q, openai calls %v, anthropic calls %v", resp.Model, openai.calls, anthropic.calls)
+	}
+	if provider.Name() != "ollama" {
+		t.Errorf("Name() = %q, want the provider that served the request", provider.Name())
+	}
+	fallback, ok := observability.FallbackUsed(ctx)
+	if !ok || fallback.Provider != "ollama" || fallback.Model != "llama3" {
+		t.Errorf("recorded fallback = %+v, %v", fallback, ok)
+	}
+}
+
+func TestFallback_PrimarySuccess(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}}
+	anthropic := &stubProvider{name: "anthropic"}
+	router := newFallbackRouter(openai, anthropic, &stubProvider{name: "ollama"})
+
+	ctx := observability.WithAttemptTimeline(context.Background())
+	provider, _ := router.GetProviderForRequest(ctx, "gpt-4o")
+	if _, err := provider.Embedding(ctx, &models.EmbeddingRequest{Model: "gpt-4o"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(anthropic.calls) != 0 {
+		t.Error("fallback called although the primary succeeded")
+	}
+	if _, ok := observability.FallbackUsed(ctx); ok {
+		t.Error("no fallback should be recorded")
+	}
+}
+
+func TestFallback_InvalidRequestIsNotRetried(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: upstreamError("openai", http.StatusBadRequest)}
+	anthropic := &stubProvider{name: "anthropic"}
+	router := newFallbackRouter(openai, anthropic, &stubProvider{name: "ollama"})
+
+	provider, _ := router.GetProviderForRequest(context.Background(), "gpt-4o")
+	_, err := provider.ChatCompletionStream(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("err = %v, want the primary's 400", err)
+	}
+	if len(anthropic.calls) != 0 {
+		t.Error("invalid request should not fall back")
+	}
+}
+
+func TestFallback_DrainedPrimary(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}}
+	anthropic := &stubProvider{name: "anthropic"}
+	router := newFallbackRouter(openai, anthropic, &stubProvider{name: "ollama"})
+	if err := router.DrainProvider("openai"); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := router.GetProviderForRequest(context.Background(), "gpt-4o")
+	if err != nil {
+		t.Fatalf("drained primary should fall back: %v", err)
+	}
+	if _, err := provider.Completion(context.Background(), &models.CompletionRequest{Model: "gpt-4o"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(openai.calls) != 0 || len(anthropic.calls) != 1 {
+		t.Errorf("openai calls %v, anthropic calls %v", openai.calls, anthropic.calls)
+	}
+}
+
+func TestFallback_AllFail(t *testing.T) {
+	fail := func(name string) *stubProvider {
+		return &stubProvider{name: name, models: []string{"gpt-4o"}, err: upstreamError(name, http.StatusBadGateway)}
+	}
+	router := newFallbackRouter(fail("openai"), fail("anthropic"), fail("ollama"))
+
+	provider, _ := router.GetProviderForRequest(context.Background(), "gpt-4o")
+	_, err := provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != "ollama" {
+		t.Errorf("err = %v, want the last fallback's error", err)
+	}
+}
+
+func TestParseFallbackTarget(t *testing.T) {
+	router := newFallbackRouter(&stubProvider{name: "openai"}, &stubProvider{name: "anthropic"}, &stubProvider{name: "ollama"})
+
+	tests := []struct {
+		entry string
+		want  fallbackTarget
+	}{
+		{"ollama/llama3", fallbackTarget{provider: "ollama", model: "llama3"}},
+		{"gpt-4o-mini", fallbackTarget{model: "gpt-4o-mini"}},
+		{"meta-llama/Llama-3-8b", fallbackTarget{model: "meta-llama/Llama-3-8b"}},
+	}
+	for _, tt := range tests {
+		if got := router.parseFallbackTarget(tt.entry); got != tt.want {
+			t.Errorf("parseFallbackTarget(%q) = %+v, want %+v", tt.entry, got, tt.want)
+		}
+	}
+}
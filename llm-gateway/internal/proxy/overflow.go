@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// overflowRoutes are the overflow targets by lowercase model name, for all
+// callers and per rate limit tier
+type overflowRoutes struct {
+	routes map[string]fallbackTarget
+	tiers  map[string]map[string]fallbackTarget
+	// disabled are the tiers whose callers never overflow
+	disabled map[string]bool
+}
+
+// initOverflow parses the configured overflow routes. Route keys are
+// lowercase, as configuration keys are.
+func (r *Router) initOverflow() {
+	cfg := r.config.Overflow
+	if !cfg.Enabled {
+		return
+	}
+	parse := func(routes map[string]string) map[string]fallbackTarget {
+		targets := make(map[string]fallbackTarget, len(routes))
+		for model, target := range routes {
+			targets[strings.ToLower(model)] = r.parseFallbackTarget(strings.TrimSpace(target))
+		}
+		return targets
+	}
+
+	r.overflow = &overflowRoutes{
+		routes:   parse(cfg.Routes),
+		tiers:    make(map[string]map[string]fallbackTarget, len(cfg.Tiers)),
+		disabled: make(map[string]bool),
+	}
+	for tier, tierCfg := range cfg.Tiers {
+		r.overflow.tiers[tier] = parse(tierCfg.Routes)
+		r.overflow.disabled[tier] = tierCfg.Disabled
+	}
+	logger.Info().
+		Int("routes", len(cfg.Routes)).
+		Int("tiers", len(cfg.Tiers)).
+		Msg("Overflow to cheaper models enabled")
+}
+
+// overflowTarget returns where model's overflow goes for callers of tier
+func (r *Router) overflowTarget(model, tier string) (fallbackTarget, bool) {
+	if r.overflow == nil || r.overflow.disabled[tier] {
+		return fallbackTarget{}, false
+	}
+	model = strings.ToLower(model)
+	if target, ok := r.overflow.tiers[tier][model]; ok {
+		return target, true
+	}
+	target, ok := r.overflow.routes[model]
+	return target, ok
+}
+
+// WithOverflow wraps the provider selected for model so that requests the
+// gateway's outbound quota would reject with a 429 are served by the model's
+// overflow route for the caller's rate limit tier instead. Models without an
+// overflow route get provider back as is.
+func (r *Router) WithOverflow(provider Provider, model, tier string) Provider {
+	target, ok := r.overflowTarget(model, tier)
+	if !ok {
+		return provider
+	}
+	return &overflowProvider{Provider: provider, router: r, model: model, tier: tier, target: target}
+}
+
+// overflowProvider sends requests to the preferred provider and, when its
+// outbound quota is exhausted, to the overflow target with the request's
+// model name remapped. Embeddings never overflow, as another model's vectors
+// are not comparable. It is created per request; Name reports the provider
+// that served the last call.
+type overflowProvider struct {
+	Provider
+	router *Router
+	model  string
+	tier   string
+	target fallbackTarget
+	served string
+}
+
+// Name returns the provider that served the last call, or the preferred one
+func (o *overflowProvider) Name() string {
+	if o.served != "" {
+		return o.served
+	}
+	return o.Provider.Name()
+}
+
+// quotaExhausted reports whether err is the gateway's outbound quota
+// rejecting a request, rather than a failure of the provider itself
+func quotaExhausted(err error) bool {
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr) && providerErr.Code == "upstream_quota_exceeded"
+}
+
+// callWithOverflow runs call on the preferred provider and, if its outbound
+// quota is exhausted, on the overflow target
+func callWithOverflow[T any](ctx context.Context, o *overflowProvider, call func(Provider, string) (T, error)) (T, error) {
+	result, err := call(o.Provider, o.model)
+	if err == nil || !quotaExhausted(err) || ctx.Err() != nil {
+		return result, err
+	}
+
+	provider, resolveErr := o.router.resolveFallback(o.target)
+	if resolveErr != nil {
+		logger.Warn().Err(resolveErr).Str("model", o.model).Str("overflow_model", o.target.model).Msg("Overflow model unavailable")
+		return result, err
+	}
+	logger.Debug().
+		Str("model", o.model).
+		Str("tier", o.tier).
+		Str("overflow_provider", provider.Name()).
+		Str("overflow_model", o.target.model).
+		Msg("Outbound quota exhausted, serving overflow model")
+
+	result, err = call(provider, o.target.model)
+	if err != nil {
+		return result, err
+	}
+	o.served = provider.Name()
+	observability.RecordFallback(ctx, observability.Fallback{Provider: provider.Name(), Model: o.target.model, Overflow: true})
+	observability.GetMetrics().RecordOverflow(o.model, o.tier, provider.Name(), o.target.model)
+	return result, nil
+}
+
+// ChatCompletion performs a chat completion, overflowing when over quota
+func (o *overflowProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	return callWithOverflow(ctx, o, func(p Provider, model string) (*models.ChatCompletionResponse, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.ChatCompletion(ctx, &remapped)
+	})
+}
+
+// ChatCompletionStream starts a streaming chat completion, overflowing when
+// over quota
+func (o *overflowProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	return callWithOverflow(ctx, o, func(p Provider, model string) (io.ReadCloser, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.ChatCompletionStream(ctx, &remapped)
+	})
+}
+
+// Completion performs a legacy completion, overflowing when over quota
+func (o *overflowProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	return callWithOverflow(ctx, o, func(p Provider, model string) (*models.CompletionResponse, error) {
+		remapped := *req
+		remapped.Model = model
+		return p.Completion(ctx, &remapped)
+	})
+}
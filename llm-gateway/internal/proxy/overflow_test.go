@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func quotaError(name string) error {
+	return &ProviderError{Provider: name, StatusCode: http.StatusTooManyRequests, Code: "upstream_quota_exceeded", Message: "quota exhausted"}
+}
+
+// newOverflowRouter overflows gpt-4o to ollama/llama3, to ollama/llama3:8b
+// for the free tier and not at all for the premium tier
+func newOverflowRouter(openai, ollama *stubProvider) *Router {
+	registry := providers.NewRegistry()
+	registry.Register("openai", openai)
+	registry.Register("ollama", ollama)
+
+	cfg := &config.Config{}
+	cfg.Overflow = config.OverflowConfig{
+		Enabled: true,
+		Routes:  map[string]string{"gpt-4o": "ollama/llama3"},
+		Tiers: map[string]config.OverflowTierConfig{
+			"free":    {Routes: map[string]string{"gpt-4o": "ollama/llama3:8b"}},
+			"premium": {Disabled: true},
+		},
+	}
+	return NewRouter(registry, cfg)
+}
+
+func TestOverflow_ServesCheaperModelOverQuota(t *testing.T) {
+	tests := []struct {
+		tier      string
+		wantModel string
+	}{
+		{"", "llama3"},
+		{"free", "llama3:8b"},
+	}
+	for _, tt := range tests {
+		t.Run("tier "+tt.tier, func(t *testing.T) {
+			openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: quotaError("openai")}
+			ollama := &stubProvider{name: "ollama"}
+			router := newOverflowRouter(openai, ollama)
+
+			ctx := observability.WithAttemptTimeline(context.Background())
+			provider, err := router.GetProviderForRequest(ctx, "gpt-4o")
+			if err != nil {
+				t.Fatal(err)
+			}
+			provider = router.WithOverflow(provider, "gpt-4o", tt.tier)
+			resp, err := provider.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "gpt-4o"})
+			if err != nil {
+				t.Fatalf("expected the overflow model to serve the request: %v", err)
+			}
+			if resp.Model != tt.wantModel || provider.Name() != "ollama" {
+				t.Errorf("served by %s/%s, want ollama/%s", provider.Name(), resp.Model, tt.wantModel)
+			}
+			fallback, ok := observability.FallbackUsed(ctx)
+			if !ok || !fallback.Overflow || fallback.Model != tt.wantModel {
+				t.Errorf("recorded fallback = %+v, %v", fallback, ok)
+			}
+		})
+	}
+}
+
+func TestOverflow_OnlyOnQuotaExhaustion(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: upstreamError("openai", http.StatusTooManyRequests)}
+	ollama := &stubProvider{name: "ollama"}
+	router := newOverflowRouter(openai, ollama)
+
+	provider := router.WithOverflow(openai, "gpt-4o", "")
+	if _, err := provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"}); err == nil {
+		t.Fatal("an upstream 429 should not overflow")
+	}
+	if len(ollama.calls) != 0 {
+		t.Errorf("overflow model called %v", ollama.calls)
+	}
+}
+
+func TestOverflow_DisabledTierGets429(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: quotaError("openai")}
+	ollama := &stubProvider{name: "ollama"}
+	router := newOverflowRouter(openai, ollama)
+
+	provider := router.WithOverflow(openai, "gpt-4o", "premium")
+	_, err := provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("err = %v, want the quota 429", err)
+	}
+	if len(ollama.calls) != 0 {
+		t.Errorf("overflow model called %v", ollama.calls)
+	}
+}
+
+func TestOverflow_EmbeddingsNeverOverflow(t *testing.T) {
+	openai := &stubProvider{name: "openai", models: []string{"gpt-4o"}, err: quotaError("openai")}
+	ollama := &stubProvider{name: "ollama"}
+	router := newOverflowRouter(openai, ollama)
+
+	provider := router.WithOverflow(openai, "gpt-4o", "")
+	if _, err := provider.Embedding(context.Background(), &models.EmbeddingRequest{Model: "gpt-4o"}); err == nil {
+		t.Fatal("embeddings should not overflow to another model")
+	}
+	if len(ollama.calls) != 0 {
+		t.Errorf("overflow model called %v", ollama.calls)
+	}
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+)
+
+// GetProviderOverride returns the provider a debug caller forced for one
+// request. An empty name keeps the provider chosen for model; a non-empty
+// baseURL points that provider at another endpoint. Overridden endpoints
+// bypass resilience wrapping and outbound shaping so a misbehaving test
+// backend cannot trip the real provider's circuit breaker or use its quota.
+func (r *Router) GetProviderOverride(name, baseURL, model string) (Provider, error) {
+	if name == "" {
+		if baseURL == "" {
+			return r.GetProviderForModel(model)
+		}
+		provider, err := r.GetProviderForModel(model)
+		if err != nil {
+			return nil, err
+		}
+		name = provider.Name()
+	}
+	if baseURL == "" {
+		if _, found := r.registry.Get(name); !found {
+			return nil, overrideError(name, "Provider "+name+" is not configured")
+		}
+		return r.GetProvider(name)
+	}
+
+	provider, found := r.registry.Get(name)
+	if !found {
+		return nil, overrideError(name, "Provider "+name+" is not configured")
+	}
+	overrider, ok := provider.(providers.BaseURLOverrider)
+	if !ok {
+		return nil, overrideError(name, "Provider "+name+" does not support base URL overrides")
+	}
+	return &trackedProvider{Provider: overrider.WithBaseURL(baseURL), inFlight: r.drain.counter(name)}, nil
+}
+
+func overrideError(name, message string) error {
+	return &ProviderError{
+		Provider:   name,
+		StatusCode: http.StatusBadRequest,
+		Code:       "invalid_provider_override",
+		Message:    message,
+	}
+}
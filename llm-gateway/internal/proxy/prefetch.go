@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// prefetcher returns the provider's prefetcher, or an error if it has none
+func (r *Router) prefetcher(name string) (providers.Prefetcher, error) {
+	provider, found := r.registry.Get(name)
+	if !found {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusNotFound,
+			Code:       "provider_not_found",
+			Message:    "provider not found: " + name,
+		}
+	}
+
+	prefetcher, ok := provider.(providers.Prefetcher)
+	if !ok {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusBadRequest,
+			Code:       "prefetch_not_supported",
+			Message:    "provider " + name + " does not support model prefetch",
+		}
+	}
+	return prefetcher, nil
+}
+
+// StartPrefetch re-runs the configured model prefetch of a provider in the background
+func (r *Router) StartPrefetch(name string) error {
+	prefetcher, err := r.prefetcher(name)
+	if err != nil {
+		return err
+	}
+	return prefetcher.StartPrefetch()
+}
+
+// PrefetchStatus returns the state of a provider's last model prefetch
+func (r *Router) PrefetchStatus(name string) (map[string]interface{}, error) {
+	prefetcher, err := r.prefetcher(name)
+	if err != nil {
+		return nil, err
+	}
+	return prefetcher.PrefetchStatus(), nil
+}
+
+// PendingProviders returns providers that are still warming up and gate readiness
+func (r *Router) PendingProviders() []string {
+	var pending []string
+	for _, name := range r.registry.List() {
+		provider, _ := r.registry.Get(name)
+		if gate, ok := provider.(providers.ReadinessGate); ok && !gate.Ready() {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// InstanceStatus returns per-replica scheduling state for a provider with multiple instances
+func (r *Router) InstanceStatus(name string) ([]map[string]interface{}, error) {
+	provider, found := r.registry.Get(name)
+	if !found {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusNotFound,
+			Code:       "provider_not_found",
+			Message:    "provider not found: " + name,
+		}
+	}
+
+	reporter, ok := provider.(providers.InstanceReporter)
+	if !ok {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusBadRequest,
+			Code:       "instances_not_supported",
+			Message:    "provider " + name + " does not report instances",
+		}
+	}
+	return reporter.InstanceStatus(), nil
+}
+
+// RefreshModels fetches a provider's model list from upstream now
+func (r *Router) RefreshModels(ctx context.Context, name string) ([]models.Model, error) {
+	provider, found := r.registry.Get(name)
+	if !found {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusNotFound,
+			Code:       "provider_not_found",
+			Message:    "provider not found: " + name,
+		}
+	}
+
+	refresher, ok := provider.(providers.ModelRefresher)
+	if !ok {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusBadRequest,
+			Code:       "model_refresh_not_supported",
+			Message:    "provider " + name + " does not support model refresh",
+		}
+	}
+	list, err := refresher.RefreshModels(ctx)
+	if err != nil {
+		return nil, &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusBadGateway,
+			Code:       "model_refresh_failed",
+			Message:    "model refresh failed: " + err.Error(),
+		}
+	}
+	return list, nil
+}
@@ -10,10 +10,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
-
-	
+	"github.com/username/llm-gateway/internal/ids"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
@@ -23,13 +20,30 @@ type AnthropicConfig struct {
 	BaseURL string
 	Timeout time.Duration
 	Version string // API version (e.g., "2023-06-01")
+
+	// StandbyAPIKey is the standby credential used for blue/green rotation
+	StandbyAPIKey string
+	// FailoverThreshold is the number of consecutive 401s before flipping to the standby key
+	FailoverThreshold int
+
+	// APIKeys are additional keys that share load with the active key
+	APIKeys []string
+	// KeyCooldown is how long a rate-limited key is skipped
+	KeyCooldown time.Duration
+
+	// Regions replace BaseURL with regional base URLs; requests go to the
+	// fastest healthy region and fail over when one degrades
+	Regions []Region
 }
 
 // AnthropicProvider implements the Provider interface for Anthropic
 type AnthropicProvider struct {
-	config     AnthropicConfig
-	httpClient *http.Client
-	models     []models.Model
+	config      AnthropicConfig
+	httpClient  *http.Client
+	models      []models.Model
+	credentials *Credentials
+	keys        *KeyPool
+	regions     *regionSet
 }
 
 // Anthropic model prefixes for routing
@@ -58,16 +72,41 @@ func NewAnthropicProvider(config AnthropicConfig) *AnthropicProvider {
 	if config.Version == "" {
 		config.Version = "2023-06-01"
 	}
+	warnUnsupportedAPIVersion("anthropic", config.Version)
 
 	return &AnthropicProvider{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
-		models: anthropicModels,
+		models:      anthropicModels,
+		credentials: NewCredentials("anthropic", config.APIKey, config.StandbyAPIKey, config.FailoverThreshold),
+		keys:        NewKeyPool("anthropic", config.APIKeys, config.KeyCooldown),
+		regions:     newRegionSet("anthropic", config.Regions),
 	}
 }
 
+// WithBaseURL returns a copy of the provider that sends requests to baseURL.
+// The copy uses the original's keys but keeps its own failover and rate
+// limit state, so the endpoint cannot flip or cool down the original's keys.
+func (p *AnthropicProvider) WithBaseURL(baseURL string) Provider {
+	config := p.config
+	config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return &AnthropicProvider{
+		config:      config,
+		httpClient:  p.httpClient,
+		models:      p.models,
+		credentials: p.credentials.detached(),
+		keys:        p.keys.detached(),
+	}
+}
+
+// InstanceStatus returns breaker, latency and routing information per
+// region, or nothing when no regions are configured
+func (p *AnthropicProvider) InstanceStatus() []map[string]interface{} {
+	return p.regions.status()
+}
+
 // Name returns the provider name
 func (p *AnthropicProvider) Name() string {
 	return "anthropic"
@@ -84,12 +123,15 @@ type anthropicRequest struct {
 	TopK        *int               `json:"top_k,omitempty"`
 	Stream      bool               `json:"stream,omitempty"`
 	StopSeq     []string           `json:"stop_sequences,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
 }
 
 // anthropicMessage represents a message in Anthropic format
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or []anthropicBlock for tool use and results
+	Content interface{} `json:"content"`
 }
 
 // anthropicResponse represents the Anthropic API response format
@@ -107,6 +149,12 @@ type anthropicResponse struct {
 type anthropicContent struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
+	// Citations are set on text blocks citing web search results or documents
+	Citations []anthropicCitation `json:"citations,omitempty"`
+	// ID, Name and Input are set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 type anthropicUsage struct {
@@ -124,14 +172,7 @@ func (p *AnthropicProvider) ChatCompletion(ctx context.Context, req *models.Chat
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/v1/messages", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.do(ctx, p.httpClient, "/v1/messages", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -141,10 +182,15 @@ func (p *AnthropicProvider) ChatCompletion(ctx context.Context, req *models.Chat
 		return nil, p.handleErrorResponse(resp)
 	}
 
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 	var anthropicResp anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+	if err := json.Unmarshal(data, &anthropicResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	anthropicChatSchema.check(p.config.Version, data)
 
 	return p.convertToOpenAIResponse(&anthropicResp, req.Model), nil
 }
@@ -159,16 +205,9 @@ func (p *AnthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/v1/messages", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
 	streamClient := &http.Client{}
 
-	resp, err := streamClient.Do(httpReq)
+	resp, err := p.do(ctx, streamClient, "/v1/messages", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -179,10 +218,8 @@ func (p *AnthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 	}
 
 	// Return a wrapper that converts Anthropic SSE format to OpenAI format
-	return &anthropicStreamConverter{
-		reader: resp.Body,
-		model:  req.Model,
-	}, nil
+	includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+	return newAnthropicStreamConverter(resp.Body, req.Model, includeUsage), nil
 }
 
 // Completion performs a legacy completion (converted to chat format)
@@ -272,28 +309,48 @@ func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
 	return err
 }
 
+// Credentials returns the provider's active/standby API keys
+func (p *AnthropicProvider) Credentials() *Credentials {
+	return p.credentials
+}
+
+// Keys returns the provider's API key pool
+func (p *AnthropicProvider) Keys() *KeyPool {
+	return p.keys
+}
+
+// do sends a POST request, rotating API keys on 401 and 429 responses
+func (p *AnthropicProvider) do(ctx context.Context, client *http.Client, path string, body []byte) (*http.Response, error) {
+	if p.regions != nil {
+		return p.regions.do(ctx, func(baseURL string) (*http.Response, error) {
+			return p.send(ctx, client, baseURL+path, body)
+		})
+	}
+	return p.send(ctx, client, p.config.BaseURL+path, body)
+}
+
+// send sends a POST request to url with the next usable API key
+func (p *AnthropicProvider) send(ctx context.Context, client *http.Client, url string, body []byte) (*http.Response, error) {
+	return doKeyed(client, p.credentials, p.keys, func(key string) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		p.setHeaders(httpReq, key)
+		return httpReq, nil
+	})
+}
+
 // setHeaders sets common headers for Anthropic API requests
-func (p *AnthropicProvider) setHeaders(req *http.Request) {
+func (p *AnthropicProvider) setHeaders(req *http.Request, key string) {
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", p.config.APIKey)
+	req.Header.Set("x-api-key", key)
 	req.Header.Set("anthropic-version", p.config.Version)
 }
 
 // convertToAnthropicRequest converts OpenAI-style request to Anthropic format
 func (p *AnthropicProvider) convertToAnthropicRequest(req *models.ChatCompletionRequest) *anthropicRequest {
-	var messages []anthropicMessage
-	var systemPrompt string
-
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			systemPrompt = msg.Content
-			continue
-		}
-		messages = append(messages, anthropicMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
+	messages, systemPrompt := toAnthropicMessages(req.Messages)
 
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
@@ -308,27 +365,33 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *models.ChatCompletion
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
 		StopSeq:     req.Stop,
+		Tools:       toAnthropicTools(req.Tools),
+		ToolChoice:  toAnthropicToolChoice(req.ToolChoice),
 	}
 }
 
 // convertToOpenAIResponse converts Anthropic response to OpenAI format
 func (p *AnthropicProvider) convertToOpenAIResponse(resp *anthropicResponse, model string) *models.ChatCompletionResponse {
 	content := ""
+	var citations []models.Citation
+	var toolCalls []models.ToolCall
 	for _, c := range resp.Content {
+		if c.Type == "tool_use" {
+			toolCalls = append(toolCalls, toolCallFromAnthropic(c))
+		}
 		if c.Type == "text" {
+			start := len(content)
 			content += c.Text
+			for _, cited := range c.Citations {
+				citation := cited.citation()
+				end := len(content)
+				citation.StartIndex, citation.EndIndex = &start, &end
+				citations = append(citations, citation)
+			}
 		}
 	}
 
-	finishReason := "stop"
-	switch resp.StopReason {
-	case "end_turn":
-		finishReason = "stop"
-	case "max_tokens":
-		finishReason = "length"
-	case "stop_sequence":
-		finishReason = "stop"
-	}
+	finishReason := anthropicFinishReason(resp.StopReason)
 
 	return &models.ChatCompletionResponse{
 		ID:      resp.ID,
@@ -339,10 +402,12 @@ func (p *AnthropicProvider) convertToOpenAIResponse(resp *anthropicResponse, mod
 			{
 				Index: 0,
 				Message: models.ChatMessage{
-					Role:    "assistant",
-					Content: content,
+					Role:      "assistant",
+					Content:   content,
+					ToolCalls: toolCalls,
 				},
 				FinishReason: finishReason,
+				Citations:    citations,
 			},
 		},
 		Usage: models.Usage{
@@ -357,7 +422,7 @@ func (p *AnthropicProvider) convertToOpenAIResponse(resp *anthropicResponse, mod
 func (p *AnthropicProvider) handleErrorResponse(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 
-	log.Error().
+	logger.Error().
 		Int("status", resp.StatusCode).
 		Str("body", string(body)).
 		Msg("Anthropic API error")
@@ -376,6 +441,7 @@ func (p *AnthropicProvider) handleErrorResponse(resp *http.Response) error {
 			StatusCode: resp.StatusCode,
 			Code:       errResp.Error.Type,
 			Message:    errResp.Error.Message,
+			RetryAfter: ParseRetryAfter(resp.Header),
 		}
 	}
 
@@ -384,27 +450,11 @@ func (p *AnthropicProvider) handleErrorResponse(resp *http.Response) error {
 		StatusCode: resp.StatusCode,
 		Code:       "api_error",
 		Message:    fmt.Sprintf("Anthropic API returned status %d", resp.StatusCode),
+		RetryAfter: ParseRetryAfter(resp.Header),
 	}
 }
 
-// anthropicStreamConverter converts Anthropic SSE stream to OpenAI format
-type anthropicStreamConverter struct {
-	reader io.ReadCloser
-	model  string
-	buffer []byte
-}
-
-func (c *anthropicStreamConverter) Read(p []byte) (n int, err error) {
-	// For simplicity, we pass through the Anthropic stream
-	// In a production implementation, you'd convert each event to OpenAI format
-	return c.reader.Read(p)
-}
-
-func (c *anthropicStreamConverter) Close() error {
-	return c.reader.Close()
-}
-
 // generateID creates a unique ID for responses
 func generateID() string {
-	return "chatcmpl-" + uuid.New().String()[:8]
+	return "chatcmpl-" + ids.New()
 }
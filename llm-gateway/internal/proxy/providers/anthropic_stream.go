@@ -0,0 +1,229 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// anthropicStreamEvent holds the fields of the Anthropic stream events the converter uses
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		ID    string         `json:"id"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamConverter converts an Anthropic SSE stream to OpenAI
+// chat.completion.chunk events. Events are converted as they are read:
+// message_start becomes the role chunk, text deltas become content chunks,
+// tool_use blocks become tool_calls deltas carrying the call's index, id and
+// name first and then its arguments as they arrive as partial JSON,
+// citations become chunks with normalized citations, message_delta carries
+// the finish_reason, and message_stop ends the stream with [DONE], preceded by
+// a usage chunk when includeUsage is set.
+type anthropicStreamConverter struct {
+	reader       io.ReadCloser
+	scanner      *bufio.Scanner
+	model        string
+	includeUsage bool
+	buffer       []byte
+	done         bool
+
+	id      string
+	created int64
+	usage   anthropicUsage
+	// toolCalls maps content block indexes to tool call indexes
+	toolCalls map[int]int
+}
+
+func newAnthropicStreamConverter(body io.ReadCloser, model string, includeUsage bool) *anthropicStreamConverter {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &anthropicStreamConverter{
+		reader:       body,
+		scanner:      scanner,
+		model:        model,
+		includeUsage: includeUsage,
+		id:           generateID(),
+		created:      time.Now().Unix(),
+	}
+}
+
+func (c *anthropicStreamConverter) Read(p []byte) (n int, err error) {
+	for len(c.buffer) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if !c.scanner.Scan() {
+			if err := c.scanner.Err(); err != nil {
+				return 0, err
+			}
+			// The upstream closed without message_stop
+			c.done = true
+			return 0, io.EOF
+		}
+		c.convert(c.scanner.Bytes())
+	}
+	n = copy(p, c.buffer)
+	c.buffer = c.buffer[n:]
+	return n, nil
+}
+
+func (c *anthropicStreamConverter) Close() error {
+	return c.reader.Close()
+}
+
+// convert buffers the OpenAI events for one line of the Anthropic stream.
+// Only data lines matter: each carries its event type in the payload.
+func (c *anthropicStreamConverter) convert(line []byte) {
+	payload, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		logger.Error().Err(err).Str("line", string(line)).Msg("Failed to parse Anthropic stream event")
+		return
+	}
+
+	// Citations arrive as their own deltas or on a starting text block
+	if citations := anthropicStreamCitations(payload); len(citations) > 0 {
+		c.write(models.ChatCompletionStreamResponse{
+			ID:      c.id,
+			Object:  "chat.completion.chunk",
+			Created: c.created,
+			Model:   c.model,
+			Choices: []models.ChatCompletionStreamChoice{{Index: 0, Citations: citations}},
+		})
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message.ID != "" {
+			c.id = event.Message.ID
+		}
+		c.usage = event.Message.Usage
+		c.writeChunk(models.ChatMessageDelta{Role: "assistant"}, nil)
+	case "content_block_start":
+		if event.ContentBlock.Type == "tool_use" {
+			if c.toolCalls == nil {
+				c.toolCalls = make(map[int]int)
+			}
+			index := len(c.toolCalls)
+			c.toolCalls[event.Index] = index
+			c.writeChunk(models.ChatMessageDelta{ToolCalls: []models.ToolCall{{
+				Index:    &index,
+				ID:       event.ContentBlock.ID,
+				Type:     "function",
+				Function: models.FunctionCall{Name: event.ContentBlock.Name},
+			}}}, nil)
+		}
+	case "content_block_delta":
+		switch {
+		case event.Delta.Type == "text_delta" && event.Delta.Text != "":
+			c.writeChunk(models.ChatMessageDelta{Content: event.Delta.Text}, nil)
+		case event.Delta.Type == "input_json_delta" && event.Delta.PartialJSON != "":
+			if index, ok := c.toolCalls[event.Index]; ok {
+				c.writeChunk(models.ChatMessageDelta{ToolCalls: []models.ToolCall{{
+					Index:    &index,
+					Function: models.FunctionCall{Arguments: event.Delta.PartialJSON},
+				}}}, nil)
+			}
+		}
+	case "message_delta":
+		// Anthropic reports the cumulative output tokens
+		if event.Usage != nil {
+			c.usage.OutputTokens = event.Usage.OutputTokens
+		}
+		if event.Delta.StopReason != "" {
+			finishReason := anthropicFinishReason(event.Delta.StopReason)
+			c.writeChunk(models.ChatMessageDelta{}, &finishReason)
+		}
+	case "message_stop":
+		if c.includeUsage {
+			c.write(models.ChatCompletionStreamResponse{
+				ID:      c.id,
+				Object:  "chat.completion.chunk",
+				Created: c.created,
+				Model:   c.model,
+				Choices: []models.ChatCompletionStreamChoice{},
+				Usage: &models.Usage{
+					PromptTokens:     c.usage.InputTokens,
+					CompletionTokens: c.usage.OutputTokens,
+					TotalTokens:      c.usage.InputTokens + c.usage.OutputTokens,
+				},
+			})
+		}
+		c.buffer = append(c.buffer, "data: [DONE]\n\n"...)
+		c.done = true
+	case "error":
+		errData, _ := json.Marshal(map[string]interface{}{
+			"error": map[string]string{
+				"type":    event.Error.Type,
+				"message": event.Error.Message,
+			},
+		})
+		c.buffer = fmt.Appendf(c.buffer, "data: %s\n\ndata: [DONE]\n\n", errData)
+		c.done = true
+	}
+}
+
+// writeChunk buffers a chunk with a single choice
+func (c *anthropicStreamConverter) writeChunk(delta models.ChatMessageDelta, finishReason *string) {
+	c.write(models.ChatCompletionStreamResponse{
+		ID:      c.id,
+		Object:  "chat.completion.chunk",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []models.ChatCompletionStreamChoice{
+			{Index: 0, Delta: delta, FinishReason: finishReason},
+		},
+	})
+}
+
+func (c *anthropicStreamConverter) write(chunk models.ChatCompletionStreamResponse) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to marshal stream response")
+		return
+	}
+	c.buffer = fmt.Appendf(c.buffer, "data: %s\n\n", data)
+}
+
+// anthropicFinishReason maps an Anthropic stop_reason to an OpenAI finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		// end_turn and stop_sequence
+		return "stop"
+	}
+}
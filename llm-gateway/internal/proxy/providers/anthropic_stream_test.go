@@ -0,0 +1,124 @@
+package providers
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+const anthropicStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://go.dev","title":"Go"}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":9}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+// readChunks converts stream and returns its chunks and whether it ended with [DONE]
+func readChunks(t *testing.T, stream string, includeUsage bool) ([]models.ChatCompletionStreamResponse, bool) {
+	t.Helper()
+	converter := newAnthropicStreamConverter(io.NopCloser(strings.NewReader(stream)), "claude-3-5-haiku-20241022", includeUsage)
+	out, err := io.ReadAll(converter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []models.ChatCompletionStreamResponse
+	done := false
+	for _, event := range strings.Split(strings.TrimSpace(string(out)), "\n\n") {
+		data, ok := strings.CutPrefix(event, "data: ")
+		if !ok {
+			t.Fatalf("unexpected event %q", event)
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk models.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, done
+}
+
+func TestAnthropicStreamConverter(t *testing.T) {
+	chunks, done := readChunks(t, anthropicStream, true)
+	if !done || len(chunks) != 6 {
+		t.Fatalf("got %d chunks (done %v): %+v", len(chunks), done, chunks)
+	}
+
+	for _, chunk := range chunks {
+		if chunk.ID != "msg_01" || chunk.Object != "chat.completion.chunk" || chunk.Model != "claude-3-5-haiku-20241022" {
+			t.Errorf("chunk = %+v", chunk)
+		}
+	}
+	if chunks[0].Choices[0].Delta.Role != "assistant" {
+		t.Errorf("first chunk = %+v, want the assistant role", chunks[0])
+	}
+	if chunks[1].Choices[0].Delta.Content != "Hello" || chunks[3].Choices[0].Delta.Content != " world" {
+		t.Errorf("content chunks = %+v, %+v", chunks[1], chunks[3])
+	}
+	if c := chunks[2].Choices[0].Citations; len(c) != 1 || c[0].URL != "https://go.dev" {
+		t.Errorf("citation chunk = %+v", chunks[2])
+	}
+	if reason := chunks[4].Choices[0].FinishReason; reason == nil || *reason != "length" {
+		t.Errorf("finish chunk = %+v, want length", chunks[4])
+	}
+	usage := chunks[5].Usage
+	if len(chunks[5].Choices) != 0 || usage == nil || usage.PromptTokens != 25 || usage.CompletionTokens != 9 || usage.TotalTokens != 34 {
+		t.Errorf("usage chunk = %+v", chunks[5])
+	}
+
+	// Without include_usage the stream ends after the finish chunk
+	if chunks, _ := readChunks(t, anthropicStream, false); len(chunks) != 5 || chunks[4].Usage != nil {
+		t.Errorf("got %d chunks without usage", len(chunks))
+	}
+}
+
+func TestAnthropicStreamConverter_Error(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_02","usage":{"input_tokens":3}}}
+
+event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ignored"}}
+
+`
+	converter := newAnthropicStreamConverter(io.NopCloser(strings.NewReader(stream)), "claude-3-5-haiku-20241022", false)
+	out, err := io.ReadAll(converter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `data: {"error":{"message":"Overloaded","type":"overloaded_error"}}`) ||
+		!strings.HasSuffix(string(out), "data: [DONE]\n\n") || strings.Contains(string(out), "ignored") {
+		t.Errorf("output = %s", out)
+	}
+}
@@ -0,0 +1,176 @@
+package providers
+
+import (
+	"encoding/json"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// anthropicBlock is a content block of a request message: text, an image, a
+// tool_use block repeating an assistant's tool call, or the tool_result
+// answering it
+type anthropicBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// Source is set on image blocks
+	Source *anthropicImageSource `json:"source,omitempty"`
+	// ID, Name and Input are set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID and Content are set on tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// anthropicImageSource is the image of an image block: base64 data with its
+// media type, or a URL
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool is a tool definition
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// anthropicToolChoice is "auto", "any", "none", or "tool" with a name
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// emptyToolInput is sent for tool calls without valid JSON arguments
+var emptyToolInput = json.RawMessage(`{}`)
+
+// toAnthropicMessages converts chat messages to Anthropic messages and the
+// system prompt. Assistant tool calls become tool_use blocks; tool messages
+// become tool_result blocks of a user message, consecutive results sharing
+// one message since Anthropic requires roles to alternate.
+func toAnthropicMessages(msgs []models.ChatMessage) ([]anthropicMessage, string) {
+	var messages []anthropicMessage
+	var systemPrompt string
+	lastIsToolResult := false
+
+	for _, msg := range msgs {
+		switch {
+		case msg.Role == "system":
+			systemPrompt = msg.Text()
+			continue
+		case msg.Role == "tool":
+			block := anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Text()}
+			if lastIsToolResult {
+				last := &messages[len(messages)-1]
+				last.Content = append(last.Content.([]anthropicBlock), block)
+			} else {
+				messages = append(messages, anthropicMessage{Role: "user", Content: []anthropicBlock{block}})
+			}
+			lastIsToolResult = true
+			continue
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			blocks := toAnthropicBlocks(msg)
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = emptyToolInput
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+			messages = append(messages, anthropicMessage{Role: "assistant", Content: blocks})
+		case msg.Parts != nil:
+			messages = append(messages, anthropicMessage{Role: msg.Role, Content: toAnthropicBlocks(msg)})
+		default:
+			messages = append(messages, anthropicMessage{Role: msg.Role, Content: msg.Content})
+		}
+		lastIsToolResult = false
+	}
+	return messages, systemPrompt
+}
+
+// toAnthropicBlocks converts a message's text and image parts to text and
+// image blocks. Data URLs become base64 sources, other URLs url sources.
+func toAnthropicBlocks(msg models.ChatMessage) []anthropicBlock {
+	if msg.Parts == nil {
+		if msg.Content == "" {
+			return nil
+		}
+		return []anthropicBlock{{Type: "text", Text: msg.Content}}
+	}
+	var blocks []anthropicBlock
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case models.ContentPartText:
+			if part.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+			}
+		case models.ContentPartImage:
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			// Messages were validated when decoded, so err is nil for data URLs
+			if mediaType, data, ok, err := part.ImageURL.DataURL(); ok && err == nil {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// toAnthropicTools converts OpenAI function tools to Anthropic tools
+func toAnthropicTools(tools []models.Tool) []anthropicTool {
+	var converted []anthropicTool
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			// Anthropic requires a schema, even for tools without arguments
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		converted = append(converted, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	return converted
+}
+
+// toAnthropicToolChoice converts an OpenAI tool_choice: "auto", "none",
+// "required", or {"type": "function", "function": {"name": ...}}
+func toAnthropicToolChoice(choice interface{}) *anthropicToolChoice {
+	switch c := choice.(type) {
+	case string:
+		switch c {
+		case "auto", "none":
+			return &anthropicToolChoice{Type: c}
+		case "required":
+			return &anthropicToolChoice{Type: "any"}
+		}
+	case map[string]interface{}:
+		if function, ok := c["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return &anthropicToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+	return nil
+}
+
+// toolCallFromAnthropic converts a tool_use block of a response
+func toolCallFromAnthropic(c anthropicContent) models.ToolCall {
+	arguments := string(c.Input)
+	if arguments == "" {
+		arguments = "{}"
+	}
+	return models.ToolCall{
+		ID:       c.ID,
+		Type:     "function",
+		Function: models.FunctionCall{Name: c.Name, Arguments: arguments},
+	}
+}
@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// toolConversation is a conversation in which the assistant called two tools
+var toolConversation = []models.ChatMessage{
+	{Role: "system", Content: "Be brief."},
+	{Role: "user", Content: "Weather in Paris and Rome?"},
+	{Role: "assistant", Content: "Checking.", ToolCalls: []models.ToolCall{
+		{ID: "call_1", Type: "function", Function: models.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{ID: "call_2", Type: "function", Function: models.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+	}},
+	{Role: "tool", ToolCallID: "call_1", Content: "18C"},
+	{Role: "tool", ToolCallID: "call_2", Content: "24C"},
+}
+
+func TestConvertToAnthropicRequest_Tools(t *testing.T) {
+	p := NewAnthropicProvider(AnthropicConfig{APIKey: "sk-ant-test"})
+	req := p.convertToAnthropicRequest(&models.ChatCompletionRequest{
+		Model:      "claude-3-5-haiku-20241022",
+		Messages:   toolConversation,
+		Tools:      []models.Tool{{Type: "function", Function: models.Function{Name: "get_weather", Description: "Current weather"}}},
+		ToolChoice: map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "get_weather"}},
+	})
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		System   string `json:"system"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools      []map[string]interface{} `json:"tools"`
+		ToolChoice map[string]string        `json:"tool_choice"`
+	}
+	json.Unmarshal(body, &got)
+
+	if got.System != "Be brief." || len(got.Messages) != 3 {
+		t.Fatalf("request = %s", body)
+	}
+	want := []string{
+		`"Weather in Paris and Rome?"`,
+		`[{"type":"text","text":"Checking."},{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"Paris"}},{"type":"tool_use","id":"call_2","name":"get_weather","input":{"city":"Rome"}}]`,
+		// Consecutive tool results share one user message
+		`[{"type":"tool_result","tool_use_id":"call_1","content":"18C"},{"type":"tool_result","tool_use_id":"call_2","content":"24C"}]`,
+	}
+	for i, role := range []string{"user", "assistant", "user"} {
+		if got.Messages[i].Role != role || string(got.Messages[i].Content) != want[i] {
+			t.Errorf("messages[%d] = %s %s, want %s %s", i, got.Messages[i].Role, got.Messages[i].Content, role, want[i])
+		}
+	}
+	if len(got.Tools) != 1 || got.Tools[0]["name"] != "get_weather" || got.Tools[0]["input_schema"] == nil {
+		t.Errorf("tools = %v", got.Tools)
+	}
+	if got.ToolChoice["type"] != "tool" || got.ToolChoice["name"] != "get_weather" {
+		t.Errorf("tool_choice = %v", got.ToolChoice)
+	}
+}
+
+func TestToAnthropicToolChoice(t *testing.T) {
+	tests := []struct {
+		choice interface{}
+		want   string
+	}{
+		{"auto", "auto"},
+		{"none", "none"},
+		{"required", "any"},
+		{"sometimes", ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got := toAnthropicToolChoice(tt.choice)
+		if (got == nil && tt.want != "") || (got != nil && got.Type != tt.want) {
+			t.Errorf("toAnthropicToolChoice(%v) = %+v, want %q", tt.choice, got, tt.want)
+		}
+	}
+}
+
+func TestConvertToOllamaRequest_Tools(t *testing.T) {
+	p := NewOllamaProvider(OllamaProviderConfig{})
+	req, err := p.convertToOllamaRequest(&models.ChatCompletionRequest{
+		Model:    "llama3",
+		Messages: toolConversation,
+		Tools:    []models.Tool{{Type: "function", Function: models.Function{Name: "get_weather"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Tools) != 1 || len(req.Messages) != 5 {
+		t.Fatalf("request = %+v", req)
+	}
+	calls := req.Messages[2].ToolCalls
+	if len(calls) != 2 || calls[1].Function.Name != "get_weather" || string(calls[1].Function.Arguments) != `{"city":"Rome"}` {
+		t.Errorf("tool_calls = %+v", calls)
+	}
+	if msg := req.Messages[3]; msg.Role != "tool" || msg.Content != "18C" {
+		t.Errorf("tool result = %+v", msg)
+	}
+}
+
+// imageMessage asks about one inline and one remote image
+var imageMessage = models.ChatMessage{Role: "user", Parts: []models.ContentPart{
+	{Type: models.ContentPartText, Text: "Compare these"},
+	{Type: models.ContentPartImage, ImageURL: &models.ImageURL{URL: "data:image/png;base64,aGk="}},
+	{Type: models.ContentPartImage, ImageURL: &models.ImageURL{URL: "https://example.com/b.png"}},
+}}
+
+func TestToAnthropicMessages_Images(t *testing.T) {
+	messages, _ := toAnthropicMessages([]models.ChatMessage{imageMessage})
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"role":"user","content":[{"type":"text","text":"Compare these"},` +
+		`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"aGk="}},` +
+		`{"type":"image","source":{"type":"url","url":"https://example.com/b.png"}}]}]`
+	if string(data) != want {
+		t.Errorf("messages = %s\nwant %s", data, want)
+	}
+}
+
+func TestConvertToOllamaRequest_Images(t *testing.T) {
+	p := NewOllamaProvider(OllamaProviderConfig{})
+	inline := imageMessage
+	inline.Parts = inline.Parts[:2]
+
+	req, err := p.convertToOllamaRequest(&models.ChatCompletionRequest{Model: "llava", Messages: []models.ChatMessage{inline}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := req.Messages[0]; msg.Content != "Compare these" || len(msg.Images) != 1 || msg.Images[0] != "aGk=" {
+		t.Errorf("message = %+v", msg)
+	}
+
+	_, err = p.convertToOllamaRequest(&models.ChatCompletionRequest{Model: "llava", Messages: []models.ChatMessage{imageMessage}})
+	if perr, ok := err.(*ProviderError); !ok || perr.StatusCode != 400 {
+		t.Errorf("remote image error = %v, want a 400 ProviderError", err)
+	}
+}
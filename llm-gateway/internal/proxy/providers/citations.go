@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// openAIAnnotation is an annotation on an OpenAI message or delta; web search
+// models return "url_citation" annotations
+type openAIAnnotation struct {
+	Type        string `json:"type"`
+	URLCitation struct {
+		URL        string `json:"url"`
+		Title      string `json:"title"`
+		StartIndex *int   `json:"start_index"`
+		EndIndex   *int   `json:"end_index"`
+	} `json:"url_citation"`
+}
+
+// openAICitationBody holds the citation fields of an OpenAI-format response or
+// stream chunk. Besides annotations, some OpenAI-compatible search APIs list
+// sources at the top level, as URLs ("citations") or results ("search_results").
+type openAICitationBody struct {
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Annotations []openAIAnnotation `json:"annotations"`
+		} `json:"message"`
+		Delta struct {
+			Annotations []openAIAnnotation `json:"annotations"`
+		} `json:"delta"`
+		// Citations are already normalized, on chunks converted by the gateway
+		Citations []models.Citation `json:"citations"`
+	} `json:"choices"`
+	Citations     []string `json:"citations"`
+	SearchResults []struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"search_results"`
+}
+
+// anthropicCitation is a citation on an Anthropic text block
+type anthropicCitation struct {
+	Type          string `json:"type"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	DocumentTitle string `json:"document_title"`
+	CitedText     string `json:"cited_text"`
+}
+
+// citation converts an Anthropic citation; document citations have no URL
+func (c anthropicCitation) citation() models.Citation {
+	title := c.Title
+	if title == "" {
+		title = c.DocumentTitle
+	}
+	return models.Citation{URL: c.URL, Title: title, CitedText: c.CitedText}
+}
+
+// openAICitations returns the citations of an OpenAI-format response or stream
+// chunk by choice index. Top-level sources belong to the first choice.
+func openAICitations(data []byte) map[int][]models.Citation {
+	var body openAICitationBody
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil
+	}
+
+	citations := make(map[int][]models.Citation)
+	for _, choice := range body.Choices {
+		for _, annotations := range [][]openAIAnnotation{choice.Message.Annotations, choice.Delta.Annotations} {
+			for _, a := range annotations {
+				if a.Type != "url_citation" {
+					continue
+				}
+				citations[choice.Index] = append(citations[choice.Index], models.Citation{
+					URL:        a.URLCitation.URL,
+					Title:      a.URLCitation.Title,
+					StartIndex: a.URLCitation.StartIndex,
+					EndIndex:   a.URLCitation.EndIndex,
+				})
+			}
+		}
+		if len(choice.Citations) > 0 {
+			citations[choice.Index] = append(citations[choice.Index], choice.Citations...)
+		}
+	}
+	if len(body.SearchResults) > 0 {
+		for _, result := range body.SearchResults {
+			citations[0] = append(citations[0], models.Citation{URL: result.URL, Title: result.Title})
+		}
+	} else {
+		for _, url := range body.Citations {
+			citations[0] = append(citations[0], models.Citation{URL: url})
+		}
+	}
+	if len(citations) == 0 {
+		return nil
+	}
+	return citations
+}
+
+// anthropicStreamCitations returns the citations of an Anthropic stream event:
+// citation deltas, or citations on a text block starting complete
+func anthropicStreamCitations(data []byte) []models.Citation {
+	var event struct {
+		Type  string `json:"type"`
+		Delta struct {
+			Type     string            `json:"type"`
+			Citation anthropicCitation `json:"citation"`
+		} `json:"delta"`
+		ContentBlock struct {
+			Citations []anthropicCitation `json:"citations"`
+		} `json:"content_block"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil
+	}
+
+	var citations []models.Citation
+	switch {
+	case event.Type == "content_block_delta" && event.Delta.Type == "citations_delta":
+		citations = append(citations, event.Delta.Citation.citation())
+	case event.Type == "content_block_start":
+		for _, c := range event.ContentBlock.Citations {
+			citations = append(citations, c.citation())
+		}
+	}
+	return citations
+}
+
+// StreamCitations returns the citations carried by one SSE data payload of a
+// provider stream, by choice index. It understands OpenAI-format chunks
+// (annotations, or top-level sources of OpenAI-compatible search APIs) and
+// Anthropic stream events.
+func StreamCitations(data []byte) map[int][]models.Citation {
+	// Most chunks carry none; skip decoding them
+	if !bytes.Contains(data, []byte("citation")) && !bytes.Contains(data, []byte("annotations")) &&
+		!bytes.Contains(data, []byte("search_results")) {
+		return nil
+	}
+	if citations := anthropicStreamCitations(data); len(citations) > 0 {
+		return map[int][]models.Citation{0: citations}
+	}
+	return openAICitations(data)
+}
+
+// attachCitations sets the citations of each choice of resp
+func attachCitations(resp *models.ChatCompletionResponse, citations map[int][]models.Citation) {
+	for i := range resp.Choices {
+		if c, ok := citations[resp.Choices[i].Index]; ok {
+			resp.Choices[i].Citations = c
+		}
+	}
+}
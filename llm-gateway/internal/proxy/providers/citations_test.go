@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"testing"
+)
+
+func TestOpenAICitations(t *testing.T) {
+	body := []byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "Go 1.24 is out.",
+		"annotations": [{"type": "url_citation", "url_citation": {"url": "https://go.dev/blog", "title": "Go Blog", "start_index": 0, "end_index": 15}}]}}]}`)
+	citations := openAICitations(body)
+	if len(citations[0]) != 1 {
+		t.Fatalf("citations = %+v", citations)
+	}
+	c := citations[0][0]
+	if c.URL != "https://go.dev/blog" || c.Title != "Go Blog" || *c.StartIndex != 0 || *c.EndIndex != 15 {
+		t.Errorf("citation = %+v", c)
+	}
+
+	// OpenAI-compatible search APIs list sources at the top level
+	citations = openAICitations([]byte(`{"choices": [{"index": 0, "delta": {"content": "Hi"}}], "citations": ["https://a.example", "https://b.example"]}`))
+	if len(citations[0]) != 2 || citations[0][1].URL != "https://b.example" {
+		t.Errorf("top-level citations = %+v", citations)
+	}
+	citations = openAICitations([]byte(`{"citations": ["https://a.example"], "search_results": [{"url": "https://a.example", "title": "A"}]}`))
+	if len(citations[0]) != 1 || citations[0][0].Title != "A" {
+		t.Errorf("search results = %+v", citations)
+	}
+
+	if citations := openAICitations([]byte(`{"choices": [{"index": 0, "message": {"content": "Hi"}}]}`)); citations != nil {
+		t.Errorf("expected no citations, got %+v", citations)
+	}
+}
+
+func TestStreamCitations_Anthropic(t *testing.T) {
+	event := []byte(`{"type": "content_block_delta", "index": 1, "delta": {"type": "citations_delta",
+		"citation": {"type": "web_search_result_location", "url": "https://go.dev", "title": "Go", "cited_text": "Go is an open source language"}}}`)
+	citations := StreamCitations(event)
+	if len(citations[0]) != 1 || citations[0][0].CitedText != "Go is an open source language" {
+		t.Errorf("citations = %+v", citations)
+	}
+	if citations := StreamCitations([]byte(`{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "Hi"}}`)); citations != nil {
+		t.Errorf("expected no citations, got %+v", citations)
+	}
+}
+
+func TestAnthropicCitationsInResponse(t *testing.T) {
+	p := &AnthropicProvider{}
+	resp := p.convertToOpenAIResponse(&anthropicResponse{
+		Content: []anthropicContent{
+			{Type: "text", Text: "According to the docs, "},
+			{Type: "text", Text: "Go is fast.", Citations: []anthropicCitation{
+				{Type: "web_search_result_location", URL: "https://go.dev", Title: "Go"},
+			}},
+		},
+	}, "claude-3-5-sonnet")
+
+	citations := resp.Choices[0].Citations
+	if len(citations) != 1 || citations[0].URL != "https://go.dev" {
+		t.Fatalf("citations = %+v", citations)
+	}
+	if *citations[0].StartIndex != 23 || *citations[0].EndIndex != 34 {
+		t.Errorf("indexes = %d..%d, want the cited block 23..34", *citations[0].StartIndex, *citations[0].EndIndex)
+	}
+}
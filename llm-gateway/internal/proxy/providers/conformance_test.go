@@ -0,0 +1,365 @@
+package providers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+	"github.com/username/llm-gateway/pkg/providertest"
+)
+
+// writeSSE writes one SSE data event and flushes it
+func writeSSE(w http.ResponseWriter, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+	w.(http.Flusher).Flush()
+}
+
+// splitArguments splits tool call arguments in two, as upstreams stream them
+func splitArguments(arguments string) []string {
+	return []string{arguments[:len(arguments)/2], arguments[len(arguments)/2:]}
+}
+
+func TestConformance_OpenAI(t *testing.T) {
+	providertest.Run(t, providertest.Harness{
+		Model: "gpt-4o",
+		New: func(baseURL string) providertest.Provider {
+			return providers.NewOpenAIProvider(providers.OpenAIConfig{APIKey: "sk-test", BaseURL: baseURL})
+		},
+		Upstream: fakeOpenAI,
+	})
+}
+
+func fakeOpenAI(s providertest.Scenario) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 1 || req.Messages[0].Content != s.Prompt ||
+			(s.ToolCall != nil && (len(req.Tools) != 1 || req.Tools[0].Function.Name != s.ToolCall.Function.Name)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.ErrorStatus != 0 {
+			w.WriteHeader(s.ErrorStatus)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]string{"message": s.ErrorMessage, "type": "invalid_request_error"},
+			})
+			return
+		}
+		usage := models.Usage{PromptTokens: s.PromptTokens, CompletionTokens: s.CompletionTokens, TotalTokens: s.PromptTokens + s.CompletionTokens}
+
+		if !req.Stream {
+			message := models.ChatMessage{Role: "assistant", Content: s.Content()}
+			if s.ToolCall != nil {
+				message = models.ChatMessage{Role: "assistant", ToolCalls: []models.ToolCall{*s.ToolCall}}
+			}
+			json.NewEncoder(w).Encode(models.ChatCompletionResponse{
+				ID: "chatcmpl-1", Object: "chat.completion", Created: 1700000000, Model: req.Model,
+				Choices: []models.ChatCompletionChoice{{Message: message, FinishReason: s.FinishReason}},
+				Usage:   usage,
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta models.ChatMessageDelta, finishReason *string) models.ChatCompletionStreamResponse {
+			return models.ChatCompletionStreamResponse{
+				ID: "chatcmpl-1", Object: "chat.completion.chunk", Created: 1700000000, Model: req.Model,
+				Choices: []models.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}},
+			}
+		}
+		writeSSE(w, "", chunk(models.ChatMessageDelta{Role: "assistant"}, nil))
+		for _, content := range s.Chunks {
+			writeSSE(w, "", chunk(models.ChatMessageDelta{Content: content}, nil))
+		}
+		if call := s.ToolCall; call != nil {
+			index := 0
+			for i, arguments := range splitArguments(call.Function.Arguments) {
+				delta := models.ToolCall{Index: &index, Function: models.FunctionCall{Arguments: arguments}}
+				if i == 0 {
+					delta.ID, delta.Type, delta.Function.Name = call.ID, call.Type, call.Function.Name
+				}
+				writeSSE(w, "", chunk(models.ChatMessageDelta{ToolCalls: []models.ToolCall{delta}}, nil))
+			}
+		}
+		writeSSE(w, "", chunk(models.ChatMessageDelta{}, &s.FinishReason))
+		if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+			writeSSE(w, "", models.ChatCompletionStreamResponse{
+				ID: "chatcmpl-1", Object: "chat.completion.chunk", Created: 1700000000, Model: req.Model,
+				Choices: []models.ChatCompletionStreamChoice{}, Usage: &usage,
+			})
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+}
+
+func TestConformance_Anthropic(t *testing.T) {
+	providertest.Run(t, providertest.Harness{
+		Model: "claude-3-5-haiku-20241022",
+		New: func(baseURL string) providertest.Provider {
+			return providers.NewAnthropicProvider(providers.AnthropicConfig{APIKey: "sk-ant-test", BaseURL: baseURL})
+		},
+		Upstream: fakeAnthropic,
+	})
+}
+
+// anthropicStopReasons maps finish reasons to Anthropic stop reasons
+var anthropicStopReasons = map[string]string{"stop": "end_turn", "length": "max_tokens", "tool_calls": "tool_use"}
+
+func fakeAnthropic(s providertest.Scenario) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			Tools []struct {
+				Name        string          `json:"name"`
+				InputSchema json.RawMessage `json:"input_schema"`
+			} `json:"tools"`
+			MaxTokens int  `json:"max_tokens"`
+			Stream    bool `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v1/messages" || req.MaxTokens == 0 ||
+			len(req.Messages) != 1 || req.Messages[0].Content != s.Prompt ||
+			(s.ToolCall != nil && (len(req.Tools) != 1 || req.Tools[0].Name != s.ToolCall.Function.Name || req.Tools[0].InputSchema == nil)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.ErrorStatus != 0 {
+			w.WriteHeader(s.ErrorStatus)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":  "error",
+				"error": map[string]string{"type": "api_error", "message": s.ErrorMessage},
+			})
+			return
+		}
+		stopReason := anthropicStopReasons[s.FinishReason]
+
+		if !req.Stream {
+			content := []map[string]interface{}{{"type": "text", "text": s.Content()}}
+			if call := s.ToolCall; call != nil {
+				content = []map[string]interface{}{{"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": json.RawMessage(call.Function.Arguments)}}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-5-haiku-20241022",
+				"content":     content,
+				"stop_reason": stopReason,
+				"usage":       map[string]int{"input_tokens": s.PromptTokens, "output_tokens": s.CompletionTokens},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeSSE(w, "message_start", map[string]interface{}{
+			"type":    "message_start",
+			"message": map[string]interface{}{"id": "msg_1", "role": "assistant", "usage": map[string]int{"input_tokens": s.PromptTokens, "output_tokens": 1}},
+		})
+		writeSSE(w, "content_block_start", map[string]interface{}{"type": "content_block_start", "index": 0, "content_block": map[string]string{"type": "text", "text": ""}})
+		writeSSE(w, "ping", map[string]string{"type": "ping"})
+		for _, content := range s.Chunks {
+			writeSSE(w, "content_block_delta", map[string]interface{}{"type": "content_block_delta", "index": 0, "delta": map[string]string{"type": "text_delta", "text": content}})
+		}
+		writeSSE(w, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": 0})
+		if call := s.ToolCall; call != nil {
+			writeSSE(w, "content_block_start", map[string]interface{}{
+				"type": "content_block_start", "index": 1,
+				"content_block": map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": map[string]string{}},
+			})
+			for _, arguments := range splitArguments(call.Function.Arguments) {
+				writeSSE(w, "content_block_delta", map[string]interface{}{"type": "content_block_delta", "index": 1, "delta": map[string]string{"type": "input_json_delta", "partial_json": arguments}})
+			}
+			writeSSE(w, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": 1})
+		}
+		writeSSE(w, "message_delta", map[string]interface{}{
+			"type":  "message_delta",
+			"delta": map[string]string{"stop_reason": stopReason},
+			"usage": map[string]int{"output_tokens": s.CompletionTokens},
+		})
+		writeSSE(w, "message_stop", map[string]string{"type": "message_stop"})
+	})
+}
+
+func TestConformance_Ollama(t *testing.T) {
+	providertest.Run(t, providertest.Harness{
+		Model: "llama3",
+		New: func(baseURL string) providertest.Provider {
+			return providers.NewOllamaProvider(providers.OllamaProviderConfig{BaseURL: baseURL})
+		},
+		Upstream: fakeOllama,
+	})
+}
+
+func fakeOllama(s providertest.Scenario) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			Tools  []models.Tool `json:"tools"`
+			Stream bool          `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/api/chat" ||
+			len(req.Messages) != 1 || req.Messages[0].Content != s.Prompt ||
+			(s.ToolCall != nil && (len(req.Tools) != 1 || req.Tools[0].Function.Name != s.ToolCall.Function.Name)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.ErrorStatus != 0 {
+			w.WriteHeader(s.ErrorStatus)
+			json.NewEncoder(w).Encode(map[string]string{"error": s.ErrorMessage})
+			return
+		}
+		// Ollama has no tool call IDs and sends arguments as an object
+		var toolCalls []map[string]interface{}
+		if call := s.ToolCall; call != nil {
+			toolCalls = []map[string]interface{}{{"function": map[string]interface{}{"name": call.Function.Name, "arguments": json.RawMessage(call.Function.Arguments)}}}
+		}
+		final := map[string]interface{}{
+			"model":             "llama3",
+			"message":           map[string]string{"role": "assistant", "content": ""},
+			"done":              true,
+			"done_reason":       s.FinishReason,
+			"prompt_eval_count": s.PromptTokens,
+			"eval_count":        s.CompletionTokens,
+		}
+
+		if !req.Stream {
+			final["message"] = map[string]interface{}{"role": "assistant", "content": s.Content(), "tool_calls": toolCalls}
+			json.NewEncoder(w).Encode(final)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, content := range s.Chunks {
+			enc.Encode(map[string]interface{}{
+				"model":   "llama3",
+				"message": map[string]string{"role": "assistant", "content": content},
+				"done":    false,
+			})
+			w.(http.Flusher).Flush()
+		}
+		if toolCalls != nil {
+			enc.Encode(map[string]interface{}{
+				"model":   "llama3",
+				"message": map[string]interface{}{"role": "assistant", "content": "", "tool_calls": toolCalls},
+				"done":    false,
+			})
+		}
+		enc.Encode(final)
+	})
+}
+
+func TestConformance_Remote(t *testing.T) {
+	providertest.Run(t, providertest.Harness{
+		Model: "bespoke-1",
+		New: func(baseURL string) providertest.Provider {
+			p, err := providers.NewRemoteProvider(providers.RemoteProviderConfig{
+				Name:    "bespoke",
+				Address: strings.TrimPrefix(baseURL, "http://"),
+				Models:  []string{"bespoke-1"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		Upstream: fakeRemote,
+		HTTP2:    true,
+	})
+}
+
+// sendStruct sends msg as a google.protobuf.Struct
+func sendStruct(stream grpc.ServerStream, msg interface{}) error {
+	payload, _ := json.Marshal(msg)
+	var out structpb.Struct
+	if err := protojson.Unmarshal(payload, &out); err != nil {
+		return err
+	}
+	return stream.SendMsg(&out)
+}
+
+// grpcCodes are the gRPC status codes a remote provider answers the suite's errors with
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+func fakeRemote(s providertest.Scenario) http.Handler {
+	return grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		var in structpb.Struct
+		if err := stream.RecvMsg(&in); err != nil {
+			return err
+		}
+		data, _ := protojson.Marshal(&in)
+		var req models.ChatCompletionRequest
+		if err := json.Unmarshal(data, &req); err != nil ||
+			len(req.Messages) != 1 || req.Messages[0].Content != s.Prompt ||
+			(s.ToolCall != nil && (len(req.Tools) != 1 || req.Tools[0].Function.Name != s.ToolCall.Function.Name)) {
+			return status.Error(codes.InvalidArgument, "unexpected request")
+		}
+		if s.ErrorStatus != 0 {
+			return status.Error(grpcCodes[s.ErrorStatus], s.ErrorMessage)
+		}
+		usage := models.Usage{PromptTokens: s.PromptTokens, CompletionTokens: s.CompletionTokens, TotalTokens: s.PromptTokens + s.CompletionTokens}
+
+		method, _ := grpc.MethodFromServerStream(stream)
+		switch method {
+		case "/llmgateway.remote.v1.RemoteProvider/ChatCompletion":
+			message := models.ChatMessage{Role: "assistant", Content: s.Content()}
+			if s.ToolCall != nil {
+				message = models.ChatMessage{Role: "assistant", ToolCalls: []models.ToolCall{*s.ToolCall}}
+			}
+			return sendStruct(stream, models.ChatCompletionResponse{
+				ID: "chatcmpl-1", Object: "chat.completion", Created: 1700000000, Model: req.Model,
+				Choices: []models.ChatCompletionChoice{{Message: message, FinishReason: s.FinishReason}},
+				Usage:   usage,
+			})
+		case "/llmgateway.remote.v1.RemoteProvider/ChatCompletionStream":
+			chunk := func(delta models.ChatMessageDelta, finishReason *string) models.ChatCompletionStreamResponse {
+				return models.ChatCompletionStreamResponse{
+					ID: "chatcmpl-1", Object: "chat.completion.chunk", Created: 1700000000, Model: req.Model,
+					Choices: []models.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}},
+				}
+			}
+			chunks := []interface{}{chunk(models.ChatMessageDelta{Role: "assistant"}, nil)}
+			for _, content := range s.Chunks {
+				chunks = append(chunks, chunk(models.ChatMessageDelta{Content: content}, nil))
+			}
+			if call := s.ToolCall; call != nil {
+				index := 0
+				chunks = append(chunks, chunk(models.ChatMessageDelta{ToolCalls: []models.ToolCall{{Index: &index, ID: call.ID, Type: call.Type, Function: call.Function}}}, nil))
+			}
+			chunks = append(chunks, chunk(models.ChatMessageDelta{}, &s.FinishReason))
+			if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+				chunks = append(chunks, models.ChatCompletionStreamResponse{
+					ID: "chatcmpl-1", Object: "chat.completion.chunk", Created: 1700000000, Model: req.Model,
+					Choices: []models.ChatCompletionStreamChoice{}, Usage: &usage,
+				})
+			}
+			for _, c := range chunks {
+				if err := sendStruct(stream, c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return status.Error(codes.Unimplemented, "unknown method "+method)
+	}))
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultFailoverThreshold is the number of consecutive 401s before flipping to the standby key
+const defaultFailoverThreshold = 3
+
+// Credentials holds an active and a standby API key for a provider (blue/green rotation)
+type Credentials struct {
+	mu                sync.RWMutex
+	provider          string
+	active            string
+	standby           string
+	failoverThreshold int
+	unauthorized      int
+	flips             int64
+	lastFlip          time.Time
+	lastFlipReason    string
+}
+
+// CredentialRotator is implemented by providers that support credential rotation
+type CredentialRotator interface {
+	Credentials() *Credentials
+}
+
+// NewCredentials creates a credential pair; threshold <= 0 uses the default
+func NewCredentials(provider, active, standby string, threshold int) *Credentials {
+	if threshold <= 0 {
+		threshold = defaultFailoverThreshold
+	}
+	return &Credentials{
+		provider:          provider,
+		active:            active,
+		standby:           standby,
+		failoverThreshold: threshold,
+	}
+}
+
+// detached returns credentials holding only the active key, for a provider
+// copy whose 401s must not flip the original's keys
+func (c *Credentials) detached() *Credentials {
+	return NewCredentials(c.provider, c.Active(), "", c.failoverThreshold)
+}
+
+// Active returns the key currently used for requests
+func (c *Credentials) Active() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active
+}
+
+// SetStandby loads a new key into the standby slot
+func (c *Credentials) SetStandby(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.standby = key
+}
+
+// Rotate makes key the active key, keeping the previous one as standby so the
+// change can be flipped back
+func (c *Credentials) Rotate(key, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key == c.active {
+		return nil
+	}
+	c.standby = key
+	return c.flipLocked(reason)
+}
+
+// Flip atomically swaps the active and standby keys
+func (c *Credentials) Flip() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flipLocked("manual")
+}
+
+func (c *Credentials) flipLocked(reason string) error {
+	if c.standby == "" {
+		return &ProviderError{
+			Provider:   c.provider,
+			StatusCode: http.StatusConflict,
+			Code:       "no_standby_credential",
+			Message:    "no standby credential configured for provider " + c.provider,
+		}
+	}
+
+	c.active, c.standby = c.standby, c.active
+	c.unauthorized = 0
+	c.flips++
+	c.lastFlip = time.Now()
+	c.lastFlipReason = reason
+
+	logger.Warn().
+		Str("provider", c.provider).
+		Str("reason", reason).
+		Str("active", maskKey(c.active)).
+		Msg("Provider credentials flipped")
+	return nil
+}
+
+// Report records the status returned for a request made with key.
+// It reports whether the key in use changed, so the caller can retry once with the new key.
+func (c *Credentials) Report(key string, statusCode int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key != c.active {
+		// A 401 for the previous active key means another request already flipped the credentials;
+		// other keys (e.g. pooled keys) do not affect the active/standby pair
+		return statusCode == http.StatusUnauthorized && key == c.standby
+	}
+
+	if statusCode != http.StatusUnauthorized {
+		c.unauthorized = 0
+		return false
+	}
+
+	c.unauthorized++
+	if c.unauthorized < c.failoverThreshold || c.standby == "" {
+		return false
+	}
+	return c.flipLocked("unauthorized") == nil
+}
+
+// Status returns the credential state without exposing the keys
+func (c *Credentials) Status() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"provider":           c.provider,
+		"active":             maskKey(c.active),
+		"standby":            maskKey(c.standby),
+		"flips":              c.flips,
+		"consecutive_401s":   c.unauthorized,
+		"failover_threshold": c.failoverThreshold,
+	}
+	if !c.lastFlip.IsZero() {
+		status["last_flip"] = c.lastFlip
+		status["last_flip_reason"] = c.lastFlipReason
+	}
+	return status
+}
+
+// maskKey returns a short, non-sensitive fingerprint of an API key
+func maskKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
@@ -0,0 +1,160 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestCredentials_Flip(t *testing.T) {
+	c := NewCredentials("openai", "sk-blue-1234", "sk-green-5678", 0)
+
+	if err := c.Flip(); err != nil {
+		t.Fatalf("Flip() error = %v", err)
+	}
+	if c.Active() != "sk-green-5678" {
+		t.Errorf("Active() = %s, want standby key after flip", c.Active())
+	}
+
+	status := c.Status()
+	if status["active"] != "****5678" || status["standby"] != "****1234" {
+		t.Errorf("status should expose only masked keys: %v", status)
+	}
+}
+
+func TestCredentials_FlipWithoutStandby(t *testing.T) {
+	c := NewCredentials("openai", "sk-blue-1234", "", 0)
+
+	if err := c.Flip(); err == nil {
+		t.Error("Flip() should fail without a standby key")
+	}
+	if c.Active() != "sk-blue-1234" {
+		t.Error("active key should be unchanged")
+	}
+}
+
+func TestCredentials_Rotate(t *testing.T) {
+	c := NewCredentials("openai", "sk-blue-1234", "", 0)
+
+	if err := c.Rotate("sk-green-5678", "control_plane"); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	status := c.Status()
+	if c.Active() != "sk-green-5678" || status["standby"] != "****1234" || status["last_flip_reason"] != "control_plane" {
+		t.Errorf("after rotate: active = %s, status = %v", c.Active(), status)
+	}
+
+	// Rotating to the active key is a no-op
+	c.Rotate("sk-green-5678", "control_plane")
+	if c.Status()["flips"] != int64(1) {
+		t.Errorf("flips = %v, want 1", c.Status()["flips"])
+	}
+}
+
+func TestCredentials_FailoverOnUnauthorized(t *testing.T) {
+	c := NewCredentials("openai", "blue", "green", 2)
+
+	if c.Report("blue", http.StatusUnauthorized) {
+		t.Error("should not flip before reaching the threshold")
+	}
+	if !c.Report("blue", http.StatusUnauthorized) {
+		t.Error("should flip once the threshold is reached")
+	}
+	if c.Active() != "green" {
+		t.Errorf("Active() = %s, want green", c.Active())
+	}
+
+	// A late 401 for the old key asks for a retry without flipping back
+	if !c.Report("blue", http.StatusUnauthorized) {
+		t.Error("stale key should be retried with the new active key")
+	}
+	if c.Active() != "green" {
+		t.Error("stale 401 must not flip the credentials again")
+	}
+}
+
+func TestCredentials_SuccessResetsCounter(t *testing.T) {
+	c := NewCredentials("openai", "blue", "green", 2)
+
+	c.Report("blue", http.StatusUnauthorized)
+	c.Report("blue", http.StatusOK)
+	if c.Report("blue", http.StatusUnauthorized) {
+		t.Error("a success should reset the consecutive 401 count")
+	}
+}
+
+func TestOpenAIProvider_RetriesWithStandbyKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer green" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"invalid key","code":"invalid_api_key"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{
+		APIKey:            "blue",
+		StandbyAPIKey:     "green",
+		BaseURL:           server.URL,
+		FailoverThreshold: 1,
+	})
+
+	resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if resp.ID != "chatcmpl-1" {
+		t.Errorf("ID = %s, want chatcmpl-1", resp.ID)
+	}
+	if p.Credentials().Active() != "green" {
+		t.Error("provider should have failed over to the standby key")
+	}
+}
+
+func TestWithBaseURL_LeavesOriginalKeys(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":{"message":"rejected"}}`))
+	}))
+	defer server.Close()
+
+	originals := []interface {
+		BaseURLOverrider
+		CredentialRotator
+		KeyPooler
+	}{
+		NewOpenAIProvider(OpenAIConfig{APIKey: "blue", StandbyAPIKey: "green", APIKeys: []string{"pooled"}, FailoverThreshold: 1}),
+		NewAnthropicProvider(AnthropicConfig{APIKey: "blue", StandbyAPIKey: "green", APIKeys: []string{"pooled"}, FailoverThreshold: 1}),
+	}
+	req := &models.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.ChatMessage{{Role: "user", Content: "hi"}},
+	}
+	for _, original := range originals {
+		for _, status = range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+			override := original.WithBaseURL(server.URL)
+			if _, err := override.ChatCompletion(context.Background(), req); err == nil {
+				t.Fatalf("%T: override request should fail with %d", original, status)
+			}
+		}
+
+		if status := original.Credentials().Status(); status["flips"] != int64(0) || status["consecutive_401s"] != 0 {
+			t.Errorf("%T: credentials = %v, want them untouched", original, status)
+		}
+		for _, key := range original.Keys().Stats() {
+			if key["requests"] != int64(0) || key["cooling_down"] != false {
+				t.Errorf("%T: pool key = %v, want it untouched", original, key)
+			}
+		}
+	}
+}
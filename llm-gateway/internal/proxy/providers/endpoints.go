@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EndpointUpdater is implemented by providers whose endpoints can be replaced
+// at runtime, e.g. from service discovery
+type EndpointUpdater interface {
+	SetEndpoints(baseURLs []string)
+}
+
+// endpointCooldown is how long an endpoint is skipped after a failed request
+const endpointCooldown = 10 * time.Second
+
+// endpoint is one replica of an HTTP provider
+type endpoint struct {
+	baseURL string
+
+	mu             sync.Mutex
+	unhealthyUntil time.Time
+	lastErr        string
+	failures       int64
+
+	routed int64 // requests routed to this endpoint
+}
+
+// healthy reports whether the endpoint is outside its failure cooldown
+func (e *endpoint) healthy(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return now.After(e.unhealthyUntil)
+}
+
+// fail takes the endpoint out of rotation for endpointCooldown
+func (e *endpoint) fail(reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if time.Now().After(e.unhealthyUntil) {
+		logger.Warn().Str("endpoint", e.baseURL).Str("error", reason).Msg("Provider endpoint failed, skipping it")
+	}
+	e.unhealthyUntil = time.Now().Add(endpointCooldown)
+	e.lastErr = reason
+	e.failures++
+}
+
+// endpointSet spreads requests round-robin over a provider's replicas,
+// skipping replicas that failed recently
+type endpointSet struct {
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	rr        uint64
+}
+
+func newEndpointSet(baseURLs ...string) *endpointSet {
+	s := &endpointSet{}
+	s.set(baseURLs)
+	return s
+}
+
+// pick returns the next healthy endpoint, or the next endpoint if none is healthy
+func (s *endpointSet) pick() *endpoint {
+	s.mu.RLock()
+	endpoints := s.endpoints
+	s.mu.RUnlock()
+
+	now := time.Now()
+	start := atomic.AddUint64(&s.rr, 1)
+	chosen := endpoints[start%uint64(len(endpoints))]
+	for i := uint64(0); i < uint64(len(endpoints)); i++ {
+		if ep := endpoints[(start+i)%uint64(len(endpoints))]; ep.healthy(now) {
+			chosen = ep
+			break
+		}
+	}
+	atomic.AddInt64(&chosen.routed, 1)
+	return chosen
+}
+
+// set replaces the replicas; replicas that remain keep their health state
+func (s *endpointSet) set(baseURLs []string) {
+	if len(baseURLs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := make(map[string]*endpoint, len(s.endpoints))
+	for _, ep := range s.endpoints {
+		existing[ep.baseURL] = ep
+	}
+	endpoints := make([]*endpoint, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		baseURL = strings.TrimSuffix(baseURL, "/")
+		if ep, ok := existing[baseURL]; ok {
+			endpoints = append(endpoints, ep)
+			continue
+		}
+		endpoints = append(endpoints, &endpoint{baseURL: baseURL})
+	}
+	s.endpoints = endpoints
+}
+
+// status returns health and routing information per endpoint
+func (s *endpointSet) status() []map[string]interface{} {
+	s.mu.RLock()
+	endpoints := s.endpoints
+	s.mu.RUnlock()
+
+	now := time.Now()
+	status := make([]map[string]interface{}, 0, len(endpoints))
+	for _, ep := range endpoints {
+		entry := map[string]interface{}{
+			"base_url": ep.baseURL,
+			"healthy":  ep.healthy(now),
+			"routed":   atomic.LoadInt64(&ep.routed),
+		}
+		ep.mu.Lock()
+		entry["failures"] = ep.failures
+		if ep.lastErr != "" {
+			entry["error"] = ep.lastErr
+		}
+		ep.mu.Unlock()
+		status = append(status, entry)
+	}
+	return status
+}
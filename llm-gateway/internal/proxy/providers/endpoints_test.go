@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestEndpointSet_SkipsFailedEndpoints(t *testing.T) {
+	s := newEndpointSet("http://a", "http://b")
+
+	s.endpoints[0].fail("connection refused")
+	for i := 0; i < 4; i++ {
+		if ep := s.pick(); ep.baseURL != "http://b" {
+			t.Fatalf("pick = %s, want the healthy endpoint", ep.baseURL)
+		}
+	}
+
+	// With every endpoint failing, requests still go somewhere
+	s.endpoints[1].fail("connection refused")
+	if ep := s.pick(); ep == nil {
+		t.Fatal("pick returned nil")
+	}
+}
+
+func TestEndpointSet_SetKeepsState(t *testing.T) {
+	s := newEndpointSet("http://a", "http://b")
+	s.endpoints[1].fail("timeout")
+
+	s.set([]string{"http://b/", "http://c"})
+	if len(s.endpoints) != 2 || s.endpoints[0].failures != 1 || s.endpoints[1].baseURL != "http://c" {
+		t.Errorf("endpoints after set: %+v, %+v", s.endpoints[0], s.endpoints[1])
+	}
+
+	// An empty list is ignored rather than leaving no endpoints
+	s.set(nil)
+	if len(s.endpoints) != 2 {
+		t.Errorf("empty update removed endpoints")
+	}
+}
+
+func TestOpenAIProvider_SetEndpoints(t *testing.T) {
+	var hits [2]int
+	newServer := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i]++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+		}))
+	}
+	a, b := newServer(0), newServer(1)
+	defer a.Close()
+	defer b.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{APIKey: "sk-test", BaseURL: "http://unused.invalid"})
+	p.SetEndpoints([]string{a.URL, b.URL})
+
+	req := &models.ChatCompletionRequest{Model: "llama", Messages: []models.ChatMessage{{Role: "user", Content: "hi"}}}
+	for i := 0; i < 4; i++ {
+		if _, err := p.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("ChatCompletion: %v", err)
+		}
+	}
+	if hits[0] != 2 || hits[1] != 2 {
+		t.Errorf("hits = %v, want requests spread across both endpoints", hits)
+	}
+	if status := p.InstanceStatus(); len(status) != 2 {
+		t.Errorf("InstanceStatus = %v", status)
+	}
+}
+
+func TestOllamaProvider_SetEndpoints(t *testing.T) {
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: "http://a:11434"})
+	defer p.Stop()
+
+	p.SetEndpoints([]string{"http://b:11434", "http://c:11434"})
+	instances := p.instanceList()
+	if len(instances) != 2 || p.primaryURL() != "http://b:11434" {
+		t.Errorf("instances = %d, primary = %s", len(instances), p.primaryURL())
+	}
+}
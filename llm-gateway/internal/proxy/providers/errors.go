@@ -1,6 +1,12 @@
 package providers
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // ProviderError represents an error from a provider
 type ProviderError struct {
@@ -8,8 +14,32 @@ type ProviderError struct {
 	StatusCode int
 	Code       string
 	Message    string
+	// RetryAfter is how long the provider asked callers to wait before
+	// retrying, from its Retry-After header (0 if it sent none)
+	RetryAfter time.Duration
 }
 
 func (e *ProviderError) Error() string {
 	return fmt.Sprintf("%s error (%d): %s - %s", e.Provider, e.StatusCode, e.Code, e.Message)
 }
+
+// HTTPStatus returns the status code the provider answered with
+func (e *ProviderError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// ParseRetryAfter returns the wait announced by a Retry-After header, in
+// delay-seconds or as an HTTP date, or 0 if there is none
+func ParseRetryAfter(header http.Header) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
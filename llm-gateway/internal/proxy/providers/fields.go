@@ -0,0 +1,43 @@
+package providers
+
+import "github.com/username/llm-gateway/pkg/models"
+
+// openAIOnlyChatFields returns the JSON names of the fields set on req that
+// only the OpenAI provider forwards; the Anthropic and Ollama translations
+// drop them
+func openAIOnlyChatFields(req *models.ChatCompletionRequest) []string {
+	var fields []string
+	set := func(name string, ok bool) {
+		if ok {
+			fields = append(fields, name)
+		}
+	}
+	set("n", req.N > 1)
+	set("presence_penalty", req.PresencePenalty != 0)
+	set("frequency_penalty", req.FrequencyPenalty != 0)
+	set("logit_bias", len(req.LogitBias) > 0)
+	set("user", req.User != "")
+	set("functions", len(req.Functions) > 0)
+	set("function_call", req.FunctionCall != nil)
+	set("response_format", req.ResponseFormat != nil)
+	set("seed", req.Seed != nil)
+	return fields
+}
+
+// UnsupportedChatFields returns the JSON names of the fields set on req that
+// the named provider drops when translating the request
+func UnsupportedChatFields(provider string, req *models.ChatCompletionRequest) []string {
+	switch provider {
+	case "anthropic":
+		return openAIOnlyChatFields(req)
+	case "ollama":
+		// Ollama takes tools but always lets the model choose
+		fields := openAIOnlyChatFields(req)
+		if req.ToolChoice != nil {
+			fields = append(fields, "tool_choice")
+		}
+		return fields
+	default:
+		return nil
+	}
+}
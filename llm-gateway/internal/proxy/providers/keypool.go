@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// defaultKeyCooldown is how long a rate-limited key is skipped when the provider sends no Retry-After
+const defaultKeyCooldown = 30 * time.Second
+
+// keyState tracks usage and rate-limit state of a single API key
+type keyState struct {
+	requests      int64
+	rateLimited   int64
+	errors        int64
+	cooldownUntil time.Time
+	lastUsed      time.Time
+}
+
+// KeyPool spreads requests over several API keys of one provider and skips keys that were rate limited
+type KeyPool struct {
+	mu       sync.Mutex
+	provider string
+	pool     []string
+	state    map[string]*keyState
+	// order lists keys by index: the pool keys, then keys first used later
+	order    []string
+	next     int
+	cooldown time.Duration
+}
+
+// KeyPooler is implemented by providers that spread load over multiple API keys
+type KeyPooler interface {
+	Keys() *KeyPool
+}
+
+// NewKeyPool creates a key pool; the active credential is always part of the rotation
+func NewKeyPool(provider string, keys []string, cooldown time.Duration) *KeyPool {
+	if cooldown <= 0 {
+		cooldown = defaultKeyCooldown
+	}
+
+	p := &KeyPool{
+		provider: provider,
+		state:    make(map[string]*keyState),
+		cooldown: cooldown,
+	}
+	for _, key := range keys {
+		if key != "" && p.state[key] == nil {
+			p.pool = append(p.pool, key)
+			p.order = append(p.order, key)
+			p.state[key] = &keyState{}
+		}
+	}
+	return p
+}
+
+// detached returns a pool of the same keys with its own state, for a provider
+// copy whose 429s must not cool down the original's keys
+func (p *KeyPool) detached() *KeyPool {
+	return NewKeyPool(p.provider, p.pool, p.cooldown)
+}
+
+// candidates returns the active key followed by the pool keys, without duplicates
+func (p *KeyPool) candidates(active string) []string {
+	keys := make([]string, 0, len(p.pool)+1)
+	if active != "" {
+		keys = append(keys, active)
+	}
+	for _, key := range p.pool {
+		if key != active {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Pick returns the next key to use, skipping keys in exclude and preferring keys that are not cooling down.
+// It reports false when every key has been excluded.
+func (p *KeyPool) Pick(active string, exclude map[string]bool) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := p.candidates(active)
+	if len(keys) == 0 {
+		return "", false
+	}
+
+	now := time.Now()
+	var fallback string
+	var fallbackUntil time.Time
+
+	for i := 0; i < len(keys); i++ {
+		key := keys[(p.next+i)%len(keys)]
+		if exclude[key] {
+			continue
+		}
+
+		st := p.stateLocked(key)
+		if now.Before(st.cooldownUntil) {
+			// Remember the key that becomes available first in case all are cooling down
+			if fallback == "" || st.cooldownUntil.Before(fallbackUntil) {
+				fallback, fallbackUntil = key, st.cooldownUntil
+			}
+			continue
+		}
+
+		p.next = (p.next + i + 1) % len(keys)
+		return key, true
+	}
+
+	return fallback, fallback != ""
+}
+
+// Available reports whether a key outside exclude is ready to take a request
+func (p *KeyPool) Available(active string, exclude map[string]bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range p.candidates(active) {
+		if !exclude[key] && !now.Before(p.stateLocked(key).cooldownUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report records the response received for a request made with key
+func (p *KeyPool) Report(key string, resp *http.Response) {
+	p.mu.Lock()
+	st := p.stateLocked(key)
+	st.requests++
+	st.lastUsed = time.Now()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		st.rateLimited++
+		st.cooldownUntil = time.Now().Add(p.retryAfter(resp))
+		logger.Warn().
+			Str("provider", p.provider).
+			Str("key", maskKey(key)).
+			Time("cooldown_until", st.cooldownUntil).
+			Msg("Provider API key rate limited")
+	case resp.StatusCode >= 400:
+		st.errors++
+	}
+	p.mu.Unlock()
+
+	observability.GetMetrics().RecordProviderKeyRequest(p.provider, maskKey(key), resp.StatusCode)
+}
+
+// retryAfter returns the cooldown announced by the provider, or the pool default
+func (p *KeyPool) retryAfter(resp *http.Response) time.Duration {
+	if wait := ParseRetryAfter(resp.Header); wait > 0 {
+		return wait
+	}
+	return p.cooldown
+}
+
+func (p *KeyPool) stateLocked(key string) *keyState {
+	st, ok := p.state[key]
+	if !ok {
+		st = &keyState{}
+		p.state[key] = st
+		p.order = append(p.order, key)
+	}
+	return st
+}
+
+// Stats returns per-key usage with masked keys, ordered by key index
+func (p *KeyPool) Stats() []map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]map[string]interface{}, 0, len(p.order))
+	for i, key := range p.order {
+		st := p.state[key]
+		entry := map[string]interface{}{
+			"index":        i,
+			"key":          maskKey(key),
+			"requests":     st.requests,
+			"rate_limited": st.rateLimited,
+			"errors":       st.errors,
+			"cooling_down": now.Before(st.cooldownUntil),
+		}
+		if !st.lastUsed.IsZero() {
+			entry["last_used"] = st.lastUsed
+		}
+		stats = append(stats, entry)
+	}
+	return stats
+}
+
+// doKeyed sends a request built by newRequest, failing over between credentials on 401
+// and retrying on another pooled key when the provider answers 429
+func doKeyed(client *http.Client, creds *Credentials, keys *KeyPool, newRequest func(key string) (*http.Request, error)) (*http.Response, error) {
+	tried := make(map[string]bool)
+	authRetried := false
+
+	for {
+		key, ok := keys.Pick(creds.Active(), tried)
+		if !ok {
+			key = creds.Active()
+		}
+		tried[key] = true
+
+		req, err := newRequest(key)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		keys.Report(key, resp)
+		observability.RecordUpstreamRequestID(req.Context(), upstreamRequestID(resp.Header))
+
+		switch {
+		case creds.Report(key, resp.StatusCode) && !authRetried:
+			// Credentials flipped: retry once with the new active key
+			authRetried = true
+			delete(tried, creds.Active())
+		case resp.StatusCode == http.StatusTooManyRequests && keys.Available(creds.Active(), tried):
+			logger.Debug().Str("provider", keys.provider).Msg("Retrying rate-limited request on another API key")
+		default:
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// upstreamRequestID returns the provider's request ID for a response: OpenAI
+// sends it as x-request-id, Anthropic as request-id
+func upstreamRequestID(header http.Header) string {
+	if id := header.Get("X-Request-Id"); id != "" {
+		return id
+	}
+	return header.Get("Request-Id")
+}
@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestKeyPool_PickRoundRobin(t *testing.T) {
+	p := NewKeyPool("openai", []string{"key-b", "key-c"}, 0)
+
+	seen := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		key, ok := p.Pick("key-a", nil)
+		if !ok {
+			t.Fatal("Pick() should return a key")
+		}
+		seen[key]++
+	}
+
+	for _, key := range []string{"key-a", "key-b", "key-c"} {
+		if seen[key] != 2 {
+			t.Errorf("key %s picked %d times, want 2", key, seen[key])
+		}
+	}
+}
+
+func TestKeyPool_SkipsRateLimitedKey(t *testing.T) {
+	p := NewKeyPool("openai", []string{"key-b"}, time.Minute)
+
+	p.Report("key-a", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+
+	for i := 0; i < 3; i++ {
+		if key, _ := p.Pick("key-a", nil); key != "key-b" {
+			t.Errorf("Pick() = %s, want key-b while key-a cools down", key)
+		}
+	}
+	if p.Available("key-a", map[string]bool{"key-b": true}) {
+		t.Error("no key should be available when the only ready key is excluded")
+	}
+}
+
+func TestKeyPool_RetryAfterHeader(t *testing.T) {
+	p := NewKeyPool("openai", nil, time.Hour)
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"1"}}}
+	if got := p.retryAfter(resp); got != time.Second {
+		t.Errorf("retryAfter() = %v, want 1s", got)
+	}
+}
+
+func TestKeyPool_PickAllExcluded(t *testing.T) {
+	p := NewKeyPool("openai", nil, 0)
+
+	if _, ok := p.Pick("key-a", map[string]bool{"key-a": true}); ok {
+		t.Error("Pick() should fail when every key is excluded")
+	}
+}
+
+func TestKeyPool_StatsOrderedByIndex(t *testing.T) {
+	keys := []string{"sk-pool-0001", "sk-pool-0002", "sk-pool-0003", "sk-pool-0004", "sk-pool-0005"}
+	p := NewKeyPool("openai", keys, 0)
+	p.Report("sk-active-0009", &http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+
+	// Map iteration order would shuffle these between calls
+	for i := 0; i < 10; i++ {
+		stats := p.Stats()
+		if len(stats) != 6 {
+			t.Fatalf("stats = %v, want 6 keys", stats)
+		}
+		for j, want := range []string{"****0001", "****0002", "****0003", "****0004", "****0005", "****0009"} {
+			if stats[j]["index"] != j || stats[j]["key"] != want {
+				t.Fatalf("stats[%d] = %v, want %s", j, stats[j], want)
+			}
+		}
+	}
+}
+
+func TestOpenAIProvider_RetriesOnAlternateKey(t *testing.T) {
+	var mu sync.Mutex
+	used := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		used[auth]++
+		mu.Unlock()
+
+		if auth == "Bearer limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"rate limited","code":"rate_limit_exceeded"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{
+		APIKey:  "limited",
+		APIKeys: []string{"spare"},
+		BaseURL: server.URL,
+	})
+
+	req := &models.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.ChatMessage{{Role: "user", Content: "hi"}},
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := p.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if used["Bearer limited"] != 1 {
+		t.Errorf("rate-limited key used %d times, want 1 before cooling down", used["Bearer limited"])
+	}
+	if used["Bearer spare"] != 3 {
+		t.Errorf("spare key used %d times, want 3", used["Bearer spare"])
+	}
+}
+
+func TestDoKeyed_RecordsUpstreamRequestID(t *testing.T) {
+	for _, header := range []string{"x-request-id", "request-id"} {
+		t.Run(header, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(header, "req_upstream")
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer server.Close()
+
+			ctx := observability.WithUpstreamIDs(context.Background())
+			resp, err := doKeyed(server.Client(), NewCredentials("openai", "key", "", 0), NewKeyPool("openai", nil, 0), func(key string) (*http.Request, error) {
+				return http.NewRequestWithContext(ctx, "POST", server.URL, nil)
+			})
+			if err != nil {
+				t.Fatalf("doKeyed() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if got := observability.UpstreamRequestID(ctx); got != "req_upstream" {
+				t.Errorf("UpstreamRequestID() = %q, want req_upstream", got)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"30", 30 * time.Second, 30 * time.Second},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		if got := ParseRetryAfter(header); got < tt.min || got > tt.max {
+			t.Errorf("ParseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
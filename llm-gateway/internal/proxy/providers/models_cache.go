@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// DefaultModelCacheTTL is how long fetched model lists and the registry's
+// model-to-provider resolutions are reused
+const DefaultModelCacheTTL = time.Minute
+
+// maxResolvedModels bounds the registry's resolution cache, since model names
+// come from clients
+const maxResolvedModels = 4096
+
+// ModelInvalidator is implemented by providers that cache a model list fetched
+// from upstream
+type ModelInvalidator interface {
+	// InvalidateModels drops the cached list, e.g. after service discovery
+	// replaced the provider's endpoints
+	InvalidateModels()
+}
+
+// ModelRefresher is implemented by providers whose model list can be
+// fetched from upstream on demand
+type ModelRefresher interface {
+	// RefreshModels fetches the model list now and caches it
+	RefreshModels(ctx context.Context) ([]models.Model, error)
+}
+
+// modelCache holds a provider's fetched model list with a set of lowercase
+// IDs, so SupportsModel is a map lookup instead of an upstream request
+type modelCache struct {
+	mu         sync.Mutex
+	models     []models.Model
+	ids        map[string]struct{}
+	fetched    time.Time
+	refreshing bool
+	// gen is bumped by invalidate so a refresh that raced it is discarded
+	gen uint64
+	// onInvalidate is called after invalidate; the registry uses it to forget
+	// the models it resolved to this provider
+	onInvalidate func()
+}
+
+// get returns the cached models, calling fetch when they are missing or
+// older than ttl. While one caller refreshes stale models, others keep using
+// the stale ones rather than queueing behind the upstream request.
+func (c *modelCache) get(ttl time.Duration, fetch func() []models.Model) ([]models.Model, map[string]struct{}) {
+	c.mu.Lock()
+	if c.ids != nil && (time.Since(c.fetched) < ttl || c.refreshing) {
+		defer c.mu.Unlock()
+		return c.models, c.ids
+	}
+	c.refreshing = true
+	gen := c.gen
+	c.mu.Unlock()
+
+	list := fetch()
+	ids := make(map[string]struct{}, len(list))
+	for _, m := range list {
+		ids[strings.ToLower(m.ID)] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.models, c.ids, c.fetched = list, ids, time.Now()
+		c.refreshing = false
+	}
+	return list, ids
+}
+
+// peek returns the cached models without fetching, and whether there are any
+func (c *modelCache) peek() ([]models.Model, map[string]struct{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.models, c.ids, c.ids != nil
+}
+
+// set replaces the cached models. If the set of IDs changed, the
+// invalidation hook is called so the registry resolves models again.
+func (c *modelCache) set(list []models.Model) {
+	ids := make(map[string]struct{}, len(list))
+	for _, m := range list {
+		ids[strings.ToLower(m.ID)] = struct{}{}
+	}
+
+	c.mu.Lock()
+	changed := c.ids == nil || !sameIDs(c.ids, ids)
+	c.models, c.ids, c.fetched = list, ids, time.Now()
+	c.refreshing = false
+	c.gen++
+	onInvalidate := c.onInvalidate
+	c.mu.Unlock()
+
+	if changed && onInvalidate != nil {
+		onInvalidate()
+	}
+}
+
+func sameIDs(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidate drops the cached models so the next get fetches them again
+func (c *modelCache) invalidate() {
+	c.mu.Lock()
+	c.models, c.ids = nil, nil
+	c.refreshing = false
+	c.gen++
+	onInvalidate := c.onInvalidate
+	c.mu.Unlock()
+
+	if onInvalidate != nil {
+		onInvalidate()
+	}
+}
+
+// setOnInvalidate registers the registry's invalidation hook
+func (c *modelCache) setOnInvalidate(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onInvalidate = fn
+}
+
+// modelCacheOwner is implemented by providers holding a modelCache, so the
+// registry can hook its invalidation
+type modelCacheOwner interface {
+	cachedModels() *modelCache
+}
@@ -0,0 +1,213 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tagsServer returns an Ollama stub whose /api/tags lists the given models,
+// counting the requests it serves
+func tagsServer(requests *int64, names ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt64(requests, 1)
+		body := `{"models":[`
+		for i, name := range names {
+			if i > 0 {
+				body += ","
+			}
+			body += `{"name":"` + name + `"}`
+		}
+		w.Write([]byte(body + `]}`))
+	}))
+}
+
+func TestOllamaSupportsModel_CachesModelList(t *testing.T) {
+	var requests int64
+	srv := tagsServer(&requests, "custom-model:7b")
+	defer srv.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: srv.URL})
+	for i := 0; i < 5; i++ {
+		if !p.SupportsModel("Custom-Model:7b") {
+			t.Fatal("model listed by /api/tags should be supported")
+		}
+		if p.SupportsModel("unknown-model") {
+			t.Fatal("unlisted model should not be supported")
+		}
+	}
+	if requests != 1 {
+		t.Errorf("/api/tags requests = %d, want 1", requests)
+	}
+
+	p.InvalidateModels()
+	p.SupportsModel("custom-model:7b")
+	if requests != 2 {
+		t.Errorf("/api/tags requests after invalidation = %d, want 2", requests)
+	}
+}
+
+func TestOllamaSetEndpoints_InvalidatesModels(t *testing.T) {
+	var oldRequests, newRequests int64
+	old := tagsServer(&oldRequests, "old-model")
+	defer old.Close()
+	replacement := tagsServer(&newRequests, "new-model")
+	defer replacement.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: old.URL})
+	registry := NewRegistry()
+	registry.Register("ollama", p)
+
+	if _, ok := registry.GetForModel("new-model"); ok {
+		t.Fatal("new-model is not served yet")
+	}
+	p.SetEndpoints([]string{replacement.URL})
+
+	// The negative resolution is forgotten along with the model list
+	if provider, ok := registry.GetForModel("new-model"); !ok || provider != p {
+		t.Errorf("GetForModel(new-model) = %v, %v after endpoints changed", provider, ok)
+	}
+	if newRequests != 1 {
+		t.Errorf("/api/tags requests to the new endpoint = %d, want 1", newRequests)
+	}
+}
+
+func TestOllamaModelRefresh_LookupsServedFromMemory(t *testing.T) {
+	var requests int64
+	var names atomic.Value
+	names.Store("custom-model:7b")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Write([]byte(`{"models":[{"name":"` + strings.ReplaceAll(names.Load().(string), ",", `"},{"name":"`) + `"}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: srv.URL, ModelRefreshInterval: time.Hour})
+	defer p.Stop()
+	registry := NewRegistry()
+	registry.Register("ollama", p)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !p.SupportsModel("custom-model:7b") {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not load the model list")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		p.SupportsModel("custom-model:7b")
+		p.ListModels()
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("/api/tags requests = %d, want 1", n)
+	}
+
+	// A manual refresh picks up a pulled model and forgets the resolution
+	if _, ok := registry.GetForModel("embed-model"); ok {
+		t.Fatal("embed-model is not pulled yet")
+	}
+	names.Store("custom-model:7b,embed-model")
+	list, err := p.RefreshModels(context.Background())
+	if err != nil || len(list) != 2 {
+		t.Fatalf("RefreshModels() = %v, %v", list, err)
+	}
+	if provider, ok := registry.GetForModel("embed-model"); !ok || provider != p {
+		t.Errorf("GetForModel(embed-model) = %v, %v after refresh", provider, ok)
+	}
+}
+
+func TestOllamaModelRefresh_OnlyLeaderRefreshes(t *testing.T) {
+	var requests int64
+	srv := tagsServer(&requests, "custom-model:7b")
+	defer srv.Close()
+
+	var leader atomic.Bool
+	p := NewOllamaProvider(OllamaProviderConfig{
+		BaseURL:              srv.URL,
+		ModelRefreshInterval: 20 * time.Millisecond,
+		ModelCacheTTL:        time.Hour,
+		RefreshGate:          leader.Load,
+	})
+	defer p.Stop()
+
+	// A follower does not poll in the background...
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt64(&requests); n != 0 {
+		t.Fatalf("/api/tags requests by a follower = %d, want 0", n)
+	}
+	// ...but looks models up on demand, once per TTL
+	for i := 0; i < 3; i++ {
+		if !p.SupportsModel("custom-model:7b") {
+			t.Fatal("follower should look up the model list")
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("/api/tags requests after follower lookups = %d, want 1", n)
+	}
+
+	leader.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt64(&requests) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("leader did not refresh in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countingModelProvider supports models with a fixed name, counting lookups
+type countingModelProvider struct {
+	Provider
+	name    string
+	model   string
+	lookups int64
+}
+
+func (p *countingModelProvider) Name() string { return p.name }
+
+func (p *countingModelProvider) SupportsModel(model string) bool {
+	atomic.AddInt64(&p.lookups, 1)
+	return model == p.model
+}
+
+func TestRegistryGetForModel_CachesResolution(t *testing.T) {
+	a := &countingModelProvider{name: "a", model: "model-a"}
+	b := &countingModelProvider{name: "b", model: "model-b"}
+	registry := NewRegistry()
+	registry.Register("a", a)
+	registry.Register("b", b)
+
+	for i := 0; i < 3; i++ {
+		if provider, ok := registry.GetForModel("model-b"); !ok || provider != b {
+			t.Fatalf("GetForModel(model-b) = %v, %v", provider, ok)
+		}
+		if _, ok := registry.GetForModel("missing"); ok {
+			t.Fatal("GetForModel(missing) should find nothing")
+		}
+	}
+	// Each model is resolved once; providers are asked in name order
+	if a.lookups != 2 || b.lookups != 2 {
+		t.Errorf("lookups = %d, %d, want 2 each", a.lookups, b.lookups)
+	}
+
+	registry.InvalidateModels("")
+	registry.GetForModel("model-b")
+	if b.lookups != 3 {
+		t.Errorf("lookups after invalidation = %d, want 3", b.lookups)
+	}
+
+	registry.SetModelCacheTTL(0)
+	registry.GetForModel("model-b")
+	registry.GetForModel("model-b")
+	if b.lookups != 5 {
+		t.Errorf("lookups without caching = %d, want 5", b.lookups)
+	}
+}
@@ -9,11 +9,11 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
-
+	"github.com/username/llm-gateway/internal/ids"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
@@ -21,6 +21,35 @@ import (
 type OllamaProviderConfig struct {
 	BaseURL string
 	Timeout time.Duration
+
+	// PrefetchModels are checked against /api/tags at startup
+	PrefetchModels []string
+	// AutoPull pulls prefetch models that are missing locally
+	AutoPull bool
+	// PullTimeout bounds the whole prefetch
+	PullTimeout time.Duration
+	// PullGate, if set, must return true for AutoPull to pull at startup; with
+	// several gateways sharing replicas, only the elected leader pulls
+	PullGate func() bool
+
+	// Instances are additional Ollama replicas; requests prefer the replica with the model loaded
+	Instances []string
+	// PollInterval is how often /api/ps is polled on each instance
+	PollInterval time.Duration
+	// ModelCacheTTL is how long the /api/tags model list is reused
+	ModelCacheTTL time.Duration
+	// ModelRefreshInterval, if set, refreshes the model list in the
+	// background, so model lookups never wait on /api/tags
+	ModelRefreshInterval time.Duration
+	// RefreshGate, if set, must return true for the background refresh to
+	// run; with several gateways sharing Ollama, only the elected leader
+	// polls, and the others look models up at most once per ModelCacheTTL
+	RefreshGate func() bool
+
+	// EmbedBatchSize is the number of inputs sent to /api/embed at once
+	EmbedBatchSize int
+	// EmbedConcurrency bounds the embedding calls one request makes at a time
+	EmbedConcurrency int
 }
 
 // OllamaProvider implements the Provider interface for Ollama
@@ -28,6 +57,18 @@ type OllamaProvider struct {
 	config     OllamaProviderConfig
 	httpClient *http.Client
 	models     []models.Model
+	prefetch   prefetchState
+	instances  []*ollamaInstance
+	instMu     sync.RWMutex // guards instances, which service discovery may replace
+	rr         uint64
+	stopPoll   chan struct{}
+	modelCache modelCache
+	// embedLegacy is set once an instance turned out not to have /api/embed
+	embedLegacy atomic.Bool
+	// stopRefresh stops the background model refresh
+	stopRefresh chan struct{}
+	// refreshNow wakes the background model refresh early
+	refreshNow chan struct{}
 }
 
 // Ollama model prefixes for routing
@@ -69,11 +110,14 @@ type ollamaChatRequest struct {
 	Messages []ollamaChatMessage   `json:"messages"`
 	Stream   bool                  `json:"stream"`
 	Options  *ollamaOptions        `json:"options,omitempty"`
+	Tools    []models.Tool         `json:"tools,omitempty"`
 }
 
 type ollamaChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
 }
 
 type ollamaOptions struct {
@@ -89,6 +133,8 @@ type ollamaChatResponse struct {
 	CreatedAt          string            `json:"created_at"`
 	Message            ollamaChatMessage `json:"message"`
 	Done               bool              `json:"done"`
+	// DoneReason is why generation stopped: "stop", or "length" at num_predict
+	DoneReason         string            `json:"done_reason,omitempty"`
 	TotalDuration      int64             `json:"total_duration,omitempty"`
 	LoadDuration       int64             `json:"load_duration,omitempty"`
 	PromptEvalCount    int               `json:"prompt_eval_count,omitempty"`
@@ -141,13 +187,62 @@ func NewOllamaProvider(config OllamaProviderConfig) *OllamaProvider {
 	if config.Timeout == 0 {
 		config.Timeout = 120 * time.Second // Longer timeout for local inference
 	}
+	if config.PullTimeout == 0 {
+		config.PullTimeout = 30 * time.Minute
+	}
 
-	return &OllamaProvider{
+	if config.PollInterval == 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.ModelCacheTTL == 0 {
+		config.ModelCacheTTL = DefaultModelCacheTTL
+	}
+	if config.EmbedBatchSize <= 0 {
+		config.EmbedBatchSize = 64
+	}
+	if config.EmbedConcurrency <= 0 {
+		config.EmbedConcurrency = 4
+	}
+
+	p := &OllamaProvider{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
-		models: defaultOllamaModels,
+		models:    defaultOllamaModels,
+		instances: []*ollamaInstance{newOllamaInstance(config.BaseURL)},
+	}
+	for _, baseURL := range config.Instances {
+		p.instances = append(p.instances, newOllamaInstance(baseURL))
+	}
+
+	// Track loaded models only when there is a choice of replica
+	if len(p.instances) > 1 {
+		p.stopPoll = make(chan struct{})
+		go p.schedulerLoop()
+	}
+
+	if config.ModelRefreshInterval > 0 {
+		p.stopRefresh = make(chan struct{})
+		p.refreshNow = make(chan struct{}, 1)
+		go p.modelRefreshLoop()
+	}
+
+	return p
+}
+
+// WithBaseURL returns a copy of the provider that sends all requests to a
+// single instance at baseURL
+func (p *OllamaProvider) WithBaseURL(baseURL string) Provider {
+	config := p.config
+	config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	config.Instances = nil
+	config.ModelRefreshInterval = 0
+	return &OllamaProvider{
+		config:     config,
+		httpClient: p.httpClient,
+		models:     p.models,
+		instances:  []*ollamaInstance{newOllamaInstance(config.BaseURL)},
 	}
 }
 
@@ -159,7 +254,10 @@ func (p *OllamaProvider) Name() string {
 // ChatCompletion performs a non-streaming chat completion
 func (p *OllamaProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
 	// Convert to Ollama format
-	ollamaReq := p.convertToOllamaRequest(req)
+	ollamaReq, err := p.convertToOllamaRequest(req)
+	if err != nil {
+		return nil, err
+	}
 	ollamaReq.Stream = false
 
 	body, err := json.Marshal(ollamaReq)
@@ -167,7 +265,7 @@ func (p *OllamaProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/chat", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.pickInstance(req.Model)+"/api/chat", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -184,10 +282,15 @@ func (p *OllamaProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 		return nil, p.handleErrorResponse(resp)
 	}
 
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 	var ollamaResp ollamaChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+	if err := json.Unmarshal(data, &ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	ollamaChatSchema.check(ollamaAPIVersion, data)
 
 	// Convert to OpenAI format
 	return p.convertToOpenAIResponse(&ollamaResp, req.Model), nil
@@ -196,7 +299,10 @@ func (p *OllamaProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 // ChatCompletionStream performs a streaming chat completion
 func (p *OllamaProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
 	// Convert to Ollama format
-	ollamaReq := p.convertToOllamaRequest(req)
+	ollamaReq, err := p.convertToOllamaRequest(req)
+	if err != nil {
+		return nil, err
+	}
 	ollamaReq.Stream = true
 
 	body, err := json.Marshal(ollamaReq)
@@ -204,7 +310,7 @@ func (p *OllamaProvider) ChatCompletionStream(ctx context.Context, req *models.C
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/chat", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.pickInstance(req.Model)+"/api/chat", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -227,19 +333,22 @@ func (p *OllamaProvider) ChatCompletionStream(ctx context.Context, req *models.C
 	// Create a pipe to convert NDJSON to SSE format
 	pr, pw := io.Pipe()
 
-	go p.convertStreamToSSE(resp.Body, pw, req.Model)
+	includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+	go p.convertStreamToSSE(resp.Body, pw, req.Model, includeUsage)
 
 	return pr, nil
 }
 
-// convertStreamToSSE converts Ollama NDJSON stream to OpenAI SSE format
-func (p *OllamaProvider) convertStreamToSSE(src io.ReadCloser, dst *io.PipeWriter, model string) {
+// convertStreamToSSE converts Ollama NDJSON stream to OpenAI SSE format. With
+// includeUsage, a usage chunk built from Ollama's final counts precedes [DONE].
+func (p *OllamaProvider) convertStreamToSSE(src io.ReadCloser, dst *io.PipeWriter, model string, includeUsage bool) {
 	defer src.Close()
 	defer dst.Close()
 
 	scanner := bufio.NewScanner(src)
-	requestID := "chatcmpl-" + uuid.New().String()[:8]
+	requestID := "chatcmpl-" + ids.New()
 	created := time.Now().Unix()
+	toolCalls := 0
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -249,7 +358,7 @@ func (p *OllamaProvider) convertStreamToSSE(src io.ReadCloser, dst *io.PipeWrite
 
 		var ollamaResp ollamaChatResponse
 		if err := json.Unmarshal([]byte(line), &ollamaResp); err != nil {
-			log.Error().Err(err).Str("line", line).Msg("Failed to parse Ollama stream response")
+			logger.Error().Err(err).Str("line", line).Msg("Failed to parse Ollama stream response")
 			continue
 		}
 
@@ -274,35 +383,65 @@ func (p *OllamaProvider) convertStreamToSSE(src io.ReadCloser, dst *io.PipeWrite
 			streamResp.Choices[0].Delta.Role = ollamaResp.Message.Role
 		}
 
+		// Ollama sends each tool call whole, so one delta carries all of it
+		calls := fromOllamaToolCalls(ollamaResp.Message.ToolCalls)
+		for i := range calls {
+			index := toolCalls + i
+			calls[i].Index = &index
+		}
+		toolCalls += len(calls)
+		streamResp.Choices[0].Delta.ToolCalls = calls
+
 		// Set finish reason on last chunk
 		if ollamaResp.Done {
-			finishReason := "stop"
+			finishReason := ollamaFinishReason(ollamaResp.DoneReason)
+			if toolCalls > 0 {
+				finishReason = "tool_calls"
+			}
 			streamResp.Choices[0].FinishReason = &finishReason
 		}
 
 		// Write SSE format
 		jsonData, err := json.Marshal(streamResp)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to marshal stream response")
+			logger.Error().Err(err).Msg("Failed to marshal stream response")
 			continue
 		}
 
 		if _, err := fmt.Fprintf(dst, "data: %s\n\n", jsonData); err != nil {
-			log.Error().Err(err).Msg("Failed to write to stream")
+			logger.Error().Err(err).Msg("Failed to write to stream")
 			return
 		}
 
 		// Send [DONE] after final message
 		if ollamaResp.Done {
+			if includeUsage {
+				usageChunk, _ := json.Marshal(models.ChatCompletionStreamResponse{
+					ID:      requestID,
+					Object:  "chat.completion.chunk",
+					Created: created,
+					Model:   model,
+					Choices: []models.ChatCompletionStreamChoice{},
+					Usage: &models.Usage{
+						PromptTokens:     ollamaResp.PromptEvalCount,
+						CompletionTokens: ollamaResp.EvalCount,
+						TotalTokens:      ollamaResp.PromptEvalCount + ollamaResp.EvalCount,
+					},
+				})
+				if _, err := fmt.Fprintf(dst, "data: %s\n\n", usageChunk); err != nil {
+					logger.Error().Err(err).Msg("Failed to write usage to stream")
+					return
+				}
+			}
 			if _, err := fmt.Fprintf(dst, "data: [DONE]\n\n"); err != nil {
-				log.Error().Err(err).Msg("Failed to write DONE to stream")
+				logger.Error().Err(err).Msg("Failed to write DONE to stream")
 			}
 			return
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Error().Err(err).Msg("Scanner error in stream conversion")
+		logger.Error().Err(err).Msg("Scanner error in stream conversion")
 	}
 }
 
@@ -325,7 +464,7 @@ func (p *OllamaProvider) Completion(ctx context.Context, req *models.CompletionR
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/generate", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.pickInstance(req.Model)+"/api/generate", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -349,7 +488,7 @@ func (p *OllamaProvider) Completion(ctx context.Context, req *models.CompletionR
 
 	// Convert to OpenAI format
 	return &models.CompletionResponse{
-		ID:      "cmpl-" + uuid.New().String()[:8],
+		ID:      "cmpl-" + ids.New(),
 		Object:  "text_completion",
 		Created: time.Now().Unix(),
 		Model:   req.Model,
@@ -368,106 +507,85 @@ func (p *OllamaProvider) Completion(ctx context.Context, req *models.CompletionR
 	}, nil
 }
 
-// Embedding generates embeddings
-func (p *OllamaProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
-	// Handle input as string or []string
-	var inputs []string
-	switch v := req.Input.(type) {
-	case string:
-		inputs = []string{v}
-	case []interface{}:
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				inputs = append(inputs, s)
-			}
-		}
-	case []string:
-		inputs = v
-	default:
-		return nil, fmt.Errorf("invalid input type")
-	}
-
-	var embeddings []models.EmbeddingData
-	var totalTokens int
-
-	for i, input := range inputs {
-		ollamaReq := ollamaEmbeddingRequest{
-			Model:  req.Model,
-			Prompt: input,
-		}
-
-		body, err := json.Marshal(ollamaReq)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/embeddings", bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
+// ListModels returns supported models, fetched from Ollama at most once per
+// ModelCacheTTL, or by the background refresh if there is one
+func (p *OllamaProvider) ListModels() []models.Model {
+	list, _ := p.lookupModels()
+	return list
+}
 
-		resp, err := p.httpClient.Do(httpReq)
-		if err != nil {
-			return nil, fmt.Errorf("request failed: %w", err)
-		}
-		defer resp.Body.Close()
+// lookupModels returns the models and their lowercase IDs. With a background
+// refresh, it never calls Ollama: the defaults stand in until the first
+// refresh completes.
+func (p *OllamaProvider) lookupModels() ([]models.Model, map[string]struct{}) {
+	if !p.backgroundRefresh() {
+		return p.modelCache.get(p.config.ModelCacheTTL, p.fetchModels)
+	}
+	if list, ids, ok := p.modelCache.peek(); ok {
+		return list, ids
+	}
+	ids := make(map[string]struct{}, len(p.models))
+	for _, m := range p.models {
+		ids[strings.ToLower(m.ID)] = struct{}{}
+	}
+	return p.models, ids
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, p.handleErrorResponse(resp)
-		}
+// backgroundRefresh reports whether this replica refreshes models in the background
+func (p *OllamaProvider) backgroundRefresh() bool {
+	return p.config.ModelRefreshInterval > 0 && (p.config.RefreshGate == nil || p.config.RefreshGate())
+}
 
-		var ollamaResp ollamaEmbeddingResponse
-		if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %w", err)
+// InvalidateModels drops the cached model list; a background refresh
+// fetches it again right away
+func (p *OllamaProvider) InvalidateModels() {
+	p.modelCache.invalidate()
+	if p.refreshNow != nil {
+		select {
+		case p.refreshNow <- struct{}{}:
+		default:
 		}
-
-		embeddings = append(embeddings, models.EmbeddingData{
-			Object:    "embedding",
-			Embedding: ollamaResp.Embedding,
-			Index:     i,
-		})
-
-		// Estimate tokens (rough approximation)
-		totalTokens += len(strings.Fields(input))
 	}
+}
 
-	return &models.EmbeddingResponse{
-		Object: "list",
-		Data:   embeddings,
-		Model:  req.Model,
-		Usage: models.EmbeddingUsage{
-			PromptTokens: totalTokens,
-			TotalTokens:  totalTokens,
-		},
-	}, nil
+func (p *OllamaProvider) cachedModels() *modelCache {
+	return &p.modelCache
 }
 
-// ListModels returns supported models
-func (p *OllamaProvider) ListModels() []models.Model {
+// fetchModels lists the models of the primary instance, falling back to the defaults
+func (p *OllamaProvider) fetchModels() []models.Model {
 	// Try to fetch actual models from Ollama
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/api/tags", nil)
+	list, err := p.fetchTags(ctx)
 	if err != nil {
 		return p.models
 	}
+	return list
+}
+
+// fetchTags lists the models of the primary instance from /api/tags; an
+// instance without models gets the defaults
+func (p *OllamaProvider) fetchTags(ctx context.Context) ([]models.Model, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.primaryURL()+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return p.models
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return p.models
+		return nil, fmt.Errorf("/api/tags returned status %d", resp.StatusCode)
 	}
 
 	var tagsResp ollamaTagsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
-		return p.models
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// Convert to our model format
@@ -482,10 +600,10 @@ func (p *OllamaProvider) ListModels() []models.Model {
 	}
 
 	if len(ollamaModels) > 0 {
-		return ollamaModels
+		return ollamaModels, nil
 	}
 
-	return p.models
+	return p.models, nil
 }
 
 // SupportsModel checks if this provider supports the given model
@@ -497,17 +615,14 @@ func (p *OllamaProvider) SupportsModel(model string) bool {
 		}
 	}
 	// Also check available models
-	for _, m := range p.ListModels() {
-		if strings.EqualFold(m.ID, model) {
-			return true
-		}
-	}
-	return false
+	_, ids := p.lookupModels()
+	_, ok := ids[modelLower]
+	return ok
 }
 
 // HealthCheck verifies the provider is accessible
 func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/api/tags", nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.primaryURL()+"/api/tags", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
@@ -525,13 +640,28 @@ func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// convertToOllamaRequest converts OpenAI request to Ollama format
-func (p *OllamaProvider) convertToOllamaRequest(req *models.ChatCompletionRequest) *ollamaChatRequest {
+// convertToOllamaRequest converts OpenAI request to Ollama format. Ollama
+// takes images as base64 data only, so image URLs other than data URLs are
+// rejected.
+func (p *OllamaProvider) convertToOllamaRequest(req *models.ChatCompletionRequest) (*ollamaChatRequest, error) {
 	messages := make([]ollamaChatMessage, len(req.Messages))
 	for i, msg := range req.Messages {
 		messages[i] = ollamaChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+			Role:      msg.Role,
+			Content:   msg.Text(),
+			ToolCalls: toOllamaToolCalls(msg.ToolCalls),
+		}
+		for _, image := range msg.Images() {
+			_, data, ok, err := image.DataURL()
+			if !ok || err != nil {
+				return nil, &ProviderError{
+					Provider:   "ollama",
+					StatusCode: http.StatusBadRequest,
+					Code:       "invalid_request_error",
+					Message:    "Ollama only accepts images as base64 data URLs",
+				}
+			}
+			messages[i].Images = append(messages[i].Images, data)
 		}
 	}
 
@@ -539,6 +669,7 @@ func (p *OllamaProvider) convertToOllamaRequest(req *models.ChatCompletionReques
 		Model:    req.Model,
 		Messages: messages,
 		Stream:   req.Stream,
+		Tools:    req.Tools,
 	}
 
 	// Set options if any are specified
@@ -551,13 +682,19 @@ func (p *OllamaProvider) convertToOllamaRequest(req *models.ChatCompletionReques
 		}
 	}
 
-	return ollamaReq
+	return ollamaReq, nil
 }
 
 // convertToOpenAIResponse converts Ollama response to OpenAI format
 func (p *OllamaProvider) convertToOpenAIResponse(resp *ollamaChatResponse, model string) *models.ChatCompletionResponse {
+	toolCalls := fromOllamaToolCalls(resp.Message.ToolCalls)
+	finishReason := ollamaFinishReason(resp.DoneReason)
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
 	return &models.ChatCompletionResponse{
-		ID:      "chatcmpl-" + uuid.New().String()[:8],
+		ID:      "chatcmpl-" + ids.New(),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
 		Model:   model,
@@ -565,10 +702,11 @@ func (p *OllamaProvider) convertToOpenAIResponse(resp *ollamaChatResponse, model
 			{
 				Index: 0,
 				Message: models.ChatMessage{
-					Role:    resp.Message.Role,
-					Content: resp.Message.Content,
+					Role:      resp.Message.Role,
+					Content:   resp.Message.Content,
+					ToolCalls: toolCalls,
 				},
-				FinishReason: "stop",
+				FinishReason: finishReason,
 			},
 		},
 		Usage: models.Usage{
@@ -579,11 +717,19 @@ func (p *OllamaProvider) convertToOpenAIResponse(resp *ollamaChatResponse, model
 	}
 }
 
+// ollamaFinishReason maps an Ollama done_reason to an OpenAI finish_reason
+func ollamaFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
 // handleErrorResponse parses an error response from Ollama
 func (p *OllamaProvider) handleErrorResponse(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 
-	log.Error().
+	logger.Error().
 		Int("status", resp.StatusCode).
 		Str("body", string(body)).
 		Msg("Ollama API error")
@@ -599,6 +745,7 @@ func (p *OllamaProvider) handleErrorResponse(resp *http.Response) error {
 			StatusCode: resp.StatusCode,
 			Code:       "ollama_error",
 			Message:    errResp.Error,
+			RetryAfter: ParseRetryAfter(resp.Header),
 		}
 	}
 
@@ -607,5 +754,6 @@ func (p *OllamaProvider) handleErrorResponse(resp *http.Response) error {
 		StatusCode: resp.StatusCode,
 		Code:       "api_error",
 		Message:    fmt.Sprintf("Ollama API returned status %d", resp.StatusCode),
+		RetryAfter: ParseRetryAfter(resp.Header),
 	}
 }
@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+const (
+	// ollamaEmbedPath embeds a batch of inputs (Ollama 0.3.4 and later)
+	ollamaEmbedPath = "/api/embed"
+	// ollamaLegacyEmbedPath embeds one input per call
+	ollamaLegacyEmbedPath = "/api/embeddings"
+)
+
+// errEmbedUnsupported means an instance has no /api/embed endpoint
+var errEmbedUnsupported = errors.New("ollama: /api/embed not supported")
+
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float64 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+}
+
+// Embedding generates embeddings. Inputs are sent to /api/embed in batches
+// of EmbedBatchSize; Ollama versions without it get one /api/embeddings call
+// per input instead. Either way, at most EmbedConcurrency calls run at once,
+// and the first failure cancels the others.
+func (p *OllamaProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	inputs, err := embeddingInputs(req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(inputs))
+	var tokens int64
+	if !p.embedLegacy.Load() {
+		err = p.embedBatches(ctx, req.Model, inputs, vectors, &tokens)
+		if errors.Is(err, errEmbedUnsupported) {
+			logger.Info().Msg("Ollama has no /api/embed, embedding one input per call")
+			p.embedLegacy.Store(true)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if p.embedLegacy.Load() {
+		tokens = 0
+		if err := p.embedEach(ctx, req.Model, inputs, vectors, &tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	data := make([]models.EmbeddingData, len(inputs))
+	for i, vector := range vectors {
+		data[i] = models.EmbeddingData{
+			Object:    "embedding",
+			Embedding: vector,
+			Index:     i,
+		}
+	}
+	return &models.EmbeddingResponse{
+		Object: "list",
+		Data:   data,
+		Model:  req.Model,
+		Usage: models.EmbeddingUsage{
+			PromptTokens: int(tokens),
+			TotalTokens:  int(tokens),
+		},
+	}, nil
+}
+
+// embeddingInputs returns the inputs of an embedding request, a string or a
+// list of strings
+func embeddingInputs(input interface{}) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		inputs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				inputs = append(inputs, s)
+			}
+		}
+		return inputs, nil
+	}
+	return nil, fmt.Errorf("invalid input type")
+}
+
+// embedBatches fills vectors from /api/embed, one call per batch of inputs
+func (p *OllamaProvider) embedBatches(ctx context.Context, model string, inputs []string, vectors [][]float64, tokens *int64) error {
+	size := p.config.EmbedBatchSize
+	batches := (len(inputs) + size - 1) / size
+	return fanOut(ctx, batches, p.config.EmbedConcurrency, func(ctx context.Context, n int) error {
+		start := n * size
+		end := min(start+size, len(inputs))
+		batch := inputs[start:end]
+
+		var resp ollamaEmbedResponse
+		if err := p.postEmbedding(ctx, model, ollamaEmbedPath, ollamaEmbedRequest{Model: model, Input: batch}, &resp); err != nil {
+			return err
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return fmt.Errorf("ollama returned %d embeddings for %d inputs", len(resp.Embeddings), len(batch))
+		}
+		copy(vectors[start:end], resp.Embeddings)
+
+		count := resp.PromptEvalCount
+		if count == 0 {
+			count = estimateEmbeddingTokens(batch...)
+		}
+		atomic.AddInt64(tokens, int64(count))
+		return nil
+	})
+}
+
+// embedEach fills vectors from /api/embeddings, one call per input
+func (p *OllamaProvider) embedEach(ctx context.Context, model string, inputs []string, vectors [][]float64, tokens *int64) error {
+	return fanOut(ctx, len(inputs), p.config.EmbedConcurrency, func(ctx context.Context, i int) error {
+		var resp ollamaEmbeddingResponse
+		if err := p.postEmbedding(ctx, model, ollamaLegacyEmbedPath, ollamaEmbeddingRequest{Model: model, Prompt: inputs[i]}, &resp); err != nil {
+			return err
+		}
+		vectors[i] = resp.Embedding
+		atomic.AddInt64(tokens, int64(estimateEmbeddingTokens(inputs[i])))
+		return nil
+	})
+}
+
+// estimateEmbeddingTokens approximates the tokens of inputs Ollama did not count
+func estimateEmbeddingTokens(inputs ...string) int {
+	total := 0
+	for _, input := range inputs {
+		total += len(strings.Fields(input))
+	}
+	return total
+}
+
+// postEmbedding posts payload to path on an instance serving model and
+// decodes the response into out. A 404 from /api/embed that is not an
+// Ollama error (such as a missing model) means the endpoint does not exist.
+func (p *OllamaProvider) postEmbedding(ctx context.Context, model, path string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.pickInstance(model)+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && path == ollamaEmbedPath {
+		data, _ := io.ReadAll(resp.Body)
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) != nil || errResp.Error == "" {
+			return errEmbedUnsupported
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return p.handleErrorResponse(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// fanOut calls fn for 0..n-1 with at most limit calls running at once. The
+// first error cancels the context of the other calls and is returned.
+func fanOut(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, max(limit, 1))
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, i); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// embedVector is the stub embedding of an input: its length
+func embedVector(input string) []float64 {
+	return []float64{float64(len(input))}
+}
+
+func TestOllamaEmbedding_BatchesWithBoundedConcurrency(t *testing.T) {
+	var calls, inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ollamaEmbedPath {
+			t.Errorf("unexpected call to %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var req ollamaEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := ollamaEmbedResponse{PromptEvalCount: len(req.Input)}
+		for _, input := range req.Input {
+			resp.Embeddings = append(resp.Embeddings, embedVector(input))
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: server.URL, EmbedBatchSize: 2, EmbedConcurrency: 2})
+	inputs := []interface{}{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "ggggggg"}
+	resp, err := p.Embedding(context.Background(), &models.EmbeddingRequest{Model: "nomic-embed-text", Input: inputs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4 batches of at most 2 inputs", calls)
+	}
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+	for i, data := range resp.Data {
+		if data.Index != i || data.Embedding[0] != float64(len(inputs[i].(string))) {
+			t.Errorf("data[%d] = %+v, want the embedding of %q", i, data, inputs[i])
+		}
+	}
+	if resp.Usage.PromptTokens != len(inputs) {
+		t.Errorf("prompt tokens = %d, want Ollama's count %d", resp.Usage.PromptTokens, len(inputs))
+	}
+}
+
+func TestOllamaEmbedding_FallsBackWithoutBatchEndpoint(t *testing.T) {
+	var legacyCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case ollamaLegacyEmbedPath:
+			atomic.AddInt32(&legacyCalls, 1)
+			var req ollamaEmbeddingRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(ollamaEmbeddingResponse{Embedding: embedVector(req.Prompt)})
+		default:
+			// Older Ollama versions answer unknown routes with a plain 404
+			http.Error(w, "404 page not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: server.URL})
+	for round := 0; round < 2; round++ {
+		resp, err := p.Embedding(context.Background(), &models.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"one", "three"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data[0].Embedding[0] != 3 || resp.Data[1].Embedding[0] != 5 {
+			t.Errorf("embeddings = %+v, want them in input order", resp.Data)
+		}
+	}
+	if legacyCalls != 4 {
+		t.Errorf("legacy calls = %d, want one per input", legacyCalls)
+	}
+	if !p.embedLegacy.Load() {
+		t.Error("the missing batch endpoint should be remembered")
+	}
+}
+
+func TestOllamaEmbedding_ModelNotFoundIsNotAFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == ollamaLegacyEmbedPath {
+			t.Error("a missing model should not fall back to /api/embeddings")
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"nope\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: server.URL})
+	_, err := p.Embedding(context.Background(), &models.EmbeddingRequest{Model: "nope", Input: "hello"})
+	providerErr, ok := err.(*ProviderError)
+	if !ok || providerErr.StatusCode != http.StatusNotFound || providerErr.Code != "ollama_error" {
+		t.Errorf("err = %v, want the Ollama error", err)
+	}
+}
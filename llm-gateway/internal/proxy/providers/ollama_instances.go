@@ -0,0 +1,272 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InstanceReporter is implemented by providers that route across multiple replicas
+type InstanceReporter interface {
+	InstanceStatus() []map[string]interface{}
+}
+
+// ollamaPsResponse is the body returned by GET /api/ps
+type ollamaPsResponse struct {
+	Models []ollamaRunningModel `json:"models"`
+}
+
+type ollamaRunningModel struct {
+	Name      string `json:"name"`
+	Model     string `json:"model"`
+	Size      int64  `json:"size"`
+	SizeVRAM  int64  `json:"size_vram"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ollamaInstance is one Ollama replica and the models it currently has loaded
+type ollamaInstance struct {
+	baseURL string
+
+	mu       sync.RWMutex
+	loaded   map[string]int64 // normalized model name -> VRAM bytes
+	vramUsed int64
+	healthy  bool
+	lastPoll time.Time
+	lastErr  string
+
+	routed int64 // requests routed to this instance
+}
+
+func newOllamaInstance(baseURL string) *ollamaInstance {
+	return &ollamaInstance{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		loaded:  make(map[string]int64),
+		healthy: true, // assume healthy until the first poll says otherwise
+	}
+}
+
+// pickInstance returns the base URL to use for model: a healthy replica that already has the
+// model loaded, otherwise the healthy replica using the least VRAM, otherwise the primary.
+func (p *OllamaProvider) pickInstance(model string) string {
+	instances := p.instanceList()
+	if len(instances) == 1 {
+		return instances[0].baseURL
+	}
+
+	var resident []*ollamaInstance
+	var best *ollamaInstance
+	var bestVRAM int64
+
+	for _, inst := range instances {
+		inst.mu.RLock()
+		healthy, vram := inst.healthy, inst.vramUsed
+		_, loaded := inst.loaded[normalizeOllamaModel(model)]
+		inst.mu.RUnlock()
+
+		if !healthy {
+			continue
+		}
+		if loaded {
+			resident = append(resident, inst)
+		}
+		if best == nil || vram < bestVRAM {
+			best, bestVRAM = inst, vram
+		}
+	}
+
+	chosen := instances[0]
+	switch {
+	case len(resident) > 0:
+		// Spread load across replicas that have the model resident
+		chosen = resident[atomic.AddUint64(&p.rr, 1)%uint64(len(resident))]
+	case best != nil:
+		chosen = best
+		// Mark the model as resident until the next poll so follow-up requests
+		// land on the same replica instead of loading the model everywhere
+		chosen.mu.Lock()
+		chosen.loaded[normalizeOllamaModel(model)] = 0
+		chosen.mu.Unlock()
+	}
+
+	atomic.AddInt64(&chosen.routed, 1)
+	return chosen.baseURL
+}
+
+// instanceList returns the current replicas; the first is the primary
+func (p *OllamaProvider) instanceList() []*ollamaInstance {
+	p.instMu.RLock()
+	defer p.instMu.RUnlock()
+	return p.instances
+}
+
+// primaryURL returns the base URL used for model listing and health checks
+func (p *OllamaProvider) primaryURL() string {
+	return p.instanceList()[0].baseURL
+}
+
+// SetEndpoints replaces the replica set, e.g. with endpoints from service
+// discovery. Replicas that remain keep their loaded-model state.
+func (p *OllamaProvider) SetEndpoints(baseURLs []string) {
+	if len(baseURLs) == 0 {
+		return
+	}
+
+	// The primary may change, and with it the model list. Deferred first so
+	// it runs after the lock is released.
+	defer p.modelCache.invalidate()
+
+	p.instMu.Lock()
+	defer p.instMu.Unlock()
+
+	existing := make(map[string]*ollamaInstance, len(p.instances))
+	for _, inst := range p.instances {
+		existing[inst.baseURL] = inst
+	}
+	instances := make([]*ollamaInstance, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		if inst, ok := existing[strings.TrimSuffix(baseURL, "/")]; ok {
+			instances = append(instances, inst)
+			continue
+		}
+		instances = append(instances, newOllamaInstance(baseURL))
+	}
+	p.instances = instances
+
+	// Start tracking loaded models once there is a choice of replica
+	if len(instances) > 1 && p.stopPoll == nil {
+		p.stopPoll = make(chan struct{})
+		go p.schedulerLoop()
+	}
+}
+
+// pollInstances refreshes the loaded-model state of every instance from /api/ps
+func (p *OllamaProvider) pollInstances(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, inst := range p.instanceList() {
+		wg.Add(1)
+		go func(inst *ollamaInstance) {
+			defer wg.Done()
+			p.pollInstance(ctx, inst)
+		}(inst)
+	}
+	wg.Wait()
+}
+
+func (p *OllamaProvider) pollInstance(ctx context.Context, inst *ollamaInstance) {
+	running, err := p.runningModels(ctx, inst.baseURL)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	inst.lastPoll = time.Now()
+
+	if err != nil {
+		if inst.healthy {
+			logger.Warn().Err(err).Str("instance", inst.baseURL).Msg("Ollama instance unreachable")
+		}
+		inst.healthy = false
+		inst.lastErr = err.Error()
+		return
+	}
+
+	inst.healthy = true
+	inst.lastErr = ""
+	inst.loaded = make(map[string]int64, len(running))
+	inst.vramUsed = 0
+	for _, m := range running {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		inst.loaded[normalizeOllamaModel(name)] = m.SizeVRAM
+		inst.vramUsed += m.SizeVRAM
+	}
+}
+
+// runningModels returns the models loaded on an instance via /api/ps
+func (p *OllamaProvider) runningModels(ctx context.Context, baseURL string) ([]ollamaRunningModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/ps", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/api/ps returned status %d", resp.StatusCode)
+	}
+
+	var psResp ollamaPsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&psResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return psResp.Models, nil
+}
+
+// schedulerLoop polls /api/ps on all instances until Stop is called
+func (p *OllamaProvider) schedulerLoop() {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.PollInterval)
+		p.pollInstances(ctx)
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-p.stopPoll:
+			return
+		}
+	}
+}
+
+// Stop stops the instance polling and model refresh goroutines
+func (p *OllamaProvider) Stop() {
+	p.instMu.Lock()
+	defer p.instMu.Unlock()
+	if p.stopPoll != nil {
+		close(p.stopPoll)
+	}
+	if p.stopRefresh != nil {
+		close(p.stopRefresh)
+	}
+}
+
+// InstanceStatus returns loaded-model and VRAM information per instance
+func (p *OllamaProvider) InstanceStatus() []map[string]interface{} {
+	instances := p.instanceList()
+	status := make([]map[string]interface{}, 0, len(instances))
+	for _, inst := range instances {
+		inst.mu.RLock()
+		loaded := make([]string, 0, len(inst.loaded))
+		for name := range inst.loaded {
+			loaded = append(loaded, name)
+		}
+		entry := map[string]interface{}{
+			"base_url":      inst.baseURL,
+			"healthy":       inst.healthy,
+			"loaded_models": loaded,
+			"vram_used":     inst.vramUsed,
+			"routed":        atomic.LoadInt64(&inst.routed),
+		}
+		if !inst.lastPoll.IsZero() {
+			entry["last_poll"] = inst.lastPoll
+		}
+		if inst.lastErr != "" {
+			entry["error"] = inst.lastErr
+		}
+		inst.mu.RUnlock()
+		status = append(status, entry)
+	}
+	return status
+}
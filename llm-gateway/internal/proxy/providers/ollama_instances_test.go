@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// psServer returns an Ollama stub whose /api/ps reports the given loaded model
+func psServer(loaded string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ps" {
+			http.NotFound(w, r)
+			return
+		}
+		if loaded == "" {
+			w.Write([]byte(`{"models":[]}`))
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"` + loaded + `","size_vram":4000000000}]}`))
+	}))
+}
+
+func newTestOllama(primary string, instances ...string) *OllamaProvider {
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: primary})
+	for _, baseURL := range instances {
+		p.instances = append(p.instances, newOllamaInstance(baseURL))
+	}
+	return p
+}
+
+func TestOllamaPickInstance_PrefersResidentModel(t *testing.T) {
+	a := psServer("mistral:latest")
+	defer a.Close()
+	b := psServer("llama3:latest")
+	defer b.Close()
+
+	p := newTestOllama(a.URL, b.URL)
+	p.pollInstances(context.Background())
+
+	for i := 0; i < 3; i++ {
+		if got := p.pickInstance("llama3"); got != b.URL {
+			t.Errorf("pickInstance(llama3) = %s, want replica with llama3 loaded", got)
+		}
+		if got := p.pickInstance("mistral"); got != a.URL {
+			t.Errorf("pickInstance(mistral) = %s, want replica with mistral loaded", got)
+		}
+	}
+}
+
+func TestOllamaPickInstance_LeastVRAMAndSticky(t *testing.T) {
+	a := psServer("mistral:latest")
+	defer a.Close()
+	b := psServer("")
+	defer b.Close()
+
+	p := newTestOllama(a.URL, b.URL)
+	p.pollInstances(context.Background())
+
+	if got := p.pickInstance("qwen2"); got != b.URL {
+		t.Errorf("pickInstance(qwen2) = %s, want the idle replica", got)
+	}
+	// Until the next poll, the model should stick to the replica now loading it
+	if got := p.pickInstance("qwen2"); got != b.URL {
+		t.Errorf("second pickInstance(qwen2) = %s, want the same replica", got)
+	}
+}
+
+func TestOllamaPickInstance_SkipsUnhealthy(t *testing.T) {
+	a := psServer("")
+	a.Close() // unreachable
+	b := psServer("")
+	defer b.Close()
+
+	p := newTestOllama(a.URL, b.URL)
+	p.pollInstances(context.Background())
+
+	if got := p.pickInstance("llama3"); got != b.URL {
+		t.Errorf("pickInstance() = %s, want healthy replica", got)
+	}
+
+	status := p.InstanceStatus()
+	if status[0]["healthy"] != false {
+		t.Error("unreachable replica should be reported unhealthy")
+	}
+}
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// modelRefreshTimeout bounds one background /api/tags call
+const modelRefreshTimeout = 5 * time.Second
+
+// modelRefreshLoop refreshes the model list every ModelRefreshInterval, and
+// after InvalidateModels, until Stop is called, while RefreshGate allows it.
+// A failed refresh keeps the last list.
+func (p *OllamaProvider) modelRefreshLoop() {
+	ticker := time.NewTicker(p.config.ModelRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if p.backgroundRefresh() {
+			ctx, cancel := context.WithTimeout(context.Background(), modelRefreshTimeout)
+			if _, err := p.RefreshModels(ctx); err != nil {
+				logger.Warn().Err(err).Str("base_url", p.primaryURL()).Msg("Ollama model refresh failed, keeping the last model list")
+			}
+			cancel()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-p.refreshNow:
+		case <-p.stopRefresh:
+			return
+		}
+	}
+}
+
+// RefreshModels fetches the model list from /api/tags now and caches it
+func (p *OllamaProvider) RefreshModels(ctx context.Context) ([]models.Model, error) {
+	list, err := p.fetchTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.modelCache.set(list)
+	return list, nil
+}
@@ -0,0 +1,313 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReadinessGate is implemented by providers that need warm-up before the gateway can take traffic
+type ReadinessGate interface {
+	Ready() bool
+}
+
+// Prefetcher is implemented by providers that can prefetch models ahead of traffic
+type Prefetcher interface {
+	StartPrefetch() error
+	PrefetchStatus() map[string]interface{}
+}
+
+// ollamaPullRequest is the body of POST /api/pull
+type ollamaPullRequest struct {
+	Name   string `json:"name"`
+	Stream bool   `json:"stream"`
+}
+
+// ollamaPullProgress is one line of the /api/pull progress stream
+type ollamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// prefetchState tracks the progress of the cold-start model prefetch
+type prefetchState struct {
+	mu        sync.RWMutex
+	running   bool
+	started   time.Time
+	finished  time.Time
+	wanted    []string
+	missing   []string
+	pulled    []string
+	failed    map[string]string
+	lastError string
+}
+
+// StartPrefetch runs Prefetch for the configured models in the background, bounded by PullTimeout.
+// The provider reports not ready from the moment this returns until the prefetch finishes.
+func (p *OllamaProvider) StartPrefetch() error {
+	wanted := p.config.PrefetchModels
+	if err := p.beginPrefetch(wanted); err != nil {
+		return err
+	}
+	pull := p.config.AutoPull && (p.config.PullGate == nil || p.config.PullGate())
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.PullTimeout)
+		defer cancel()
+		p.runPrefetch(ctx, wanted, pull)
+	}()
+	return nil
+}
+
+// Prefetch compares the wanted models against /api/tags and, if pull is set, pulls the missing ones.
+// The provider reports not ready until the prefetch finishes.
+func (p *OllamaProvider) Prefetch(ctx context.Context, wanted []string, pull bool) error {
+	if err := p.beginPrefetch(wanted); err != nil {
+		return err
+	}
+	return p.runPrefetch(ctx, wanted, pull)
+}
+
+// beginPrefetch marks a prefetch as running, failing if one is already in progress
+func (p *OllamaProvider) beginPrefetch(wanted []string) error {
+	st := &p.prefetch
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.running {
+		return &ProviderError{
+			Provider:   "ollama",
+			StatusCode: http.StatusConflict,
+			Code:       "prefetch_running",
+			Message:    "a model prefetch is already running",
+		}
+	}
+	st.running = true
+	st.started = time.Now()
+	st.finished = time.Time{}
+	st.wanted = wanted
+	st.missing = nil
+	st.pulled = nil
+	st.failed = make(map[string]string)
+	st.lastError = ""
+	return nil
+}
+
+// runPrefetch performs the prefetch started by beginPrefetch
+func (p *OllamaProvider) runPrefetch(ctx context.Context, wanted []string, pull bool) error {
+	st := &p.prefetch
+	defer func() {
+		st.mu.Lock()
+		st.running = false
+		st.finished = time.Now()
+		st.mu.Unlock()
+	}()
+
+	// Pull per instance so every replica can serve the configured models
+	var missing []string
+	seen := make(map[string]bool)
+	pending := make(map[*ollamaInstance][]string)
+	instances := p.instanceList()
+
+	for _, inst := range instances {
+		local, err := p.localModels(ctx, inst.baseURL)
+		if err != nil {
+			st.mu.Lock()
+			st.lastError = err.Error()
+			st.mu.Unlock()
+			logger.Error().Err(err).Str("instance", inst.baseURL).Msg("Ollama prefetch could not list local models")
+			return err
+		}
+
+		for _, name := range wanted {
+			if local[normalizeOllamaModel(name)] {
+				continue
+			}
+			pending[inst] = append(pending[inst], name)
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+		}
+	}
+
+	st.mu.Lock()
+	st.missing = missing
+	st.mu.Unlock()
+
+	if len(missing) == 0 {
+		logger.Info().Int("models", len(wanted)).Msg("All prefetch models present in Ollama")
+		return nil
+	}
+
+	if !pull {
+		logger.Warn().Strs("missing", missing).Msg("Models referenced in config are not available in Ollama")
+		return nil
+	}
+
+	for _, inst := range instances {
+		for _, name := range pending[inst] {
+			if err := p.pullModel(ctx, inst.baseURL, name); err != nil {
+				logger.Error().Err(err).Str("model", name).Str("instance", inst.baseURL).Msg("Ollama model pull failed")
+				st.mu.Lock()
+				st.failed[name] = err.Error()
+				st.mu.Unlock()
+				continue
+			}
+			st.mu.Lock()
+			st.pulled = append(st.pulled, name)
+			st.mu.Unlock()
+		}
+	}
+
+	// Pulled models become routable without waiting for the cache to expire
+	p.modelCache.invalidate()
+
+	return nil
+}
+
+// localModels returns the set of models available in an Ollama instance
+func (p *OllamaProvider) localModels(ctx context.Context, baseURL string) (map[string]bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.handleErrorResponse(resp)
+	}
+
+	var tagsResp ollamaTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	local := make(map[string]bool, len(tagsResp.Models))
+	for _, m := range tagsResp.Models {
+		local[normalizeOllamaModel(m.Name)] = true
+	}
+	return local, nil
+}
+
+// pullModel pulls a model on an instance via /api/pull, logging download progress
+func (p *OllamaProvider) pullModel(ctx context.Context, baseURL, name string) error {
+	body, err := json.Marshal(ollamaPullRequest{Name: name, Stream: true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Pulls can take many minutes; the caller's context bounds them
+	pullClient := &http.Client{}
+
+	resp, err := pullClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return p.handleErrorResponse(resp)
+	}
+
+	logger.Info().Str("model", name).Str("instance", baseURL).Msg("Pulling Ollama model")
+
+	lastStatus := ""
+	lastPercent := -1
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var progress ollamaPullProgress
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			continue
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("pull %s: %s", name, progress.Error)
+		}
+
+		// Log status changes and every 10% of a layer download
+		percent := -1
+		if progress.Total > 0 {
+			percent = int(progress.Completed * 100 / progress.Total)
+		}
+		if progress.Status != lastStatus || (percent >= 0 && percent/10 != lastPercent/10) {
+			logger.Info().
+				Str("model", name).
+				Str("status", progress.Status).
+				Int("percent", percent).
+				Msg("Ollama pull progress")
+			lastStatus = progress.Status
+			lastPercent = percent
+		}
+
+		if progress.Status == "success" {
+			logger.Info().Str("model", name).Msg("Ollama model pulled")
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pull %s: %w", name, err)
+	}
+
+	return fmt.Errorf("pull %s: stream ended before success", name)
+}
+
+// Ready reports whether the cold-start prefetch has finished
+func (p *OllamaProvider) Ready() bool {
+	p.prefetch.mu.RLock()
+	defer p.prefetch.mu.RUnlock()
+	return !p.prefetch.running
+}
+
+// PrefetchStatus returns the state of the last model prefetch
+func (p *OllamaProvider) PrefetchStatus() map[string]interface{} {
+	st := &p.prefetch
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"running": st.running,
+		"wanted":  st.wanted,
+		"missing": st.missing,
+		"pulled":  st.pulled,
+		"failed":  st.failed,
+	}
+	if !st.started.IsZero() {
+		status["started"] = st.started
+	}
+	if !st.finished.IsZero() {
+		status["finished"] = st.finished
+	}
+	if st.lastError != "" {
+		status["error"] = st.lastError
+	}
+	return status
+}
+
+// normalizeOllamaModel adds the implicit ":latest" tag so "llama3" matches "llama3:latest"
+func normalizeOllamaModel(name string) string {
+	name = strings.ToLower(name)
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	return name
+}
@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeOllama serves /api/tags and /api/pull, recording pulled models
+type fakeOllama struct {
+	mu     sync.Mutex
+	local  []string
+	pulled []string
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/tags":
+		var resp ollamaTagsResponse
+		for _, name := range f.local {
+			resp.Models = append(resp.Models, ollamaModelInfo{Name: name})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/api/pull":
+		var req ollamaPullRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Name == "broken" {
+			w.Write([]byte(`{"error":"pull model manifest: file does not exist"}` + "\n"))
+			return
+		}
+		f.pulled = append(f.pulled, req.Name)
+		f.local = append(f.local, req.Name+":latest")
+		w.Write([]byte(`{"status":"pulling manifest"}` + "\n"))
+		w.Write([]byte(`{"status":"downloading","total":100,"completed":50}` + "\n"))
+		w.Write([]byte(`{"status":"success"}` + "\n"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestOllamaPrefetch_PullsMissingModels(t *testing.T) {
+	fake := &fakeOllama{local: []string{"llama3:latest"}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: server.URL})
+
+	if err := p.Prefetch(context.Background(), []string{"llama3", "mistral", "broken"}, true); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+
+	if len(fake.pulled) != 1 || fake.pulled[0] != "mistral" {
+		t.Errorf("pulled = %v, want [mistral]", fake.pulled)
+	}
+
+	status := p.PrefetchStatus()
+	if missing := status["missing"].([]string); len(missing) != 2 {
+		t.Errorf("missing = %v, want mistral and broken", missing)
+	}
+	if failed := status["failed"].(map[string]string); failed["broken"] == "" {
+		t.Error("failed pull should be recorded")
+	}
+	if !p.Ready() {
+		t.Error("provider should be ready once prefetch finished")
+	}
+}
+
+func TestOllamaPrefetch_NoPull(t *testing.T) {
+	fake := &fakeOllama{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: server.URL})
+
+	if err := p.Prefetch(context.Background(), []string{"mistral"}, false); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	if len(fake.pulled) != 0 {
+		t.Errorf("no model should be pulled without auto pull, got %v", fake.pulled)
+	}
+}
+
+func TestOllamaPrefetch_NotReadyWhileRunning(t *testing.T) {
+	p := NewOllamaProvider(OllamaProviderConfig{BaseURL: "http://127.0.0.1:1"})
+
+	if err := p.beginPrefetch([]string{"mistral"}); err != nil {
+		t.Fatalf("beginPrefetch() error = %v", err)
+	}
+	if p.Ready() {
+		t.Error("provider should not be ready while prefetch runs")
+	}
+	if err := p.beginPrefetch(nil); err == nil {
+		t.Error("a second prefetch should be rejected while one is running")
+	}
+}
+
+func TestNormalizeOllamaModel(t *testing.T) {
+	tests := map[string]string{
+		"llama3":        "llama3:latest",
+		"Llama3:8B":     "llama3:8b",
+		"mistral:7b-q4": "mistral:7b-q4",
+	}
+	for in, want := range tests {
+		if got := normalizeOllamaModel(in); got != want {
+			t.Errorf("normalizeOllamaModel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"encoding/json"
+
+	"github.com/username/llm-gateway/internal/ids"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// ollamaToolCall is a tool call in Ollama format. Ollama has no call IDs
+// and carries the arguments as a JSON object rather than a string.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// toOllamaToolCalls converts the tool calls of an assistant message
+func toOllamaToolCalls(calls []models.ToolCall) []ollamaToolCall {
+	var converted []ollamaToolCall
+	for _, call := range calls {
+		var tc ollamaToolCall
+		tc.Function.Name = call.Function.Name
+		tc.Function.Arguments = json.RawMessage(call.Function.Arguments)
+		if !json.Valid(tc.Function.Arguments) {
+			tc.Function.Arguments = emptyToolInput
+		}
+		converted = append(converted, tc)
+	}
+	return converted
+}
+
+// fromOllamaToolCalls converts the tool calls of a response, generating the
+// IDs Ollama lacks
+func fromOllamaToolCalls(calls []ollamaToolCall) []models.ToolCall {
+	var converted []models.ToolCall
+	for _, call := range calls {
+		arguments := string(call.Function.Arguments)
+		if arguments == "" || arguments == "null" {
+			arguments = "{}"
+		}
+		converted = append(converted, models.ToolCall{
+			ID:       "call_" + ids.New(),
+			Type:     "function",
+			Function: models.FunctionCall{Name: call.Function.Name, Arguments: arguments},
+		})
+	}
+	return converted
+}
@@ -7,10 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
 
 	
 	"github.com/username/llm-gateway/pkg/models"
@@ -21,13 +21,35 @@ type OpenAIConfig struct {
 	APIKey  string
 	BaseURL string
 	Timeout time.Duration
+
+	// StandbyAPIKey is the standby credential used for blue/green rotation
+	StandbyAPIKey string
+	// FailoverThreshold is the number of consecutive 401s before flipping to the standby key
+	FailoverThreshold int
+
+	// APIKeys are additional keys that share load with the active key
+	APIKeys []string
+	// KeyCooldown is how long a rate-limited key is skipped
+	KeyCooldown time.Duration
+
+	// Regions replace BaseURL with regional base URLs; requests go to the
+	// fastest healthy region and fail over when one degrades
+	Regions []Region
+
+	// APIVersion pins Azure OpenAI's api-version query parameter; empty for
+	// api.openai.com, which versions its API in the path
+	APIVersion string
 }
 
 // OpenAIProvider implements the Provider interface for OpenAI
 type OpenAIProvider struct {
-	config     OpenAIConfig
-	httpClient *http.Client
-	models     []models.Model
+	config      OpenAIConfig
+	httpClient  *http.Client
+	models      []models.Model
+	credentials *Credentials
+	keys        *KeyPool
+	endpoints   *endpointSet
+	regions     *regionSet
 }
 
 // OpenAI model prefixes for routing
@@ -60,16 +82,54 @@ func NewOpenAIProvider(config OpenAIConfig) *OpenAIProvider {
 	if config.Timeout == 0 {
 		config.Timeout = 60 * time.Second
 	}
+	if config.APIVersion != "" {
+		warnUnsupportedAPIVersion("openai", config.APIVersion)
+	}
 
 	return &OpenAIProvider{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
-		models: openAIModels,
+		models:      openAIModels,
+		credentials: NewCredentials("openai", config.APIKey, config.StandbyAPIKey, config.FailoverThreshold),
+		keys:        NewKeyPool("openai", config.APIKeys, config.KeyCooldown),
+		endpoints:   newEndpointSet(config.BaseURL),
+		regions:     newRegionSet("openai", config.Regions),
 	}
 }
 
+// WithBaseURL returns a copy of the provider that sends requests to baseURL.
+// The copy uses the original's keys but keeps its own failover and rate
+// limit state, so the endpoint cannot flip or cool down the original's keys.
+func (p *OpenAIProvider) WithBaseURL(baseURL string) Provider {
+	config := p.config
+	config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return &OpenAIProvider{
+		config:      config,
+		httpClient:  p.httpClient,
+		models:      p.models,
+		credentials: p.credentials.detached(),
+		keys:        p.keys.detached(),
+		endpoints:   newEndpointSet(config.BaseURL),
+	}
+}
+
+// SetEndpoints replaces the OpenAI-compatible replicas requests are spread
+// over, e.g. vLLM servers found by service discovery
+func (p *OpenAIProvider) SetEndpoints(baseURLs []string) {
+	p.endpoints.set(baseURLs)
+}
+
+// InstanceStatus returns health and routing information per endpoint, or
+// per region when regions are configured
+func (p *OpenAIProvider) InstanceStatus() []map[string]interface{} {
+	if p.regions != nil {
+		return p.regions.status()
+	}
+	return p.endpoints.status()
+}
+
 // Name returns the provider name
 func (p *OpenAIProvider) Name() string {
 	return "openai"
@@ -80,20 +140,14 @@ func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 	// Ensure stream is false for sync request
 	reqCopy := *req
 	reqCopy.Stream = false
+	reqCopy.StreamOptions = nil // only valid when streaming
 
 	body, err := json.Marshal(reqCopy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.do(ctx, p.httpClient, "/chat/completions", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -103,10 +157,16 @@ func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 		return nil, p.handleErrorResponse(resp)
 	}
 
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 	var result models.ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(data, &result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	openAIChatSchema.check(p.apiVersion(), data)
+	attachCitations(&result, openAICitations(data))
 
 	return &result, nil
 }
@@ -122,19 +182,12 @@ func (p *OpenAIProvider) ChatCompletionStream(ctx context.Context, req *models.C
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
 	// Use a client without timeout for streaming
 	streamClient := &http.Client{
 		// No timeout - streaming can be long
 	}
 
-	resp, err := streamClient.Do(httpReq)
+	resp, err := p.do(ctx, streamClient, "/chat/completions", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -154,14 +207,7 @@ func (p *OpenAIProvider) Completion(ctx context.Context, req *models.CompletionR
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.do(ctx, p.httpClient, "/completions", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -186,14 +232,7 @@ func (p *OpenAIProvider) Embedding(ctx context.Context, req *models.EmbeddingReq
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/embeddings", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	p.setHeaders(httpReq)
-
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.do(ctx, p.httpClient, "/embeddings", body)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -235,12 +274,12 @@ func (p *OpenAIProvider) SupportsModel(model string) bool {
 
 // HealthCheck verifies the provider is accessible
 func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL()+p.versioned("/models"), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
 
-	p.setHeaders(httpReq)
+	p.setHeaders(httpReq, p.credentials.Active())
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -255,17 +294,85 @@ func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// Credentials returns the provider's active/standby API keys
+func (p *OpenAIProvider) Credentials() *Credentials {
+	return p.credentials
+}
+
+// Keys returns the provider's API key pool
+func (p *OpenAIProvider) Keys() *KeyPool {
+	return p.keys
+}
+
+// baseURL returns the base URL for requests not sent via do
+func (p *OpenAIProvider) baseURL() string {
+	if p.regions != nil {
+		return p.regions.preferred()
+	}
+	return p.endpoints.pick().baseURL
+}
+
+// apiVersion returns the upstream API version the provider is pinned to
+func (p *OpenAIProvider) apiVersion() string {
+	if p.config.APIVersion == "" {
+		return openAIDefaultAPIVersion
+	}
+	return p.config.APIVersion
+}
+
+// versioned adds the pinned api-version query parameter to path, if any
+func (p *OpenAIProvider) versioned(path string) string {
+	if p.config.APIVersion == "" {
+		return path
+	}
+	return path + "?api-version=" + url.QueryEscape(p.config.APIVersion)
+}
+
+// do sends a POST request, rotating API keys on 401 and 429 responses
+func (p *OpenAIProvider) do(ctx context.Context, client *http.Client, path string, body []byte) (*http.Response, error) {
+	path = p.versioned(path)
+	if p.regions != nil {
+		return p.regions.do(ctx, func(baseURL string) (*http.Response, error) {
+			return p.send(ctx, client, baseURL+path, body)
+		})
+	}
+
+	ep := p.endpoints.pick()
+	resp, err := p.send(ctx, client, ep.baseURL+path, body)
+
+	// Take unreachable or overloaded replicas out of rotation for a while
+	switch {
+	case err != nil && ctx.Err() == nil:
+		ep.fail(err.Error())
+	case err == nil && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable):
+		ep.fail(resp.Status)
+	}
+	return resp, err
+}
+
+// send sends a POST request to url with the next usable API key
+func (p *OpenAIProvider) send(ctx context.Context, client *http.Client, url string, body []byte) (*http.Response, error) {
+	return doKeyed(client, p.credentials, p.keys, func(key string) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		p.setHeaders(httpReq, key)
+		return httpReq, nil
+	})
+}
+
 // setHeaders sets common headers for OpenAI API requests
-func (p *OpenAIProvider) setHeaders(req *http.Request) {
+func (p *OpenAIProvider) setHeaders(req *http.Request, key string) {
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	req.Header.Set("Authorization", "Bearer "+key)
 }
 
 // handleErrorResponse parses an error response from OpenAI
 func (p *OpenAIProvider) handleErrorResponse(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 
-	log.Error().
+	logger.Error().
 		Int("status", resp.StatusCode).
 		Str("body", string(body)).
 		Msg("OpenAI API error")
@@ -285,6 +392,7 @@ func (p *OpenAIProvider) handleErrorResponse(resp *http.Response) error {
 			StatusCode: resp.StatusCode,
 			Code:       errResp.Error.Code,
 			Message:    errResp.Error.Message,
+			RetryAfter: ParseRetryAfter(resp.Header),
 		}
 	}
 
@@ -293,5 +401,6 @@ func (p *OpenAIProvider) handleErrorResponse(resp *http.Response) error {
 		StatusCode: resp.StatusCode,
 		Code:       "api_error",
 		Message:    fmt.Sprintf("OpenAI API returned status %d", resp.StatusCode),
+		RetryAfter: ParseRetryAfter(resp.Header),
 	}
 }
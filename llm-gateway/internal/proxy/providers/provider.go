@@ -3,11 +3,17 @@ package providers
 import (
 	"context"
 	"io"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/username/llm-gateway/internal/observability"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
+// logger is the providers module logger; its level can be set via log.modules.providers
+var logger = observability.ModuleLogger("providers")
+
 // Provider defines the interface that all LLM providers must implement
 type Provider interface {
 	// Name returns the provider name (e.g., "openai", "anthropic")
@@ -36,24 +42,86 @@ type Provider interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// BaseURLOverrider is implemented by providers that can be pointed at another
+// endpoint, e.g. a local mock while troubleshooting
+type BaseURLOverrider interface {
+	// WithBaseURL returns a copy of the provider that sends requests to baseURL
+	WithBaseURL(baseURL string) Provider
+}
+
 // Registry manages provider registration and lookup
 type Registry struct {
 	mu        sync.RWMutex
 	providers map[string]Provider
+
+	// resolved caches ProvidersForModel by model name; an empty list records
+	// that no provider supports the model. The whole map expires modelCacheTTL
+	// after it was started.
+	resolved      map[string][]string
+	resolvedSince time.Time
+	modelCacheTTL time.Duration
+	// resolvedGen is bumped whenever resolutions are forgotten, so a lookup
+	// racing an invalidation does not store a stale result
+	resolvedGen uint64
 }
 
 // NewRegistry creates a new provider registry
 func NewRegistry() *Registry {
 	return &Registry{
-		providers: make(map[string]Provider),
+		providers:     make(map[string]Provider),
+		modelCacheTTL: DefaultModelCacheTTL,
 	}
 }
 
+// SetModelCacheTTL sets how long model resolutions are reused
+func (r *Registry) SetModelCacheTTL(ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modelCacheTTL = ttl
+	r.resolved = nil
+	r.resolvedGen++
+}
+
 // Register adds a provider to the registry
 func (r *Registry) Register(name string, provider Provider) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.providers[name] = provider
+	r.resolved = nil
+	r.resolvedGen++
+
+	// A provider dropping its model list also drops what was resolved to it
+	if owner, ok := provider.(modelCacheOwner); ok {
+		owner.cachedModels().setOnInvalidate(r.forgetResolutions)
+	}
+}
+
+// InvalidateModels drops the cached model list of the named provider, or of
+// every provider if name is empty, and the model resolutions. Service
+// discovery calls it when a provider's endpoints change.
+func (r *Registry) InvalidateModels(name string) {
+	r.mu.RLock()
+	var targets []Provider
+	for n, provider := range r.providers {
+		if name == "" || n == name {
+			targets = append(targets, provider)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, provider := range targets {
+		if invalidator, ok := provider.(ModelInvalidator); ok {
+			invalidator.InvalidateModels()
+		}
+	}
+	r.forgetResolutions()
+}
+
+func (r *Registry) forgetResolutions() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolved = nil
+	r.resolvedGen++
 }
 
 // Get retrieves a provider by name
@@ -64,17 +132,58 @@ func (r *Registry) Get(name string) (Provider, bool) {
 	return provider, ok
 }
 
-// GetForModel finds a provider that supports the given model
+// GetForModel finds a provider that supports the given model, the first in
+// name order if several do
 func (r *Registry) GetForModel(model string) (Provider, bool) {
+	names := r.ProvidersForModel(model)
+	if len(names) == 0 {
+		return nil, false
+	}
+	return r.Get(names[0])
+}
+
+// ProvidersForModel returns the names of the providers supporting the given
+// model, in name order. Results are cached, so repeated lookups of a model
+// are a single map lookup. The returned slice must not be modified.
+func (r *Registry) ProvidersForModel(model string) []string {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
+	if names, ok := r.resolved[model]; ok && time.Since(r.resolvedSince) < r.modelCacheTTL {
+		r.mu.RUnlock()
+		return names
+	}
 
-	for _, provider := range r.providers {
-		if provider.SupportsModel(model) {
-			return provider, true
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	candidates := make([]Provider, len(names))
+	for i, name := range names {
+		candidates[i] = r.providers[name]
+	}
+	gen := r.resolvedGen
+	r.mu.RUnlock()
+
+	// SupportsModel may fetch a model list, so ask without holding the lock
+	supporting := []string{}
+	for i, candidate := range candidates {
+		if candidate.SupportsModel(model) {
+			supporting = append(supporting, names[i])
 		}
 	}
-	return nil, false
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gen != r.resolvedGen {
+		return supporting
+	}
+	now := time.Now()
+	if r.resolved == nil || now.Sub(r.resolvedSince) >= r.modelCacheTTL || len(r.resolved) >= maxResolvedModels {
+		r.resolved = make(map[string][]string)
+		r.resolvedSince = now
+	}
+	r.resolved[model] = supporting
+	return supporting
 }
 
 // List returns all registered provider names
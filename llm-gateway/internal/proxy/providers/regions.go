@@ -0,0 +1,300 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// Region is one regional base URL of a SaaS provider, e.g. an egress region
+// or an Azure OpenAI deployment
+type Region struct {
+	Name    string
+	BaseURL string
+}
+
+// Region breaker states
+const (
+	RegionClosed   = "closed"
+	RegionOpen     = "open"
+	RegionHalfOpen = "half_open"
+)
+
+const (
+	// regionFailureThreshold is the number of consecutive failures that open
+	// a region's breaker
+	regionFailureThreshold = 3
+	// regionOpenTimeout is how long an open region is skipped before a
+	// single probe request is let through
+	regionOpenTimeout = 30 * time.Second
+	// regionLatencyDecay is the weight of the newest request in a region's
+	// latency average
+	regionLatencyDecay = 0.2
+	// regionExploreEvery sends every nth request to the least recently used
+	// healthy region, so the latency of slower regions stays current
+	regionExploreEvery = 20
+)
+
+// region is the routing state of one region; its fields are guarded by
+// regionSet.mu
+type region struct {
+	Region
+
+	state    string
+	openedAt time.Time
+	probing  bool
+
+	latency  float64 // milliseconds to response headers, moving average
+	samples  int64
+	lastUsed time.Time
+
+	consecutive int
+	requests    int64
+	failures    int64
+	lastErr     string
+}
+
+// regionSet sends a provider's requests to its fastest healthy region and
+// fails over to the next region when one is unreachable or overloaded. Each
+// region has its own breaker: after regionFailureThreshold consecutive
+// failures it is skipped for regionOpenTimeout, then one probe request
+// decides whether it closes again.
+type regionSet struct {
+	provider string
+
+	mu      sync.Mutex
+	regions []*region
+	picks   uint64
+	now     func() time.Time
+}
+
+// newRegionSet creates the region set of a provider, or returns nil if no
+// regions are configured
+func newRegionSet(provider string, regions []Region) *regionSet {
+	if len(regions) == 0 {
+		return nil
+	}
+	s := &regionSet{provider: provider, now: time.Now}
+	for _, r := range regions {
+		r.BaseURL = strings.TrimSuffix(r.BaseURL, "/")
+		s.regions = append(s.regions, &region{Region: r, state: RegionClosed})
+	}
+	return s
+}
+
+// pick chooses the region for the next attempt, skipping regions already
+// tried. An open region past its timeout is probed first; otherwise regions
+// without latency samples come first, then the fastest closed region. It
+// returns nil once no usable region is left, except on the first attempt,
+// which falls back to the region open the longest.
+func (s *regionSet) pick(tried map[*region]bool) *region {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.picks++
+
+	var fastest, stalest, oldestOpen *region
+	for _, r := range s.regions {
+		if tried[r] {
+			continue
+		}
+		if r.state != RegionClosed {
+			if r.state == RegionOpen && !r.probing && now.Sub(r.openedAt) >= regionOpenTimeout {
+				r.state, r.probing = RegionHalfOpen, true
+				s.recordState(r)
+				return s.use(r, now)
+			}
+			if r.state == RegionOpen && (oldestOpen == nil || r.openedAt.Before(oldestOpen.openedAt)) {
+				oldestOpen = r
+			}
+			continue
+		}
+		if r.samples == 0 {
+			return s.use(r, now)
+		}
+		if fastest == nil || r.latency < fastest.latency {
+			fastest = r
+		}
+		if stalest == nil || r.lastUsed.Before(stalest.lastUsed) {
+			stalest = r
+		}
+	}
+	switch {
+	case fastest != nil && s.picks%regionExploreEvery == 0:
+		return s.use(stalest, now)
+	case fastest != nil:
+		return s.use(fastest, now)
+	case len(tried) == 0 && oldestOpen != nil:
+		return s.use(oldestOpen, now)
+	}
+	return nil
+}
+
+// use marks r as chosen; s.mu must be held
+func (s *regionSet) use(r *region, now time.Time) *region {
+	r.lastUsed = now
+	r.requests++
+	return r
+}
+
+// preferred returns the base URL of the region pick would choose, without
+// counting a request, for calls outside do such as listing models
+func (s *regionSet) preferred() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best *region
+	for _, r := range s.regions {
+		if r.state == RegionClosed && (best == nil || r.latency < best.latency) {
+			best = r
+		}
+	}
+	if best == nil {
+		best = s.regions[0]
+	}
+	return best.BaseURL
+}
+
+// observe records the outcome of a request to r; reason is empty on success
+func (s *regionSet) observe(r *region, latency time.Duration, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics := observability.GetMetrics()
+	metrics.RecordRegionRequest(s.provider, r.Name, reason == "")
+
+	if reason == "" {
+		ms := float64(latency.Microseconds()) / 1000
+		if r.samples == 0 {
+			r.latency = ms
+		} else {
+			r.latency += regionLatencyDecay * (ms - r.latency)
+		}
+		r.samples++
+		r.consecutive = 0
+		metrics.RegionLatencyMs.WithLabels(map[string]string{"provider": s.provider, "region": r.Name}).Set(r.latency)
+		if r.state != RegionClosed {
+			r.state, r.probing = RegionClosed, false
+			s.recordState(r)
+			logger.Info().Str("provider", s.provider).Str("region", r.Name).Msg("Provider region recovered")
+		}
+		return
+	}
+
+	r.consecutive++
+	r.failures++
+	r.lastErr = reason
+	if r.state == RegionHalfOpen || (r.state == RegionClosed && r.consecutive >= regionFailureThreshold) {
+		r.state, r.openedAt, r.probing = RegionOpen, s.now(), false
+		s.recordState(r)
+		logger.Warn().
+			Str("provider", s.provider).
+			Str("region", r.Name).
+			Str("error", reason).
+			Dur("retry_in", regionOpenTimeout).
+			Msg("Provider region degraded, failing over")
+	}
+}
+
+// release returns a region probed by a cancelled request to open, so the
+// next request probes it again
+func (s *regionSet) release(r *region) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.state == RegionHalfOpen {
+		r.state, r.probing = RegionOpen, false
+		s.recordState(r)
+	}
+}
+
+// recordState exports r's breaker state; s.mu must be held
+func (s *regionSet) recordState(r *region) {
+	open := 0.0
+	if r.state == RegionOpen {
+		open = 1
+	}
+	observability.GetMetrics().RegionBreakerOpen.WithLabels(map[string]string{"provider": s.provider, "region": r.Name}).Set(open)
+}
+
+// do sends a request with send to the chosen region, failing over to the
+// next region on transport errors and 502, 503 and 504 responses. The last
+// region's outcome is returned as is. Requests cancelled by the caller do
+// not count against a region.
+func (s *regionSet) do(ctx context.Context, send func(baseURL string) (*http.Response, error)) (*http.Response, error) {
+	tried := make(map[*region]bool)
+	r := s.pick(tried)
+	for {
+		tried[r] = true
+		start := time.Now()
+		resp, err := send(r.BaseURL)
+		if ctx.Err() != nil {
+			s.release(r)
+			return resp, err
+		}
+		reason := regionFailure(resp, err)
+		s.observe(r, time.Since(start), reason)
+		if reason == "" {
+			return resp, nil
+		}
+
+		next := s.pick(tried)
+		if next == nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		observability.GetMetrics().RecordRegionFailover(s.provider, r.Name, next.Name)
+		logger.Warn().
+			Str("provider", s.provider).
+			Str("from", r.Name).
+			Str("to", next.Name).
+			Str("error", reason).
+			Msg("Retrying request in another region")
+		r = next
+	}
+}
+
+// regionFailure returns why a response counts against its region, or ""
+func regionFailure(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status
+	}
+	return ""
+}
+
+// status returns breaker, latency and routing information per region; it
+// is empty on nil
+func (s *regionSet) status() []map[string]interface{} {
+	if s == nil {
+		return []map[string]interface{}{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := make([]map[string]interface{}, 0, len(s.regions))
+	for _, r := range s.regions {
+		entry := map[string]interface{}{
+			"region":               r.Name,
+			"base_url":             r.BaseURL,
+			"state":                r.state,
+			"healthy":              r.state == RegionClosed,
+			"latency_ms":           r.latency,
+			"routed":               r.requests,
+			"failures":             r.failures,
+			"consecutive_failures": r.consecutive,
+		}
+		if r.lastErr != "" {
+			entry["error"] = r.lastErr
+		}
+		status = append(status, entry)
+	}
+	return status
+}
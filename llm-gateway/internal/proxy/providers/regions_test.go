@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func TestRegionSet_FailsOverAndRecovers(t *testing.T) {
+	var hits [2]int
+	down := true
+	eu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[0]++
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"eu"},"finish_reason":"stop"}]}`))
+	}))
+	us := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[1]++
+		w.Write([]byte(`{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"us"},"finish_reason":"stop"}]}`))
+	}))
+	defer eu.Close()
+	defer us.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{APIKey: "sk-test", Regions: []Region{{Name: "eu", BaseURL: eu.URL}, {Name: "us", BaseURL: us.URL + "/"}}})
+	now := time.Now()
+	p.regions.now = func() time.Time { return now }
+	chat := func() string {
+		resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Choices[0].Message.Content
+	}
+
+	// Every request is retried in us while eu fails, until eu's breaker opens
+	for i := 0; i < regionFailureThreshold+2; i++ {
+		if got := chat(); got != "us" {
+			t.Fatalf("request %d answered by %q, want us", i, got)
+		}
+	}
+	if hits[0] != regionFailureThreshold {
+		t.Errorf("eu hits = %d, want %d before its breaker opens", hits[0], regionFailureThreshold)
+	}
+	status := p.InstanceStatus()
+	if status[0]["state"] != RegionOpen || status[1]["state"] != RegionClosed {
+		t.Errorf("status = %+v", status)
+	}
+
+	// After the open timeout one probe closes eu again
+	down = false
+	now = now.Add(regionOpenTimeout)
+	if got := chat(); got != "eu" {
+		t.Errorf("probe answered by %q, want eu", got)
+	}
+	if status := p.InstanceStatus(); status[0]["state"] != RegionClosed {
+		t.Errorf("eu state after probe = %v", status[0]["state"])
+	}
+}
+
+func TestRegionSet_PrefersFastestRegion(t *testing.T) {
+	s := newRegionSet("openai", []Region{{Name: "eu", BaseURL: "http://eu"}, {Name: "us", BaseURL: "http://us"}})
+
+	// Regions without samples are tried first
+	if r := s.pick(nil); r.Name != "eu" {
+		t.Fatalf("pick = %s, want eu", r.Name)
+	}
+	s.observe(s.regions[0], 200*time.Millisecond, "")
+	if r := s.pick(nil); r.Name != "us" {
+		t.Fatalf("pick = %s, want us (not measured yet)", r.Name)
+	}
+	s.observe(s.regions[1], 50*time.Millisecond, "")
+
+	counts := map[string]int{}
+	for i := 0; i < 2*regionExploreEvery; i++ {
+		counts[s.pick(nil).Name]++
+	}
+	if counts["eu"] == 0 || counts["eu"] > 2 {
+		t.Errorf("counts = %v, want us except for the occasional eu sample", counts)
+	}
+	if s.preferred() != "http://us" {
+		t.Errorf("preferred = %s", s.preferred())
+	}
+
+	// With every region open, requests still go to the one open the longest
+	for _, r := range s.regions {
+		for i := 0; i < regionFailureThreshold; i++ {
+			s.observe(r, 0, "connection refused")
+		}
+	}
+	if r := s.pick(nil); r == nil || r.Name != "eu" {
+		t.Errorf("pick with every region open = %v, want eu", r)
+	}
+	if r := s.pick(map[*region]bool{s.regions[0]: true}); r != nil {
+		t.Errorf("failover pick = %s, want none with every region open", r.Name)
+	}
+}
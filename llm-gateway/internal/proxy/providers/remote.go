@@ -0,0 +1,361 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// RemoteService is the gRPC service remote providers implement; see
+// proto/remote_provider.proto
+const RemoteService = "llmgateway.remote.v1.RemoteProvider"
+
+// RemoteAddressEnv tells a remote provider started by the gateway the
+// address to listen on
+const RemoteAddressEnv = "LLM_GATEWAY_REMOTE_ADDRESS"
+
+// errNotServing is returned by health checks of a provider reporting NOT_SERVING
+var errNotServing = errors.New("provider reported NOT_SERVING")
+
+// RemoteProviderConfig holds configuration for a remote provider
+type RemoteProviderConfig struct {
+	// Name is the provider name used for routing, metrics and logs
+	Name string
+	// Address is host:port, or unix:///path/to.sock
+	Address string
+	TLS     bool
+	Timeout time.Duration
+
+	// Models served by the provider; empty asks it with ListModels
+	Models []string
+	// ModelCacheTTL is how long a model list fetched with ListModels is reused
+	ModelCacheTTL time.Duration
+
+	// Command, if set, is started as a subprocess serving the provider
+	Command []string
+	// HealthInterval is how often the provider's Health method is polled
+	HealthInterval time.Duration
+}
+
+// RemoteProvider forwards requests to a provider implemented out of process,
+// e.g. in another language, over gRPC. Requests and replies are the
+// OpenAI-compatible JSON documents carried as google.protobuf.Struct
+// messages, so the provider needs no translation on the gateway side.
+type RemoteProvider struct {
+	config     RemoteProviderConfig
+	client     *grpcClient
+	modelCache modelCache
+
+	// ready is set once a health check passed; healthy is the latest result
+	ready   atomic.Bool
+	healthy atomic.Bool
+
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRemoteProvider creates a remote provider, starting its command if one
+// is configured, and polls its health in the background until Stop
+func NewRemoteProvider(config RemoteProviderConfig) (*RemoteProvider, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+	if config.ModelCacheTTL == 0 {
+		config.ModelCacheTTL = DefaultModelCacheTTL
+	}
+	if config.HealthInterval == 0 {
+		config.HealthInterval = 10 * time.Second
+	}
+
+	client, err := newGRPCClient(config.Name, config.Address, config.TLS, RemoteService, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	p := &RemoteProvider{
+		config: config,
+		client: client,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if len(config.Command) > 0 {
+		if err := p.startCommand(); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	go p.healthLoop()
+	return p, nil
+}
+
+// startCommand starts the configured subprocess, passing it the address in
+// LLM_GATEWAY_REMOTE_ADDRESS; its output goes to the gateway's
+func (p *RemoteProvider) startCommand() error {
+	cmd := exec.Command(p.config.Command[0], p.config.Command[1:]...)
+	cmd.Env = append(os.Environ(), RemoteAddressEnv+"="+p.config.Address)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start remote provider %s: %w", p.config.Name, err)
+	}
+
+	p.cmd = cmd
+	p.exited = make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		close(p.exited)
+		select {
+		case <-p.stop:
+		default:
+			logger.Error().
+				Err(err).
+				Str("provider", p.config.Name).
+				Int("pid", cmd.Process.Pid).
+				Msg("Remote provider process exited")
+		}
+	}()
+	logger.Info().
+		Str("provider", p.config.Name).
+		Int("pid", cmd.Process.Pid).
+		Strs("command", p.config.Command).
+		Msg("Remote provider process started")
+	return nil
+}
+
+// healthLoop checks the provider's health every HealthInterval, logging changes
+func (p *RemoteProvider) healthLoop() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.HealthInterval)
+		err := p.HealthCheck(ctx)
+		cancel()
+
+		healthy := err == nil
+		if healthy {
+			p.ready.Store(true)
+		}
+		if was := p.healthy.Swap(healthy); was != healthy {
+			event := logger.Info()
+			if !healthy {
+				event = logger.Warn().Err(err)
+			}
+			event.Str("provider", p.config.Name).Bool("healthy", healthy).Msg("Remote provider health changed")
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop ends health polling, closes the connection and terminates the
+// provider's subprocess, if any
+func (p *RemoteProvider) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.client.Close()
+		if p.cmd == nil {
+			return
+		}
+		p.cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-p.exited:
+		case <-time.After(5 * time.Second):
+			p.cmd.Process.Kill()
+			<-p.exited
+		}
+	})
+}
+
+// Ready reports whether the provider passed a health check, so the gateway
+// waits for a starting subprocess before taking traffic
+func (p *RemoteProvider) Ready() bool {
+	return p.ready.Load()
+}
+
+// Healthy reports the result of the latest health check
+func (p *RemoteProvider) Healthy() bool {
+	return p.healthy.Load()
+}
+
+// Name returns the provider name
+func (p *RemoteProvider) Name() string {
+	return p.config.Name
+}
+
+// call runs a unary method, recording it in the provider metrics
+func (p *RemoteProvider) call(ctx context.Context, method string, in, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := p.client.unary(ctx, method, in, out)
+	observability.GetMetrics().RecordProviderRequestWithTrace(p.config.Name, method, err == nil, time.Since(start), observability.SampledTraceID(ctx))
+	return err
+}
+
+// ChatCompletion performs a non-streaming chat completion
+func (p *RemoteProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	remoteReq := *req
+	remoteReq.Stream = false
+	remoteReq.StreamOptions = nil
+
+	var resp models.ChatCompletionResponse
+	if err := p.call(ctx, "ChatCompletion", &remoteReq, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ChatCompletionStream performs a streaming chat completion, converting the
+// provider's stream of chunks to SSE
+func (p *RemoteProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	remoteReq := *req
+	remoteReq.Stream = true
+
+	start := time.Now()
+	stream, err := p.client.stream(ctx, "ChatCompletionStream", &remoteReq)
+	if err != nil {
+		observability.GetMetrics().RecordProviderRequestWithTrace(p.config.Name, "ChatCompletionStream", false, time.Since(start), observability.SampledTraceID(ctx))
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer stream.Close()
+		err := p.streamToSSE(stream, pw)
+		observability.GetMetrics().RecordProviderRequestWithTrace(p.config.Name, "ChatCompletionStream", err == nil, time.Since(start), observability.SampledTraceID(ctx))
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// streamToSSE writes each chunk read from stream as an SSE event, ending
+// with [DONE] once the call succeeded
+func (p *RemoteProvider) streamToSSE(stream *grpcStream, dst io.Writer) error {
+	var compact bytes.Buffer
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			_, err = io.WriteString(dst, "data: [DONE]\n\n")
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		// One event per line, whatever the provider's JSON formatting
+		compact.Reset()
+		if err := json.Compact(&compact, msg); err != nil {
+			return fmt.Errorf("invalid chunk from %s: %w", p.config.Name, err)
+		}
+		if _, err := fmt.Fprintf(dst, "data: %s\n\n", compact.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// Completion performs a legacy completion
+func (p *RemoteProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	var resp models.CompletionResponse
+	if err := p.call(ctx, "Completion", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Embedding generates embeddings for the input
+func (p *RemoteProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	var resp models.EmbeddingResponse
+	if err := p.call(ctx, "Embedding", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListModels returns the configured models, or those the provider lists
+func (p *RemoteProvider) ListModels() []models.Model {
+	list, _ := p.modelCache.get(p.config.ModelCacheTTL, p.fetchModels)
+	return list
+}
+
+// InvalidateModels drops the cached model list
+func (p *RemoteProvider) InvalidateModels() {
+	p.modelCache.invalidate()
+}
+
+func (p *RemoteProvider) cachedModels() *modelCache {
+	return &p.modelCache
+}
+
+// fetchModels returns the configured models, or asks the provider with
+// ListModels; a failed request lists no models
+func (p *RemoteProvider) fetchModels() []models.Model {
+	if len(p.config.Models) > 0 {
+		list := make([]models.Model, len(p.config.Models))
+		for i, id := range p.config.Models {
+			list[i] = models.Model{ID: id, Object: "model", OwnedBy: p.config.Name, Provider: p.config.Name}
+		}
+		return list
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var resp struct {
+		Data []models.Model `json:"data"`
+	}
+	if err := p.call(ctx, "ListModels", struct{}{}, &resp); err != nil {
+		logger.Warn().Err(err).Str("provider", p.config.Name).Msg("Failed to list remote provider models")
+		return nil
+	}
+	for i := range resp.Data {
+		resp.Data[i].Object = "model"
+		resp.Data[i].Provider = p.config.Name
+		if resp.Data[i].OwnedBy == "" {
+			resp.Data[i].OwnedBy = p.config.Name
+		}
+	}
+	return resp.Data
+}
+
+// SupportsModel checks if the provider serves the given model
+func (p *RemoteProvider) SupportsModel(model string) bool {
+	_, ids := p.modelCache.get(p.config.ModelCacheTTL, p.fetchModels)
+	_, ok := ids[strings.ToLower(model)]
+	return ok
+}
+
+// HealthCheck calls the provider's Health method; a reply with status
+// NOT_SERVING is unhealthy
+func (p *RemoteProvider) HealthCheck(ctx context.Context) error {
+	var resp struct {
+		Status string `json:"status"`
+	}
+	if err := p.call(ctx, "Health", struct{}{}, &resp); err != nil {
+		return err
+	}
+	if resp.Status == "NOT_SERVING" {
+		return errNotServing
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxGRPCMessage bounds a single message read from a remote provider
+const maxGRPCMessage = 64 << 20
+
+// grpcStreamDesc describes the server-streaming methods
+var grpcStreamDesc = grpc.StreamDesc{ServerStreams: true}
+
+// grpcClient calls a gRPC service whose messages are google.protobuf.Struct
+// values holding the JSON documents of the OpenAI-compatible API
+type grpcClient struct {
+	provider string
+	service  string
+	conn     *grpc.ClientConn
+	// headerTimeout bounds the wait for a stream's first reply
+	headerTimeout time.Duration
+}
+
+// newGRPCClient creates a client for service at address, either host:port
+// or unix:///path/to.sock. Without useTLS it speaks cleartext HTTP/2, as
+// gRPC servers do by default. Connections are made on first use.
+func newGRPCClient(provider, address string, useTLS bool, service string, headerTimeout time.Duration) (*grpcClient, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxGRPCMessage)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address for remote provider %s: %w", provider, err)
+	}
+	return &grpcClient{provider: provider, service: service, conn: conn, headerTimeout: headerTimeout}, nil
+}
+
+// method returns the full name of a method of the service
+func (c *grpcClient) method(name string) string {
+	return "/" + c.service + "/" + name
+}
+
+// unary calls method with in and decodes its reply into out
+func (c *grpcClient) unary(ctx context.Context, method string, in, out interface{}) error {
+	req, err := toStruct(in)
+	if err != nil {
+		return err
+	}
+	var resp structpb.Struct
+	if err := c.conn.Invoke(ctx, c.method(method), req, &resp); err != nil {
+		return c.statusError(err)
+	}
+	data, err := protojson.Marshal(&resp)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s reply: %w", method, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s reply: %w", method, err)
+	}
+	return nil
+}
+
+// stream calls the server-streaming method with in, returning its replies as
+// they arrive. The first reply is awaited, so a call failing before it
+// returns its error here.
+func (c *grpcClient) stream(ctx context.Context, method string, in interface{}) (*grpcStream, error) {
+	req, err := toStruct(in)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cs, err := c.conn.NewStream(ctx, &grpcStreamDesc, c.method(method))
+	if err == nil {
+		err = cs.SendMsg(req)
+	}
+	if err == nil {
+		err = cs.CloseSend()
+	}
+	s := &grpcStream{client: c, stream: cs, cancel: cancel}
+	if err == nil {
+		// Fail a provider that accepts the call but never answers
+		var timer *time.Timer
+		if c.headerTimeout > 0 {
+			timer = time.AfterFunc(c.headerTimeout, cancel)
+		}
+		s.first, s.err = s.recv()
+		if timer != nil && !timer.Stop() {
+			s.first, s.err = nil, context.DeadlineExceeded
+		}
+		if s.err != nil && s.err != io.EOF {
+			err = s.err
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, c.statusError(err)
+	}
+	return s, nil
+}
+
+// grpcStream reads the replies of one call
+type grpcStream struct {
+	client *grpcClient
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+	// first and err are the result of the first read, made by stream
+	first *structpb.Struct
+	err   error
+	read  bool
+}
+
+// Recv returns the next message as JSON, or io.EOF once the call ended successfully
+func (s *grpcStream) Recv() ([]byte, error) {
+	msg, err := s.first, s.err
+	if s.read {
+		msg, err = s.recv()
+	}
+	s.read = true
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, s.client.statusError(err)
+	}
+	return protojson.Marshal(msg)
+}
+
+// recv reads the next message
+func (s *grpcStream) recv() (*structpb.Struct, error) {
+	var msg structpb.Struct
+	if err := s.stream.RecvMsg(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Close ends the call
+func (s *grpcStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// Close closes the connection to the service
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+// toStruct converts a JSON document to a google.protobuf.Struct
+func toStruct(in interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var s structpb.Struct
+	if err := protojson.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return &s, nil
+}
+
+// grpcCodes names the gRPC status codes, with the HTTP status the gateway
+// answers with when a remote provider returns them
+var grpcCodes = map[int]struct {
+	name   string
+	status int
+}{
+	1:  {"cancelled", 499},
+	2:  {"unknown", http.StatusBadGateway},
+	3:  {"invalid_argument", http.StatusBadRequest},
+	4:  {"deadline_exceeded", http.StatusGatewayTimeout},
+	5:  {"not_found", http.StatusNotFound},
+	7:  {"permission_denied", http.StatusForbidden},
+	8:  {"resource_exhausted", http.StatusTooManyRequests},
+	9:  {"failed_precondition", http.StatusBadRequest},
+	11: {"out_of_range", http.StatusBadRequest},
+	12: {"unimplemented", http.StatusNotImplemented},
+	13: {"internal", http.StatusInternalServerError},
+	14: {"unavailable", http.StatusServiceUnavailable},
+	16: {"unauthenticated", http.StatusUnauthorized},
+}
+
+// statusError converts the gRPC status of a failed call to a ProviderError
+func (c *grpcClient) statusError(err error) error {
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return &ProviderError{
+			Provider:   c.provider,
+			StatusCode: http.StatusBadGateway,
+			Code:       "unavailable",
+			Message:    "request failed: " + err.Error(),
+		}
+	}
+
+	code := int(st.Code())
+	known, ok := grpcCodes[code]
+	if !ok {
+		known.name, known.status = fmt.Sprintf("grpc_status_%d", code), http.StatusBadGateway
+	}
+	return &ProviderError{
+		Provider:   c.provider,
+		StatusCode: known.status,
+		Code:       known.name,
+		Message:    st.Message(),
+	}
+}
@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// fakeRemoteServer serves the remote provider methods with replies; health
+// is the status Health reports
+func fakeRemoteServer(t *testing.T, listener net.Listener, health *atomic.Value) {
+	t.Helper()
+	reply := func(stream grpc.ServerStream, msg interface{}) error {
+		data, _ := json.Marshal(msg)
+		var out structpb.Struct
+		if err := protojson.Unmarshal(data, &out); err != nil {
+			return err
+		}
+		return stream.SendMsg(&out)
+	}
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		var in structpb.Struct
+		if err := stream.RecvMsg(&in); err != nil {
+			return err
+		}
+		method, _ := grpc.MethodFromServerStream(stream)
+		switch strings.TrimPrefix(method, "/"+RemoteService+"/") {
+		case "ListModels":
+			return reply(stream, map[string]interface{}{"data": []models.Model{{ID: "Bespoke-1"}, {ID: "bespoke-2", OwnedBy: "acme"}}})
+		case "Health":
+			return reply(stream, map[string]string{"status": health.Load().(string)})
+		case "ChatCompletion":
+			if in.Fields["model"].GetStringValue() != "bespoke-1" {
+				return status.Error(codes.InvalidArgument, "unknown model")
+			}
+			return reply(stream, models.ChatCompletionResponse{ID: "chatcmpl-1", Object: "chat.completion", Model: "bespoke-1"})
+		case "ChatCompletionStream":
+			if err := reply(stream, models.ChatCompletionStreamResponse{ID: "chatcmpl-1", Object: "chat.completion.chunk"}); err != nil {
+				return err
+			}
+			return status.Error(codes.Unavailable, "model unloaded")
+		}
+		return status.Error(codes.Unimplemented, "unknown method "+method)
+	}))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+}
+
+func TestRemoteProvider_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "remote.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	var health atomic.Value
+	health.Store("NOT_SERVING")
+	fakeRemoteServer(t, listener, &health)
+
+	p, err := NewRemoteProvider(RemoteProviderConfig{Name: "bespoke", Address: "unix://" + socket, HealthInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// Models come from ListModels, attributed to the provider
+	list := p.ListModels()
+	if len(list) != 2 || list[0].Provider != "bespoke" || list[0].OwnedBy != "bespoke" || list[1].OwnedBy != "acme" {
+		t.Errorf("ListModels() = %+v", list)
+	}
+	if !p.SupportsModel("bespoke-1") || p.SupportsModel("gpt-4o") {
+		t.Error("SupportsModel() does not match the listed models")
+	}
+
+	// Not ready until a health check passes
+	if err := p.HealthCheck(context.Background()); !errors.Is(err, errNotServing) {
+		t.Errorf("HealthCheck() = %v, want errNotServing", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if p.Ready() || p.Healthy() {
+		t.Fatal("provider ready while NOT_SERVING")
+	}
+	health.Store("SERVING")
+	for deadline := time.Now().Add(2 * time.Second); !p.Ready() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !p.Ready() || !p.Healthy() {
+		t.Error("provider not ready after a passing health check")
+	}
+
+	// Requests and replies are JSON documents carried as Structs
+	resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "bespoke-1"})
+	if err != nil || resp.ID != "chatcmpl-1" || resp.Model != "bespoke-1" {
+		t.Errorf("ChatCompletion() = %+v, %v", resp, err)
+	}
+	_, err = p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "other"})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusBadRequest || providerErr.Code != "invalid_argument" {
+		t.Errorf("ChatCompletion() err = %v, want invalid_argument", err)
+	}
+
+	// A status failing the stream after messages ends it with an error
+	stream, err := p.ChatCompletionStream(context.Background(), &models.ChatCompletionRequest{Model: "bespoke-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(stream)
+	if !strings.Contains(string(data), `"chat.completion.chunk"`) || !errors.As(err, &providerErr) ||
+		providerErr.StatusCode != http.StatusServiceUnavailable || providerErr.Message != "model unloaded" {
+		t.Errorf("stream = %q, err = %v", data, err)
+	}
+}
+
+func TestRemoteProvider_Command(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "address")
+	p, err := NewRemoteProvider(RemoteProviderConfig{
+		Name:    "bespoke",
+		Address: "127.0.0.1:1",
+		Models:  []string{"bespoke-1"},
+		Command: []string{"sh", "-c", `echo "$` + RemoteAddressEnv + `" > ` + marker + `; exec sleep 30`},
+	})
+	if err != nil {
+		t.Skipf("cannot start sh: %v", err)
+	}
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, _ := os.ReadFile(marker); strings.TrimSpace(string(data)) == "127.0.0.1:1" {
+			break
+		}
+	}
+	if data, _ := os.ReadFile(marker); strings.TrimSpace(string(data)) != "127.0.0.1:1" {
+		t.Errorf("subprocess got address %q", data)
+	}
+
+	start := time.Now()
+	p.Stop()
+	if time.Since(start) > 4*time.Second {
+		t.Error("Stop() waited for the kill timeout instead of terminating the subprocess")
+	}
+	select {
+	case <-p.exited:
+	default:
+		t.Error("subprocess still running after Stop()")
+	}
+}
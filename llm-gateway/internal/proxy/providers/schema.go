@@ -0,0 +1,234 @@
+package providers
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// SupportedAPIVersions are the upstream API versions each adapter is pinned
+// to and has recorded contract fixtures for, under testdata/<provider>/<version>.
+// Ollama does not version its API; its version is the Ollama release the
+// fixtures were recorded from.
+var SupportedAPIVersions = map[string][]string{
+	"openai":    {openAIDefaultAPIVersion, "2024-10-21"},
+	"anthropic": {"2023-06-01"},
+	"ollama":    {ollamaAPIVersion},
+}
+
+const (
+	// openAIDefaultAPIVersion is api.openai.com's path version, used when no
+	// api-version query parameter is configured
+	openAIDefaultAPIVersion = "v1"
+	// ollamaAPIVersion is the Ollama release the adapter is tested against
+	ollamaAPIVersion = "0.5"
+)
+
+// supportsAPIVersion reports whether provider has fixtures for version
+func supportsAPIVersion(provider, version string) bool {
+	for _, v := range SupportedAPIVersions[provider] {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUnsupportedAPIVersion logs at startup when an adapter is configured
+// for a version its contract tests do not cover
+func warnUnsupportedAPIVersion(provider, version string) {
+	if supportsAPIVersion(provider, version) {
+		return
+	}
+	logger.Warn().
+		Str("provider", provider).
+		Str("version", version).
+		Strs("supported", SupportedAPIVersions[provider]).
+		Msg("Provider API version has no contract fixtures; responses may not decode as expected")
+}
+
+// schemaNode is the part of a response schema below one JSON value
+type schemaNode struct {
+	// open values (interface{}, maps, raw JSON) accept any content
+	open   bool
+	fields map[string]*schemaNode
+	elem   *schemaNode
+}
+
+// responseSchema is the set of response fields an adapter decodes, used to
+// spot fields an upstream added that the gateway silently drops
+type responseSchema struct {
+	provider string
+	root     *schemaNode
+	// ignored are fields known to be dropped on purpose, by API version; the
+	// "" entry applies to every version
+	ignored map[string]map[string]bool
+}
+
+// newResponseSchema builds the schema of the fields decoded into the types
+// of values; a response is usually decoded into more than one type
+func newResponseSchema(provider string, ignored map[string][]string, values ...interface{}) *responseSchema {
+	root := &schemaNode{}
+	for _, v := range values {
+		mergeSchema(root, reflect.TypeOf(v), map[reflect.Type]bool{})
+	}
+	s := &responseSchema{provider: provider, root: root, ignored: map[string]map[string]bool{}}
+	for version, fields := range ignored {
+		s.ignored[version] = map[string]bool{}
+		for _, field := range fields {
+			s.ignored[version][field] = true
+		}
+	}
+	return s
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// mergeSchema adds the JSON fields of t to node
+func mergeSchema(node *schemaNode, t reflect.Type, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		node.open = true
+		return
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.Map:
+		node.open = true
+	case reflect.Slice, reflect.Array:
+		if node.elem == nil {
+			node.elem = &schemaNode{}
+		}
+		mergeSchema(node.elem, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			// Recursive types accept anything below the first level
+			node.open = true
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		if node.fields == nil {
+			node.fields = map[string]*schemaNode{}
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			if name == "" && f.Anonymous {
+				mergeSchema(node, f.Type, seen)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			child := node.fields[name]
+			if child == nil {
+				child = &schemaNode{}
+				node.fields[name] = child
+			}
+			mergeSchema(child, f.Type, seen)
+		}
+	}
+}
+
+// unknownFields returns the paths of the fields in data the schema does not
+// decode, e.g. choices[].message.refusal, sorted and without duplicates
+func (s *responseSchema) unknownFields(version string, data []byte) []string {
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil
+	}
+	found := map[string]bool{}
+	s.walk(s.root, body, "", version, found)
+
+	fields := make([]string, 0, len(found))
+	for field := range found {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (s *responseSchema) walk(node *schemaNode, value interface{}, path, version string, found map[string]bool) {
+	if node.open {
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			next := node.fields[key]
+			if next == nil {
+				if !s.ignored[""][field] && !s.ignored[version][field] {
+					found[field] = true
+				}
+				continue
+			}
+			s.walk(next, child, field, version, found)
+		}
+	case []interface{}:
+		if node.elem == nil {
+			return
+		}
+		for _, child := range v {
+			s.walk(node.elem, child, path+"[]", version, found)
+		}
+	}
+}
+
+// warnedUnknownFields holds the provider/version/field combinations already
+// logged, so a new upstream field is logged once rather than per response
+var warnedUnknownFields sync.Map
+
+// check counts the unknown fields of a response from an upstream pinned to
+// version, logging each the first time it is seen
+func (s *responseSchema) check(version string, data []byte) {
+	for _, field := range s.unknownFields(version, data) {
+		observability.GetMetrics().RecordUpstreamUnknownField(s.provider, version, field)
+		if _, warned := warnedUnknownFields.LoadOrStore(s.provider+"/"+version+"/"+field, true); warned {
+			continue
+		}
+		logger.Warn().
+			Str("provider", s.provider).
+			Str("version", version).
+			Str("field", field).
+			Msg("Upstream response has a field the adapter does not know; check for API changes")
+	}
+}
+
+// Response schemas of the chat completion endpoints
+var (
+	openAIChatSchema = newResponseSchema("openai", map[string][]string{
+		"": {
+			"service_tier",
+			"choices[].message.refusal",
+			"usage.prompt_tokens_details",
+			"usage.completion_tokens_details",
+		},
+		// Azure OpenAI adds its content filter verdicts
+		"2024-10-21": {
+			"prompt_filter_results",
+			"choices[].content_filter_results",
+		},
+	}, models.ChatCompletionResponse{}, openAICitationBody{})
+
+	anthropicChatSchema = newResponseSchema("anthropic", map[string][]string{
+		// Prompt caching token counts are not billed separately yet
+		"": {"usage.cache_creation_input_tokens", "usage.cache_read_input_tokens"},
+	}, anthropicResponse{})
+
+	ollamaChatSchema = newResponseSchema("ollama", nil, ollamaChatResponse{})
+)
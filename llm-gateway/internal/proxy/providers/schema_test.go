@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// contractProviders builds each adapter pinned to version, and names the
+// recorded chat response fixture of its upstream
+var contractProviders = map[string]struct {
+	fixture string
+	path    string
+	new     func(baseURL, version string) Provider
+	schema  *responseSchema
+	// pinned reports whether a request carries the pinned version
+	pinned func(r *http.Request, version string) bool
+}{
+	"openai": {
+		fixture: "chat_completions.json",
+		path:    "/chat/completions",
+		new: func(baseURL, version string) Provider {
+			if version == openAIDefaultAPIVersion {
+				version = ""
+			}
+			return NewOpenAIProvider(OpenAIConfig{APIKey: "sk-test", BaseURL: baseURL, APIVersion: version})
+		},
+		schema: openAIChatSchema,
+		pinned: func(r *http.Request, version string) bool {
+			if version == openAIDefaultAPIVersion {
+				return !r.URL.Query().Has("api-version")
+			}
+			return r.URL.Query().Get("api-version") == version
+		},
+	},
+	"anthropic": {
+		fixture: "messages.json",
+		path:    "/v1/messages",
+		new: func(baseURL, version string) Provider {
+			return NewAnthropicProvider(AnthropicConfig{APIKey: "sk-ant-test", BaseURL: baseURL, Version: version})
+		},
+		schema: anthropicChatSchema,
+		pinned: func(r *http.Request, version string) bool {
+			return r.Header.Get("anthropic-version") == version
+		},
+	},
+	"ollama": {
+		fixture: "chat.json",
+		path:    "/api/chat",
+		new: func(baseURL, version string) Provider {
+			return NewOllamaProvider(OllamaProviderConfig{BaseURL: baseURL})
+		},
+		schema: ollamaChatSchema,
+		pinned: func(r *http.Request, version string) bool { return true },
+	},
+}
+
+func TestContract_RecordedFixtures(t *testing.T) {
+	for provider, versions := range SupportedAPIVersions {
+		contract, ok := contractProviders[provider]
+		if !ok {
+			t.Fatalf("no contract test for provider %s", provider)
+		}
+		for _, version := range versions {
+			t.Run(provider+"/"+version, func(t *testing.T) {
+				fixture, err := os.ReadFile(filepath.Join("testdata", provider, version, contract.fixture))
+				if err != nil {
+					t.Fatalf("missing fixture for a supported version: %v", err)
+				}
+				if unknown := contract.schema.unknownFields(version, fixture); len(unknown) > 0 {
+					t.Errorf("fixture has fields the adapter does not know: %v", unknown)
+				}
+
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != contract.path || !contract.pinned(r, version) {
+						t.Errorf("request %s is not pinned to %s", r.URL, version)
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(fixture)
+				}))
+				defer server.Close()
+
+				resp, err := contract.new(server.URL, version).ChatCompletion(context.Background(), &models.ChatCompletionRequest{
+					Model:    "test-model",
+					Messages: []models.ChatMessage{{Role: "user", Content: "What is the capital of France?"}},
+				})
+				if err != nil {
+					t.Fatalf("ChatCompletion: %v", err)
+				}
+				if len(resp.Choices) != 1 {
+					t.Fatalf("got %d choices, want 1", len(resp.Choices))
+				}
+				choice := resp.Choices[0]
+				if choice.Message.Content != "The capital of France is Paris." || choice.FinishReason != "stop" {
+					t.Errorf("choice = %q (%s), want the recorded answer (stop)", choice.Message.Content, choice.FinishReason)
+				}
+				if resp.Usage != (models.Usage{PromptTokens: 14, CompletionTokens: 8, TotalTokens: 22}) {
+					t.Errorf("usage = %+v, want 14 + 8 tokens", resp.Usage)
+				}
+			})
+		}
+	}
+}
+
+func TestResponseSchema_UnknownFields(t *testing.T) {
+	data := []byte(`{
+		"id": "chatcmpl-1",
+		"service_tier": "default",
+		"choices": [
+			{"index": 0, "message": {"role": "assistant", "content": "hi", "audio": {"id": "a"}}, "content_filter_results": {}},
+			{"index": 1, "message": {"role": "assistant", "content": "hi", "audio": null}}
+		],
+		"usage": {"prompt_tokens": 1, "cost": 0.1}
+	}`)
+
+	got := openAIChatSchema.unknownFields("v1", data)
+	want := []string{"choices[].content_filter_results", "choices[].message.audio", "usage.cost"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknown fields = %v, want %v", got, want)
+	}
+
+	// Azure's content filter verdicts are known for its pinned version
+	got = openAIChatSchema.unknownFields("2024-10-21", data)
+	want = []string{"choices[].message.audio", "usage.cost"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknown fields for 2024-10-21 = %v, want %v", got, want)
+	}
+}
+
+func TestResponseSchema_OpenValues(t *testing.T) {
+	// Tool input is raw JSON; anything below it is known
+	data := []byte(`{"content": [{"type": "tool_use", "input": {"city": "Paris", "units": {"temp": "c"}}}], "usage": {"input_tokens": 1}}`)
+	if got := anthropicChatSchema.unknownFields("2023-06-01", data); len(got) != 0 {
+		t.Errorf("unknown fields = %v, want none", got)
+	}
+}
@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/proxy/providers"
+)
+
+// maxModelConflicts bounds the conflicts kept for reporting, since model names come from clients
+const maxModelConflicts = 1000
+
+// Resolution reasons
+const (
+	ResolvedByRoute   = "route"
+	ResolvedByClaim   = "claimed"
+	ResolvedByDefault = "default"
+	ResolvedByStandby = "standby"
+	Unresolved        = "unresolved"
+)
+
+// Resolution records how a model name was resolved to a provider
+type Resolution struct {
+	Model string `json:"model"`
+	// Provider is the chosen provider; empty if none could serve the model
+	Provider string `json:"provider,omitempty"`
+	// Reason is ResolvedByRoute, ResolvedByConfig, ResolvedByClaim,
+	// ResolvedByDefault, ResolvedByStandby or Unresolved
+	Reason string `json:"reason"`
+	// Candidates are the providers claiming the model, in priority order
+	Candidates []string `json:"candidates,omitempty"`
+	// Conflict is set when more than one provider claims the model
+	Conflict bool `json:"conflict,omitempty"`
+	// Drained are the candidates skipped because they are drained
+	Drained []string `json:"drained,omitempty"`
+	// Available are the candidates that can serve the model now, excluding
+	// standbys, over which providers.balancing spreads requests
+	Available []string `json:"available,omitempty"`
+	// Steps explain each decision, in order
+	Steps []string `json:"steps"`
+	Error string   `json:"error,omitempty"`
+
+	err error
+}
+
+// ModelConflict is a model claimed by several providers
+type ModelConflict struct {
+	Model string `json:"model"`
+	// Providers claim the model, in priority order; the first wins
+	Providers []string  `json:"providers"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// ModelResolver resolves model names to providers. Aliases in the routing
+// table are resolved first. Runtime routes come first, then the routing
+// table, then the providers claiming the model, ordered by the configured
+// priority and then by name, skipping drained ones, then the default provider.
+// Standby providers claiming the model are only chosen when every other
+// claimant is drained or has an open circuit breaker.
+type ModelResolver struct {
+	registry        *providers.Registry
+	defaultProvider string
+	// priority ranks providers listed in providers.priority
+	priority map[string]int
+	// standby holds the providers listed in providers.standby
+	standby   map[string]bool
+	isDrained func(name string) bool
+	isOpen    func(name string) bool
+	// routing is the routing table from config, replaced on reload
+	routing atomic.Pointer[routingTable]
+
+	mu        sync.Mutex
+	conflicts map[string]*ModelConflict
+}
+
+// NewModelResolver creates a resolver over registry. isOpen reports whether
+// a provider's circuit breaker rejects requests.
+func NewModelResolver(registry *providers.Registry, defaultProvider string, priority, standby []string, isDrained, isOpen func(name string) bool) *ModelResolver {
+	ranks := make(map[string]int, len(priority))
+	for i, name := range priority {
+		if _, found := registry.Get(name); !found {
+			logger.Warn().Str("provider", name).Msg("Ignoring unknown provider in providers.priority")
+			continue
+		}
+		if _, dup := ranks[name]; !dup {
+			ranks[name] = i
+		}
+	}
+	standbys := make(map[string]bool, len(standby))
+	for _, name := range standby {
+		if _, found := registry.Get(name); !found {
+			logger.Warn().Str("provider", name).Msg("Ignoring unknown provider in providers.standby")
+			continue
+		}
+		standbys[name] = true
+	}
+	return &ModelResolver{
+		registry:        registry,
+		defaultProvider: defaultProvider,
+		priority:        ranks,
+		standby:         standbys,
+		isDrained:       isDrained,
+		isOpen:          isOpen,
+		conflicts:       make(map[string]*ModelConflict),
+	}
+}
+
+// Resolve resolves model, consulting routes (the runtime or canary routes) first
+func (m *ModelResolver) Resolve(model string, routes *map[string]string) Resolution {
+	res := Resolution{Model: model}
+	table := m.routing.Load()
+	if target, ok := table.alias(model); ok {
+		res.Steps = append(res.Steps, fmt.Sprintf("%s is an alias for %s", model, target))
+		model = target
+	}
+
+	// Routes set at runtime (e.g. by the control plane) take precedence
+	if name, ok := routedProvider(routes, model); ok {
+		res.Steps = append(res.Steps, fmt.Sprintf("runtime route sends %s to %s", model, name))
+		if m.isDrained(name) {
+			return res.fail(drainingError(name), name+" is drained")
+		}
+		res.Provider, res.Reason = name, ResolvedByRoute
+		return res
+	}
+	if name, step, ok := table.route(model); ok {
+		res.Steps = append(res.Steps, step)
+		if m.isDrained(name) {
+			return res.fail(drainingError(name), name+" is drained")
+		}
+		res.Provider, res.Reason = name, ResolvedByConfig
+		return res
+	}
+
+	res.Candidates = m.rank(m.registry.ProvidersForModel(model))
+	switch len(res.Candidates) {
+	case 0:
+		res.Steps = append(res.Steps, "no provider claims "+model)
+	case 1:
+		res.Steps = append(res.Steps, res.Candidates[0]+" claims "+model)
+	default:
+		res.Conflict = true
+		res.Steps = append(res.Steps, fmt.Sprintf("%s claim %s; %s", strings.Join(res.Candidates, ", "), model, m.priorityRule(res.Candidates)))
+		m.reportConflict(model, res.Candidates)
+	}
+
+	primary, down := "", true
+	var standbys []string
+	for _, name := range res.Candidates {
+		switch {
+		case m.isDrained(name):
+			res.Drained = append(res.Drained, name)
+			res.Steps = append(res.Steps, name+" is drained, skipping it")
+		case m.standby[name]:
+			standbys = append(standbys, name)
+		default:
+			if primary == "" {
+				primary = name
+			}
+			if m.isOpen(name) {
+				res.Steps = append(res.Steps, name+" has an open circuit breaker")
+			} else {
+				down = false
+				res.Available = append(res.Available, name)
+			}
+		}
+	}
+	if primary != "" && !down {
+		res.Provider, res.Reason = primary, ResolvedByClaim
+		return res
+	}
+
+	// Every other claimant is drained or failing: use a standby
+	for _, name := range standbys {
+		if m.isOpen(name) {
+			res.Steps = append(res.Steps, "standby "+name+" has an open circuit breaker, skipping it")
+			continue
+		}
+		res.Steps = append(res.Steps, "no primary provider is available, using standby "+name)
+		res.Provider, res.Reason = name, ResolvedByStandby
+		return res
+	}
+	if primary != "" {
+		res.Provider, res.Reason = primary, ResolvedByClaim
+		return res
+	}
+
+	// No claiming provider is available: use the default
+	if m.defaultProvider == "" {
+		if len(res.Drained) > 0 {
+			return res.fail(drainingError(res.Drained[0]), "no default provider is configured")
+		}
+		return res.fail(fmt.Errorf("no provider found for model: %s", model), "no default provider is configured")
+	}
+	if _, found := m.registry.Get(m.defaultProvider); !found {
+		return res.fail(fmt.Errorf("no provider found for model: %s", model), "default provider "+m.defaultProvider+" is not registered")
+	}
+	// The default only stands in for claimants if it serves the model too
+	if len(res.Candidates) > 0 && !slices.Contains(res.Candidates, m.defaultProvider) {
+		step := "default provider " + m.defaultProvider + " does not serve " + model
+		if len(res.Drained) > 0 {
+			return res.fail(drainingError(res.Drained[0]), step)
+		}
+		return res.fail(fmt.Errorf("no provider available for model: %s", model), step)
+	}
+	if m.isDrained(m.defaultProvider) {
+		return res.fail(drainingError(m.defaultProvider), "default provider "+m.defaultProvider+" is drained")
+	}
+	res.Steps = append(res.Steps, "using default provider "+m.defaultProvider)
+	res.Provider, res.Reason = m.defaultProvider, ResolvedByDefault
+	return res
+}
+
+// fail marks the resolution unresolved with a final step
+func (res Resolution) fail(err error, step string) Resolution {
+	res.Steps = append(res.Steps, step)
+	res.Reason = Unresolved
+	res.Error = err.Error()
+	res.err = err
+	return res
+}
+
+// rank orders candidates by priority, then by name
+func (m *ModelResolver) rank(candidates []string) []string {
+	ranked := slices.Clone(candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ri, iok := m.priority[ranked[i]]
+		rj, jok := m.priority[ranked[j]]
+		if iok != jok {
+			return iok
+		}
+		if iok && ri != rj {
+			return ri < rj
+		}
+		return ranked[i] < ranked[j]
+	})
+	return ranked
+}
+
+// priorityRule explains why the first candidate won
+func (m *ModelResolver) priorityRule(ranked []string) string {
+	if _, ok := m.priority[ranked[0]]; ok {
+		return ranked[0] + " ranks first in providers.priority"
+	}
+	return "none is in providers.priority, so they are ordered by name"
+}
+
+// reportConflict logs a conflict the first time it is seen
+func (m *ModelResolver) reportConflict(model string, ranked []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.conflicts[model]
+	if ok && slices.Equal(existing.Providers, ranked) {
+		return
+	}
+	if !ok && len(m.conflicts) >= maxModelConflicts {
+		return
+	}
+	m.conflicts[model] = &ModelConflict{Model: model, Providers: ranked, FirstSeen: time.Now()}
+	logger.Warn().
+		Str("model", model).
+		Strs("providers", ranked).
+		Str("chosen", ranked[0]).
+		Msg("Several providers claim the same model")
+}
+
+// Conflicts returns the models claimed by several providers seen so far
+func (m *ModelResolver) Conflicts() []ModelConflict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conflicts := make([]ModelConflict, 0, len(m.conflicts))
+	for _, conflict := range m.conflicts {
+		conflicts = append(conflicts, *conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Model < conflicts[j].Model })
+	return conflicts
+}
@@ -0,0 +1,195 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// newResolverRouter registers openai and ollama both claiming "shared-model"
+func newResolverRouter(priority ...string) *Router {
+	registry := providers.NewRegistry()
+	registry.Register("openai", &stubProvider{name: "openai", models: []string{"gpt-4o", "shared-model"}})
+	registry.Register("ollama", &stubProvider{name: "ollama", models: []string{"llama3", "shared-model"}})
+	registry.Register("anthropic", &stubProvider{name: "anthropic"})
+
+	cfg := &config.Config{}
+	cfg.Providers.Default = "anthropic"
+	cfg.Providers.Priority = priority
+	return NewRouter(registry, cfg)
+}
+
+func TestModelResolver_ConflictUsesPriority(t *testing.T) {
+	router := newResolverRouter("ollama")
+
+	res := router.ExplainModel("shared-model", false)
+	if res.Provider != "ollama" || res.Reason != ResolvedByClaim || !res.Conflict {
+		t.Fatalf("resolution = %+v, want ollama by priority", res)
+	}
+	if len(res.Candidates) != 2 || res.Candidates[1] != "openai" {
+		t.Errorf("candidates = %v, want [ollama openai]", res.Candidates)
+	}
+
+	provider, err := router.GetProviderForModel("shared-model")
+	if err != nil || provider.Name() != "ollama" {
+		t.Errorf("GetProviderForModel() = %v, %v", provider, err)
+	}
+
+	conflicts := router.ModelConflicts()
+	if len(conflicts) != 1 || conflicts[0].Model != "shared-model" || conflicts[0].Providers[0] != "ollama" {
+		t.Errorf("conflicts = %+v", conflicts)
+	}
+}
+
+func TestModelResolver_ConflictWithoutPriorityUsesName(t *testing.T) {
+	router := newResolverRouter()
+	if res := router.ExplainModel("shared-model", false); res.Provider != "ollama" || len(res.Steps) != 1 {
+		t.Errorf("resolution = %+v, want ollama by name", res)
+	}
+	if res := router.ExplainModel("gpt-4o", false); res.Conflict || res.Provider != "openai" {
+		t.Errorf("resolution = %+v, want openai without conflict", res)
+	}
+}
+
+func TestModelResolver_DrainedCandidateIsSkipped(t *testing.T) {
+	router := newResolverRouter("ollama")
+	router.DrainProvider("ollama")
+
+	res := router.ExplainModel("shared-model", false)
+	if res.Provider != "openai" || len(res.Drained) != 1 || res.Drained[0] != "ollama" {
+		t.Errorf("resolution = %+v, want openai with ollama drained", res)
+	}
+
+	// A drained sole claimant does not fall back to a default that does not
+	// serve its model
+	_, err := router.GetProviderForModel("llama3")
+	var perr *ProviderError
+	if !errors.As(err, &perr) || perr.Code != "provider_draining" || perr.Provider != "ollama" {
+		t.Errorf("err = %v, want ollama draining", err)
+	}
+	if res := router.ExplainModel("llama3", false); res.Reason != Unresolved || res.Error == "" {
+		t.Errorf("resolution = %+v, want unresolved", res)
+	}
+
+	// Models nobody claims still go to the default
+	if res := router.ExplainModel("unknown-model", false); res.Provider != "anthropic" || res.Reason != ResolvedByDefault {
+		t.Errorf("resolution = %+v, want the default provider", res)
+	}
+}
+
+func TestModelResolver_RoutesTakePrecedence(t *testing.T) {
+	router := newResolverRouter("ollama")
+	router.SetModelRoutes(map[string]string{"shared-model": "openai"})
+	router.SetCanaryModelRoutes(map[string]string{"shared-model": "anthropic"})
+
+	if res := router.ExplainModel("shared-model", false); res.Provider != "openai" || res.Reason != ResolvedByRoute {
+		t.Errorf("resolution = %+v, want openai by route", res)
+	}
+	if res := router.ExplainModel("shared-model", true); res.Provider != "anthropic" {
+		t.Errorf("canary resolution = %+v, want anthropic", res)
+	}
+}
+
+func TestModelResolver_StandbyOnlyWhenPrimariesDown(t *testing.T) {
+	primary := &stubProvider{name: "openai", models: []string{"shared-model"}, err: &ProviderError{StatusCode: 500, Message: "down"}}
+	registry := providers.NewRegistry()
+	registry.Register("openai", primary)
+	registry.Register("ollama", &stubProvider{name: "ollama", models: []string{"shared-model"}})
+
+	cfg := &config.Config{}
+	cfg.Providers.Default = "openai"
+	cfg.Providers.Priority = []string{"ollama"}
+	cfg.Providers.Standby = []string{"ollama"}
+	cfg.Reliability.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 1, SuccessThreshold: 1, Timeout: 50 * time.Millisecond, MaxHalfOpenRequests: 1}
+	router := NewRouter(registry, cfg)
+
+	// The standby ranks first by priority but is skipped while openai is up
+	if res := router.ExplainModel("shared-model", false); res.Provider != "openai" || res.Reason != ResolvedByClaim {
+		t.Fatalf("resolution = %+v, want the primary", res)
+	}
+	provider, _ := router.GetProviderForModel("shared-model")
+	provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "shared-model"})
+
+	metrics := observability.GetMetrics()
+	labels := map[string]string{"provider": "ollama", "model": "shared-model"}
+	activations := metrics.StandbyActivations.WithLabels(labels).Value()
+	for i := 0; i < 2; i++ {
+		if provider, err := router.GetProviderForModel("shared-model"); err != nil || provider.Name() != "ollama" {
+			t.Fatalf("with the primary's breaker open: %v, %v", provider, err)
+		}
+	}
+	if got := metrics.StandbyActivations.WithLabels(labels).Value() - activations; got != 1 {
+		t.Errorf("standby activations = %d, want 1", got)
+	}
+	if res := router.ExplainModel("shared-model", false); res.Reason != ResolvedByStandby {
+		t.Errorf("resolution = %+v, want the standby", res)
+	}
+
+	// Once the breaker's timeout passes, requests probe the primary again
+	time.Sleep(60 * time.Millisecond)
+	primary.err = nil
+	provider, _ = router.GetProviderForModel("shared-model")
+	if _, err := provider.ChatCompletion(context.Background(), &models.ChatCompletionRequest{Model: "shared-model"}); err != nil || provider.Name() != "openai" {
+		t.Fatalf("after recovery: %s, %v", provider.Name(), err)
+	}
+	if _, active := router.standbyActive.Load("shared-model"); active {
+		t.Error("standby still marked active after the primary recovered")
+	}
+}
+
+func TestModelResolver_RoutingTable(t *testing.T) {
+	router := newResolverRouter()
+	unknown := router.SetRouting(config.RoutingConfig{
+		Aliases: map[string]string{"default-chat": "shared-model"},
+		Models:  map[string]string{"shared-model": "openai", "lost-model": "azure"},
+		Rules: []config.RoutingRule{
+			{Models: "llama*", Provider: "anthropic"},
+			{Models: "*", Provider: "ollama"},
+		},
+	})
+	if len(unknown) != 1 || unknown[0] != "routing.models.lost-model" {
+		t.Errorf("unknown = %v, want the route to azure dropped", unknown)
+	}
+
+	tests := []struct {
+		model    string
+		provider string
+	}{
+		{"shared-model", "openai"},
+		{"Default-Chat", "openai"},
+		{"llama3", "anthropic"},
+		{"anything-else", "ollama"},
+	}
+	for _, tt := range tests {
+		res := router.ExplainModel(tt.model, false)
+		if res.Provider != tt.provider || res.Reason != ResolvedByConfig {
+			t.Errorf("ExplainModel(%s) = %+v, want %s by config", tt.model, res, tt.provider)
+		}
+	}
+	if model := router.ResolveAlias("default-chat"); model != "shared-model" {
+		t.Errorf("ResolveAlias(default-chat) = %s, want shared-model", model)
+	}
+
+	// Runtime routes still take precedence
+	router.SetModelRoutes(map[string]string{"llama3": "ollama"})
+	if res := router.ExplainModel("llama3", false); res.Provider != "ollama" || res.Reason != ResolvedByRoute {
+		t.Errorf("resolution = %+v, want the runtime route", res)
+	}
+
+	table := router.RoutingTable()
+	if len(table.Rules) != 2 || table.Models["shared-model"] != "openai" || table.RuntimeRoutes["llama3"] != "ollama" {
+		t.Errorf("routing table = %+v", table)
+	}
+
+	// A reload replaces the table
+	router.SetRouting(config.RoutingConfig{})
+	if res := router.ExplainModel("llama3", true); res.Reason == ResolvedByConfig {
+		t.Errorf("resolution = %+v after the table was cleared", res)
+	}
+}
@@ -2,16 +2,21 @@ package proxy
 
 import (
 	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/rs/zerolog/log"
-
 	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
 	"github.com/username/llm-gateway/internal/proxy/providers"
 	"github.com/username/llm-gateway/internal/reliability"
 	"github.com/username/llm-gateway/pkg/models"
 )
 
+// logger is the proxy module logger; its level can be set via log.modules.proxy
+var logger = observability.ModuleLogger("proxy")
+
 // Provider is an alias to the providers.Provider interface for external access
 type Provider = providers.Provider
 
@@ -20,21 +25,52 @@ type ProviderError = providers.ProviderError
 
 // Router handles routing requests to the appropriate provider
 type Router struct {
-	registry          *providers.Registry
-	resilientRegistry map[string]*reliability.ResilientProvider
-	config            *config.Config
-	defaultProvider   string
+	registry           *providers.Registry
+	resilientRegistry  map[string]*reliability.ResilientProvider
+	config             *config.Config
+	defaultProvider    string
 	reliabilityEnabled bool
+	drain              *drainState
+	limiters           map[string]*reliability.OutboundLimiter
+	modelRoutes        atomic.Pointer[map[string]string]
+	canaryRoutes       atomic.Pointer[map[string]string]
+	// fallbacks are the fallback chains by lowercase model name
+	fallbacks map[string][]fallbackTarget
+	resolver  *ModelResolver
+	// balancer spreads models over their providers by providers.balancing (nil without rules)
+	balancer *balancer
+	// slo shifts traffic off providers missing providers.ttft_slos (nil without rules)
+	slo *sloGuard
+	// standbyActive holds the standby provider serving each model whose
+	// primaries are unavailable, by model name
+	standbyActive sync.Map
+	// degraded is the operator's degraded mode switch
+	degraded degradedState
+	// overflow are the overflow routes of models over their outbound quota (nil when disabled)
+	overflow *overflowRoutes
 }
 
 // NewRouter creates a new proxy router
 func NewRouter(registry *providers.Registry, cfg *config.Config) *Router {
 	r := &Router{
-		registry:          registry,
-		resilientRegistry: make(map[string]*reliability.ResilientProvider),
-		config:            cfg,
-		defaultProvider:   cfg.Providers.Default,
+		registry:           registry,
+		resilientRegistry:  make(map[string]*reliability.ResilientProvider),
+		config:             cfg,
+		defaultProvider:    cfg.Providers.Default,
 		reliabilityEnabled: cfg.Reliability.CircuitBreaker.Enabled || cfg.Reliability.Retry.Enabled,
+		drain:              newDrainState(),
+		limiters:           make(map[string]*reliability.OutboundLimiter),
+	}
+	r.resolver = NewModelResolver(registry, cfg.Providers.Default, cfg.Providers.Priority, cfg.Providers.Standby, r.IsDrained, r.breakerRejecting)
+	if unknown := r.SetRouting(cfg.Routing); len(unknown) > 0 {
+		logger.Warn().Strs("routes", unknown).Msg("Ignoring routes to unknown providers")
+	}
+	r.balancer = newBalancer(cfg.Providers.Balancing)
+	r.slo = newSLOGuard(cfg.Providers.TTFTSLOs)
+	for _, rule := range cfg.Providers.TTFTSLOs {
+		if _, found := registry.Get(rule.Fallback); !found {
+			logger.Warn().Str("models", rule.Models).Str("fallback", rule.Fallback).Msg("TTFT SLO fallback is not a configured provider")
+		}
 	}
 
 	// Wrap providers with resilience features if enabled
@@ -42,37 +78,72 @@ func NewRouter(registry *providers.Registry, cfg *config.Config) *Router {
 		r.initResilientProviders()
 	}
 
+	// Shape outbound traffic to stay within upstream quotas
+	r.initOutboundLimiters()
+
+	// Retry failed requests on the models of their fallback chain
+	r.initFallbacks()
+
+	// Serve requests over a model's outbound quota with a cheaper model
+	r.initOverflow()
+
+	// Apply drains requested in config
+	for _, name := range cfg.Maintenance.DrainedProviders {
+		if err := r.DrainProvider(name); err != nil {
+			logger.Warn().Str("provider", name).Msg("Cannot drain unknown provider from config")
+		}
+	}
+	if cfg.Degraded.Enabled {
+		r.SetDegraded(true)
+	}
+
 	return r
 }
 
 // initResilientProviders wraps all providers with resilience features
 func (r *Router) initResilientProviders() {
+	// One retry budget is shared by all providers
+	var budget *reliability.RetryBudget
+	if cfg := r.config.Reliability.Retry.Budget; cfg.Enabled {
+		budget = reliability.NewRetryBudget(cfg.Percent, cfg.Window, cfg.MinRetries)
+	}
+
 	for _, name := range r.registry.List() {
 		provider, _ := r.registry.Get(name)
 
 		// Build config from settings
+		breaker := r.config.Reliability.CircuitBreaker.For(name)
 		resConfig := reliability.ResilientProviderConfig{
 			CircuitBreaker: reliability.CircuitBreakerConfig{
-				Name:                name,
-				FailureThreshold:    r.config.Reliability.CircuitBreaker.FailureThreshold,
-				SuccessThreshold:    r.config.Reliability.CircuitBreaker.SuccessThreshold,
-				Timeout:             r.config.Reliability.CircuitBreaker.Timeout,
-				MaxHalfOpenRequests: r.config.Reliability.CircuitBreaker.MaxHalfOpenRequests,
+				Name:                 name,
+				FailureThreshold:     breaker.FailureThreshold,
+				SuccessThreshold:     breaker.SuccessThreshold,
+				Timeout:              breaker.Timeout,
+				MaxHalfOpenRequests:  breaker.MaxHalfOpenRequests,
+				Mode:                 breaker.Mode,
+				WindowType:           breaker.WindowType,
+				WindowSize:           breaker.WindowSize,
+				WindowDuration:       breaker.WindowDuration,
+				FailureRateThreshold: breaker.FailureRateThreshold,
+				MinimumRequests:      breaker.MinimumRequests,
 			},
 			Retry: reliability.RetryConfig{
-				MaxRetries:        r.config.Reliability.Retry.MaxRetries,
-				InitialBackoff:    r.config.Reliability.Retry.InitialBackoff,
-				MaxBackoff:        r.config.Reliability.Retry.MaxBackoff,
-				BackoffMultiplier: r.config.Reliability.Retry.BackoffMultiplier,
-				JitterFactor:      0.2, // Default jitter
+				MaxRetries:           r.config.Reliability.Retry.MaxRetries,
+				InitialBackoff:       r.config.Reliability.Retry.InitialBackoff,
+				MaxBackoff:           r.config.Reliability.Retry.MaxBackoff,
+				BackoffMultiplier:    r.config.Reliability.Retry.BackoffMultiplier,
+				JitterFactor:         0.2, // Default jitter
+				MinAttemptTime:       r.config.Reliability.Retry.MinAttemptTime,
 				RetryableStatusCodes: []int{429, 500, 502, 503, 504},
+				Budget:               budget,
+				MaxConcurrentRetries: r.config.Reliability.Retry.MaxConcurrentRetries,
 			},
 			RequestTimeout: 60 * time.Second,
 		}
 
 		r.resilientRegistry[name] = reliability.NewResilientProvider(provider, resConfig)
 
-		log.Info().
+		logger.Info().
 			Str("provider", name).
 			Bool("circuit_breaker", r.config.Reliability.CircuitBreaker.Enabled).
 			Bool("retry", r.config.Reliability.Retry.Enabled).
@@ -80,35 +151,89 @@ func (r *Router) initResilientProviders() {
 	}
 }
 
-// GetProviderForModel returns the appropriate provider for a given model
+// GetProviderForModel returns the appropriate provider for a given model,
+// wrapped with the model's fallback chain if one is configured
 func (r *Router) GetProviderForModel(model string) (Provider, error) {
-	// First, try to find a provider that explicitly supports this model
-	provider, found := r.registry.GetForModel(model)
-	if found {
-		// Return resilient wrapper if available
-		if r.reliabilityEnabled {
-			if resilient, ok := r.resilientRegistry[provider.Name()]; ok {
-				return resilient, nil
-			}
-		}
-		return provider, nil
-	}
-
-	// If no specific provider found, use the default
-	if r.defaultProvider != "" {
-		provider, found := r.registry.Get(r.defaultProvider)
-		if found {
-			// Return resilient wrapper if available
-			if r.reliabilityEnabled {
-				if resilient, ok := r.resilientRegistry[provider.Name()]; ok {
-					return resilient, nil
-				}
-			}
+	provider, err := r.providerForModel(model, r.modelRoutes.Load())
+	return r.withFallbacks(model, provider, err)
+}
+
+func (r *Router) providerForModel(model string, routes *map[string]string) (Provider, error) {
+	res := r.resolver.Resolve(model, routes)
+	if res.err != nil {
+		return nil, res.err
+	}
+	r.trackStandby(res)
+	if name, ok := r.balancer.pick(model, res.Available); ok {
+		res.Provider = name
+	}
+	if fallback, ok := r.slo.reroute(model, res.Provider); ok {
+		if provider, err := r.GetProvider(fallback); err == nil {
 			return provider, nil
 		}
 	}
+	return r.GetProvider(res.Provider)
+}
 
-	return nil, fmt.Errorf("no provider found for model: %s", model)
+// trackStandby counts requests resolved to a standby provider and logs when
+// a model starts and stops being served by one
+func (r *Router) trackStandby(res Resolution) {
+	if res.Reason != ResolvedByStandby {
+		if previous, ok := r.standbyActive.LoadAndDelete(res.Model); ok {
+			logger.Info().
+				Str("model", res.Model).
+				Str("standby", previous.(string)).
+				Str("provider", res.Provider).
+				Msg("Primary provider available again, leaving standby")
+		}
+		return
+	}
+
+	metrics := observability.GetMetrics()
+	metrics.RecordStandbyRequest(res.Provider, res.Model)
+	if previous, ok := r.standbyActive.Swap(res.Model, res.Provider); !ok || previous.(string) != res.Provider {
+		metrics.RecordStandbyActivation(res.Provider, res.Model)
+		logger.Warn().
+			Str("model", res.Model).
+			Str("standby", res.Provider).
+			Strs("drained", res.Drained).
+			Msg("No primary provider available, routing to standby")
+	}
+}
+
+// breakerRejecting reports whether a provider's circuit breaker rejects requests
+func (r *Router) breakerRejecting(name string) bool {
+	if !r.reliabilityEnabled {
+		return false
+	}
+	resilient, ok := r.resilientRegistry[name]
+	return ok && resilient.BreakerRejecting()
+}
+
+// ExplainModel resolves model as a request would, by the canary routes if
+// canary is set, and returns every step of the decision
+func (r *Router) ExplainModel(model string, canary bool) Resolution {
+	routes := r.modelRoutes.Load()
+	if canary {
+		if canaryRoutes := r.canaryRoutes.Load(); canaryRoutes != nil {
+			routes = canaryRoutes
+		}
+	}
+	res := r.resolver.Resolve(model, routes)
+	if res.Reason == ResolvedByClaim {
+		if step, ok := r.balancer.explain(model, res.Available); ok {
+			res.Steps = append(res.Steps, step)
+		}
+	}
+	if step, ok := r.slo.explain(model, res.Provider); ok {
+		res.Steps = append(res.Steps, step)
+	}
+	return res
+}
+
+// ModelConflicts returns the models claimed by several providers seen so far
+func (r *Router) ModelConflicts() []ModelConflict {
+	return r.resolver.Conflicts()
 }
 
 // GetProvider returns a specific provider by name
@@ -118,14 +243,33 @@ func (r *Router) GetProvider(name string) (Provider, error) {
 		return nil, fmt.Errorf("provider not found: %s", name)
 	}
 
-	// Return resilient wrapper if available
+	if r.IsDrained(name) {
+		return nil, drainingError(name)
+	}
+
+	return r.wrap(provider), nil
+}
+
+// wrap returns the resilient wrapper for a provider if available, with outbound
+// rate shaping and in-flight tracking
+func (r *Router) wrap(provider Provider) Provider {
+	name := provider.Name()
 	if r.reliabilityEnabled {
 		if resilient, ok := r.resilientRegistry[name]; ok {
-			return resilient, nil
+			provider = resilient
 		}
 	}
+	if limiter, ok := r.limiters[name]; ok {
+		provider = &shapedProvider{Provider: provider, limiter: limiter}
+	}
+	return &trackedProvider{Provider: provider, inFlight: r.drain.counter(name), observe: r.balancer.observe, firstToken: r.observeFirstToken}
+}
 
-	return provider, nil
+// observeFirstToken feeds a stream's time to first token to the SLO guard and
+// the gateway's time series
+func (r *Router) observeFirstToken(name, model string, ttft time.Duration) {
+	r.slo.observe(name, model, ttft)
+	observability.GetTimeSeries().RecordTTFT(ttft, time.Now())
 }
 
 // AvailableProviders returns a list of available provider names
@@ -133,9 +277,14 @@ func (r *Router) AvailableProviders() []string {
 	return r.registry.List()
 }
 
-// ListModels returns all available models from all providers
+// ListModels returns all available models from all providers, with their
+// catalog metadata
 func (r *Router) ListModels() []models.Model {
-	return r.registry.ListAllModels()
+	list := r.registry.ListAllModels()
+	for i, m := range list {
+		list[i] = r.describeModel(m)
+	}
+	return list
 }
 
 // GetReliabilityStats returns stats for all resilient providers
@@ -147,6 +296,36 @@ func (r *Router) GetReliabilityStats() map[string]interface{} {
 	return stats
 }
 
+// BreakerStates returns the circuit breaker state of each provider
+func (r *Router) BreakerStates() map[string]string {
+	states := make(map[string]string, len(r.resilientRegistry))
+	for name, provider := range r.resilientRegistry {
+		states[name] = provider.BreakerState().String()
+	}
+	return states
+}
+
+// ResetCircuitBreaker closes a provider's circuit breaker, clearing its
+// failure counts
+func (r *Router) ResetCircuitBreaker(name string) error {
+	if _, found := r.registry.Get(name); !found {
+		return providerNotFound(name)
+	}
+	resilient, ok := r.resilientRegistry[name]
+	if !r.reliabilityEnabled || !ok {
+		return &ProviderError{
+			Provider:   name,
+			StatusCode: http.StatusConflict,
+			Code:       "circuit_breaker_disabled",
+			Message:    "Provider " + name + " has no circuit breaker",
+		}
+	}
+
+	resilient.ResetCircuitBreaker()
+	logger.Warn().Str("provider", name).Msg("Circuit breaker reset")
+	return nil
+}
+
 // IsReliabilityEnabled returns whether reliability features are enabled
 func (r *Router) IsReliabilityEnabled() bool {
 	return r.reliabilityEnabled
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/canary"
+)
+
+// SetModelRoutes replaces the runtime model -> provider routes, which take
+// precedence over the models providers advertise. Routes to unknown providers
+// are dropped and returned.
+func (r *Router) SetModelRoutes(routes map[string]string) []string {
+	valid, unknown := r.validRoutes(routes)
+	r.modelRoutes.Store(&valid)
+	return unknown
+}
+
+// SetCanaryModelRoutes sets the routes used instead of the runtime routes for
+// requests in the canary cohort; nil clears them
+func (r *Router) SetCanaryModelRoutes(routes map[string]string) []string {
+	if routes == nil {
+		r.canaryRoutes.Store(nil)
+		return nil
+	}
+	valid, unknown := r.validRoutes(routes)
+	r.canaryRoutes.Store(&valid)
+	return unknown
+}
+
+// GetProviderForRequest returns the provider for model, routing requests in
+// the canary cohort by the canary routes if set
+func (r *Router) GetProviderForRequest(ctx context.Context, model string) (Provider, error) {
+	if canary.InCanary(ctx) {
+		if routes := r.canaryRoutes.Load(); routes != nil {
+			provider, err := r.providerForModel(model, routes)
+			return r.withFallbacks(model, provider, err)
+		}
+	}
+	return r.GetProviderForModel(model)
+}
+
+// validRoutes splits routes into those to known providers and the models routed elsewhere
+func (r *Router) validRoutes(routes map[string]string) (map[string]string, []string) {
+	valid := make(map[string]string, len(routes))
+	var unknown []string
+	for model, name := range routes {
+		if _, found := r.registry.Get(name); !found {
+			unknown = append(unknown, model)
+			continue
+		}
+		valid[model] = name
+	}
+	sort.Strings(unknown)
+	return valid, unknown
+}
+
+// ModelRoutes returns the runtime model -> provider routes
+func (r *Router) ModelRoutes() map[string]string {
+	routes := r.modelRoutes.Load()
+	if routes == nil {
+		return map[string]string{}
+	}
+	return *routes
+}
+
+// routedProvider returns the provider routes send model to
+func routedProvider(routes *map[string]string, model string) (string, bool) {
+	if routes == nil {
+		return "", false
+	}
+	name, ok := (*routes)[model]
+	return name, ok
+}
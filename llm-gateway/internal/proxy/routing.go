@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+// ResolvedByConfig is the resolution reason of a model sent to a provider by
+// the routing table in config
+const ResolvedByConfig = "config"
+
+// routingTable is the routing block of the config, with the routes to
+// unknown providers dropped
+type routingTable struct {
+	// aliases and models are keyed by lowercase model name
+	aliases map[string]string
+	models  map[string]string
+	rules   []config.RoutingRule
+}
+
+// RoutingTable is the effective routing table, as reported by the admin API
+type RoutingTable struct {
+	Aliases map[string]string    `json:"aliases"`
+	Models  map[string]string    `json:"models"`
+	Rules   []config.RoutingRule `json:"rules"`
+	// RuntimeRoutes are set at runtime and take precedence over the config
+	RuntimeRoutes   map[string]string `json:"runtime_routes"`
+	DefaultProvider string            `json:"default_provider,omitempty"`
+}
+
+// alias returns the model an alias stands for
+func (t *routingTable) alias(model string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	target, ok := t.aliases[strings.ToLower(model)]
+	return target, ok
+}
+
+// route returns the provider the table sends model to, and the step
+// explaining it
+func (t *routingTable) route(model string) (name, step string, ok bool) {
+	if t == nil {
+		return "", "", false
+	}
+	if name, ok := t.models[strings.ToLower(model)]; ok {
+		return name, "routing.models sends " + model + " to " + name, true
+	}
+	for _, rule := range t.rules {
+		if ok, _ := path.Match(rule.Models, model); ok || rule.Models == model {
+			return rule.Provider, "routing rule " + rule.Models + " sends " + model + " to " + rule.Provider, true
+		}
+	}
+	return "", "", false
+}
+
+// SetRouting replaces the routing table. Routes to unknown providers are
+// dropped and returned, as "routing.models.<model>" or "routing.rules[i]".
+func (r *Router) SetRouting(cfg config.RoutingConfig) []string {
+	table := &routingTable{
+		aliases: make(map[string]string, len(cfg.Aliases)),
+		models:  make(map[string]string, len(cfg.Models)),
+	}
+	for alias, model := range cfg.Aliases {
+		table.aliases[strings.ToLower(alias)] = model
+	}
+
+	var unknown []string
+	for model, name := range cfg.Models {
+		if _, found := r.registry.Get(name); !found {
+			unknown = append(unknown, "routing.models."+model)
+			continue
+		}
+		table.models[strings.ToLower(model)] = name
+	}
+	for i, rule := range cfg.Rules {
+		if _, found := r.registry.Get(rule.Provider); !found {
+			unknown = append(unknown, fmt.Sprintf("routing.rules[%d]", i))
+			continue
+		}
+		table.rules = append(table.rules, rule)
+	}
+	sort.Strings(unknown)
+
+	r.resolver.routing.Store(table)
+	return unknown
+}
+
+// ResolveAlias returns the model an alias in the routing table stands for,
+// or model if it is not an alias
+func (r *Router) ResolveAlias(model string) string {
+	if target, ok := r.resolver.routing.Load().alias(model); ok {
+		return target
+	}
+	return model
+}
+
+// RoutingTable returns the effective routing table
+func (r *Router) RoutingTable() RoutingTable {
+	table := RoutingTable{
+		Aliases:         map[string]string{},
+		Models:          map[string]string{},
+		Rules:           []config.RoutingRule{},
+		RuntimeRoutes:   maps.Clone(r.ModelRoutes()),
+		DefaultProvider: r.defaultProvider,
+	}
+	if t := r.resolver.routing.Load(); t != nil {
+		maps.Copy(table.Aliases, t.aliases)
+		maps.Copy(table.Models, t.models)
+		table.Rules = append(table.Rules, t.rules...)
+	}
+	return table
+}
@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/username/llm-gateway/internal/reliability"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// initOutboundLimiters creates a limiter for every provider with an outbound quota
+func (r *Router) initOutboundLimiters() {
+	for name, limit := range r.config.Providers.OutboundLimits {
+		if _, found := r.registry.Get(name); !found {
+			logger.Warn().Str("provider", name).Msg("Outbound limit configured for unknown provider")
+			continue
+		}
+		if limit.RequestsPerSec <= 0 && limit.TokensPerMin <= 0 {
+			continue
+		}
+
+		r.limiters[name] = reliability.NewOutboundLimiter(reliability.OutboundLimiterConfig{
+			Name:           name,
+			RequestsPerSec: limit.RequestsPerSec,
+			TokensPerMin:   limit.TokensPerMin,
+			MaxQueue:       limit.MaxQueue,
+			MaxWait:        limit.MaxWait,
+		})
+
+		logger.Info().
+			Str("provider", name).
+			Float64("requests_per_sec", limit.RequestsPerSec).
+			Int("tokens_per_min", limit.TokensPerMin).
+			Msg("Outbound rate shaping enabled")
+	}
+}
+
+// OutboundLimitStats returns outbound rate shaping stats per provider
+func (r *Router) OutboundLimitStats() []map[string]interface{} {
+	names := make([]string, 0, len(r.limiters))
+	for name := range r.limiters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		entry := r.limiters[name].Stats()
+		entry["provider"] = name
+		stats = append(stats, entry)
+	}
+	return stats
+}
+
+// FlushOutboundQueues rejects the requests waiting for outbound quota to a
+// provider, or to every provider if name is empty, and returns how many were
+// waiting per provider
+func (r *Router) FlushOutboundQueues(name string) (map[string]int, error) {
+	if name != "" {
+		if _, found := r.registry.Get(name); !found {
+			return nil, providerNotFound(name)
+		}
+	}
+
+	flushed := make(map[string]int)
+	for provider, limiter := range r.limiters {
+		if name == "" || provider == name {
+			flushed[provider] = limiter.Flush()
+		}
+	}
+	logger.Warn().
+		Str("provider", name).
+		Interface("flushed", flushed).
+		Msg("Outbound request queues flushed")
+	return flushed, nil
+}
+
+// shapedProvider waits for outbound quota before dispatching to the provider.
+// It wraps the resilient provider so retries of one request share one slot.
+type shapedProvider struct {
+	Provider
+	limiter *reliability.OutboundLimiter
+}
+
+func (p *shapedProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	if err := p.wait(ctx, chatTokens(req)); err != nil {
+		return nil, err
+	}
+	return p.Provider.ChatCompletion(ctx, req)
+}
+
+func (p *shapedProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error) {
+	if err := p.wait(ctx, chatTokens(req)); err != nil {
+		return nil, err
+	}
+	return p.Provider.ChatCompletionStream(ctx, req)
+}
+
+func (p *shapedProvider) Completion(ctx context.Context, req *models.CompletionRequest) (*models.CompletionResponse, error) {
+	if err := p.wait(ctx, estimateTokens(req.Prompt)+req.MaxTokens); err != nil {
+		return nil, err
+	}
+	return p.Provider.Completion(ctx, req)
+}
+
+func (p *shapedProvider) Embedding(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	if err := p.wait(ctx, embeddingTokens(req)); err != nil {
+		return nil, err
+	}
+	return p.Provider.Embedding(ctx, req)
+}
+
+// wait queues for quota and converts limiter errors into provider errors
+func (p *shapedProvider) wait(ctx context.Context, tokens int) error {
+	err := p.limiter.Wait(ctx, tokens)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, reliability.ErrOutboundQueueFlushed) {
+		return &ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       "upstream_queue_flushed",
+			Message:    "The request was waiting for outbound quota to " + p.Name() + " when an operator flushed the queue",
+		}
+	}
+	if !errors.Is(err, reliability.ErrOutboundQueueFull) && !errors.Is(err, reliability.ErrOutboundWaitExceeded) {
+		return err
+	}
+
+	logger.Warn().Err(err).Str("provider", p.Name()).Int("tokens", tokens).Msg("Outbound quota exhausted")
+	return &ProviderError{
+		Provider:   p.Name(),
+		StatusCode: http.StatusTooManyRequests,
+		Code:       "upstream_quota_exceeded",
+		Message:    "Gateway outbound quota for " + p.Name() + " is exhausted: " + err.Error(),
+	}
+}
+
+// estimateTokens approximates the token count of text at ~4 characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// chatTokens estimates the prompt tokens plus the requested completion budget
+func chatTokens(req *models.ChatCompletionRequest) int {
+	tokens := req.MaxTokens
+	for _, msg := range req.Messages {
+		tokens += estimateTokens(msg.Text())
+	}
+	return tokens
+}
+
+// embeddingTokens estimates the tokens of a string or []string input
+func embeddingTokens(req *models.EmbeddingRequest) int {
+	switch input := req.Input.(type) {
+	case string:
+		return estimateTokens(input)
+	case []string:
+		tokens := 0
+		for _, s := range input {
+			tokens += estimateTokens(s)
+		}
+		return tokens
+	case []interface{}:
+		tokens := 0
+		for _, v := range input {
+			if s, ok := v.(string); ok {
+				tokens += estimateTokens(s)
+			}
+		}
+		return tokens
+	}
+	return 0
+}
@@ -0,0 +1,368 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/notify"
+)
+
+const (
+	// defaultSLOPercentile is the rolling TTFT percentile compared with the target
+	defaultSLOPercentile = 0.9
+	// defaultSLOWindow is the number of streams the percentile is taken over
+	defaultSLOWindow = 20
+	// defaultSLOShift is the fraction of traffic moved to the fallback
+	defaultSLOShift = 0.5
+	// maxSLODecisions is the number of reroute decisions kept for the admin API
+	maxSLODecisions = 100
+)
+
+// SLO decision actions
+const (
+	SLOReroute = "reroute"
+	SLORecover = "recover"
+	SLORevert  = "revert"
+)
+
+// SLODecision records a TTFT SLO reroute being started or ended
+type SLODecision struct {
+	Time     time.Time `json:"time"`
+	Model    string    `json:"model"`
+	Provider string    `json:"provider"`
+	Fallback string    `json:"fallback"`
+	// Action is SLOReroute, SLORecover, or SLORevert when an operator ended it
+	Action string `json:"action"`
+	// TTFTMs is the rolling percentile that triggered the decision
+	TTFTMs   float64 `json:"ttft_ms,omitempty"`
+	TargetMs float64 `json:"target_ms"`
+	Actor    string  `json:"actor,omitempty"`
+}
+
+// SLOStatus is the TTFT SLO state of a model on its provider
+type SLOStatus struct {
+	ID       string  `json:"id"`
+	Model    string  `json:"model"`
+	Provider string  `json:"provider"`
+	Fallback string  `json:"fallback"`
+	TargetMs float64 `json:"target_ms"`
+	// TTFTMs is the rolling percentile over the samples since the last decision
+	TTFTMs   float64    `json:"ttft_ms"`
+	Samples  int        `json:"samples"`
+	Rerouted bool       `json:"rerouted"`
+	Since    *time.Time `json:"since,omitempty"`
+	Shift    float64    `json:"shift"`
+	// Shifted is the number of requests sent to the fallback
+	Shifted int64 `json:"shifted"`
+}
+
+// sloRoute is the TTFT state of one model on its assigned provider
+type sloRoute struct {
+	rule     config.TTFTSLORule
+	model    string
+	provider string
+
+	// samples is a ring of the latest first-token latencies
+	samples  []time.Duration
+	next     int
+	filled   int
+	rerouted bool
+	since    time.Time
+	// credit accumulates the shift fraction; a request goes to the fallback
+	// each time it reaches 1
+	credit  float64
+	shifted int64
+}
+
+// sloGuard enforces providers.ttft_slos. It keeps a rolling window of the
+// first-token latency of each model's streams on the provider it is routed
+// to. Once the window's percentile exceeds the target, a fraction of the
+// model's requests go to the fallback provider; once a fresh window on the
+// provider is within target again, they return. The window is restarted on
+// every decision, so one slow burst does not flap the route.
+type sloGuard struct {
+	rules []config.TTFTSLORule
+
+	mu sync.Mutex
+	// routes are keyed by model and provider
+	routes    map[string]*sloRoute
+	decisions []SLODecision
+	now       func() time.Time
+}
+
+// newSLOGuard creates a guard, or returns nil if no rules are configured
+func newSLOGuard(rules []config.TTFTSLORule) *sloGuard {
+	if len(rules) == 0 {
+		return nil
+	}
+	normalized := make([]config.TTFTSLORule, len(rules))
+	for i, rule := range rules {
+		if rule.Percentile == 0 {
+			rule.Percentile = defaultSLOPercentile
+		}
+		if rule.Window == 0 {
+			rule.Window = defaultSLOWindow
+		}
+		if rule.Shift == 0 {
+			rule.Shift = defaultSLOShift
+		}
+		normalized[i] = rule
+	}
+	return &sloGuard{rules: normalized, routes: make(map[string]*sloRoute), now: time.Now}
+}
+
+// sloRouteID identifies the state of a model on a provider
+func sloRouteID(model, provider string) string {
+	return model + "@" + provider
+}
+
+// rule returns the first rule matching model
+func (g *sloGuard) rule(model string) (config.TTFTSLORule, bool) {
+	for _, rule := range g.rules {
+		if ok, _ := path.Match(rule.Models, model); ok || rule.Models == model {
+			return rule, true
+		}
+	}
+	return config.TTFTSLORule{}, false
+}
+
+// routeLocked returns the state of model on provider, creating it if a rule
+// applies; g.mu must be held
+func (g *sloGuard) routeLocked(model, provider string) *sloRoute {
+	id := sloRouteID(model, provider)
+	if s, ok := g.routes[id]; ok {
+		return s
+	}
+	rule, ok := g.rule(model)
+	if !ok || rule.Fallback == provider {
+		return nil
+	}
+	s := &sloRoute{rule: rule, model: model, provider: provider, samples: make([]time.Duration, rule.Window)}
+	g.routes[id] = s
+	return s
+}
+
+// reroute returns the fallback provider for a request for model routed to
+// provider, if the route is shifted and it is the request's turn
+func (g *sloGuard) reroute(model, provider string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := g.routeLocked(model, provider)
+	if s == nil || !s.rerouted {
+		return "", false
+	}
+	s.credit += s.rule.Shift
+	if s.credit < 1 {
+		return "", false
+	}
+	s.credit--
+	s.shifted++
+	return s.rule.Fallback, true
+}
+
+// observe records the first-token latency of a stream of model from provider
+func (g *sloGuard) observe(provider, model string, ttft time.Duration) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	s := g.routes[sloRouteID(model, provider)]
+	if s == nil {
+		g.mu.Unlock()
+		return
+	}
+	s.samples[s.next] = ttft
+	s.next = (s.next + 1) % len(s.samples)
+	s.filled = min(s.filled+1, len(s.samples))
+	if s.filled < len(s.samples) {
+		g.mu.Unlock()
+		return
+	}
+
+	var decision *SLODecision
+	p := s.percentile()
+	switch {
+	case !s.rerouted && p > s.rule.Target:
+		s.rerouted, s.since, s.credit = true, g.now(), 0
+		decision = g.decideLocked(s, SLOReroute, p, "")
+	case s.rerouted && p <= s.rule.Target:
+		s.rerouted = false
+		decision = g.decideLocked(s, SLORecover, p, "")
+	}
+	g.mu.Unlock()
+
+	if decision != nil {
+		reportSLODecision(decision, s.rule.Shift)
+	}
+}
+
+// percentile returns the configured percentile of the window
+func (s *sloRoute) percentile() time.Duration {
+	if s.filled == 0 {
+		return 0
+	}
+	sorted := slices.Clone(s.samples[:s.filled])
+	slices.Sort(sorted)
+	i := int(math.Ceil(s.rule.Percentile*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+// decideLocked records a decision and restarts the route's window; g.mu must be held
+func (g *sloGuard) decideLocked(s *sloRoute, action string, ttft time.Duration, actor string) *SLODecision {
+	s.filled, s.next = 0, 0
+	d := SLODecision{
+		Time:     g.now().UTC(),
+		Model:    s.model,
+		Provider: s.provider,
+		Fallback: s.rule.Fallback,
+		Action:   action,
+		TTFTMs:   durationMs(ttft),
+		TargetMs: durationMs(s.rule.Target),
+		Actor:    actor,
+	}
+	g.decisions = append(g.decisions, d)
+	if len(g.decisions) > maxSLODecisions {
+		g.decisions = g.decisions[len(g.decisions)-maxSLODecisions:]
+	}
+	return &d
+}
+
+// reportSLODecision logs a decision and emits it as a "slo.ttft_reroute",
+// "slo.ttft_recover" or "slo.ttft_revert" event
+func reportSLODecision(d *SLODecision, shift float64) {
+	var event *zerolog.Event
+	var msg string
+	switch d.Action {
+	case SLOReroute:
+		event = logger.Warn().Float64("shift", shift)
+		msg = "Provider missing TTFT SLO, shifting traffic to fallback"
+	case SLORevert:
+		event = logger.Info().Str("actor", d.Actor)
+		msg = "TTFT SLO reroute reverted"
+	default:
+		event = logger.Info()
+		msg = "Provider back within TTFT SLO, restoring route"
+	}
+	event.
+		Str("model", d.Model).
+		Str("provider", d.Provider).
+		Str("fallback", d.Fallback).
+		Float64("ttft_ms", d.TTFTMs).
+		Float64("target_ms", d.TargetMs).
+		Msg(msg)
+
+	notify.Default().Emit(notify.Event{
+		Type: "slo.ttft_" + d.Action,
+		Data: map[string]interface{}{
+			"model":     d.Model,
+			"provider":  d.Provider,
+			"fallback":  d.Fallback,
+			"ttft_ms":   d.TTFTMs,
+			"target_ms": d.TargetMs,
+			"shift":     shift,
+		},
+	})
+}
+
+// revert ends the reroute of a route by ID and restarts its window, so the
+// provider must miss the target over a fresh window to be rerouted again
+func (g *sloGuard) revert(id, actor string) (SLODecision, bool) {
+	if g == nil {
+		return SLODecision{}, false
+	}
+	g.mu.Lock()
+	s, ok := g.routes[id]
+	if !ok || !s.rerouted {
+		g.mu.Unlock()
+		return SLODecision{}, false
+	}
+	s.rerouted = false
+	d := g.decideLocked(s, SLORevert, 0, actor)
+	g.mu.Unlock()
+
+	reportSLODecision(d, s.rule.Shift)
+	return *d, true
+}
+
+// status returns the state of every route with a TTFT SLO
+func (g *sloGuard) status() []SLOStatus {
+	if g == nil {
+		return []SLOStatus{}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := make([]SLOStatus, 0, len(g.routes))
+	for id, s := range g.routes {
+		entry := SLOStatus{
+			ID:       id,
+			Model:    s.model,
+			Provider: s.provider,
+			Fallback: s.rule.Fallback,
+			TargetMs: durationMs(s.rule.Target),
+			TTFTMs:   durationMs(s.percentile()),
+			Samples:  s.filled,
+			Rerouted: s.rerouted,
+			Shift:    s.rule.Shift,
+			Shifted:  s.shifted,
+		}
+		if s.rerouted {
+			since := s.since.UTC()
+			entry.Since = &since
+		}
+		status = append(status, entry)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].ID < status[j].ID })
+	return status
+}
+
+// explain describes an active reroute of model on provider, for ExplainModel
+func (g *sloGuard) explain(model, provider string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.routes[sloRouteID(model, provider)]
+	if !ok || !s.rerouted {
+		return "", false
+	}
+	return fmt.Sprintf("%s misses its TTFT SLO of %s, %.0f%% of requests go to %s",
+		provider, s.rule.Target, s.rule.Shift*100, s.rule.Fallback), true
+}
+
+// durationMs converts d to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// TTFTSLOStatus returns the TTFT SLO state of each model routed so far
+func (r *Router) TTFTSLOStatus() []SLOStatus {
+	return r.slo.status()
+}
+
+// TTFTSLODecisions returns the recent TTFT SLO reroute decisions, oldest first
+func (r *Router) TTFTSLODecisions() []SLODecision {
+	if r.slo == nil {
+		return []SLODecision{}
+	}
+	r.slo.mu.Lock()
+	defer r.slo.mu.Unlock()
+	return append([]SLODecision{}, r.slo.decisions...)
+}
+
+// RevertTTFTSLO routes a rerouted model back to its provider; id is
+// "model@provider". It reports false if the route is not rerouted.
+func (r *Router) RevertTTFTSLO(id, actor string) (SLODecision, bool) {
+	return r.slo.revert(id, actor)
+}
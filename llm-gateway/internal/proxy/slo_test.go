@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// newSLORouter routes gpt-4o to openai, with a 1s TTFT SLO falling back to azure
+func newSLORouter() *Router {
+	registry := providers.NewRegistry()
+	registry.Register("openai", &stubProvider{name: "openai", models: []string{"gpt-4o"}})
+	registry.Register("azure", &stubProvider{name: "azure"})
+
+	cfg := &config.Config{}
+	cfg.Providers.TTFTSLOs = []config.TTFTSLORule{{Models: "gpt-4o*", Target: time.Second, Window: 4, Fallback: "azure"}}
+	return NewRouter(registry, cfg)
+}
+
+func routedTo(t *testing.T, r *Router, n int) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		p, err := r.GetProviderForModel("gpt-4o")
+		if err != nil {
+			t.Fatalf("GetProviderForModel() = %v", err)
+		}
+		counts[p.Name()]++
+	}
+	return counts
+}
+
+func TestSLOGuard_ReroutesAndRecovers(t *testing.T) {
+	r := newSLORouter()
+	if counts := routedTo(t, r, 4); counts["openai"] != 4 {
+		t.Fatalf("within SLO: %v", counts)
+	}
+
+	// The p90 of a window of 4 is its slowest stream
+	for _, ttft := range []time.Duration{200, 300, 400, 2000} {
+		r.slo.observe("openai", "gpt-4o", ttft*time.Millisecond)
+	}
+	if counts := routedTo(t, r, 10); counts["openai"] != 5 || counts["azure"] != 5 {
+		t.Errorf("rerouted: %v, want half to azure", counts)
+	}
+	status := r.TTFTSLOStatus()
+	if len(status) != 1 || status[0].ID != "gpt-4o@openai" || !status[0].Rerouted || status[0].Shifted != 5 || status[0].Samples != 0 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Fallback streams do not count towards the provider's window
+	for i := 0; i < 4; i++ {
+		r.slo.observe("azure", "gpt-4o", 5*time.Second)
+		r.slo.observe("openai", "gpt-4o", 300*time.Millisecond)
+	}
+	if counts := routedTo(t, r, 4); counts["openai"] != 4 {
+		t.Errorf("recovered: %v", counts)
+	}
+	decisions := r.TTFTSLODecisions()
+	if len(decisions) != 2 || decisions[0].Action != SLOReroute || decisions[0].TTFTMs != 2000 || decisions[1].Action != SLORecover {
+		t.Errorf("decisions = %+v", decisions)
+	}
+}
+
+func TestSLOGuard_Revert(t *testing.T) {
+	r := newSLORouter()
+	routedTo(t, r, 1)
+	if _, ok := r.RevertTTFTSLO("gpt-4o@openai", "ops"); ok {
+		t.Error("a route within its SLO cannot be reverted")
+	}
+	for i := 0; i < 4; i++ {
+		r.slo.observe("openai", "gpt-4o", 3*time.Second)
+	}
+	d, ok := r.RevertTTFTSLO("gpt-4o@openai", "ops")
+	if !ok || d.Action != SLORevert || d.Actor != "ops" {
+		t.Fatalf("RevertTTFTSLO() = %+v, %v", d, ok)
+	}
+	if counts := routedTo(t, r, 4); counts["openai"] != 4 {
+		t.Errorf("reverted: %v", counts)
+	}
+}
+
+func TestTrackedStream_FirstToken(t *testing.T) {
+	r := newSLORouter()
+	p, _ := r.GetProviderForModel("gpt-4o")
+	stream, err := p.ChatCompletionStream(context.Background(), &models.ChatCompletionRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(stream)
+	stream.Close()
+	if status := r.TTFTSLOStatus(); len(status) != 1 || status[0].Samples != 1 {
+		t.Errorf("status = %+v, want one sample from the stream", status)
+	}
+}
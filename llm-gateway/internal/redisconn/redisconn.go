@@ -0,0 +1,261 @@
+// Package redisconn is the Redis client shared by the gateway's Redis users:
+// the response cache, the API key store, the leader lock and SLA reports. It
+// speaks RESP directly over a small pool of connections, pipelining
+// multi-command calls, which avoids a client library dependency.
+package redisconn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error is an error reply from the server; the connection stays usable
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// ErrClosed is returned by calls on a closed client
+var ErrClosed = errors.New("redis: client closed")
+
+// Options configures a client
+type Options struct {
+	Address  string
+	Password string
+	DB       int
+	// PoolSize is the number of idle connections kept open; with 0 every
+	// call dials its own connection, for infrequent callers
+	PoolSize int
+	// Timeout bounds dialing and each call, on top of the context deadline
+	// (0 leaves only the context deadline)
+	Timeout time.Duration
+}
+
+// Client runs commands against one Redis server
+type Client struct {
+	opts Options
+
+	// idle holds open connections for reuse
+	idle   chan *conn
+	mu     sync.Mutex
+	closed bool
+}
+
+// conn is one connection to the server
+type conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// New creates a client; connections are dialed on first use
+func New(opts Options) *Client {
+	return &Client{opts: opts, idle: make(chan *conn, max(opts.PoolSize, 0))}
+}
+
+// Address returns the server address
+func (c *Client) Address() string {
+	return c.opts.Address
+}
+
+// Do runs one command and returns its reply. A server error reply is
+// returned as an Error.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	replies, err := c.Pipeline(ctx, args)
+	if len(replies) == 0 {
+		return nil, err
+	}
+	return replies[0], err
+}
+
+// Pipeline sends commands in one pipeline and returns their replies in
+// order. A server error reply to any command is returned as the error,
+// after every reply has been read.
+func (c *Client) Pipeline(ctx context.Context, cmds ...[]string) ([]interface{}, error) {
+	cn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cn.conn.SetDeadline(c.deadline(ctx))
+
+	replies, err := cn.pipeline(cmds)
+	var serverErr Error
+	if err != nil && !errors.As(err, &serverErr) {
+		// The connection may hold a partial reply
+		cn.conn.Close()
+		return nil, err
+	}
+	c.release(cn)
+	return replies, err
+}
+
+// Close closes the idle connections; later calls fail with ErrClosed
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.idle)
+	for cn := range c.idle {
+		cn.conn.Close()
+	}
+	return nil
+}
+
+// deadline is the earlier of the context deadline and Timeout from now
+func (c *Client) deadline(ctx context.Context) time.Time {
+	deadline, ok := ctx.Deadline()
+	if c.opts.Timeout > 0 {
+		if d := time.Now().Add(c.opts.Timeout); !ok || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	return deadline
+}
+
+// conn returns an idle connection or dials a new one
+func (c *Client) conn(ctx context.Context) (*conn, error) {
+	select {
+	case cn, ok := <-c.idle:
+		if ok {
+			return cn, nil
+		}
+		return nil, ErrClosed
+	default:
+	}
+
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", c.opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("redis connect failed: %w", err)
+	}
+	nc.SetDeadline(c.deadline(ctx))
+
+	cn := &conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	var setup [][]string
+	if c.opts.Password != "" {
+		setup = append(setup, []string{"AUTH", c.opts.Password})
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	if len(setup) > 0 {
+		if _, err := cn.pipeline(setup); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// release returns a connection to the pool, or closes it if the pool is full or closed
+func (c *Client) release(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		select {
+		case c.idle <- cn:
+			return
+		default:
+		}
+	}
+	cn.conn.Close()
+}
+
+// pipeline writes commands as RESP arrays in one flush and reads all replies.
+// The first server error is returned after every reply has been read.
+func (cn *conn) pipeline(cmds [][]string) ([]interface{}, error) {
+	for _, args := range cmds {
+		WriteCommand(cn.w, args...)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis write failed: %w", err)
+	}
+
+	replies := make([]interface{}, len(cmds))
+	var firstErr error
+	for i := range cmds {
+		reply, err := ReadReply(cn.r)
+		var serverErr Error
+		if err != nil && !errors.As(err, &serverErr) {
+			return nil, err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
+
+// WriteCommand writes a command as a RESP array
+func WriteCommand(w io.Writer, args ...string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// ReadReply reads one RESP reply; bulk strings are returned as strings, nil
+// as nil and server errors as Error. A server error inside an array is kept
+// in place as a nil item.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read failed: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis read failed: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := ReadReply(r)
+			var serverErr Error
+			if err != nil && !errors.As(err, &serverErr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
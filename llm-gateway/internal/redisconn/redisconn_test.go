@@ -0,0 +1,121 @@
+package redisconn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteCommand(t *testing.T) {
+	var out bytes.Buffer
+	WriteCommand(&out, "EVAL", "return 1", "0")
+	if want := "*3\r\n$4\r\nEVAL\r\n$8\r\nreturn 1\r\n$1\r\n0\r\n"; out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    interface{}
+		wantErr bool
+	}{
+		{"+OK\r\n", "OK", false},
+		{":0\r\n", int64(0), false},
+		{"$5\r\nhello\r\n", "hello", false},
+		{"$-1\r\n", nil, false},
+		{"-NOAUTH Authentication required\r\n", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ReadReply(bufio.NewReader(strings.NewReader(tt.in)))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ReadReply(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+
+	got, err := ReadReply(bufio.NewReader(strings.NewReader("*2\r\n$1\r\na\r\n:2\r\n")))
+	items, ok := got.([]interface{})
+	if err != nil || !ok || len(items) != 2 || items[0] != "a" || items[1] != int64(2) {
+		t.Errorf("array reply = %v, %v", got, err)
+	}
+}
+
+// fakeServer answers PING with PONG and anything else with an error reply,
+// counting connections
+func fakeServer(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var dials atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			dials.Add(1)
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					cmd, err := ReadReply(r)
+					if err != nil {
+						return
+					}
+					if cmd.([]interface{})[0] == "PING" {
+						conn.Write([]byte("+PONG\r\n"))
+					} else {
+						conn.Write([]byte("-ERR unknown command\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), &dials
+}
+
+func TestClient_PipelineAndPooling(t *testing.T) {
+	addr, dials := fakeServer(t)
+	c := New(Options{Address: addr, PoolSize: 1})
+	defer c.Close()
+	ctx := context.Background()
+
+	replies, err := c.Pipeline(ctx, []string{"PING"}, []string{"NOPE"}, []string{"PING"})
+	var serverErr Error
+	if !errors.As(err, &serverErr) || len(replies) != 3 || replies[0] != "PONG" || replies[2] != "PONG" {
+		t.Fatalf("Pipeline() = %v, %v, want both PONGs and a server error", replies, err)
+	}
+	// A server error leaves the connection usable
+	if reply, err := c.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Fatalf("Do() = %v, %v", reply, err)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dials = %d, want the pooled connection reused", n)
+	}
+
+	c.Close()
+	if _, err := c.Do(ctx, "PING"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Do() after Close err = %v, want ErrClosed", err)
+	}
+}
+
+func TestClient_NoPoolDialsPerCall(t *testing.T) {
+	addr, dials := fakeServer(t)
+	c := New(Options{Address: addr})
+	for range 3 {
+		if _, err := c.Do(context.Background(), "PING"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := dials.Load(); n != 3 {
+		t.Errorf("dials = %d, want one per call", n)
+	}
+}
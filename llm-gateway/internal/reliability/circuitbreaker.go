@@ -5,9 +5,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
+
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/internal/observability"
 )
 
+// logger is the reliability module logger; its level can be set via log.modules.reliability
+var logger = observability.ModuleLogger("reliability")
+
 // CircuitState represents the state of a circuit breaker
 type CircuitState int
 
@@ -49,6 +54,20 @@ type CircuitBreakerConfig struct {
 	Timeout time.Duration
 	// MaxHalfOpenRequests is the max concurrent requests allowed in half-open state
 	MaxHalfOpenRequests int
+	// Mode is CircuitModeConsecutive (the default), using FailureThreshold,
+	// or CircuitModeSlidingWindow, using the failure rate over a window
+	Mode string
+	// WindowType is WindowTypeCount (the default) or WindowTypeTime
+	WindowType string
+	// WindowSize is the number of requests in a count window
+	WindowSize int
+	// WindowDuration is the length of a time window
+	WindowDuration time.Duration
+	// FailureRateThreshold is the failure percentage that opens the circuit
+	FailureRateThreshold float64
+	// MinimumRequests is the number of requests the window needs before its
+	// failure rate can open the circuit
+	MinimumRequests int
 }
 
 // DefaultCircuitBreakerConfig returns sensible defaults
@@ -72,6 +91,13 @@ type CircuitBreaker struct {
 	successes        int
 	lastFailure      time.Time
 	halfOpenRequests int
+	// window holds the recent outcomes in sliding window mode
+	window outcomeWindow
+	// generation changes with every state change, so results of requests
+	// admitted before it can be told apart
+	generation uint64
+	// clock times the open state and the window; tests replace it with a fake
+	clock clock.Clock
 }
 
 // NewCircuitBreaker creates a new circuit breaker
@@ -79,55 +105,66 @@ func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
 	return &CircuitBreaker{
 		config: config,
 		state:  StateClosed,
+		window: newOutcomeWindow(config),
+		clock:  clock.Real,
 	}
 }
 
 // Execute runs the given function with circuit breaker protection
 func (cb *CircuitBreaker) Execute(fn func() error) error {
-	if err := cb.beforeRequest(); err != nil {
+	generation, err := cb.beforeRequest()
+	if err != nil {
 		return err
 	}
 
-	err := fn()
+	err = fn()
 
-	cb.afterRequest(err)
+	cb.afterRequest(generation, err)
 	return err
 }
 
-// beforeRequest checks if the request should proceed
-func (cb *CircuitBreaker) beforeRequest() error {
+// beforeRequest checks if the request should proceed, and returns the
+// generation it is admitted in
+func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
 	switch cb.state {
 	case StateClosed:
-		return nil
+		return cb.generation, nil
 
 	case StateOpen:
 		// Check if timeout has passed
-		if time.Since(cb.lastFailure) > cb.config.Timeout {
+		if cb.clock.Since(cb.lastFailure) > cb.config.Timeout {
 			cb.toHalfOpen()
 			cb.halfOpenRequests++
-			return nil
+			return cb.generation, nil
 		}
-		return ErrCircuitOpen
+		return 0, ErrCircuitOpen
 
 	case StateHalfOpen:
 		if cb.halfOpenRequests >= cb.config.MaxHalfOpenRequests {
-			return ErrTooManyRequests
+			return 0, ErrTooManyRequests
 		}
 		cb.halfOpenRequests++
-		return nil
+		return cb.generation, nil
 	}
 
-	return nil
+	return cb.generation, nil
 }
 
-// afterRequest records the result of the request
-func (cb *CircuitBreaker) afterRequest(err error) {
+// afterRequest records the result of a request admitted in generation
+func (cb *CircuitBreaker) afterRequest(generation uint64, err error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	// The state changed while the request ran. Its result says nothing about
+	// the new state, and must not release a half-open slot it never held
+	// (a request admitted while closed used to, letting extra probes through).
+	if generation != cb.generation {
+		return
+	}
+
 	if cb.state == StateHalfOpen {
 		cb.halfOpenRequests--
 	}
@@ -143,11 +180,15 @@ func (cb *CircuitBreaker) afterRequest(err error) {
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failures++
 	cb.successes = 0
-	cb.lastFailure = time.Now()
+	cb.lastFailure = cb.clock.Now()
 
 	switch cb.state {
 	case StateClosed:
-		if cb.failures >= cb.config.FailureThreshold {
+		if cb.window != nil {
+			if cb.recordWindow(true) {
+				cb.toOpen()
+			}
+		} else if cb.failures >= cb.config.FailureThreshold {
 			cb.toOpen()
 		}
 	case StateHalfOpen:
@@ -168,13 +209,36 @@ func (cb *CircuitBreaker) recordSuccess() {
 	case StateClosed:
 		// Reset failures counter on success
 		cb.failures = 0
+		if cb.window != nil {
+			cb.recordWindow(false)
+		}
+	}
+}
+
+// recordWindow adds an outcome to the sliding window and reports whether
+// the failure rate opens the circuit. Only failures are checked, since a
+// success cannot raise the rate.
+func (cb *CircuitBreaker) recordWindow(failed bool) bool {
+	now := cb.clock.Now()
+	cb.window.record(failed, now)
+	total, failures := cb.window.counts(now)
+	rate := failureRate(total, failures)
+	observability.GetMetrics().RecordCircuitBreakerWindow(cb.config.Name, total, rate)
+	return failed && total >= cb.config.MinimumRequests && rate >= cb.config.FailureRateThreshold
+}
+
+// resetWindow empties the sliding window, if there is one
+func (cb *CircuitBreaker) resetWindow() {
+	if cb.window != nil {
+		cb.window.reset()
+		observability.GetMetrics().RecordCircuitBreakerWindow(cb.config.Name, 0, 0)
 	}
 }
 
 // State transitions
 func (cb *CircuitBreaker) toOpen() {
 	if cb.state != StateOpen {
-		log.Warn().
+		logger.Warn().
 			Str("circuit", cb.config.Name).
 			Int("failures", cb.failures).
 			Str("from_state", cb.state.String()).
@@ -182,26 +246,38 @@ func (cb *CircuitBreaker) toOpen() {
 	}
 	cb.state = StateOpen
 	cb.successes = 0
+	cb.generation++
 }
 
 func (cb *CircuitBreaker) toHalfOpen() {
-	log.Info().
+	logger.Info().
 		Str("circuit", cb.config.Name).
 		Msg("Circuit breaker entering half-open state")
 	cb.state = StateHalfOpen
 	cb.failures = 0
 	cb.successes = 0
 	cb.halfOpenRequests = 0
+	cb.generation++
 }
 
 func (cb *CircuitBreaker) toClosed() {
-	log.Info().
+	logger.Info().
 		Str("circuit", cb.config.Name).
 		Int("successes", cb.successes).
 		Msg("Circuit breaker closed")
 	cb.state = StateClosed
 	cb.failures = 0
 	cb.successes = 0
+	cb.generation++
+	cb.resetWindow()
+}
+
+// Rejecting reports whether the breaker is open and will reject a request
+// made now, i.e. its timeout has not passed yet
+func (cb *CircuitBreaker) Rejecting() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.state == StateOpen && cb.clock.Since(cb.lastFailure) <= cb.config.Timeout
 }
 
 // State returns the current state of the circuit breaker
@@ -216,7 +292,7 @@ func (cb *CircuitBreaker) Stats() map[string]interface{} {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"name":             cb.config.Name,
 		"state":            cb.state.String(),
 		"failures":         cb.failures,
@@ -224,7 +300,17 @@ func (cb *CircuitBreaker) Stats() map[string]interface{} {
 		"failure_threshold": cb.config.FailureThreshold,
 		"success_threshold": cb.config.SuccessThreshold,
 		"timeout":           cb.config.Timeout.String(),
+		"mode":              CircuitModeConsecutive,
 	}
+	if cb.window != nil {
+		total, failures := cb.window.counts(cb.clock.Now())
+		stats["mode"] = CircuitModeSlidingWindow
+		stats["window_requests"] = total
+		stats["window_failures"] = failures
+		stats["failure_rate"] = failureRate(total, failures)
+		stats["failure_rate_threshold"] = cb.config.FailureRateThreshold
+	}
+	return stats
 }
 
 // Reset resets the circuit breaker to closed state
@@ -236,8 +322,10 @@ func (cb *CircuitBreaker) Reset() {
 	cb.failures = 0
 	cb.successes = 0
 	cb.halfOpenRequests = 0
+	cb.generation++
+	cb.resetWindow()
 
-	log.Info().
+	logger.Info().
 		Str("circuit", cb.config.Name).
 		Msg("Circuit breaker reset")
 }
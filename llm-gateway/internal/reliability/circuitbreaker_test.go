@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
 )
 
 func TestCircuitState_String(t *testing.T) {
@@ -126,10 +128,12 @@ func TestCircuitBreaker_TransitionsToHalfOpen(t *testing.T) {
 		Name:                "test",
 		FailureThreshold:    2,
 		SuccessThreshold:    1,
-		Timeout:             50 * time.Millisecond, // Short timeout for testing
+		Timeout:             50 * time.Millisecond,
 		MaxHalfOpenRequests: 1,
 	}
 	cb := NewCircuitBreaker(config)
+	fake := clock.NewFake(time.Now())
+	cb.clock = fake
 	testErr := errors.New("test error")
 
 	// Open the circuit
@@ -143,8 +147,8 @@ func TestCircuitBreaker_TransitionsToHalfOpen(t *testing.T) {
 		t.Fatalf("state = %v, want open", cb.State())
 	}
 
-	// Wait for timeout
-	time.Sleep(config.Timeout + 10*time.Millisecond)
+	// Let the timeout pass
+	fake.Advance(config.Timeout + 10*time.Millisecond)
 
 	// Next request should be allowed (half-open)
 	err := cb.Execute(func() error {
@@ -165,6 +169,8 @@ func TestCircuitBreaker_ClosesAfterSuccessInHalfOpen(t *testing.T) {
 		MaxHalfOpenRequests: 3,
 	}
 	cb := NewCircuitBreaker(config)
+	fake := clock.NewFake(time.Now())
+	cb.clock = fake
 	testErr := errors.New("test error")
 
 	// Open the circuit
@@ -174,8 +180,8 @@ func TestCircuitBreaker_ClosesAfterSuccessInHalfOpen(t *testing.T) {
 		})
 	}
 
-	// Wait for timeout
-	time.Sleep(config.Timeout + 10*time.Millisecond)
+	// Let the timeout pass
+	fake.Advance(config.Timeout + 10*time.Millisecond)
 
 	// Successful requests in half-open should close circuit
 	for i := 0; i < config.SuccessThreshold; i++ {
@@ -346,3 +352,103 @@ func TestCircuitBreakerRegistry_Concurrent(t *testing.T) {
 		t.Errorf("AllStats() returned %d items, want 5", len(stats))
 	}
 }
+
+func TestCircuitBreaker_StaleResultKeepsHalfOpenLimit(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:                "stale",
+		FailureThreshold:    1,
+		SuccessThreshold:    1,
+		Timeout:             10 * time.Millisecond,
+		MaxHalfOpenRequests: 1,
+	})
+	fake := clock.NewFake(time.Now())
+	cb.clock = fake
+
+	// A slow request admitted while closed
+	slow, err := cb.beforeRequest()
+	if err != nil {
+		t.Fatalf("beforeRequest() error = %v", err)
+	}
+	cb.Execute(func() error { return errors.New("fail") })
+	fake.Advance(20 * time.Millisecond)
+
+	// The probe is admitted, a second request is not
+	probe, err := cb.beforeRequest()
+	if err != nil || cb.State() != StateHalfOpen {
+		t.Fatalf("probe: state = %v, error = %v", cb.State(), err)
+	}
+	cb.afterRequest(slow, errors.New("fail"))
+	if cb.State() != StateHalfOpen {
+		t.Errorf("state = %v after a stale failure, want half-open", cb.State())
+	}
+	if _, err := cb.beforeRequest(); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("second half-open request error = %v, want ErrTooManyRequests", err)
+	}
+
+	cb.afterRequest(probe, nil)
+	if cb.State() != StateClosed {
+		t.Errorf("state = %v after the probe succeeded, want closed", cb.State())
+	}
+}
+
+func TestCircuitBreaker_SlidingCountWindow(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:                 "window",
+		FailureThreshold:     2,
+		SuccessThreshold:     1,
+		Timeout:              time.Minute,
+		Mode:                 CircuitModeSlidingWindow,
+		WindowType:           WindowTypeCount,
+		WindowSize:           4,
+		FailureRateThreshold: 50,
+		MinimumRequests:      4,
+	})
+	fail := func() error { return errors.New("fail") }
+	ok := func() error { return nil }
+
+	// Failures that are not consecutive, below the minimum requests
+	for _, fn := range []func() error{fail, fail, ok} {
+		cb.Execute(fn)
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("state = %v before minimum_requests, want closed", cb.State())
+	}
+
+	// The oldest failure slides out: ok, ok, ok, fail is 25%
+	for _, fn := range []func() error{ok, ok, ok, fail} {
+		cb.Execute(fn)
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("state = %v at 25%% failures, want closed", cb.State())
+	}
+	stats := cb.Stats()
+	if stats["mode"] != CircuitModeSlidingWindow || stats["window_requests"] != 4 || stats["window_failures"] != 1 {
+		t.Errorf("stats = %v, want 4 requests with 1 failure", stats)
+	}
+
+	// ok, ok, fail, fail is 50%
+	cb.Execute(fail)
+	if cb.State() != StateOpen {
+		t.Errorf("state = %v at 50%% failures, want open", cb.State())
+	}
+}
+
+func TestTimeWindow_Slides(t *testing.T) {
+	w := newTimeWindow(3 * time.Second)
+	start := time.Unix(1000, 0)
+
+	w.record(true, start)
+	w.record(false, start.Add(time.Second))
+	w.record(true, start.Add(2*time.Second))
+	if total, failures := w.counts(start.Add(2 * time.Second)); total != 3 || failures != 2 {
+		t.Errorf("counts() = %d, %d, want 3, 2", total, failures)
+	}
+	if total, failures := w.counts(start.Add(3 * time.Second)); total != 2 || failures != 1 {
+		t.Errorf("counts() a second later = %d, %d, want 2, 1", total, failures)
+	}
+
+	w.record(false, start.Add(3*time.Second))
+	if total, failures := w.counts(start.Add(3 * time.Second)); total != 3 || failures != 1 {
+		t.Errorf("counts() after reusing a bucket = %d, %d, want 3, 1", total, failures)
+	}
+}
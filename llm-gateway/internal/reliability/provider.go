@@ -2,14 +2,16 @@ package reliability
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
 
 	"github.com/username/llm-gateway/pkg/models"
+	"github.com/username/llm-gateway/internal/observability"
 	"github.com/username/llm-gateway/internal/proxy/providers"
 )
 
@@ -62,16 +64,16 @@ func (rp *ResilientProvider) ChatCompletion(ctx context.Context, req *models.Cha
 	var result *models.ChatCompletionResponse
 
 	err := rp.circuitBreaker.Execute(func() error {
-		res, retryResult := rp.retryer.ExecuteFunc(ctx, operation, func() (interface{}, error) {
-			resp, err := rp.provider.ChatCompletion(ctx, req)
+		res, retryResult := rp.retryer.ExecuteContext(ctx, operation, rp.recorded(ctx, operation, func(attemptCtx context.Context) (interface{}, error) {
+			resp, err := rp.provider.ChatCompletion(attemptCtx, req)
 			if err != nil {
 				return nil, rp.wrapError(err)
 			}
 			return resp, nil
-		})
+		}))
 
 		if !retryResult.Successful {
-			return retryResult.LastError
+			return rp.retryError(ctx, retryResult)
 		}
 
 		if res != nil {
@@ -95,16 +97,18 @@ func (rp *ResilientProvider) ChatCompletionStream(ctx context.Context, req *mode
 	var result io.ReadCloser
 
 	err := rp.circuitBreaker.Execute(func() error {
-		res, retryResult := rp.retryer.ExecuteFunc(ctx, operation, func() (interface{}, error) {
-			stream, err := rp.provider.ChatCompletionStream(ctx, req)
+		// The stream outlives the attempt, so it uses the request context rather
+		// than the attempt's shrunken one
+		res, retryResult := rp.retryer.ExecuteContext(ctx, operation, rp.recorded(ctx, operation, func(attemptCtx context.Context) (interface{}, error) {
+			stream, err := rp.provider.ChatCompletionStream(observability.WithUpstreamIDsFrom(ctx, attemptCtx), req)
 			if err != nil {
 				return nil, rp.wrapError(err)
 			}
 			return stream, nil
-		})
+		}))
 
 		if !retryResult.Successful {
-			return retryResult.LastError
+			return rp.retryError(ctx, retryResult)
 		}
 
 		if res != nil {
@@ -127,16 +131,16 @@ func (rp *ResilientProvider) Completion(ctx context.Context, req *models.Complet
 	var result *models.CompletionResponse
 
 	err := rp.circuitBreaker.Execute(func() error {
-		res, retryResult := rp.retryer.ExecuteFunc(ctx, operation, func() (interface{}, error) {
-			resp, err := rp.provider.Completion(ctx, req)
+		res, retryResult := rp.retryer.ExecuteContext(ctx, operation, rp.recorded(ctx, operation, func(attemptCtx context.Context) (interface{}, error) {
+			resp, err := rp.provider.Completion(attemptCtx, req)
 			if err != nil {
 				return nil, rp.wrapError(err)
 			}
 			return resp, nil
-		})
+		}))
 
 		if !retryResult.Successful {
-			return retryResult.LastError
+			return rp.retryError(ctx, retryResult)
 		}
 
 		if res != nil {
@@ -159,16 +163,16 @@ func (rp *ResilientProvider) Embedding(ctx context.Context, req *models.Embeddin
 	var result *models.EmbeddingResponse
 
 	err := rp.circuitBreaker.Execute(func() error {
-		res, retryResult := rp.retryer.ExecuteFunc(ctx, operation, func() (interface{}, error) {
-			resp, err := rp.provider.Embedding(ctx, req)
+		res, retryResult := rp.retryer.ExecuteContext(ctx, operation, rp.recorded(ctx, operation, func(attemptCtx context.Context) (interface{}, error) {
+			resp, err := rp.provider.Embedding(attemptCtx, req)
 			if err != nil {
 				return nil, rp.wrapError(err)
 			}
 			return resp, nil
-		})
+		}))
 
 		if !retryResult.Successful {
-			return retryResult.LastError
+			return rp.retryError(ctx, retryResult)
 		}
 
 		if res != nil {
@@ -184,6 +188,75 @@ func (rp *ResilientProvider) Embedding(ctx context.Context, req *models.Embeddin
 	return result, nil
 }
 
+// recorded wraps a provider call so each attempt is added to the request's flight record
+func (rp *ResilientProvider) recorded(ctx context.Context, operation string, fn func(ctx context.Context) (interface{}, error)) func(ctx context.Context) (interface{}, error) {
+	attempt := 0
+	return func(attemptCtx context.Context) (interface{}, error) {
+		attempt++
+		start := time.Now()
+		attemptCtx = observability.WithUpstreamIDs(attemptCtx)
+		res, err := fn(attemptCtx)
+
+		record := observability.ProviderAttempt{
+			Provider:          rp.provider.Name(),
+			Operation:         operation,
+			Attempt:           attempt,
+			Duration:          time.Since(start),
+			BreakerState:      rp.circuitBreaker.State().String(),
+			UpstreamRequestID: observability.UpstreamRequestID(attemptCtx),
+			ResponseID:        responseID(res),
+		}
+		if err != nil {
+			record.Error = err.Error()
+			record.Status, record.ErrorClass = classifyError(err)
+		}
+		observability.RecordProviderAttempt(ctx, record)
+
+		return res, err
+	}
+}
+
+// responseID returns the provider's ID of a response, if it has one
+func responseID(res interface{}) string {
+	switch resp := res.(type) {
+	case *models.ChatCompletionResponse:
+		return resp.ID
+	case *models.CompletionResponse:
+		return resp.ID
+	}
+	return ""
+}
+
+// classifyError returns the upstream status of a failed attempt (0 if none was
+// received) and a coarse error class clients can act on
+func classifyError(err error) (int, string) {
+	status := 0
+	var providerErr *providers.ProviderError
+	var retryableErr *RetryableError
+	if errors.As(err, &providerErr) {
+		status = providerErr.StatusCode
+	} else if errors.As(err, &retryableErr) {
+		status = retryableErr.StatusCode
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || status == http.StatusGatewayTimeout:
+		return status, "timeout"
+	case errors.Is(err, context.Canceled):
+		return status, "canceled"
+	case status == http.StatusTooManyRequests:
+		return status, "rate_limited"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return status, "auth"
+	case status >= 500:
+		return status, "upstream_error"
+	case status >= 400:
+		return status, "invalid_request"
+	default:
+		return status, "network"
+	}
+}
+
 // ListModels returns supported models (no retry needed - cached locally)
 func (rp *ResilientProvider) ListModels() []models.Model {
 	return rp.provider.ListModels()
@@ -207,12 +280,30 @@ func (rp *ResilientProvider) CircuitState() CircuitState {
 
 // Stats returns reliability statistics for this provider
 func (rp *ResilientProvider) Stats() map[string]interface{} {
+	retry := map[string]interface{}{
+		"retries_in_flight":      rp.retryer.RetriesInFlight(),
+		"max_concurrent_retries": rp.config.Retry.MaxConcurrentRetries,
+	}
+	if budget := rp.config.Retry.Budget; budget != nil {
+		retry["budget"] = budget.Stats()
+	}
 	return map[string]interface{}{
 		"provider":        rp.provider.Name(),
 		"circuit_breaker": rp.circuitBreaker.Stats(),
+		"retry":           retry,
 	}
 }
 
+// BreakerState returns the state of the provider's circuit breaker
+func (rp *ResilientProvider) BreakerState() CircuitState {
+	return rp.circuitBreaker.State()
+}
+
+// BreakerRejecting reports whether the circuit breaker rejects requests now
+func (rp *ResilientProvider) BreakerRejecting() bool {
+	return rp.circuitBreaker.Rejecting()
+}
+
 // ResetCircuitBreaker resets the circuit breaker to closed state
 func (rp *ResilientProvider) ResetCircuitBreaker() {
 	rp.circuitBreaker.Reset()
@@ -234,6 +325,29 @@ func (rp *ResilientProvider) wrapError(err error) error {
 	return NewRetryableError(err, 0, true)
 }
 
+// retryError returns the error for a failed retry run: a 504 listing every
+// attempt when the request deadline ran out, otherwise the last error
+func (rp *ResilientProvider) retryError(ctx context.Context, result RetryResult) error {
+	if result.RetrySuppressed != "" {
+		observability.GetMetrics().RecordRetrySuppressed(rp.provider.Name(), result.RetrySuppressed)
+	}
+	if !result.DeadlineExhausted && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result.LastError
+	}
+
+	history := make([]string, len(result.History))
+	for i, attempt := range result.History {
+		history[i] = attempt.String()
+	}
+	return &providers.ProviderError{
+		Provider:   rp.provider.Name(),
+		StatusCode: http.StatusGatewayTimeout,
+		Code:       "deadline_exceeded",
+		Message: fmt.Sprintf("Provider %s did not succeed before the request deadline after %d attempt(s): %s",
+			rp.provider.Name(), result.Attempts, strings.Join(history, "; ")),
+	}
+}
+
 // unwrapError converts internal errors back to provider errors
 func (rp *ResilientProvider) unwrapError(err error) error {
 	if err == nil {
@@ -301,7 +415,7 @@ func NewResilientRegistry(registry *providers.Registry) *ResilientRegistry {
 		config := DefaultResilientProviderConfig(name)
 		rr.providers[name] = NewResilientProvider(provider, config)
 
-		log.Info().
+		logger.Info().
 			Str("provider", name).
 			Msg("Wrapped provider with resilience features")
 	}
@@ -0,0 +1,244 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+)
+
+var (
+	// ErrOutboundQueueFull is returned when too many requests are already waiting for quota
+	ErrOutboundQueueFull = errors.New("outbound request queue is full")
+	// ErrOutboundWaitExceeded is returned when quota would not be available within MaxWait
+	ErrOutboundWaitExceeded = errors.New("outbound quota not available within max wait")
+	// ErrOutboundQueueFlushed is returned to requests waiting for quota when
+	// an operator flushes the queue
+	ErrOutboundQueueFlushed = errors.New("outbound request queue was flushed")
+)
+
+// OutboundLimiterConfig holds the upstream quota for one provider
+type OutboundLimiterConfig struct {
+	Name string
+	// RequestsPerSec limits dispatched requests (0 = unlimited)
+	RequestsPerSec float64
+	// TokensPerMin limits estimated prompt + completion tokens (0 = unlimited)
+	TokensPerMin int
+	// MaxQueue is the number of requests allowed to wait for quota
+	MaxQueue int
+	// MaxWait is the longest a request may wait for quota before it is rejected
+	MaxWait time.Duration
+}
+
+// DefaultOutboundLimiterConfig returns sensible defaults
+func DefaultOutboundLimiterConfig(name string) OutboundLimiterConfig {
+	return OutboundLimiterConfig{
+		Name:     name,
+		MaxQueue: 100,
+		MaxWait:  30 * time.Second,
+	}
+}
+
+// bucket is a token bucket that may go negative: callers reserve capacity up
+// front and wait until the debt is repaid, which keeps waiters in FIFO order
+type bucket struct {
+	rate     float64 // tokens per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(rate, capacity float64, now time.Time) *bucket {
+	return &bucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// take removes n tokens; a negative n returns them, up to capacity
+func (b *bucket) take(n float64) {
+	b.tokens -= n
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// delay returns how long until n tokens are available
+func (b *bucket) delay(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// OutboundLimiter shapes requests to an upstream provider so they stay within
+// its contracted request and token quotas. Requests over quota are queued
+// rather than rejected, up to MaxQueue waiters and MaxWait.
+type OutboundLimiter struct {
+	config OutboundLimiterConfig
+
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	waiting  int
+	// flushed is closed to release the current waiters by Flush
+	flushed chan struct{}
+
+	admitted int64
+	queued   int64
+	rejected int64
+	waited   time.Duration
+
+	// clock refills the buckets and times queued requests; tests replace it
+	// with a fake
+	clock clock.Clock
+}
+
+// NewOutboundLimiter creates a new outbound limiter
+func NewOutboundLimiter(config OutboundLimiterConfig) *OutboundLimiter {
+	defaults := DefaultOutboundLimiterConfig(config.Name)
+	if config.MaxQueue <= 0 {
+		config.MaxQueue = defaults.MaxQueue
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaults.MaxWait
+	}
+
+	l := &OutboundLimiter{config: config, clock: clock.Real, flushed: make(chan struct{})}
+	now := l.clock.Now()
+	if config.RequestsPerSec > 0 {
+		// Allow a one-second burst, and at least one request
+		capacity := config.RequestsPerSec
+		if capacity < 1 {
+			capacity = 1
+		}
+		l.requests = newBucket(config.RequestsPerSec, capacity, now)
+	}
+	if config.TokensPerMin > 0 {
+		l.tokens = newBucket(float64(config.TokensPerMin)/60, float64(config.TokensPerMin), now)
+	}
+	return l
+}
+
+// Wait blocks until one request using the given number of tokens may be
+// dispatched. It returns an error without waiting if the queue is full or
+// quota would not be available within MaxWait, and ctx's error if it is
+// cancelled while queued.
+func (l *OutboundLimiter) Wait(ctx context.Context, tokens int) error {
+	l.mu.Lock()
+	now := l.clock.Now()
+
+	// A request larger than a whole minute of quota can never fit; charge the full bucket
+	cost := float64(tokens)
+	if l.tokens != nil && cost > l.tokens.capacity {
+		cost = l.tokens.capacity
+	}
+
+	var delay time.Duration
+	if l.requests != nil {
+		l.requests.refill(now)
+		delay = l.requests.delay(1)
+	}
+	if l.tokens != nil {
+		l.tokens.refill(now)
+		if d := l.tokens.delay(cost); d > delay {
+			delay = d
+		}
+	}
+
+	if delay > 0 && l.waiting >= l.config.MaxQueue {
+		l.rejected++
+		l.mu.Unlock()
+		return ErrOutboundQueueFull
+	}
+	if delay > l.config.MaxWait {
+		l.rejected++
+		l.mu.Unlock()
+		return ErrOutboundWaitExceeded
+	}
+
+	// Reserve capacity now so later callers queue behind this one
+	l.reserve(1, cost)
+	if delay == 0 {
+		l.admitted++
+		l.mu.Unlock()
+		return nil
+	}
+	l.waiting++
+	l.queued++
+	flushed := l.flushed
+	l.mu.Unlock()
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		l.mu.Lock()
+		l.waiting--
+		l.admitted++
+		l.waited += delay
+		l.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.waiting--
+		l.reserve(-1, -cost)
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-flushed:
+		l.mu.Lock()
+		l.waiting--
+		l.rejected++
+		l.reserve(-1, -cost)
+		l.mu.Unlock()
+		return ErrOutboundQueueFlushed
+	}
+}
+
+// Flush rejects every request waiting for quota with ErrOutboundQueueFlushed
+// and returns how many there were. Requests arriving later queue as usual.
+func (l *OutboundLimiter) Flush() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	waiting := l.waiting
+	close(l.flushed)
+	l.flushed = make(chan struct{})
+	return waiting
+}
+
+// reserve debits (or, with negative values, refunds) both buckets. Callers hold l.mu.
+func (l *OutboundLimiter) reserve(requests, tokens float64) {
+	if l.requests != nil {
+		l.requests.take(requests)
+	}
+	if l.tokens != nil {
+		l.tokens.take(tokens)
+	}
+}
+
+// Stats returns the limiter configuration and counters
+func (l *OutboundLimiter) Stats() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return map[string]interface{}{
+		"requests_per_sec": l.config.RequestsPerSec,
+		"tokens_per_min":   l.config.TokensPerMin,
+		"max_queue":        l.config.MaxQueue,
+		"max_wait":         l.config.MaxWait.String(),
+		"waiting":          l.waiting,
+		"admitted":         l.admitted,
+		"queued":           l.queued,
+		"rejected":         l.rejected,
+		"total_wait":       l.waited.String(),
+	}
+}
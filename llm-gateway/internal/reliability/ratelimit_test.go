@@ -0,0 +1,127 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+)
+
+// withFakeClock makes l read time from a fake clock
+func withFakeClock(l *OutboundLimiter) *clock.Fake {
+	fake := clock.NewFake(time.Now())
+	l.clock = fake
+	return fake
+}
+
+func TestOutboundLimiter_QueuesOverRequestRate(t *testing.T) {
+	l := NewOutboundLimiter(OutboundLimiterConfig{Name: "test", RequestsPerSec: 20, MaxWait: time.Second})
+	fake := withFakeClock(l)
+
+	// The one-second burst is admitted immediately
+	for i := 0; i < 20; i++ {
+		if err := l.Wait(context.Background(), 0); err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// The next request waits 50ms for the bucket to refill
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(context.Background(), 0) }()
+	fake.BlockUntil(1)
+	fake.Advance(49 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("request admitted before the bucket refilled: %v", err)
+	default:
+	}
+	fake.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := l.Stats()
+	if stats["admitted"].(int64) != 21 || stats["queued"].(int64) != 1 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestOutboundLimiter_RejectsBeyondMaxWait(t *testing.T) {
+	l := NewOutboundLimiter(OutboundLimiterConfig{Name: "test", TokensPerMin: 600, MaxWait: 100 * time.Millisecond})
+
+	if err := l.Wait(context.Background(), 600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 100 tokens take 10s to refill at 10 tokens/sec
+	err := l.Wait(context.Background(), 100)
+	if !errors.Is(err, ErrOutboundWaitExceeded) {
+		t.Errorf("expected ErrOutboundWaitExceeded, got %v", err)
+	}
+	if l.Stats()["rejected"].(int64) != 1 {
+		t.Errorf("expected 1 rejection, got %v", l.Stats()["rejected"])
+	}
+}
+
+func TestOutboundLimiter_RejectsWhenQueueFull(t *testing.T) {
+	l := NewOutboundLimiter(OutboundLimiterConfig{Name: "test", RequestsPerSec: 1, MaxQueue: 1, MaxWait: 5 * time.Second})
+	fake := withFakeClock(l)
+
+	if err := l.Wait(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx, 0) }()
+
+	// Wait for the goroutine to be queued
+	fake.BlockUntil(1)
+
+	if err := l.Wait(context.Background(), 0); !errors.Is(err, ErrOutboundQueueFull) {
+		t.Errorf("expected ErrOutboundQueueFull, got %v", err)
+	}
+
+	// Cancelling a queued request releases its slot
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if l.Stats()["waiting"].(int) != 0 {
+		t.Errorf("expected no waiters after cancel")
+	}
+}
+
+func TestOutboundLimiter_Unlimited(t *testing.T) {
+	l := NewOutboundLimiter(DefaultOutboundLimiterConfig("test"))
+
+	for i := 0; i < 1000; i++ {
+		if err := l.Wait(context.Background(), 10000); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestOutboundLimiter_Flush(t *testing.T) {
+	l := NewOutboundLimiter(OutboundLimiterConfig{Name: "test", RequestsPerSec: 1, MaxWait: 5 * time.Second})
+	fake := withFakeClock(l)
+	if err := l.Wait(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	errs := make(chan error)
+	go func() { errs <- l.Wait(context.Background(), 0) }()
+	fake.BlockUntil(1)
+
+	if flushed := l.Flush(); flushed != 1 {
+		t.Errorf("Flush() = %d, want 1", flushed)
+	}
+	if err := <-errs; !errors.Is(err, ErrOutboundQueueFlushed) {
+		t.Errorf("waiting request error = %v, want ErrOutboundQueueFlushed", err)
+	}
+	if waiting := l.Stats()["waiting"].(int); waiting != 0 {
+		t.Errorf("waiting = %d after the flush, want 0", waiting)
+	}
+}
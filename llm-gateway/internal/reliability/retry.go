@@ -3,12 +3,26 @@ package reliability
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"net/http"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+)
+
+// Reasons a retry was not made though the error allowed one
+const (
+	// RetrySuppressedBudget means the shared retry budget was spent
+	RetrySuppressedBudget = "budget"
+	// RetrySuppressedConcurrency means MaxConcurrentRetries retries were
+	// already in flight
+	RetrySuppressedConcurrency = "concurrency"
+	// RetrySuppressedRetryAfter means the provider asked for a wait longer
+	// than MaxBackoff
+	RetrySuppressedRetryAfter = "retry_after"
 )
 
 // RetryConfig holds configuration for retry behavior
@@ -25,6 +39,17 @@ type RetryConfig struct {
 	JitterFactor float64
 	// RetryableStatusCodes are HTTP status codes that should trigger a retry
 	RetryableStatusCodes []int
+	// MinAttemptTime is the least time worth starting an attempt with. When the
+	// context has a deadline, attempts are skipped rather than started with less,
+	// and each attempt's timeout is shrunk to a share of the remaining time.
+	// 0 disables deadline budgeting.
+	MinAttemptTime time.Duration
+	// Budget, if set, caps retries at a share of requests, across every
+	// retryer given the same budget
+	Budget *RetryBudget
+	// MaxConcurrentRetries caps the retries in flight at once through this
+	// retryer; 0 means no cap
+	MaxConcurrentRetries int
 }
 
 // DefaultRetryConfig returns sensible defaults for LLM API calls
@@ -35,6 +60,7 @@ func DefaultRetryConfig() RetryConfig {
 		MaxBackoff:        30 * time.Second,
 		BackoffMultiplier: 2.0,
 		JitterFactor:      0.2,
+		MinAttemptTime:    time.Second,
 		RetryableStatusCodes: []int{
 			http.StatusTooManyRequests,     // 429
 			http.StatusInternalServerError, // 500
@@ -48,11 +74,20 @@ func DefaultRetryConfig() RetryConfig {
 // Retryer handles retry logic with exponential backoff
 type Retryer struct {
 	config RetryConfig
+	// retrySlots holds a token per retry in flight (nil without a cap)
+	retrySlots chan struct{}
+	// clock times attempts and backoffs; tests replace it with a fake.
+	// Context deadlines are always real time.
+	clock clock.Clock
 }
 
 // NewRetryer creates a new retryer with the given config
 func NewRetryer(config RetryConfig) *Retryer {
-	return &Retryer{config: config}
+	r := &Retryer{config: config, clock: clock.Real}
+	if config.MaxConcurrentRetries > 0 {
+		r.retrySlots = make(chan struct{}, config.MaxConcurrentRetries)
+	}
+	return r
 }
 
 // RetryableError is an error that can be retried
@@ -85,49 +120,134 @@ type RetryResult struct {
 	TotalTime  time.Duration
 	LastError  error
 	Successful bool
+	// History records every attempt made, in order
+	History []AttemptRecord
+	// DeadlineExhausted is set when retries stopped because too little of the
+	// context deadline was left for another attempt
+	DeadlineExhausted bool
+	// RetrySuppressed is set when a retryable failure was not retried: one
+	// of the RetrySuppressed reasons
+	RetrySuppressed string
+}
+
+// AttemptRecord describes one attempt made by the retryer
+type AttemptRecord struct {
+	Attempt  int
+	Duration time.Duration
+	// Timeout is the attempt's own timeout (0 if it ran until the context deadline)
+	Timeout time.Duration
+	Err     string
+}
+
+// String formats the attempt for error messages, e.g. "attempt 1: timed out (7.5s)"
+func (a AttemptRecord) String() string {
+	outcome := "ok"
+	if a.Err != "" {
+		outcome = a.Err
+	}
+	return fmt.Sprintf("attempt %d: %s (%s)", a.Attempt, outcome, a.Duration.Round(time.Millisecond))
 }
 
 // Execute runs a function with retry logic
 func (r *Retryer) Execute(ctx context.Context, operation string, fn func() error) RetryResult {
+	_, result := r.ExecuteContext(ctx, operation, func(context.Context) (interface{}, error) {
+		return nil, fn()
+	})
+	return result
+}
+
+// ExecuteFunc runs a function that returns an interface{} result with retry logic
+func (r *Retryer) ExecuteFunc(ctx context.Context, operation string, fn func() (interface{}, error)) (interface{}, RetryResult) {
+	return r.ExecuteContext(ctx, operation, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+}
+
+// ExecuteContext runs fn with retry logic, budgeting the context deadline
+// across attempts: each attempt gets a share of the remaining time (at least
+// MinAttemptTime, the last attempt gets all of it) via the context passed to
+// fn, and no attempt is started or waited for when less than MinAttemptTime
+// would be left for it.
+func (r *Retryer) ExecuteContext(ctx context.Context, operation string, fn func(ctx context.Context) (interface{}, error)) (interface{}, RetryResult) {
 	result := RetryResult{}
-	startTime := time.Now()
+	startTime := r.clock.Now()
+	if r.config.Budget != nil {
+		r.config.Budget.recordRequest()
+	}
 
-	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
-		result.Attempts = attempt + 1
+	// A retry holds a concurrency slot from its backoff until it returns
+	holdingSlot := false
+	releaseSlot := func() {
+		if holdingSlot {
+			<-r.retrySlots
+			holdingSlot = false
+		}
+	}
+	defer releaseSlot()
 
+	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
 		// Check context before attempt
 		if ctx.Err() != nil {
 			result.LastError = ctx.Err()
-			result.TotalTime = time.Since(startTime)
-			return result
+			result.TotalTime = r.clock.Since(startTime)
+			return nil, result
+		}
+
+		timeout := r.attemptTimeout(ctx, attempt)
+		if timeout < 0 {
+			if attempt > 0 {
+				result.DeadlineExhausted = true
+				break
+			}
+			// Always make the first attempt, with whatever time is left
+			timeout = 0
+		}
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
 		}
 
 		// Execute the operation
-		err := fn()
+		result.Attempts = attempt + 1
+		attemptStart := r.clock.Now()
+		res, err := fn(attemptCtx)
+		releaseSlot()
+		if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			// Only this attempt's share ran out; later attempts may still succeed
+			err = NewRetryableError(fmt.Errorf("attempt timed out after %s", timeout), http.StatusGatewayTimeout, true)
+		}
+		cancel()
+
+		record := AttemptRecord{Attempt: attempt + 1, Duration: r.clock.Since(attemptStart), Timeout: timeout}
+		if err != nil {
+			record.Err = err.Error()
+		}
+		result.History = append(result.History, record)
+
 		if err == nil {
 			result.Successful = true
-			result.TotalTime = time.Since(startTime)
+			result.TotalTime = r.clock.Since(startTime)
 
 			if attempt > 0 {
-				log.Info().
+				logger.Info().
 					Str("operation", operation).
 					Int("attempts", result.Attempts).
 					Dur("total_time", result.TotalTime).
 					Msg("Operation succeeded after retry")
 			}
-			return result
+			return res, result
 		}
 
 		result.LastError = err
 
 		// Check if error is retryable
 		if !r.isRetryable(err) {
-			result.TotalTime = time.Since(startTime)
-			log.Debug().
+			result.TotalTime = r.clock.Since(startTime)
+			logger.Debug().
 				Str("operation", operation).
 				Err(err).
 				Msg("Error is not retryable, giving up")
-			return result
+			return nil, result
 		}
 
 		// Don't wait after the last attempt
@@ -135,10 +255,31 @@ func (r *Retryer) Execute(ctx context.Context, operation string, fn func() error
 			break
 		}
 
-		// Calculate backoff with jitter
+		// Calculate backoff with jitter, waiting at least as long as the
+		// provider asked
 		backoff := r.calculateBackoff(attempt)
+		if retryAfter := retryAfterOf(err); retryAfter > 0 {
+			if retryAfter > r.config.MaxBackoff {
+				result.RetrySuppressed = RetrySuppressedRetryAfter
+				break
+			}
+			backoff = max(backoff, retryAfter)
+		}
+
+		// Don't wait if no worthwhile attempt would fit after the backoff
+		if deadline, ok := ctx.Deadline(); ok && r.config.MinAttemptTime > 0 &&
+			time.Until(deadline)-backoff < r.config.MinAttemptTime {
+			result.DeadlineExhausted = true
+			break
+		}
 
-		log.Warn().
+		if reason := r.acquireRetry(); reason != "" {
+			result.RetrySuppressed = reason
+			break
+		}
+		holdingSlot = r.retrySlots != nil
+
+		logger.Warn().
 			Str("operation", operation).
 			Int("attempt", attempt+1).
 			Int("max_retries", r.config.MaxRetries).
@@ -147,98 +288,93 @@ func (r *Retryer) Execute(ctx context.Context, operation string, fn func() error
 			Msg("Operation failed, retrying")
 
 		// Wait with context cancellation support
-		timer := time.NewTimer(backoff)
+		timer := r.clock.NewTimer(backoff)
 		select {
 		case <-ctx.Done():
 			timer.Stop()
 			result.LastError = ctx.Err()
-			result.TotalTime = time.Since(startTime)
-			return result
-		case <-timer.C:
+			result.TotalTime = r.clock.Since(startTime)
+			return nil, result
+		case <-timer.C():
 			// Continue to next attempt
 		}
 	}
 
-	result.TotalTime = time.Since(startTime)
-	log.Error().
+	result.TotalTime = r.clock.Since(startTime)
+	logger.Error().
 		Str("operation", operation).
 		Int("attempts", result.Attempts).
 		Dur("total_time", result.TotalTime).
+		Bool("deadline_exhausted", result.DeadlineExhausted).
+		Str("retry_suppressed", result.RetrySuppressed).
 		Err(result.LastError).
 		Msg("Operation failed after all retries")
 
-	return result
+	return nil, result
 }
 
-// ExecuteFunc runs a function that returns an interface{} result with retry logic
-func (r *Retryer) ExecuteFunc(ctx context.Context, operation string, fn func() (interface{}, error)) (interface{}, RetryResult) {
-	var result interface{}
-	retryResult := RetryResult{}
-	startTime := time.Now()
-
-	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
-		retryResult.Attempts = attempt + 1
-
-		// Check context before attempt
-		if ctx.Err() != nil {
-			retryResult.LastError = ctx.Err()
-			retryResult.TotalTime = time.Since(startTime)
-			return result, retryResult
-		}
-
-		// Execute the operation
-		res, err := fn()
-		if err == nil {
-			retryResult.Successful = true
-			retryResult.TotalTime = time.Since(startTime)
-
-			if attempt > 0 {
-				log.Info().
-					Str("operation", operation).
-					Int("attempts", retryResult.Attempts).
-					Dur("total_time", retryResult.TotalTime).
-					Msg("Operation succeeded after retry")
-			}
-			return res, retryResult
-		}
+// attemptTimeout returns the timeout for an attempt given the context
+// deadline: 0 for no timeout of its own, or -1 if less than MinAttemptTime is
+// left. The remaining time, less the expected backoffs, is shared equally
+// between the attempts still allowed.
+func (r *Retryer) attemptTimeout(ctx context.Context, attempt int) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok || r.config.MinAttemptTime <= 0 {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	if remaining < r.config.MinAttemptTime {
+		return -1
+	}
+	if attempt >= r.config.MaxRetries {
+		return 0
+	}
 
-		retryResult.LastError = err
+	budget := remaining
+	for i := attempt; i < r.config.MaxRetries; i++ {
+		budget -= r.baseBackoff(i)
+	}
+	share := budget / time.Duration(r.config.MaxRetries-attempt+1)
+	if share < r.config.MinAttemptTime {
+		share = r.config.MinAttemptTime
+	}
+	if share >= remaining {
+		return 0
+	}
+	return share
+}
 
-		// Check if error is retryable
-		if !r.isRetryable(err) {
-			retryResult.TotalTime = time.Since(startTime)
-			return result, retryResult
+// acquireRetry takes a concurrency slot and a retry from the budget for the
+// next retry, or returns why the retry may not be made
+func (r *Retryer) acquireRetry() string {
+	if r.retrySlots != nil {
+		select {
+		case r.retrySlots <- struct{}{}:
+		default:
+			return RetrySuppressedConcurrency
 		}
-
-		// Don't wait after the last attempt
-		if attempt >= r.config.MaxRetries {
-			break
+	}
+	if r.config.Budget != nil && !r.config.Budget.tryRetry() {
+		if r.retrySlots != nil {
+			<-r.retrySlots
 		}
+		return RetrySuppressedBudget
+	}
+	return ""
+}
 
-		// Calculate backoff with jitter
-		backoff := r.calculateBackoff(attempt)
-
-		log.Warn().
-			Str("operation", operation).
-			Int("attempt", attempt+1).
-			Dur("backoff", backoff).
-			Err(err).
-			Msg("Operation failed, retrying")
+// RetriesInFlight returns the retries holding a concurrency slot
+func (r *Retryer) RetriesInFlight() int {
+	return len(r.retrySlots)
+}
 
-		// Wait with context cancellation support
-		timer := time.NewTimer(backoff)
-		select {
-		case <-ctx.Done():
-			timer.Stop()
-			retryResult.LastError = ctx.Err()
-			retryResult.TotalTime = time.Since(startTime)
-			return result, retryResult
-		case <-timer.C:
-		}
+// retryAfterOf returns the wait a provider asked for before a retry, or 0
+func retryAfterOf(err error) time.Duration {
+	var providerErr *providers.ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.RetryAfter
 	}
-
-	retryResult.TotalTime = time.Since(startTime)
-	return result, retryResult
+	return 0
 }
 
 // isRetryable checks if an error should trigger a retry
@@ -277,8 +413,8 @@ func (r *Retryer) isRetryable(err error) bool {
 	return true
 }
 
-// calculateBackoff calculates the backoff duration for a given attempt
-func (r *Retryer) calculateBackoff(attempt int) time.Duration {
+// baseBackoff returns the backoff for a given attempt before jitter
+func (r *Retryer) baseBackoff(attempt int) time.Duration {
 	// Exponential backoff: initialBackoff * (multiplier ^ attempt)
 	backoff := float64(r.config.InitialBackoff) * math.Pow(r.config.BackoffMultiplier, float64(attempt))
 
@@ -286,6 +422,12 @@ func (r *Retryer) calculateBackoff(attempt int) time.Duration {
 	if backoff > float64(r.config.MaxBackoff) {
 		backoff = float64(r.config.MaxBackoff)
 	}
+	return time.Duration(backoff)
+}
+
+// calculateBackoff calculates the backoff duration for a given attempt
+func (r *Retryer) calculateBackoff(attempt int) time.Duration {
+	backoff := float64(r.baseBackoff(attempt))
 
 	// Apply jitter
 	if r.config.JitterFactor > 0 {
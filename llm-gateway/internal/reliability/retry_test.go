@@ -0,0 +1,239 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/clock"
+	"github.com/username/llm-gateway/internal/proxy/providers"
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+func testRetryConfig() RetryConfig {
+	cfg := DefaultRetryConfig()
+	cfg.InitialBackoff = 10 * time.Millisecond
+	cfg.MaxBackoff = 50 * time.Millisecond
+	cfg.JitterFactor = 0
+	cfg.MinAttemptTime = 50 * time.Millisecond
+	return cfg
+}
+
+func TestRetryer_ShrinksAttemptTimeouts(t *testing.T) {
+	r := NewRetryer(testRetryConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	calls := 0
+	_, result := r.ExecuteContext(ctx, "test", func(attemptCtx context.Context) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			// Hang until the attempt's share of the deadline runs out
+			<-attemptCtx.Done()
+			return nil, attemptCtx.Err()
+		}
+		return "ok", nil
+	})
+
+	if !result.Successful || result.Attempts != 2 {
+		t.Fatalf("result = %+v, want success on the second attempt", result)
+	}
+	first := result.History[0]
+	if first.Timeout <= 0 || first.Timeout > 300*time.Millisecond || !strings.Contains(first.Err, "timed out") {
+		t.Errorf("first attempt = %+v, want a timeout of about a quarter of the deadline", first)
+	}
+}
+
+func TestRetryer_SkipsAttemptsThatCannotFit(t *testing.T) {
+	cfg := testRetryConfig()
+	cfg.InitialBackoff = 100 * time.Millisecond
+	cfg.MaxBackoff = time.Second
+	r := NewRetryer(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	_, result := r.ExecuteContext(ctx, "test", func(context.Context) (interface{}, error) {
+		return nil, NewRetryableError(errors.New("bad gateway"), http.StatusBadGateway, true)
+	})
+
+	if result.Successful || !result.DeadlineExhausted || result.Attempts != 1 {
+		t.Errorf("result = %+v, want one attempt and the deadline reported exhausted", result)
+	}
+	if ctx.Err() != nil {
+		t.Error("retryer waited until the deadline instead of giving up early")
+	}
+}
+
+func TestRetryer_NoDeadline(t *testing.T) {
+	r := NewRetryer(testRetryConfig())
+
+	_, result := r.ExecuteContext(context.Background(), "test", func(ctx context.Context) (interface{}, error) {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("attempt got a deadline without a request deadline")
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	if result.Attempts != 4 || result.DeadlineExhausted || len(result.History) != 4 {
+		t.Errorf("result = %+v, want all 4 attempts", result)
+	}
+}
+
+func TestRetryer_HonorsRetryAfter(t *testing.T) {
+	r := NewRetryer(testRetryConfig())
+	rateLimited := func(wait time.Duration) error {
+		return NewRetryableError(&providers.ProviderError{StatusCode: http.StatusTooManyRequests, RetryAfter: wait}, http.StatusTooManyRequests, true)
+	}
+
+	fake := clock.NewFake(time.Now())
+	r.clock = fake
+	calls := 0
+	done := make(chan RetryResult)
+	go func() {
+		_, result := r.ExecuteContext(context.Background(), "test", func(context.Context) (interface{}, error) {
+			calls++
+			if calls == 1 {
+				return nil, rateLimited(40 * time.Millisecond)
+			}
+			return "ok", nil
+		})
+		done <- result
+	}()
+	fake.BlockUntil(1)
+	fake.Advance(40 * time.Millisecond)
+	result := <-done
+	if !result.Successful || result.TotalTime != 40*time.Millisecond {
+		t.Errorf("retried after %v, want the 40ms Retry-After rather than the 10ms backoff", result.TotalTime)
+	}
+
+	// A wait longer than max_backoff is not worth holding the request for
+	_, result = r.ExecuteContext(context.Background(), "test", func(context.Context) (interface{}, error) {
+		return nil, rateLimited(time.Minute)
+	})
+	if result.Attempts != 1 || result.RetrySuppressed != RetrySuppressedRetryAfter {
+		t.Errorf("result = %+v, want one attempt suppressed by retry_after", result)
+	}
+}
+
+func TestRetryer_Budget(t *testing.T) {
+	cfg := testRetryConfig()
+	cfg.Budget = NewRetryBudget(50, 10*time.Second, 1)
+	r := NewRetryer(cfg)
+	fail := func(context.Context) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	// The first request may retry once, on the minimum
+	_, result := r.ExecuteContext(context.Background(), "test", fail)
+	if result.Attempts != 2 || result.RetrySuppressed != RetrySuppressedBudget {
+		t.Errorf("result = %+v, want one retry before the budget runs out", result)
+	}
+	// With three more requests, a 50% budget allows one more retry of four
+	for i := 0; i < 2; i++ {
+		r.ExecuteContext(context.Background(), "test", func(context.Context) (interface{}, error) { return "ok", nil })
+	}
+	_, result = r.ExecuteContext(context.Background(), "test", fail)
+	if result.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", result.Attempts)
+	}
+	if stats := cfg.Budget.Stats(); stats["requests"] != 4 || stats["retries"] != 2 {
+		t.Errorf("budget stats = %v, want 4 requests and 2 retries", stats)
+	}
+}
+
+func TestRetryer_MaxConcurrentRetries(t *testing.T) {
+	cfg := testRetryConfig()
+	cfg.MaxConcurrentRetries = 1
+	r := NewRetryer(cfg)
+
+	retrying := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan RetryResult)
+	go func() {
+		calls := 0
+		_, result := r.ExecuteContext(context.Background(), "first", func(context.Context) (interface{}, error) {
+			calls++
+			if calls == 1 {
+				return nil, errors.New("connection refused")
+			}
+			close(retrying)
+			<-release
+			return "ok", nil
+		})
+		done <- result
+	}()
+	<-retrying
+
+	if got := r.RetriesInFlight(); got != 1 {
+		t.Errorf("RetriesInFlight() = %d, want 1", got)
+	}
+	_, result := r.ExecuteContext(context.Background(), "second", func(context.Context) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	if result.Attempts != 1 || result.RetrySuppressed != RetrySuppressedConcurrency {
+		t.Errorf("result = %+v, want no retry while another is in flight", result)
+	}
+
+	close(release)
+	if result := <-done; !result.Successful {
+		t.Errorf("first request = %+v, want success on its retry", result)
+	}
+	if got := r.RetriesInFlight(); got != 0 {
+		t.Errorf("RetriesInFlight() = %d after the retry returned, want 0", got)
+	}
+}
+
+// failingProvider fails every chat completion with a retryable error
+type failingProvider struct {
+	providers.Provider
+}
+
+func (p *failingProvider) Name() string { return "test" }
+
+func (p *failingProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	return nil, &providers.ProviderError{Provider: "test", StatusCode: http.StatusBadGateway, Code: "bad_gateway", Message: "upstream failed"}
+}
+
+func TestResilientProvider_DeadlineError(t *testing.T) {
+	cfg := DefaultResilientProviderConfig("test")
+	cfg.Retry = testRetryConfig()
+	cfg.Retry.InitialBackoff = 100 * time.Millisecond
+	cfg.Retry.MaxBackoff = time.Second
+	rp := NewResilientProvider(&failingProvider{}, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	_, err := rp.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "m"})
+
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("err = %v, want a 504 provider error", err)
+	}
+	if !strings.Contains(providerErr.Message, "attempt 1: ") || !strings.Contains(providerErr.Message, "upstream failed") {
+		t.Errorf("message = %q, want the attempt history", providerErr.Message)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantClass  string
+	}{
+		{NewRetryableError(&providers.ProviderError{StatusCode: 429}, 429, true), 429, "rate_limited"},
+		{&providers.ProviderError{StatusCode: 503}, 503, "upstream_error"},
+		{&providers.ProviderError{StatusCode: 401}, 401, "auth"},
+		{&providers.ProviderError{StatusCode: 400}, 400, "invalid_request"},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), 0, "timeout"},
+		{errors.New("connection refused"), 0, "network"},
+	}
+	for _, tt := range tests {
+		if status, class := classifyError(tt.err); status != tt.wantStatus || class != tt.wantClass {
+			t.Errorf("classifyError(%v) = %d, %s, want %d, %s", tt.err, status, class, tt.wantStatus, tt.wantClass)
+		}
+	}
+}
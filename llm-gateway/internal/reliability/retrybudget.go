@@ -0,0 +1,63 @@
+package reliability
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget caps retries at a percentage of the requests made over a
+// sliding window, shared by every retryer it is given to, so retries cannot
+// multiply load on providers that are already failing
+type RetryBudget struct {
+	mu         sync.Mutex
+	window     *timeWindow
+	percent    float64
+	minRetries int
+}
+
+// NewRetryBudget returns a budget allowing retries of up to percent of the
+// requests of the last window, and at least minRetries retries per window
+// however few requests were made
+func NewRetryBudget(percent float64, window time.Duration, minRetries int) *RetryBudget {
+	return &RetryBudget{
+		window:     newTimeWindow(window),
+		percent:    percent,
+		minRetries: minRetries,
+	}
+}
+
+// recordRequest counts a request's first attempt
+func (b *RetryBudget) recordRequest() {
+	b.mu.Lock()
+	b.window.record(false, time.Now())
+	b.mu.Unlock()
+}
+
+// tryRetry spends a retry if the budget has one left, reporting whether it did
+func (b *RetryBudget) tryRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	total, retries := b.window.counts(now)
+	requests := total - retries
+	if retries >= b.minRetries && float64(retries+1) > b.percent/100*float64(requests) {
+		return false
+	}
+	b.window.record(true, now)
+	return true
+}
+
+// Stats returns the requests and retries counted in the window
+func (b *RetryBudget) Stats() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total, retries := b.window.counts(time.Now())
+	return map[string]interface{}{
+		"requests":    total - retries,
+		"retries":     retries,
+		"percent":     b.percent,
+		"min_retries": b.minRetries,
+	}
+}
@@ -0,0 +1,129 @@
+package reliability
+
+import "time"
+
+// Circuit breaker modes
+const (
+	// CircuitModeConsecutive opens the circuit after FailureThreshold
+	// failures in a row
+	CircuitModeConsecutive = "consecutive"
+	// CircuitModeSlidingWindow opens the circuit when the failure rate over
+	// a window of recent requests reaches FailureRateThreshold
+	CircuitModeSlidingWindow = "sliding_window"
+)
+
+// Sliding window types
+const (
+	// WindowTypeCount covers the last WindowSize requests
+	WindowTypeCount = "count"
+	// WindowTypeTime covers the requests of the last WindowDuration
+	WindowTypeTime = "time"
+)
+
+// outcomeWindow holds the outcomes of recent requests
+type outcomeWindow interface {
+	record(failed bool, now time.Time)
+	// counts returns the requests in the window, and how many failed
+	counts(now time.Time) (total, failures int)
+	reset()
+}
+
+// newOutcomeWindow returns the window of a sliding window circuit breaker,
+// or nil in consecutive mode
+func newOutcomeWindow(config CircuitBreakerConfig) outcomeWindow {
+	if config.Mode != CircuitModeSlidingWindow {
+		return nil
+	}
+	if config.WindowType == WindowTypeTime {
+		return newTimeWindow(config.WindowDuration)
+	}
+	return newCountWindow(config.WindowSize)
+}
+
+// failureRate returns the percentage of failed requests
+func failureRate(total, failures int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(failures) / float64(total)
+}
+
+// countWindow is a ring of the last outcomes
+type countWindow struct {
+	failed   []bool
+	next     int
+	filled   int
+	failures int
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{failed: make([]bool, max(size, 1))}
+}
+
+func (w *countWindow) record(failed bool, _ time.Time) {
+	if w.filled == len(w.failed) {
+		if w.failed[w.next] {
+			w.failures--
+		}
+	} else {
+		w.filled++
+	}
+	w.failed[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.failed)
+}
+
+func (w *countWindow) counts(time.Time) (int, int) {
+	return w.filled, w.failures
+}
+
+func (w *countWindow) reset() {
+	clear(w.failed)
+	w.next, w.filled, w.failures = 0, 0, 0
+}
+
+// timeWindow counts outcomes in one-second buckets, so the window slides a
+// second at a time
+type timeWindow struct {
+	buckets []outcomeBucket
+}
+
+type outcomeBucket struct {
+	second   int64
+	total    int
+	failures int
+}
+
+func newTimeWindow(duration time.Duration) *timeWindow {
+	seconds := int((duration + time.Second - 1) / time.Second)
+	return &timeWindow{buckets: make([]outcomeBucket, max(seconds, 1))}
+}
+
+func (w *timeWindow) record(failed bool, now time.Time) {
+	second := now.Unix()
+	b := &w.buckets[second%int64(len(w.buckets))]
+	if b.second != second {
+		*b = outcomeBucket{second: second}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) (total, failures int) {
+	second := now.Unix()
+	for _, b := range w.buckets {
+		if age := second - b.second; age >= 0 && age < int64(len(w.buckets)) {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+func (w *timeWindow) reset() {
+	clear(w.buckets)
+}
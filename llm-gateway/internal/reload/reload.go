@@ -0,0 +1,243 @@
+// Package reload re-reads the config file while the gateway runs, on SIGHUP
+// or, with config_reload.watch, whenever the file is written. Changes that
+// are safe at runtime (rate limits, the cache TTL, provider API keys, the
+// model routing table and log levels) are applied by the subsystems registered with OnReload, without
+// dropping requests or streams in flight; other changes are logged as
+// needing a restart. Each reload that changes anything increments the
+// config generation.
+package reload
+
+import (
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the reload module logger; its level can be set via log.modules.reload
+var logger = observability.ModuleLogger("reload")
+
+// safePaths are the settings applied at runtime
+var safePaths = []string{
+	"rate_limit.requests_per_min",
+	"rate_limit.burst_size",
+	"rate_limit.tiers",
+	"cache.ttl",
+	"providers.openai.api_key",
+	"providers.anthropic.api_key",
+	"log.level",
+	"log.modules",
+	"routing.aliases",
+	"routing.models",
+	"routing.rules",
+}
+
+// Safe reports whether a changed setting is applied without a restart
+func Safe(path string) bool {
+	return slices.Contains(safePaths, path)
+}
+
+// Status reports the configuration in use
+type Status struct {
+	Generation int64     `json:"generation"`
+	LoadedAt   time.Time `json:"loaded_at"`
+	// Applied are the settings the last reload changed at runtime
+	Applied []string `json:"applied"`
+	// RestartRequired are the settings changed since startup that only take
+	// effect after a restart
+	RestartRequired []string   `json:"restart_required"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Reloader reloads the configuration and hands the changes to the
+// registered callbacks
+type Reloader struct {
+	watch bool
+	load  func() (*config.Config, error)
+
+	// mu serializes reloads and guards the fields below
+	mu        sync.Mutex
+	initial   *config.Config
+	current   *config.Config
+	status    Status
+	callbacks []func(old, cur *config.Config)
+
+	trigger chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New creates a reloader for the configuration the gateway started with, or
+// returns nil if reloading is disabled
+func New(cfg config.ConfigReloadConfig, current *config.Config) *Reloader {
+	if !cfg.Enabled {
+		return nil
+	}
+	observability.GetMetrics().ConfigGeneration.Set(1)
+	return &Reloader{
+		watch:   cfg.Watch,
+		load:    config.Load,
+		initial: current,
+		current: current,
+		status:  Status{Generation: 1, LoadedAt: time.Now().UTC(), Applied: []string{}, RestartRequired: []string{}},
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// OnReload registers fn to apply a reloaded configuration; old is the
+// configuration previously loaded. Callbacks apply only what changed, so
+// settings changed at runtime by other means are kept otherwise.
+func (r *Reloader) OnReload(fn func(old, cur *config.Config)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// Start begins reloading on SIGHUP and, with watch, on config file writes
+func (r *Reloader) Start() {
+	if r == nil {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	if r.watch {
+		watching, err := config.WatchFile(r.request)
+		switch {
+		case err != nil:
+			logger.Error().Err(err).Msg("Failed to watch the config file")
+		case !watching:
+			logger.Warn().Msg("No config file in use, nothing to watch")
+		}
+	}
+	go r.loop(hup)
+}
+
+// Stop stops reloading
+func (r *Reloader) Stop() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// request asks the loop for a reload; requests arriving during one are
+// merged, as editors often write a file several times
+func (r *Reloader) request() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Reloader) loop(hup chan os.Signal) {
+	defer close(r.done)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-hup:
+			logger.Info().Msg("Received SIGHUP, reloading configuration")
+			r.Reload()
+		case <-r.trigger:
+			r.Reload()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Reload reads the configuration again and applies it. An invalid
+// configuration is rejected and the current one kept.
+func (r *Reloader) Reload() (Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.load()
+	if err != nil {
+		now := time.Now().UTC()
+		r.status.LastError, r.status.LastErrorAt = err.Error(), &now
+		logger.Error().Err(err).Int64("generation", r.status.Generation).Msg("Configuration reload failed, keeping the current configuration")
+		return r.statusLocked(), err
+	}
+	r.status.LastError, r.status.LastErrorAt = "", nil
+
+	changed := config.Diff(r.current, cfg)
+	if len(changed) == 0 {
+		logger.Debug().Msg("Configuration unchanged")
+		return r.statusLocked(), nil
+	}
+
+	old := r.current
+	r.current = cfg
+	for _, fn := range r.callbacks {
+		fn(old, cfg)
+	}
+
+	applied, restart := []string{}, []string{}
+	for _, path := range changed {
+		if Safe(path) {
+			applied = append(applied, path)
+		}
+	}
+	for _, path := range config.Diff(r.initial, cfg) {
+		if !Safe(path) {
+			restart = append(restart, path)
+		}
+	}
+	r.status.Generation++
+	r.status.LoadedAt = time.Now().UTC()
+	r.status.Applied, r.status.RestartRequired = applied, restart
+	observability.GetMetrics().ConfigGeneration.Set(float64(r.status.Generation))
+
+	logger.Info().
+		Int64("generation", r.status.Generation).
+		Strs("applied", applied).
+		Msg("Configuration reloaded")
+	if len(restart) > 0 {
+		logger.Warn().
+			Strs("settings", restart).
+			Msg("Changed settings take effect after a restart")
+	}
+	return r.statusLocked(), nil
+}
+
+// Status returns the generation of the configuration in use and what the
+// last reload changed
+func (r *Reloader) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statusLocked()
+}
+
+// statusLocked returns a copy of the status; r.mu must be held
+func (r *Reloader) statusLocked() Status {
+	status := r.status
+	status.Applied = slices.Clone(status.Applied)
+	status.RestartRequired = slices.Clone(status.RestartRequired)
+	return status
+}
+
+// defaultReloader is the process-wide reloader used by the admin API and subsystems
+var defaultReloader atomic.Pointer[Reloader]
+
+// SetDefault sets the process-wide reloader
+func SetDefault(r *Reloader) {
+	defaultReloader.Store(r)
+}
+
+// Default returns the process-wide reloader (nil when reloading is disabled)
+func Default() *Reloader {
+	return defaultReloader.Load()
+}
@@ -0,0 +1,84 @@
+package reload
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	if r := New(config.ConfigReloadConfig{}, &config.Config{}); r != nil {
+		t.Error("expected nil reloader when disabled")
+	}
+	// Methods are no-ops on nil
+	var r *Reloader
+	r.OnReload(func(old, cur *config.Config) {})
+	r.Start()
+	r.Stop()
+}
+
+func TestReloader_Reload(t *testing.T) {
+	initial := &config.Config{}
+	initial.RateLimit.RequestsPerMin = 60
+	initial.Server.Port = 8080
+	r := New(config.ConfigReloadConfig{Enabled: true}, initial)
+
+	var next *config.Config
+	var loadErr error
+	r.load = func() (*config.Config, error) { return next, loadErr }
+	var applied []int
+	r.OnReload(func(old, cur *config.Config) {
+		applied = append(applied, cur.RateLimit.RequestsPerMin)
+	})
+
+	// Unchanged: no callbacks, same generation
+	copied := *initial
+	next = &copied
+	status, err := r.Reload()
+	if err != nil || status.Generation != 1 || len(applied) != 0 {
+		t.Fatalf("unchanged: %+v %v %v", status, err, applied)
+	}
+
+	// A safe change is applied and bumps the generation
+	changed := *initial
+	changed.RateLimit.RequestsPerMin = 120
+	next = &changed
+	status, err = r.Reload()
+	if err != nil || status.Generation != 2 || !slices.Equal(applied, []int{120}) {
+		t.Fatalf("safe change: %+v %v %v", status, err, applied)
+	}
+	if !slices.Equal(status.Applied, []string{"rate_limit.requests_per_min"}) || len(status.RestartRequired) != 0 {
+		t.Errorf("safe change status = %+v", status)
+	}
+
+	// An invalid config is rejected and the current one kept
+	loadErr = errors.New("invalid server.port")
+	status, err = r.Reload()
+	if err == nil || status.Generation != 2 || status.LastError == "" || status.LastErrorAt == nil {
+		t.Fatalf("invalid: %+v %v", status, err)
+	}
+	loadErr = nil
+
+	// Other changes need a restart, and stay reported until one
+	restart := changed
+	restart.Server.Port = 9090
+	next = &restart
+	status, err = r.Reload()
+	if err != nil || status.Generation != 3 || status.LastError != "" {
+		t.Fatalf("restart change: %+v %v", status, err)
+	}
+	if len(status.Applied) != 0 || !slices.Equal(status.RestartRequired, []string{"server.port"}) {
+		t.Errorf("restart change status = %+v", status)
+	}
+	cache := restart
+	cache.Cache.TTL = 1
+	next = &cache
+	if status, _ = r.Reload(); !slices.Equal(status.Applied, []string{"cache.ttl"}) || !slices.Equal(status.RestartRequired, []string{"server.port"}) {
+		t.Errorf("after restart change = %+v", status)
+	}
+	if r.Status().Generation != 4 {
+		t.Errorf("generation = %d, want 4", r.Status().Generation)
+	}
+}
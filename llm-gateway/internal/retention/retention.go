@@ -0,0 +1,243 @@
+// Package retention bounds how long stored records are kept. Stores register
+// with a Manager, which periodically purges records past their retention
+// period and handles tenant deletion requests: a deleted tenant's records are
+// hidden straight away and purged once a grace period ends.
+package retention
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the retention module logger; its level can be set via log.modules.retention
+var logger = observability.ModuleLogger("retention")
+
+// Store is a set of records subject to retention
+type Store interface {
+	// Purge drops records older than their tenant's cutoff (a zero cutoff keeps
+	// them) and deleted records whose deletion is older than deletedBefore,
+	// returning the number of records dropped
+	Purge(cutoff func(tenant string) time.Time, deletedBefore time.Time) int
+	// DeleteTenant hides every record of tenant until it is purged, returning
+	// the number of records deleted
+	DeleteTenant(tenant string) int
+}
+
+// Manager applies the retention policy to the registered stores
+type Manager struct {
+	cfg config.RetentionConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	names     []string
+	stores    map[string]Store
+	shared    map[string]bool
+	purged    map[string]int64
+	lastPurge time.Time
+	deletions int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a manager from configuration, or returns nil if retention is disabled
+func New(cfg config.RetentionConfig) *Manager {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Manager{
+		cfg:    cfg,
+		now:    time.Now,
+		stores: make(map[string]Store),
+		shared: make(map[string]bool),
+		purged: make(map[string]int64),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Register adds a store under name; name selects its retention period in
+// retention.stores and retention.tenants
+func (m *Manager) Register(name string, store Store) {
+	m.register(name, store, false)
+}
+
+// RegisterShared adds a store that all replicas share, such as one kept in
+// Redis. Only the elected leader purges it; stores held in each replica's
+// memory are purged by every replica.
+func (m *Manager) RegisterShared(name string, store Store) {
+	m.register(name, store, true)
+}
+
+func (m *Manager) register(name string, store Store, shared bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stores[name]; !ok {
+		m.names = append(m.names, name)
+		sort.Strings(m.names)
+	}
+	m.stores[name] = store
+	m.shared[name] = shared
+}
+
+// Retention returns how long store keeps tenant's records; 0 keeps them
+func (m *Manager) Retention(store, tenant string) time.Duration {
+	// Viper lowercases map keys, so tenant overrides match case-insensitively
+	if override, ok := m.cfg.Tenants[strings.ToLower(tenant)][store]; ok {
+		return override
+	}
+	return m.cfg.Stores[store]
+}
+
+// Start purges once, then keeps purging in the background every purge interval
+func (m *Manager) Start() {
+	if m == nil {
+		return
+	}
+	m.Purge()
+	go m.loop()
+}
+
+// Stop stops purging
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+func (m *Manager) loop() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Purge()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Purge applies the retention policy to every store now, returning the number
+// of records dropped per store. Shared stores are skipped unless this replica
+// is the leader.
+func (m *Manager) Purge() map[string]int {
+	now := m.now()
+	deletedBefore := now.Add(-m.cfg.DeleteGrace)
+	isLeader := leader.Default().IsLeader()
+
+	m.mu.Lock()
+	var names []string
+	stores := make(map[string]Store, len(m.stores))
+	for _, name := range m.names {
+		if m.shared[name] && !isLeader {
+			continue
+		}
+		names = append(names, name)
+		stores[name] = m.stores[name]
+	}
+	m.mu.Unlock()
+
+	result := make(map[string]int, len(names))
+	for _, name := range names {
+		cutoff := func(tenant string) time.Time {
+			if keep := m.Retention(name, tenant); keep > 0 {
+				return now.Add(-keep)
+			}
+			return time.Time{}
+		}
+		result[name] = stores[name].Purge(cutoff, deletedBefore)
+		if result[name] > 0 {
+			logger.Debug().Str("store", name).Int("purged", result[name]).Msg("Purged expired records")
+		}
+	}
+
+	m.mu.Lock()
+	for name, n := range result {
+		m.purged[name] += int64(n)
+	}
+	m.lastPurge = now
+	m.mu.Unlock()
+	return result
+}
+
+// DeleteTenant deletes tenant's records from every store, returning the number
+// of records deleted per store. They are purged after the deletion grace period.
+func (m *Manager) DeleteTenant(tenant string) map[string]int {
+	m.mu.Lock()
+	m.deletions++
+	stores := make(map[string]Store, len(m.stores))
+	for name, store := range m.stores {
+		stores[name] = store
+	}
+	m.mu.Unlock()
+
+	result := make(map[string]int, len(stores))
+	for name, store := range stores {
+		result[name] = store.DeleteTenant(tenant)
+	}
+	logger.Info().
+		Str("tenant", tenant).
+		Interface("deleted", result).
+		Dur("grace", m.cfg.DeleteGrace).
+		Msg("Deleted tenant records")
+	return result
+}
+
+// Stats returns the retention policy and purge counters
+func (m *Manager) Stats() map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stores := make([]map[string]interface{}, 0, len(m.names))
+	for _, name := range m.names {
+		stores = append(stores, map[string]interface{}{
+			"name":      name,
+			"retention": m.cfg.Stores[name].String(),
+			"purged":    m.purged[name],
+			"shared":    m.shared[name],
+		})
+	}
+	stats := map[string]interface{}{
+		"enabled":          true,
+		"purge_interval":   m.cfg.PurgeInterval.String(),
+		"delete_grace":     m.cfg.DeleteGrace.String(),
+		"stores":           stores,
+		"tenant_overrides": len(m.cfg.Tenants),
+		"tenant_deletions": m.deletions,
+	}
+	if !m.lastPurge.IsZero() {
+		stats["last_purge"] = m.lastPurge
+	}
+	return stats
+}
+
+// defaultManager is the process-wide manager used by the API router and admin endpoints
+var defaultManager atomic.Pointer[Manager]
+
+// SetDefault sets the process-wide manager
+func SetDefault(m *Manager) {
+	defaultManager.Store(m)
+}
+
+// Default returns the process-wide manager (nil when retention is disabled)
+func Default() *Manager {
+	return defaultManager.Load()
+}
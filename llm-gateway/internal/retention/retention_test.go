@@ -0,0 +1,142 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/leader"
+)
+
+// record is one fakeStore record
+type record struct {
+	tenant    string
+	created   time.Time
+	deletedAt time.Time
+}
+
+type fakeStore struct {
+	records []record
+}
+
+func (s *fakeStore) Purge(cutoff func(tenant string) time.Time, deletedBefore time.Time) int {
+	kept := s.records[:0]
+	for _, r := range s.records {
+		if c := cutoff(r.tenant); !c.IsZero() && r.created.Before(c) {
+			continue
+		}
+		if !r.deletedAt.IsZero() && r.deletedAt.Before(deletedBefore) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	purged := len(s.records) - len(kept)
+	s.records = kept
+	return purged
+}
+
+func (s *fakeStore) DeleteTenant(tenant string) int {
+	deleted := 0
+	for i := range s.records {
+		if s.records[i].tenant == tenant {
+			s.records[i].deletedAt = time.Now()
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func testManager(now *time.Time) *Manager {
+	m := New(config.RetentionConfig{
+		Enabled:       true,
+		PurgeInterval: time.Hour,
+		DeleteGrace:   time.Hour,
+		Stores:        map[string]time.Duration{"audit": 24 * time.Hour},
+		Tenants:       map[string]map[string]time.Duration{"acme": {"audit": 72 * time.Hour}},
+	})
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestManager_PurgesByTenantRetention(t *testing.T) {
+	now := time.Now()
+	m := testManager(&now)
+	store := &fakeStore{records: []record{
+		{tenant: "other", created: now.Add(-48 * time.Hour)},
+		{tenant: "ACME", created: now.Add(-48 * time.Hour)},
+		{tenant: "other", created: now.Add(-time.Hour)},
+	}}
+	m.Register("audit", store)
+	m.Register("usage", &fakeStore{records: []record{{tenant: "other", created: now.Add(-1000 * time.Hour)}}})
+
+	purged := m.Purge()
+	if purged["audit"] != 1 || purged["usage"] != 0 {
+		t.Fatalf("purged = %v, want one audit record and no usage (no retention set)", purged)
+	}
+	if len(store.records) != 2 || store.records[0].tenant != "ACME" {
+		t.Errorf("records = %+v, want acme's override to keep its record", store.records)
+	}
+}
+
+func TestManager_OnlyLeaderPurgesSharedStores(t *testing.T) {
+	// An elector that never campaigned is a follower
+	leader.SetDefault(leader.NewElector(nil, "follower", time.Minute, time.Second))
+	t.Cleanup(func() { leader.SetDefault(nil) })
+
+	now := time.Now()
+	m := testManager(&now)
+	local := &fakeStore{records: []record{{tenant: "other", created: now.Add(-48 * time.Hour)}}}
+	shared := &fakeStore{records: []record{{tenant: "other", created: now.Add(-48 * time.Hour)}}}
+	m.cfg.Stores["reports"] = 24 * time.Hour
+	m.Register("audit", local)
+	m.RegisterShared("reports", shared)
+
+	m.Purge()
+	if len(local.records) != 0 || len(shared.records) != 1 {
+		t.Fatalf("follower purged local %d/1 and shared %d/1 records, want only local", 1-len(local.records), 1-len(shared.records))
+	}
+
+	leader.SetDefault(nil)
+	m.Purge()
+	if len(shared.records) != 0 {
+		t.Error("expected the leader to purge the shared store")
+	}
+}
+
+func TestManager_DeleteTenantAfterGrace(t *testing.T) {
+	now := time.Now()
+	m := testManager(&now)
+	store := &fakeStore{records: []record{
+		{tenant: "acme", created: now},
+		{tenant: "other", created: now},
+	}}
+	m.Register("audit", store)
+
+	if deleted := m.DeleteTenant("acme"); deleted["audit"] != 1 {
+		t.Fatalf("deleted = %v", deleted)
+	}
+	if purged := m.Purge(); purged["audit"] != 0 {
+		t.Errorf("purged %v within the grace period", purged)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if purged := m.Purge(); purged["audit"] != 1 || len(store.records) != 1 {
+		t.Errorf("purged = %v, records = %+v, want the deleted record gone", purged, store.records)
+	}
+	if stats := m.Stats(); stats["tenant_deletions"] != int64(1) {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	m := New(config.RetentionConfig{})
+	if m != nil {
+		t.Fatal("New returned a manager with retention disabled")
+	}
+	m.Register("audit", &fakeStore{})
+	m.Start()
+	m.Stop()
+	if m.Stats()["enabled"] != false {
+		t.Error("nil manager should report retention disabled")
+	}
+}
@@ -0,0 +1,117 @@
+package sla
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// send delivers a report to the configured webhook and email recipients;
+// failures are logged, the report stays available from the admin API
+func (c *Collector) send(report *Report) {
+	if c.cfg.WebhookURL != "" {
+		if err := c.sendWebhook(report); err != nil {
+			logger.Warn().Err(err).Str("report", report.ID).Msg("Failed to deliver SLA report to webhook")
+		}
+	}
+	if c.cfg.Email.SMTPAddr != "" {
+		if err := c.sendEmail(report); err != nil {
+			logger.Warn().Err(err).Str("report", report.ID).Msg("Failed to email SLA report")
+		}
+	}
+}
+
+// sendWebhook posts the report as JSON, signed with the webhook secret if set
+func (c *Collector) sendWebhook(report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(c.cfg.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendEmail mails a plain text summary of the report
+func (c *Collector) sendEmail(report *Report) error {
+	email := c.cfg.Email
+	var auth smtp.Auth
+	if email.Username != "" {
+		host, _, _ := net.SplitHostPort(email.SMTPAddr)
+		auth = smtp.PlainAuth("", email.Username, email.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", email.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&msg, "Subject: LLM gateway %s SLA report %s\r\n", report.Period, report.Start.Format("2006-01-02"))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(FormatText(report))
+
+	// smtp.SendMail has no timeout, so bound it from the outside
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(email.SMTPAddr, auth, email.From, email.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.cfg.Timeout):
+		return fmt.Errorf("smtp delivery timed out after %s", c.cfg.Timeout)
+	}
+}
+
+// FormatText renders a report as a plain text table
+func FormatText(report *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s SLA report, %s to %s (UTC), instance %s\n\n", report.Period,
+		report.Start.Format("2006-01-02 15:04"), report.End.Format("2006-01-02 15:04"), report.Instance)
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tATTEMPTS\tAVAILABILITY\tP50 MS\tP95 MS\tBREAKER OPEN MIN\tTOKENS\tSPEND\tERRORS")
+	for _, p := range report.Providers {
+		classes := make([]string, 0, len(p.Errors))
+		for class, n := range p.Errors {
+			classes = append(classes, fmt.Sprintf("%s=%d", class, n))
+		}
+		sort.Strings(classes)
+		fmt.Fprintf(tw, "%s\t%d\t%.3f%%\t%.0f\t%.0f\t%d\t%d\t%.2f\t%s\n",
+			p.Provider, p.Attempts, p.Availability*100, p.P50LatencyMs, p.P95LatencyMs,
+			p.BreakerOpenMinutes, p.PromptTokens+p.CompletionTokens, p.Spend, strings.Join(classes, " "))
+	}
+	tw.Flush()
+	if len(report.Providers) == 0 {
+		b.WriteString("\nNo provider traffic in this period.\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,504 @@
+// Package sla generates periodic per-provider SLA reports for vendor reviews:
+// availability, latency percentiles, errors by class, minutes with the circuit
+// breaker open and token spend, per day and per week. Reports are kept for the
+// admin API and optionally delivered to a webhook and by email. Figures and
+// reports are kept in memory per replica, or in Redis shared by all replicas.
+package sla
+
+import (
+	"context"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the SLA module logger; its level can be set via log.modules.sla
+var logger = observability.ModuleLogger("sla")
+
+// Report periods
+const (
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+)
+
+// keepHours is how much hourly data is kept: a week plus the current day
+const keepHours = 8 * 24
+
+// sharedReportDelay is how long after a period ends the leader waits before
+// reporting it from a shared store, so every replica has added its figures
+// for the period's last minute
+const sharedReportDelay = 2 * time.Minute
+
+// latencyBounds are the upper bounds (ms) of the latency histogram buckets;
+// percentiles are interpolated within a bucket
+var latencyBounds = []float64{
+	25, 50, 100, 200, 300, 500, 750, 1000, 1500, 2000, 3000, 5000, 7500, 10000, 15000, 30000, 60000, 120000,
+}
+
+// availabilityErrors are the attempt error classes that count against a
+// provider's availability; invalid requests, bad credentials and requests
+// canceled by the client are not the provider's fault
+var availabilityErrors = map[string]bool{
+	"upstream_error": true,
+	"timeout":        true,
+	"network":        true,
+	"rate_limited":   true,
+}
+
+// ProviderReport is one provider's figures over a report period
+type ProviderReport struct {
+	Provider string `json:"provider"`
+	Attempts int64  `json:"attempts"`
+	// Failures counts attempts that failed for provider-side reasons
+	Failures int64 `json:"failures"`
+	// Availability is the share of attempts that did not fail for provider-side reasons
+	Availability float64          `json:"availability"`
+	P50LatencyMs float64          `json:"p50_latency_ms"`
+	P95LatencyMs float64          `json:"p95_latency_ms"`
+	Errors       map[string]int64 `json:"errors"`
+	// BreakerOpenMinutes counts the minutes the circuit breaker was seen open
+	BreakerOpenMinutes int64   `json:"breaker_open_minutes"`
+	PromptTokens       int64   `json:"prompt_tokens"`
+	CompletionTokens   int64   `json:"completion_tokens"`
+	Spend              float64 `json:"spend"`
+}
+
+// Report is the SLA report of one period
+type Report struct {
+	ID          string           `json:"id"`
+	Period      string           `json:"period"`
+	Start       time.Time        `json:"start"`
+	End         time.Time        `json:"end"`
+	Instance    string           `json:"instance"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Providers   []ProviderReport `json:"providers"`
+}
+
+// hourStats holds one provider's figures for one hour; breakerOpen holds the
+// Unix minutes its circuit breaker was seen open
+type hourStats struct {
+	attempts         int64
+	failures         int64
+	errors           map[string]int64
+	latency          []int64
+	breakerOpen      map[int64]bool
+	promptTokens     int64
+	completionTokens int64
+	spend            float64
+}
+
+// Collector gathers provider figures and generates the reports
+type Collector struct {
+	cfg      config.SLAReportsConfig
+	pricing  config.PricingConfig
+	instance string
+	now      func() time.Time
+	// breakerStates returns the circuit breaker state per provider
+	breakerStates func() map[string]string
+	deliver       func(*Report)
+
+	store store
+	// shared is set when the store is shared by all replicas
+	shared bool
+
+	mu sync.Mutex
+	// pending holds the figures recorded since they were last added to the store
+	pending map[time.Time]map[string]*hourStats
+	// lastTick is when the previous tick ran, to find periods that ended since
+	lastTick time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a collector from configuration, pricing spend with pricing, or
+// returns nil if SLA reports are disabled
+func New(cfg config.SLAReportsConfig, pricing config.PricingConfig) *Collector {
+	if !cfg.Enabled {
+		return nil
+	}
+	instance, _ := os.Hostname()
+	c := &Collector{
+		cfg:      cfg,
+		pricing:  pricing,
+		instance: instance,
+		now:      time.Now,
+		store:    newStore(cfg),
+		shared:   cfg.Store == StoreRedis,
+		pending:  make(map[time.Time]map[string]*hourStats),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	c.deliver = c.send
+	return c
+}
+
+// SetBreakerStates sets the function sampled every minute for breaker open minutes
+func (c *Collector) SetBreakerStates(fn func() map[string]string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakerStates = fn
+}
+
+// Shared reports whether reports are kept in a store all replicas share
+func (c *Collector) Shared() bool {
+	return c != nil && c.shared
+}
+
+// hour returns the pending figures of provider for the hour of t. Callers hold c.mu.
+func (c *Collector) hour(t time.Time, provider string) *hourStats {
+	start := t.UTC().Truncate(time.Hour)
+	providers, ok := c.pending[start]
+	if !ok {
+		providers = make(map[string]*hourStats)
+		c.pending[start] = providers
+	}
+	h, ok := providers[provider]
+	if !ok {
+		h = newHourStats()
+		providers[provider] = h
+	}
+	return h
+}
+
+// flush adds the pending figures to the store. If the store cannot be
+// reached they are kept for the next flush.
+func (c *Collector) flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[time.Time]map[string]*hourStats)
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := c.store.addHours(ctx, pending)
+	if err != nil {
+		c.mu.Lock()
+		for start, providers := range pending {
+			for provider, h := range providers {
+				c.hour(start, provider).add(h)
+			}
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// RecordAttempt adds a provider attempt
+func (c *Collector) RecordAttempt(attempt observability.ProviderAttempt) {
+	if c == nil {
+		return
+	}
+	ms := float64(attempt.Duration.Microseconds()) / 1000
+	bucket := sort.SearchFloat64s(latencyBounds, ms)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hour(c.now(), attempt.Provider)
+	h.attempts++
+	h.latency[bucket]++
+	if attempt.ErrorClass != "" {
+		h.errors[attempt.ErrorClass]++
+		if availabilityErrors[attempt.ErrorClass] {
+			h.failures++
+		}
+	}
+}
+
+// RecordTokens adds tokens a provider served for model to its spend
+func (c *Collector) RecordTokens(provider, model string, promptTokens, completionTokens int) {
+	if c == nil {
+		return
+	}
+	spend := c.pricing.Cost(model, int64(promptTokens), int64(completionTokens))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.hour(c.now(), provider)
+	h.promptTokens += int64(promptTokens)
+	h.completionTokens += int64(completionTokens)
+	h.spend += spend
+}
+
+// Start samples breaker states every minute, adds the figures to the store
+// and generates each report once its period ends. Reports from a memory store
+// cover time this replica was running.
+func (c *Collector) Start() {
+	if c == nil {
+		return
+	}
+	observability.OnProviderAttempt(c.RecordAttempt)
+	c.mu.Lock()
+	c.lastTick = c.now()
+	c.mu.Unlock()
+	go c.loop()
+}
+
+// Stop stops sampling and report generation, adding the last figures to the store
+func (c *Collector) Stop() {
+	if c == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	if err := c.flush(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Failed to store SLA figures")
+	}
+}
+
+func (c *Collector) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.tick()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// tick samples the breakers, adds the figures to the store and generates the
+// reports of periods that ended since the previous tick
+func (c *Collector) tick() {
+	now := c.now()
+	c.mu.Lock()
+	states := c.breakerStates
+	last := c.lastTick
+	c.lastTick = now
+	c.mu.Unlock()
+
+	if states != nil {
+		open := states()
+		c.mu.Lock()
+		for provider, state := range open {
+			if state == "open" {
+				c.hour(now, provider).breakerOpen[now.Unix()/60] = true
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	if err := c.flush(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Failed to store SLA figures")
+	}
+
+	// A period is due once the previous tick was before it ended, plus the
+	// delay for other replicas' figures with a shared store. With a memory
+	// store every replica keeps its own report; shared reports are generated
+	// by the leader alone. Only the leader delivers, so the webhook and
+	// mailbox get one copy.
+	isLeader := leader.Default().IsLeader()
+	delay := time.Duration(0)
+	if c.shared {
+		delay = sharedReportDelay
+	}
+	for _, period := range c.cfg.Periods {
+		start, _ := periodBounds(period, now.Add(-delay))
+		if !last.Before(start.Add(delay)) || (c.shared && !isLeader) {
+			continue
+		}
+		report, err := c.Generate(ctx, period, start.Add(-periodLength(period)), start)
+		if err != nil {
+			logger.Error().Err(err).Str("period", period).Msg("Failed to generate SLA report")
+			continue
+		}
+		if isLeader {
+			c.deliver(report)
+		}
+	}
+	c.store.pruneHours(now.UTC().Truncate(time.Hour).Add(-keepHours * time.Hour))
+}
+
+// periodBounds returns the UTC period (day, or week from Monday) containing t
+func periodBounds(period string, t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == PeriodWeekly {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	return start, start.Add(periodLength(period))
+}
+
+func periodLength(period string) time.Duration {
+	if period == PeriodWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Generate builds, stores and returns the report of period for [start, end)
+func (c *Collector) Generate(ctx context.Context, period string, start, end time.Time) (*Report, error) {
+	report, err := c.build(ctx, period, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.putReport(ctx, report, c.cfg.MaxReports); err != nil {
+		return nil, err
+	}
+	logger.Info().
+		Str("report", report.ID).
+		Int("providers", len(report.Providers)).
+		Msg("Generated SLA report")
+	return report, nil
+}
+
+// Current returns the report of the period in progress so far, without storing it
+func (c *Collector) Current(ctx context.Context, period string) (*Report, error) {
+	start, _ := periodBounds(period, c.now())
+	return c.build(ctx, period, start, c.now())
+}
+
+// build aggregates the stored hours in [start, end), with this replica's
+// pending figures, into a report
+func (c *Collector) build(ctx context.Context, period string, start, end time.Time) (*Report, error) {
+	if err := c.flush(ctx); err != nil {
+		return nil, err
+	}
+	totals, err := c.store.sumHours(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ID:          period + "-" + start.Format("2006-01-02"),
+		Period:      period,
+		Start:       start,
+		End:         end,
+		Instance:    c.instance,
+		GeneratedAt: c.now().UTC(),
+		Providers:   []ProviderReport{},
+	}
+
+	for provider, t := range totals {
+		pr := ProviderReport{
+			Provider:           provider,
+			Attempts:           t.attempts,
+			Failures:           t.failures,
+			Availability:       1,
+			P50LatencyMs:       percentile(t.latency, 0.50),
+			P95LatencyMs:       percentile(t.latency, 0.95),
+			Errors:             t.errors,
+			BreakerOpenMinutes: int64(len(t.breakerOpen)),
+			PromptTokens:       t.promptTokens,
+			CompletionTokens:   t.completionTokens,
+			Spend:              math.Round(t.spend*1e6) / 1e6,
+		}
+		if t.attempts > 0 {
+			pr.Availability = 1 - float64(t.failures)/float64(t.attempts)
+		}
+		report.Providers = append(report.Providers, pr)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool {
+		return report.Providers[i].Provider < report.Providers[j].Provider
+	})
+	return report, nil
+}
+
+// percentile estimates the q quantile (ms) of a latency histogram by linear
+// interpolation within the bucket it falls in
+func percentile(counts []int64, q float64) float64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	var seen int64
+	for i, n := range counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		if i == len(latencyBounds) {
+			// Past the last bound there is nothing to interpolate to
+			return lower
+		}
+		return lower + (latencyBounds[i]-lower)*(rank-float64(seen))/float64(n)
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// Reports returns the stored reports, oldest first
+func (c *Collector) Reports(ctx context.Context) ([]*Report, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return c.store.reports(ctx)
+}
+
+// Report returns the stored report with id
+func (c *Collector) Report(ctx context.Context, id string) (*Report, bool, error) {
+	if c == nil {
+		return nil, false, nil
+	}
+	reports, err := c.store.reports(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, r := range reports {
+		if r.ID == id {
+			return r, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Purge drops reports that ended before the retention cutoff, returning the
+// number dropped. Reports belong to no tenant, so the "" cutoff applies.
+func (c *Collector) Purge(cutoff func(tenant string) time.Time, _ time.Time) int {
+	before := cutoff("")
+	if before.IsZero() {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	purged, err := c.store.purgeReports(ctx, before)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to purge SLA reports")
+	}
+	return purged
+}
+
+// DeleteTenant deletes nothing: reports hold no tenant data
+func (c *Collector) DeleteTenant(string) int {
+	return 0
+}
+
+// defaultCollector is the process-wide collector used by the API handlers and admin endpoints
+var defaultCollector atomic.Pointer[Collector]
+
+// SetDefault sets the process-wide collector
+func SetDefault(c *Collector) {
+	defaultCollector.Store(c)
+}
+
+// Default returns the process-wide collector (nil when SLA reports are disabled)
+func Default() *Collector {
+	return defaultCollector.Load()
+}
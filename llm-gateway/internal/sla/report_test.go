@@ -0,0 +1,229 @@
+package sla
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+func newTestCollector(t *testing.T, cfg config.SLAReportsConfig, now *time.Time) *Collector {
+	t.Helper()
+	cfg.Enabled = true
+	if cfg.MaxReports == 0 {
+		cfg.MaxReports = 10
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	pricing := config.PricingConfig{"gpt-4o": {Prompt: 2.5, Completion: 10}}
+	c := New(cfg, pricing)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestNew_Disabled(t *testing.T) {
+	if c := New(config.SLAReportsConfig{}, nil); c != nil {
+		t.Fatal("expected nil collector when disabled")
+	}
+	var c *Collector
+	c.RecordAttempt(observability.ProviderAttempt{Provider: "openai"})
+	c.RecordTokens("openai", "gpt-4o", 1, 1)
+	if reports, _ := c.Reports(context.Background()); reports != nil {
+		t.Error("expected no reports from a nil collector")
+	}
+}
+
+func TestCollector_Generate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := newTestCollector(t, config.SLAReportsConfig{}, &now)
+
+	for i := 0; i < 18; i++ {
+		c.RecordAttempt(observability.ProviderAttempt{Provider: "openai", Duration: 80 * time.Millisecond})
+	}
+	c.RecordAttempt(observability.ProviderAttempt{Provider: "openai", Duration: 2 * time.Second, ErrorClass: "timeout"})
+	// Invalid requests are not the provider's fault
+	c.RecordAttempt(observability.ProviderAttempt{Provider: "openai", Duration: 40 * time.Millisecond, ErrorClass: "invalid_request"})
+	c.RecordTokens("openai", "gpt-4o", 1_000_000, 100_000)
+	c.SetBreakerStates(func() map[string]string {
+		return map[string]string{"openai": "closed", "anthropic": "open"}
+	})
+	c.tick()
+	now = now.Add(time.Minute)
+	c.tick()
+	// A minute sampled twice counts once
+	c.tick()
+
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	report, err := c.Generate(context.Background(), PeriodDaily, start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ID != "daily-2026-03-10" {
+		t.Errorf("expected ID daily-2026-03-10, got %s", report.ID)
+	}
+	if len(report.Providers) != 2 || report.Providers[0].Provider != "anthropic" {
+		t.Fatalf("expected anthropic and openai, got %+v", report.Providers)
+	}
+	if got := report.Providers[0].BreakerOpenMinutes; got != 2 {
+		t.Errorf("expected 2 breaker open minutes, got %d", got)
+	}
+
+	openai := report.Providers[1]
+	if openai.Attempts != 20 || openai.Failures != 1 {
+		t.Errorf("expected 20 attempts and 1 failure, got %d and %d", openai.Attempts, openai.Failures)
+	}
+	if openai.Availability != 0.95 {
+		t.Errorf("expected availability 0.95, got %v", openai.Availability)
+	}
+	if openai.P50LatencyMs < 50 || openai.P50LatencyMs > 100 {
+		t.Errorf("expected p50 in the 50-100ms bucket, got %v", openai.P50LatencyMs)
+	}
+	if openai.P95LatencyMs < 100 {
+		t.Errorf("expected p95 above 100ms, got %v", openai.P95LatencyMs)
+	}
+	if openai.Errors["timeout"] != 1 || openai.Errors["invalid_request"] != 1 {
+		t.Errorf("unexpected errors %v", openai.Errors)
+	}
+	if openai.Spend != 3.5 {
+		t.Errorf("expected spend 3.5, got %v", openai.Spend)
+	}
+
+	// Regenerating a period replaces its report
+	c.Generate(context.Background(), PeriodDaily, start, start.Add(24*time.Hour))
+	if reports, _ := c.Reports(context.Background()); len(reports) != 1 {
+		t.Errorf("expected 1 stored report, got %d", len(reports))
+	}
+	if _, ok, _ := c.Report(context.Background(), "daily-2026-03-10"); !ok {
+		t.Error("expected report to be found by ID")
+	}
+}
+
+func TestCollector_TickGeneratesEndedPeriods(t *testing.T) {
+	// Sunday evening, so both the day and the week end at midnight
+	now := time.Date(2026, 3, 15, 23, 59, 30, 0, time.UTC)
+	c := newTestCollector(t, config.SLAReportsConfig{Periods: []string{PeriodDaily, PeriodWeekly}}, &now)
+	var delivered []string
+	c.deliver = func(r *Report) { delivered = append(delivered, r.ID) }
+	c.lastTick = now
+
+	c.RecordAttempt(observability.ProviderAttempt{Provider: "openai", Duration: time.Millisecond})
+	c.tick()
+	if len(delivered) != 0 {
+		t.Fatalf("expected no report before the period ends, got %v", delivered)
+	}
+
+	now = now.Add(time.Minute)
+	c.tick()
+	if strings.Join(delivered, ",") != "daily-2026-03-15,weekly-2026-03-09" {
+		t.Fatalf("unexpected reports %v", delivered)
+	}
+	report, _, _ := c.Report(context.Background(), "weekly-2026-03-09")
+	if len(report.Providers) != 1 || report.Providers[0].Attempts != 1 {
+		t.Errorf("expected the week's attempt in the weekly report, got %+v", report.Providers)
+	}
+
+	now = now.Add(time.Minute)
+	c.tick()
+	if len(delivered) != 2 {
+		t.Errorf("expected each report once, got %v", delivered)
+	}
+}
+
+func TestCollector_OnlyLeaderDelivers(t *testing.T) {
+	// An elector that never campaigned is a follower
+	leader.SetDefault(leader.NewElector(nil, "follower", time.Minute, time.Second))
+	t.Cleanup(func() { leader.SetDefault(nil) })
+
+	now := time.Date(2026, 3, 15, 23, 59, 30, 0, time.UTC)
+	c := newTestCollector(t, config.SLAReportsConfig{Periods: []string{PeriodDaily}}, &now)
+	var delivered []string
+	c.deliver = func(r *Report) { delivered = append(delivered, r.ID) }
+	c.lastTick = now
+
+	now = now.Add(time.Minute)
+	c.tick()
+	if len(delivered) != 0 {
+		t.Errorf("follower delivered %v", delivered)
+	}
+	if _, ok, _ := c.Report(context.Background(), "daily-2026-03-15"); !ok {
+		t.Error("expected the follower to keep its report")
+	}
+}
+
+func TestCollector_MaxReportsAndPurge(t *testing.T) {
+	now := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	c := newTestCollector(t, config.SLAReportsConfig{MaxReports: 3}, &now)
+	for day := 1; day <= 5; day++ {
+		start := time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC)
+		c.Generate(context.Background(), PeriodDaily, start, start.Add(24*time.Hour))
+	}
+	reports, _ := c.Reports(context.Background())
+	if len(reports) != 3 || reports[0].ID != "daily-2026-03-03" {
+		t.Fatalf("expected the 3 newest reports, got %d starting %s", len(reports), reports[0].ID)
+	}
+
+	cutoff := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	purged := c.Purge(func(string) time.Time { return cutoff }, time.Time{})
+	if purged != 1 {
+		t.Errorf("expected 1 report purged, got %d", purged)
+	}
+	if n := c.Purge(func(string) time.Time { return time.Time{} }, time.Time{}); n != 0 {
+		t.Errorf("expected a zero cutoff to keep reports, purged %d", n)
+	}
+}
+
+func TestCollector_SendWebhook(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature")
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	c := newTestCollector(t, config.SLAReportsConfig{WebhookURL: server.URL, WebhookSecret: "s3cret"}, &now)
+	if err := c.sendWebhook(&Report{ID: "daily-2026-03-09", Period: PeriodDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(body, &report); err != nil || report.ID != "daily-2026-03-09" {
+		t.Fatalf("unexpected body %s (%v)", body, err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("expected signature %s, got %s", want, signature)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	report := &Report{
+		Period: PeriodDaily,
+		Start:  time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+		Providers: []ProviderReport{{
+			Provider: "openai", Attempts: 10, Availability: 0.9,
+			Errors: map[string]int64{"timeout": 1},
+		}},
+	}
+	text := FormatText(report)
+	for _, want := range []string{"daily SLA report", "openai", "90.000%", "timeout=1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+}
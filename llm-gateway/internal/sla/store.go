@@ -0,0 +1,356 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// Report stores
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// defaultKeyPrefix namespaces the Redis store's keys
+const defaultKeyPrefix = "llm-gateway:sla:"
+
+// store keeps the hourly figures replicas record and the generated reports
+type store interface {
+	// addHours adds figures to the hours they were recorded in
+	addHours(ctx context.Context, hours map[time.Time]map[string]*hourStats) error
+	// sumHours returns the figures of the hours in [start, end) summed per provider
+	sumHours(ctx context.Context, start, end time.Time) (map[string]*hourStats, error)
+	// pruneHours drops the figures of hours before cutoff
+	pruneHours(cutoff time.Time)
+	// putReport stores report, replacing one with its ID, and keeps the newest max reports
+	putReport(ctx context.Context, report *Report, max int) error
+	// reports returns the stored reports, oldest first
+	reports(ctx context.Context) ([]*Report, error)
+	// purgeReports drops reports that ended before cutoff, returning how many
+	purgeReports(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// newStore creates the store cfg selects
+func newStore(cfg config.SLAReportsConfig) store {
+	if cfg.Store == StoreRedis {
+		prefix := cfg.KeyPrefix
+		if prefix == "" {
+			prefix = defaultKeyPrefix
+		}
+		return &redisStore{client: redisconn.New(cfg.Redis.Options()), prefix: prefix}
+	}
+	return &memoryStore{hours: make(map[time.Time]map[string]*hourStats)}
+}
+
+func newHourStats() *hourStats {
+	return &hourStats{
+		errors:      make(map[string]int64),
+		latency:     make([]int64, len(latencyBounds)+1),
+		breakerOpen: make(map[int64]bool),
+	}
+}
+
+// add adds o's figures to h
+func (h *hourStats) add(o *hourStats) {
+	h.attempts += o.attempts
+	h.failures += o.failures
+	for class, n := range o.errors {
+		h.errors[class] += n
+	}
+	for i, n := range o.latency {
+		h.latency[i] += n
+	}
+	for minute := range o.breakerOpen {
+		h.breakerOpen[minute] = true
+	}
+	h.promptTokens += o.promptTokens
+	h.completionTokens += o.completionTokens
+	h.spend += o.spend
+}
+
+// memoryStore keeps figures and reports in this replica's memory
+type memoryStore struct {
+	mu    sync.Mutex
+	hours map[time.Time]map[string]*hourStats
+	list  []*Report
+}
+
+func (s *memoryStore) addHours(_ context.Context, hours map[time.Time]map[string]*hourStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for start, providers := range hours {
+		stored, ok := s.hours[start]
+		if !ok {
+			stored = make(map[string]*hourStats)
+			s.hours[start] = stored
+		}
+		for provider, h := range providers {
+			if _, ok := stored[provider]; !ok {
+				stored[provider] = newHourStats()
+			}
+			stored[provider].add(h)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) sumHours(_ context.Context, start, end time.Time) (map[string]*hourStats, error) {
+	totals := make(map[string]*hourStats)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hourStart, providers := range s.hours {
+		if hourStart.Before(start) || !hourStart.Before(end) {
+			continue
+		}
+		for provider, h := range providers {
+			if _, ok := totals[provider]; !ok {
+				totals[provider] = newHourStats()
+			}
+			totals[provider].add(h)
+		}
+	}
+	return totals, nil
+}
+
+func (s *memoryStore) pruneHours(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for start := range s.hours {
+		if start.Before(cutoff) {
+			delete(s.hours, start)
+		}
+	}
+}
+
+func (s *memoryStore) putReport(_ context.Context, report *Report, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.list {
+		if r.ID == report.ID {
+			s.list = append(s.list[:i], s.list[i+1:]...)
+			break
+		}
+	}
+	s.list = append(s.list, report)
+	if over := len(s.list) - max; over > 0 {
+		s.list = append(s.list[:0], s.list[over:]...)
+	}
+	return nil
+}
+
+func (s *memoryStore) reports(context.Context) ([]*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Report(nil), s.list...), nil
+}
+
+func (s *memoryStore) purgeReports(_ context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.list[:0]
+	for _, r := range s.list {
+		if !r.End.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	purged := len(s.list) - len(kept)
+	s.list = kept
+	return purged, nil
+}
+
+// redisStore keeps figures and reports in Redis, so every replica adds to
+// the same hours and reads the same reports. Each hour is a hash with
+// "<provider>|<figure>" fields that replicas increment; breaker minutes are
+// "<provider>|breaker|<Unix minute>" fields, so a minute two replicas saw open
+// counts once. Hours expire once no report needs them. Reports are JSON in a
+// hash keyed by report ID.
+type redisStore struct {
+	client *redisconn.Client
+	prefix string
+}
+
+func (s *redisStore) hourKey(start time.Time) string {
+	return s.prefix + "hour:" + start.UTC().Format(time.RFC3339)
+}
+
+func (s *redisStore) addHours(ctx context.Context, hours map[time.Time]map[string]*hourStats) error {
+	var cmds [][]string
+	ttl := strconv.Itoa((keepHours + 1) * 3600)
+	for start, providers := range hours {
+		key := s.hourKey(start)
+		for provider, h := range providers {
+			incr := func(figure string, n int64) {
+				if n != 0 {
+					cmds = append(cmds, []string{"HINCRBY", key, provider + "|" + figure, strconv.FormatInt(n, 10)})
+				}
+			}
+			incr("attempts", h.attempts)
+			incr("failures", h.failures)
+			for class, n := range h.errors {
+				incr("error|"+class, n)
+			}
+			for i, n := range h.latency {
+				incr("latency|"+strconv.Itoa(i), n)
+			}
+			incr("prompt_tokens", h.promptTokens)
+			incr("completion_tokens", h.completionTokens)
+			if h.spend != 0 {
+				cmds = append(cmds, []string{"HINCRBYFLOAT", key, provider + "|spend", strconv.FormatFloat(h.spend, 'f', -1, 64)})
+			}
+			for minute := range h.breakerOpen {
+				cmds = append(cmds, []string{"HSET", key, provider + "|breaker|" + strconv.FormatInt(minute, 10), "1"})
+			}
+		}
+		cmds = append(cmds, []string{"EXPIRE", key, ttl})
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	_, err := s.client.Pipeline(ctx, cmds...)
+	return err
+}
+
+func (s *redisStore) sumHours(ctx context.Context, start, end time.Time) (map[string]*hourStats, error) {
+	var cmds [][]string
+	for hour := start.UTC().Truncate(time.Hour); hour.Before(end); hour = hour.Add(time.Hour) {
+		cmds = append(cmds, []string{"HGETALL", s.hourKey(hour)})
+	}
+	totals := make(map[string]*hourStats)
+	if len(cmds) == 0 {
+		return totals, nil
+	}
+	replies, err := s.client.Pipeline(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reply := range replies {
+		fields, _ := reply.([]interface{})
+		// The reply alternates field names and values
+		for j := 1; j < len(fields); j += 2 {
+			name, _ := fields[j-1].(string)
+			value, _ := fields[j].(string)
+			provider, figure, ok := strings.Cut(name, "|")
+			if !ok {
+				continue
+			}
+			if _, ok := totals[provider]; !ok {
+				totals[provider] = newHourStats()
+			}
+			addFigure(totals[provider], figure, value)
+		}
+	}
+	return totals, nil
+}
+
+// addFigure adds one stored figure to t
+func addFigure(t *hourStats, figure, value string) {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	switch kind, rest, _ := strings.Cut(figure, "|"); kind {
+	case "attempts":
+		t.attempts += n
+	case "failures":
+		t.failures += n
+	case "error":
+		t.errors[rest] += n
+	case "latency":
+		if i, err := strconv.Atoi(rest); err == nil && i >= 0 && i < len(t.latency) {
+			t.latency[i] += n
+		}
+	case "breaker":
+		minute, _ := strconv.ParseInt(rest, 10, 64)
+		t.breakerOpen[minute] = true
+	case "prompt_tokens":
+		t.promptTokens += n
+	case "completion_tokens":
+		t.completionTokens += n
+	case "spend":
+		spend, _ := strconv.ParseFloat(value, 64)
+		t.spend += spend
+	}
+}
+
+// pruneHours does nothing: hours expire in Redis
+func (s *redisStore) pruneHours(time.Time) {}
+
+func (s *redisStore) putReport(ctx context.Context, report *Report, max int) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.Do(ctx, "HSET", s.prefix+"reports", report.ID, string(data)); err != nil {
+		return err
+	}
+
+	reports, err := s.reports(ctx)
+	if err != nil {
+		return err
+	}
+	if over := len(reports) - max; over > 0 {
+		return s.deleteReports(ctx, reports[:over])
+	}
+	return nil
+}
+
+func (s *redisStore) reports(ctx context.Context) ([]*Report, error) {
+	reply, err := s.client.Do(ctx, "HGETALL", s.prefix+"reports")
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected HGETALL reply %v", reply)
+	}
+
+	var reports []*Report
+	for i := 1; i < len(fields); i += 2 {
+		data, _ := fields[i].(string)
+		var r Report
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, fmt.Errorf("failed to decode SLA report %v: %w", fields[i-1], err)
+		}
+		reports = append(reports, &r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].End.Equal(reports[j].End) {
+			return reports[i].End.Before(reports[j].End)
+		}
+		return reports[i].ID < reports[j].ID
+	})
+	return reports, nil
+}
+
+func (s *redisStore) purgeReports(ctx context.Context, cutoff time.Time) (int, error) {
+	reports, err := s.reports(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var old []*Report
+	for _, r := range reports {
+		if r.End.Before(cutoff) {
+			old = append(old, r)
+		}
+	}
+	if len(old) == 0 {
+		return 0, nil
+	}
+	return len(old), s.deleteReports(ctx, old)
+}
+
+func (s *redisStore) deleteReports(ctx context.Context, reports []*Report) error {
+	cmd := []string{"HDEL", s.prefix + "reports"}
+	for _, r := range reports {
+		cmd = append(cmd, r.ID)
+	}
+	_, err := s.client.Do(ctx, cmd...)
+	return err
+}
@@ -0,0 +1,166 @@
+package sla
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/leader"
+	"github.com/username/llm-gateway/internal/observability"
+	"github.com/username/llm-gateway/internal/redisconn"
+)
+
+// fakeRedis serves the hash commands of the redis store, returning its address
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	hashes := map[string]map[string]string{}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			reply, err := redisconn.ReadReply(r)
+			items, _ := reply.([]interface{})
+			if err != nil || len(items) < 2 {
+				return
+			}
+			args := make([]string, len(items))
+			for i, item := range items {
+				args[i], _ = item.(string)
+			}
+
+			mu.Lock()
+			hash := hashes[args[1]]
+			if hash == nil {
+				hash = map[string]string{}
+				hashes[args[1]] = hash
+			}
+			switch args[0] {
+			case "HSET":
+				hash[args[2]] = args[3]
+				fmt.Fprint(conn, ":1\r\n")
+			case "HINCRBY":
+				n, _ := strconv.ParseInt(hash[args[2]], 10, 64)
+				by, _ := strconv.ParseInt(args[3], 10, 64)
+				hash[args[2]] = strconv.FormatInt(n+by, 10)
+				fmt.Fprintf(conn, ":%d\r\n", n+by)
+			case "HINCRBYFLOAT":
+				n, _ := strconv.ParseFloat(hash[args[2]], 64)
+				by, _ := strconv.ParseFloat(args[3], 64)
+				hash[args[2]] = strconv.FormatFloat(n+by, 'f', -1, 64)
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(hash[args[2]]), hash[args[2]])
+			case "HDEL":
+				for _, field := range args[2:] {
+					delete(hash, field)
+				}
+				fmt.Fprint(conn, ":1\r\n")
+			case "EXPIRE":
+				fmt.Fprint(conn, ":1\r\n")
+			case "HGETALL":
+				fields := make([]string, 0, len(hash))
+				for f := range hash {
+					fields = append(fields, f)
+				}
+				sort.Strings(fields)
+				fmt.Fprintf(conn, "*%d\r\n", 2*len(fields))
+				for _, f := range fields {
+					fmt.Fprintf(conn, "$%d\r\n%s\r\n$%d\r\n%s\r\n", len(f), f, len(hash[f]), hash[f])
+				}
+			default:
+				fmt.Fprintf(conn, "-ERR unknown command %v\r\n", args[0])
+			}
+			mu.Unlock()
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCollector_SharedStoreAggregatesReplicas(t *testing.T) {
+	cfg := config.SLAReportsConfig{
+		Periods: []string{PeriodDaily},
+		Store:   StoreRedis,
+		Redis:   config.RedisConfig{Address: fakeRedis(t)},
+	}
+	now := time.Date(2026, 3, 15, 23, 58, 30, 0, time.UTC)
+	replicas := []*Collector{newTestCollector(t, cfg, &now), newTestCollector(t, cfg, &now)}
+	var delivered []string
+	for _, c := range replicas {
+		if !c.Shared() {
+			t.Fatal("expected a shared store")
+		}
+		c.deliver = func(r *Report) { delivered = append(delivered, r.ID+"@"+r.Instance) }
+		c.lastTick = now
+		c.RecordAttempt(observability.ProviderAttempt{Provider: "openai", Duration: time.Millisecond})
+		c.RecordTokens("openai", "gpt-4o", 1_000_000, 0)
+		// Both replicas see the breaker open in the same minute
+		c.SetBreakerStates(func() map[string]string { return map[string]string{"openai": "open"} })
+	}
+	replicas[0].instance, replicas[1].instance = "leader", "follower"
+
+	// The follower never reports, however late it ticks
+	leader.SetDefault(leader.NewElector(nil, "follower", time.Minute, time.Second))
+	t.Cleanup(func() { leader.SetDefault(nil) })
+	replicas[1].tick()
+	now = time.Date(2026, 3, 16, 0, 5, 0, 0, time.UTC)
+	replicas[1].tick()
+	if reports, err := replicas[1].Reports(t.Context()); err != nil || len(reports) != 0 || len(delivered) != 0 {
+		t.Fatalf("follower reported %v (%v), delivered %v", reports, err, delivered)
+	}
+
+	// The leader waits for the other replicas' last figures
+	leader.SetDefault(nil)
+	now = time.Date(2026, 3, 15, 23, 58, 30, 0, time.UTC)
+	replicas[0].tick()
+	now = now.Add(time.Minute + 30*time.Second)
+	replicas[0].tick()
+	if len(delivered) != 0 {
+		t.Fatalf("delivered %v before the report delay", delivered)
+	}
+	now = now.Add(2 * time.Minute)
+	replicas[0].tick()
+	if len(delivered) != 1 || delivered[0] != "daily-2026-03-15@leader" {
+		t.Fatalf("delivered %v, want the daily report once from the leader", delivered)
+	}
+
+	// Either replica reads the report, which covers both replicas' figures
+	report, ok, err := replicas[1].Report(t.Context(), "daily-2026-03-15")
+	if err != nil || !ok {
+		t.Fatalf("Report() = %v, %v", ok, err)
+	}
+	if len(report.Providers) != 1 {
+		t.Fatalf("providers = %+v", report.Providers)
+	}
+	openai := report.Providers[0]
+	if openai.Attempts != 2 || openai.PromptTokens != 2_000_000 || openai.Spend != 5 {
+		t.Errorf("openai = %+v, want both replicas' attempts and spend", openai)
+	}
+	if openai.BreakerOpenMinutes != 1 {
+		t.Errorf("breaker open minutes = %d, want the shared minute once", openai.BreakerOpenMinutes)
+	}
+
+	purged := replicas[0].Purge(func(string) time.Time { return now }, time.Time{})
+	if reports, _ := replicas[1].Reports(t.Context()); purged != 1 || len(reports) != 0 {
+		t.Errorf("purged %d, %d reports left", purged, len(reports))
+	}
+}
@@ -0,0 +1,304 @@
+// Package styles manages named, versioned system prompt presets ("styles").
+// Requests reference a style by name and the gateway injects the version
+// serving them: the stable version, or a new version being rolled out to a
+// percentage of callers. Callers are assigned to a version by a hash of their
+// identity, so each caller keeps the same version for the whole rollout.
+package styles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/username/llm-gateway/internal/config"
+	"github.com/username/llm-gateway/internal/observability"
+)
+
+// logger is the styles module logger; its level can be set via log.modules.styles
+var logger = observability.ModuleLogger("styles")
+
+var (
+	// ErrNotFound is returned for a style that does not exist
+	ErrNotFound = errors.New("style not found")
+	// ErrVersionNotFound is returned for a version a style does not have
+	ErrVersionNotFound = errors.New("style version not found")
+	// ErrInvalid is returned for invalid names, content or rollouts
+	ErrInvalid = errors.New("invalid style")
+)
+
+// namePattern restricts style names; "@" is reserved for pinning a version
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Version is one revision of a style's system prompt
+type Version struct {
+	Version   int       `json:"version"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+// Style is a named system prompt preset
+type Style struct {
+	Name     string    `json:"name"`
+	Versions []Version `json:"versions"`
+	// Stable is the version serving callers outside the rollout
+	Stable int `json:"stable"`
+	// Candidate is the version being rolled out to Percent of callers (0 if none)
+	Candidate int       `json:"candidate,omitempty"`
+	Percent   float64   `json:"percent,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// version returns version n of s
+func (s *Style) version(n int) (Version, bool) {
+	for _, v := range s.Versions {
+		if v.Version == n {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// copy returns a deep copy of s for callers outside the registry
+func (s *Style) copy() Style {
+	c := *s
+	c.Versions = append([]Version(nil), s.Versions...)
+	return c
+}
+
+// Resolved is the version of a style serving one request
+type Resolved struct {
+	Name    string
+	Version int
+	Content string
+	// Candidate is set when the version was picked by a rollout
+	Candidate bool
+}
+
+// String identifies the version, e.g. "support_agent@3"
+func (r Resolved) String() string {
+	return r.Name + "@" + strconv.Itoa(r.Version)
+}
+
+// Registry holds the styles and persists them to the configured file
+type Registry struct {
+	file string
+
+	mu     sync.Mutex
+	styles map[string]*Style
+}
+
+// New creates a registry from configuration, loading presets saved in
+// styles.file. It returns nil if styles are disabled.
+func New(cfg config.StylesConfig) (*Registry, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &Registry{file: cfg.File, styles: make(map[string]*Style)}
+	if r.file == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(r.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []*Style
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", r.file, err)
+	}
+	for _, s := range saved {
+		r.styles[s.Name] = s
+	}
+	logger.Info().Int("styles", len(saved)).Str("file", r.file).Msg("Loaded styles")
+	return r, nil
+}
+
+// List returns every style, sorted by name
+func (r *Registry) List() []Style {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Style, 0, len(r.styles))
+	for _, s := range r.styles {
+		list = append(list, s.copy())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the style called name
+func (r *Registry) Get(name string) (Style, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.styles[name]
+	if !ok {
+		return Style{}, ErrNotFound
+	}
+	return s.copy(), nil
+}
+
+// AddVersion adds a version of the style called name, creating the style if
+// needed. The first version becomes stable; later ones only serve traffic once
+// rolled out with SetRollout.
+func (r *Registry) AddVersion(name, content, createdBy string) (Version, error) {
+	if !namePattern.MatchString(name) {
+		return Version{}, fmt.Errorf("%w: name must be 1-64 letters, digits, '_', '.' or '-'", ErrInvalid)
+	}
+	if strings.TrimSpace(content) == "" {
+		return Version{}, fmt.Errorf("%w: content is required", ErrInvalid)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	s, ok := r.styles[name]
+	if !ok {
+		s = &Style{Name: name}
+		r.styles[name] = s
+	}
+	v := Version{Version: 1, Content: content, CreatedAt: now, CreatedBy: createdBy}
+	if n := len(s.Versions); n > 0 {
+		v.Version = s.Versions[n-1].Version + 1
+	}
+	s.Versions = append(s.Versions, v)
+	if s.Stable == 0 {
+		s.Stable = v.Version
+	}
+	s.UpdatedAt = now
+	r.save()
+	return v, nil
+}
+
+// SetRollout serves version to percent of callers. 100 makes it the stable
+// version; 0 ends the rollout, leaving the stable version serving everyone.
+func (r *Registry) SetRollout(name string, version int, percent float64) (Style, error) {
+	if percent < 0 || percent > 100 {
+		return Style{}, fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalid)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.styles[name]
+	if !ok {
+		return Style{}, ErrNotFound
+	}
+	if _, ok := s.version(version); !ok {
+		return Style{}, ErrVersionNotFound
+	}
+	switch {
+	case percent == 100:
+		s.Stable, s.Candidate, s.Percent = version, 0, 0
+	case percent == 0 || version == s.Stable:
+		s.Candidate, s.Percent = 0, 0
+	default:
+		s.Candidate, s.Percent = version, percent
+	}
+	s.UpdatedAt = time.Now().UTC()
+	r.save()
+	return s.copy(), nil
+}
+
+// Delete removes the style called name
+func (r *Registry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.styles[name]; !ok {
+		return ErrNotFound
+	}
+	delete(r.styles, name)
+	r.save()
+	return nil
+}
+
+// Resolve returns the version of a style serving a caller. ref is a style name,
+// or "name@version" to pin a version; caller (an end user or tenant) keeps the
+// same side of a rollout on every request.
+func (r *Registry) Resolve(ref, caller string) (Resolved, error) {
+	name, pinned := ref, 0
+	if i := strings.LastIndexByte(ref, '@'); i >= 0 {
+		n, err := strconv.Atoi(ref[i+1:])
+		if err != nil || n <= 0 {
+			return Resolved{}, fmt.Errorf("%w: bad version in %q", ErrInvalid, ref)
+		}
+		name, pinned = ref[:i], n
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.styles[name]
+	if !ok {
+		return Resolved{}, ErrNotFound
+	}
+
+	version, candidate := s.Stable, false
+	switch {
+	case pinned > 0:
+		version = pinned
+	case s.Candidate != 0 && bucket(name, caller) < s.Percent:
+		version, candidate = s.Candidate, true
+	}
+	v, ok := s.version(version)
+	if !ok {
+		return Resolved{}, ErrVersionNotFound
+	}
+	return Resolved{Name: name, Version: v.Version, Content: v.Content, Candidate: candidate}, nil
+}
+
+// bucket maps a caller to [0, 100) for a style. The style name is part of the
+// hash, so callers are not always early adopters across every style.
+func bucket(name, caller string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(caller))
+	return float64(h.Sum64()%10000) / 100
+}
+
+// save writes the styles to the configured file via a temporary file, so a
+// crash never leaves a partial file. Callers hold r.mu.
+func (r *Registry) save() {
+	if r.file == "" {
+		return
+	}
+	list := make([]*Style, 0, len(r.styles))
+	for _, s := range r.styles {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err == nil {
+		tmp := r.file + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, r.file)
+		}
+	}
+	if err != nil {
+		logger.Warn().Err(err).Str("file", r.file).Msg("Failed to save styles")
+	}
+}
+
+// defaultRegistry is the process-wide registry used by the API handlers and admin endpoints
+var defaultRegistry atomic.Pointer[Registry]
+
+// SetDefault sets the process-wide registry
+func SetDefault(r *Registry) {
+	defaultRegistry.Store(r)
+}
+
+// Default returns the process-wide registry (nil when styles are disabled)
+func Default() *Registry {
+	return defaultRegistry.Load()
+}
@@ -0,0 +1,120 @@
+package styles
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/username/llm-gateway/internal/config"
+)
+
+func newTestRegistry(t *testing.T, file string) *Registry {
+	t.Helper()
+	r, err := New(config.StylesConfig{Enabled: true, File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegistry_Versions(t *testing.T) {
+	r := newTestRegistry(t, "")
+
+	if _, err := r.AddVersion("bad name", "x", ""); !errors.Is(err, ErrInvalid) {
+		t.Errorf("AddVersion(bad name) error = %v, want ErrInvalid", err)
+	}
+	if _, err := r.AddVersion("support_agent", " ", ""); !errors.Is(err, ErrInvalid) {
+		t.Errorf("AddVersion(empty content) error = %v, want ErrInvalid", err)
+	}
+
+	r.AddVersion("support_agent", "You are a support agent.", "admin")
+	v2, _ := r.AddVersion("support_agent", "You are a friendly support agent.", "admin")
+	if v2.Version != 2 {
+		t.Errorf("second version = %d, want 2", v2.Version)
+	}
+
+	// New versions do not serve traffic until rolled out
+	got, err := r.Resolve("support_agent", "user-1")
+	if err != nil || got.Version != 1 || got.Content != "You are a support agent." {
+		t.Errorf("Resolve() = %+v, %v, want version 1", got, err)
+	}
+	if got, err := r.Resolve("support_agent@2", "user-1"); err != nil || got.Version != 2 {
+		t.Errorf("Resolve(pinned) = %+v, %v, want version 2", got, err)
+	}
+	if _, err := r.Resolve("support_agent@7", "user-1"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Resolve(missing version) error = %v", err)
+	}
+	if _, err := r.Resolve("support_agent@x", "user-1"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Resolve(bad version) error = %v", err)
+	}
+	if _, err := r.Resolve("other", "user-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(missing style) error = %v", err)
+	}
+}
+
+func TestRegistry_Rollout(t *testing.T) {
+	r := newTestRegistry(t, "")
+	r.AddVersion("support_agent", "v1", "")
+	r.AddVersion("support_agent", "v2", "")
+
+	if _, err := r.SetRollout("support_agent", 3, 10); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("SetRollout(missing version) error = %v", err)
+	}
+	if _, err := r.SetRollout("support_agent", 2, 120); !errors.Is(err, ErrInvalid) {
+		t.Errorf("SetRollout(120%%) error = %v", err)
+	}
+	if _, err := r.SetRollout("support_agent", 2, 25); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := 0
+	for i := 0; i < 1000; i++ {
+		caller := fmt.Sprintf("user-%d", i)
+		first, _ := r.Resolve("support_agent", caller)
+		// Callers stay on the same version for the whole rollout
+		for j := 0; j < 3; j++ {
+			if again, _ := r.Resolve("support_agent", caller); again.Version != first.Version {
+				t.Fatalf("caller %s moved from version %d to %d", caller, first.Version, again.Version)
+			}
+		}
+		if first.Candidate {
+			candidates++
+		}
+	}
+	if candidates < 180 || candidates > 320 {
+		t.Errorf("%d of 1000 callers on the candidate, want about 250", candidates)
+	}
+
+	style, _ := r.SetRollout("support_agent", 2, 100)
+	if style.Stable != 2 || style.Candidate != 0 {
+		t.Errorf("after promotion stable = %d, candidate = %d", style.Stable, style.Candidate)
+	}
+	style, _ = r.SetRollout("support_agent", 1, 10)
+	style, _ = r.SetRollout("support_agent", 1, 0)
+	if style.Stable != 2 || style.Candidate != 0 {
+		t.Errorf("after rollback stable = %d, candidate = %d", style.Stable, style.Candidate)
+	}
+}
+
+func TestRegistry_Persistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "styles.json")
+	r := newTestRegistry(t, file)
+	r.AddVersion("support_agent", "v1", "")
+	r.AddVersion("support_agent", "v2", "")
+	r.SetRollout("support_agent", 2, 50)
+	r.AddVersion("gone", "v1", "")
+	r.Delete("gone")
+
+	loaded := newTestRegistry(t, file)
+	list := loaded.List()
+	if len(list) != 1 || list[0].Name != "support_agent" || len(list[0].Versions) != 2 || list[0].Candidate != 2 {
+		t.Errorf("loaded styles = %+v", list)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	if r, err := New(config.StylesConfig{}); r != nil || err != nil {
+		t.Errorf("New(disabled) = %v, %v, want nil", r, err)
+	}
+}
@@ -0,0 +1,165 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testClient is a minimal WebSocket client speaking raw frames
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, url string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return &testClient{conn: conn, br: br}
+}
+
+// send writes one masked frame
+func (c *testClient) send(fin bool, opcode int, payload []byte) {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+// read reads one unmasked frame
+func (c *testClient) read(t *testing.T) (int, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(c.br, payload)
+	return int(header[0] & 0x0F), payload
+}
+
+func TestConn_Messages(t *testing.T) {
+	received := make(chan string, 1)
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, 1024)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		op, msg, err := conn.ReadMessage()
+		if err != nil || op != OpText {
+			t.Errorf("ReadMessage = %d, %v", op, err)
+			return
+		}
+		received <- string(msg)
+		conn.WriteMessage(OpText, []byte("reply: "+string(msg)))
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	}))
+	defer server.Close()
+
+	client := dial(t, server.URL)
+	// A fragmented message with a ping between its frames
+	client.send(false, OpText, []byte("hel"))
+	client.send(true, OpPing, []byte("are you there"))
+	client.send(true, OpContinuation, []byte("lo"))
+
+	if op, payload := client.read(t); op != OpPong || string(payload) != "are you there" {
+		t.Errorf("ping answer = %d %q, want a pong echoing it", op, payload)
+	}
+	if got := <-received; got != "hello" {
+		t.Errorf("message = %q, want hello", got)
+	}
+	if op, payload := client.read(t); op != OpText || string(payload) != "reply: hello" {
+		t.Errorf("reply = %d %q", op, payload)
+	}
+
+	client.send(true, OpClose, []byte{0x03, 0xE8})
+	if op, payload := client.read(t); op != OpClose || binary.BigEndian.Uint16(payload) != CloseNormal {
+		t.Errorf("close answer = %d %v, want a normal close", op, payload)
+	}
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != CloseNormal {
+		t.Errorf("ReadMessage after close = %v, want a CloseError", err)
+	}
+}
+
+func TestConn_RejectsUnmaskedAndOversizedFrames(t *testing.T) {
+	errs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, 8)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		errs <- err
+	}))
+	defer server.Close()
+
+	client := dial(t, server.URL)
+	client.send(true, OpText, []byte("much too long"))
+	if op, payload := client.read(t); op != OpClose || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Errorf("close = %d %v, want 1009", op, payload)
+	}
+	var closeErr *CloseError
+	if err := <-errs; !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Errorf("ReadMessage = %v, want message too big", err)
+	}
+}
+
+func TestUpgrade_InvalidHandshake(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "short")
+
+	rr := httptest.NewRecorder()
+	if _, err := Upgrade(rr, req, 0); !errors.Is(err, ErrHandshake) {
+		t.Errorf("Upgrade = %v, want ErrHandshake", err)
+	}
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 {
+		t.Errorf("nothing should be written on a failed handshake, got %d %q", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Content part types
+const (
+	ContentPartText  = "text"
+	ContentPartImage = "image_url"
+)
+
+// ContentPart is one part of a multimodal message: text or an image
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is an image given by URL, either remote or a base64 data URL
+// such as data:image/png;base64,...
+type ImageURL struct {
+	URL string `json:"url"`
+	// Detail is "auto", "low" or "high"
+	Detail string `json:"detail,omitempty"`
+}
+
+// DataURL decodes a base64 data URL into its media type and data. ok is
+// false for other URLs.
+func (u ImageURL) DataURL() (mediaType, data string, ok bool, err error) {
+	rest, found := strings.CutPrefix(u.URL, "data:")
+	if !found {
+		return "", "", false, nil
+	}
+	header, data, found := strings.Cut(rest, ",")
+	mediaType, encoding, _ := strings.Cut(header, ";")
+	if !found || encoding != "base64" || mediaType == "" {
+		return "", "", true, errors.New("image data URLs must be base64 encoded with a media type")
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return "", "", true, fmt.Errorf("invalid base64 image data: %w", err)
+	}
+	return mediaType, data, true, nil
+}
+
+// chatMessageJSON is ChatMessage without its methods, for encoding
+type chatMessageJSON ChatMessage
+
+// MarshalJSON encodes Parts as the content array if the message has them,
+// and Content as a string otherwise
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	if m.Parts == nil {
+		return json.Marshal(chatMessageJSON(m))
+	}
+	return json.Marshal(struct {
+		chatMessageJSON
+		Content []ContentPart `json:"content"`
+	}{chatMessageJSON(m), m.Parts})
+}
+
+// UnmarshalJSON decodes string content into Content, and an array of
+// content parts into Parts
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	var msg struct {
+		chatMessageJSON
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	*m = ChatMessage(msg.chatMessageJSON)
+
+	content := strings.TrimSpace(string(msg.Content))
+	switch {
+	case content == "" || content == "null":
+		return nil
+	case strings.HasPrefix(content, "["):
+		if err := json.Unmarshal(msg.Content, &m.Parts); err != nil {
+			return fmt.Errorf("invalid message content parts: %w", err)
+		}
+		if m.Parts == nil {
+			m.Parts = []ContentPart{}
+		}
+		for i, part := range m.Parts {
+			switch {
+			case part.Type == ContentPartText:
+			case part.Type == ContentPartImage && part.ImageURL != nil && part.ImageURL.URL != "":
+				if _, _, _, err := part.ImageURL.DataURL(); err != nil {
+					return fmt.Errorf("message content part %d: %w", i, err)
+				}
+			default:
+				return fmt.Errorf("unsupported message content part %d of type %q", i, part.Type)
+			}
+		}
+		return nil
+	default:
+		return json.Unmarshal(msg.Content, &m.Content)
+	}
+}
+
+// Text returns the message's text: Content, or the text parts joined by
+// newlines
+func (m ChatMessage) Text() string {
+	if m.Parts == nil {
+		return m.Content
+	}
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == ContentPartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// TextFields returns pointers to the message's text, Content or that of each
+// text part, for checks that rewrite text in place
+func (m *ChatMessage) TextFields() []*string {
+	if m.Parts == nil {
+		return []*string{&m.Content}
+	}
+	var fields []*string
+	for i := range m.Parts {
+		if m.Parts[i].Type == ContentPartText {
+			fields = append(fields, &m.Parts[i].Text)
+		}
+	}
+	return fields
+}
+
+// AppendText adds text to the end of the message
+func (m *ChatMessage) AppendText(text string) {
+	if m.Parts == nil {
+		m.Content += text
+		return
+	}
+	m.Parts = append(m.Parts, ContentPart{Type: ContentPartText, Text: text})
+}
+
+// Images returns the message's image parts
+func (m ChatMessage) Images() []ImageURL {
+	var images []ImageURL
+	for _, part := range m.Parts {
+		if part.Type == ContentPartImage {
+			images = append(images, *part.ImageURL)
+		}
+	}
+	return images
+}
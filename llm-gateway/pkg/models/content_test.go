@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChatMessage_JSONContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		wantText  string
+		wantParts int
+		wantErr   bool
+	}{
+		{"string", `{"role":"user","content":"hi"}`, "hi", 0, false},
+		{"null", `{"role":"assistant","content":null}`, "", 0, false},
+		{"missing", `{"role":"assistant"}`, "", 0, false},
+		{"parts", `{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}`, "What is this?", 2, false},
+		{"data url", `{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,aGk=","detail":"low"}}]}`, "", 1, false},
+		{"unknown part", `{"role":"user","content":[{"type":"audio"}]}`, "", 0, true},
+		{"image without url", `{"role":"user","content":[{"type":"image_url"}]}`, "", 0, true},
+		{"bad data url", `{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,!!"}}]}`, "", 0, true},
+		{"data url without base64", `{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png,hi"}}]}`, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg ChatMessage
+			err := json.Unmarshal([]byte(tt.in), &msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if msg.Text() != tt.wantText || len(msg.Parts) != tt.wantParts {
+				t.Errorf("message = %+v, want text %q and %d parts", msg, tt.wantText, tt.wantParts)
+			}
+		})
+	}
+}
+
+func TestChatMessage_JSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"role":"user","content":"hi"}`,
+		`{"role":"user","content":[{"type":"text","text":"Describe"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGk=","detail":"high"}}]}`,
+	}
+
+	for _, in := range tests {
+		var msg ChatMessage
+		if err := json.Unmarshal([]byte(in), &msg); err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("round trip = %s, want %s", out, in)
+		}
+	}
+}
+
+func TestChatMessage_TextHelpers(t *testing.T) {
+	msg := ChatMessage{Role: "user", Parts: []ContentPart{
+		{Type: ContentPartText, Text: "a"},
+		{Type: ContentPartImage, ImageURL: &ImageURL{URL: "https://example.com/a.png"}},
+		{Type: ContentPartText, Text: "b"},
+	}}
+
+	for _, field := range msg.TextFields() {
+		*field += "!"
+	}
+	msg.AppendText("c")
+	if got := msg.Text(); got != "a!\nb!\nc" {
+		t.Errorf("Text() = %q", got)
+	}
+	if images := msg.Images(); len(images) != 1 || images[0].URL != "https://example.com/a.png" {
+		t.Errorf("Images() = %+v", images)
+	}
+
+	plain := ChatMessage{Role: "user", Content: "x"}
+	plain.AppendText("y")
+	if plain.Text() != "xy" || plain.Parts != nil {
+		t.Errorf("plain message = %+v", plain)
+	}
+}
+
+func TestImageURL_DataURL(t *testing.T) {
+	mediaType, data, ok, err := ImageURL{URL: "data:image/jpeg;base64,aGk="}.DataURL()
+	if err != nil || !ok || mediaType != "image/jpeg" || data != "aGk=" {
+		t.Errorf("DataURL() = %q, %q, %v, %v", mediaType, data, ok, err)
+	}
+	if _, _, ok, err := (ImageURL{URL: "https://example.com/a.png"}).DataURL(); ok || err != nil {
+		t.Errorf("remote URL: ok = %v, err = %v", ok, err)
+	}
+}
@@ -6,12 +6,14 @@ import (
 
 // ChatCompletionRequest represents an OpenAI-compatible chat completion request
 type ChatCompletionRequest struct {
-	Model            string         `json:"model"`
-	Messages         []ChatMessage  `json:"messages"`
-	Temperature      *float64       `json:"temperature,omitempty"`
-	TopP             *float64       `json:"top_p,omitempty"`
-	N                int            `json:"n,omitempty"`
-	Stream           bool           `json:"stream,omitempty"`
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	N           int           `json:"n,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk with the stream's token usage
+	StreamOptions    *StreamOptions `json:"stream_options,omitempty"`
 	Stop             []string       `json:"stop,omitempty"`
 	MaxTokens        int            `json:"max_tokens,omitempty"`
 	PresencePenalty  float64        `json:"presence_penalty,omitempty"`
@@ -19,7 +21,7 @@ type ChatCompletionRequest struct {
 	LogitBias        map[string]int `json:"logit_bias,omitempty"`
 	User             string         `json:"user,omitempty"`
 	// Function calling (OpenAI)
-	Functions    []Function `json:"functions,omitempty"`
+	Functions    []Function  `json:"functions,omitempty"`
 	FunctionCall interface{} `json:"function_call,omitempty"`
 	// Tool use (newer API)
 	Tools      []Tool      `json:"tools,omitempty"`
@@ -28,15 +30,36 @@ type ChatCompletionRequest struct {
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 	// Seed for reproducibility
 	Seed *int `json:"seed,omitempty"`
+	// Style names a server-managed system prompt preset (gateway extension, not forwarded)
+	Style string `json:"style,omitempty"`
+	// Preset names a server-managed generation parameter preset (gateway extension, not forwarded)
+	Preset string `json:"preset,omitempty"`
+	// Assemble keeps the assembled message of a stream, retrievable from
+	// /v1/responses/{id} (gateway extension, not forwarded)
+	Assemble bool `json:"assemble,omitempty"`
+	// TranslateTo asks for the response translated into this language (ISO
+	// 639-1), returned next to the original (gateway extension, not forwarded)
+	TranslateTo string `json:"translate_to,omitempty"`
+}
+
+// StreamOptions holds options for streamed chat completions (OpenAI)
+type StreamOptions struct {
+	// IncludeUsage adds a chunk with no choices and the usage of the whole request before [DONE]
+	IncludeUsage bool `json:"include_usage,omitempty"`
 }
 
-// ChatMessage represents a message in a chat completion request
+// ChatMessage represents a message in a chat completion request. Its JSON
+// content is a string or, for multimodal messages, an array of content parts;
+// see MarshalJSON.
 type ChatMessage struct {
-	Role       string      `json:"role"`
-	Content    string      `json:"content"`
-	Name       string      `json:"name,omitempty"`
-	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
-	ToolCallID string      `json:"tool_call_id,omitempty"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Parts hold the content of messages sent as content parts; Content is
+	// then empty
+	Parts      []ContentPart `json:"-"`
+	Name       string        `json:"name,omitempty"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
 }
 
 // Function represents a function definition for function calling
@@ -54,14 +77,17 @@ type Tool struct {
 
 // ToolCall represents a tool call in a message
 type ToolCall struct {
-	ID       string       `json:"id"`
-	Type     string       `json:"type"`
+	// Index identifies the call across the deltas of a streamed response,
+	// which set ID, Type and the function name only on its first delta
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
 	Function FunctionCall `json:"function"`
 }
 
 // FunctionCall represents a function call
 type FunctionCall struct {
-	Name      string `json:"name"`
+	Name      string `json:"name,omitempty"`
 	Arguments string `json:"arguments"`
 }
 
@@ -112,6 +138,8 @@ type CompletionRequest struct {
 	FrequencyPenalty float64  `json:"frequency_penalty,omitempty"`
 	BestOf           int      `json:"best_of,omitempty"`
 	User             string   `json:"user,omitempty"`
+	// Preset names a server-managed generation parameter preset (gateway extension, not forwarded)
+	Preset string `json:"preset,omitempty"`
 }
 
 // Validate validates the completion request
@@ -157,12 +185,16 @@ type AnthropicMessageRequest struct {
 	Stream      bool          `json:"stream,omitempty"`
 	StopSeq     []string      `json:"stop_sequences,omitempty"`
 	Metadata    interface{}   `json:"metadata,omitempty"`
+	// Style names a server-managed system prompt preset (gateway extension, not forwarded)
+	Style string `json:"style,omitempty"`
+	// Preset names a server-managed generation parameter preset (gateway extension, not forwarded)
+	Preset string `json:"preset,omitempty"`
 }
 
 // ToChatCompletionRequest converts Anthropic request to OpenAI format
 func (r *AnthropicMessageRequest) ToChatCompletionRequest() *ChatCompletionRequest {
 	messages := r.Messages
-	
+
 	// Add system message if present
 	if r.System != "" {
 		messages = append([]ChatMessage{
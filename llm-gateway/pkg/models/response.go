@@ -9,6 +9,8 @@ type ChatCompletionResponse struct {
 	Choices           []ChatCompletionChoice `json:"choices"`
 	Usage             Usage                  `json:"usage"`
 	SystemFingerprint string                 `json:"system_fingerprint,omitempty"`
+	// PromptWarnings are the prompt linter's findings, with prompt_lint.annotate
+	PromptWarnings    []PromptWarning        `json:"prompt_warnings,omitempty"`
 }
 
 // ChatCompletionChoice represents a choice in a chat completion response
@@ -17,6 +19,43 @@ type ChatCompletionChoice struct {
 	Message      ChatMessage `json:"message"`
 	FinishReason string      `json:"finish_reason"`
 	LogProbs     *LogProbs   `json:"logprobs,omitempty"`
+	// Citations are the sources the answer cites, normalized across providers
+	Citations []Citation `json:"citations,omitempty"`
+	// Translation is the message translated as the request's translate_to asked
+	Translation *Translation `json:"translation,omitempty"`
+}
+
+// Translation is a response message translated by the gateway
+type Translation struct {
+	// Language is the ISO 639-1 code of the translation
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+// PromptWarning is a likely mistake the prompt linter found in a prompt
+type PromptWarning struct {
+	// Rule identifies the check, e.g. unclosed_code_fence
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Messages are the indexes of the messages the warning is about
+	Messages []int `json:"messages,omitempty"`
+}
+
+// LintResponse is the response of POST /v1/lint
+type LintResponse struct {
+	Warnings []PromptWarning `json:"warnings"`
+}
+
+// Citation is a source cited by a response (web search results and the like)
+type Citation struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	// CitedText is the passage of the source the answer relies on, if the provider returns it
+	CitedText string `json:"cited_text,omitempty"`
+	// StartIndex and EndIndex delimit the part of the message content citing
+	// the source, when the provider returns positions
+	StartIndex *int `json:"start_index,omitempty"`
+	EndIndex   *int `json:"end_index,omitempty"`
 }
 
 // ChatCompletionStreamResponse represents a streaming chat completion chunk
@@ -27,6 +66,8 @@ type ChatCompletionStreamResponse struct {
 	Model             string                       `json:"model"`
 	Choices           []ChatCompletionStreamChoice `json:"choices"`
 	SystemFingerprint string                       `json:"system_fingerprint,omitempty"`
+	// Usage is set on the final chunk when stream_options.include_usage is requested
+	Usage *Usage `json:"usage,omitempty"`
 }
 
 // ChatCompletionStreamChoice represents a choice in a streaming response
@@ -35,6 +76,8 @@ type ChatCompletionStreamChoice struct {
 	Delta        ChatMessageDelta `json:"delta"`
 	FinishReason *string          `json:"finish_reason"`
 	LogProbs     *LogProbs        `json:"logprobs,omitempty"`
+	// Citations are set on chunks converted from providers that stream citations separately
+	Citations []Citation `json:"citations,omitempty"`
 }
 
 // ChatMessageDelta represents the delta content in streaming
@@ -117,6 +160,25 @@ type Model struct {
 	Created  int64  `json:"created,omitempty"`
 	OwnedBy  string `json:"owned_by"`
 	Provider string `json:"provider,omitempty"` // Custom field for routing
+
+	// Metadata from the gateway's model catalog, omitted for models it does not know
+	ContextWindow   int                `json:"context_window,omitempty"`
+	MaxOutputTokens int                `json:"max_output_tokens,omitempty"`
+	Pricing         *ModelPricing      `json:"pricing,omitempty"`
+	Capabilities    *ModelCapabilities `json:"capabilities,omitempty"`
+}
+
+// ModelPricing is a model's price in USD per million tokens
+type ModelPricing struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// ModelCapabilities are the features a model supports
+type ModelCapabilities struct {
+	Vision   bool `json:"vision"`
+	Tools    bool `json:"tools"`
+	JSONMode bool `json:"json_mode"`
 }
 
 // ErrorResponse represents an API error response
@@ -130,4 +192,21 @@ type APIError struct {
 	Type    string `json:"type"`
 	Param   string `json:"param,omitempty"`
 	Code    string `json:"code,omitempty"`
+	// Metadata carries diagnostics for failed upstream calls
+	Metadata *ErrorMetadata `json:"metadata,omitempty"`
+}
+
+// ErrorMetadata describes how the gateway tried to serve a failed request
+type ErrorMetadata struct {
+	Attempts []AttemptSummary `json:"attempts,omitempty"`
+}
+
+// AttemptSummary is one upstream call made for a failed request
+type AttemptSummary struct {
+	Provider   string `json:"provider"`
+	Status     int    `json:"status,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	ErrorClass string `json:"error_class,omitempty"`
+	// UpstreamRequestID is the provider's request ID, to quote to its support
+	UpstreamRequestID string `json:"upstream_request_id,omitempty"`
 }
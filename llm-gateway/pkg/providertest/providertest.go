@@ -0,0 +1,446 @@
+// Package providertest is a conformance suite for gateway providers. Every
+// Provider implementation must pass it, so providers contributed later meet
+// the same bar as the built-in ones: OpenAI-shaped chat responses, SSE stream
+// framing, tool calls, error mapping and usage reporting.
+//
+// The suite does not talk to the real upstream. A provider's test supplies a
+// Harness whose Upstream fakes the upstream API for each Scenario, in the
+// provider's own wire format, and Run checks what the provider makes of it:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.Run(t, providertest.Harness{
+//			Model:    "my-model",
+//			New:      func(baseURL string) providertest.Provider { return NewMyProvider(baseURL) },
+//			Upstream: fakeMyAPI,
+//		})
+//	}
+package providertest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/username/llm-gateway/pkg/models"
+)
+
+// Provider is the part of a gateway provider the suite exercises; every
+// provider registered with the gateway satisfies it
+type Provider interface {
+	Name() string
+	ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error)
+	ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (io.ReadCloser, error)
+	SupportsModel(model string) bool
+}
+
+// StatusError is implemented by the errors a provider returns for upstream
+// error responses
+type StatusError interface {
+	error
+	// HTTPStatus returns the status code the upstream answered with
+	HTTPStatus() int
+}
+
+// Feature is an optional capability checked by the suite
+type Feature string
+
+// Optional features
+const (
+	// FeatureToolCalls is returning tool calls requested by the model
+	FeatureToolCalls Feature = "tool_calls"
+)
+
+// Scenario is what the fake upstream must answer for one request
+type Scenario struct {
+	// Prompt is the content of the single user message sent. The fake should
+	// answer 400 if the request it receives does not carry it.
+	Prompt string
+	// Chunks are the reply's content, one per streamed event; complete
+	// replies join them
+	Chunks []string
+	// ToolCall, when set, is the reply instead of Chunks. The request defines
+	// the tool it names; the fake should answer 400 if it does not.
+	ToolCall *models.ToolCall
+	// FinishReason is the OpenAI finish_reason the reply must map to: "stop",
+	// "length" or "tool_calls". The fake answers with its native equivalent.
+	FinishReason string
+	// PromptTokens and CompletionTokens are the usage the fake reports
+	PromptTokens     int
+	CompletionTokens int
+
+	// ErrorStatus, when set, makes the fake fail with this status and
+	// ErrorMessage, in the upstream's error format
+	ErrorStatus  int
+	ErrorMessage string
+}
+
+// Content returns the reply's full content
+func (s Scenario) Content() string {
+	return strings.Join(s.Chunks, "")
+}
+
+// Harness connects a provider to the suite
+type Harness struct {
+	// Model is a model the provider serves
+	Model string
+	// New creates the provider under test, sending requests to baseURL
+	New func(baseURL string) Provider
+	// Upstream fakes the upstream API for s. It answers complete or streamed
+	// requests as the request asks, in the upstream's wire format.
+	Upstream func(s Scenario) http.Handler
+	// Unsupported lists the optional features the provider lacks; their
+	// checks are skipped
+	Unsupported []Feature
+	// HTTP2 serves Upstream over cleartext HTTP/2 (h2c) instead of HTTP/1.1,
+	// for providers speaking gRPC
+	HTTP2 bool
+}
+
+// requestTimeout bounds each call to the provider
+const requestTimeout = 10 * time.Second
+
+// Run runs the conformance suite against the harness' provider
+func Run(t *testing.T, h Harness) {
+	t.Run("SupportsModel", func(t *testing.T) {
+		p := h.New("http://127.0.0.1:0")
+		if p.Name() == "" {
+			t.Error("Name() is empty")
+		}
+		if !p.SupportsModel(h.Model) {
+			t.Errorf("SupportsModel(%q) = false", h.Model)
+		}
+	})
+
+	t.Run("Chat", func(t *testing.T) {
+		s := Scenario{Prompt: "Say hello", Chunks: []string{"Hello", " there"}, FinishReason: "stop", PromptTokens: 11, CompletionTokens: 3}
+		resp, err := h.chat(t, s)
+		if err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+		checkResponse(t, resp, s)
+	})
+
+	t.Run("ChatLength", func(t *testing.T) {
+		s := Scenario{Prompt: "Count to a million", Chunks: []string{"1, 2, 3"}, FinishReason: "length", PromptTokens: 6, CompletionTokens: 5}
+		resp, err := h.chat(t, s)
+		if err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+		checkResponse(t, resp, s)
+	})
+
+	t.Run("ToolCalls", func(t *testing.T) {
+		if slices.Contains(h.Unsupported, FeatureToolCalls) {
+			t.Skip("provider does not support tool calls")
+		}
+		s := toolCallScenario()
+		resp, err := h.chat(t, s)
+		if err != nil {
+			t.Fatalf("ChatCompletion() error = %v", err)
+		}
+		if len(resp.Choices) != 1 {
+			t.Fatalf("got %d choices, want 1", len(resp.Choices))
+		}
+		choice := resp.Choices[0]
+		if choice.FinishReason != "tool_calls" {
+			t.Errorf("finish_reason = %q, want tool_calls", choice.FinishReason)
+		}
+		checkToolCalls(t, choice.Message.ToolCalls, s)
+	})
+
+	t.Run("StreamToolCalls", func(t *testing.T) {
+		if slices.Contains(h.Unsupported, FeatureToolCalls) {
+			t.Skip("provider does not support tool calls")
+		}
+		s := toolCallScenario()
+		chunks := h.stream(t, s, false)
+		checkStream(t, chunks, s)
+
+		// Deltas of one call share its index; the first carries its ID and name
+		var calls []models.ToolCall
+		for _, chunk := range chunks {
+			for _, choice := range chunk.Choices {
+				for _, delta := range choice.Delta.ToolCalls {
+					if delta.Index == nil {
+						t.Fatalf("tool call delta %+v has no index", delta)
+					}
+					switch index := *delta.Index; {
+					case index == len(calls):
+						calls = append(calls, delta)
+					case index < len(calls):
+						calls[index].Function.Arguments += delta.Function.Arguments
+					default:
+						t.Fatalf("tool call index %d skips %d", index, len(calls))
+					}
+				}
+			}
+		}
+		checkToolCalls(t, calls, s)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		s := Scenario{Prompt: "Say hello", Chunks: []string{"Hel", "lo", " there"}, FinishReason: "stop", PromptTokens: 11, CompletionTokens: 3}
+		chunks := h.stream(t, s, false)
+		checkStream(t, chunks, s)
+		for _, chunk := range chunks {
+			if chunk.Usage != nil {
+				t.Errorf("usage chunk sent without stream_options.include_usage: %+v", chunk)
+			}
+		}
+	})
+
+	t.Run("StreamUsage", func(t *testing.T) {
+		s := Scenario{Prompt: "Say hello", Chunks: []string{"Hello", "!"}, FinishReason: "stop", PromptTokens: 9, CompletionTokens: 2}
+		chunks := h.stream(t, s, true)
+		checkStream(t, chunks, s)
+
+		var usage []models.ChatCompletionStreamResponse
+		for _, chunk := range chunks {
+			if chunk.Usage != nil {
+				usage = append(usage, chunk)
+			}
+		}
+		if len(usage) != 1 {
+			t.Fatalf("got %d usage chunks, want 1", len(usage))
+		}
+		if last := chunks[len(chunks)-1]; last.Usage == nil || len(last.Choices) != 0 {
+			t.Errorf("last chunk = %+v, want the usage chunk without choices", last)
+		}
+		checkUsage(t, *usage[0].Usage, s)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError} {
+			s := Scenario{Prompt: "Say hello", ErrorStatus: status, ErrorMessage: "conformance failure " + http.StatusText(status)}
+
+			_, err := h.chat(t, s)
+			checkError(t, "ChatCompletion", err, s)
+
+			p, stop := h.start(s)
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			stream, err := p.ChatCompletionStream(ctx, request(h.Model, s, true, false))
+			if stream != nil {
+				stream.Close()
+			}
+			cancel()
+			stop()
+			checkError(t, "ChatCompletionStream", err, s)
+		}
+	})
+}
+
+// toolCallScenario is a reply calling one tool
+func toolCallScenario() Scenario {
+	call := &models.ToolCall{ID: "call_1", Type: "function", Function: models.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}}
+	return Scenario{Prompt: "Weather in Paris?", ToolCall: call, FinishReason: "tool_calls", PromptTokens: 20, CompletionTokens: 8}
+}
+
+// start serves s from a fake upstream and returns a provider sending to it
+func (h Harness) start(s Scenario) (Provider, func()) {
+	server := httptest.NewUnstartedServer(h.Upstream(s))
+	if h.HTTP2 {
+		server.Config.Protocols = new(http.Protocols)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+	}
+	server.Start()
+	return h.New(server.URL), server.Close
+}
+
+// request builds the request sent for s
+func request(model string, s Scenario, stream, includeUsage bool) *models.ChatCompletionRequest {
+	req := &models.ChatCompletionRequest{
+		Model:    model,
+		Messages: []models.ChatMessage{{Role: "user", Content: s.Prompt}},
+		Stream:   stream,
+	}
+	if includeUsage {
+		req.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	}
+	if s.ToolCall != nil {
+		req.Tools = []models.Tool{{Type: "function", Function: models.Function{
+			Name: s.ToolCall.Function.Name,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"city": map[string]string{"type": "string"}},
+			},
+		}}}
+	}
+	return req
+}
+
+func (h Harness) chat(t *testing.T, s Scenario) (*models.ChatCompletionResponse, error) {
+	t.Helper()
+	p, stop := h.start(s)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return p.ChatCompletion(ctx, request(h.Model, s, false, false))
+}
+
+// stream reads a whole stream for s, checking its SSE framing, and returns its chunks
+func (h Harness) stream(t *testing.T, s Scenario, includeUsage bool) []models.ChatCompletionStreamResponse {
+	t.Helper()
+	p, stop := h.start(s)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	body, err := p.ChatCompletionStream(ctx, request(h.Model, s, true, includeUsage))
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	defer body.Close()
+
+	var chunks []models.ChatCompletionStreamResponse
+	done := false
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		if done {
+			t.Fatalf("data after [DONE]: %q", line)
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			t.Fatalf("line %q is not an SSE data line", line)
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk models.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q is not JSON: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading stream: %v", err)
+	}
+	if !done {
+		t.Fatal("stream did not end with data: [DONE]")
+	}
+	if len(chunks) == 0 {
+		t.Fatal("stream has no chunks")
+	}
+	return chunks
+}
+
+func checkResponse(t *testing.T, resp *models.ChatCompletionResponse, s Scenario) {
+	t.Helper()
+	if resp.Object != "chat.completion" {
+		t.Errorf("object = %q, want chat.completion", resp.Object)
+	}
+	if resp.ID == "" || resp.Model == "" || resp.Created == 0 {
+		t.Errorf("id, model and created must be set: %+v", resp)
+	}
+	if len(resp.Choices) != 1 {
+		t.Fatalf("got %d choices, want 1", len(resp.Choices))
+	}
+	choice := resp.Choices[0]
+	if choice.Index != 0 || choice.Message.Role != "assistant" {
+		t.Errorf("choice = %+v, want index 0 and role assistant", choice)
+	}
+	if choice.Message.Content != s.Content() {
+		t.Errorf("content = %q, want %q", choice.Message.Content, s.Content())
+	}
+	if choice.FinishReason != s.FinishReason {
+		t.Errorf("finish_reason = %q, want %q", choice.FinishReason, s.FinishReason)
+	}
+	checkUsage(t, resp.Usage, s)
+}
+
+// checkStream checks the chunks of a stream: one ID, the content in order and
+// a single finish_reason
+func checkStream(t *testing.T, chunks []models.ChatCompletionStreamResponse, s Scenario) {
+	t.Helper()
+	var content strings.Builder
+	var finishReasons []string
+	for _, chunk := range chunks {
+		if chunk.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q, want chat.completion.chunk", chunk.Object)
+		}
+		if chunk.ID == "" || chunk.ID != chunks[0].ID {
+			t.Errorf("chunk id = %q, want %q on every chunk", chunk.ID, chunks[0].ID)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				t.Errorf("choice index = %d, want 0", choice.Index)
+			}
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != nil {
+				finishReasons = append(finishReasons, *choice.FinishReason)
+			}
+		}
+	}
+	if content.String() != s.Content() {
+		t.Errorf("streamed content = %q, want %q", content.String(), s.Content())
+	}
+	if len(finishReasons) != 1 || finishReasons[0] != s.FinishReason {
+		t.Errorf("finish reasons = %v, want [%s]", finishReasons, s.FinishReason)
+	}
+}
+
+// checkToolCalls checks that calls is the scenario's tool call. Upstreams
+// without call IDs get generated ones, so only the presence of an ID is checked.
+func checkToolCalls(t *testing.T, calls []models.ToolCall, s Scenario) {
+	t.Helper()
+	call := s.ToolCall
+	if len(calls) != 1 || calls[0].ID == "" || calls[0].Type != "function" || calls[0].Function.Name != call.Function.Name {
+		t.Fatalf("tool_calls = %+v, want %+v", calls, call)
+	}
+	var got, want interface{}
+	if err := json.Unmarshal([]byte(calls[0].Function.Arguments), &got); err != nil {
+		t.Fatalf("arguments %q are not JSON: %v", calls[0].Function.Arguments, err)
+	}
+	json.Unmarshal([]byte(call.Function.Arguments), &want)
+	if !jsonEqual(got, want) {
+		t.Errorf("arguments = %s, want %s", calls[0].Function.Arguments, call.Function.Arguments)
+	}
+}
+
+func checkUsage(t *testing.T, usage models.Usage, s Scenario) {
+	t.Helper()
+	if usage.PromptTokens != s.PromptTokens || usage.CompletionTokens != s.CompletionTokens ||
+		usage.TotalTokens != s.PromptTokens+s.CompletionTokens {
+		t.Errorf("usage = %+v, want %d prompt and %d completion tokens", usage, s.PromptTokens, s.CompletionTokens)
+	}
+}
+
+// checkError checks that err carries the upstream's status and message
+func checkError(t *testing.T, call string, err error, s Scenario) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("%s() succeeded on a %d upstream response", call, s.ErrorStatus)
+		return
+	}
+	var statusErr StatusError
+	if !errors.As(err, &statusErr) {
+		t.Errorf("%s() error %v does not report the upstream status", call, err)
+		return
+	}
+	if statusErr.HTTPStatus() != s.ErrorStatus {
+		t.Errorf("%s() status = %d, want %d", call, statusErr.HTTPStatus(), s.ErrorStatus)
+	}
+	if !strings.Contains(err.Error(), s.ErrorMessage) {
+		t.Errorf("%s() error %q does not carry the upstream message %q", call, err, s.ErrorMessage)
+	}
+}
+
+// jsonEqual compares decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}